@@ -7,9 +7,10 @@ dex-image:
 	perl -pi -e "s#ghcr.io/dexidp/dex:v2.30.2#${DEX_IMAGE}#g" config/manager/manager.yaml
 
 
+# Without the OpenShift APIs, the devmode build fakes the OpenShift serving certificates and routes
 .PHONY: run-local
 run-local: generate install
-	go run ./main.go
+	RELATED_IMAGE_DEX=$${RELATED_IMAGE_DEX:-$(DEX_IMAGE)} go run -tags devmode ./main.go
 
 .PHONY: bits
 bits: build dex-image manifests docker-build docker-push bundle bundle-build bundle-push
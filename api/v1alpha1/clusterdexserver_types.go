@@ -0,0 +1,87 @@
+// Copyright Red Hat
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterDexServerSpec defines the desired state of ClusterDexServer
+type ClusterDexServerSpec struct {
+	// Namespace the DexServer is created in. The namespace is created when it does not exist, and is left in place
+	// when the ClusterDexServer is deleted, unless createNamespace is set.
+	// +kubebuilder:validation:MinLength=1
+	TargetNamespace string `json:"targetNamespace"`
+	// Whether the target namespace is dedicated to the ClusterDexServer: the namespace is created by the operator,
+	// labeled, isolated with NetworkPolicies, limited by the quota of spec.namespace and deleted with the
+	// ClusterDexServer. An existing namespace the ClusterDexServer did not create is refused.
+	// +optional
+	CreateNamespace bool `json:"createNamespace,omitempty"`
+	// Settings of the namespace dedicated to the ClusterDexServer, see createNamespace
+	// +optional
+	Namespace TargetNamespaceSpec `json:"namespace,omitempty"`
+	// Spec of the DexServer created in the target namespace
+	DexServerSpec `json:",inline"`
+}
+
+// TargetNamespaceSpec defines the namespace dedicated to a ClusterDexServer
+type TargetNamespaceSpec struct {
+	// Labels added to the namespace, for the policies of the cluster selecting namespaces
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Hard limits of the ResourceQuota of the namespace, no ResourceQuota is created when empty
+	// +optional
+	ResourceQuota corev1.ResourceList `json:"resourceQuota,omitempty"`
+}
+
+const (
+	// Set when the DexServer is created or updated in the target namespace
+	ClusterDexServerConditionTypeApplied string = "Applied"
+)
+
+// ClusterDexServerStatus defines the observed state of ClusterDexServer
+type ClusterDexServerStatus struct {
+	// The DexServer created in the target namespace
+	// +optional
+	DexServer *RelatedObjectReference `json:"dexServer,omitempty"`
+	// The issuer reported by the DexServer
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// Conditions contains the Applied condition of this ClusterDexServer, and the Available and Ready conditions
+	// of the DexServer.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=cdexsrv,categories={auth}
+//+kubebuilder:printcolumn:name="Target Namespace",type=string,JSONPath=`.spec.targetNamespace`
+//+kubebuilder:printcolumn:name="Issuer",type=string,JSONPath=`.status.issuer`
+//+kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterDexServer is the Schema for the clusterdexservers API. It manages a DexServer in spec.targetNamespace, so
+// that dex servers can be defined without write access to the target namespaces, and optionally the namespace itself.
+type ClusterDexServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterDexServerSpec   `json:"spec,omitempty"`
+	Status ClusterDexServerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterDexServerList contains a list of ClusterDexServer
+type ClusterDexServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterDexServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterDexServer{}, &ClusterDexServerList{})
+}
@@ -19,18 +19,33 @@ type DexClientSpec struct {
 	// The shared oidc secret
 	ClientSecretRef corev1.SecretReference `json:"clientSecretRef,omitempty"`
 	// +optional
-	// Sets the public flag
+	// Public clients, such as native apps, have no secret and must use PKCE. Their redirect URIs must be loopback
+	// http URIs, https URIs, or private-use schemes in reverse domain name notation
 	Public bool `json:"public,omitempty"`
+	// +optional
+	// Register the client for the device authorization grant of CLI tools such as kubectl oidc-login with
+	// --grant-type=device-code. Device flow clients are public clients, the device callback of dex is added to
+	// their redirect URIs
+	DeviceFlow bool `json:"deviceFlow,omitempty"`
 	// Redirect URIs
 	RedirectURIs []string `json:"redirectURIs,omitempty"`
 	// +optional
-	// Trusted Peers
+	// Client ids of the clients allowed to request tokens issued to this client, with the
+	// audience:server:client_id:<this client id> scope
 	TrustedPeers []string `json:"trustedPeers,omitempty"`
 	// +optional
 	// LogoURL
 	LogoURL string `json:"logoURL,omitempty"`
 }
 
+// Redirect URI of the device authorization grant, relative to the issuer of dex
+const DeviceCallbackURI = "/device/callback"
+
+// IsPublic returns whether the client is registered without a secret, as the public and device flow clients are
+func (spec *DexClientSpec) IsPublic() bool {
+	return spec.Public || spec.DeviceFlow
+}
+
 const (
 	DexClientConditionTypeApplied             string = "Applied"
 	DexClientConditionTypeOAuth2ClientCreated string = "OAuth2ClientCreated"
@@ -47,6 +62,10 @@ type DexClientStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dexcl,categories={auth,all}
+//+kubebuilder:printcolumn:name="Client ID",type=string,JSONPath=`.spec.clientID`
+//+kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // DexClient is the Schema for the dexclients API
 type DexClient struct {
@@ -0,0 +1,139 @@
+// Copyright Red Hat
+
+package v1alpha1
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var dexclientlog = logf.Log.WithName("dexclient-resource")
+
+func (r *DexClient) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-auth-identitatem-io-v1alpha1-dexclient,mutating=true,failurePolicy=fail,sideEffects=None,groups=auth.identitatem.io,resources=dexclients,verbs=create;update,versions=v1alpha1,name=mdexclient.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &DexClient{}
+
+// Default implements webhook.Defaulter. Redirect URIs without a scheme get http:// for the loopback addresses
+// native apps listen on, and https:// otherwise.
+func (r *DexClient) Default() {
+	dexclientlog.V(1).Info("default", "name", r.Name)
+	for i, redirectURI := range r.Spec.RedirectURIs {
+		if strings.Contains(redirectURI, ":/") {
+			continue
+		}
+		// left to the validation when it is not a host followed by a path, such as an urn
+		u, err := url.Parse("http://" + redirectURI)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		if isLoopbackHost(u.Hostname()) {
+			r.Spec.RedirectURIs[i] = "http://" + redirectURI
+		} else {
+			r.Spec.RedirectURIs[i] = "https://" + redirectURI
+		}
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-auth-identitatem-io-v1alpha1-dexclient,mutating=false,failurePolicy=fail,sideEffects=None,groups=auth.identitatem.io,resources=dexclients,verbs=create;update,versions=v1alpha1,name=vdexclient.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &DexClient{}
+
+// ValidateCreate implements webhook.Validator
+func (r *DexClient) ValidateCreate() error {
+	dexclientlog.V(1).Info("validate create", "name", r.Name)
+	return r.validateDexClient()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *DexClient) ValidateUpdate(old runtime.Object) error {
+	dexclientlog.V(1).Info("validate update", "name", r.Name)
+	return r.validateDexClient()
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *DexClient) ValidateDelete() error {
+	return nil
+}
+
+func (r *DexClient) validateDexClient() error {
+	allErrs := ValidateDexClientSpec(&r.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "DexClient"}, r.Name, allErrs)
+}
+
+// ValidateDexClientSpec checks the redirect URIs of the client. Public clients are the native apps of RFC 8252:
+// they are registered without a secret, so they can only redeem codes with PKCE, and their redirect URIs must be
+// loopback http URIs, https URIs, or private-use schemes in reverse domain name notation. The device flow clients
+// are public clients. The trusted peers must be the client ids of other clients.
+func ValidateDexClientSpec(spec *DexClientSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	peers := map[string]bool{}
+	for i, peer := range spec.TrustedPeers {
+		peerPath := fldPath.Child("trustedPeers").Index(i)
+		switch {
+		case peer == "":
+			allErrs = append(allErrs, field.Required(peerPath, "must be a client id"))
+		case peer == spec.ClientID:
+			allErrs = append(allErrs, field.Invalid(peerPath, peer, "a client can't be its own trusted peer"))
+		case peers[peer]:
+			allErrs = append(allErrs, field.Duplicate(peerPath, peer))
+		}
+		peers[peer] = true
+	}
+	if spec.IsPublic() && spec.ClientSecretRef.Name != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("clientSecretRef"), "public clients use PKCE and have no secret"))
+	}
+	for i, redirectURI := range spec.RedirectURIs {
+		uriPath := fldPath.Child("redirectURIs").Index(i)
+		u, err := url.Parse(redirectURI)
+		if err != nil || u.Scheme == "" {
+			allErrs = append(allErrs, field.Invalid(uriPath, redirectURI, "must be an absolute URI"))
+			continue
+		}
+		if u.Fragment != "" {
+			allErrs = append(allErrs, field.Invalid(uriPath, redirectURI, "must not include a fragment"))
+			continue
+		}
+		if !spec.IsPublic() {
+			continue
+		}
+		switch {
+		case u.Scheme == "http":
+			if !isLoopbackHost(u.Hostname()) {
+				allErrs = append(allErrs, field.Invalid(uriPath, redirectURI, "http redirect URIs of public clients must use a loopback address"))
+			}
+		case u.Scheme == "https":
+		case strings.Contains(u.Scheme, "."):
+			// private-use URI scheme, such as com.example.app:/callback
+		default:
+			allErrs = append(allErrs, field.Invalid(uriPath, redirectURI, "custom schemes of public clients must be in reverse domain name notation, such as com.example.app"))
+		}
+	}
+	return allErrs
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,84 @@
+// Copyright Red Hat
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DexQuickstartConnectorSpec is the single connector of a DexQuickstart, an OAuth application registered with the
+// identity provider
+type DexQuickstartConnectorSpec struct {
+	// +kubebuilder:validation:Enum=github;microsoft;oidc
+	Type ConnectorType `json:"type"`
+	// Client id of the OAuth application
+	// +kubebuilder:validation:MinLength=1
+	ClientID string `json:"clientID"`
+	// Secret of the namespace of the DexQuickstart holding the client secret of the OAuth application in its
+	// clientSecret key
+	ClientSecretRef corev1.LocalObjectReference `json:"clientSecretRef"`
+	// Issuer of the identity provider, required by the oidc connector
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+}
+
+// DexQuickstartSpec defines the desired state of DexQuickstart
+type DexQuickstartSpec struct {
+	// The issuer URL of dex. When omitted on OpenShift, the issuer is generated from the cluster ingress domain, as
+	// for a DexServer.
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// The connector users log in with
+	Connector DexQuickstartConnectorSpec `json:"connector"`
+}
+
+const (
+	// Set when the DexServer is created or updated from the DexQuickstart
+	DexQuickstartConditionTypeApplied string = "Applied"
+)
+
+// DexQuickstartStatus defines the observed state of DexQuickstart
+type DexQuickstartStatus struct {
+	// The DexServer expanded from the DexQuickstart
+	// +optional
+	DexServer *RelatedObjectReference `json:"dexServer,omitempty"`
+	// The issuer reported by the DexServer
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// Conditions contains the Applied condition of this DexQuickstart, and the Available and Ready conditions of
+	// the DexServer.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dexqs,categories={auth}
+//+kubebuilder:printcolumn:name="Issuer",type=string,JSONPath=`.status.issuer`
+//+kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DexQuickstart is the Schema for the dexquickstarts API. It is expanded into a DexServer of the same name with the
+// defaults of the operator, for a first evaluation of the operator with a single connector.
+type DexQuickstart struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DexQuickstartSpec   `json:"spec,omitempty"`
+	Status DexQuickstartStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DexQuickstartList contains a list of DexQuickstart
+type DexQuickstartList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DexQuickstart `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DexQuickstart{}, &DexQuickstartList{})
+}
@@ -4,10 +4,11 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// Org holds org-team filters (GitHub), in which teams are optional.
+// Org holds org-team filters (GitHub, Gitea), in which teams are optional.
 type Org struct {
 	// Organization name in github (not slug, full name). Only users in this github
 	// organization can authenticate.
@@ -22,16 +23,97 @@ type Org struct {
 
 // GitHubConfigSpec describes the configuration specific to the GitHub connector
 type GitHubConfigSpec struct {
-	ClientID        string                 `json:"clientID,omitempty"`
+	ClientID string `json:"clientID,omitempty"`
+	// Reference to the secret holding the client ID of the OAuth app in the "clientID" key, instead of clientID. It
+	// can be the secret of clientSecretRef.
+	// +optional
+	ClientIDRef     corev1.SecretReference `json:"clientIDRef,omitempty"`
 	ClientSecretRef corev1.SecretReference `json:"clientSecretRef,omitempty"`
 	RedirectURI     string                 `json:"redirectURI,omitempty"`
 	Org             string                 `json:"org,omitempty"`
 	Orgs            []Org                  `json:"orgs,omitempty"`
-	HostName        string                 `json:"hostName,omitempty"`
-	RootCA          string                 `json:"rootCA,omitempty"`
-	TeamNameField   string                 `json:"teamNameField,omitempty"`
-	LoadAllGroups   bool                   `json:"loadAllGroups,omitempty"`
-	UseLoginAsID    bool                   `json:"useLoginAsID,omitempty"`
+	// Host name of a GitHub Enterprise server, for example github.example.com. Defaults to github.com.
+	// +optional
+	HostName string `json:"hostName,omitempty"`
+	// Reference to the secret holding the CA of the GitHub Enterprise server in the "ca.crt" key. dex trusts the
+	// system CAs when neither rootCARef nor rootCAData is set.
+	// +optional
+	RootCARef corev1.SecretReference `json:"rootCARef,omitempty"`
+	// The CA of the GitHub Enterprise server can also be provided inline as a base64 encoded PEM file.
+	// +optional
+	RootCAData []byte `json:"rootCAData,omitempty"`
+	// How the teams are named in the groups claim, <org>:<team> with the name of the team (default), its slug, or both.
+	// +kubebuilder:validation:Enum=name;slug;both
+	// +optional
+	TeamNameField string `json:"teamNameField,omitempty"`
+	// Return all the orgs and teams of the user in the groups claim, not only those of orgs.
+	// +optional
+	LoadAllGroups bool `json:"loadAllGroups,omitempty"`
+	// Use the login of the user as the user id, rather than the numeric id.
+	// +optional
+	UseLoginAsID bool `json:"useLoginAsID,omitempty"`
+	// Domain of the email returned for the user, e.g. example.com or *.example.com, when the user has a verified
+	// email in it. Defaults to the primary email. Read by the dex releases newer than v2.30.
+	// +optional
+	PreferredEmailDomain string `json:"preferredEmailDomain,omitempty"`
+}
+
+// BitbucketCloudConfigSpec describes the configuration specific to the Bitbucket Cloud connector
+type BitbucketCloudConfigSpec struct {
+	ClientID        string                 `json:"clientID,omitempty"`
+	ClientSecretRef corev1.SecretReference `json:"clientSecretRef,omitempty"`
+	RedirectURI     string                 `json:"redirectURI,omitempty"`
+	// Names of the Bitbucket Cloud teams (workspaces) whose members can authenticate. dex refuses the users that
+	// are members of none of them, and only returns these teams in the groups claim. All the users can
+	// authenticate if this field is omitted.
+	// +optional
+	Teams []string `json:"teams,omitempty"`
+	// Also return the groups of the teams, as <team>/<group>, in the groups claim.
+	// +optional
+	IncludeTeamGroups bool `json:"includeTeamGroups,omitempty"`
+}
+
+// GiteaConfigSpec describes the configuration specific to the Gitea connector
+type GiteaConfigSpec struct {
+	// URL of the Gitea instance, for example https://gitea.example.com
+	BaseURL         string                 `json:"baseURL,omitempty"`
+	ClientID        string                 `json:"clientID,omitempty"`
+	ClientSecretRef corev1.SecretReference `json:"clientSecretRef,omitempty"`
+	RedirectURI     string                 `json:"redirectURI,omitempty"`
+	// Gitea organizations, and optionally their teams, whose members can authenticate. dex refuses the users that
+	// are members of none of them, and returns the orgs and org:team groups in the groups claim. All the users can
+	// authenticate if this field is omitted.
+	// +optional
+	Orgs []Org `json:"orgs,omitempty"`
+	// Return all the orgs and teams of the user in the groups claim, not only those of orgs.
+	// +optional
+	LoadAllGroups bool `json:"loadAllGroups,omitempty"`
+	// Use the login of the user as the user id, rather than the numeric id.
+	// +optional
+	UseLoginAsID bool `json:"useLoginAsID,omitempty"`
+}
+
+// GoogleConfigSpec describes the configuration specific to the Google connector
+type GoogleConfigSpec struct {
+	ClientID        string                 `json:"clientID,omitempty"`
+	ClientSecretRef corev1.SecretReference `json:"clientSecretRef,omitempty"`
+	RedirectURI     string                 `json:"redirectURI,omitempty"`
+	// Google Workspace domains whose users can authenticate, for example example.com. All the Google accounts can
+	// authenticate if this field is omitted.
+	// +optional
+	HostedDomains []string `json:"hostedDomains,omitempty"`
+	// Workspace groups whose members can authenticate. dex refuses the users that are members of none of them, and
+	// only returns these groups in the groups claim. Requires serviceAccountRef and adminEmail.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// Reference to the secret containing the JSON key of the service account dex looks up the Workspace groups of
+	// the users with, in the "service-account.json" key. The service account must be granted domain-wide
+	// delegation of the admin.directory.group.readonly scope. No groups are returned when unset.
+	// +optional
+	ServiceAccountRef corev1.SecretReference `json:"serviceAccountRef,omitempty"`
+	// Email of a Workspace administrator the service account impersonates to look up the groups
+	// +optional
+	AdminEmail string `json:"adminEmail,omitempty"`
 }
 
 // MicrosoftConfigSpec describes the configuration specific to the Microsoft connector
@@ -107,6 +189,10 @@ type GroupSearchSpec struct {
 type LDAPConfigSpec struct {
 	// The host and optional port of the LDAP server. If port isn't supplied, it will be guessed based on the TLS configuration. 389 or 636.
 	Host string `json:"host,omitempty"`
+	// Replicas of the LDAP directory, host and optional port, following host in order of preference. The hosts are
+	// probed before dex is configured, and dex connects to the first reachable host, as it only supports one host.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
 	// Required if LDAP host does not use TLS
 	InsecureNoSSL bool `json:"insecureNoSSL,omitempty"`
 	// Connect to the insecure port then issue a StartTLS command to negotiate a
@@ -155,6 +241,46 @@ type ClaimMappingSpec struct {
 	// If there is list of email, we are supporting only first entry from list.
 	// +optional
 	Email string `json:"email,omitempty"`
+
+	// groups is the claim holding the groups of the user, read with insecureEnableGroups. Defaults to the groups
+	// claim.
+	// +optional
+	Groups string `json:"groups,omitempty"`
+}
+
+// ClaimModificationsSpec modifies the groups dex returns for the users of an OIDC connector
+type ClaimModificationsSpec struct {
+	// Groups built from the values of other claims, added to the groups of the users
+	// +optional
+	NewGroupFromClaims []NewGroupFromClaimsSpec `json:"newGroupFromClaims,omitempty"`
+	// Filter of the groups read from the groups claim
+	// +optional
+	FilterGroupClaims FilterGroupClaimsSpec `json:"filterGroupClaims,omitempty"`
+}
+
+// NewGroupFromClaimsSpec builds a group from the values of claims, e.g. the group "team:web:admin" from the prefix
+// "team", the delimiter ":" and the claims department and role
+type NewGroupFromClaimsSpec struct {
+	// Claims whose values are joined after the prefix. The claims that are missing or are not strings are skipped, and
+	// no group is added when all of them are.
+	// +kubebuilder:validation:MinItems=1
+	Claims []string `json:"claims"`
+	// Delimiter joining the prefix and the values of the claims
+	// +optional
+	Delimiter string `json:"delimiter,omitempty"`
+	// Remove the delimiter from the values of the claims, so that a value can't forge the segments of another group
+	// +optional
+	ClearDelimiter bool `json:"clearDelimiter,omitempty"`
+	// First segment of the group
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// FilterGroupClaimsSpec filters the groups claim
+type FilterGroupClaimsSpec struct {
+	// Regular expression the groups of the groups claim must match, the other groups are dropped
+	// +optional
+	GroupsFilter string `json:"groupsFilter,omitempty"`
 }
 
 // OIDCConfigSpec describes the configuration specific to the OpenID connector
@@ -164,55 +290,1201 @@ type OIDCConfigSpec struct {
 	Issuer          string                 `json:"issuer,omitempty"`
 	RedirectURI     string                 `json:"redirectURI,omitempty"`
 	ClaimMapping    ClaimMappingSpec       `json:"claimMapping,omitempty"`
+	// Scopes requested from the provider. dex always requests the openid scope, and defaults to the profile and email
+	// scopes.
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+	// Accept the users whose email_verified claim is false or missing, for providers that don't verify the emails.
+	// +optional
+	InsecureSkipEmailVerified bool `json:"insecureSkipEmailVerified,omitempty"`
+	// Return the groups of the groups claim, see claimMapping.groups. dex trusts the claim as is, the provider must
+	// only release the groups meant for dex.
+	// +optional
+	InsecureEnableGroups bool `json:"insecureEnableGroups,omitempty"`
+	// Groups built from other claims and filter of the groups claim, read by the dex releases newer than v2.36
+	// +optional
+	ClaimModifications ClaimModificationsSpec `json:"claimModifications,omitempty"`
+}
+
+// OpenShiftConfigSpec describes the configuration specific to the OpenShift connector, logging the users in with the
+// OAuth server of an OpenShift cluster
+type OpenShiftConfigSpec struct {
+	// URL of the API server of the cluster whose OAuth server the users log in with, dex discovers the OAuth server
+	// from it. Defaults to the in-cluster address of the API server of the cluster the DexServer runs on.
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// Name of the OAuthClient dex logs in with. When unset, the operator creates an OAuthClient for the connector,
+	// named dex-<DexServer namespace>-<DexServer name>-<connector id>, with the secret of clientSecretRef and the
+	// redirect URI of dex. Only supported with the default issuer.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+	// Reference to the secret holding the secret of the OAuthClient in the "clientSecret" key
+	ClientSecretRef corev1.SecretReference `json:"clientSecretRef,omitempty"`
+	RedirectURI     string                 `json:"redirectURI,omitempty"`
+	// OpenShift groups whose members can authenticate. dex refuses the users that are members of none of them. All
+	// the users of the cluster can authenticate if this field is omitted.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// Reference to the secret holding the CA of the API and OAuth servers in the "ca.crt" key. Defaults to the CA
+	// bundle of the service account of the dex pods, which trusts the API server and the default ingress certificate
+	// of the cluster.
+	// +optional
+	RootCARef corev1.SecretReference `json:"rootCARef,omitempty"`
+}
+
+// SAMLConfigSpec describes the configuration specific to the SAML 2.0 connector
+type SAMLConfigSpec struct {
+	// URL of the SSO service of the identity provider the users are redirected to, for example
+	// https://adfs.example.com/adfs/ls
+	SSOURL string `json:"ssoURL,omitempty"`
+	// Reference to the secret containing the certificate the identity provider signs its responses with, in the
+	// "ca.crt" key
+	CARef corev1.SecretReference `json:"caRef,omitempty"`
+	// The signing certificate can also be provided inline as a base64 encoded PEM file.
+	CAData []byte `json:"caData,omitempty"`
+	// Issuer of the authentication requests of dex. The responses must be restricted to this audience when it is
+	// set, it is often the redirect URI.
+	// +optional
+	EntityIssuer string `json:"entityIssuer,omitempty"`
+	// Issuer of the responses of the identity provider. The issuer of the responses is not checked when unset.
+	// +optional
+	SSOIssuer   string `json:"ssoIssuer,omitempty"`
+	RedirectURI string `json:"redirectURI,omitempty"`
+	// Attribute of the responses holding the name of the user
+	UsernameAttr string `json:"usernameAttr,omitempty"`
+	// Attribute of the responses holding the email of the user
+	EmailAttr string `json:"emailAttr,omitempty"`
+	// Attribute of the responses holding the groups of the user. No groups are returned when unset.
+	// +optional
+	GroupsAttr string `json:"groupsAttr,omitempty"`
+}
+
+// AuthProxyConfigSpec describes the configuration specific to the authproxy connector. The users authenticate with a
+// reverse proxy in front of the callback of the connector, and dex trusts the headers the proxy sets on the requests
+// to the callback.
+type AuthProxyConfigSpec struct {
+	// Header holding the name of the user. Defaults to X-Remote-User.
+	// +optional
+	UserHeader string `json:"userHeader,omitempty"`
+	// Header holding the email of the user. Only read by the dex releases newer than v2.30.
+	// +optional
+	EmailHeader string `json:"emailHeader,omitempty"`
+	// Header holding the groups of the user, separated by commas. Only read by the dex releases newer than v2.30.
+	// +optional
+	GroupHeader string `json:"groupHeader,omitempty"`
+	// Groups added to the groups of all the users of the connector
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// Service of the proxy in the namespace of the DexServer, required. The requests to the callback of the connector
+	// are routed to the proxy rather than to dex, and a NetworkPolicy only admits the proxy, the ingress controllers
+	// and the operator to dex, so that the headers can't be set by the users. Not supported when dex is exposed by its
+	// Service or by a wildcard route.
+	// +optional
+	ProxyService *AuthProxyServiceSpec `json:"proxyService,omitempty"`
+}
+
+// AuthProxyServiceSpec is the Service the requests to the callback of an authproxy connector are routed to. On
+// OpenShift the routes of dex re-encrypt the requests, the proxy must serve TLS.
+type AuthProxyServiceSpec struct {
+	// Name of the Service
+	Name string `json:"name"`
+	// Port of the Service the proxy listens on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+}
+
+// MockPasswordConfigSpec describes the configuration specific to the mockPassword connector, which logs in the fixed
+// test user of dex with a single username and password
+type MockPasswordConfigSpec struct {
+	// Username of the test user
+	Username string `json:"username,omitempty"`
+	// Reference to the secret holding the password of the test user in the "password" key
+	PasswordRef corev1.SecretReference `json:"passwordRef,omitempty"`
+}
+
+// RawConfigSpec describes a connector of a type, or with settings, the DexServer does not model. Its config is
+// inserted verbatim in the connectors of the dex config.
+type RawConfigSpec struct {
+	// Type of the dex connector, e.g. gitlab or keystone
+	ConnectorType string `json:"connectorType,omitempty"`
+	// Config of the dex connector, as YAML or JSON. Each $(PLACEHOLDER) is replaced with a reference to the
+	// environment variable dex reads the value of the secret of the placeholder from. The config must not hold
+	// credentials, it is stored in a ConfigMap, and settings such as clientSecret or bindPW must be a placeholder.
+	Config string `json:"config,omitempty"`
+	// Secrets of the placeholders of the config
+	// +optional
+	Secrets []RawConnectorSecretSpec `json:"secrets,omitempty"`
+}
+
+// RawConnectorSecretSpec is the secret of a placeholder of the config of a raw connector
+type RawConnectorSecretSpec struct {
+	// Name of the placeholder, $(<placeholder>) in the config
+	// +kubebuilder:validation:Pattern=`^[A-Z][A-Z0-9_]*$`
+	Placeholder string `json:"placeholder"`
+	// Reference to the secret holding the value of the placeholder
+	SecretRef corev1.SecretReference `json:"secretRef"`
+	// Key of the secret holding the value of the placeholder
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
 }
 
 // ConnectorSpec defines the OIDC connector config details
 type ConnectorSpec struct {
+	// Name displayed on the login button of the connector. Defaults to the id of the connector.
+	// Names must be unique among the connectors of a DexServer.
 	Name string `json:"name,omitempty"`
-	// +kubebuilder:validation:Enum=github;ldap;microsoft;oidc
+	// +kubebuilder:validation:Enum=authproxy;bitbucketcloud;gitea;github;google;ldap;microsoft;mockCallback;mockPassword;oidc;openshift;raw;saml
 	Type ConnectorType `json:"type,omitempty"`
 	// Unique Id for the connector
-	Id        string              `json:"id,omitempty"`
-	GitHub    GitHubConfigSpec    `json:"github,omitempty"`
-	LDAP      LDAPConfigSpec      `json:"ldap,omitempty"`
-	Microsoft MicrosoftConfigSpec `json:"microsoft,omitempty"`
-	OIDC      OIDCConfigSpec      `json:"oidc,omitempty"`
+	Id string `json:"id,omitempty"`
+	// Position of the login button of the connector. Connectors are listed by increasing display order, then in
+	// the order of spec.connectors. The icon of the button is chosen by dex from the connector type.
+	// +optional
+	DisplayOrder   int32                    `json:"displayOrder,omitempty"`
+	AuthProxy      AuthProxyConfigSpec      `json:"authproxy,omitempty"`
+	BitbucketCloud BitbucketCloudConfigSpec `json:"bitbucketcloud,omitempty"`
+	GitHub         GitHubConfigSpec         `json:"github,omitempty"`
+	Gitea          GiteaConfigSpec          `json:"gitea,omitempty"`
+	Google         GoogleConfigSpec         `json:"google,omitempty"`
+	LDAP           LDAPConfigSpec           `json:"ldap,omitempty"`
+	Microsoft      MicrosoftConfigSpec      `json:"microsoft,omitempty"`
+	MockPassword   MockPasswordConfigSpec   `json:"mockPassword,omitempty"`
+	OIDC           OIDCConfigSpec           `json:"oidc,omitempty"`
+	OpenShift      OpenShiftConfigSpec      `json:"openshift,omitempty"`
+	Raw            RawConfigSpec            `json:"raw,omitempty"`
+	SAML           SAMLConfigSpec           `json:"saml,omitempty"`
+	// Proxy the requests of the connector to its identity provider go through. Not supported by the authproxy, LDAP,
+	// mock and raw connectors.
+	// +optional
+	Proxy *ConnectorProxySpec `json:"proxy,omitempty"`
+	// Period within which the credential secret of the connector, its client secret or LDAP bind password, must be
+	// rotated. A CredentialRotationDue warning Event is recorded once the credential is older.
+	// +optional
+	RotateAfter *metav1.Duration `json:"rotateAfter,omitempty"`
+}
+
+// ConnectorProxySpec is the proxy of the requests of a connector. dex reads its proxy from its environment for all
+// its requests, so the connectors with a proxy must use the same proxy, and can't share their hosts with the
+// connectors without a proxy.
+type ConnectorProxySpec struct {
+	// URL of the proxy, with the http, https or socks5 scheme. It can't hold credentials, as the environment of dex
+	// is not secret.
+	URL string `json:"url"`
 }
 
 type ConnectorType string
 
 const (
+	// ConnectorTypeAuthProxy enables Dex to identify the end user through the headers set by an authenticating reverse proxy
+	ConnectorTypeAuthProxy ConnectorType = "authproxy"
+
+	// ConnectorTypeBitbucketCloud enables Dex to use the Bitbucket Cloud OAuth2 flow to identify the end user through their Bitbucket account
+	ConnectorTypeBitbucketCloud ConnectorType = "bitbucketcloud"
+
+	// ConnectorTypeGitea enables Dex to use the Gitea OAuth2 flow to identify the end user through their Gitea account
+	ConnectorTypeGitea ConnectorType = "gitea"
+
 	// ConnectorTypeGitHub enables Dex to use the GitHub OAuth2 flow to identify the end user through their GitHub account
 	ConnectorTypeGitHub ConnectorType = "github"
 
+	// ConnectorTypeGoogle enables Dex to use the Google OAuth2 flow to identify the end user through their Google account
+	ConnectorTypeGoogle ConnectorType = "google"
+
 	// ConnectorTypeLDAP enables Dex to allow email/password based authentication, backed by an LDAP directory
 	ConnectorTypeLDAP ConnectorType = "ldap"
 
 	// ConnectorTypeMicrosoft enables Dex to use the Microsoft OAuth2 flow to identify the end user through their Microsoft account
 	ConnectorTypeMicrosoft ConnectorType = "microsoft"
 
+	// ConnectorTypeMockCallback enables Dex to log in a fixed test user without any interaction, for test environments only
+	ConnectorTypeMockCallback ConnectorType = "mockCallback"
+
+	// ConnectorTypeMockPassword enables Dex to log in a fixed test user with a single username and password, for test environments only
+	ConnectorTypeMockPassword ConnectorType = "mockPassword"
+
 	//ConnectorTypeOIDC enables Dex to use OpenID OAuth2 floww to identify the end user
 	ConnectorTypeOIDC ConnectorType = "oidc"
+
+	// ConnectorTypeOpenShift enables Dex to use the OAuth server of an OpenShift cluster to identify the end user through their cluster account
+	ConnectorTypeOpenShift ConnectorType = "openshift"
+
+	// ConnectorTypeRaw enables Dex to use a connector of a type the DexServer does not model, configured verbatim
+	ConnectorTypeRaw ConnectorType = "raw"
+
+	// ConnectorTypeSAML enables Dex to use the SAML 2.0 flow to identify the end user through an enterprise identity provider
+	ConnectorTypeSAML ConnectorType = "saml"
+)
+
+// MTLSSpec describes the certificates used for the mutual TLS gRPC connection to the dex server
+type MTLSSpec struct {
+	// How long the previous CA stays in the ca.crt trust bundle after the CA is rotated, so that certificates
+	// signed by either CA are trusted while gRPC consumers pick up the new credentials. Defaults to 1h.
+	// Set to 0s to drop the previous CA immediately.
+	// +optional
+	CAOverlapWindow *metav1.Duration `json:"caOverlapWindow,omitempty"`
+}
+
+// ServiceSpec describes how the dex web Service is exposed
+type ServiceSpec struct {
+	// Type of the dex web Service. NodePort and LoadBalancer expose dex directly on clusters without an
+	// ingress controller, in which case no Ingress is created. Defaults to ClusterIP.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// Node port to pin the dex web Service to when the type is NodePort or LoadBalancer.
+	// If unset, a port is allocated by Kubernetes.
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
+	// When the issuer is not set and the type is NodePort, derive the issuer from the address of a cluster
+	// node and the node port. The derived issuer is reported in the status.
+	// +optional
+	IssuerFromNodeAddress bool `json:"issuerFromNodeAddress,omitempty"`
+	// Where the TLS connections to the dex web server are terminated, Dex or LoadBalancer. With LoadBalancer, which
+	// requires the LoadBalancer type, the load balancer presents a cloud managed certificate selected through
+	// annotations, and forwards plain HTTP to dex on port 443 of the Service. Defaults to Dex.
+	// +kubebuilder:validation:Enum=Dex;LoadBalancer
+	// +optional
+	TLSTermination TLSTermination `json:"tlsTermination,omitempty"`
+	// Additional annotations of the dex web Service, e.g. the cloud provider annotations selecting the managed
+	// certificate of a LoadBalancer.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// IP families of the dex Services, e.g. [IPv6] on IPv6-only clusters or [IPv4, IPv6] for dual-stack Services.
+	// Defaults to the primary IP family of the cluster.
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	IPFamilies []corev1.IPFamily `json:"ipFamilies,omitempty"`
+	// IP family policy of the dex Services. Defaults to SingleStack, or to RequireDualStack when two ipFamilies are set.
+	// +kubebuilder:validation:Enum=SingleStack;PreferDualStack;RequireDualStack
+	// +optional
+	IPFamilyPolicy *corev1.IPFamilyPolicyType `json:"ipFamilyPolicy,omitempty"`
+	// Cloud provider whose load balancer annotations configure the health checks of dex, AWS, Azure or GCP. The
+	// load balancer of a LoadBalancer Service, or of the Ingress, then checks the /healthz path of dex with the
+	// protocol dex serves instead of a plain TCP or HTTP check. The annotations of spec.service.annotations and
+	// spec.ingress.annotations take precedence.
+	// +kubebuilder:validation:Enum=AWS;Azure;GCP
+	// +optional
+	HealthCheckPreset HealthCheckPreset `json:"healthCheckPreset,omitempty"`
+}
+
+// HealthCheckPreset selects the cloud provider annotations configuring the health checks of the load balancers
+type HealthCheckPreset string
+
+const (
+	// The annotations of the AWS cloud provider and of the AWS Load Balancer Controller
+	HealthCheckPresetAWS HealthCheckPreset = "AWS"
+	// The annotations of the Azure cloud provider and of the Application Gateway Ingress Controller
+	HealthCheckPresetAzure HealthCheckPreset = "Azure"
+	// The annotations of the GKE Ingress
+	HealthCheckPresetGCP HealthCheckPreset = "GCP"
+)
+
+// TLSTermination selects where the TLS connections to the dex web server are terminated
+type TLSTermination string
+
+const (
+	// TLSTerminationDex serves HTTPS from dex, with the certificate of the dex web Secret
+	TLSTerminationDex TLSTermination = "Dex"
+
+	// TLSTerminationLoadBalancer serves HTTPS from the cloud load balancer, dex serves plain HTTP
+	TLSTerminationLoadBalancer TLSTermination = "LoadBalancer"
+)
+
+// ErrorPolicy decides how the dex server is configured when the secret of a connector is missing
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyFailClosed does not configure the dex server until the secrets of all the connectors exist
+	ErrorPolicyFailClosed ErrorPolicy = "FailClosed"
+
+	// ErrorPolicyFailOpen configures the dex server without the connectors whose secret is missing
+	ErrorPolicyFailOpen ErrorPolicy = "FailOpen"
+)
+
+// PortsSpec describes the ports dex listens on in its container. When dex runs in the host network namespace,
+// these are the ports opened on the node.
+type PortsSpec struct {
+	// Port of the dex web server. Defaults to 5556.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	HTTPS int32 `json:"https,omitempty"`
+	// Port of the dex gRPC API. Defaults to 5557.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	GRPC int32 `json:"grpc,omitempty"`
+	// Port of the dex metrics endpoint, when spec.telemetry is enabled. Defaults to 5558.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Telemetry int32 `json:"telemetry,omitempty"`
+	// IP address dex listens on, e.g. an IPv6 address of the pod. Defaults to all the addresses of every IP family.
+	// +optional
+	ListenAddress string `json:"listenAddress,omitempty"`
+}
+
+// TelemetrySpec describes the Prometheus metrics endpoint of dex, exposed by the <DexServer name>-metrics Service
+type TelemetrySpec struct {
+	// Serve the dex metrics on spec.ports.telemetry.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Front the metrics endpoint with a kube-rbac-proxy sidecar serving HTTPS on port 8443, so that scraping
+	// requires a token allowed to get the /metrics non-resource URL. Dex then only serves the metrics on the
+	// loopback address of the pod.
+	// +optional
+	RBACProxy bool `json:"rbacProxy,omitempty"`
+}
+
+// LoggerSpec describes the logs of dex
+type LoggerSpec struct {
+	// Level of the logs of dex. dex has a single log level, the connectors have no level of their own: the logs
+	// of a connector are told apart by its id. Defaults to info.
+	// +kubebuilder:validation:Enum=debug;info;error
+	// +optional
+	Level string `json:"level,omitempty"`
+	// Format of the logs of dex. Defaults to text.
+	// +kubebuilder:validation:Enum=text;json
+	// +optional
+	Format string `json:"format,omitempty"`
+}
+
+// GRPCSpec describes the gRPC API of dex
+type GRPCSpec struct {
+	// Whether the gRPC server reflection is enabled, for example for grpcurl. Disabled by default as it lets the
+	// clients with the mTLS client certificate list the services of the API.
+	// +optional
+	Reflection bool `json:"reflection,omitempty"`
+}
+
+// SecurityProfileType is the kind of a seccomp or AppArmor profile
+type SecurityProfileType string
+
+const (
+	// SecurityProfileTypeRuntimeDefault uses the default profile of the container runtime
+	SecurityProfileTypeRuntimeDefault SecurityProfileType = "RuntimeDefault"
+
+	// SecurityProfileTypeLocalhost uses a profile loaded on the node
+	SecurityProfileTypeLocalhost SecurityProfileType = "Localhost"
+
+	// SecurityProfileTypeUnconfined runs dex without a profile
+	SecurityProfileTypeUnconfined SecurityProfileType = "Unconfined"
+)
+
+// SecurityProfile selects a seccomp or AppArmor profile
+type SecurityProfile struct {
+	// Kind of the profile.
+	// +kubebuilder:validation:Enum=RuntimeDefault;Localhost;Unconfined
+	Type SecurityProfileType `json:"type"`
+	// Name of the profile loaded on the node, required when type is Localhost. For seccomp, this is the path of the
+	// profile relative to the seccomp directory of the kubelet.
+	// +optional
+	LocalhostProfile string `json:"localhostProfile,omitempty"`
+}
+
+// SecurityProfilesSpec describes the seccomp and AppArmor profiles of the dex pod. The profiles are set in the pod
+// security context, and in the equivalent annotations read by clusters older than Kubernetes 1.19 for seccomp and
+// older than 1.30 for AppArmor.
+type SecurityProfilesSpec struct {
+	// Seccomp profile of the dex pod. Defaults to RuntimeDefault.
+	// +optional
+	Seccomp *SecurityProfile `json:"seccomp,omitempty"`
+	// AppArmor profile of the dex container. No profile is set by default, as the pod is rejected on nodes that do
+	// not enable AppArmor.
+	// +optional
+	AppArmor *SecurityProfile `json:"appArmor,omitempty"`
+}
+
+// FilesystemSpec describes the filesystem of the dex containers
+type FilesystemSpec struct {
+	// Mount the root filesystem of the dex containers read-only. Defaults to true.
+	// +optional
+	ReadOnlyRootFilesystem *bool `json:"readOnlyRootFilesystem,omitempty"`
+	// Writable emptyDir volumes of the dex container, replacing the default volume mounted on /tmp. Dex and some of
+	// its connectors write temporary files, keep a volume on /tmp when the root filesystem is read-only.
+	// +optional
+	ScratchVolumes []ScratchVolume `json:"scratchVolumes,omitempty"`
+}
+
+// ScratchVolume is a writable emptyDir volume of the dex container
+type ScratchVolume struct {
+	// Name of the volume, the volume of the pod is named scratch-<name>.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=55
+	Name string `json:"name"`
+	// Absolute path of the volume in the dex container.
+	MountPath string `json:"mountPath"`
+	// Storage medium of the emptyDir, Memory for a tmpfs. Defaults to the storage of the node.
+	// +optional
+	Medium corev1.StorageMedium `json:"medium,omitempty"`
+	// Maximum size of the emptyDir.
+	// +optional
+	SizeLimit *resource.Quantity `json:"sizeLimit,omitempty"`
+}
+
+// ExpirySpec describes the lifetime of the tokens and keys issued by dex. Dex has no per client token lifetime,
+// the policy applies to all the clients of the dex server.
+type ExpirySpec struct {
+	// Lifetime of the ID tokens. Defaults to 24h.
+	// +optional
+	IDTokens *metav1.Duration `json:"idTokens,omitempty"`
+	// Rotation period of the signing keys. Defaults to 6h.
+	// +optional
+	SigningKeys *metav1.Duration `json:"signingKeys,omitempty"`
+	// Lifetime of the authentication requests. Defaults to 24h.
+	// +optional
+	AuthRequests *metav1.Duration `json:"authRequests,omitempty"`
+	// Lifetime of the device codes of the device authorization grant, in which the user must complete the login.
+	// Defaults to 5m.
+	// +optional
+	DeviceRequests *metav1.Duration `json:"deviceRequests,omitempty"`
+	// Offline access policy of the refresh tokens.
+	// +optional
+	RefreshTokens RefreshTokensSpec `json:"refreshTokens,omitempty"`
+}
+
+// LifecycleSpec configures the shutdown of the dex pods. A terminating pod keeps serving during the preStop delay,
+// while the Service, the router and the load balancers stop sending it new requests, then dex is stopped and given
+// the rest of the termination grace period to complete the requests in flight.
+type LifecycleSpec struct {
+	// Delay between the termination of a dex pod and the stop of dex. Defaults to 10s, 0s stops dex right away.
+	// +optional
+	PreStopDelay *metav1.Duration `json:"preStopDelay,omitempty"`
+	// Time given to a terminating dex pod before it is killed, including the preStop delay. Defaults to the preStop
+	// delay and 30s.
+	// +optional
+	TerminationGracePeriod *metav1.Duration `json:"terminationGracePeriod,omitempty"`
+}
+
+// DexServerProfile is a vetted sizing of a dex server: the resources and replicas of dex, and the lifetimes of the
+// objects dex stores for each login
+// +kubebuilder:validation:Enum=small;medium;large
+type DexServerProfile string
+
+const (
+	// A single dex pod, for a few logins per minute
+	DexServerProfileSmall DexServerProfile = "small"
+	// Two dex pods, for up to a few logins per second
+	DexServerProfileMedium DexServerProfile = "medium"
+	// Three dex pods with shorter lived authentication requests, for the login peaks of large organizations
+	DexServerProfileLarge DexServerProfile = "large"
+)
+
+// RefreshTokensSpec describes the offline access policy of the refresh tokens issued by dex
+type RefreshTokensSpec struct {
+	// Refresh tokens not used for this duration are invalidated. Refresh tokens never expire from inactivity when unset.
+	// +optional
+	ValidIfNotUsedFor *metav1.Duration `json:"validIfNotUsedFor,omitempty"`
+	// Refresh tokens are invalidated after this duration, whether they are used or not. Refresh tokens have no
+	// absolute lifetime when unset.
+	// +optional
+	AbsoluteLifetime *metav1.Duration `json:"absoluteLifetime,omitempty"`
+	// Interval during which a rotated refresh token can still be used, to tolerate concurrent refreshes. Defaults to 3s.
+	// +optional
+	ReuseInterval *metav1.Duration `json:"reuseInterval,omitempty"`
+	// Keep the same refresh token when it is used instead of issuing a new one.
+	// +optional
+	DisableRotation bool `json:"disableRotation,omitempty"`
+}
+
+// ConnectorFailoverPolicy is applied to the connectors whose upstream identity provider is unhealthy
+type ConnectorFailoverPolicy string
+
+const (
+	// ConnectorFailoverPolicyReorder lists unhealthy connectors after the healthy ones on the login screen
+	ConnectorFailoverPolicyReorder ConnectorFailoverPolicy = "Reorder"
+
+	// ConnectorFailoverPolicyHide removes unhealthy connectors from the login screen until they are healthy again
+	ConnectorFailoverPolicyHide ConnectorFailoverPolicy = "Hide"
+)
+
+// ConnectorFailoverSpec describes how connectors are presented on the login screen when their upstream identity
+// provider is down
+type ConnectorFailoverSpec struct {
+	// Probe the upstream identity provider of each connector and render the dex config again when its health changes.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Policy applied to the unhealthy connectors. Connectors are never all hidden, and the password connector is
+	// reordered rather than hidden. Defaults to Reorder.
+	// +kubebuilder:validation:Enum=Reorder;Hide
+	// +optional
+	Policy ConnectorFailoverPolicy `json:"policy,omitempty"`
+	// Interval between two health probes of the connectors. Defaults to 1m.
+	// +optional
+	ProbeInterval *metav1.Duration `json:"probeInterval,omitempty"`
+}
+
+// RouteSpec describes how the dex server is exposed through a route
+type RouteSpec struct {
+	// Allow an issuer host outside of the cluster ingress domain. By default the issuer host is rejected when it
+	// is not a subdomain of the ingress domain, as the route would never be admitted by the default router. The
+	// hosts the dex server already serves are kept.
+	// +optional
+	AllowExternalHost bool `json:"allowExternalHost,omitempty"`
+	// Wildcard policy of the route. With Subdomain, the route serves all the hosts of the subdomain of the issuer
+	// host, and is created by the operator instead of being generated from the Ingress. The router must admit
+	// wildcard routes. Defaults to None.
+	// +optional
+	WildcardPolicy RouteWildcardPolicy `json:"wildcardPolicy,omitempty"`
+	// Path of the generated issuer, e.g. /dex to serve dex next to other applications on the same host. The path of
+	// spec.issuer is used when the issuer is set.
+	// +kubebuilder:validation:Pattern=`^(/[A-Za-z0-9._~-]+)+$`
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Limits of the connections and requests of each client IP address, mitigating the brute-force attacks on the
+	// login forms of dex. They are enforced by the OpenShift router, or by the ingress-nginx controller.
+	// +optional
+	RateLimit RouteRateLimitSpec `json:"rateLimit,omitempty"`
+	// Check that the issuer host and the additional hosts resolve before creating the Route or Ingress, so that a
+	// misspelt issuer is reported instead of producing an unreachable route. On OpenShift, the hosts of the cluster
+	// ingress domain are served by the wildcard DNS record of the router and are not looked up.
+	// +optional
+	ValidateDNS bool `json:"validateDNS,omitempty"`
+}
+
+// RouteRateLimitSpec limits the connections and requests of each client IP address at the router
+type RouteRateLimitSpec struct {
+	// Maximum number of concurrent connections of a client IP address.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ConcurrentConnections *int32 `json:"concurrentConnections,omitempty"`
+	// Maximum number of HTTP requests per second of a client IP address. The OpenShift router counts the requests
+	// over 3 seconds.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RequestsPerSecond *int32 `json:"requestsPerSecond,omitempty"`
+}
+
+// RouteWildcardPolicy is the wildcard policy of the route exposing dex
+// +kubebuilder:validation:Enum=None;Subdomain
+type RouteWildcardPolicy string
+
+const (
+	RouteWildcardPolicyNone      RouteWildcardPolicy = "None"
+	RouteWildcardPolicySubdomain RouteWildcardPolicy = "Subdomain"
+)
+
+// IngressSpec describes the Ingress exposing dex when the dex web Service is of type ClusterIP
+type IngressSpec struct {
+	// Class of the Ingress. Defaults to the default IngressClass of the cluster.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+	// Additional annotations of the Ingress, e.g. the annotations of the ingress controller. The annotations set by
+	// the operator can't be overridden.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Publish the issuer host with external-dns, through the external-dns.alpha.kubernetes.io/hostname annotation.
+	// +optional
+	ExternalDNS bool `json:"externalDNS,omitempty"`
+	// Optional certificate and cipher suites of the Ingress.
+	// +optional
+	TLS IngressTLSSpec `json:"tls,omitempty"`
+}
+
+// IngressTLSStrategy is how the certificate of the Ingress is provided
+type IngressTLSStrategy string
+
+const (
+	// The certificate is in a Secret created by the user
+	IngressTLSUserProvided IngressTLSStrategy = "UserProvided"
+	// cert-manager issues the certificate from spec.ingress.tls.issuerRef
+	IngressTLSCertManager IngressTLSStrategy = "CertManager"
+	// The certificate is requested with the kubernetes.io/tls-acme annotation, from the default ACME issuer of the
+	// controller watching the Ingresses, such as the ingress-shim of cert-manager
+	IngressTLSACME IngressTLSStrategy = "ACME"
+)
+
+// IngressTLSSpec describes how the certificate of the Ingress is provided
+type IngressTLSSpec struct {
+	// How the certificate is provided, UserProvided, CertManager or ACME. Defaults to UserProvided.
+	// +kubebuilder:validation:Enum=UserProvided;CertManager;ACME
+	// +optional
+	Strategy IngressTLSStrategy `json:"strategy,omitempty"`
+	// Secret of the DexServer namespace holding the certificate. With UserProvided, it defaults to
+	// spec.ingressCertificateRef, and the Ingress uses the default certificate of the ingress controller when neither
+	// is set. With CertManager and ACME, the issued certificate is stored in it, it defaults to
+	// <DexServer name>-ingress-tls.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// cert-manager Issuer or ClusterIssuer of the certificate, required with CertManager.
+	// +optional
+	IssuerRef CertManagerIssuerReference `json:"issuerRef,omitempty"`
+	// OpenSSL names of the cipher suites the ingress controller negotiates with the clients, for the TLS 1.2
+	// connections terminated at the edge, for example ECDHE-RSA-AES128-GCM-SHA256. Set with the
+	// nginx.ingress.kubernetes.io/ssl-ciphers annotation. Not supported on OpenShift, where the cipher suites of the
+	// router are set by the tlsSecurityProfile of the IngressController.
+	// +optional
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+}
+
+// CertManagerIssuerReference references a cert-manager Issuer or ClusterIssuer
+type CertManagerIssuerReference struct {
+	// Name of the issuer
+	Name string `json:"name"`
+	// Issuer, in the DexServer namespace, or ClusterIssuer. Defaults to Issuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// OAuth2Spec describes the oauth2 configuration of dex
+type OAuth2Spec struct {
+	// Id of the connector used for the password grant, for example an LDAP connector used by CLI tools.
+	// The password grant is not enabled when unset.
+	// +optional
+	PasswordConnector string `json:"passwordConnector,omitempty"`
+	// Skip the screen asking users to approve the scopes requested by a client. Defaults to true.
+	// +optional
+	SkipApprovalScreen *bool `json:"skipApprovalScreen,omitempty"`
+	// Show the login screen even when a single connector is configured, instead of redirecting to it.
+	// +optional
+	AlwaysShowLoginScreen bool `json:"alwaysShowLoginScreen,omitempty"`
+	// Grant types enabled on dex. All the grant types supported by dex are enabled when unset, including the
+	// device authorization grant of the CLI tools. The password grant is added when passwordConnector is set.
+	// +optional
+	GrantTypes []GrantType `json:"grantTypes,omitempty"`
+	// Static client of the password grant, for CI systems logging in with a service account of the password
+	// connector.
+	// +optional
+	PasswordClient PasswordClientSpec `json:"passwordClient,omitempty"`
+}
+
+// PasswordClientSpec defines the DexClient created for the password grant of the password connector
+type PasswordClientSpec struct {
+	// Create the DexClient of the password grant and publish its secret, issuer and token endpoint in the
+	// <DexServer name>-password-client Secret. Requires passwordConnector.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Client id of the DexClient. Defaults to <DexServer name>-password-client.
+	// +kubebuilder:validation:MinLength=4
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+}
+
+// GrantType is an OAuth2 grant type supported by dex
+// +kubebuilder:validation:Enum=authorization_code;refresh_token;implicit;password;urn:ietf:params:oauth:grant-type:device_code
+type GrantType string
+
+const (
+	GrantTypeAuthorizationCode GrantType = "authorization_code"
+	GrantTypeRefreshToken      GrantType = "refresh_token"
+	GrantTypeImplicit          GrantType = "implicit"
+	GrantTypePassword          GrantType = "password"
+	GrantTypeDeviceCode        GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// FrontendTheme is one of the themes of the login page bundled with dex
+type FrontendTheme string
+
+const (
+	FrontendThemeLight FrontendTheme = "light"
+	FrontendThemeDark  FrontendTheme = "dark"
+)
+
+// FrontendSpec describes the branding of the dex login page
+type FrontendSpec struct {
+	// Name displayed on the login page in place of "dex".
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// URL of the logo displayed on the login page.
+	// +optional
+	LogoURL string `json:"logoURL,omitempty"`
+	// Theme of the login page. Defaults to the light theme.
+	// +kubebuilder:validation:Enum=light;dark
+	// +optional
+	Theme FrontendTheme `json:"theme,omitempty"`
+}
+
+// WebSpec describes custom content of the dex login page
+type WebSpec struct {
+	// ConfigMap in the DexServer namespace holding login page templates, for example translated templates. Its keys
+	// ending with .html replace the dex templates of the same name, the other keys are served under /static/.
+	// The dex server is restarted when the content of the ConfigMap changes.
+	// +optional
+	TemplatesConfigMapRef *corev1.LocalObjectReference `json:"templatesConfigMapRef,omitempty"`
+	// Minimum version of TLS negotiated by the dex web and gRPC listeners, 1.2 or 1.3. dex always refuses the
+	// versions older than 1.2, and picks the cipher suites of TLS 1.2 from a fixed list of ECDHE suites with AES-GCM
+	// or ChaCha20-Poly1305. Only read by the dex releases newer than v2.30.
+	// +kubebuilder:validation:Enum="1.2";"1.3"
+	// +optional
+	TLSMinVersion string `json:"tlsMinVersion,omitempty"`
+}
+
+// TrustDistributionSpec configures the distribution of the issuer trust to the clusters managed by an ACM hub
+type TrustDistributionSpec struct {
+	// Create a ManifestWork delivering the issuer CA bundle to each selected managed cluster.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Labels of the ManagedClusters the trust is distributed to. All the managed clusters are selected when unset.
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// Key of a ConfigMap in the DexServer namespace holding the CA bundle of the issuer certificate. Defaults to the
+	// OpenShift ingress CA, or to the certificate generated by the operator on plain Kubernetes.
+	// +optional
+	CABundleRef *corev1.ConfigMapKeySelector `json:"caBundleRef,omitempty"`
+	// Namespace of the ConfigMap created on the managed clusters. Defaults to openshift-config.
+	// +optional
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// Client ID of the DexClient the API servers of the managed clusters authenticate users with.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+}
+
+// StorageType is the backend dex stores its clients, refresh tokens, offline sessions and signing keys in
+type StorageType string
+
+const (
+	// The dex objects are custom resources of the DexServer namespace
+	StorageTypeKubernetes StorageType = "kubernetes"
+	StorageTypePostgres   StorageType = "postgres"
+	StorageTypeEtcd       StorageType = "etcd"
 )
 
+// StorageSpec selects the storage of dex. Changing it starts dex with the objects of the new storage, see the
+// DexStorageMigration to copy the objects of the current storage first.
+type StorageSpec struct {
+	// Type of the storage. Defaults to kubernetes.
+	// +kubebuilder:validation:Enum=kubernetes;postgres;etcd
+	// +optional
+	Type StorageType `json:"type,omitempty"`
+	// Database of the postgres storage, required by the postgres type
+	// +optional
+	Postgres *PostgresStorageSpec `json:"postgres,omitempty"`
+	// Cluster of the etcd storage, required by the etcd type
+	// +optional
+	Etcd *EtcdStorageSpec `json:"etcd,omitempty"`
+}
+
+// PostgresStorageSpec is the database of the postgres storage of dex
+type PostgresStorageSpec struct {
+	// Host name of the postgres server
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+	// Port of the postgres server. Defaults to 5432.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// +kubebuilder:validation:MinLength=1
+	Database string `json:"database"`
+	// +kubebuilder:validation:MinLength=1
+	User string `json:"user"`
+	// Key of a Secret in the DexServer namespace holding the password of the user
+	PasswordRef corev1.SecretKeySelector `json:"passwordRef"`
+	// SSL mode of the connections to the server. Defaults to verify-full.
+	// +kubebuilder:validation:Enum=disable;require;verify-ca;verify-full
+	// +optional
+	SSLMode string `json:"sslMode,omitempty"`
+}
+
+// EtcdStorageSpec is the cluster of the etcd storage of dex
+type EtcdStorageSpec struct {
+	// URLs of the etcd members
+	// +kubebuilder:validation:MinItems=1
+	Endpoints []string `json:"endpoints"`
+	// Prefix of the keys of dex
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// +optional
+	Username string `json:"username,omitempty"`
+	// Key of a Secret in the DexServer namespace holding the password of the user
+	// +optional
+	PasswordRef *corev1.SecretKeySelector `json:"passwordRef,omitempty"`
+}
+
+// GroupBinding binds a cluster role to a group of the users of a connector
+type GroupBinding struct {
+	// Id of the connector providing the group.
+	Connector string `json:"connector"`
+	// Name of the group in the groups claim of the tokens, for example "org:team" for a github connector.
+	Group string `json:"group"`
+	// Name of the ClusterRole bound to the group.
+	ClusterRole string `json:"clusterRole"`
+}
+
+// GroupBindingsSpec maps the groups of the connectors to cluster roles
+type GroupBindingsSpec struct {
+	// Create the ClusterRoleBindings of the groups. By default, they are only suggested in the
+	// <DexServer name>-group-bindings ConfigMap.
+	// +optional
+	Create bool `json:"create,omitempty"`
+	// Prefix added to the groups by the API server, set by its --oidc-groups-prefix flag.
+	// +optional
+	GroupsPrefix string `json:"groupsPrefix,omitempty"`
+	// Cluster roles bound to the groups.
+	// +optional
+	Bindings []GroupBinding `json:"bindings,omitempty"`
+}
+
+// TeamSyncTarget is the kind of objects the members of the GitHub teams are written to
+type TeamSyncTarget string
+
+const (
+	// TeamSyncTargetConfigMap writes the members of the teams in the <DexServer name>-github-teams ConfigMap
+	TeamSyncTargetConfigMap TeamSyncTarget = "ConfigMap"
+	// TeamSyncTargetGroup creates an OpenShift Group of the members of each team
+	TeamSyncTargetGroup TeamSyncTarget = "Group"
+)
+
+// TeamSyncSpec periodically syncs the members of the GitHub teams of a connector to Kubernetes, so that RBAC can be
+// bound to the teams of the users even when their tokens don't carry the groups claim
+type TeamSyncSpec struct {
+	// Id of the github connector whose org teams are synced. The teams of the orgs of the connector are synced, or
+	// all the teams of an org when the connector doesn't list its teams. The sync is disabled when unset.
+	// +optional
+	Connector string `json:"connector,omitempty"`
+	// Key of a Secret in the DexServer namespace holding a GitHub token allowed to read the members of the teams,
+	// with the read:org scope. Defaults to the token key of the clientSecretRef Secret of the connector, as the
+	// credentials of an OAuth app can't list the members of the teams.
+	// +optional
+	TokenRef *corev1.SecretKeySelector `json:"tokenRef,omitempty"`
+	// Objects the members of the teams are written to. Defaults to ConfigMap, Group is only supported on OpenShift.
+	// +kubebuilder:validation:Enum=ConfigMap;Group
+	// +optional
+	Target TeamSyncTarget `json:"target,omitempty"`
+	// Prefix added to the GitHub logins of the members by the API server, set by its --oidc-username-prefix flag.
+	// The groups are named like the groups claim, with spec.groupBindings.groupsPrefix.
+	// +optional
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+	// Interval between the syncs. Defaults to 1h.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// StorageCleanupSpec periodically deletes the expired objects of the kubernetes storage of dex in the DexServer
+// namespace, on top of the garbage collection of dex, so that they don't pile up in etcd on busy clusters
+type StorageCleanupSpec struct {
+	// Delete the expired auth codes, auth requests, device requests and device tokens
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval between the cleanups. Defaults to 1h.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Time the objects are kept once expired. Defaults to 0s, the objects are deleted as soon as they expire.
+	// +optional
+	Retention *metav1.Duration `json:"retention,omitempty"`
+}
+
+// OAuthProxySpec is the DexClient shared by the oauth2-proxy sidecars of the Services labelled with
+// auth.identitatem.io/oauth-proxy=<DexServer namespace>.<DexServer name>. Its secret is published in the
+// <DexServer name>-oauth-proxy Secret of the namespaces of the Services.
+type OAuthProxySpec struct {
+	// Create the DexClient and publish its secret, a cookie secret and the issuer
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Client id of the DexClient. Defaults to <DexServer name>-oauth-proxy.
+	// +kubebuilder:validation:MinLength=4
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+	// Go template of the redirect URI of a labelled Service, with the .Service, .Namespace and .Domain fields,
+	// .Domain being the domain of the issuer host. Defaults to
+	// https://{{ .Service }}-{{ .Namespace }}.{{ .Domain }}/oauth2/callback, the callback of oauth2-proxy behind
+	// the default Route host of the Service on OpenShift.
+	// +optional
+	RedirectURITemplate string `json:"redirectURITemplate,omitempty"`
+}
+
+// VerticalAutoscalingSpec is the VerticalPodAutoscaler of the dex Deployment, collecting right-sizing
+// recommendations for the dex container. Requires the vertical pod autoscaler to be installed on the cluster.
+type VerticalAutoscalingSpec struct {
+	// Create the VerticalPodAutoscaler
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Off only publishes the recommendations in the status of the VerticalPodAutoscaler, Auto applies them to the
+	// dex pods by evicting them. Defaults to Off.
+	// +kubebuilder:validation:Enum=Off;Auto
+	// +optional
+	UpdateMode VerticalAutoscalingUpdateMode `json:"updateMode,omitempty"`
+	// Lower bound of the resources recommended for the dex container.
+	// +optional
+	MinAllowed corev1.ResourceList `json:"minAllowed,omitempty"`
+	// Upper bound of the resources recommended for the dex container.
+	// +optional
+	MaxAllowed corev1.ResourceList `json:"maxAllowed,omitempty"`
+}
+
+// VerticalAutoscalingUpdateMode is the update mode of the VerticalPodAutoscaler of dex
+type VerticalAutoscalingUpdateMode string
+
+const (
+	VerticalAutoscalingUpdateModeOff  VerticalAutoscalingUpdateMode = "Off"
+	VerticalAutoscalingUpdateModeAuto VerticalAutoscalingUpdateMode = "Auto"
+)
+
+// ConsoleLinkSpec is the ConsoleLink of the OpenShift web console pointing to the issuer of dex, so that the users
+// find the SSO endpoint from the console
+type ConsoleLinkSpec struct {
+	// Create the ConsoleLink. Only supported on OpenShift.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Text of the link. Defaults to "<DexServer name> SSO".
+	// +optional
+	Text string `json:"text,omitempty"`
+	// Menu of the console the link is shown in. Defaults to ApplicationMenu.
+	// +kubebuilder:validation:Enum=ApplicationMenu;HelpMenu;UserMenu
+	// +optional
+	Location ConsoleLinkLocation `json:"location,omitempty"`
+	// Section of the application menu the link is listed in. Defaults to "Single Sign-On".
+	// +optional
+	Section string `json:"section,omitempty"`
+	// URL of the icon of the link in the application menu.
+	// +optional
+	ImageURL string `json:"imageURL,omitempty"`
+}
+
+// ConsoleLinkLocation is a menu of the OpenShift web console
+type ConsoleLinkLocation string
+
+const (
+	ConsoleLinkLocationApplicationMenu ConsoleLinkLocation = "ApplicationMenu"
+	ConsoleLinkLocationHelpMenu        ConsoleLinkLocation = "HelpMenu"
+	ConsoleLinkLocationUserMenu        ConsoleLinkLocation = "UserMenu"
+)
+
+// HandoverSpec references the DexServer replaced by a new DexServer serving the same issuer
+type HandoverSpec struct {
+	// Name of the DexServer being replaced
+	Name string `json:"name"`
+	// Namespace of the DexServer being replaced
+	Namespace string `json:"namespace"`
+}
+
+// HandoverPhase is a step of the handover of an issuer from a DexServer to its replacement
+type HandoverPhase string
+
+const (
+	// HandoverPhaseImportingKeys copies the signing keys of the replaced DexServer before the new dex server starts
+	HandoverPhaseImportingKeys HandoverPhase = "ImportingKeys"
+	// HandoverPhaseWaitingForAvailable waits for the new dex server to be available, the replaced one still serves the issuer
+	HandoverPhaseWaitingForAvailable HandoverPhase = "WaitingForAvailable"
+	// HandoverPhaseSwitchingRoute waits for the replaced DexServer to remove its Ingress, before the new one is created
+	HandoverPhaseSwitchingRoute HandoverPhase = "SwitchingRoute"
+	// HandoverPhaseCompleted is set once the issuer is served by the new dex server
+	HandoverPhaseCompleted HandoverPhase = "Completed"
+)
+
+// DiscoveryCacheSpec configures the copy of the discovery document and signing keys of dex kept in the cluster
+type DiscoveryCacheSpec struct {
+	// Copy the OpenID Connect discovery document and the JWKS of the dex server to the <DexServer name>-discovery
+	// object, for the consumers that can read the cluster but can't reach the issuer.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Kind of the object, Secret or ConfigMap. Defaults to Secret.
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// ExternalIssuerSpec configures a dex server whose issuer is served by a global load balancer, in front of the dex
+// servers of several clusters
+type ExternalIssuerSpec struct {
+	// spec.issuer is the host of the global load balancer rather than the host of the Route or Ingress of this
+	// cluster. The operator probes the issuer through the load balancer and reports it in the
+	// ExternalIssuerReachable condition.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Host of the Route or Ingress of this cluster, the backend of the load balancer. Defaults on OpenShift to the
+	// host generated from the cluster ingress domain.
+	// +optional
+	RouteHost string `json:"routeHost,omitempty"`
+	// Key of a ConfigMap in the DexServer namespace holding the CA bundle of the certificate of the load balancer.
+	// Defaults to the system trust.
+	// +optional
+	CABundleRef *corev1.ConfigMapKeySelector `json:"caBundleRef,omitempty"`
+	// Interval between two probes of the issuer. Defaults to 5m.
+	// +optional
+	ProbeInterval *metav1.Duration `json:"probeInterval,omitempty"`
+}
+
+// SmokeTestSpec configures the Job validating the dex server after each configuration rollout
+type SmokeTestSpec struct {
+	// Run a Job fetching the OpenID Connect discovery document and the JWKS of the dex server once its configuration
+	// is rolled out. The Ready condition is only set once the Job succeeds.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Client ID used to request a token with the client_credentials grant. The token request is skipped when unset,
+	// or when the tag of the dex image is older than v2.35.0.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+	// Key of a Secret in the DexServer namespace holding the secret of the client.
+	// +optional
+	ClientSecretRef *corev1.SecretKeySelector `json:"clientSecretRef,omitempty"`
+}
+
 // DexServerSpec defines the desired state of DexServer
 type DexServerSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
-	// TODO: Issuer references the dex instance web URI. Should this be returned as status?
+	// The issuer URL of dex. When omitted on OpenShift, the issuer is generated from the cluster ingress domain as
+	// https://<name>-<namespace>.<ingress domain>, and reported in the status.
+	// +optional
 	Issuer     string          `json:"issuer,omitempty"`
 	Connectors []ConnectorSpec `json:"connectors,omitempty"`
+	// Allow the mockCallback and mockPassword connectors, which log in fixed test users without authenticating them,
+	// for CI and demo clusters. The DexServers with these connectors are refused, and the connectors rejected,
+	// unless it is set.
+	// +optional
+	AllowInsecureConnectors bool `json:"allowInsecureConnectors,omitempty"`
 	// Optional bring-your-own-certificate. Otherwise, the default certificate is used for dex server Ingress.
 	IngressCertificateRef corev1.LocalObjectReference `json:"ingressCertificateRef,omitempty"`
+	// Optional configuration of the gRPC mutual TLS certificates.
+	// +optional
+	MTLS MTLSSpec `json:"mtls,omitempty"`
+	// Optional configuration of the dex web Service.
+	// +optional
+	Service ServiceSpec `json:"service,omitempty"`
+	// Optional oauth2 configuration of dex.
+	// +optional
+	OAuth2 OAuth2Spec `json:"oauth2,omitempty"`
+	// Optional configuration of the route exposing the dex server.
+	// +optional
+	Route RouteSpec `json:"route,omitempty"`
+	// Optional class, annotations and TLS of the Ingress exposing dex.
+	// +optional
+	Ingress IngressSpec `json:"ingress,omitempty"`
+	// Optional hosts serving dex along with the host of the issuer, for example the previous host of the issuer
+	// during a DNS migration. The Ingress has a rule for each host, which OpenShift exposes as a Route, and the
+	// hosts are added to the certificates of the Ingress and of the dex web server.
+	// +optional
+	AdditionalHosts []string `json:"additionalHosts,omitempty"`
+	// Optional issuer served by a global load balancer, with the Route or Ingress of this cluster on another host.
+	// +optional
+	ExternalIssuer ExternalIssuerSpec `json:"externalIssuer,omitempty"`
+	// Optional branding of the login page.
+	// +optional
+	Frontend FrontendSpec `json:"frontend,omitempty"`
+	// Optional custom templates of the login page, and TLS version of the dex listeners.
+	// +optional
+	Web WebSpec `json:"web,omitempty"`
+	// Optional health driven ordering of the connectors on the login screen.
+	// +optional
+	ConnectorFailover ConnectorFailoverSpec `json:"connectorFailover,omitempty"`
+	// Optional token lifetimes and offline access policy.
+	// +optional
+	Expiry ExpirySpec `json:"expiry,omitempty"`
+	// Optional sizing of the dex server for its login volume, see DexServerProfile. The replicas, resources and expiry
+	// fields take precedence over the settings of the profile.
+	// +optional
+	Profile DexServerProfile `json:"profile,omitempty"`
+	// Number of dex pods. Defaults to 1, or to the replicas of spec.profile.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Compute resources of the dex container. Defaults to none, or to the resources of spec.profile.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Optional shutdown of the dex pods, so that the rolling restarts don't cut off the logins in flight.
+	// +optional
+	Lifecycle LifecycleSpec `json:"lifecycle,omitempty"`
+	// Run dex in the host network namespace of the node, for clusters without a load balancer or ingress controller.
+	// Dex is then reachable on spec.ports of the node it runs on.
+	// +optional
+	HostNetwork bool `json:"hostNetwork,omitempty"`
+	// Optional ports of the dex container.
+	// +optional
+	Ports PortsSpec `json:"ports,omitempty"`
+	// Optional seccomp and AppArmor profiles of the dex pod.
+	// +optional
+	SecurityProfiles SecurityProfilesSpec `json:"securityProfiles,omitempty"`
+	// Optional read-only root filesystem and writable volumes of the dex containers.
+	// +optional
+	Filesystem FilesystemSpec `json:"filesystem,omitempty"`
+	// How a missing connector secret is handled. FailClosed blocks the configuration of the dex server until the
+	// secret exists, FailOpen skips the connector and sets the ConnectorsSkipped condition. Defaults to FailClosed.
+	// +kubebuilder:validation:Enum=FailClosed;FailOpen
+	// +optional
+	ErrorPolicy ErrorPolicy `json:"errorPolicy,omitempty"`
+	// Optional distribution of the issuer CA bundle and OIDC settings to ACM managed clusters.
+	// +optional
+	TrustDistribution TrustDistributionSpec `json:"trustDistribution,omitempty"`
+	// Optional ClusterRoleBindings granting cluster roles to the groups of the connectors.
+	// +optional
+	GroupBindings GroupBindingsSpec `json:"groupBindings,omitempty"`
+	// Optional periodic sync of the members of the GitHub teams of a connector to Groups or a ConfigMap.
+	// +optional
+	TeamSync TeamSyncSpec `json:"teamSync,omitempty"`
+	// Optional link to the issuer of dex in the OpenShift web console.
+	// +optional
+	ConsoleLink ConsoleLinkSpec `json:"consoleLink,omitempty"`
+	// Optional storage of dex, the kubernetes storage of the DexServer namespace by default.
+	// +optional
+	Storage StorageSpec `json:"storage,omitempty"`
+	// Optional periodic deletion of the expired objects of the dex storage.
+	// +optional
+	StorageCleanup StorageCleanupSpec `json:"storageCleanup,omitempty"`
+	// Optional client of the oauth2-proxy sidecars protecting the Services labelled for the DexServer.
+	// +optional
+	OAuthProxy OAuthProxySpec `json:"oauthProxy,omitempty"`
+	// Optional VerticalPodAutoscaler of the dex Deployment.
+	// +optional
+	VerticalAutoscaling VerticalAutoscalingSpec `json:"verticalAutoscaling,omitempty"`
+	// Optional DexServer serving the same issuer that this DexServer replaces. Its signing keys are imported so that
+	// the tokens it issued stay valid, and its Ingress is removed once this dex server is available.
+	// +optional
+	Replaces *HandoverSpec `json:"replaces,omitempty"`
+	// Optional validation of the dex server after each configuration rollout.
+	// +optional
+	SmokeTest SmokeTestSpec `json:"smokeTest,omitempty"`
+	// Optional copy of the discovery document and signing keys of dex in a Secret or ConfigMap, refreshed every 5 minutes.
+	// +optional
+	DiscoveryCache DiscoveryCacheSpec `json:"discoveryCache,omitempty"`
+	// Optional Prometheus metrics endpoint of dex.
+	// +optional
+	Telemetry TelemetrySpec `json:"telemetry,omitempty"`
+	// Optional log level and format of the whole dex server. There is no log level per connector.
+	// +optional
+	Logger LoggerSpec `json:"logger,omitempty"`
+	// Optional settings of the gRPC API of dex.
+	// +optional
+	GRPC GRPCSpec `json:"grpc,omitempty"`
+	// Optional lifetime of the DexServer, counted from its creation, for example for the demo dex servers of workshop
+	// clusters. The DexServer and the objects it owns are deleted once it elapses.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
 }
 
 const (
 	DexServerConditionTypeApplied string = "Applied"
 	DexServerDeploymentAvailable  string = "Available"
+	// Set when connectors are left out of the dex configuration, see spec.errorPolicy
+	DexServerConditionTypeConnectorsSkipped string = "ConnectorsSkipped"
+	// Set while the secret of a connector does not exist, the message names the missing secret
+	DexServerConditionTypeWaitingForSecret string = "WaitingForSecret"
+	// Set when the deployment is available and, with spec.smokeTest, the smoke test of its configuration succeeded
+	DexServerConditionTypeReady string = "Ready"
+	// Whether the etcd of the cluster is encrypted at rest, Unknown when it can't be verified
+	DexServerConditionTypeSecretsEncryptedAtRest string = "SecretsEncryptedAtRest"
+	// Set when the operator is not allowed to create the ClusterRole of dex or its ClusterRoleBinding, the message
+	// names the missing permissions
+	DexServerConditionTypeRBACEscalationDenied string = "RBACEscalationDenied"
+	// Set while the dex pods serve the logins with an outdated or partial configuration, see status.phase
+	DexServerConditionTypeDegraded string = "Degraded"
+	// Whether the issuer answers through the global load balancer with a valid certificate, see spec.externalIssuer
+	DexServerConditionTypeExternalIssuerReachable string = "ExternalIssuerReachable"
+)
+
+// Summary of the conditions of a DexServer, for the integrations that can't read the conditions
+// +kubebuilder:validation:Enum=Pending;Progressing;Ready;Degraded;Failed;Deleting
+type DexServerPhase string
+
+const (
+	// The configuration of dex was not applied yet, or it waits for the secret of a connector
+	DexServerPhasePending DexServerPhase = "Pending"
+	// The configuration of dex is applied and the Deployment is rolling it out or running the smoke test
+	DexServerPhaseProgressing DexServerPhase = "Progressing"
+	// The dex pods serve the logins with the current configuration
+	DexServerPhaseReady DexServerPhase = "Ready"
+	// The dex pods serve the logins, but the Degraded condition is True
+	DexServerPhaseDegraded DexServerPhase = "Degraded"
+	// No dex pod serves the logins and the configuration could not be applied
+	DexServerPhaseFailed DexServerPhase = "Failed"
+	// The DexServer is being deleted
+	DexServerPhaseDeleting DexServerPhase = "Deleting"
 )
 
 // DexServerStatus defines the observed state of DexServer
 type DexServerStatus struct {
+	// The issuer the dex server is configured with, either spec.issuer, the issuer derived from the node address, or
+	// the issuer generated from the cluster ingress domain
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
 	// +optional
 	State string `json:"state,omitempty"`
 	// +optional
@@ -222,6 +1494,218 @@ type DexServerStatus struct {
 	// Conditions contains the different condition statuses for this DexServer.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Result of the last health probe of each connector, set when spec.connectorFailover is enabled
+	// +optional
+	ConnectorHealth []ConnectorHealthStatus `json:"connectorHealth,omitempty"`
+	// Hosts of the LDAP connectors with replicas, see spec.connectors[].ldap.hosts
+	// +optional
+	LDAPHosts []LDAPHostsStatus `json:"ldapHosts,omitempty"`
+	// Connectors left out of the dex configuration, with the reason they could not be rendered
+	// +optional
+	RejectedConnectors []RejectedConnectorStatus `json:"rejectedConnectors,omitempty"`
+	// Managed clusters the issuer trust is distributed to, see spec.trustDistribution
+	// +optional
+	TrustDistributedClusters []string `json:"trustDistributedClusters,omitempty"`
+	// Progress of the handover of the issuer from the DexServer in spec.replaces
+	// +optional
+	Handover *HandoverStatus `json:"handover,omitempty"`
+	// Result of the smoke test Job of the current configuration, see spec.smokeTest
+	// +optional
+	SmokeTest *SmokeTestStatus `json:"smokeTest,omitempty"`
+	// Result of the last probe of the issuer through the global load balancer, see spec.externalIssuer
+	// +optional
+	ExternalIssuer *ExternalIssuerStatus `json:"externalIssuer,omitempty"`
+	// Expiry of the certificates used by the dex server, reported on each reconcile
+	// +optional
+	CredentialExpiry []CredentialExpiryStatus `json:"credentialExpiry,omitempty"`
+	// Last rotation of the credential secrets of the connectors with a rotateAfter, reported on each reconcile
+	// +optional
+	CredentialRotation []CredentialRotationStatus `json:"credentialRotation,omitempty"`
+	// Countdown to the deletion of the DexServer, set when spec.ttl is
+	// +optional
+	TTL *TTLStatus `json:"ttl,omitempty"`
+	// Result of the last sync of the GitHub teams, see spec.teamSync
+	// +optional
+	TeamSync *TeamSyncStatus `json:"teamSync,omitempty"`
+	// Number of the sessions stored by dex in the DexServer namespace, counted periodically
+	// +optional
+	Sessions *SessionsStatus `json:"sessions,omitempty"`
+	// Result of the last cleanup of the dex storage, see spec.storageCleanup
+	// +optional
+	StorageCleanup *StorageCleanupStatus `json:"storageCleanup,omitempty"`
+	// Image of the dex pods serving the logins, set once the rollout of the Deployment is complete
+	// +optional
+	DeployedImage string `json:"deployedImage,omitempty"`
+	// Hash of the dex configuration the pods serving the logins run with, set once the rollout of the Deployment is
+	// complete. It changes with the ConfigMap of the dex configuration.
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+	// Time the last rollout of a new image or configuration was found complete
+	// +optional
+	LastRolloutTime *metav1.Time `json:"lastRolloutTime,omitempty"`
+	// Summary of the Applied, Available, Ready and Degraded conditions, set on each status update
+	// +optional
+	Phase DexServerPhase `json:"phase,omitempty"`
+}
+
+// TeamSyncStatus is the result of the last sync of the GitHub teams of a connector
+type TeamSyncStatus struct {
+	// Time of the last sync, the teams are synced again once spec.teamSync.interval elapses
+	LastSyncTime metav1.Time `json:"lastSyncTime"`
+	// Generation of the DexServer the teams were last synced for, a change of the spec syncs them again
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// Groups written by the last successful sync
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// Error of the last sync, the groups of the previous successful sync are kept
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SessionsStatus is the number of the sessions stored by dex, the objects of its kubernetes storage in the DexServer
+// namespace. The DexServers of a namespace share the storage and report the same counts.
+type SessionsStatus struct {
+	// Time of the last count, the sessions are counted again once 15 minutes elapse
+	LastCountTime metav1.Time `json:"lastCountTime"`
+	// Number of the refresh tokens, the RefreshToken objects
+	RefreshTokens int64 `json:"refreshTokens"`
+	// Number of the users and connectors with offline sessions, the OfflineSessions objects
+	OfflineSessions int64 `json:"offlineSessions"`
+}
+
+// StorageCleanupStatus is the result of the last cleanup of the expired objects of the dex storage
+type StorageCleanupStatus struct {
+	// Time of the last cleanup, the storage is cleaned up again once spec.storageCleanup.interval elapses
+	LastCleanupTime metav1.Time `json:"lastCleanupTime"`
+	// Number of the expired objects deleted by the last cleanup
+	Deleted int64 `json:"deleted"`
+	// Error of the last cleanup, the objects it could not list or delete are deleted by the next cleanup
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ExternalIssuerStatus is the result of the last probe of an issuer served by a global load balancer
+type ExternalIssuerStatus struct {
+	// Time of the last probe
+	LastProbeTime metav1.Time `json:"lastProbeTime"`
+	// Time the certificate served by the load balancer expires, the earliest expiry of its chain
+	// +optional
+	CertificateNotAfter *metav1.Time `json:"certificateNotAfter,omitempty"`
+}
+
+// TTLStatus is the countdown to the deletion of a DexServer with a spec.ttl
+type TTLStatus struct {
+	// Time the DexServer is deleted
+	ExpiresAt metav1.Time `json:"expiresAt"`
+	// Time left before the DexServer is deleted, as of the last reconcile
+	Remaining string `json:"remaining"`
+}
+
+// CredentialExpiryStatus is the expiry of a certificate used by the dex server
+type CredentialExpiryStatus struct {
+	// Name of the certificate: web, grpc-ca, grpc-server, grpc-client, or connector/<id> for the root CA of a connector
+	Name string `json:"name"`
+	// Secret holding the certificate, in the DexServer namespace. Empty for the rootCAData of a connector.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// Time the certificate expires. The earliest expiry is reported for a bundle of certificates.
+	NotAfter metav1.Time `json:"notAfter"`
+	// Whether the certificate is renewed before it expires, by the operator or the cluster
+	Renewed bool `json:"renewed"`
+}
+
+// CredentialRotationStatus is the last rotation of the credential secret of a connector
+type CredentialRotationStatus struct {
+	// Id of the connector
+	ConnectorId string `json:"connectorId"`
+	// Secret holding the credential, as <namespace>/<name>
+	SecretName string `json:"secretName"`
+	// Time the credential last changed, as observed by the operator or set in the auth.identitatem.io/rotatedAt
+	// annotation of the secret
+	RotatedAt metav1.Time `json:"rotatedAt"`
+	// Time the credential must be rotated by, rotatedAt and the rotateAfter of the connector
+	RotateBy metav1.Time `json:"rotateBy"`
+}
+
+// SmokeTestResult is the outcome of a smoke test Job
+type SmokeTestResult string
+
+const (
+	SmokeTestRunning   SmokeTestResult = "Running"
+	SmokeTestSucceeded SmokeTestResult = "Succeeded"
+	SmokeTestFailed    SmokeTestResult = "Failed"
+)
+
+// SmokeTestStatus is the result of the smoke test Job of the current configuration
+type SmokeTestStatus struct {
+	// Name of the Job, in the DexServer namespace. Its logs are kept until the Job is deleted.
+	JobName string `json:"jobName"`
+	// Outcome of the Job
+	Result SmokeTestResult `json:"result"`
+	// Details about a failure
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// HandoverStatus is the progress of the handover of the issuer from a replaced DexServer
+type HandoverStatus struct {
+	// Current step of the handover
+	Phase HandoverPhase `json:"phase"`
+	// Details about the current step
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time the handover entered the current step
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// RejectedConnectorStatus describes a connector that is left out of the dex configuration
+type RejectedConnectorStatus struct {
+	// Id of the connector
+	Id string `json:"id"`
+	// Type of the connector
+	// +optional
+	Type ConnectorType `json:"type,omitempty"`
+	// Why the connector could not be rendered
+	Reason string `json:"reason"`
+}
+
+// ConnectorHealthStatus is the result of the last health probe of the upstream identity provider of a connector
+type ConnectorHealthStatus struct {
+	// Id of the connector
+	Id string `json:"id"`
+	// Whether the upstream identity provider was reachable
+	Healthy bool `json:"healthy"`
+	// Error returned by the probe of an unhealthy connector
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time of the last probe
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// LDAPHostsStatus is the result of the last probe of the hosts of an LDAP connector with replicas
+type LDAPHostsStatus struct {
+	// Id of the connector
+	Id string `json:"id"`
+	// Host dex connects to, the first reachable host
+	// +optional
+	Host string `json:"host,omitempty"`
+	// Hosts that were not reachable
+	// +optional
+	Unreachable []LDAPUnreachableHost `json:"unreachable,omitempty"`
+	// Time of the last probe
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// LDAPUnreachableHost is a host of an LDAP connector that was not reachable
+type LDAPUnreachableHost struct {
+	// Host and port of the LDAP server
+	Host string `json:"host"`
+	// Error returned by the probe of the host
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 type RelatedObjectReference struct {
@@ -235,6 +1719,12 @@ type RelatedObjectReference struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dexsrv,categories={auth,all}
+//+kubebuilder:printcolumn:name="Issuer",type=string,JSONPath=`.status.issuer`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // DexServer is the Schema for the dexservers API
 type DexServer struct {
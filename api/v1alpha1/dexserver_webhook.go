@@ -0,0 +1,491 @@
+// Copyright Red Hat
+
+package v1alpha1
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var dexserverlog = logf.Log.WithName("dexserver-resource")
+
+func (r *DexServer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-auth-identitatem-io-v1alpha1-dexserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=auth.identitatem.io,resources=dexservers,verbs=create;update,versions=v1alpha1,name=vdexserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &DexServer{}
+
+// ValidateCreate implements webhook.Validator
+func (r *DexServer) ValidateCreate() error {
+	dexserverlog.V(1).Info("validate create", "name", r.Name)
+	return r.validateDexServer()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *DexServer) ValidateUpdate(old runtime.Object) error {
+	dexserverlog.V(1).Info("validate update", "name", r.Name)
+	return r.validateDexServer()
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *DexServer) ValidateDelete() error {
+	return nil
+}
+
+func (r *DexServer) validateDexServer() error {
+	allErrs := ValidateConnectorIds(&r.Spec, field.NewPath("spec", "connectors"))
+	for i := range r.Spec.Connectors {
+		allErrs = append(allErrs, ValidateConnectorFilters(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateSAMLConnector(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateGitHubConnector(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateGoogleConnector(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateOIDCConnector(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateOpenShiftConnector(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateAuthProxyConnector(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateMockConnector(&r.Spec, &r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+		allErrs = append(allErrs, ValidateRawConnector(&r.Spec.Connectors[i], field.NewPath("spec", "connectors").Index(i))...)
+	}
+	allErrs = append(allErrs, ValidateRoute(&r.Spec, field.NewPath("spec", "route"))...)
+	allErrs = append(allErrs, ValidateOAuth2(&r.Spec, field.NewPath("spec", "oauth2"))...)
+	allErrs = append(allErrs, ValidateOAuthProxy(&r.Spec, field.NewPath("spec", "oauthProxy"))...)
+	allErrs = append(allErrs, ValidateStorage(&r.Spec, field.NewPath("spec", "storage"))...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "DexServer"}, r.Name, allErrs)
+}
+
+// ValidateConnectorIds checks the ids and the names of the connectors are unique. dex refuses a config with duplicate
+// ids, and duplicate names can't be told apart on the login page. The name of a connector defaults to its id.
+func ValidateConnectorIds(spec *DexServerSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	ids := map[string]bool{}
+	names := map[string]bool{}
+	for i, connector := range spec.Connectors {
+		name := connector.Name
+		if name == "" {
+			name = connector.Id
+		}
+		switch {
+		case ids[connector.Id]:
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i).Child("id"), connector.Id))
+		case names[name]:
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i).Child("name"), name))
+		}
+		ids[connector.Id] = true
+		names[name] = true
+	}
+	return allErrs
+}
+
+// ValidateConnectorFilters checks the filters restricting the users of the Bitbucket Cloud and Gitea connectors. dex
+// does not apply a filter set on a connector of another type, and would let every user of the identity provider
+// authenticate, so such filters are refused rather than left to the RBAC of the groups.
+func ValidateConnectorFilters(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	bitbucketPath := fldPath.Child("bitbucketcloud")
+	if connector.Type != ConnectorTypeBitbucketCloud && len(connector.BitbucketCloud.Teams) > 0 {
+		allErrs = append(allErrs, field.Forbidden(bitbucketPath.Child("teams"), "only applies to the bitbucketcloud connectors"))
+	}
+	allErrs = append(allErrs, validateFilterNames(connector.BitbucketCloud.Teams, bitbucketPath.Child("teams"))...)
+
+	giteaPath := fldPath.Child("gitea")
+	if connector.Type != ConnectorTypeGitea && len(connector.Gitea.Orgs) > 0 {
+		allErrs = append(allErrs, field.Forbidden(giteaPath.Child("orgs"), "only applies to the gitea connectors"))
+	}
+	if connector.Type == ConnectorTypeGitea {
+		if connector.Gitea.BaseURL == "" {
+			allErrs = append(allErrs, field.Required(giteaPath.Child("baseURL"), "the URL of the Gitea instance is required"))
+		} else if u, err := url.Parse(connector.Gitea.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(giteaPath.Child("baseURL"), connector.Gitea.BaseURL, "must be an http or https URL"))
+		}
+	}
+	orgs := map[string]bool{}
+	for i, org := range connector.Gitea.Orgs {
+		orgPath := giteaPath.Child("orgs").Index(i)
+		switch {
+		case org.Name == "":
+			allErrs = append(allErrs, field.Required(orgPath.Child("name"), "names can't be empty"))
+		case orgs[org.Name]:
+			allErrs = append(allErrs, field.Duplicate(orgPath.Child("name"), org.Name))
+		}
+		orgs[org.Name] = true
+		allErrs = append(allErrs, validateFilterNames(org.Teams, orgPath.Child("teams"))...)
+	}
+	return allErrs
+}
+
+// ValidateSAMLConnector checks the settings dex requires to start with a SAML connector: the SSO URL, the username
+// and email attributes, and the certificate the signatures of the responses are verified with.
+func ValidateSAMLConnector(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeSAML {
+		return allErrs
+	}
+	samlPath := fldPath.Child("saml")
+	if connector.SAML.SSOURL == "" {
+		allErrs = append(allErrs, field.Required(samlPath.Child("ssoURL"), "the SSO URL of the identity provider is required"))
+	} else if u, err := url.Parse(connector.SAML.SSOURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(samlPath.Child("ssoURL"), connector.SAML.SSOURL, "must be an http or https URL"))
+	}
+	if connector.SAML.UsernameAttr == "" {
+		allErrs = append(allErrs, field.Required(samlPath.Child("usernameAttr"), "the username attribute is required"))
+	}
+	if connector.SAML.EmailAttr == "" {
+		allErrs = append(allErrs, field.Required(samlPath.Child("emailAttr"), "the email attribute is required"))
+	}
+	switch {
+	case connector.SAML.CARef.Name == "" && len(connector.SAML.CAData) == 0:
+		allErrs = append(allErrs, field.Required(samlPath.Child("caRef"), "the signing certificate of the identity provider is required"))
+	case connector.SAML.CARef.Name != "" && len(connector.SAML.CAData) > 0:
+		allErrs = append(allErrs, field.Forbidden(samlPath.Child("caData"), "only one of caRef and caData can be set"))
+	}
+	return allErrs
+}
+
+// ValidateGitHubConnector checks the client ID and the GitHub Enterprise server of the GitHub connectors. dex builds
+// the URLs of the server from its host name.
+func ValidateGitHubConnector(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeGitHub {
+		return allErrs
+	}
+	gitHubPath := fldPath.Child("github")
+	if connector.GitHub.ClientID != "" && connector.GitHub.ClientIDRef.Name != "" {
+		allErrs = append(allErrs, field.Forbidden(gitHubPath.Child("clientIDRef"), "only one of clientID and clientIDRef can be set"))
+	}
+	if strings.Contains(connector.GitHub.HostName, "/") {
+		allErrs = append(allErrs, field.Invalid(gitHubPath.Child("hostName"), connector.GitHub.HostName, "must be a host name, without scheme or path"))
+	}
+	if connector.GitHub.RootCARef.Name != "" && len(connector.GitHub.RootCAData) > 0 {
+		allErrs = append(allErrs, field.Forbidden(gitHubPath.Child("rootCAData"), "only one of rootCARef and rootCAData can be set"))
+	}
+	if connector.GitHub.HostName == "" && (connector.GitHub.RootCARef.Name != "" || len(connector.GitHub.RootCAData) > 0) {
+		allErrs = append(allErrs, field.Required(gitHubPath.Child("hostName"), "the root CA is only used for a GitHub Enterprise server"))
+	}
+	return allErrs
+}
+
+// ValidateGoogleConnector checks the Workspace group lookup of the Google connectors. dex impersonates the admin email
+// with the service account to look up the groups, and would refuse every user of a group filter it can't look up.
+func ValidateGoogleConnector(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeGoogle {
+		return allErrs
+	}
+	googlePath := fldPath.Child("google")
+	hasServiceAccount := connector.Google.ServiceAccountRef.Name != ""
+	switch {
+	case hasServiceAccount && connector.Google.AdminEmail == "":
+		allErrs = append(allErrs, field.Required(googlePath.Child("adminEmail"), "the service account impersonates a Workspace administrator"))
+	case !hasServiceAccount && (connector.Google.AdminEmail != "" || len(connector.Google.Groups) > 0):
+		allErrs = append(allErrs, field.Required(googlePath.Child("serviceAccountRef"), "the groups are looked up with a service account"))
+	}
+	allErrs = append(allErrs, validateFilterNames(connector.Google.Groups, googlePath.Child("groups"))...)
+	return allErrs
+}
+
+// ValidateOIDCConnector checks the claim modifications of the OIDC connectors. dex refuses to start with a groups
+// filter that is not a regular expression.
+func ValidateOIDCConnector(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeOIDC {
+		return allErrs
+	}
+	modificationsPath := fldPath.Child("oidc", "claimModifications")
+	for i, newGroup := range connector.OIDC.ClaimModifications.NewGroupFromClaims {
+		if len(newGroup.Claims) == 0 {
+			allErrs = append(allErrs, field.Required(modificationsPath.Child("newGroupFromClaims").Index(i).Child("claims"), "the claims of the group are required"))
+		}
+	}
+	if groupsFilter := connector.OIDC.ClaimModifications.FilterGroupClaims.GroupsFilter; groupsFilter != "" {
+		filterPath := modificationsPath.Child("filterGroupClaims", "groupsFilter")
+		if _, err := regexp.Compile(groupsFilter); err != nil {
+			allErrs = append(allErrs, field.Invalid(filterPath, groupsFilter, "must be a regular expression: "+err.Error()))
+		}
+		if !connector.OIDC.InsecureEnableGroups {
+			allErrs = append(allErrs, field.Forbidden(filterPath, "the groups claim is only read with insecureEnableGroups"))
+		}
+	}
+	return allErrs
+}
+
+// ValidateOpenShiftConnector checks the OAuthClient of the OpenShift connectors. The operator only creates the
+// OAuthClient in the cluster it runs on, the OAuthClient of another cluster must be named.
+func ValidateOpenShiftConnector(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeOpenShift {
+		return allErrs
+	}
+	openShiftPath := fldPath.Child("openshift")
+	if connector.OpenShift.ClientSecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(openShiftPath.Child("clientSecretRef", "name"), "the secret of the OAuthClient is required"))
+	}
+	if connector.OpenShift.Issuer != "" && connector.OpenShift.ClientID == "" {
+		allErrs = append(allErrs, field.Required(openShiftPath.Child("clientID"), "the OAuthClient of another cluster is not created by the operator"))
+	}
+	allErrs = append(allErrs, validateFilterNames(connector.OpenShift.Groups, openShiftPath.Child("groups"))...)
+	return allErrs
+}
+
+// ValidateAuthProxyConnector checks the headers and the proxy Service of the authproxy connectors
+func ValidateAuthProxyConnector(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeAuthProxy {
+		return allErrs
+	}
+	authProxyPath := fldPath.Child("authproxy")
+	headers := []struct {
+		name  string
+		value string
+	}{
+		{"userHeader", connector.AuthProxy.UserHeader},
+		{"emailHeader", connector.AuthProxy.EmailHeader},
+		{"groupHeader", connector.AuthProxy.GroupHeader},
+	}
+	for _, header := range headers {
+		if header.value == "" {
+			continue
+		}
+		for _, msg := range validation.IsHTTPHeaderName(header.value) {
+			allErrs = append(allErrs, field.Invalid(authProxyPath.Child(header.name), header.value, msg))
+		}
+	}
+	allErrs = append(allErrs, validateFilterNames(connector.AuthProxy.Groups, authProxyPath.Child("groups"))...)
+	// anyone reaching the callback directly could set the headers
+	if service := connector.AuthProxy.ProxyService; service == nil {
+		allErrs = append(allErrs, field.Required(authProxyPath.Child("proxyService"), "the callback must only be reachable through the proxy"))
+	} else {
+		if service.Name == "" {
+			allErrs = append(allErrs, field.Required(authProxyPath.Child("proxyService", "name"), "the name of the Service of the proxy is required"))
+		}
+		if service.Port <= 0 || service.Port > 65535 {
+			allErrs = append(allErrs, field.Invalid(authProxyPath.Child("proxyService", "port"), service.Port, "must be a port number"))
+		}
+	}
+	return allErrs
+}
+
+// ValidateMockConnector checks the mock connectors are allowed by spec.allowInsecureConnectors. They log in their test
+// user without authenticating it.
+func ValidateMockConnector(spec *DexServerSpec, connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeMockCallback && connector.Type != ConnectorTypeMockPassword {
+		return allErrs
+	}
+	if !spec.AllowInsecureConnectors {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("type"), "the mock connectors log in without authenticating, spec.allowInsecureConnectors is required"))
+	}
+	if connector.Type == ConnectorTypeMockPassword {
+		mockPasswordPath := fldPath.Child("mockPassword")
+		if connector.MockPassword.Username == "" {
+			allErrs = append(allErrs, field.Required(mockPasswordPath.Child("username"), "the username of the test user is required"))
+		}
+		if connector.MockPassword.PasswordRef.Name == "" {
+			allErrs = append(allErrs, field.Required(mockPasswordPath.Child("passwordRef", "name"), "the secret of the password is required"))
+		}
+	}
+	return allErrs
+}
+
+// RawConfigPlaceholder matches the $(PLACEHOLDER) of the secrets in the config of the raw connectors
+var RawConfigPlaceholder = regexp.MustCompile(`\$\(([A-Z][A-Z0-9_]*)\)`)
+
+// rawCredentialKeys are the keys, in lower case, of the settings of the dex connectors holding credentials. The config
+// of a raw connector is stored in a ConfigMap, these settings must be placeholders.
+var rawCredentialKeys = map[string]bool{
+	"clientsecret":  true,
+	"bindpw":        true,
+	"password":      true,
+	"adminpassword": true,
+	"secret":        true,
+	"token":         true,
+	"accesstoken":   true,
+	"apitoken":      true,
+	"privatekey":    true,
+}
+
+// findRawCredentials lists the paths of the credential settings of a raw config that are not a single placeholder
+func findRawCredentials(value interface{}, fldPath *field.Path) []*field.Path {
+	paths := []*field.Path{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if rawCredentialKeys[strings.ToLower(key)] {
+				if s, ok := child.(string); !ok || !isRawConfigPlaceholder(s) {
+					paths = append(paths, fldPath.Child(key))
+				}
+				continue
+			}
+			paths = append(paths, findRawCredentials(child, fldPath.Child(key))...)
+		}
+	case []interface{}:
+		for i, child := range v {
+			paths = append(paths, findRawCredentials(child, fldPath.Index(i))...)
+		}
+	}
+	return paths
+}
+
+func isRawConfigPlaceholder(s string) bool {
+	match := RawConfigPlaceholder.FindStringIndex(s)
+	return match != nil && match[0] == 0 && match[1] == len(s)
+}
+
+// ValidateRawConnector checks the config of the raw connectors is a YAML or JSON object, that each of its
+// placeholders has a secret, and that its credentials are placeholders
+func ValidateRawConnector(connector *ConnectorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if connector.Type != ConnectorTypeRaw {
+		return allErrs
+	}
+	rawPath := fldPath.Child("raw")
+	if connector.Raw.ConnectorType == "" {
+		allErrs = append(allErrs, field.Required(rawPath.Child("connectorType"), "the type of the dex connector is required"))
+	}
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(connector.Raw.Config), &config); err != nil {
+		allErrs = append(allErrs, field.Invalid(rawPath.Child("config"), connector.Raw.Config, "must be a YAML or JSON object: "+err.Error()))
+	}
+	// the paths are sorted, the keys of the config are iterated in random order
+	credentials := findRawCredentials(config, rawPath.Child("config"))
+	sort.Slice(credentials, func(i, j int) bool { return credentials[i].String() < credentials[j].String() })
+	for _, credentialPath := range credentials {
+		allErrs = append(allErrs, field.Forbidden(credentialPath, "the config is stored in a ConfigMap, credentials must be a $(PLACEHOLDER) of a secret"))
+	}
+	placeholders := map[string]bool{}
+	for i, secret := range connector.Raw.Secrets {
+		secretPath := rawPath.Child("secrets").Index(i)
+		if placeholders[secret.Placeholder] {
+			allErrs = append(allErrs, field.Duplicate(secretPath.Child("placeholder"), secret.Placeholder))
+		}
+		placeholders[secret.Placeholder] = true
+		if secret.SecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(secretPath.Child("secretRef", "name"), "the secret of the placeholder is required"))
+		}
+		if secret.Key == "" {
+			allErrs = append(allErrs, field.Required(secretPath.Child("key"), "the key of the secret is required"))
+		}
+	}
+	for _, match := range RawConfigPlaceholder.FindAllStringSubmatch(connector.Raw.Config, -1) {
+		if !placeholders[match[1]] {
+			allErrs = append(allErrs, field.Invalid(rawPath.Child("config"), match[0], "the placeholder has no secret"))
+		}
+	}
+	return allErrs
+}
+
+// ValidateRoute checks spec.route against the issuer and the exposure of dex. The path of the issuer is the path of
+// the route, and a wildcard route is the only route of dex.
+func ValidateRoute(spec *DexServerSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spec.Route.Path != "" && spec.Issuer != "" {
+		if u, err := url.Parse(spec.Issuer); err == nil && strings.TrimSuffix(u.Path, "/") != spec.Route.Path {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), spec.Route.Path, "must be the path of spec.issuer"))
+		}
+	}
+	if spec.Route.WildcardPolicy == RouteWildcardPolicySubdomain {
+		if len(spec.AdditionalHosts) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("wildcardPolicy"), "a Subdomain route can't serve spec.additionalHosts"))
+		}
+		if spec.Service.Type == corev1.ServiceTypeNodePort || spec.Service.Type == corev1.ServiceTypeLoadBalancer {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("wildcardPolicy"), "dex is exposed by its Service rather than a route"))
+		}
+	}
+	return allErrs
+}
+
+// ValidateOAuth2 checks the password grant of spec.oauth2. Among the connector types of the operator, only the ldap
+// connectors can serve the password grant, and the password client requires the password connector.
+func ValidateOAuth2(spec *DexServerSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if id := spec.OAuth2.PasswordConnector; id != "" {
+		for _, connector := range spec.Connectors {
+			if connector.Id == id && connector.Type != ConnectorTypeLDAP {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("passwordConnector"), id, "must be the id of an ldap connector"))
+			}
+		}
+	}
+	if spec.OAuth2.PasswordClient.Enabled && spec.OAuth2.PasswordConnector == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("passwordConnector"), "the password client requires the password connector"))
+	}
+	return allErrs
+}
+
+// ValidateOAuthProxy checks the redirect URI template of spec.oauthProxy parses
+func ValidateOAuthProxy(spec *DexServerSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if text := spec.OAuthProxy.RedirectURITemplate; text != "" {
+		if _, err := template.New("redirectURI").Parse(text); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("redirectURITemplate"), text, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// ValidateStorage checks spec.storage has the settings of its type, and that the features reading the kubernetes
+// storage are only enabled with it
+func ValidateStorage(spec *DexServerSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := ValidateStorageSpec(&spec.Storage, fldPath)
+	if spec.Storage.Type == "" || spec.Storage.Type == StorageTypeKubernetes {
+		return allErrs
+	}
+	if spec.StorageCleanup.Enabled {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "storageCleanup", "enabled"), "the cleanup requires the kubernetes storage"))
+	}
+	return allErrs
+}
+
+// ValidateStorageSpec checks a storage has the settings of its type, and only them
+func ValidateStorageSpec(storage *StorageSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if storage.Type == StorageTypePostgres && storage.Postgres == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("postgres"), "required by the postgres storage"))
+	}
+	if storage.Type != StorageTypePostgres && storage.Postgres != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("postgres"), "only allowed with the postgres storage"))
+	}
+	if storage.Type == StorageTypeEtcd && storage.Etcd == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("etcd"), "required by the etcd storage"))
+	}
+	if storage.Type != StorageTypeEtcd && storage.Etcd != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("etcd"), "only allowed with the etcd storage"))
+	}
+	return allErrs
+}
+
+// Check the names of a filter are set and unique
+func validateFilterNames(names []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	seen := map[string]bool{}
+	for i, name := range names {
+		switch {
+		case name == "":
+			allErrs = append(allErrs, field.Required(fldPath.Index(i), "names can't be empty"))
+		case seen[name]:
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), name))
+		}
+		seen[name] = true
+	}
+	return allErrs
+}
@@ -0,0 +1,87 @@
+// Copyright Red Hat
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DexServerTemplateSpec is the template of the DexServers of a DexServerSet
+type DexServerTemplateSpec struct {
+	// Labels added to the DexServers
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Spec of the DexServers. The ttl is ignored, the DexServers live as long as their namespace is selected.
+	Spec DexServerSpec `json:"spec"`
+}
+
+// DexServerSetSpec defines the desired state of DexServerSet
+type DexServerSetSpec struct {
+	// Selector of the namespaces a DexServer is created in, e.g. the namespaces of the preview environments of pull
+	// requests
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	// Go template of the issuer of each DexServer, with the .Name of the DexServerSet and the .Namespace of the
+	// DexServer, e.g. https://dex-{{ .Namespace }}.apps.example.com. Overrides template.spec.issuer. When both are
+	// omitted on OpenShift, the issuer is generated from the cluster ingress domain and the namespace.
+	// +optional
+	IssuerTemplate string `json:"issuerTemplate,omitempty"`
+	// Template of the DexServers created in the selected namespaces
+	Template DexServerTemplateSpec `json:"template"`
+}
+
+const (
+	// Set when the DexServers are created or updated in all the selected namespaces
+	DexServerSetConditionTypeApplied string = "Applied"
+)
+
+// DexServerSetMemberStatus is the status of a DexServer of a DexServerSet
+type DexServerSetMemberStatus struct {
+	// Namespace of the DexServer
+	Namespace string `json:"namespace"`
+	// The issuer reported by the DexServer
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// Whether the Ready condition of the DexServer is True
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+}
+
+// DexServerSetStatus defines the observed state of DexServerSet
+type DexServerSetStatus struct {
+	// The DexServers of the selected namespaces, sorted by namespace
+	// +optional
+	DexServers []DexServerSetMemberStatus `json:"dexServers,omitempty"`
+	// Conditions contains the Applied condition of this DexServerSet
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=dexsrvset,categories={auth}
+//+kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DexServerSet is the Schema for the dexserversets API. It stamps out a DexServer from spec.template in each
+// namespace of spec.namespaceSelector, for short-lived environments like the previews of pull requests, and deletes
+// it once the namespace is no longer selected.
+type DexServerSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DexServerSetSpec   `json:"spec,omitempty"`
+	Status DexServerSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DexServerSetList contains a list of DexServerSet
+type DexServerSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DexServerSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DexServerSet{}, &DexServerSetList{})
+}
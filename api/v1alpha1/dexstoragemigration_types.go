@@ -0,0 +1,86 @@
+// Copyright Red Hat
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DexStorageMigrationSpec defines the desired state of DexStorageMigration
+type DexStorageMigrationSpec struct {
+	// Name of the DexServer of the namespace whose storage is migrated
+	// +kubebuilder:validation:MinLength=1
+	DexServerName string `json:"dexServerName"`
+	// Storage the objects are copied to, set as spec.storage of the DexServer once they are
+	Target StorageSpec `json:"target"`
+}
+
+// DexStorageMigrationPhase is the progress of a DexStorageMigration
+type DexStorageMigrationPhase string
+
+const (
+	// The dex pods are stopped so that the objects don't change while they are copied
+	DexStorageMigrationPhaseStopping DexStorageMigrationPhase = "Stopping"
+	// The Job copies the objects to the target storage
+	DexStorageMigrationPhaseRunning DexStorageMigrationPhase = "Running"
+	// The objects are copied and the DexServer uses the target storage
+	DexStorageMigrationPhaseSucceeded DexStorageMigrationPhase = "Succeeded"
+	// The objects could not be copied, the DexServer still uses the source storage
+	DexStorageMigrationPhaseFailed DexStorageMigrationPhase = "Failed"
+)
+
+const (
+	// Set once the migration is over, True when it succeeded
+	DexStorageMigrationConditionTypeComplete string = "Complete"
+)
+
+// DexStorageMigrationStatus defines the observed state of DexStorageMigration
+type DexStorageMigrationStatus struct {
+	// +optional
+	Phase DexStorageMigrationPhase `json:"phase,omitempty"`
+	// Storage of the DexServer when the migration started, the objects are copied from
+	// +optional
+	Source *StorageSpec `json:"source,omitempty"`
+	// Name of the Job copying the objects
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Conditions contains the Complete condition of this DexStorageMigration
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dexsm,categories={auth}
+//+kubebuilder:printcolumn:name="DexServer",type=string,JSONPath=`.spec.dexServerName`
+//+kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.target.type`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DexStorageMigration is the Schema for the dexstoragemigrations API. It copies the clients, refresh tokens, offline
+// sessions, passwords, connectors and signing keys of a DexServer from its storage to another one with a Job, then
+// switches the DexServer to the other storage. Dex is stopped while the objects are copied.
+type DexStorageMigration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DexStorageMigrationSpec   `json:"spec,omitempty"`
+	Status DexStorageMigrationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DexStorageMigrationList contains a list of DexStorageMigration
+type DexStorageMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DexStorageMigration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DexStorageMigration{}, &DexStorageMigrationList{})
+}
@@ -22,158 +22,127 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ClaimMappingSpec) DeepCopyInto(out *ClaimMappingSpec) {
+func (in *AuthProxyConfigSpec) DeepCopyInto(out *AuthProxyConfigSpec) {
 	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProxyService != nil {
+		in, out := &in.ProxyService, &out.ProxyService
+		*out = new(AuthProxyServiceSpec)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClaimMappingSpec.
-func (in *ClaimMappingSpec) DeepCopy() *ClaimMappingSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthProxyConfigSpec.
+func (in *AuthProxyConfigSpec) DeepCopy() *AuthProxyConfigSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ClaimMappingSpec)
+	out := new(AuthProxyConfigSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ConnectorSpec) DeepCopyInto(out *ConnectorSpec) {
+func (in *AuthProxyServiceSpec) DeepCopyInto(out *AuthProxyServiceSpec) {
 	*out = *in
-	in.GitHub.DeepCopyInto(&out.GitHub)
-	in.LDAP.DeepCopyInto(&out.LDAP)
-	in.Microsoft.DeepCopyInto(&out.Microsoft)
-	out.OIDC = in.OIDC
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectorSpec.
-func (in *ConnectorSpec) DeepCopy() *ConnectorSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthProxyServiceSpec.
+func (in *AuthProxyServiceSpec) DeepCopy() *AuthProxyServiceSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ConnectorSpec)
+	out := new(AuthProxyServiceSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexClient) DeepCopyInto(out *DexClient) {
+func (in *BitbucketCloudConfigSpec) DeepCopyInto(out *BitbucketCloudConfigSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.Teams != nil {
+		in, out := &in.Teams, &out.Teams
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClient.
-func (in *DexClient) DeepCopy() *DexClient {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitbucketCloudConfigSpec.
+func (in *BitbucketCloudConfigSpec) DeepCopy() *BitbucketCloudConfigSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DexClient)
+	out := new(BitbucketCloudConfigSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DexClient) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexClientList) DeepCopyInto(out *DexClientList) {
+func (in *CertManagerIssuerReference) DeepCopyInto(out *CertManagerIssuerReference) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]DexClient, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClientList.
-func (in *DexClientList) DeepCopy() *DexClientList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerReference.
+func (in *CertManagerIssuerReference) DeepCopy() *CertManagerIssuerReference {
 	if in == nil {
 		return nil
 	}
-	out := new(DexClientList)
+	out := new(CertManagerIssuerReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DexClientList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexClientSpec) DeepCopyInto(out *DexClientSpec) {
+func (in *ClaimMappingSpec) DeepCopyInto(out *ClaimMappingSpec) {
 	*out = *in
-	out.ClientSecretRef = in.ClientSecretRef
-	if in.RedirectURIs != nil {
-		in, out := &in.RedirectURIs, &out.RedirectURIs
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.TrustedPeers != nil {
-		in, out := &in.TrustedPeers, &out.TrustedPeers
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClientSpec.
-func (in *DexClientSpec) DeepCopy() *DexClientSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClaimMappingSpec.
+func (in *ClaimMappingSpec) DeepCopy() *ClaimMappingSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DexClientSpec)
+	out := new(ClaimMappingSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexClientStatus) DeepCopyInto(out *DexClientStatus) {
+func (in *ClaimModificationsSpec) DeepCopyInto(out *ClaimModificationsSpec) {
 	*out = *in
-	if in.RelatedObjects != nil {
-		in, out := &in.RelatedObjects, &out.RelatedObjects
-		*out = make([]RelatedObjectReference, len(*in))
-		copy(*out, *in)
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+	if in.NewGroupFromClaims != nil {
+		in, out := &in.NewGroupFromClaims, &out.NewGroupFromClaims
+		*out = make([]NewGroupFromClaimsSpec, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	out.FilterGroupClaims = in.FilterGroupClaims
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClientStatus.
-func (in *DexClientStatus) DeepCopy() *DexClientStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClaimModificationsSpec.
+func (in *ClaimModificationsSpec) DeepCopy() *ClaimModificationsSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DexClientStatus)
+	out := new(ClaimModificationsSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexServer) DeepCopyInto(out *DexServer) {
+func (in *ClusterDexServer) DeepCopyInto(out *ClusterDexServer) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -181,18 +150,18 @@ func (in *DexServer) DeepCopyInto(out *DexServer) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServer.
-func (in *DexServer) DeepCopy() *DexServer {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDexServer.
+func (in *ClusterDexServer) DeepCopy() *ClusterDexServer {
 	if in == nil {
 		return nil
 	}
-	out := new(DexServer)
+	out := new(ClusterDexServer)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DexServer) DeepCopyObject() runtime.Object {
+func (in *ClusterDexServer) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -200,31 +169,31 @@ func (in *DexServer) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexServerList) DeepCopyInto(out *DexServerList) {
+func (in *ClusterDexServerList) DeepCopyInto(out *ClusterDexServerList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]DexServer, len(*in))
+		*out = make([]ClusterDexServer, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerList.
-func (in *DexServerList) DeepCopy() *DexServerList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDexServerList.
+func (in *ClusterDexServerList) DeepCopy() *ClusterDexServerList {
 	if in == nil {
 		return nil
 	}
-	out := new(DexServerList)
+	out := new(ClusterDexServerList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DexServerList) DeepCopyObject() runtime.Object {
+func (in *ClusterDexServerList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -232,35 +201,29 @@ func (in *DexServerList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexServerSpec) DeepCopyInto(out *DexServerSpec) {
+func (in *ClusterDexServerSpec) DeepCopyInto(out *ClusterDexServerSpec) {
 	*out = *in
-	if in.Connectors != nil {
-		in, out := &in.Connectors, &out.Connectors
-		*out = make([]ConnectorSpec, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	out.IngressCertificateRef = in.IngressCertificateRef
+	in.Namespace.DeepCopyInto(&out.Namespace)
+	in.DexServerSpec.DeepCopyInto(&out.DexServerSpec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerSpec.
-func (in *DexServerSpec) DeepCopy() *DexServerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDexServerSpec.
+func (in *ClusterDexServerSpec) DeepCopy() *ClusterDexServerSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DexServerSpec)
+	out := new(ClusterDexServerSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DexServerStatus) DeepCopyInto(out *DexServerStatus) {
+func (in *ClusterDexServerStatus) DeepCopyInto(out *ClusterDexServerStatus) {
 	*out = *in
-	if in.RelatedObjects != nil {
-		in, out := &in.RelatedObjects, &out.RelatedObjects
-		*out = make([]RelatedObjectReference, len(*in))
-		copy(*out, *in)
+	if in.DexServer != nil {
+		in, out := &in.DexServer, &out.DexServer
+		*out = new(RelatedObjectReference)
+		**out = **in
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -271,182 +234,2166 @@ func (in *DexServerStatus) DeepCopyInto(out *DexServerStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerStatus.
-func (in *DexServerStatus) DeepCopy() *DexServerStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDexServerStatus.
+func (in *ClusterDexServerStatus) DeepCopy() *ClusterDexServerStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DexServerStatus)
+	out := new(ClusterDexServerStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GitHubConfigSpec) DeepCopyInto(out *GitHubConfigSpec) {
+func (in *ConnectorFailoverSpec) DeepCopyInto(out *ConnectorFailoverSpec) {
 	*out = *in
-	out.ClientSecretRef = in.ClientSecretRef
-	if in.Orgs != nil {
-		in, out := &in.Orgs, &out.Orgs
-		*out = make([]Org, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.ProbeInterval != nil {
+		in, out := &in.ProbeInterval, &out.ProbeInterval
+		*out = new(v1.Duration)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubConfigSpec.
-func (in *GitHubConfigSpec) DeepCopy() *GitHubConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectorFailoverSpec.
+func (in *ConnectorFailoverSpec) DeepCopy() *ConnectorFailoverSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(GitHubConfigSpec)
+	out := new(ConnectorFailoverSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GroupSearchSpec) DeepCopyInto(out *GroupSearchSpec) {
+func (in *ConnectorHealthStatus) DeepCopyInto(out *ConnectorHealthStatus) {
 	*out = *in
-	if in.UserMatchers != nil {
-		in, out := &in.UserMatchers, &out.UserMatchers
-		*out = make([]UserMatcher, len(*in))
-		copy(*out, *in)
-	}
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupSearchSpec.
-func (in *GroupSearchSpec) DeepCopy() *GroupSearchSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectorHealthStatus.
+func (in *ConnectorHealthStatus) DeepCopy() *ConnectorHealthStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(GroupSearchSpec)
+	out := new(ConnectorHealthStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LDAPConfigSpec) DeepCopyInto(out *LDAPConfigSpec) {
+func (in *ConnectorProxySpec) DeepCopyInto(out *ConnectorProxySpec) {
 	*out = *in
-	out.RootCARef = in.RootCARef
-	if in.RootCAData != nil {
-		in, out := &in.RootCAData, &out.RootCAData
-		*out = make([]byte, len(*in))
-		copy(*out, *in)
-	}
-	out.BindPWRef = in.BindPWRef
-	out.UserSearch = in.UserSearch
-	in.GroupSearch.DeepCopyInto(&out.GroupSearch)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPConfigSpec.
-func (in *LDAPConfigSpec) DeepCopy() *LDAPConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectorProxySpec.
+func (in *ConnectorProxySpec) DeepCopy() *ConnectorProxySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(LDAPConfigSpec)
+	out := new(ConnectorProxySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MicrosoftConfigSpec) DeepCopyInto(out *MicrosoftConfigSpec) {
+func (in *ConnectorSpec) DeepCopyInto(out *ConnectorSpec) {
 	*out = *in
-	out.ClientSecretRef = in.ClientSecretRef
-	if in.Groups != nil {
-		in, out := &in.Groups, &out.Groups
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	in.AuthProxy.DeepCopyInto(&out.AuthProxy)
+	in.BitbucketCloud.DeepCopyInto(&out.BitbucketCloud)
+	in.GitHub.DeepCopyInto(&out.GitHub)
+	in.Gitea.DeepCopyInto(&out.Gitea)
+	in.Google.DeepCopyInto(&out.Google)
+	in.LDAP.DeepCopyInto(&out.LDAP)
+	in.Microsoft.DeepCopyInto(&out.Microsoft)
+	out.MockPassword = in.MockPassword
+	in.OIDC.DeepCopyInto(&out.OIDC)
+	in.OpenShift.DeepCopyInto(&out.OpenShift)
+	in.Raw.DeepCopyInto(&out.Raw)
+	in.SAML.DeepCopyInto(&out.SAML)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ConnectorProxySpec)
+		**out = **in
+	}
+	if in.RotateAfter != nil {
+		in, out := &in.RotateAfter, &out.RotateAfter
+		*out = new(v1.Duration)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrosoftConfigSpec.
-func (in *MicrosoftConfigSpec) DeepCopy() *MicrosoftConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectorSpec.
+func (in *ConnectorSpec) DeepCopy() *ConnectorSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(MicrosoftConfigSpec)
+	out := new(ConnectorSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OIDCConfigSpec) DeepCopyInto(out *OIDCConfigSpec) {
+func (in *ConsoleLinkSpec) DeepCopyInto(out *ConsoleLinkSpec) {
 	*out = *in
-	out.ClientSecretRef = in.ClientSecretRef
-	out.ClaimMapping = in.ClaimMapping
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCConfigSpec.
-func (in *OIDCConfigSpec) DeepCopy() *OIDCConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsoleLinkSpec.
+func (in *ConsoleLinkSpec) DeepCopy() *ConsoleLinkSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(OIDCConfigSpec)
+	out := new(ConsoleLinkSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Org) DeepCopyInto(out *Org) {
+func (in *CredentialExpiryStatus) DeepCopyInto(out *CredentialExpiryStatus) {
 	*out = *in
-	if in.Teams != nil {
-		in, out := &in.Teams, &out.Teams
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Org.
-func (in *Org) DeepCopy() *Org {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialExpiryStatus.
+func (in *CredentialExpiryStatus) DeepCopy() *CredentialExpiryStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(Org)
+	out := new(CredentialExpiryStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RelatedObjectReference) DeepCopyInto(out *RelatedObjectReference) {
+func (in *CredentialRotationStatus) DeepCopyInto(out *CredentialRotationStatus) {
 	*out = *in
+	in.RotatedAt.DeepCopyInto(&out.RotatedAt)
+	in.RotateBy.DeepCopyInto(&out.RotateBy)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelatedObjectReference.
-func (in *RelatedObjectReference) DeepCopy() *RelatedObjectReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialRotationStatus.
+func (in *CredentialRotationStatus) DeepCopy() *CredentialRotationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RelatedObjectReference)
+	out := new(CredentialRotationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UserMatcher) DeepCopyInto(out *UserMatcher) {
+func (in *DexClient) DeepCopyInto(out *DexClient) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserMatcher.
-func (in *UserMatcher) DeepCopy() *UserMatcher {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClient.
+func (in *DexClient) DeepCopy() *DexClient {
 	if in == nil {
 		return nil
 	}
-	out := new(UserMatcher)
+	out := new(DexClient)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexClient) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UserSearchSpec) DeepCopyInto(out *UserSearchSpec) {
+func (in *DexClientList) DeepCopyInto(out *DexClientList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DexClient, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSearchSpec.
-func (in *UserSearchSpec) DeepCopy() *UserSearchSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClientList.
+func (in *DexClientList) DeepCopy() *DexClientList {
 	if in == nil {
 		return nil
 	}
-	out := new(UserSearchSpec)
+	out := new(DexClientList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexClientList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexClientSpec) DeepCopyInto(out *DexClientSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.RedirectURIs != nil {
+		in, out := &in.RedirectURIs, &out.RedirectURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TrustedPeers != nil {
+		in, out := &in.TrustedPeers, &out.TrustedPeers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClientSpec.
+func (in *DexClientSpec) DeepCopy() *DexClientSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DexClientSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexClientStatus) DeepCopyInto(out *DexClientStatus) {
+	*out = *in
+	if in.RelatedObjects != nil {
+		in, out := &in.RelatedObjects, &out.RelatedObjects
+		*out = make([]RelatedObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexClientStatus.
+func (in *DexClientStatus) DeepCopy() *DexClientStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DexClientStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexQuickstart) DeepCopyInto(out *DexQuickstart) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexQuickstart.
+func (in *DexQuickstart) DeepCopy() *DexQuickstart {
+	if in == nil {
+		return nil
+	}
+	out := new(DexQuickstart)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexQuickstart) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexQuickstartConnectorSpec) DeepCopyInto(out *DexQuickstartConnectorSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexQuickstartConnectorSpec.
+func (in *DexQuickstartConnectorSpec) DeepCopy() *DexQuickstartConnectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DexQuickstartConnectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexQuickstartList) DeepCopyInto(out *DexQuickstartList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DexQuickstart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexQuickstartList.
+func (in *DexQuickstartList) DeepCopy() *DexQuickstartList {
+	if in == nil {
+		return nil
+	}
+	out := new(DexQuickstartList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexQuickstartList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexQuickstartSpec) DeepCopyInto(out *DexQuickstartSpec) {
+	*out = *in
+	out.Connector = in.Connector
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexQuickstartSpec.
+func (in *DexQuickstartSpec) DeepCopy() *DexQuickstartSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DexQuickstartSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexQuickstartStatus) DeepCopyInto(out *DexQuickstartStatus) {
+	*out = *in
+	if in.DexServer != nil {
+		in, out := &in.DexServer, &out.DexServer
+		*out = new(RelatedObjectReference)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexQuickstartStatus.
+func (in *DexQuickstartStatus) DeepCopy() *DexQuickstartStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DexQuickstartStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServer) DeepCopyInto(out *DexServer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServer.
+func (in *DexServer) DeepCopy() *DexServer {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexServer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerList) DeepCopyInto(out *DexServerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DexServer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerList.
+func (in *DexServerList) DeepCopy() *DexServerList {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexServerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerSet) DeepCopyInto(out *DexServerSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerSet.
+func (in *DexServerSet) DeepCopy() *DexServerSet {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexServerSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerSetList) DeepCopyInto(out *DexServerSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DexServerSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerSetList.
+func (in *DexServerSetList) DeepCopy() *DexServerSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexServerSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerSetMemberStatus) DeepCopyInto(out *DexServerSetMemberStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerSetMemberStatus.
+func (in *DexServerSetMemberStatus) DeepCopy() *DexServerSetMemberStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerSetMemberStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerSetSpec) DeepCopyInto(out *DexServerSetSpec) {
+	*out = *in
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerSetSpec.
+func (in *DexServerSetSpec) DeepCopy() *DexServerSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerSetStatus) DeepCopyInto(out *DexServerSetStatus) {
+	*out = *in
+	if in.DexServers != nil {
+		in, out := &in.DexServers, &out.DexServers
+		*out = make([]DexServerSetMemberStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerSetStatus.
+func (in *DexServerSetStatus) DeepCopy() *DexServerSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerSpec) DeepCopyInto(out *DexServerSpec) {
+	*out = *in
+	if in.Connectors != nil {
+		in, out := &in.Connectors, &out.Connectors
+		*out = make([]ConnectorSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.IngressCertificateRef = in.IngressCertificateRef
+	in.MTLS.DeepCopyInto(&out.MTLS)
+	in.Service.DeepCopyInto(&out.Service)
+	in.OAuth2.DeepCopyInto(&out.OAuth2)
+	in.Route.DeepCopyInto(&out.Route)
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	if in.AdditionalHosts != nil {
+		in, out := &in.AdditionalHosts, &out.AdditionalHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.ExternalIssuer.DeepCopyInto(&out.ExternalIssuer)
+	out.Frontend = in.Frontend
+	in.Web.DeepCopyInto(&out.Web)
+	in.ConnectorFailover.DeepCopyInto(&out.ConnectorFailover)
+	in.Expiry.DeepCopyInto(&out.Expiry)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Lifecycle.DeepCopyInto(&out.Lifecycle)
+	out.Ports = in.Ports
+	in.SecurityProfiles.DeepCopyInto(&out.SecurityProfiles)
+	in.Filesystem.DeepCopyInto(&out.Filesystem)
+	in.TrustDistribution.DeepCopyInto(&out.TrustDistribution)
+	in.GroupBindings.DeepCopyInto(&out.GroupBindings)
+	in.TeamSync.DeepCopyInto(&out.TeamSync)
+	out.ConsoleLink = in.ConsoleLink
+	in.Storage.DeepCopyInto(&out.Storage)
+	in.StorageCleanup.DeepCopyInto(&out.StorageCleanup)
+	out.OAuthProxy = in.OAuthProxy
+	in.VerticalAutoscaling.DeepCopyInto(&out.VerticalAutoscaling)
+	if in.Replaces != nil {
+		in, out := &in.Replaces, &out.Replaces
+		*out = new(HandoverSpec)
+		**out = **in
+	}
+	in.SmokeTest.DeepCopyInto(&out.SmokeTest)
+	out.DiscoveryCache = in.DiscoveryCache
+	out.Telemetry = in.Telemetry
+	out.Logger = in.Logger
+	out.GRPC = in.GRPC
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerSpec.
+func (in *DexServerSpec) DeepCopy() *DexServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerStatus) DeepCopyInto(out *DexServerStatus) {
+	*out = *in
+	if in.RelatedObjects != nil {
+		in, out := &in.RelatedObjects, &out.RelatedObjects
+		*out = make([]RelatedObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ConnectorHealth != nil {
+		in, out := &in.ConnectorHealth, &out.ConnectorHealth
+		*out = make([]ConnectorHealthStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LDAPHosts != nil {
+		in, out := &in.LDAPHosts, &out.LDAPHosts
+		*out = make([]LDAPHostsStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RejectedConnectors != nil {
+		in, out := &in.RejectedConnectors, &out.RejectedConnectors
+		*out = make([]RejectedConnectorStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.TrustDistributedClusters != nil {
+		in, out := &in.TrustDistributedClusters, &out.TrustDistributedClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Handover != nil {
+		in, out := &in.Handover, &out.Handover
+		*out = new(HandoverStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SmokeTest != nil {
+		in, out := &in.SmokeTest, &out.SmokeTest
+		*out = new(SmokeTestStatus)
+		**out = **in
+	}
+	if in.ExternalIssuer != nil {
+		in, out := &in.ExternalIssuer, &out.ExternalIssuer
+		*out = new(ExternalIssuerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CredentialExpiry != nil {
+		in, out := &in.CredentialExpiry, &out.CredentialExpiry
+		*out = make([]CredentialExpiryStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CredentialRotation != nil {
+		in, out := &in.CredentialRotation, &out.CredentialRotation
+		*out = make([]CredentialRotationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(TTLStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TeamSync != nil {
+		in, out := &in.TeamSync, &out.TeamSync
+		*out = new(TeamSyncStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sessions != nil {
+		in, out := &in.Sessions, &out.Sessions
+		*out = new(SessionsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StorageCleanup != nil {
+		in, out := &in.StorageCleanup, &out.StorageCleanup
+		*out = new(StorageCleanupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastRolloutTime != nil {
+		in, out := &in.LastRolloutTime, &out.LastRolloutTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerStatus.
+func (in *DexServerStatus) DeepCopy() *DexServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexServerTemplateSpec) DeepCopyInto(out *DexServerTemplateSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexServerTemplateSpec.
+func (in *DexServerTemplateSpec) DeepCopy() *DexServerTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DexServerTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexStorageMigration) DeepCopyInto(out *DexStorageMigration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexStorageMigration.
+func (in *DexStorageMigration) DeepCopy() *DexStorageMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(DexStorageMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexStorageMigration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexStorageMigrationList) DeepCopyInto(out *DexStorageMigrationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DexStorageMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexStorageMigrationList.
+func (in *DexStorageMigrationList) DeepCopy() *DexStorageMigrationList {
+	if in == nil {
+		return nil
+	}
+	out := new(DexStorageMigrationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DexStorageMigrationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexStorageMigrationSpec) DeepCopyInto(out *DexStorageMigrationSpec) {
+	*out = *in
+	in.Target.DeepCopyInto(&out.Target)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexStorageMigrationSpec.
+func (in *DexStorageMigrationSpec) DeepCopy() *DexStorageMigrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DexStorageMigrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexStorageMigrationStatus) DeepCopyInto(out *DexStorageMigrationStatus) {
+	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexStorageMigrationStatus.
+func (in *DexStorageMigrationStatus) DeepCopy() *DexStorageMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DexStorageMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveryCacheSpec) DeepCopyInto(out *DiscoveryCacheSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveryCacheSpec.
+func (in *DiscoveryCacheSpec) DeepCopy() *DiscoveryCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveryCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdStorageSpec) DeepCopyInto(out *EtcdStorageSpec) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PasswordRef != nil {
+		in, out := &in.PasswordRef, &out.PasswordRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdStorageSpec.
+func (in *EtcdStorageSpec) DeepCopy() *EtcdStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExpirySpec) DeepCopyInto(out *ExpirySpec) {
+	*out = *in
+	if in.IDTokens != nil {
+		in, out := &in.IDTokens, &out.IDTokens
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.SigningKeys != nil {
+		in, out := &in.SigningKeys, &out.SigningKeys
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AuthRequests != nil {
+		in, out := &in.AuthRequests, &out.AuthRequests
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DeviceRequests != nil {
+		in, out := &in.DeviceRequests, &out.DeviceRequests
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	in.RefreshTokens.DeepCopyInto(&out.RefreshTokens)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExpirySpec.
+func (in *ExpirySpec) DeepCopy() *ExpirySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExpirySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalIssuerSpec) DeepCopyInto(out *ExternalIssuerSpec) {
+	*out = *in
+	if in.CABundleRef != nil {
+		in, out := &in.CABundleRef, &out.CABundleRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProbeInterval != nil {
+		in, out := &in.ProbeInterval, &out.ProbeInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalIssuerSpec.
+func (in *ExternalIssuerSpec) DeepCopy() *ExternalIssuerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalIssuerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalIssuerStatus) DeepCopyInto(out *ExternalIssuerStatus) {
+	*out = *in
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+	if in.CertificateNotAfter != nil {
+		in, out := &in.CertificateNotAfter, &out.CertificateNotAfter
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalIssuerStatus.
+func (in *ExternalIssuerStatus) DeepCopy() *ExternalIssuerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalIssuerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemSpec) DeepCopyInto(out *FilesystemSpec) {
+	*out = *in
+	if in.ReadOnlyRootFilesystem != nil {
+		in, out := &in.ReadOnlyRootFilesystem, &out.ReadOnlyRootFilesystem
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ScratchVolumes != nil {
+		in, out := &in.ScratchVolumes, &out.ScratchVolumes
+		*out = make([]ScratchVolume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemSpec.
+func (in *FilesystemSpec) DeepCopy() *FilesystemSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilterGroupClaimsSpec) DeepCopyInto(out *FilterGroupClaimsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilterGroupClaimsSpec.
+func (in *FilterGroupClaimsSpec) DeepCopy() *FilterGroupClaimsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FilterGroupClaimsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FrontendSpec) DeepCopyInto(out *FrontendSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrontendSpec.
+func (in *FrontendSpec) DeepCopy() *FrontendSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FrontendSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCSpec) DeepCopyInto(out *GRPCSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCSpec.
+func (in *GRPCSpec) DeepCopy() *GRPCSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubConfigSpec) DeepCopyInto(out *GitHubConfigSpec) {
+	*out = *in
+	out.ClientIDRef = in.ClientIDRef
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.Orgs != nil {
+		in, out := &in.Orgs, &out.Orgs
+		*out = make([]Org, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.RootCARef = in.RootCARef
+	if in.RootCAData != nil {
+		in, out := &in.RootCAData, &out.RootCAData
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubConfigSpec.
+func (in *GitHubConfigSpec) DeepCopy() *GitHubConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaConfigSpec) DeepCopyInto(out *GiteaConfigSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.Orgs != nil {
+		in, out := &in.Orgs, &out.Orgs
+		*out = make([]Org, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaConfigSpec.
+func (in *GiteaConfigSpec) DeepCopy() *GiteaConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GoogleConfigSpec) DeepCopyInto(out *GoogleConfigSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.HostedDomains != nil {
+		in, out := &in.HostedDomains, &out.HostedDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.ServiceAccountRef = in.ServiceAccountRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GoogleConfigSpec.
+func (in *GoogleConfigSpec) DeepCopy() *GoogleConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GoogleConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupBinding) DeepCopyInto(out *GroupBinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupBinding.
+func (in *GroupBinding) DeepCopy() *GroupBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupBindingsSpec) DeepCopyInto(out *GroupBindingsSpec) {
+	*out = *in
+	if in.Bindings != nil {
+		in, out := &in.Bindings, &out.Bindings
+		*out = make([]GroupBinding, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupBindingsSpec.
+func (in *GroupBindingsSpec) DeepCopy() *GroupBindingsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupBindingsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupSearchSpec) DeepCopyInto(out *GroupSearchSpec) {
+	*out = *in
+	if in.UserMatchers != nil {
+		in, out := &in.UserMatchers, &out.UserMatchers
+		*out = make([]UserMatcher, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupSearchSpec.
+func (in *GroupSearchSpec) DeepCopy() *GroupSearchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupSearchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HandoverSpec) DeepCopyInto(out *HandoverSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HandoverSpec.
+func (in *HandoverSpec) DeepCopy() *HandoverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HandoverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HandoverStatus) DeepCopyInto(out *HandoverStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HandoverStatus.
+func (in *HandoverStatus) DeepCopy() *HandoverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HandoverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.TLS.DeepCopyInto(&out.TLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpec.
+func (in *IngressSpec) DeepCopy() *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTLSSpec) DeepCopyInto(out *IngressTLSSpec) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTLSSpec.
+func (in *IngressTLSSpec) DeepCopy() *IngressTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPConfigSpec) DeepCopyInto(out *LDAPConfigSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.RootCARef = in.RootCARef
+	if in.RootCAData != nil {
+		in, out := &in.RootCAData, &out.RootCAData
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	out.BindPWRef = in.BindPWRef
+	out.UserSearch = in.UserSearch
+	in.GroupSearch.DeepCopyInto(&out.GroupSearch)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPConfigSpec.
+func (in *LDAPConfigSpec) DeepCopy() *LDAPConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPHostsStatus) DeepCopyInto(out *LDAPHostsStatus) {
+	*out = *in
+	if in.Unreachable != nil {
+		in, out := &in.Unreachable, &out.Unreachable
+		*out = make([]LDAPUnreachableHost, len(*in))
+		copy(*out, *in)
+	}
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPHostsStatus.
+func (in *LDAPHostsStatus) DeepCopy() *LDAPHostsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPHostsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPUnreachableHost) DeepCopyInto(out *LDAPUnreachableHost) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPUnreachableHost.
+func (in *LDAPUnreachableHost) DeepCopy() *LDAPUnreachableHost {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPUnreachableHost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LifecycleSpec) DeepCopyInto(out *LifecycleSpec) {
+	*out = *in
+	if in.PreStopDelay != nil {
+		in, out := &in.PreStopDelay, &out.PreStopDelay
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TerminationGracePeriod != nil {
+		in, out := &in.TerminationGracePeriod, &out.TerminationGracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LifecycleSpec.
+func (in *LifecycleSpec) DeepCopy() *LifecycleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LifecycleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggerSpec) DeepCopyInto(out *LoggerSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggerSpec.
+func (in *LoggerSpec) DeepCopy() *LoggerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTLSSpec) DeepCopyInto(out *MTLSSpec) {
+	*out = *in
+	if in.CAOverlapWindow != nil {
+		in, out := &in.CAOverlapWindow, &out.CAOverlapWindow
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTLSSpec.
+func (in *MTLSSpec) DeepCopy() *MTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MicrosoftConfigSpec) DeepCopyInto(out *MicrosoftConfigSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrosoftConfigSpec.
+func (in *MicrosoftConfigSpec) DeepCopy() *MicrosoftConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MicrosoftConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MockPasswordConfigSpec) DeepCopyInto(out *MockPasswordConfigSpec) {
+	*out = *in
+	out.PasswordRef = in.PasswordRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MockPasswordConfigSpec.
+func (in *MockPasswordConfigSpec) DeepCopy() *MockPasswordConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MockPasswordConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NewGroupFromClaimsSpec) DeepCopyInto(out *NewGroupFromClaimsSpec) {
+	*out = *in
+	if in.Claims != nil {
+		in, out := &in.Claims, &out.Claims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NewGroupFromClaimsSpec.
+func (in *NewGroupFromClaimsSpec) DeepCopy() *NewGroupFromClaimsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NewGroupFromClaimsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OAuth2Spec) DeepCopyInto(out *OAuth2Spec) {
+	*out = *in
+	if in.SkipApprovalScreen != nil {
+		in, out := &in.SkipApprovalScreen, &out.SkipApprovalScreen
+		*out = new(bool)
+		**out = **in
+	}
+	if in.GrantTypes != nil {
+		in, out := &in.GrantTypes, &out.GrantTypes
+		*out = make([]GrantType, len(*in))
+		copy(*out, *in)
+	}
+	out.PasswordClient = in.PasswordClient
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OAuth2Spec.
+func (in *OAuth2Spec) DeepCopy() *OAuth2Spec {
+	if in == nil {
+		return nil
+	}
+	out := new(OAuth2Spec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OAuthProxySpec) DeepCopyInto(out *OAuthProxySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OAuthProxySpec.
+func (in *OAuthProxySpec) DeepCopy() *OAuthProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OAuthProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCConfigSpec) DeepCopyInto(out *OIDCConfigSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	out.ClaimMapping = in.ClaimMapping
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.ClaimModifications.DeepCopyInto(&out.ClaimModifications)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCConfigSpec.
+func (in *OIDCConfigSpec) DeepCopy() *OIDCConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenShiftConfigSpec) DeepCopyInto(out *OpenShiftConfigSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.RootCARef = in.RootCARef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenShiftConfigSpec.
+func (in *OpenShiftConfigSpec) DeepCopy() *OpenShiftConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenShiftConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Org) DeepCopyInto(out *Org) {
+	*out = *in
+	if in.Teams != nil {
+		in, out := &in.Teams, &out.Teams
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Org.
+func (in *Org) DeepCopy() *Org {
+	if in == nil {
+		return nil
+	}
+	out := new(Org)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PasswordClientSpec) DeepCopyInto(out *PasswordClientSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PasswordClientSpec.
+func (in *PasswordClientSpec) DeepCopy() *PasswordClientSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PasswordClientSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortsSpec) DeepCopyInto(out *PortsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortsSpec.
+func (in *PortsSpec) DeepCopy() *PortsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PortsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresStorageSpec) DeepCopyInto(out *PostgresStorageSpec) {
+	*out = *in
+	in.PasswordRef.DeepCopyInto(&out.PasswordRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStorageSpec.
+func (in *PostgresStorageSpec) DeepCopy() *PostgresStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RawConfigSpec) DeepCopyInto(out *RawConfigSpec) {
+	*out = *in
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]RawConnectorSecretSpec, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RawConfigSpec.
+func (in *RawConfigSpec) DeepCopy() *RawConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RawConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RawConnectorSecretSpec) DeepCopyInto(out *RawConnectorSecretSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RawConnectorSecretSpec.
+func (in *RawConnectorSecretSpec) DeepCopy() *RawConnectorSecretSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RawConnectorSecretSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RefreshTokensSpec) DeepCopyInto(out *RefreshTokensSpec) {
+	*out = *in
+	if in.ValidIfNotUsedFor != nil {
+		in, out := &in.ValidIfNotUsedFor, &out.ValidIfNotUsedFor
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AbsoluteLifetime != nil {
+		in, out := &in.AbsoluteLifetime, &out.AbsoluteLifetime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ReuseInterval != nil {
+		in, out := &in.ReuseInterval, &out.ReuseInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RefreshTokensSpec.
+func (in *RefreshTokensSpec) DeepCopy() *RefreshTokensSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RefreshTokensSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelatedObjectReference) DeepCopyInto(out *RelatedObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelatedObjectReference.
+func (in *RelatedObjectReference) DeepCopy() *RelatedObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RelatedObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteRateLimitSpec) DeepCopyInto(out *RouteRateLimitSpec) {
+	*out = *in
+	if in.ConcurrentConnections != nil {
+		in, out := &in.ConcurrentConnections, &out.ConcurrentConnections
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RequestsPerSecond != nil {
+		in, out := &in.RequestsPerSecond, &out.RequestsPerSecond
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteRateLimitSpec.
+func (in *RouteRateLimitSpec) DeepCopy() *RouteRateLimitSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteRateLimitSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteSpec) DeepCopyInto(out *RouteSpec) {
+	*out = *in
+	in.RateLimit.DeepCopyInto(&out.RateLimit)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteSpec.
+func (in *RouteSpec) DeepCopy() *RouteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SAMLConfigSpec) DeepCopyInto(out *SAMLConfigSpec) {
+	*out = *in
+	out.CARef = in.CARef
+	if in.CAData != nil {
+		in, out := &in.CAData, &out.CAData
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SAMLConfigSpec.
+func (in *SAMLConfigSpec) DeepCopy() *SAMLConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SAMLConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScratchVolume) DeepCopyInto(out *ScratchVolume) {
+	*out = *in
+	if in.SizeLimit != nil {
+		in, out := &in.SizeLimit, &out.SizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScratchVolume.
+func (in *ScratchVolume) DeepCopy() *ScratchVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(ScratchVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityProfile) DeepCopyInto(out *SecurityProfile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityProfile.
+func (in *SecurityProfile) DeepCopy() *SecurityProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityProfilesSpec) DeepCopyInto(out *SecurityProfilesSpec) {
+	*out = *in
+	if in.Seccomp != nil {
+		in, out := &in.Seccomp, &out.Seccomp
+		*out = new(SecurityProfile)
+		**out = **in
+	}
+	if in.AppArmor != nil {
+		in, out := &in.AppArmor, &out.AppArmor
+		*out = new(SecurityProfile)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityProfilesSpec.
+func (in *SecurityProfilesSpec) DeepCopy() *SecurityProfilesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityProfilesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceSpec) DeepCopyInto(out *ServiceSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]corev1.IPFamily, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPFamilyPolicy != nil {
+		in, out := &in.IPFamilyPolicy, &out.IPFamilyPolicy
+		*out = new(corev1.IPFamilyPolicyType)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceSpec.
+func (in *ServiceSpec) DeepCopy() *ServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionsStatus) DeepCopyInto(out *SessionsStatus) {
+	*out = *in
+	in.LastCountTime.DeepCopyInto(&out.LastCountTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionsStatus.
+func (in *SessionsStatus) DeepCopy() *SessionsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmokeTestSpec) DeepCopyInto(out *SmokeTestSpec) {
+	*out = *in
+	if in.ClientSecretRef != nil {
+		in, out := &in.ClientSecretRef, &out.ClientSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmokeTestSpec.
+func (in *SmokeTestSpec) DeepCopy() *SmokeTestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmokeTestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmokeTestStatus) DeepCopyInto(out *SmokeTestStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmokeTestStatus.
+func (in *SmokeTestStatus) DeepCopy() *SmokeTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SmokeTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageCleanupSpec) DeepCopyInto(out *StorageCleanupSpec) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageCleanupSpec.
+func (in *StorageCleanupSpec) DeepCopy() *StorageCleanupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageCleanupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageCleanupStatus) DeepCopyInto(out *StorageCleanupStatus) {
+	*out = *in
+	in.LastCleanupTime.DeepCopyInto(&out.LastCleanupTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageCleanupStatus.
+func (in *StorageCleanupStatus) DeepCopy() *StorageCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	if in.Postgres != nil {
+		in, out := &in.Postgres, &out.Postgres
+		*out = new(PostgresStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Etcd != nil {
+		in, out := &in.Etcd, &out.Etcd
+		*out = new(EtcdStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TTLStatus) DeepCopyInto(out *TTLStatus) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TTLStatus.
+func (in *TTLStatus) DeepCopy() *TTLStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TTLStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetNamespaceSpec) DeepCopyInto(out *TargetNamespaceSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ResourceQuota != nil {
+		in, out := &in.ResourceQuota, &out.ResourceQuota
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetNamespaceSpec.
+func (in *TargetNamespaceSpec) DeepCopy() *TargetNamespaceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetNamespaceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeamSyncSpec) DeepCopyInto(out *TeamSyncSpec) {
+	*out = *in
+	if in.TokenRef != nil {
+		in, out := &in.TokenRef, &out.TokenRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeamSyncSpec.
+func (in *TeamSyncSpec) DeepCopy() *TeamSyncSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TeamSyncSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeamSyncStatus) DeepCopyInto(out *TeamSyncStatus) {
+	*out = *in
+	in.LastSyncTime.DeepCopyInto(&out.LastSyncTime)
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeamSyncStatus.
+func (in *TeamSyncStatus) DeepCopy() *TeamSyncStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TeamSyncStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TelemetrySpec) DeepCopyInto(out *TelemetrySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TelemetrySpec.
+func (in *TelemetrySpec) DeepCopy() *TelemetrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TelemetrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustDistributionSpec) DeepCopyInto(out *TrustDistributionSpec) {
+	*out = *in
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CABundleRef != nil {
+		in, out := &in.CABundleRef, &out.CABundleRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustDistributionSpec.
+func (in *TrustDistributionSpec) DeepCopy() *TrustDistributionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustDistributionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserMatcher) DeepCopyInto(out *UserMatcher) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserMatcher.
+func (in *UserMatcher) DeepCopy() *UserMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(UserMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserSearchSpec) DeepCopyInto(out *UserSearchSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSearchSpec.
+func (in *UserSearchSpec) DeepCopy() *UserSearchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UserSearchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerticalAutoscalingSpec) DeepCopyInto(out *VerticalAutoscalingSpec) {
+	*out = *in
+	if in.MinAllowed != nil {
+		in, out := &in.MinAllowed, &out.MinAllowed
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.MaxAllowed != nil {
+		in, out := &in.MaxAllowed, &out.MaxAllowed
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerticalAutoscalingSpec.
+func (in *VerticalAutoscalingSpec) DeepCopy() *VerticalAutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VerticalAutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSpec) DeepCopyInto(out *WebSpec) {
+	*out = *in
+	if in.TemplatesConfigMapRef != nil {
+		in, out := &in.TemplatesConfigMapRef, &out.TemplatesConfigMapRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSpec.
+func (in *WebSpec) DeepCopy() *WebSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,211 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Suffix of the NetworkPolicy only admitting the proxies of the authproxy connectors to the HTTPS port of dex
+	AUTHPROXY_POLICY_SUFFIX = "-authproxy"
+	// Label of the namespaces of the OpenShift routers
+	OPENSHIFT_POLICY_GROUP_LABEL   = "network.openshift.io/policy-group"
+	OPENSHIFT_POLICY_GROUP_INGRESS = "ingress"
+	// Label of the operator pods, which fetch the discovery document of dex
+	OPERATOR_POD_LABEL = "control-plane"
+	OPERATOR_POD_VALUE = "controller-manager"
+	// Default namespace of the ingress controller outside of OpenShift
+	DEFAULT_INGRESS_CONTROLLER_NAMESPACE = "ingress-nginx"
+)
+
+// Path of the Ingress routing the callback of an authproxy connector to its proxy
+type authProxyPath struct {
+	Path    string
+	Service string
+	Port    int32
+}
+
+// Check the callback of the authproxy connectors can be routed to their proxy. The Service of dex exposed as a
+// NodePort or LoadBalancer, and the wildcard Route, can't route a path to another Service.
+func canRouteAuthProxyCallback(dexServer *authv1alpha1.DexServer, openShift bool) bool {
+	if serviceType := dexServer.Spec.Service.Type; serviceType == corev1.ServiceTypeNodePort || serviceType == corev1.ServiceTypeLoadBalancer {
+		return false
+	}
+	return !isWildcardRoute(dexServer, openShift)
+}
+
+// Get the paths of the Ingress routing the callbacks of the authproxy connectors with a proxy Service. dex trusts the
+// headers of the requests to the callback, only the proxy must reach it. The rejected connectors are left out.
+func getAuthProxyPaths(dexServer *authv1alpha1.DexServer) []authProxyPath {
+	rejected := map[string]bool{}
+	for _, connector := range dexServer.Status.RejectedConnectors {
+		rejected[connector.Id] = true
+	}
+	paths := []authProxyPath{}
+	for _, connector := range dexServer.Spec.Connectors {
+		service := connector.AuthProxy.ProxyService
+		if connector.Type != authv1alpha1.ConnectorTypeAuthProxy || service == nil || rejected[connector.Id] {
+			continue
+		}
+		paths = append(paths, authProxyPath{
+			Path:    strings.TrimSuffix(getRoutePath(dexServer), "/") + "/callback/" + connector.Id,
+			Service: service.Name,
+			Port:    service.Port,
+		})
+	}
+	return paths
+}
+
+// Check the DexServer has an authproxy connector, its dex pods then only admit the proxies to their HTTPS port
+func hasAuthProxyConnector(dexServer *authv1alpha1.DexServer) bool {
+	for _, connector := range dexServer.Spec.Connectors {
+		if connector.Type == authv1alpha1.ConnectorTypeAuthProxy {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the namespaces of the ingress controllers routing the requests to dex
+func (r *DexServerReconciler) getIngressControllerNamespaces() []string {
+	if len(r.IngressControllerNamespaces) > 0 {
+		return r.IngressControllerNamespaces
+	}
+	return []string{DEFAULT_INGRESS_CONTROLLER_NAMESPACE}
+}
+
+// Get the NetworkPolicy of a DexServer with authproxy connectors. dex trusts the headers of the requests to the
+// callbacks, so its HTTPS port only admits the pods of the proxy Services, the ingress controllers and the operator.
+// The gRPC and metrics ports are left open.
+func (r *DexServerReconciler) getAuthProxyNetworkPolicy(dexServer *authv1alpha1.DexServer, ctx context.Context) (*networkingv1.NetworkPolicy, error) {
+	peers := []networkingv1.NetworkPolicyPeer{{
+		NamespaceSelector: &metav1.LabelSelector{},
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{OPERATOR_POD_LABEL: OPERATOR_POD_VALUE}},
+	}}
+	if r.OpenShift {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{OPENSHIFT_POLICY_GROUP_LABEL: OPENSHIFT_POLICY_GROUP_INGRESS}},
+		})
+	} else {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      corev1.LabelMetadataName,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   r.getIngressControllerNamespaces(),
+			}}},
+		})
+	}
+	for _, path := range getAuthProxyPaths(dexServer) {
+		service := &corev1.Service{}
+		if err := r.Get(ctx, types.NamespacedName{Name: path.Service, Namespace: dexServer.Namespace}, service); err != nil {
+			return nil, errors.Wrapf(err, "error getting the proxy Service %s", path.Service)
+		}
+		if len(service.Spec.Selector) == 0 {
+			return nil, fmt.Errorf("the proxy Service %s has no selector", path.Service)
+		}
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{MatchLabels: service.Spec.Selector},
+		})
+	}
+
+	port := func(port int32) networkingv1.NetworkPolicyPort {
+		p := intstr.FromInt(int(port))
+		return networkingv1.NetworkPolicyPort{Port: &p}
+	}
+	httpsPort, grpcPort := getDexPorts(dexServer)
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dexServer.Name + AUTHPROXY_POLICY_SUFFIX,
+			Namespace:   dexServer.Namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": dexServer.Name}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{Ports: []networkingv1.NetworkPolicyPort{port(httpsPort)}, From: peers},
+				{Ports: []networkingv1.NetworkPolicyPort{port(grpcPort), port(getTelemetryPort(dexServer)), port(RBAC_PROXY_PORT)}},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	r.addManagedMetadata(dexServer, componentAuthProxy, policy.Labels, policy.Annotations)
+	return policy, nil
+}
+
+// Create, update or delete the NetworkPolicy of the authproxy connectors. It is owned by the DexServer, and deleted
+// once the DexServer has no authproxy connector.
+func (r *DexServerReconciler) syncAuthProxyNetworkPolicy(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	name := dexServer.Name + AUTHPROXY_POLICY_SUFFIX
+	log.Info("syncAuthProxyNetworkPolicy", "NetworkPolicy.Name", name)
+
+	existing := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: dexServer.Namespace}, existing)
+	notFound := kubeerrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return errors.Wrap(err, "error getting the NetworkPolicy")
+	}
+	if !hasAuthProxyConnector(dexServer) {
+		if notFound || !metav1.IsControlledBy(existing, dexServer) {
+			return nil
+		}
+		log.Info("Deleting the NetworkPolicy of the authproxy connectors", "NetworkPolicy.Name", name)
+		return client.IgnoreNotFound(r.Delete(ctx, existing))
+	}
+
+	policy, err := r.getAuthProxyNetworkPolicy(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	if err := ctrl.SetControllerReference(dexServer, policy, r.Scheme); err != nil {
+		return err
+	}
+	if notFound {
+		log.Info("Creating the NetworkPolicy of the authproxy connectors", "NetworkPolicy.Name", name)
+		return errors.Wrap(r.Create(ctx, policy), "error creating the NetworkPolicy")
+	}
+	if !metav1.IsControlledBy(existing, dexServer) {
+		return fmt.Errorf("NetworkPolicy %s already exists and is not managed by the DexServer", name)
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, policy.Spec) && equality.Semantic.DeepEqual(existing.Labels, policy.Labels) {
+		return nil
+	}
+	existing.Spec = policy.Spec
+	existing.Labels = policy.Labels
+	existing.Annotations = policy.Annotations
+	log.Info("Updating the NetworkPolicy of the authproxy connectors", "NetworkPolicy.Name", name)
+	return errors.Wrap(r.Update(ctx, existing), "error updating the NetworkPolicy")
+}
+
+// Render the dex config of an authproxy connector, dex reads the user from the headers set by the proxy
+func (r *DexServerReconciler) renderAuthProxyConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeAuthProxy),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			UserHeader:  connector.AuthProxy.UserHeader,
+			EmailHeader: connector.AuthProxy.EmailHeader,
+			GroupHeader: connector.AuthProxy.GroupHeader,
+			Groups:      connector.AuthProxy.Groups,
+		},
+	}, nil
+}
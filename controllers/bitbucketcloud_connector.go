@@ -0,0 +1,39 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Type of the Bitbucket Cloud connector in the config of dex. The DexServer keeps the bitbucketcloud type, without
+// dash like its other connector types.
+const DEX_BITBUCKET_CLOUD_CONNECTOR_TYPE = "bitbucket-cloud"
+
+// Render the dex config of a Bitbucket Cloud connector, its client secret is read by dex from an environment variable
+func (r *DexServerReconciler) renderBitbucketCloudConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	err := r.copySecretToDexServerNamespace(dexServer, connector.BitbucketCloud.ClientSecretRef, ctx)
+	if err != nil {
+		return DexConnectorSpec{}, err
+	}
+
+	// Environment variable that references the Bitbucket Cloud client secret copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple Bitbucket Cloud connectors
+	clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + getUniqueAlphanumericIdForConnector(connector)
+
+	return DexConnectorSpec{
+		Type: DEX_BITBUCKET_CLOUD_CONNECTOR_TYPE,
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			ClientID:          connector.BitbucketCloud.ClientID,
+			ClientSecret:      clientSecretEnvVariable,
+			RedirectURI:       connector.BitbucketCloud.RedirectURI,
+			Teams:             connector.BitbucketCloud.Teams,
+			IncludeTeamGroups: connector.BitbucketCloud.IncludeTeamGroups,
+		},
+	}, nil
+}
@@ -0,0 +1,81 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// IsOpenShift checks whether the cluster serves the OpenShift route API. When it does not, the operator runs in
+// plain Kubernetes mode: no service serving certificates or route annotations are requested, and the dex web
+// certificate is generated by the operator.
+func IsOpenShift(kubeClient kubernetes.Interface) (bool, error) {
+	_, err := kubeClient.Discovery().ServerResourcesForGroupVersion(routev1.GroupVersion.String())
+	switch {
+	case err == nil:
+		return true, nil
+	case kubeerrors.IsNotFound(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Time after which the cluster ingress domain is read again
+var ingressDomainCacheTTL = 10 * time.Minute
+
+var clusterIngressDomain = &ingressDomainCache{}
+
+// ingressDomainCache holds the domain of the OpenShift ingress config, so that the issuer host of every DexServer
+// can be validated without reading the cluster config on each reconcile.
+type ingressDomainCache struct {
+	mu      sync.Mutex
+	domain  string
+	expires time.Time
+}
+
+// get returns the cluster ingress domain, or an empty string when the cluster has no ingress config
+func (c *ingressDomainCache) get(ctx context.Context, dynamicClient dynamic.Interface) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.expires) {
+		return c.domain, nil
+	}
+	ingress, err := dynamicClient.Resource(ingressConfigGVR).Get(ctx, "cluster", metav1.GetOptions{})
+	switch {
+	case err == nil:
+	case kubeerrors.IsNotFound(err):
+		ingress = nil
+	default:
+		return "", err
+	}
+	domain := ""
+	if ingress != nil {
+		domain, _, _ = unstructured.NestedString(ingress.Object, "spec", "domain")
+	}
+	c.domain = domain
+	c.expires = time.Now().Add(ingressDomainCacheTTL)
+	return domain, nil
+}
+
+var ingressConfigGVR = schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "ingresses"}
+
+// isHostInIngressDomain checks whether host is a subdomain of the ingress domain
+func isHostInIngressDomain(host string, domain string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(strings.TrimPrefix(domain, ".")))
+}
@@ -0,0 +1,270 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/tracing"
+)
+
+const (
+	// Name of the ClusterDexServer a DexServer is managed for
+	CLUSTER_DEXSERVER_LABEL = "auth.identitatem.io/clusterdexserver"
+)
+
+// ClusterDexServerReconciler reconciles a ClusterDexServer object. The DexServer of a ClusterDexServer is created in
+// its target namespace and is reconciled by the DexServerReconciler like any other DexServer.
+type ClusterDexServerReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=clusterdexservers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=clusterdexservers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=clusterdexservers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;delete
+
+// Reconcile creates the target namespace and the DexServer of a ClusterDexServer, and reports the status of the
+// DexServer. The DexServer is owned by the ClusterDexServer and is garbage collected with it.
+func (r *ClusterDexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	log.V(1).Info("Reconciling...")
+	ctx, span := tracing.Start(ctx, "ClusterDexServer.Reconcile", "name", req.Name)
+	defer span.End()
+
+	clusterDexServer := &authv1alpha1.ClusterDexServer{}
+	if err := r.Get(ctx, req.NamespacedName, clusterDexServer); err != nil {
+		log.Error(err, "failed to fetch ClusterDexServer instance")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if clusterDexServer.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.syncTargetNamespace(clusterDexServer, ctx); err != nil {
+		log.Error(err, "failed to sync target namespace")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.ClusterDexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: "ConfigTargetNamespaceFailed",
+			Message: fmt.Sprintf("failed to sync target namespace. error: %s",
+				err.Error()),
+		}
+		if err := updateClusterDexServerStatusConditions(r.Client, clusterDexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	dexServer, err := r.syncDexServer(clusterDexServer, ctx)
+	if err != nil {
+		log.Error(err, "failed to sync DexServer")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.ClusterDexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: "ConfigDexServerFailed",
+			Message: fmt.Sprintf("failed to sync DexServer. error: %s",
+				err.Error()),
+		}
+		if err := updateClusterDexServerStatusConditions(r.Client, clusterDexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := r.deleteStaleDexServers(clusterDexServer, ctx); err != nil {
+		log.Error(err, "failed to delete the DexServers of a previous target namespace")
+		return ctrl.Result{}, err
+	}
+	if err := r.deleteStaleNamespaces(clusterDexServer, ctx); err != nil {
+		log.Error(err, "failed to delete the dedicated namespaces of a previous target namespace")
+		return ctrl.Result{}, err
+	}
+
+	clusterDexServer.Status.DexServer = &authv1alpha1.RelatedObjectReference{
+		Kind:      "DexServer",
+		Name:      dexServer.Name,
+		Namespace: dexServer.Namespace,
+	}
+	clusterDexServer.Status.Issuer = dexServer.Status.Issuer
+	conds := []metav1.Condition{{
+		Type:    authv1alpha1.ClusterDexServerConditionTypeApplied,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: fmt.Sprintf("DexServer %s/%s is applied", dexServer.Namespace, dexServer.Name),
+	}}
+	// Report the state of the dex server itself
+	for _, condType := range []string{authv1alpha1.DexServerDeploymentAvailable, authv1alpha1.DexServerConditionTypeReady} {
+		if cond := meta.FindStatusCondition(dexServer.Status.Conditions, condType); cond != nil {
+			conds = append(conds, metav1.Condition{
+				Type:    cond.Type,
+				Status:  cond.Status,
+				Reason:  cond.Reason,
+				Message: cond.Message,
+			})
+		}
+	}
+	if err := updateClusterDexServerStatusConditions(r.Client, clusterDexServer, conds...); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Create the target namespace when it does not exist. With spec.createNamespace, the namespace is owned by the
+// ClusterDexServer, see syncDedicatedNamespace.
+func (r *ClusterDexServerReconciler) syncTargetNamespace(clusterDexServer *authv1alpha1.ClusterDexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	namespaceName := clusterDexServer.Spec.TargetNamespace
+	if namespaceName == "" {
+		return fmt.Errorf("spec.targetNamespace is not set")
+	}
+
+	namespace := &corev1.Namespace{}
+	err := r.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace)
+	switch {
+	case err == nil:
+		if !clusterDexServer.Spec.CreateNamespace {
+			return r.releaseTargetNamespace(clusterDexServer, namespace, ctx)
+		}
+		if !metav1.IsControlledBy(namespace, clusterDexServer) {
+			return fmt.Errorf("namespace %s already exists and is not dedicated to ClusterDexServer %s",
+				namespaceName, clusterDexServer.Name)
+		}
+		return r.syncDedicatedNamespace(clusterDexServer, namespace, ctx)
+	case !kubeerrors.IsNotFound(err):
+		return err
+	}
+	if clusterDexServer.Spec.CreateNamespace {
+		namespace = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   namespaceName,
+				Labels: getDedicatedNamespaceLabels(clusterDexServer),
+			},
+		}
+		if err := ctrl.SetControllerReference(clusterDexServer, namespace, r.Scheme); err != nil {
+			return err
+		}
+		log.Info("Creating dedicated target namespace", "Namespace.Name", namespaceName)
+		if err := r.Create(ctx, namespace); err != nil {
+			return err
+		}
+		return r.syncDedicatedNamespace(clusterDexServer, namespace, ctx)
+	}
+	// The namespace is not owned by the ClusterDexServer, it may hold objects created by the tenant
+	namespace = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespaceName,
+			Labels: map[string]string{
+				MANAGED_BY_LABEL: MANAGED_BY_VALUE,
+			},
+		},
+	}
+	log.Info("Creating target namespace", "Namespace.Name", namespaceName)
+	return r.Create(ctx, namespace)
+}
+
+// Create or update the DexServer of the ClusterDexServer in the target namespace
+func (r *ClusterDexServerReconciler) syncDexServer(clusterDexServer *authv1alpha1.ClusterDexServer, ctx context.Context) (*authv1alpha1.DexServer, error) {
+	log := ctrllog.FromContext(ctx)
+	namespace := clusterDexServer.Spec.TargetNamespace
+	log.Info("syncDexServer", "DexServer.Namespace", namespace, "DexServer.Name", clusterDexServer.Name)
+
+	dexServer := &authv1alpha1.DexServer{}
+	err := r.Get(ctx, types.NamespacedName{Name: clusterDexServer.Name, Namespace: namespace}, dexServer)
+	switch {
+	case err == nil:
+		if !metav1.IsControlledBy(dexServer, clusterDexServer) {
+			return nil, fmt.Errorf("DexServer %s/%s already exists and is not managed by ClusterDexServer %s",
+				namespace, dexServer.Name, clusterDexServer.Name)
+		}
+		if equality.Semantic.DeepEqual(dexServer.Spec, clusterDexServer.Spec.DexServerSpec) {
+			return dexServer, nil
+		}
+		dexServer.Spec = *clusterDexServer.Spec.DexServerSpec.DeepCopy()
+		log.Info("Updating DexServer", "DexServer.Namespace", namespace, "DexServer.Name", dexServer.Name)
+		if err := r.Update(ctx, dexServer); err != nil {
+			return nil, err
+		}
+		return dexServer, nil
+	case !kubeerrors.IsNotFound(err):
+		return nil, err
+	}
+
+	dexServer = &authv1alpha1.DexServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterDexServer.Name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				MANAGED_BY_LABEL:        MANAGED_BY_VALUE,
+				CLUSTER_DEXSERVER_LABEL: clusterDexServer.Name,
+			},
+		},
+		Spec: *clusterDexServer.Spec.DexServerSpec.DeepCopy(),
+	}
+	if err := ctrl.SetControllerReference(clusterDexServer, dexServer, r.Scheme); err != nil {
+		return nil, err
+	}
+	log.Info("Creating a new DexServer", "DexServer.Namespace", namespace, "DexServer.Name", dexServer.Name)
+	if err := r.Create(ctx, dexServer); err != nil {
+		return nil, err
+	}
+	return dexServer, nil
+}
+
+// Delete the DexServers left in a previous target namespace of the ClusterDexServer
+func (r *ClusterDexServerReconciler) deleteStaleDexServers(clusterDexServer *authv1alpha1.ClusterDexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	dexServers := &authv1alpha1.DexServerList{}
+	if err := r.List(ctx, dexServers, client.MatchingLabels{CLUSTER_DEXSERVER_LABEL: clusterDexServer.Name}); err != nil {
+		return err
+	}
+	for i := range dexServers.Items {
+		dexServer := &dexServers.Items[i]
+		if dexServer.Namespace == clusterDexServer.Spec.TargetNamespace || !metav1.IsControlledBy(dexServer, clusterDexServer) {
+			continue
+		}
+		log.Info("Deleting DexServer of a previous target namespace", "DexServer.Namespace", dexServer.Namespace, "DexServer.Name", dexServer.Name)
+		if err := r.Delete(ctx, dexServer); err != nil && !kubeerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateClusterDexServerStatusConditions(c client.Client, clusterDexServer *authv1alpha1.ClusterDexServer, newConditions ...metav1.Condition) error {
+	clusterDexServer.Status.Conditions = mergeStatusConditions(clusterDexServer.Status.Conditions, newConditions...)
+	return c.Status().Update(context.TODO(), clusterDexServer)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterDexServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// only handle spec changes, the status is updated by this controller
+		For(&authv1alpha1.ClusterDexServer{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the status of the DexServer is reported in the ClusterDexServer status
+		Owns(&authv1alpha1.DexServer{}).
+		// the dedicated namespaces and their isolation are restored when changed
+		Owns(&corev1.Namespace{}).
+		Owns(&corev1.ResourceQuota{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Complete(r)
+}
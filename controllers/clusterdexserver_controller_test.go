@@ -0,0 +1,91 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Process ClusterDexServer CR", func() {
+	ClusterDexServerName := "my-clusterdexserver"
+	TargetNamespace := "my-clusterdexserver-target-ns"
+	DexServerIssuer := "https://clusterdexserver.testhost.com"
+
+	It("should create the DexServer in the target namespace", func() {
+		clusterDexServer := &authv1alpha1.ClusterDexServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: ClusterDexServerName,
+			},
+			Spec: authv1alpha1.ClusterDexServerSpec{
+				TargetNamespace: TargetNamespace,
+				DexServerSpec: authv1alpha1.DexServerSpec{
+					Issuer: DexServerIssuer,
+				},
+			},
+		}
+		err := k8sClient.Create(context.TODO(), clusterDexServer)
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = ClusterDexServerName
+		_, err = rClusterDexServer.Reconcile(context.TODO(), req)
+		Expect(err).To(BeNil())
+
+		By("creating the target namespace", func() {
+			ns := &corev1.Namespace{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: TargetNamespace}, ns)
+			Expect(err).To(BeNil())
+		})
+		By("creating the DexServer owned by the ClusterDexServer", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: ClusterDexServerName, Namespace: TargetNamespace}, dexServer)
+			Expect(err).To(BeNil())
+			Expect(dexServer.Spec.Issuer).To(Equal(DexServerIssuer))
+			Expect(metav1.IsControlledBy(dexServer, clusterDexServer)).To(BeTrue())
+			Expect(dexServer.Labels[CLUSTER_DEXSERVER_LABEL]).To(Equal(ClusterDexServerName))
+		})
+		By("reporting the DexServer in the status", func() {
+			updated := &authv1alpha1.ClusterDexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: ClusterDexServerName}, updated)
+			Expect(err).To(BeNil())
+			Expect(updated.Status.DexServer).ToNot(BeNil())
+			Expect(updated.Status.DexServer.Namespace).To(Equal(TargetNamespace))
+		})
+	})
+	It("should not take over a DexServer it does not own", func() {
+		otherNamespace := "my-clusterdexserver-other-ns"
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: otherNamespace}})
+		Expect(err).To(BeNil())
+		err = k8sClient.Create(context.TODO(), &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: ClusterDexServerName, Namespace: otherNamespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://other.testhost.com"},
+		})
+		Expect(err).To(BeNil())
+
+		clusterDexServer := &authv1alpha1.ClusterDexServer{}
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: ClusterDexServerName}, clusterDexServer)
+		Expect(err).To(BeNil())
+		clusterDexServer.Spec.TargetNamespace = otherNamespace
+		err = k8sClient.Update(context.TODO(), clusterDexServer)
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = ClusterDexServerName
+		_, err = rClusterDexServer.Reconcile(context.TODO(), req)
+		Expect(err).ToNot(BeNil())
+
+		dexServer := &authv1alpha1.DexServer{}
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: ClusterDexServerName, Namespace: otherNamespace}, dexServer)
+		Expect(err).To(BeNil())
+		Expect(dexServer.Spec.Issuer).To(Equal("https://other.testhost.com"))
+	})
+})
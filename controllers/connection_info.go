@@ -0,0 +1,137 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// ConfigMap publishing how to connect to a dex server, for the operators consuming it
+	CONNECTION_INFO_SUFFIX = "-connection"
+
+	CONNECTION_INFO_ISSUER_KEY             = "issuer"
+	CONNECTION_INFO_ISSUER_CA_KEY          = "issuer-ca.crt"
+	CONNECTION_INFO_GRPC_ENDPOINT_KEY      = "grpc-endpoint"
+	CONNECTION_INFO_GRPC_CA_KEY            = "grpc-ca.crt"
+	CONNECTION_INFO_GRPC_CLIENT_SECRET_KEY = "grpc-client-secret"
+	// Expiry of the gRPC certificates, changes when the client certificate is renewed without a new CA
+	CONNECTION_INFO_GRPC_CERT_EXPIRY_KEY = "grpc-cert-expiry"
+	// Hash of the other keys, and a counter incremented each time the hash changes
+	CONNECTION_INFO_HASH_KEY       = "hash"
+	CONNECTION_INFO_GENERATION_KEY = "generation"
+)
+
+// Get the connection info of the dex server, without the hash and generation
+func (r *DexServerReconciler) getConnectionInfo(dexServer *authv1alpha1.DexServer, ctx context.Context) (map[string]string, error) {
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return nil, err
+	}
+	mtlsSecret, err := r.getMTLSSecret(dexServer, ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting dex server grpc mtls secret")
+	}
+	data := map[string]string{
+		CONNECTION_INFO_ISSUER_KEY:             issuer,
+		CONNECTION_INFO_GRPC_ENDPOINT_KEY:      net.JoinHostPort(getServiceName(dexServer.Namespace), strconv.Itoa(DEX_GRPC_PORT)),
+		CONNECTION_INFO_GRPC_CA_KEY:            string(mtlsSecret.Data["ca.crt"]),
+		CONNECTION_INFO_GRPC_CLIENT_SECRET_KEY: mtlsSecret.Name,
+		CONNECTION_INFO_GRPC_CERT_EXPIRY_KEY:   mtlsSecret.Annotations[MTLS_CERT_EXPIRY_ANNOTATION],
+	}
+	caBundle, err := r.getIssuerCABundle(dexServer, ctx)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		// published once the CA bundle exists, on a later reconcile
+		ctrllog.FromContext(ctx).Info("Issuer CA bundle not found, leaving it out of the connection info")
+	case err != nil:
+		return nil, err
+	default:
+		data[CONNECTION_INFO_ISSUER_CA_KEY] = caBundle
+	}
+	return data, nil
+}
+
+func getConnectionInfoHash(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if key != CONNECTION_INFO_HASH_KEY && key != CONNECTION_INFO_GENERATION_KEY {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%q\n", key, data[key])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// Publish the issuer, gRPC endpoint, CAs and gRPC client certificate secret of the dex server in one ConfigMap, so that
+// consuming operators watch a single object. The hash and generation keys change whenever any of them rotates.
+func (r *DexServerReconciler) syncConnectionInfo(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	name := dexServer.Name + CONNECTION_INFO_SUFFIX
+	log.Info("syncConnectionInfo", "ConfigMap.Name", name)
+
+	data, err := r.getConnectionInfo(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	hash := getConnectionInfoHash(data)
+	data[CONNECTION_INFO_HASH_KEY] = hash
+
+	configMap := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: dexServer.Namespace}, configMap)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		data[CONNECTION_INFO_GENERATION_KEY] = "1"
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   dexServer.Namespace,
+				Labels:      map[string]string{"app": dexServer.Name},
+				Annotations: map[string]string{},
+			},
+			Data: data,
+		}
+		r.addManagedMetadata(dexServer, componentConnectionInfo, configMap.Labels, configMap.Annotations)
+		if err := ctrl.SetControllerReference(dexServer, configMap, r.Scheme); err != nil {
+			return err
+		}
+		log.Info("Creating the connection info", "ConfigMap.Name", name)
+		return r.Create(ctx, configMap)
+	case err != nil:
+		return err
+	}
+	if configMap.Data[CONNECTION_INFO_HASH_KEY] == hash {
+		return nil
+	}
+	generation, _ := strconv.ParseInt(configMap.Data[CONNECTION_INFO_GENERATION_KEY], 10, 64)
+	data[CONNECTION_INFO_GENERATION_KEY] = strconv.FormatInt(generation+1, 10)
+	configMap.Data = data
+	if configMap.Labels == nil {
+		configMap.Labels = map[string]string{}
+	}
+	if configMap.Annotations == nil {
+		configMap.Annotations = map[string]string{}
+	}
+	r.addManagedMetadata(dexServer, componentConnectionInfo, configMap.Labels, configMap.Annotations)
+	log.Info("Updating the connection info", "ConfigMap.Name", name, "Generation", data[CONNECTION_INFO_GENERATION_KEY])
+	return r.Update(ctx, configMap)
+}
@@ -0,0 +1,270 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+var (
+	defaultConnectorProbeInterval = time.Minute
+	connectorProbeTimeout         = 5 * time.Second
+	// Deadline of the probes of all the connectors of a dex server, which bounds the time they add to a reconcile
+	connectorProbesDeadline = 10 * time.Second
+)
+
+// probeConnector checks that the upstream identity provider of a connector is reachable
+func probeConnector(ctx context.Context, connector authv1alpha1.ConnectorSpec) error {
+	ctx, cancel := context.WithTimeout(ctx, connectorProbeTimeout)
+	defer cancel()
+
+	switch connector.Type {
+	case authv1alpha1.ConnectorTypeAuthProxy:
+		// the proxy authenticates the users in front of dex, dex does not connect to it
+		return nil
+	case authv1alpha1.ConnectorTypeMockCallback, authv1alpha1.ConnectorTypeMockPassword:
+		// the test user is logged in by dex itself
+		return nil
+	case authv1alpha1.ConnectorTypeRaw:
+		// the identity provider is not known from the verbatim config
+		return nil
+	case authv1alpha1.ConnectorTypeBitbucketCloud:
+		return probeTCP(ctx, "bitbucket.org:443")
+	case authv1alpha1.ConnectorTypeGitea:
+		return probeHTTP(ctx, strings.TrimSuffix(connector.Gitea.BaseURL, "/")+"/api/v1/version")
+	case authv1alpha1.ConnectorTypeGitHub:
+		host := "github.com"
+		if connector.GitHub.HostName != "" {
+			host = connector.GitHub.HostName
+		}
+		return probeTCP(ctx, withDefaultPort(host, "443"))
+	case authv1alpha1.ConnectorTypeGoogle:
+		return probeTCP(ctx, "accounts.google.com:443")
+	case authv1alpha1.ConnectorTypeMicrosoft:
+		return probeTCP(ctx, "login.microsoftonline.com:443")
+	case authv1alpha1.ConnectorTypeLDAP:
+		// healthy when a replica is reachable
+		err := fmt.Errorf("the LDAP connector has no host")
+		for _, host := range getLDAPHosts(connector.LDAP) {
+			if err = probeTCP(ctx, host); err == nil {
+				return nil
+			}
+		}
+		return err
+	case authv1alpha1.ConnectorTypeOIDC:
+		return probeHTTP(ctx, strings.TrimSuffix(connector.OIDC.Issuer, "/")+"/.well-known/openid-configuration")
+	case authv1alpha1.ConnectorTypeOpenShift:
+		// the API server is served with the CA of the cluster, which the operator does not trust
+		issuer, err := url.Parse(getOpenShiftIssuer(connector))
+		if err != nil {
+			return err
+		}
+		return probeTCP(ctx, withDefaultPort(issuer.Host, "443"))
+	case authv1alpha1.ConnectorTypeSAML:
+		// the SSO service only answers the authentication requests of the browsers
+		ssoURL, err := url.Parse(connector.SAML.SSOURL)
+		if err != nil {
+			return err
+		}
+		port := "443"
+		if ssoURL.Scheme == "http" {
+			port = "80"
+		}
+		return probeTCP(ctx, withDefaultPort(ssoURL.Host, port))
+	default:
+		return nil
+	}
+}
+
+func probeTCP(ctx context.Context, address string) error {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func probeHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+func withDefaultPort(host string, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Get the hosts of an LDAP connector with their port, spec.connectors[].ldap.host followed by its replicas
+func getLDAPHosts(ldap authv1alpha1.LDAPConfigSpec) []string {
+	port := "636"
+	if ldap.InsecureNoSSL || ldap.StartTLS {
+		port = "389"
+	}
+	hosts := []string{}
+	for _, host := range append([]string{ldap.Host}, ldap.Hosts...) {
+		if host != "" {
+			hosts = append(hosts, withDefaultPort(host, port))
+		}
+	}
+	return hosts
+}
+
+// Check the hosts of an LDAP connector are host names or IP addresses, with a valid port, and are not repeated
+func validateLDAPHosts(connector authv1alpha1.ConnectorSpec) error {
+	hosts := map[string]bool{}
+	for _, address := range getLDAPHosts(connector.LDAP) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("host %s of the LDAP connector %s is invalid: %s", address, connector.Id, err.Error())
+		}
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 && net.ParseIP(host) == nil {
+			return fmt.Errorf("host %s of the LDAP connector %s is invalid: %s", address, connector.Id, strings.Join(errs, ", "))
+		}
+		if number, err := strconv.Atoi(port); err != nil || len(validation.IsValidPortNum(number)) > 0 {
+			return fmt.Errorf("port of the host %s of the LDAP connector %s is invalid", address, connector.Id)
+		}
+		if hosts[address] {
+			return fmt.Errorf("host %s of the LDAP connector %s is repeated", address, connector.Id)
+		}
+		hosts[address] = true
+	}
+	return nil
+}
+
+// Probe the hosts of an LDAP connector with replicas in order, and get the first reachable host. When no host is
+// reachable, the first host is configured so that dex connects to it once it is back.
+func probeLDAPHosts(ctx context.Context, connector authv1alpha1.ConnectorSpec) authv1alpha1.LDAPHostsStatus {
+	log := ctrllog.FromContext(ctx)
+	hosts := getLDAPHosts(connector.LDAP)
+	status := authv1alpha1.LDAPHostsStatus{
+		Id:            connector.Id,
+		LastProbeTime: metav1.Now(),
+	}
+	for _, host := range hosts {
+		probeCtx, cancel := context.WithTimeout(ctx, connectorProbeTimeout)
+		err := probeTCP(probeCtx, host)
+		cancel()
+		if err == nil {
+			status.Host = host
+			return status
+		}
+		log.Info("LDAP host is unreachable", "Connector.Id", connector.Id, "Host", host, "error", err.Error())
+		status.Unreachable = append(status.Unreachable, authv1alpha1.LDAPUnreachableHost{Host: host, Message: err.Error()})
+	}
+	if len(hosts) > 0 {
+		status.Host = hosts[0]
+	}
+	return status
+}
+
+// Whether a connector of the dex server is an LDAP connector with replicas, whose hosts are probed on each reconcile
+func hasLDAPReplicas(dexServer *authv1alpha1.DexServer) bool {
+	for _, connector := range dexServer.Spec.Connectors {
+		if connector.Type == authv1alpha1.ConnectorTypeLDAP && len(connector.LDAP.Hosts) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Probe the connectors of the dex server and record the result in its status. The connectors are probed in parallel,
+// and the probes still running at the deadline fail, so that the unreachable identity providers don't hold the
+// reconcile for a timeout each.
+func probeConnectors(dexServer *authv1alpha1.DexServer, ctx context.Context) map[string]bool {
+	log := ctrllog.FromContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, connectorProbesDeadline)
+	defer cancel()
+
+	errs := make([]error, len(dexServer.Spec.Connectors))
+	var wg sync.WaitGroup
+	for i := range dexServer.Spec.Connectors {
+		wg.Add(1)
+		go func(i int, connector authv1alpha1.ConnectorSpec) {
+			defer wg.Done()
+			errs[i] = probeConnector(ctx, connector)
+		}(i, dexServer.Spec.Connectors[i])
+	}
+	wg.Wait()
+
+	healthy := map[string]bool{}
+	health := []authv1alpha1.ConnectorHealthStatus{}
+	now := metav1.Now()
+	for i, connector := range dexServer.Spec.Connectors {
+		status := authv1alpha1.ConnectorHealthStatus{
+			Id:            connector.Id,
+			Healthy:       true,
+			LastProbeTime: now,
+		}
+		if err := errs[i]; err != nil {
+			log.Info("connector is unhealthy", "Connector.Id", connector.Id, "error", err.Error())
+			status.Healthy = false
+			status.Message = err.Error()
+		}
+		healthy[connector.Id] = status.Healthy
+		health = append(health, status)
+	}
+	dexServer.Status.ConnectorHealth = health
+	return healthy
+}
+
+// Apply the failover policy to the connectors rendered in the dex config. Unhealthy connectors are moved after the
+// healthy ones, or removed with the Hide policy, unless no connector is healthy.
+func applyConnectorFailover(dexServer *authv1alpha1.DexServer, connectors []DexConnectorSpec, healthy map[string]bool) []DexConnectorSpec {
+	anyHealthy := false
+	for _, connector := range connectors {
+		anyHealthy = anyHealthy || healthy[connector.Id]
+	}
+	if !anyHealthy {
+		return connectors
+	}
+
+	result := []DexConnectorSpec{}
+	for _, connector := range connectors {
+		if !healthy[connector.Id] &&
+			dexServer.Spec.ConnectorFailover.Policy == authv1alpha1.ConnectorFailoverPolicyHide &&
+			connector.Id != dexServer.Spec.OAuth2.PasswordConnector {
+			continue
+		}
+		result = append(result, connector)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return healthy[result[i].Id] && !healthy[result[j].Id]
+	})
+	return result
+}
+
+func getConnectorProbeInterval(dexServer *authv1alpha1.DexServer) time.Duration {
+	if interval := dexServer.Spec.ConnectorFailover.ProbeInterval; interval != nil && interval.Duration > 0 {
+		return interval.Duration
+	}
+	return defaultConnectorProbeInterval
+}
@@ -0,0 +1,136 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+var _ = Describe("Probe the replicas of the LDAP connectors", func() {
+	It("should configure the first reachable host", func() {
+		replica, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+		defer replica.Close()
+		// a port nothing listens on
+		closed, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+		Expect(closed.Close()).To(Succeed())
+
+		connector := authv1alpha1.ConnectorSpec{
+			Id:   "my-ldap",
+			Type: authv1alpha1.ConnectorTypeLDAP,
+			LDAP: authv1alpha1.LDAPConfigSpec{
+				Host:  closed.Addr().String(),
+				Hosts: []string{replica.Addr().String()},
+			},
+		}
+		Expect(validateLDAPHosts(connector)).To(Succeed())
+		status := probeLDAPHosts(context.TODO(), connector)
+		Expect(status.Host).To(Equal(replica.Addr().String()))
+		Expect(status.Unreachable).To(HaveLen(1))
+		Expect(status.Unreachable[0].Host).To(Equal(closed.Addr().String()))
+		Expect(probeConnector(context.TODO(), connector)).To(Succeed())
+
+		By("configuring the first host when no host is reachable", func() {
+			connector.LDAP.Hosts = []string{"127.0.0.1:" + portOf(closed.Addr())}
+			connector.LDAP.Host = "localhost:" + portOf(closed.Addr())
+			status := probeLDAPHosts(context.TODO(), connector)
+			Expect(status.Host).To(Equal(connector.LDAP.Host))
+			Expect(status.Unreachable).To(HaveLen(2))
+			Expect(probeConnector(context.TODO(), connector)).NotTo(Succeed())
+		})
+	})
+	It("should validate the hosts", func() {
+		connector := authv1alpha1.ConnectorSpec{
+			Id:   "my-ldap",
+			Type: authv1alpha1.ConnectorTypeLDAP,
+			LDAP: authv1alpha1.LDAPConfigSpec{
+				Host:  "ldap-0.example.com",
+				Hosts: []string{"ldap-1.example.com:636", "10.0.0.2"},
+			},
+		}
+		Expect(getLDAPHosts(connector.LDAP)).To(Equal([]string{"ldap-0.example.com:636", "ldap-1.example.com:636", "10.0.0.2:636"}))
+		Expect(validateLDAPHosts(connector)).To(Succeed())
+		connector.LDAP.Hosts = []string{"ldap-0.example.com:636"}
+		Expect(validateLDAPHosts(connector)).NotTo(Succeed())
+		connector.LDAP.Hosts = []string{"ldap_1.example.com"}
+		Expect(validateLDAPHosts(connector)).NotTo(Succeed())
+		connector.LDAP.Hosts = []string{"ldap-1.example.com:99999"}
+		Expect(validateLDAPHosts(connector)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Probe the connectors of a dex server", func() {
+	It("should probe the connectors in parallel within the deadline", func() {
+		reachable, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+		defer reachable.Close()
+		// the connections are accepted by the kernel but never answered, the probes wait for their timeout
+		hanging, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+		defer hanging.Close()
+
+		previousTimeout, previousDeadline := connectorProbeTimeout, connectorProbesDeadline
+		defer func() {
+			connectorProbeTimeout, connectorProbesDeadline = previousTimeout, previousDeadline
+		}()
+		connectorProbeTimeout = 3 * time.Second
+		connectorProbesDeadline = time.Second
+
+		dexServer := &authv1alpha1.DexServer{}
+		for _, id := range []string{"my-oidc-1", "my-oidc-2", "my-oidc-3"} {
+			dexServer.Spec.Connectors = append(dexServer.Spec.Connectors, authv1alpha1.ConnectorSpec{
+				Id:   id,
+				Type: authv1alpha1.ConnectorTypeOIDC,
+				OIDC: authv1alpha1.OIDCConfigSpec{Issuer: "http://" + hanging.Addr().String()},
+			})
+		}
+		dexServer.Spec.Connectors = append(dexServer.Spec.Connectors, authv1alpha1.ConnectorSpec{
+			Id:   "my-ldap",
+			Type: authv1alpha1.ConnectorTypeLDAP,
+			LDAP: authv1alpha1.LDAPConfigSpec{Host: reachable.Addr().String()},
+		})
+
+		start := time.Now()
+		healthy := probeConnectors(dexServer, context.TODO())
+		// sequential probes would take a timeout for each hanging connector
+		Expect(time.Since(start)).To(BeNumerically("<", connectorProbeTimeout))
+		Expect(healthy).To(Equal(map[string]bool{"my-oidc-1": false, "my-oidc-2": false, "my-oidc-3": false, "my-ldap": true}))
+
+		By("reporting the health of the connectors in their order", func() {
+			Expect(dexServer.Status.ConnectorHealth).To(HaveLen(4))
+			for i, status := range dexServer.Status.ConnectorHealth {
+				Expect(status.Id).To(Equal(dexServer.Spec.Connectors[i].Id))
+			}
+			Expect(dexServer.Status.ConnectorHealth[0].Message).To(ContainSubstring("deadline exceeded"))
+			Expect(dexServer.Status.ConnectorHealth[3].Healthy).To(BeTrue())
+			Expect(dexServer.Status.ConnectorHealth[3].Message).To(BeEmpty())
+		})
+	})
+	It("should list the unhealthy connectors last, or hide them", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		dexServer.Spec.OAuth2.PasswordConnector = "local"
+		connectors := []DexConnectorSpec{{Id: "down"}, {Id: "local"}, {Id: "up"}}
+		healthy := map[string]bool{"up": true}
+		Expect(applyConnectorFailover(dexServer, connectors, healthy)).To(Equal([]DexConnectorSpec{{Id: "up"}, {Id: "down"}, {Id: "local"}}))
+
+		dexServer.Spec.ConnectorFailover.Policy = authv1alpha1.ConnectorFailoverPolicyHide
+		// the password connector is kept for the password grant
+		Expect(applyConnectorFailover(dexServer, connectors, healthy)).To(Equal([]DexConnectorSpec{{Id: "up"}, {Id: "local"}}))
+		By("keeping all the connectors when none is healthy", func() {
+			Expect(applyConnectorFailover(dexServer, connectors, map[string]bool{})).To(Equal(connectors))
+		})
+	})
+})
+
+func portOf(addr net.Addr) string {
+	_, port, _ := net.SplitHostPort(addr.String())
+	return port
+}
@@ -0,0 +1,149 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Destinations of dex that never go through the proxy of the connectors: the Kubernetes API of the kubernetes
+// storage, and the services of the cluster
+var defaultNoProxy = []string{"localhost", "127.0.0.1", ".svc", ".cluster.local"}
+
+// Get the hosts the connector sends its HTTP requests to
+func getConnectorHTTPHosts(connector authv1alpha1.ConnectorSpec) []string {
+	switch connector.Type {
+	case authv1alpha1.ConnectorTypeBitbucketCloud:
+		return []string{"bitbucket.org", "api.bitbucket.org"}
+	case authv1alpha1.ConnectorTypeGitea:
+		if baseURL, err := url.Parse(connector.Gitea.BaseURL); err == nil && baseURL.Hostname() != "" {
+			return []string{baseURL.Hostname()}
+		}
+	case authv1alpha1.ConnectorTypeGitHub:
+		if connector.GitHub.HostName != "" {
+			return []string{connector.GitHub.HostName}
+		}
+		return []string{"github.com", "api.github.com"}
+	case authv1alpha1.ConnectorTypeGoogle:
+		return []string{"accounts.google.com", "oauth2.googleapis.com", "openidconnect.googleapis.com", "admin.googleapis.com"}
+	case authv1alpha1.ConnectorTypeMicrosoft:
+		return []string{"login.microsoftonline.com", "graph.microsoft.com"}
+	case authv1alpha1.ConnectorTypeOIDC:
+		if issuer, err := url.Parse(connector.OIDC.Issuer); err == nil && issuer.Hostname() != "" {
+			return []string{issuer.Hostname()}
+		}
+	case authv1alpha1.ConnectorTypeOpenShift:
+		// the OAuth server is discovered from the API server
+		if issuer, err := url.Parse(getOpenShiftIssuer(connector)); err == nil && issuer.Hostname() != "" {
+			return []string{issuer.Hostname()}
+		}
+	}
+	return nil
+}
+
+// Check the proxy of a connector is a URL dex can connect through, see ConnectorProxySpec
+func validateConnectorProxy(connector authv1alpha1.ConnectorSpec) error {
+	if connector.Type == authv1alpha1.ConnectorTypeLDAP {
+		return fmt.Errorf("the LDAP connectors don't support a proxy, dex connects to the LDAP servers directly")
+	}
+	if connector.Type == authv1alpha1.ConnectorTypeAuthProxy {
+		return fmt.Errorf("the authproxy connectors don't support a proxy, dex makes no requests for them")
+	}
+	if connector.Type == authv1alpha1.ConnectorTypeMockCallback || connector.Type == authv1alpha1.ConnectorTypeMockPassword {
+		return fmt.Errorf("the mock connectors don't support a proxy, dex makes no requests for them")
+	}
+	if connector.Type == authv1alpha1.ConnectorTypeRaw {
+		return fmt.Errorf("the raw connectors don't support a proxy, the hosts of their identity provider are not known")
+	}
+	proxy, err := url.Parse(connector.Proxy.URL)
+	if err != nil {
+		return fmt.Errorf("the proxy URL is invalid: %s", err.Error())
+	}
+	switch {
+	case proxy.Scheme != "http" && proxy.Scheme != "https" && proxy.Scheme != "socks5":
+		return fmt.Errorf("the proxy URL %s must have the http, https or socks5 scheme", connector.Proxy.URL)
+	case proxy.Host == "":
+		return fmt.Errorf("the proxy URL %s has no host", connector.Proxy.URL)
+	case proxy.User != nil:
+		return fmt.Errorf("the proxy URL can't hold credentials, the environment of dex is not secret")
+	}
+	return nil
+}
+
+// connectorProxies checks the proxies of the connectors rendered in the dex config can be set in the environment
+// of dex: all the connectors with a proxy use the same proxy, and the hosts of the connectors with a proxy are not
+// the hosts of the connectors without a proxy, which are excluded from the proxy
+type connectorProxies struct {
+	proxy string
+	// proxy of the hosts of the connectors, empty for the hosts excluded from the proxy
+	hosts map[string]string
+}
+
+func newConnectorProxies() *connectorProxies {
+	return &connectorProxies{hosts: map[string]string{}}
+}
+
+// Add the proxy of the connector, or return why it conflicts with the proxies of the previous connectors
+func (p *connectorProxies) add(connector authv1alpha1.ConnectorSpec) error {
+	proxy := ""
+	if connector.Proxy != nil {
+		if err := validateConnectorProxy(connector); err != nil {
+			return err
+		}
+		proxy = connector.Proxy.URL
+		if p.proxy != "" && p.proxy != proxy {
+			return fmt.Errorf("the proxy %s conflicts with the proxy %s of a previous connector, dex only supports one proxy", proxy, p.proxy)
+		}
+	}
+	hosts := getConnectorHTTPHosts(connector)
+	for _, host := range hosts {
+		if previous, ok := p.hosts[host]; ok && previous != proxy {
+			return fmt.Errorf("the host %s is reached through a proxy by a connector and directly by another connector", host)
+		}
+	}
+	if proxy != "" {
+		p.proxy = proxy
+	}
+	for _, host := range hosts {
+		p.hosts[host] = proxy
+	}
+	return nil
+}
+
+// Get the environment variables of dex sending the requests of the connectors through their proxy, none when no
+// connector has a proxy
+func getProxyEnvVariables(connectors []authv1alpha1.ConnectorSpec) []corev1.EnvVar {
+	proxies := newConnectorProxies()
+	for _, connector := range connectors {
+		// the conflicting connectors are rejected by getRenderedConnectors
+		_ = proxies.add(connector)
+	}
+	if proxies.proxy == "" {
+		return nil
+	}
+	noProxy := append([]string{}, defaultNoProxy...)
+	if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" {
+		noProxy = append(noProxy, host)
+	}
+	direct := []string{}
+	for host, proxy := range proxies.hosts {
+		if proxy == "" {
+			direct = append(direct, host)
+		}
+	}
+	sort.Strings(direct)
+	noProxy = append(noProxy, direct...)
+	return []corev1.EnvVar{
+		{Name: "HTTPS_PROXY", Value: proxies.proxy},
+		{Name: "HTTP_PROXY", Value: proxies.proxy},
+		{Name: "NO_PROXY", Value: strings.Join(noProxy, ",")},
+	}
+}
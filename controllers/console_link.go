@@ -0,0 +1,134 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Section of the application menu of the console listing the issuers of the DexServers
+	defaultConsoleLinkSection = "Single Sign-On"
+)
+
+var consoleLinkGVR = schema.GroupVersionResource{Group: "console.openshift.io", Version: "v1", Resource: "consolelinks"}
+
+// The ConsoleLinks are cluster scoped, their name includes the namespace of the DexServer
+func getConsoleLinkName(dexServer *authv1alpha1.DexServer) string {
+	return fmt.Sprintf("dex-%s-%s", dexServer.Namespace, dexServer.Name)
+}
+
+// Labels of the ConsoleLink of the DexServer. The ConsoleLink can't be owned by the DexServer, the labels tell it
+// apart from a ConsoleLink of the same name created by someone else.
+func getConsoleLinkLabels(dexServer *authv1alpha1.DexServer) map[string]string {
+	labels := getManagedLabels(dexServer, componentConsoleLink)
+	labels[DEXSERVER_NAMESPACE_LABEL] = dexServer.Namespace
+	return labels
+}
+
+// Get the ConsoleLink of spec.consoleLink pointing to the issuer
+func newConsoleLink(dexServer *authv1alpha1.DexServer, issuer string) *unstructured.Unstructured {
+	spec := dexServer.Spec.ConsoleLink
+	text := spec.Text
+	if text == "" {
+		text = dexServer.Name + " SSO"
+	}
+	location := spec.Location
+	if location == "" {
+		location = authv1alpha1.ConsoleLinkLocationApplicationMenu
+	}
+	linkSpec := map[string]interface{}{
+		"href":     issuer,
+		"text":     text,
+		"location": string(location),
+	}
+	if location == authv1alpha1.ConsoleLinkLocationApplicationMenu {
+		section := spec.Section
+		if section == "" {
+			section = defaultConsoleLinkSection
+		}
+		applicationMenu := map[string]interface{}{"section": section}
+		if spec.ImageURL != "" {
+			applicationMenu["imageURL"] = spec.ImageURL
+		}
+		linkSpec["applicationMenu"] = applicationMenu
+	}
+	link := &unstructured.Unstructured{Object: map[string]interface{}{"spec": linkSpec}}
+	link.SetAPIVersion("console.openshift.io/v1")
+	link.SetKind("ConsoleLink")
+	link.SetName(getConsoleLinkName(dexServer))
+	link.SetLabels(getConsoleLinkLabels(dexServer))
+	return link
+}
+
+// Create or update the ConsoleLink of the OpenShift web console pointing to the issuer, see spec.consoleLink. The
+// ConsoleLink is deleted when the link is disabled.
+func (r *DexServerReconciler) syncConsoleLink(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	name := getConsoleLinkName(dexServer)
+	log.Info("syncConsoleLink", "ConsoleLink.Name", name)
+
+	if !dexServer.Spec.ConsoleLink.Enabled {
+		return r.deleteConsoleLink(dexServer, ctx)
+	}
+	if !r.OpenShift {
+		return fmt.Errorf("spec.consoleLink is only supported on OpenShift")
+	}
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	link := newConsoleLink(dexServer, issuer)
+	client := r.DynamicClient.Resource(consoleLinkGVR)
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case kubeerrors.IsNotFound(err):
+		log.Info("Creating the ConsoleLink", "ConsoleLink.Name", name)
+		_, err = client.Create(ctx, link, metav1.CreateOptions{DryRun: r.dryRun})
+		return errors.Wrap(err, "error creating the ConsoleLink")
+	case err != nil:
+		return errors.Wrap(err, "error getting the ConsoleLink")
+	}
+	if existing.GetLabels()[DEXSERVER_NAMESPACE_LABEL] != dexServer.Namespace || existing.GetLabels()[INSTANCE_LABEL] != dexServer.Name {
+		return fmt.Errorf("ConsoleLink %s already exists and is not managed by the DexServer", name)
+	}
+	existing.Object["spec"] = link.Object["spec"]
+	existing.SetLabels(link.GetLabels())
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{DryRun: r.dryRun})
+	return errors.Wrap(err, "error updating the ConsoleLink")
+}
+
+// Delete the ConsoleLink of the DexServer, unless it is not managed by the DexServer
+func (r *DexServerReconciler) deleteConsoleLink(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	if !r.OpenShift {
+		return nil
+	}
+	client := r.DynamicClient.Resource(consoleLinkGVR)
+	existing, err := client.Get(ctx, getConsoleLinkName(dexServer), metav1.GetOptions{})
+	switch {
+	case kubeerrors.IsNotFound(err):
+		// also returned when the console is not installed
+		return nil
+	case err != nil:
+		return errors.Wrap(err, "error getting the ConsoleLink")
+	}
+	if existing.GetLabels()[DEXSERVER_NAMESPACE_LABEL] != dexServer.Namespace || existing.GetLabels()[INSTANCE_LABEL] != dexServer.Name {
+		return nil
+	}
+	ctrllog.FromContext(ctx).Info("Deleting the ConsoleLink", "ConsoleLink.Name", existing.GetName())
+	err = client.Delete(ctx, existing.GetName(), metav1.DeleteOptions{DryRun: r.dryRun})
+	if err != nil && !kubeerrors.IsNotFound(err) {
+		return errors.Wrap(err, "error deleting the ConsoleLink")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Link the issuer in the OpenShift web console", func() {
+	It("should point a ConsoleLink to the issuer", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-dexserver", Namespace: "my-console-ns"},
+			Spec:       authv1alpha1.DexServerSpec{ConsoleLink: authv1alpha1.ConsoleLinkSpec{Enabled: true}},
+		}
+		link := newConsoleLink(dexServer, "https://console.testhost.com")
+		Expect(link.GetName()).To(Equal("dex-my-console-ns-my-dexserver"))
+		Expect(link.GetLabels()).To(HaveKeyWithValue(DEXSERVER_NAMESPACE_LABEL, "my-console-ns"))
+		spec := link.Object["spec"].(map[string]interface{})
+		Expect(spec).To(HaveKeyWithValue("href", "https://console.testhost.com"))
+		Expect(spec).To(HaveKeyWithValue("text", "my-dexserver SSO"))
+		Expect(spec).To(HaveKeyWithValue("location", "ApplicationMenu"))
+		Expect(spec["applicationMenu"]).To(HaveKeyWithValue("section", "Single Sign-On"))
+
+		By("linking from another menu", func() {
+			dexServer.Spec.ConsoleLink.Location = authv1alpha1.ConsoleLinkLocationHelpMenu
+			dexServer.Spec.ConsoleLink.Text = "Corporate login"
+			spec := newConsoleLink(dexServer, "https://console.testhost.com").Object["spec"].(map[string]interface{})
+			Expect(spec).To(HaveKeyWithValue("text", "Corporate login"))
+			Expect(spec).To(HaveKeyWithValue("location", "HelpMenu"))
+			Expect(spec).ToNot(HaveKey("applicationMenu"))
+		})
+	})
+})
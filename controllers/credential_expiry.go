@@ -0,0 +1,198 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Certificates that are not renewed by the operator or the cluster are reported in a warning Event when they expire
+// within this window
+var credentialExpiryWarningWindow = time.Hour * 24 * 30
+
+// credentialExpiry is the expiry of each certificate used by a DexServer, as reported in status.credentialExpiry
+var credentialExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "dex_operator_credential_expiry_timestamp_seconds",
+	Help: "Time the certificates used by the DexServer expire, in seconds since the epoch",
+}, []string{"namespace", "name", "credential"})
+
+func init() {
+	metrics.Registry.MustRegister(credentialExpiry)
+}
+
+// Get the earliest expiry of the certificates of a PEM bundle
+func getCertificateExpiry(bundle []byte) (time.Time, error) {
+	var notAfter time.Time
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return notAfter, err
+		}
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	if notAfter.IsZero() {
+		return notAfter, fmt.Errorf("no certificate found")
+	}
+	return notAfter, nil
+}
+
+// Check that a PEM bundle holds certificates and that they have all expired
+func isCertificateBundleExpired(bundle []byte) bool {
+	expired := false
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return false
+		}
+		if time.Now().Before(cert.NotAfter) {
+			return false
+		}
+		expired = true
+	}
+	return expired
+}
+
+// A certificate used by the dex server, and where to read it from
+type credentialSource struct {
+	name       string
+	secretName string
+	key        string
+	data       []byte
+	renewed    bool
+}
+
+func getCredentialSources(dexServer *authv1alpha1.DexServer) []credentialSource {
+	sources := []credentialSource{
+		// the web certificate is renewed by the OpenShift service CA, or by syncServingCertSecret
+		{name: "web", secretName: dexServer.Name + SECRET_WEB_TLS_SUFFIX, key: corev1.TLSCertKey, renewed: true},
+		{name: "grpc-ca", secretName: SECRET_MTLS_NAME, key: "ca.crt", renewed: true},
+		{name: "grpc-server", secretName: SECRET_MTLS_NAME, key: "tls.crt", renewed: true},
+		{name: "grpc-client", secretName: SECRET_MTLS_NAME, key: "client.crt", renewed: true},
+	}
+	for _, connector := range dexServer.Spec.Connectors {
+		var rootCARef corev1.SecretReference
+		var rootCAData []byte
+		switch connector.Type {
+		case authv1alpha1.ConnectorTypeGitHub:
+			rootCARef, rootCAData = connector.GitHub.RootCARef, connector.GitHub.RootCAData
+		case authv1alpha1.ConnectorTypeLDAP:
+			rootCARef, rootCAData = connector.LDAP.RootCARef, connector.LDAP.RootCAData
+		default:
+			continue
+		}
+		name := "connector/" + connector.Id
+		switch {
+		case len(rootCAData) > 0:
+			sources = append(sources, credentialSource{name: name, data: rootCAData})
+		case rootCARef.Name != "":
+			// read from the copy in the dex server namespace
+			secretName := rootCARef.Namespace + "-" + rootCARef.Name
+			sources = append(sources, credentialSource{name: name, secretName: secretName, key: "ca.crt"})
+		}
+	}
+	return sources
+}
+
+// Report the expiry of the certificates used by the dex server in the status, in a metric, and in a warning Event and
+// a notification when some are about to expire. Certificates that can't be read yet, such as a serving certificate not yet issued,
+// are left out of the report.
+func (r *DexServerReconciler) reportCredentialExpiry(dexServer *authv1alpha1.DexServer, ctx context.Context) {
+	log := ctrllog.FromContext(ctx)
+	secrets := map[string]*corev1.Secret{}
+	report := []authv1alpha1.CredentialExpiryStatus{}
+	expiring := []string{}
+	expiringStatus := []authv1alpha1.CredentialExpiryStatus{}
+	for _, source := range getCredentialSources(dexServer) {
+		data := source.data
+		if source.secretName != "" {
+			secret, ok := secrets[source.secretName]
+			if !ok {
+				secret = &corev1.Secret{}
+				if err := r.Get(ctx, types.NamespacedName{Name: source.secretName, Namespace: dexServer.Namespace}, secret); err != nil {
+					log.V(1).Info("credential is not available", "Credential", source.name, "error", err.Error())
+					secret = nil
+				}
+				secrets[source.secretName] = secret
+			}
+			if secret == nil {
+				continue
+			}
+			data = secret.Data[source.key]
+			if source.name == "grpc-ca" {
+				// the previous CA is only trusted for the overlap window
+				data = firstPEMCertificate(data)
+			}
+		}
+		notAfter, err := getCertificateExpiry(data)
+		if err != nil {
+			log.Info("credential expiry could not be parsed", "Credential", source.name, "error", err.Error())
+			continue
+		}
+		status := authv1alpha1.CredentialExpiryStatus{
+			Name:       source.name,
+			SecretName: source.secretName,
+			NotAfter:   metav1.NewTime(notAfter),
+			Renewed:    source.renewed,
+		}
+		report = append(report, status)
+		// renewed certificates are only reported when their renewal is late
+		if (source.renewed && inCertRenewalWindow(notAfter)) ||
+			(!source.renewed && time.Now().Add(credentialExpiryWarningWindow).After(notAfter)) {
+			expiring = append(expiring, fmt.Sprintf("%s on %s", source.name, notAfter.UTC().Format(time.RFC3339)))
+			expiringStatus = append(expiringStatus, status)
+		}
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+
+	reported := map[string]bool{}
+	for _, status := range report {
+		reported[status.Name] = true
+		credentialExpiry.WithLabelValues(dexServer.Namespace, dexServer.Name, status.Name).Set(float64(status.NotAfter.Unix()))
+	}
+	for _, status := range dexServer.Status.CredentialExpiry {
+		if !reported[status.Name] {
+			credentialExpiry.DeleteLabelValues(dexServer.Namespace, dexServer.Name, status.Name)
+		}
+	}
+	// persisted with the Applied condition
+	dexServer.Status.CredentialExpiry = report
+
+	if len(expiring) > 0 {
+		log.Info("WARNING: credentials are about to expire", "Credentials", expiring)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(dexServer, corev1.EventTypeWarning, "CredentialExpiring", "Certificates about to expire: %s",
+				strings.Join(expiring, ", "))
+		}
+		r.Notifier.notifyCredentialsExpiring(dexServer, expiringStatus, ctx)
+	}
+}
+
+// Delete the metrics of the credentials of a deleted DexServer
+func deleteCredentialExpiryMetrics(dexServer *authv1alpha1.DexServer) {
+	for _, status := range dexServer.Status.CredentialExpiry {
+		credentialExpiry.DeleteLabelValues(dexServer.Namespace, dexServer.Name, status.Name)
+	}
+}
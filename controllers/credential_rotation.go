@@ -0,0 +1,147 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Time the credential of a connector secret last changed, set by the operator when it observes a change, or by
+	// the owner of the secret
+	CREDENTIAL_ROTATED_AT_ANNOTATION = "auth.identitatem.io/rotatedAt"
+	// Hash of the credential of a connector secret, compared on each reconcile to observe its rotation
+	CREDENTIAL_HASH_ANNOTATION = "auth.identitatem.io/credentialHash"
+)
+
+// credentialRotationDue is the time the credential of each connector with a rotateAfter must be rotated by, as
+// reported in status.credentialRotation
+var credentialRotationDue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "dex_operator_credential_rotation_due_timestamp_seconds",
+	Help: "Time the credentials of the connectors must be rotated by, in seconds since the epoch",
+}, []string{"namespace", "name", "connector"})
+
+func init() {
+	metrics.Registry.MustRegister(credentialRotationDue)
+}
+
+// Get the secret holding the credential of a connector and the key of the credential. The SAML connectors have no
+// credential.
+func getConnectorCredentialRef(connector authv1alpha1.ConnectorSpec) (corev1.SecretReference, string, bool) {
+	connectorSecret, ok := envVariableForConnector[connector.Type]
+	refs := getConnectorSecretRefs(connector)
+	if !ok || len(refs) == 0 {
+		return corev1.SecretReference{}, "", false
+	}
+	return refs[0], connectorSecret.SecretKey, true
+}
+
+// Get the time the credential of a secret last changed. The annotations of the secret are updated when the hash of
+// the credential changes, or on the first reconcile, where the credential is assumed to be as old as the secret
+// unless rotatedAt is already set. Returns whether the annotations were updated.
+func observeCredentialRotation(secret *corev1.Secret, key string, now time.Time) (time.Time, bool) {
+	hash := fmt.Sprintf("%x", sha256.Sum256(secret.Data[key]))
+	rotatedAt, err := time.Parse(time.RFC3339, secret.Annotations[CREDENTIAL_ROTATED_AT_ANNOTATION])
+	previousHash, observed := secret.Annotations[CREDENTIAL_HASH_ANNOTATION]
+	switch {
+	case observed && previousHash != hash:
+		rotatedAt = now
+	case err != nil:
+		rotatedAt = secret.CreationTimestamp.Time
+	case previousHash == hash:
+		return rotatedAt, false
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[CREDENTIAL_HASH_ANNOTATION] = hash
+	secret.Annotations[CREDENTIAL_ROTATED_AT_ANNOTATION] = rotatedAt.UTC().Format(time.RFC3339)
+	return rotatedAt, true
+}
+
+// Report the last rotation of the credential secrets of the connectors with a rotateAfter in the status, in a metric,
+// and in a warning Event when they are due. Secrets that don't exist yet are left out of the report.
+func (r *DexServerReconciler) reportCredentialRotation(dexServer *authv1alpha1.DexServer, ctx context.Context) {
+	log := ctrllog.FromContext(ctx)
+	now := time.Now()
+	report := []authv1alpha1.CredentialRotationStatus{}
+	due := []string{}
+	for _, connector := range dexServer.Spec.Connectors {
+		if connector.RotateAfter == nil {
+			continue
+		}
+		secretRef, key, ok := getConnectorCredentialRef(connector)
+		if !ok {
+			continue
+		}
+		if secretRef.Namespace == "" {
+			secretRef.Namespace = dexServer.Namespace
+		}
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretRef.Name, Namespace: secretRef.Namespace}, secret); err != nil {
+			log.V(1).Info("credential is not available", "Connector", connector.Id, "error", err.Error())
+			continue
+		}
+		rotatedAt, updated := observeCredentialRotation(secret, key, now)
+		if updated {
+			if err := r.Update(ctx, secret); err != nil {
+				// observed again on the next reconcile
+				log.Error(err, "Error updating the rotation annotations of the secret", "name", secret.Name)
+			}
+		}
+		rotateBy := rotatedAt.Add(connector.RotateAfter.Duration)
+		report = append(report, authv1alpha1.CredentialRotationStatus{
+			ConnectorId: connector.Id,
+			SecretName:  secretRef.Namespace + "/" + secretRef.Name,
+			RotatedAt:   metav1.NewTime(rotatedAt),
+			RotateBy:    metav1.NewTime(rotateBy),
+		})
+		if now.After(rotateBy) {
+			due = append(due, fmt.Sprintf("%s/%s of connector %s since %s", secretRef.Namespace, secretRef.Name, connector.Id,
+				rotateBy.UTC().Format(time.RFC3339)))
+		}
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].ConnectorId < report[j].ConnectorId })
+
+	reported := map[string]bool{}
+	for _, status := range report {
+		reported[status.ConnectorId] = true
+		credentialRotationDue.WithLabelValues(dexServer.Namespace, dexServer.Name, status.ConnectorId).Set(float64(status.RotateBy.Unix()))
+	}
+	for _, status := range dexServer.Status.CredentialRotation {
+		if !reported[status.ConnectorId] {
+			credentialRotationDue.DeleteLabelValues(dexServer.Namespace, dexServer.Name, status.ConnectorId)
+		}
+	}
+	// persisted with the Applied condition
+	dexServer.Status.CredentialRotation = report
+
+	if len(due) > 0 {
+		log.Info("WARNING: connector credentials are due for rotation", "Credentials", due)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(dexServer, corev1.EventTypeWarning, "CredentialRotationDue", "Credentials due for rotation: %s",
+				strings.Join(due, ", "))
+		}
+	}
+}
+
+// Delete the metrics of the credential rotation of a deleted DexServer
+func deleteCredentialRotationMetrics(dexServer *authv1alpha1.DexServer) {
+	for _, status := range dexServer.Status.CredentialRotation {
+		credentialRotationDue.DeleteLabelValues(dexServer.Namespace, dexServer.Name, status.ConnectorId)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Remind to rotate the connector credentials", func() {
+	It("should get the credential of the connectors", func() {
+		connector := authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeLDAP,
+			LDAP: authv1alpha1.LDAPConfigSpec{
+				BindPWRef: corev1.SecretReference{Name: "my-bind-pw"},
+				RootCARef: corev1.SecretReference{Name: "my-root-ca"},
+			},
+		}
+		ref, key, ok := getConnectorCredentialRef(connector)
+		Expect(ok).To(BeTrue())
+		Expect(ref.Name).To(Equal("my-bind-pw"))
+		Expect(key).To(Equal("bindPW"))
+
+		_, _, ok = getConnectorCredentialRef(authv1alpha1.ConnectorSpec{Type: authv1alpha1.ConnectorTypeSAML})
+		Expect(ok).To(BeFalse())
+	})
+	It("should observe the rotation of a credential", func() {
+		created := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+		now := created.Add(90 * 24 * time.Hour)
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-client-secret", CreationTimestamp: metav1.NewTime(created)},
+			Data:       map[string][]byte{"clientSecret": []byte("my-secret")},
+		}
+
+		rotatedAt, updated := observeCredentialRotation(secret, "clientSecret", now)
+		Expect(updated).To(BeTrue())
+		Expect(rotatedAt).To(Equal(created))
+		Expect(secret.Annotations).To(HaveKey(CREDENTIAL_HASH_ANNOTATION))
+
+		By("keeping the time of an unchanged credential", func() {
+			rotatedAt, updated := observeCredentialRotation(secret, "clientSecret", now.Add(time.Hour))
+			Expect(updated).To(BeFalse())
+			Expect(rotatedAt).To(Equal(created))
+		})
+		By("observing a new credential", func() {
+			secret.Data["clientSecret"] = []byte("my-new-secret")
+			rotatedAt, updated := observeCredentialRotation(secret, "clientSecret", now)
+			Expect(updated).To(BeTrue())
+			Expect(rotatedAt).To(Equal(now))
+			Expect(secret.Annotations).To(HaveKeyWithValue(CREDENTIAL_ROTATED_AT_ANNOTATION, now.Format(time.RFC3339)))
+		})
+		By("keeping the rotation time set by the owner of the secret", func() {
+			secret.Annotations = map[string]string{CREDENTIAL_ROTATED_AT_ANNOTATION: "2021-05-01T00:00:00Z"}
+			rotatedAt, updated := observeCredentialRotation(secret, "clientSecret", now)
+			Expect(updated).To(BeTrue())
+			Expect(rotatedAt).To(Equal(time.Date(2021, time.May, 1, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+})
@@ -0,0 +1,214 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// ResourceQuota of the namespaces dedicated to a ClusterDexServer
+	DEDICATED_NAMESPACE_QUOTA_NAME = "dex-operator"
+	// NetworkPolicy of the dedicated namespaces only admitting the traffic from the namespace itself
+	DEDICATED_NAMESPACE_ISOLATION_POLICY_NAME = "dex-operator-isolation"
+	// NetworkPolicy of the dedicated namespaces admitting the users and the gRPC clients of dex from anywhere
+	DEDICATED_NAMESPACE_DEX_POLICY_NAME = "dex-operator-allow-dex"
+)
+
+// Get the labels of the namespace dedicated to a ClusterDexServer, the labels of the operator can't be overridden
+func getDedicatedNamespaceLabels(clusterDexServer *authv1alpha1.ClusterDexServer) map[string]string {
+	labels := map[string]string{}
+	for key, value := range clusterDexServer.Spec.Namespace.Labels {
+		labels[key] = value
+	}
+	labels[MANAGED_BY_LABEL] = MANAGED_BY_VALUE
+	labels[CLUSTER_DEXSERVER_LABEL] = clusterDexServer.Name
+	return labels
+}
+
+// Get the NetworkPolicies of the namespace dedicated to a ClusterDexServer. The pods of the namespace only admit
+// the traffic from the namespace, except the dex pods serving the logins and the gRPC API.
+func getDedicatedNamespaceNetworkPolicies(clusterDexServer *authv1alpha1.ClusterDexServer) []*networkingv1.NetworkPolicy {
+	namespace := clusterDexServer.Spec.TargetNamespace
+	return []*networkingv1.NetworkPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: DEDICATED_NAMESPACE_ISOLATION_POLICY_NAME, Namespace: namespace},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+				}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: DEDICATED_NAMESPACE_DEX_POLICY_NAME, Namespace: namespace},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": clusterDexServer.Name}},
+				Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		},
+	}
+}
+
+// Label the namespace dedicated to a ClusterDexServer, and apply its ResourceQuota and NetworkPolicies
+func (r *ClusterDexServerReconciler) syncDedicatedNamespace(clusterDexServer *authv1alpha1.ClusterDexServer, namespace *corev1.Namespace, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	if namespace.DeletionTimestamp != nil {
+		return fmt.Errorf("namespace %s is being deleted", namespace.Name)
+	}
+	if namespace.Labels == nil {
+		namespace.Labels = map[string]string{}
+	}
+	updated := false
+	for key, value := range getDedicatedNamespaceLabels(clusterDexServer) {
+		if namespace.Labels[key] != value {
+			namespace.Labels[key] = value
+			updated = true
+		}
+	}
+	if updated {
+		log.Info("Labeling the dedicated namespace", "Namespace.Name", namespace.Name)
+		if err := r.Update(ctx, namespace); err != nil {
+			return err
+		}
+	}
+
+	if err := r.syncDedicatedNamespaceQuota(clusterDexServer, ctx); err != nil {
+		return errors.Wrap(err, "error syncing the ResourceQuota of the namespace")
+	}
+	for _, policy := range getDedicatedNamespaceNetworkPolicies(clusterDexServer) {
+		if err := r.syncNetworkPolicy(clusterDexServer, policy, ctx); err != nil {
+			return errors.Wrapf(err, "error syncing the NetworkPolicy %s", policy.Name)
+		}
+	}
+	return nil
+}
+
+// Create, update or delete the ResourceQuota of spec.namespace.resourceQuota
+func (r *ClusterDexServerReconciler) syncDedicatedNamespaceQuota(clusterDexServer *authv1alpha1.ClusterDexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	hard := clusterDexServer.Spec.Namespace.ResourceQuota
+	quota := &corev1.ResourceQuota{}
+	err := r.Get(ctx, types.NamespacedName{Name: DEDICATED_NAMESPACE_QUOTA_NAME, Namespace: clusterDexServer.Spec.TargetNamespace}, quota)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		if len(hard) == 0 {
+			return nil
+		}
+		quota = &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      DEDICATED_NAMESPACE_QUOTA_NAME,
+				Namespace: clusterDexServer.Spec.TargetNamespace,
+				Labels:    map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE},
+			},
+			Spec: corev1.ResourceQuotaSpec{Hard: hard.DeepCopy()},
+		}
+		if err := ctrl.SetControllerReference(clusterDexServer, quota, r.Scheme); err != nil {
+			return err
+		}
+		log.Info("Creating the ResourceQuota of the dedicated namespace", "Namespace.Name", quota.Namespace)
+		return r.Create(ctx, quota)
+	case err != nil:
+		return err
+	}
+	if len(hard) == 0 {
+		log.Info("Deleting the ResourceQuota of the dedicated namespace", "Namespace.Name", quota.Namespace)
+		return client.IgnoreNotFound(r.Delete(ctx, quota))
+	}
+	if equality.Semantic.DeepEqual(quota.Spec.Hard, hard) {
+		return nil
+	}
+	quota.Spec.Hard = hard.DeepCopy()
+	log.Info("Updating the ResourceQuota of the dedicated namespace", "Namespace.Name", quota.Namespace)
+	return r.Update(ctx, quota)
+}
+
+// Create or update a NetworkPolicy of the dedicated namespace
+func (r *ClusterDexServerReconciler) syncNetworkPolicy(clusterDexServer *authv1alpha1.ClusterDexServer, policy *networkingv1.NetworkPolicy, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	existing := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(policy), existing)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		policy.Labels = map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE}
+		if err := ctrl.SetControllerReference(clusterDexServer, policy, r.Scheme); err != nil {
+			return err
+		}
+		log.Info("Creating the NetworkPolicy of the dedicated namespace", "NetworkPolicy.Name", policy.Name, "Namespace.Name", policy.Namespace)
+		return r.Create(ctx, policy)
+	case err != nil:
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, policy.Spec) {
+		return nil
+	}
+	existing.Spec = policy.Spec
+	log.Info("Updating the NetworkPolicy of the dedicated namespace", "NetworkPolicy.Name", policy.Name, "Namespace.Name", policy.Namespace)
+	return r.Update(ctx, existing)
+}
+
+// Release the target namespace when createNamespace is turned off: the namespace is left in place when the
+// ClusterDexServer is deleted, and its ResourceQuota and NetworkPolicies are deleted.
+func (r *ClusterDexServerReconciler) releaseTargetNamespace(clusterDexServer *authv1alpha1.ClusterDexServer, namespace *corev1.Namespace, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	if !metav1.IsControlledBy(namespace, clusterDexServer) {
+		return nil
+	}
+	objects := []client.Object{&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: DEDICATED_NAMESPACE_QUOTA_NAME, Namespace: namespace.Name}}}
+	for _, policy := range getDedicatedNamespaceNetworkPolicies(clusterDexServer) {
+		objects = append(objects, policy)
+	}
+	for _, obj := range objects {
+		if err := r.Delete(ctx, obj); err != nil && !kubeerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error deleting %s of the released namespace", obj.GetName())
+		}
+	}
+
+	ownerReferences := []metav1.OwnerReference{}
+	for _, ownerReference := range namespace.OwnerReferences {
+		if ownerReference.UID != clusterDexServer.UID {
+			ownerReferences = append(ownerReferences, ownerReference)
+		}
+	}
+	namespace.OwnerReferences = ownerReferences
+	delete(namespace.Labels, CLUSTER_DEXSERVER_LABEL)
+	log.Info("Releasing the dedicated namespace", "Namespace.Name", namespace.Name)
+	return r.Update(ctx, namespace)
+}
+
+// Delete the namespaces dedicated to the ClusterDexServer that are no longer its target namespace
+func (r *ClusterDexServerReconciler) deleteStaleNamespaces(clusterDexServer *authv1alpha1.ClusterDexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces, client.MatchingLabels{CLUSTER_DEXSERVER_LABEL: clusterDexServer.Name}); err != nil {
+		return err
+	}
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+		if namespace.Name == clusterDexServer.Spec.TargetNamespace || namespace.DeletionTimestamp != nil ||
+			!metav1.IsControlledBy(namespace, clusterDexServer) {
+			continue
+		}
+		log.Info("Deleting the dedicated namespace of a previous target namespace", "Namespace.Name", namespace.Name)
+		if err := r.Delete(ctx, namespace); err != nil && !kubeerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
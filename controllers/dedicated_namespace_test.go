@@ -0,0 +1,94 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Dedicate the target namespace to a ClusterDexServer", func() {
+	name := "my-dedicated-clusterdexserver"
+	namespace := "my-dedicated-target-ns"
+	reconcile := func() error {
+		_, err := rClusterDexServer.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKey{Name: name}})
+		return err
+	}
+
+	It("should create, isolate and release the dedicated namespace", func() {
+		clusterDexServer := &authv1alpha1.ClusterDexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: authv1alpha1.ClusterDexServerSpec{
+				TargetNamespace: namespace,
+				CreateNamespace: true,
+				Namespace: authv1alpha1.TargetNamespaceSpec{
+					Labels:        map[string]string{"tenant": "my-tenant", MANAGED_BY_LABEL: "someone-else"},
+					ResourceQuota: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+				},
+				DexServerSpec: authv1alpha1.DexServerSpec{Issuer: "https://dedicated.testhost.com"},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), clusterDexServer)).To(Succeed())
+		Expect(reconcile()).To(Succeed())
+
+		By("creating the labeled namespace owned by the ClusterDexServer", func() {
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: namespace}, ns)).To(Succeed())
+			Expect(metav1.IsControlledBy(ns, clusterDexServer)).To(BeTrue())
+			Expect(ns.Labels).To(HaveKeyWithValue("tenant", "my-tenant"))
+			Expect(ns.Labels).To(HaveKeyWithValue(MANAGED_BY_LABEL, MANAGED_BY_VALUE))
+			Expect(ns.Labels).To(HaveKeyWithValue(CLUSTER_DEXSERVER_LABEL, name))
+		})
+		By("applying the ResourceQuota and the NetworkPolicies", func() {
+			quota := &corev1.ResourceQuota{}
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: DEDICATED_NAMESPACE_QUOTA_NAME, Namespace: namespace}, quota)).To(Succeed())
+			Expect(quota.Spec.Hard.Pods().String()).To(Equal("10"))
+			for _, policyName := range []string{DEDICATED_NAMESPACE_ISOLATION_POLICY_NAME, DEDICATED_NAMESPACE_DEX_POLICY_NAME} {
+				policy := &networkingv1.NetworkPolicy{}
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: policyName, Namespace: namespace}, policy)).To(Succeed())
+				Expect(metav1.IsControlledBy(policy, clusterDexServer)).To(BeTrue())
+			}
+			policy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: DEDICATED_NAMESPACE_DEX_POLICY_NAME, Namespace: namespace}, policy)).To(Succeed())
+			Expect(policy.Spec.PodSelector.MatchLabels).To(HaveKeyWithValue("app", name))
+		})
+		By("deleting the ResourceQuota removed from the spec", func() {
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: name}, clusterDexServer)).To(Succeed())
+			clusterDexServer.Spec.Namespace.ResourceQuota = nil
+			Expect(k8sClient.Update(context.TODO(), clusterDexServer)).To(Succeed())
+			Expect(reconcile()).To(Succeed())
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DEDICATED_NAMESPACE_QUOTA_NAME, Namespace: namespace}, &corev1.ResourceQuota{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+		By("releasing the namespace when createNamespace is turned off", func() {
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: name}, clusterDexServer)).To(Succeed())
+			clusterDexServer.Spec.CreateNamespace = false
+			Expect(k8sClient.Update(context.TODO(), clusterDexServer)).To(Succeed())
+			Expect(reconcile()).To(Succeed())
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: namespace}, ns)).To(Succeed())
+			Expect(metav1.IsControlledBy(ns, clusterDexServer)).To(BeFalse())
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DEDICATED_NAMESPACE_ISOLATION_POLICY_NAME, Namespace: namespace}, &networkingv1.NetworkPolicy{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+		By("refusing to dedicate an existing namespace", func() {
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: name}, clusterDexServer)).To(Succeed())
+			clusterDexServer.Spec.CreateNamespace = true
+			Expect(k8sClient.Update(context.TODO(), clusterDexServer)).To(Succeed())
+			err := reconcile()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("is not dedicated to ClusterDexServer"))
+		})
+	})
+})
@@ -8,13 +8,33 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"time"
 
 	api "github.com/dexidp/dex/api/v2"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 )
 
+const (
+	// DefaultMaxConcurrentCalls is the number of calls in flight on a connection when Options.MaxConcurrentCalls is unset
+	DefaultMaxConcurrentCalls = 4
+	// Time given to the connection to be established
+	dialTimeout = 30 * time.Second
+)
+
+// Reconnection backoff of the connections to dex, so that a restarting dex server is not hit by every client at once
+var connectParams = grpc.ConnectParams{
+	Backoff: backoff.Config{
+		BaseDelay:  time.Second,
+		Multiplier: 1.6,
+		Jitter:     0.2,
+		MaxDelay:   30 * time.Second,
+	},
+	MinConnectTimeout: 5 * time.Second,
+}
+
 // Options keeps some configuration options for Dex client
 type Options struct {
 	// HostAndPort host name and port of gRPC server
@@ -25,6 +45,8 @@ type Options struct {
 	KeyBuffer *bytes.Buffer
 	// ClientCA self signed CA certificate for gRPC TLS connection
 	CABuffer *bytes.Buffer
+	// MaxConcurrentCalls limits the calls in flight on the connection, further calls wait for one to complete
+	MaxConcurrentCalls int
 }
 
 // APIClient represent a client wrapper for Dex
@@ -52,7 +74,19 @@ func NewClientPEM(opts *Options) (*APIClient, error) {
 	}
 	creds := credentials.NewTLS(clientTLSConfig)
 
-	conn, err := grpc.Dial(opts.HostAndPort, grpc.WithTransportCredentials(creds), grpc.WithBlock(), grpc.FailOnNonTempDialError(true))
+	maxConcurrentCalls := opts.MaxConcurrentCalls
+	if maxConcurrentCalls <= 0 {
+		maxConcurrentCalls = DefaultMaxConcurrentCalls
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, opts.HostAndPort,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		grpc.FailOnNonTempDialError(true),
+		grpc.WithConnectParams(connectParams),
+		grpc.WithUnaryInterceptor(limitConcurrentCalls(maxConcurrentCalls)))
 	if err != nil {
 		return nil, errors.Wrapf(err, "opening the gRPC connection with server %q", opts.HostAndPort)
 	}
@@ -153,3 +187,17 @@ func (c *APIClient) CloseConnection() error {
 	}
 	return nil
 }
+
+// limitConcurrentCalls returns an interceptor letting at most max calls in flight
+func limitConcurrentCalls(max int) grpc.UnaryClientInterceptor {
+	inFlight := make(chan struct{}, max)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		select {
+		case inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-inFlight }()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright Red Hat
+
+package dex
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// Pool shares the connections to the dex gRPC servers between reconciles, instead of opening a connection for
+// each of them. A connection is replaced when the credentials it was opened with change, and the replaced
+// connection is closed once the last reconcile using it released it.
+type Pool struct {
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+type pooledClient struct {
+	client          *APIClient
+	credentialsHash string
+	// Number of the callers of Get that did not release the client yet
+	refs int
+	// Set once the client is replaced, it is closed when refs drops to 0
+	replaced bool
+}
+
+// Get returns the connection to the server of opts, opened with newClient when there is none for these credentials.
+// The connection is opened without holding the lock of the pool, so that a slow server doesn't block the
+// reconciles of the other servers. release must be called once the caller no longer uses the connection.
+func (p *Pool) Get(opts *Options, newClient func(opts *Options) (*APIClient, error)) (client *APIClient, release func(), err error) {
+	h := sha256.New()
+	h.Write(opts.CABuffer.Bytes())
+	h.Write(opts.CrtBuffer.Bytes())
+	h.Write(opts.KeyBuffer.Bytes())
+	credentialsHash := fmt.Sprintf("%x", h.Sum(nil))
+
+	p.mu.Lock()
+	if pooled, ok := p.clients[opts.HostAndPort]; ok && pooled.credentialsHash == credentialsHash {
+		pooled.refs++
+		p.mu.Unlock()
+		return pooled.client, p.releaseFunc(pooled), nil
+	}
+	p.mu.Unlock()
+
+	client, err = newClient(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, ok := p.clients[opts.HostAndPort]; ok {
+		if pooled.credentialsHash == credentialsHash {
+			// another caller opened a connection with the same credentials in the meantime
+			client.CloseConnection()
+			pooled.refs++
+			return pooled.client, p.releaseFunc(pooled), nil
+		}
+		pooled.replaced = true
+		if pooled.refs == 0 {
+			pooled.client.CloseConnection()
+		}
+	}
+	if p.clients == nil {
+		p.clients = map[string]*pooledClient{}
+	}
+	pooled := &pooledClient{client: client, credentialsHash: credentialsHash, refs: 1}
+	p.clients[opts.HostAndPort] = pooled
+	return client, p.releaseFunc(pooled), nil
+}
+
+// releaseFunc returns the function releasing a client returned by Get, closing it when it was replaced and no
+// other caller uses it
+func (p *Pool) releaseFunc(pooled *pooledClient) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			pooled.refs--
+			if pooled.replaced && pooled.refs == 0 {
+				pooled.client.CloseConnection()
+			}
+		})
+	}
+}
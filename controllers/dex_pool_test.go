@@ -0,0 +1,111 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"bytes"
+	"sync"
+
+	dexapi "github.com/identitatem/dex-operator/controllers/dex"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+var _ = Describe("Share the connections to the dex servers between the reconciles", func() {
+	poolOptions := func(crt string) *dexapi.Options {
+		return &dexapi.Options{
+			HostAndPort: "dex-pool-test.svc:5557",
+			CABuffer:    bytes.NewBufferString("ca"),
+			CrtBuffer:   bytes.NewBufferString(crt),
+			KeyBuffer:   bytes.NewBufferString("key"),
+		}
+	}
+	// newLazyClient opens the connections without blocking, the tests only check their state
+	newLazyClient := func(opts *dexapi.Options) (*dexapi.APIClient, error) {
+		conn, err := grpc.Dial(opts.HostAndPort, grpc.WithInsecure())
+		if err != nil {
+			return nil, err
+		}
+		return &dexapi.APIClient{Cc: conn}, nil
+	}
+
+	It("should reuse the connection opened with the same credentials", func() {
+		pool := &dexapi.Pool{}
+		first, release, err := pool.Get(poolOptions("crt"), newLazyClient)
+		Expect(err).Should(BeNil())
+		release()
+		second, release, err := pool.Get(poolOptions("crt"), newLazyClient)
+		Expect(err).Should(BeNil())
+		defer release()
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(first.Cc.GetState()).ToNot(Equal(connectivity.Shutdown))
+	})
+	It("should close a replaced connection once the reconciles using it released it", func() {
+		pool := &dexapi.Pool{}
+		old, releaseOld, err := pool.Get(poolOptions("crt"), newLazyClient)
+		Expect(err).Should(BeNil())
+		rotated, releaseRotated, err := pool.Get(poolOptions("rotated crt"), newLazyClient)
+		Expect(err).Should(BeNil())
+		defer releaseRotated()
+		Expect(rotated).ToNot(BeIdenticalTo(old))
+		By("keeping the replaced connection open while it is used", func() {
+			Expect(old.Cc.GetState()).ToNot(Equal(connectivity.Shutdown))
+		})
+		By("closing the replaced connection once released", func() {
+			releaseOld()
+			// releasing twice must not release the connection of another reconcile
+			releaseOld()
+			Expect(old.Cc.GetState()).To(Equal(connectivity.Shutdown))
+			Expect(rotated.Cc.GetState()).ToNot(Equal(connectivity.Shutdown))
+		})
+		By("closing the replaced connection right away when it isn't used", func() {
+			releaseRotated()
+			_, release, err := pool.Get(poolOptions("crt"), newLazyClient)
+			Expect(err).Should(BeNil())
+			defer release()
+			Expect(rotated.Cc.GetState()).To(Equal(connectivity.Shutdown))
+		})
+	})
+	It("should not hold the pool while a connection is opened", func() {
+		pool := &dexapi.Pool{}
+		dialing := make(chan struct{})
+		unblock := make(chan struct{})
+		blockingClient := func(opts *dexapi.Options) (*dexapi.APIClient, error) {
+			close(dialing)
+			<-unblock
+			return newLazyClient(opts)
+		}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var blocked *dexapi.APIClient
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			var release func()
+			var err error
+			blocked, release, err = pool.Get(poolOptions("crt"), blockingClient)
+			Expect(err).Should(BeNil())
+			release()
+		}()
+		Eventually(dialing, 30).Should(BeClosed())
+
+		By("getting the connection of another server while the first one is opened", func() {
+			opts := poolOptions("crt")
+			opts.HostAndPort = "other-dex-pool-test.svc:5557"
+			other, release, err := pool.Get(opts, newLazyClient)
+			Expect(err).Should(BeNil())
+			release()
+			Expect(other.Cc.GetState()).ToNot(Equal(connectivity.Shutdown))
+		})
+		By("keeping a single connection when the same server is opened concurrently", func() {
+			concurrent, release, err := pool.Get(poolOptions("crt"), newLazyClient)
+			Expect(err).Should(BeNil())
+			defer release()
+			close(unblock)
+			wg.Wait()
+			Expect(blocked).To(BeIdenticalTo(concurrent))
+		})
+	})
+})
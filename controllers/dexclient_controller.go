@@ -20,6 +20,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -30,6 +31,7 @@ import (
 
 	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
 	dexapi "github.com/identitatem/dex-operator/controllers/dex"
+	"github.com/identitatem/dex-operator/controllers/tracing"
 )
 
 const (
@@ -42,6 +44,12 @@ const (
 type DexClientReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Connections to the dex servers shared between reconciles. A connection is opened for each reconcile when nil.
+	Pool *dexapi.Pool
+	// Limit of the calls in flight on a connection to a dex server, see dexapi.DefaultMaxConcurrentCalls
+	MaxConcurrentGRPCCalls int
+	// Number of DexClients reconciled in parallel
+	MaxConcurrentReconciles int
 }
 
 var DexapiNewClientPEM = dexapi.NewClientPEM
@@ -65,6 +73,8 @@ var DexapiNewClientPEM = dexapi.NewClientPEM
 func (r *DexClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := ctrllog.FromContext(ctx)
 	log.V(1).Info("Reconciling...")
+	ctx, span := tracing.Start(ctx, "DexClient.Reconcile", "namespace", req.Namespace, "name", req.Name)
+	defer span.End()
 
 	dexv1Client := &authv1alpha1.DexClient{}
 	if err := r.Get(ctx, req.NamespacedName, dexv1Client); err != nil {
@@ -134,12 +144,22 @@ func (r *DexClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// Fetch the mTLS client cert and create the grpc client
 	dexApiOptions := &dexapi.Options{
-		HostAndPort: fmt.Sprintf("%s.%s.%s%s", GRPC_SERVICE_NAME, dexv1Client.Namespace, "svc.cluster.local", ":5557"),
-		CABuffer:    bytes.NewBuffer(mTLSSecret.Data["ca.crt"]),
-		CrtBuffer:   bytes.NewBuffer(mTLSSecret.Data["client.crt"]),
-		KeyBuffer:   bytes.NewBuffer(mTLSSecret.Data["client.key"]),
+		HostAndPort:        fmt.Sprintf("%s.%s.%s%s", GRPC_SERVICE_NAME, dexv1Client.Namespace, "svc.cluster.local", ":5557"),
+		CABuffer:           bytes.NewBuffer(mTLSSecret.Data["ca.crt"]),
+		CrtBuffer:          bytes.NewBuffer(mTLSSecret.Data["client.crt"]),
+		KeyBuffer:          bytes.NewBuffer(mTLSSecret.Data["client.key"]),
+		MaxConcurrentCalls: r.MaxConcurrentGRPCCalls,
+	}
+	dexApiClient, release, err := r.getDexAPIClient(dexApiOptions)
+	if err != nil && hasPreviousMTLSCerts(mTLSSecret) {
+		// The dex server may still be rolling out a new certificate, the replicas that have not been
+		// replaced yet only accept the previous client credentials
+		log.Info("Retrying connection to gRPC server with the previous mTLS credentials", "client", dexv1Client.Name)
+		dexApiOptions.CABuffer = bytes.NewBuffer(mTLSSecret.Data[MTLS_PREVIOUS_CA_KEY])
+		dexApiOptions.CrtBuffer = bytes.NewBuffer(mTLSSecret.Data[MTLS_PREVIOUS_CLIENT_CRT_KEY])
+		dexApiOptions.KeyBuffer = bytes.NewBuffer(mTLSSecret.Data[MTLS_PREVIOUS_CLIENT_KEY_KEY])
+		dexApiClient, release, err = r.getDexAPIClient(dexApiOptions)
 	}
-	dexApiClient, err := DexapiNewClientPEM(dexApiOptions)
 	if err != nil {
 		log.Error(err, "Failed to create api client connection to gRPC server", "client", dexv1Client.Name)
 		cond := metav1.Condition{
@@ -154,7 +174,7 @@ func (r *DexClientReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	defer dexApiClient.CloseConnection()
+	defer release()
 
 	// If a deletionTimestamp exists this means the dex client is being deleted. Delete the oauth2client and remove the finalizer.
 	if dexv1Client.DeletionTimestamp != nil {
@@ -195,13 +215,18 @@ func (r *DexClientReconciler) CreateOAuth2Client(dexApiClient *dexapi.APIClient,
 	log.Info("Creating dex client", "name", dexv1Client.Name,
 		"redirectURIs", dexv1Client.Spec.RedirectURIs,
 		"TrustedPeers", dexv1Client.Spec.TrustedPeers,
-		"Public", dexv1Client.Spec.Public,
+		"Public", dexv1Client.Spec.IsPublic(),
 		"ClientID", dexv1Client.Spec.ClientID,
 		"LogoURL", dexv1Client.Spec.LogoURL,
 		"clientSecretRef", dexv1Client.Spec.ClientSecretRef.Name)
 
-	// read clientSecret from secret
-	dexclientclientSecret, err := r.getClientClientSecretFromRef(dexv1Client, ctx)
+	// read clientSecret from secret. Public clients are registered without a secret, they redeem their codes
+	// with PKCE, see ValidateDexClientSpec.
+	var dexclientclientSecret string
+	var err error
+	if !dexv1Client.Spec.IsPublic() {
+		dexclientclientSecret, err = r.getClientClientSecretFromRef(dexv1Client, ctx)
+	}
 
 	if err != nil {
 		log.Error(err, "Client create failed on client secret", "client", dexv1Client.Name)
@@ -220,9 +245,9 @@ func (r *DexClientReconciler) CreateOAuth2Client(dexApiClient *dexapi.APIClient,
 	// Implement dex auth client creation here
 	res, createClientError := dexApiClient.CreateClient(
 		ctx,
-		dexv1Client.Spec.RedirectURIs,
+		getRedirectURIs(dexv1Client),
 		dexv1Client.Spec.TrustedPeers,
-		dexv1Client.Spec.Public,
+		dexv1Client.Spec.IsPublic(),
 		dexv1Client.Name,
 		dexv1Client.Spec.ClientID,
 		dexv1Client.Spec.LogoURL,
@@ -282,9 +307,9 @@ func (r *DexClientReconciler) UpdateOAuth2Client(dexApiClient *dexapi.APIClient,
 	err := dexApiClient.UpdateClient(
 		ctx,
 		dexv1Client.Spec.ClientID,
-		dexv1Client.Spec.RedirectURIs,
+		getRedirectURIs(dexv1Client),
 		dexv1Client.Spec.TrustedPeers,
-		dexv1Client.Spec.Public,
+		dexv1Client.Spec.IsPublic(),
 		dexv1Client.Name,
 		dexv1Client.Spec.LogoURL,
 	)
@@ -375,6 +400,10 @@ func (r *DexClientReconciler) getHashForASecret(dexv1Client *authv1alpha1.DexCli
 // the client secret's hash to the Dex Client resource and comparing the stored hash with the newly computed hash
 func (r *DexClientReconciler) hasClientSecretBeenUpdated(dexv1Client *authv1alpha1.DexClient, ctx context.Context) (bool, error) {
 	log := ctrllog.FromContext(ctx)
+	if dexv1Client.Spec.IsPublic() {
+		// public clients have no secret
+		return false, nil
+	}
 
 	// Get hash for the client secret
 	dexClientSecretHash, err := r.getHashForASecret(dexv1Client, ctx)
@@ -435,6 +464,19 @@ func DexClientHasCondition(dexClient *authv1alpha1.DexClient, c metav1.Condition
 	return false
 }
 
+// getDexAPIClient returns the pooled connection to a dex server, or a new connection without a pool. release must be
+// called once the connection is no longer used.
+func (r *DexClientReconciler) getDexAPIClient(opts *dexapi.Options) (dexApiClient *dexapi.APIClient, release func(), err error) {
+	if r.Pool != nil {
+		return r.Pool.Get(opts, DexapiNewClientPEM)
+	}
+	dexApiClient, err = DexapiNewClientPEM(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dexApiClient, func() { dexApiClient.CloseConnection() }, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *DexClientReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
@@ -466,6 +508,7 @@ func (r *DexClientReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&authv1alpha1.DexClient{}, builder.WithPredicates(dexClientPredicate)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Owns(&corev1.Secret{}).
 		Watches(&source.Kind{Type: &corev1.Secret{}}, // Since the client secrets for Dex Clients are not generated by this controller, updates to them will not trigger the reconcile loop. We need map them to a resource (dex client) that is managed by this controller.
 			handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
@@ -504,11 +547,26 @@ func (r *DexClientReconciler) getMTLSSecret(m *authv1alpha1.DexClient, ctx conte
 	return resource, nil
 }
 
+// Get the redirect URIs the client is registered with. Dex accepts the device callback for the public clients without
+// redirect URIs, the device flow clients with redirect URIs must list it.
+func getRedirectURIs(dexv1Client *authv1alpha1.DexClient) []string {
+	redirectURIs := dexv1Client.Spec.RedirectURIs
+	if !dexv1Client.Spec.DeviceFlow || len(redirectURIs) == 0 {
+		return redirectURIs
+	}
+	for _, redirectURI := range redirectURIs {
+		if redirectURI == authv1alpha1.DeviceCallbackURI {
+			return redirectURIs
+		}
+	}
+	return append(append([]string{}, redirectURIs...), authv1alpha1.DeviceCallbackURI)
+}
+
 func (r *DexClientReconciler) getClientClientSecretFromRef(m *authv1alpha1.DexClient, ctx context.Context) (string, error) {
 	log := ctrllog.FromContext(ctx)
 	secretName := m.Spec.ClientSecretRef.Name
 	secretNamespace := m.Spec.ClientSecretRef.Namespace
-	log.Info("getClientClientSecretFromRef", "secretName", secretName, "secretNamespace", "secretNamespace")
+	log.Info("getClientClientSecretFromRef", "secretName", secretName, "secretNamespace", secretNamespace)
 
 	resource := &corev1.Secret{}
 	if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil {
@@ -518,9 +576,9 @@ func (r *DexClientReconciler) getClientClientSecretFromRef(m *authv1alpha1.DexCl
 	// Add the label "auth.identitatem.io/dex-client-secret" to the Dex Client so that we can watch for any updates to it
 	checkAndAddLabelToClientSecret(resource, r, ctx)
 
-	log.Info("retrieve clientSecret in ", "secretName", secretName, "secretNamespace", "secretNamespace")
+	log.Info("retrieve clientSecret in ", "secretName", secretName, "secretNamespace", secretNamespace)
 	if secret, ok := resource.Data["clientSecret"]; ok {
-		log.Info("found clientSecret in ", "secretName", secretName, "secretNamespace", "secretNamespace")
+		log.Info("found clientSecret in ", "secretName", secretName, "secretNamespace", secretNamespace)
 		return string(secret), nil
 	}
 	return "", fmt.Errorf("secret %s/%s doesn't contain the data clientSecret", secretNamespace, secretName)
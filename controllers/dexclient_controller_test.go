@@ -261,4 +261,87 @@ var _ = Describe("Process DexClient CR", func() {
 			DexapiNewClientPEM = dexapi.NewClientPEM
 		})
 	})
+
+	It("should default and validate the redirect URIs of public clients", func() {
+		dexClient := &authv1alpha1.DexClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "public-client", Namespace: MyDexClientNamespace},
+			Spec: authv1alpha1.DexClientSpec{
+				ClientID: "public-client",
+				Public:   true,
+				RedirectURIs: []string{
+					"127.0.0.1:8000/callback",
+					"app.example.com/callback",
+					"com.example.app:/callback",
+				},
+			},
+		}
+		By("defaulting the schemes", func() {
+			dexClient.Default()
+			Expect(dexClient.Spec.RedirectURIs).To(Equal([]string{
+				"http://127.0.0.1:8000/callback",
+				"https://app.example.com/callback",
+				"com.example.app:/callback",
+			}))
+			Expect(dexClient.ValidateCreate()).To(Succeed())
+		})
+		By("rejecting a secret", func() {
+			invalid := dexClient.DeepCopy()
+			invalid.Spec.ClientSecretRef.Name = "public-client-secret"
+			Expect(invalid.ValidateCreate()).NotTo(Succeed())
+		})
+		By("rejecting the redirect URIs of web apps", func() {
+			for _, redirectURI := range []string{"http://app.example.com/callback", "myapp:/callback", "https://app.example.com/#callback"} {
+				invalid := dexClient.DeepCopy()
+				invalid.Spec.RedirectURIs = []string{redirectURI}
+				Expect(invalid.ValidateCreate()).NotTo(Succeed(), redirectURI)
+			}
+		})
+		By("allowing any absolute URI for confidential clients", func() {
+			confidential := dexClient.DeepCopy()
+			confidential.Spec.Public = false
+			confidential.Spec.RedirectURIs = []string{"http://app.example.com/callback"}
+			Expect(confidential.ValidateCreate()).To(Succeed())
+		})
+	})
+
+	It("should register the device flow clients as public clients with the device callback", func() {
+		dexClient := &authv1alpha1.DexClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-client", Namespace: MyDexClientNamespace},
+			Spec: authv1alpha1.DexClientSpec{
+				ClientID:   "device-client",
+				DeviceFlow: true,
+			},
+		}
+		Expect(dexClient.Spec.IsPublic()).To(BeTrue())
+		By("relying on the device callback dex accepts for the public clients without redirect URIs", func() {
+			Expect(getRedirectURIs(dexClient)).To(BeEmpty())
+		})
+		By("adding the device callback to the redirect URIs", func() {
+			dexClient.Spec.RedirectURIs = []string{"http://localhost:8000"}
+			Expect(getRedirectURIs(dexClient)).To(Equal([]string{"http://localhost:8000", authv1alpha1.DeviceCallbackURI}))
+			Expect(dexClient.Spec.RedirectURIs).To(Equal([]string{"http://localhost:8000"}))
+		})
+		By("rejecting a secret", func() {
+			invalid := dexClient.DeepCopy()
+			invalid.Spec.ClientSecretRef.Name = "device-client-secret"
+			Expect(invalid.ValidateCreate()).NotTo(Succeed())
+		})
+	})
+
+	It("should validate the trusted peers", func() {
+		dexClient := &authv1alpha1.DexClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: MyDexClientNamespace},
+			Spec: authv1alpha1.DexClientSpec{
+				ClientID:     "frontend",
+				RedirectURIs: []string{"https://frontend.example.com/callback"},
+				TrustedPeers: []string{"gateway", "backend"},
+			},
+		}
+		Expect(dexClient.ValidateCreate()).To(Succeed())
+		for _, trustedPeers := range [][]string{{""}, {"frontend"}, {"gateway", "gateway"}} {
+			invalid := dexClient.DeepCopy()
+			invalid.Spec.TrustedPeers = trustedPeers
+			Expect(invalid.ValidateCreate()).NotTo(Succeed(), "%v", trustedPeers)
+		}
+	})
 })
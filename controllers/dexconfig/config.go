@@ -0,0 +1,307 @@
+// Copyright Red Hat
+
+// Package dexconfig validates a dex config.yaml the way dex loads it on startup. The configuration structs are
+// adapted from the dex v2.30 configuration loader (github.com/dexidp/dex cmd/dex/config.go and the connector
+// packages, Apache License 2.0), without depending on the dex server packages.
+package dexconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// Config is the config format for the main application.
+type Config struct {
+	Issuer    string    `json:"issuer"`
+	Storage   Storage   `json:"storage"`
+	Web       Web       `json:"web"`
+	Telemetry Telemetry `json:"telemetry"`
+	OAuth2    OAuth2    `json:"oauth2"`
+	GRPC      GRPC      `json:"grpc"`
+	Expiry    Expiry    `json:"expiry"`
+	Logger    Logger    `json:"logger"`
+
+	Frontend WebConfig `json:"frontend"`
+
+	// StaticConnectors are user defined connectors specified in the ConfigMap
+	StaticConnectors []Connector `json:"connectors"`
+
+	// StaticClients cause the server to use this list of clients rather than
+	// querying the storage.
+	StaticClients []json.RawMessage `json:"staticClients"`
+
+	// If enabled, the server will maintain a list of passwords which can be used
+	// to identify a user.
+	EnablePasswordDB bool `json:"enablePasswordDB"`
+
+	// StaticPasswords cause the server use this list of passwords rather than
+	// querying the storage.
+	StaticPasswords []json.RawMessage `json:"staticPasswords"`
+}
+
+// OAuth2 describes enabled OAuth2 extensions.
+type OAuth2 struct {
+	// list of allowed grant types,
+	// defaults to all supported types
+	GrantTypes    []string `json:"grantTypes"`
+	ResponseTypes []string `json:"responseTypes"`
+	// If specified, do not prompt the user to approve client authorization. The
+	// act of logging in implies authorization.
+	SkipApprovalScreen bool `json:"skipApprovalScreen"`
+	// If specified, show the connector selection screen even if there's only one
+	AlwaysShowLoginScreen bool `json:"alwaysShowLoginScreen"`
+	// This is the connector that can be used for password grant
+	PasswordConnector string `json:"passwordConnector"`
+}
+
+// Web is the config format for the HTTP server.
+type Web struct {
+	HTTP           string   `json:"http"`
+	HTTPS          string   `json:"https"`
+	TLSCert        string   `json:"tlsCert"`
+	TLSKey         string   `json:"tlsKey"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// Read by the dex releases newer than v2.30, for the web and gRPC listeners
+	TLSMinVersion string `json:"tlsMinVersion"`
+}
+
+// Telemetry is the config format for telemetry including the HTTP server config.
+type Telemetry struct {
+	HTTP string `json:"http"`
+}
+
+// GRPC is the config for the gRPC API.
+type GRPC struct {
+	// The port to listen on.
+	Addr        string `json:"addr"`
+	TLSCert     string `json:"tlsCert"`
+	TLSKey      string `json:"tlsKey"`
+	TLSClientCA string `json:"tlsClientCA"`
+	Reflection  bool   `json:"reflection"`
+}
+
+// Storage holds app's storage configuration.
+type Storage struct {
+	Type   string          `json:"type"`
+	Config json.RawMessage `json:"config"`
+}
+
+// Expiry holds configuration for the validity period of components.
+type Expiry struct {
+	// SigningKeys defines the duration of time after which the SigningKeys will be rotated.
+	SigningKeys string `json:"signingKeys"`
+
+	// IdTokens defines the duration of time for which the IdTokens will be valid.
+	IDTokens string `json:"idTokens"`
+
+	// AuthRequests defines the duration of time for which the AuthRequests will be valid.
+	AuthRequests string `json:"authRequests"`
+
+	// DeviceRequests defines the duration of time for which the DeviceRequests will be valid.
+	DeviceRequests string `json:"deviceRequests"`
+
+	// RefreshTokens defines refresh tokens expiry policy
+	RefreshTokens RefreshToken `json:"refreshTokens"`
+}
+
+// RefreshToken holds the refresh tokens expiry policy.
+type RefreshToken struct {
+	DisableRotation   bool   `json:"disableRotation"`
+	ReuseInterval     string `json:"reuseInterval"`
+	AbsoluteLifetime  string `json:"absoluteLifetime"`
+	ValidIfNotUsedFor string `json:"validIfNotUsedFor"`
+}
+
+// Logger holds configuration required to customize logging for dex.
+type Logger struct {
+	// Level sets logging level severity.
+	Level string `json:"level"`
+
+	// Format specifies the format to be used for logging.
+	Format string `json:"format"`
+}
+
+// WebConfig holds the branding of the login page.
+type WebConfig struct {
+	Dir       string            `json:"dir"`
+	LogoURL   string            `json:"logoURL"`
+	Issuer    string            `json:"issuer"`
+	Theme     string            `json:"theme"`
+	IssuerURL string            `json:"issuerURL"`
+	Extra     map[string]string `json:"extra"`
+}
+
+// Connector is a magical type that can unmarshal YAML dynamically. The
+// Type field determines the connector type, which is then customized for Config.
+type Connector struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name"`
+	ID     string          `json:"id"`
+	Config json.RawMessage `json:"config"`
+}
+
+// The storages dex can be built with
+var storages = map[string]bool{
+	"etcd":       true,
+	"kubernetes": true,
+	"memory":     true,
+	"sqlite3":    true,
+	"postgres":   true,
+	"mysql":      true,
+}
+
+// Grant types supported by the dex server
+var grantTypes = map[string]bool{
+	"authorization_code": true,
+	"refresh_token":      true,
+	"implicit":           true,
+	"password":           true,
+	"urn:ietf:params:oauth:grant-type:device_code": true,
+}
+
+// Log levels and formats accepted by dex when it creates its logger
+var (
+	logLevels  = map[string]bool{"": true, "debug": true, "info": true, "error": true}
+	logFormats = map[string]bool{"": true, "text": true, "json": true}
+)
+
+// Load decodes a dex config.yaml and validates it. Unlike dex, the decoding is strict: a field dex does not know
+// is reported instead of being ignored.
+func Load(data []byte) (*Config, error) {
+	j, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parse config file: %v", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(j))
+	d.DisallowUnknownFields()
+	c := &Config{}
+	if err := d.Decode(c); err != nil {
+		return nil, fmt.Errorf("error unmarshaling JSON: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate the configuration, with the checks dex runs when it loads the configuration and starts the server
+func (c Config) Validate() error {
+	// Fast checks. Perform these first for a more responsive CLI.
+	checks := []struct {
+		bad    bool
+		errMsg string
+	}{
+		{c.Issuer == "", "no issuer specified in config file"},
+		{!c.EnablePasswordDB && len(c.StaticPasswords) != 0, "cannot specify static passwords without enabling password db"},
+		{c.Storage.Config == nil, "no storage supplied in config file"},
+		{c.Web.HTTP == "" && c.Web.HTTPS == "", "must supply a HTTP/HTTPS  address to listen on"},
+		{c.Web.HTTPS != "" && c.Web.TLSCert == "", "no cert specified for HTTPS"},
+		{c.Web.HTTPS != "" && c.Web.TLSKey == "", "no private key specified for HTTPS"},
+		{c.GRPC.TLSCert != "" && c.GRPC.Addr == "", "no address specified for gRPC"},
+		{c.GRPC.TLSKey != "" && c.GRPC.Addr == "", "no address specified for gRPC"},
+		{(c.GRPC.TLSCert == "") != (c.GRPC.TLSKey == ""), "must specific both a gRPC TLS cert and key"},
+		{c.GRPC.TLSCert == "" && c.GRPC.TLSClientCA != "", "cannot specify gRPC TLS client CA without a gRPC TLS cert"},
+	}
+
+	var checkErrors []string
+
+	for _, check := range checks {
+		if check.bad {
+			checkErrors = append(checkErrors, check.errMsg)
+		}
+	}
+
+	if u, err := url.Parse(c.Issuer); c.Issuer != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		checkErrors = append(checkErrors, fmt.Sprintf("invalid issuer URL %q", c.Issuer))
+	}
+	if !logLevels[strings.ToLower(c.Logger.Level)] {
+		checkErrors = append(checkErrors, fmt.Sprintf("log level is not one of the supported values (debug, info, error): %s", c.Logger.Level))
+	}
+	if !logFormats[strings.ToLower(c.Logger.Format)] {
+		checkErrors = append(checkErrors, fmt.Sprintf("log format is not one of the supported values (json, text): %s", c.Logger.Format))
+	}
+	if !storages[c.Storage.Type] {
+		checkErrors = append(checkErrors, fmt.Sprintf("unknown storage type %q", c.Storage.Type))
+	}
+	for _, grantType := range c.OAuth2.GrantTypes {
+		if !grantTypes[grantType] {
+			checkErrors = append(checkErrors, fmt.Sprintf("unsupported grant type %q", grantType))
+		}
+	}
+	for name, d := range map[string]string{
+		"expiry.signingKeys":                     c.Expiry.SigningKeys,
+		"expiry.idTokens":                        c.Expiry.IDTokens,
+		"expiry.authRequests":                    c.Expiry.AuthRequests,
+		"expiry.deviceRequests":                  c.Expiry.DeviceRequests,
+		"expiry.refreshTokens.reuseInterval":     c.Expiry.RefreshTokens.ReuseInterval,
+		"expiry.refreshTokens.absoluteLifetime":  c.Expiry.RefreshTokens.AbsoluteLifetime,
+		"expiry.refreshTokens.validIfNotUsedFor": c.Expiry.RefreshTokens.ValidIfNotUsedFor,
+	} {
+		if _, err := time.ParseDuration(d); d != "" && err != nil {
+			checkErrors = append(checkErrors, fmt.Sprintf("invalid duration %q for %s", d, name))
+		}
+	}
+
+	passwordConnectorFound := false
+	ids := map[string]bool{}
+	for _, conn := range c.StaticConnectors {
+		if conn.ID == "" || conn.Name == "" || conn.Type == "" {
+			checkErrors = append(checkErrors, fmt.Sprintf("invalid connector %q: ID, Type and Name fields are required for a connector", conn.ID))
+			continue
+		}
+		if ids[conn.ID] {
+			checkErrors = append(checkErrors, fmt.Sprintf("duplicate connector id %q", conn.ID))
+		}
+		ids[conn.ID] = true
+		if conn.ID == c.OAuth2.PasswordConnector {
+			passwordConnectorFound = true
+		}
+		if err := validateConnectorConfig(conn); err != nil {
+			checkErrors = append(checkErrors, fmt.Sprintf("connector %q: %v", conn.ID, err))
+		}
+	}
+	if c.OAuth2.PasswordConnector != "" && !passwordConnectorFound {
+		checkErrors = append(checkErrors, fmt.Sprintf("password connector %q not found", c.OAuth2.PasswordConnector))
+	}
+
+	if len(checkErrors) != 0 {
+		return fmt.Errorf("invalid Config:\n\t-\t%s", strings.Join(checkErrors, "\n\t-\t"))
+	}
+	return nil
+}
+
+// Decode the config of a connector like dex does, and report the fields that are set but unknown to dex. Dex
+// silently ignores them, which leaves the connector misconfigured. The config of the types only deployed as raw
+// connectors is not vendored, it is only checked to be an object.
+func validateConnectorConfig(conn Connector) error {
+	if rawConnectorTypes[conn.Type] {
+		var fields map[string]interface{}
+		if len(conn.Config) != 0 {
+			if err := json.Unmarshal(conn.Config, &fields); err != nil {
+				return fmt.Errorf("parse connector config: %v", err)
+			}
+		}
+		return nil
+	}
+	config, ok := connectorsConfig[conn.Type]
+	if !ok {
+		return fmt.Errorf("unknown connector type %q", conn.Type)
+	}
+	if len(conn.Config) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(conn.Config, config()); err != nil {
+		return fmt.Errorf("parse connector config: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(conn.Config, &fields); err != nil {
+		return fmt.Errorf("parse connector config: %v", err)
+	}
+	return checkIgnoredFields("config", fields, config())
+}
@@ -0,0 +1,346 @@
+// Copyright Red Hat
+
+package dexconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The connector configs, as decoded by dex for each connector type deployed by the operator
+var connectorsConfig = map[string]func() interface{}{
+	"authproxy":       func() interface{} { return new(AuthProxyConfig) },
+	"bitbucket-cloud": func() interface{} { return new(BitbucketCloudConfig) },
+	"gitea":           func() interface{} { return new(GiteaConfig) },
+	"github":          func() interface{} { return new(GitHubConfig) },
+	"google":          func() interface{} { return new(GoogleConfig) },
+	"ldap":            func() interface{} { return new(LDAPConfig) },
+	"microsoft":       func() interface{} { return new(MicrosoftConfig) },
+	"mockCallback":    func() interface{} { return new(MockCallbackConfig) },
+	"mockPassword":    func() interface{} { return new(MockPasswordConfig) },
+	"oidc":            func() interface{} { return new(OIDCConfig) },
+	"openshift":       func() interface{} { return new(OpenShiftConfig) },
+	"saml":            func() interface{} { return new(SAMLConfig) },
+}
+
+// The connector types of dex the operator only deploys as raw connectors, their config is not decoded
+var rawConnectorTypes = map[string]bool{
+	"atlassian-crowd": true,
+	"gitlab":          true,
+	"keystone":        true,
+	"linkedin":        true,
+}
+
+// IsConnectorType returns whether dex has connectors of the type, see the raw connectors
+func IsConnectorType(connectorType string) bool {
+	_, ok := connectorsConfig[connectorType]
+	return ok || rawConnectorTypes[connectorType]
+}
+
+// AuthProxyConfig holds the configuration parameters for a connector which requires no interaction with the user and
+// relies on a proxy to authenticate. The email and group headers are read by the dex releases newer than v2.30.
+type AuthProxyConfig struct {
+	UserHeader  string   `json:"userHeader"`
+	EmailHeader string   `json:"emailHeader"`
+	GroupHeader string   `json:"groupHeader"`
+	Groups      []string `json:"groups"`
+}
+
+// BitbucketCloudConfig holds configuration options for bitbucket cloud logins.
+type BitbucketCloudConfig struct {
+	ClientID          string   `json:"clientID"`
+	ClientSecret      string   `json:"clientSecret"`
+	RedirectURI       string   `json:"redirectURI"`
+	Teams             []string `json:"teams"`
+	IncludeTeamGroups bool     `json:"includeTeamGroups,omitempty"`
+}
+
+// GiteaConfig holds configuration options for gitea logins.
+type GiteaConfig struct {
+	BaseURL       string     `json:"baseURL"`
+	ClientID      string     `json:"clientID"`
+	ClientSecret  string     `json:"clientSecret"`
+	RedirectURI   string     `json:"redirectURI"`
+	Orgs          []GiteaOrg `json:"orgs"`
+	LoadAllGroups bool       `json:"loadAllGroups"`
+	UseLoginAsID  bool       `json:"useLoginAsID"`
+}
+
+// GiteaOrg holds org-team filters, in which teams are optional.
+type GiteaOrg struct {
+	Name  string   `json:"name"`
+	Teams []string `json:"teams,omitempty"`
+}
+
+// GitHubConfig holds configuration options for github logins.
+type GitHubConfig struct {
+	ClientID      string      `json:"clientID"`
+	ClientSecret  string      `json:"clientSecret"`
+	RedirectURI   string      `json:"redirectURI"`
+	Org           string      `json:"org"`
+	Orgs          []GitHubOrg `json:"orgs"`
+	HostName      string      `json:"hostName"`
+	RootCA        string      `json:"rootCA"`
+	TeamNameField string      `json:"teamNameField"`
+	LoadAllGroups bool        `json:"loadAllGroups"`
+	UseLoginAsID  bool        `json:"useLoginAsID"`
+
+	PreferredEmailDomain string `json:"preferredEmailDomain"`
+}
+
+// GitHubOrg holds org-team filters, in which teams are optional.
+type GitHubOrg struct {
+	Name  string   `json:"name"`
+	Teams []string `json:"teams,omitempty"`
+}
+
+// LDAPConfig holds configuration options for LDAP logins.
+type LDAPConfig struct {
+	Host               string `json:"host"`
+	InsecureNoSSL      bool   `json:"insecureNoSSL"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	StartTLS           bool   `json:"startTLS"`
+	RootCA             string `json:"rootCA"`
+	ClientCert         string `json:"clientCert"`
+	ClientKey          string `json:"clientKey"`
+	RootCAData         []byte `json:"rootCAData"`
+	BindDN             string `json:"bindDN"`
+	BindPW             string `json:"bindPW"`
+	UsernamePrompt     string `json:"usernamePrompt"`
+
+	UserSearch struct {
+		BaseDN                    string `json:"baseDN"`
+		Filter                    string `json:"filter"`
+		Username                  string `json:"username"`
+		Scope                     string `json:"scope"`
+		IDAttr                    string `json:"idAttr"`
+		EmailAttr                 string `json:"emailAttr"`
+		NameAttr                  string `json:"nameAttr"`
+		PreferredUsernameAttrAttr string `json:"preferredUsernameAttr"`
+		EmailSuffix               string `json:"emailSuffix"`
+	} `json:"userSearch"`
+
+	GroupSearch struct {
+		BaseDN       string            `json:"baseDN"`
+		Filter       string            `json:"filter"`
+		Scope        string            `json:"scope"`
+		UserAttr     string            `json:"userAttr"`
+		GroupAttr    string            `json:"groupAttr"`
+		UserMatchers []LDAPUserMatcher `json:"userMatchers"`
+		NameAttr     string            `json:"nameAttr"`
+	} `json:"groupSearch"`
+}
+
+// LDAPUserMatcher holds information about user and group matching.
+type LDAPUserMatcher struct {
+	UserAttr  string `json:"userAttr"`
+	GroupAttr string `json:"groupAttr"`
+}
+
+// GoogleConfig holds configuration options for google logins.
+type GoogleConfig struct {
+	ClientID               string   `json:"clientID"`
+	ClientSecret           string   `json:"clientSecret"`
+	RedirectURI            string   `json:"redirectURI"`
+	Scopes                 []string `json:"scopes"`
+	HostedDomains          []string `json:"hostedDomains"`
+	Groups                 []string `json:"groups"`
+	ServiceAccountFilePath string   `json:"serviceAccountFilePath"`
+	AdminEmail             string   `json:"adminEmail"`
+}
+
+// MicrosoftConfig holds configuration options for microsoft logins.
+type MicrosoftConfig struct {
+	ClientID             string   `json:"clientID"`
+	ClientSecret         string   `json:"clientSecret"`
+	RedirectURI          string   `json:"redirectURI"`
+	Tenant               string   `json:"tenant"`
+	OnlySecurityGroups   bool     `json:"onlySecurityGroups"`
+	Groups               []string `json:"groups"`
+	GroupNameFormat      string   `json:"groupNameFormat"`
+	UseGroupsAsWhitelist bool     `json:"useGroupsAsWhitelist"`
+	EmailToLowercase     bool     `json:"emailToLowercase"`
+	APIURL               string   `json:"apiURL"`
+	GraphURL             string   `json:"graphURL"`
+	PromptType           string   `json:"promptType"`
+	DomainHint           string   `json:"domainHint"`
+	Scopes               []string `json:"scopes"`
+}
+
+// MockCallbackConfig holds the configuration parameters for a connector which requires no interaction with the
+// user and logs in a fixed test user.
+type MockCallbackConfig struct{}
+
+// MockPasswordConfig holds the configuration parameters for a connector which logs in a fixed test user with a
+// static username and password.
+type MockPasswordConfig struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// OIDCConfig holds configuration options for OpenID Connect logins.
+type OIDCConfig struct {
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientID"`
+	ClientSecret string `json:"clientSecret"`
+	RedirectURI  string `json:"redirectURI"`
+
+	BasicAuthUnsupported *bool    `json:"basicAuthUnsupported"`
+	HostedDomains        []string `json:"hostedDomains"`
+	RootCAs              []string `json:"rootCAs"`
+	Scopes               []string `json:"scopes"`
+
+	InsecureSkipEmailVerified bool `json:"insecureSkipEmailVerified"`
+	InsecureEnableGroups      bool `json:"insecureEnableGroups"`
+	InsecureSkipVerify        bool `json:"insecureSkipVerify"`
+	GetUserInfo               bool `json:"getUserInfo"`
+
+	UserIDKey   string `json:"userIDKey"`
+	UserNameKey string `json:"userNameKey"`
+	PromptType  string `json:"promptType"`
+
+	OverrideClaimMapping bool `json:"overrideClaimMapping"`
+	ClaimMapping         struct {
+		PreferredUsernameKey string `json:"preferred_username"`
+		EmailKey             string `json:"email"`
+		GroupsKey            string `json:"groups"`
+	} `json:"claimMapping"`
+
+	ClaimMutations struct {
+		NewGroupFromClaims []NewGroupFromClaims `json:"newGroupFromClaims"`
+		FilterGroupClaims  struct {
+			GroupsFilter string `json:"groupsFilter"`
+		} `json:"filterGroupClaims"`
+	} `json:"claimModifications"`
+}
+
+// NewGroupFromClaims creates a new group from a list of claims and appends it to the list of existing groups.
+type NewGroupFromClaims struct {
+	Claims         []string `json:"claims"`
+	Delimiter      string   `json:"delimiter"`
+	ClearDelimiter bool     `json:"clearDelimiter"`
+	Prefix         string   `json:"prefix"`
+}
+
+// OpenShiftConfig holds configuration options for OpenShift logins.
+type OpenShiftConfig struct {
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientID"`
+	ClientSecret string   `json:"clientSecret"`
+	RedirectURI  string   `json:"redirectURI"`
+	Groups       []string `json:"groups"`
+	InsecureCA   bool     `json:"insecureCA"`
+	RootCA       string   `json:"rootCA"`
+}
+
+// SAMLConfig holds configuration options for SAML 2.0 logins.
+type SAMLConfig struct {
+	EntityIssuer string `json:"entityIssuer"`
+	SSOIssuer    string `json:"ssoIssuer"`
+	SSOURL       string `json:"ssoURL"`
+
+	CA     string `json:"ca"`
+	CAData []byte `json:"caData"`
+
+	InsecureSkipSignatureValidation bool `json:"insecureSkipSignatureValidation"`
+
+	RedirectURI string `json:"redirectURI"`
+
+	UsernameAttr  string   `json:"usernameAttr"`
+	EmailAttr     string   `json:"emailAttr"`
+	GroupsAttr    string   `json:"groupsAttr"`
+	GroupsDelim   string   `json:"groupsDelim"`
+	AllowedGroups []string `json:"allowedGroups"`
+	FilterGroups  bool     `json:"filterGroups"`
+
+	NameIDPolicyFormat string `json:"nameIDPolicyFormat"`
+}
+
+// Report the fields of a decoded config that dex ignores because they match no field of its config struct. Like
+// encoding/json, the field names are matched case-insensitively. Unset fields are not reported, as the operator
+// renders the fields of every connector type.
+func checkIgnoredFields(path string, fields map[string]interface{}, config interface{}) error {
+	t := reflect.TypeOf(config)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := fields[key]
+		field, ok := findField(t, key)
+		if !ok {
+			if !isZero(value) {
+				return fmt.Errorf("%s.%s is not a dex setting and would be ignored", path, key)
+			}
+			continue
+		}
+		if err := checkIgnoredValue(path+"."+key, value, field.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkIgnoredValue(path string, value interface{}, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if t.Kind() == reflect.Struct {
+			return checkIgnoredFields(path, v, reflect.New(t).Interface())
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i, item := range v {
+				if err := checkIgnoredValue(fmt.Sprintf("%s[%d]", path, i), item, t.Elem()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func isZero(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		for _, item := range v {
+			if !isZero(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
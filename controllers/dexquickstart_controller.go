@@ -0,0 +1,237 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/tracing"
+)
+
+const (
+	// Name of the DexQuickstart a DexServer is expanded from
+	DEX_QUICKSTART_LABEL = "auth.identitatem.io/dexquickstart"
+)
+
+// Display names of the connector of a DexQuickstart on the login page of dex
+var quickstartConnectorNames = map[authv1alpha1.ConnectorType]string{
+	authv1alpha1.ConnectorTypeGitHub:    "GitHub",
+	authv1alpha1.ConnectorTypeMicrosoft: "Microsoft",
+	authv1alpha1.ConnectorTypeOIDC:      "OpenID Connect",
+}
+
+// DexQuickstartReconciler reconciles a DexQuickstart object. The DexServer of a DexQuickstart is created with the
+// same name in its namespace and is reconciled by the DexServerReconciler like any other DexServer.
+type DexQuickstartReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexquickstarts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexquickstarts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexquickstarts/finalizers,verbs=update
+
+// Reconcile expands a DexQuickstart into a DexServer with a single connector, and reports the status of the
+// DexServer. The DexServer is owned by the DexQuickstart and is garbage collected with it.
+func (r *DexQuickstartReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	log.V(1).Info("Reconciling...")
+	ctx, span := tracing.Start(ctx, "DexQuickstart.Reconcile", "namespace", req.Namespace, "name", req.Name)
+	defer span.End()
+
+	dexQuickstart := &authv1alpha1.DexQuickstart{}
+	if err := r.Get(ctx, req.NamespacedName, dexQuickstart); err != nil {
+		log.Error(err, "failed to fetch DexQuickstart instance")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if dexQuickstart.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	dexServer, err := r.syncDexServer(dexQuickstart, ctx)
+	if err != nil {
+		log.Error(err, "failed to sync DexServer")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexQuickstartConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: "ConfigDexServerFailed",
+			Message: fmt.Sprintf("failed to sync DexServer. error: %s",
+				err.Error()),
+		}
+		if err := updateDexQuickstartStatusConditions(r.Client, dexQuickstart, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	dexQuickstart.Status.DexServer = &authv1alpha1.RelatedObjectReference{
+		Kind:      "DexServer",
+		Name:      dexServer.Name,
+		Namespace: dexServer.Namespace,
+	}
+	dexQuickstart.Status.Issuer = dexServer.Status.Issuer
+	conds := []metav1.Condition{{
+		Type:    authv1alpha1.DexQuickstartConditionTypeApplied,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: fmt.Sprintf("DexServer %s/%s is applied", dexServer.Namespace, dexServer.Name),
+	}}
+	// Report the state of the dex server itself
+	for _, condType := range []string{authv1alpha1.DexServerDeploymentAvailable, authv1alpha1.DexServerConditionTypeReady} {
+		if cond := meta.FindStatusCondition(dexServer.Status.Conditions, condType); cond != nil {
+			conds = append(conds, metav1.Condition{
+				Type:    cond.Type,
+				Status:  cond.Status,
+				Reason:  cond.Reason,
+				Message: cond.Message,
+			})
+		}
+	}
+	if err := updateDexQuickstartStatusConditions(r.Client, dexQuickstart, conds...); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Get the connector of the DexServer of a DexQuickstart. The redirect URI is derived from the issuer of dex, it is
+// left empty until the issuer is known.
+func getQuickstartConnector(dexQuickstart *authv1alpha1.DexQuickstart, issuer string) (authv1alpha1.ConnectorSpec, error) {
+	spec := dexQuickstart.Spec.Connector
+	connector := authv1alpha1.ConnectorSpec{
+		Id:   string(spec.Type),
+		Name: quickstartConnectorNames[spec.Type],
+		Type: spec.Type,
+	}
+	if spec.ClientSecretRef.Name == "" {
+		return connector, fmt.Errorf("spec.connector.clientSecretRef.name is not set")
+	}
+	secretRef := corev1.SecretReference{Name: spec.ClientSecretRef.Name, Namespace: dexQuickstart.Namespace}
+	redirectURI := ""
+	if issuer != "" {
+		redirectURI = strings.TrimSuffix(issuer, "/") + "/callback"
+	}
+	switch spec.Type {
+	case authv1alpha1.ConnectorTypeGitHub:
+		connector.GitHub = authv1alpha1.GitHubConfigSpec{
+			ClientID:        spec.ClientID,
+			ClientSecretRef: secretRef,
+			RedirectURI:     redirectURI,
+		}
+	case authv1alpha1.ConnectorTypeMicrosoft:
+		connector.Microsoft = authv1alpha1.MicrosoftConfigSpec{
+			ClientID:        spec.ClientID,
+			ClientSecretRef: secretRef,
+			RedirectURI:     redirectURI,
+		}
+	case authv1alpha1.ConnectorTypeOIDC:
+		if spec.Issuer == "" {
+			return connector, fmt.Errorf("spec.connector.issuer is required by the oidc connector")
+		}
+		connector.OIDC = authv1alpha1.OIDCConfigSpec{
+			ClientID:        spec.ClientID,
+			ClientSecretRef: secretRef,
+			Issuer:          spec.Issuer,
+			RedirectURI:     redirectURI,
+		}
+	default:
+		return connector, fmt.Errorf("the connector type %s is not supported by a DexQuickstart", spec.Type)
+	}
+	return connector, nil
+}
+
+// Create or update the DexServer of the DexQuickstart. Only the issuer and the connectors of the DexServer are set
+// by the DexQuickstart, the other settings of the DexServer keep their defaults or can be edited on the DexServer.
+func (r *DexQuickstartReconciler) syncDexServer(dexQuickstart *authv1alpha1.DexQuickstart, ctx context.Context) (*authv1alpha1.DexServer, error) {
+	log := ctrllog.FromContext(ctx)
+	log.Info("syncDexServer", "DexServer.Namespace", dexQuickstart.Namespace, "DexServer.Name", dexQuickstart.Name)
+
+	dexServer := &authv1alpha1.DexServer{}
+	err := r.Get(ctx, types.NamespacedName{Name: dexQuickstart.Name, Namespace: dexQuickstart.Namespace}, dexServer)
+	switch {
+	case err == nil:
+		if !metav1.IsControlledBy(dexServer, dexQuickstart) {
+			return nil, fmt.Errorf("DexServer %s/%s already exists and is not managed by DexQuickstart %s",
+				dexServer.Namespace, dexServer.Name, dexQuickstart.Name)
+		}
+		issuer := dexQuickstart.Spec.Issuer
+		if issuer == "" {
+			issuer = dexServer.Status.Issuer
+		}
+		connector, err := getQuickstartConnector(dexQuickstart, issuer)
+		if err != nil {
+			return nil, err
+		}
+		connectors := []authv1alpha1.ConnectorSpec{connector}
+		if dexServer.Spec.Issuer == dexQuickstart.Spec.Issuer && equality.Semantic.DeepEqual(dexServer.Spec.Connectors, connectors) {
+			return dexServer, nil
+		}
+		dexServer.Spec.Issuer = dexQuickstart.Spec.Issuer
+		dexServer.Spec.Connectors = connectors
+		log.Info("Updating DexServer", "DexServer.Namespace", dexServer.Namespace, "DexServer.Name", dexServer.Name)
+		if err := r.Update(ctx, dexServer); err != nil {
+			return nil, err
+		}
+		return dexServer, nil
+	case !kubeerrors.IsNotFound(err):
+		return nil, err
+	}
+
+	connector, err := getQuickstartConnector(dexQuickstart, dexQuickstart.Spec.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	dexServer = &authv1alpha1.DexServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dexQuickstart.Name,
+			Namespace: dexQuickstart.Namespace,
+			Labels: map[string]string{
+				MANAGED_BY_LABEL:     MANAGED_BY_VALUE,
+				DEX_QUICKSTART_LABEL: dexQuickstart.Name,
+			},
+		},
+		Spec: authv1alpha1.DexServerSpec{
+			Issuer:     dexQuickstart.Spec.Issuer,
+			Connectors: []authv1alpha1.ConnectorSpec{connector},
+		},
+	}
+	if err := ctrl.SetControllerReference(dexQuickstart, dexServer, r.Scheme); err != nil {
+		return nil, err
+	}
+	log.Info("Creating a new DexServer", "DexServer.Namespace", dexServer.Namespace, "DexServer.Name", dexServer.Name)
+	if err := r.Create(ctx, dexServer); err != nil {
+		return nil, err
+	}
+	return dexServer, nil
+}
+
+func updateDexQuickstartStatusConditions(c client.Client, dexQuickstart *authv1alpha1.DexQuickstart, newConditions ...metav1.Condition) error {
+	dexQuickstart.Status.Conditions = mergeStatusConditions(dexQuickstart.Status.Conditions, newConditions...)
+	return c.Status().Update(context.TODO(), dexQuickstart)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DexQuickstartReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// only handle spec changes, the status is updated by this controller
+		For(&authv1alpha1.DexQuickstart{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the issuer of the DexServer sets the redirect URI of the connector, and its status is reported in the
+		// DexQuickstart status
+		Owns(&authv1alpha1.DexServer{}).
+		Complete(r)
+}
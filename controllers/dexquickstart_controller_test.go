@@ -0,0 +1,140 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Process DexQuickstart CR", func() {
+	DexQuickstartName := "my-dexquickstart"
+	DexQuickstartNamespace := "my-dexquickstart-ns"
+	DexServerIssuer := "https://dexquickstart.testhost.com"
+
+	It("should expand the DexQuickstart into a DexServer", func() {
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: DexQuickstartNamespace}})
+		Expect(err).To(BeNil())
+		dexQuickstart := &authv1alpha1.DexQuickstart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      DexQuickstartName,
+				Namespace: DexQuickstartNamespace,
+			},
+			Spec: authv1alpha1.DexQuickstartSpec{
+				Issuer: DexServerIssuer,
+				Connector: authv1alpha1.DexQuickstartConnectorSpec{
+					Type:            authv1alpha1.ConnectorTypeGitHub,
+					ClientID:        "my-github-client-id",
+					ClientSecretRef: corev1.LocalObjectReference{Name: "my-github-client-secret"},
+				},
+			},
+		}
+		err = k8sClient.Create(context.TODO(), dexQuickstart)
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = DexQuickstartName
+		req.Namespace = DexQuickstartNamespace
+		_, err = rDexQuickstart.Reconcile(context.TODO(), req)
+		Expect(err).To(BeNil())
+
+		By("creating the DexServer owned by the DexQuickstart", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexQuickstartName, Namespace: DexQuickstartNamespace}, dexServer)
+			Expect(err).To(BeNil())
+			Expect(dexServer.Spec.Issuer).To(Equal(DexServerIssuer))
+			Expect(metav1.IsControlledBy(dexServer, dexQuickstart)).To(BeTrue())
+			Expect(dexServer.Labels[DEX_QUICKSTART_LABEL]).To(Equal(DexQuickstartName))
+			Expect(dexServer.Spec.Connectors).To(HaveLen(1))
+			connector := dexServer.Spec.Connectors[0]
+			Expect(connector.Id).To(Equal("github"))
+			Expect(connector.GitHub.ClientID).To(Equal("my-github-client-id"))
+			Expect(connector.GitHub.ClientSecretRef).To(Equal(corev1.SecretReference{Name: "my-github-client-secret", Namespace: DexQuickstartNamespace}))
+			Expect(connector.GitHub.RedirectURI).To(Equal(DexServerIssuer + "/callback"))
+		})
+		By("reporting the DexServer in the status", func() {
+			updated := &authv1alpha1.DexQuickstart{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexQuickstartName, Namespace: DexQuickstartNamespace}, updated)
+			Expect(err).To(BeNil())
+			Expect(updated.Status.DexServer).ToNot(BeNil())
+			Expect(updated.Status.DexServer.Name).To(Equal(DexQuickstartName))
+		})
+		By("keeping the settings edited on the DexServer", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			// the DexServer is also updated by the DexServerReconciler of the manager
+			Eventually(func() error {
+				if err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexQuickstartName, Namespace: DexQuickstartNamespace}, dexServer); err != nil {
+					return err
+				}
+				dexServer.Spec.Logger.Level = "debug"
+				dexServer.Spec.Connectors[0].GitHub.ClientID = "edited"
+				return k8sClient.Update(context.TODO(), dexServer)
+			}, 10, 1).Should(Succeed())
+
+			_, err := rDexQuickstart.Reconcile(context.TODO(), req)
+			Expect(err).To(BeNil())
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexQuickstartName, Namespace: DexQuickstartNamespace}, dexServer)
+			Expect(err).To(BeNil())
+			Expect(dexServer.Spec.Logger.Level).To(Equal("debug"))
+			Expect(dexServer.Spec.Connectors[0].GitHub.ClientID).To(Equal("my-github-client-id"))
+		})
+	})
+	It("should not take over a DexServer it does not own", func() {
+		otherName := "my-other-dexquickstart"
+		err := k8sClient.Create(context.TODO(), &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: otherName, Namespace: DexQuickstartNamespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://other.testhost.com"},
+		})
+		Expect(err).To(BeNil())
+		err = k8sClient.Create(context.TODO(), &authv1alpha1.DexQuickstart{
+			ObjectMeta: metav1.ObjectMeta{Name: otherName, Namespace: DexQuickstartNamespace},
+			Spec: authv1alpha1.DexQuickstartSpec{
+				Connector: authv1alpha1.DexQuickstartConnectorSpec{
+					Type:            authv1alpha1.ConnectorTypeMicrosoft,
+					ClientID:        "my-microsoft-client-id",
+					ClientSecretRef: corev1.LocalObjectReference{Name: "my-microsoft-client-secret"},
+				},
+			},
+		})
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = otherName
+		req.Namespace = DexQuickstartNamespace
+		_, err = rDexQuickstart.Reconcile(context.TODO(), req)
+		Expect(err).ToNot(BeNil())
+
+		dexServer := &authv1alpha1.DexServer{}
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: otherName, Namespace: DexQuickstartNamespace}, dexServer)
+		Expect(err).To(BeNil())
+		Expect(dexServer.Spec.Connectors).To(BeEmpty())
+	})
+	It("should require the issuer of the oidc connector", func() {
+		dexQuickstart := &authv1alpha1.DexQuickstart{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-oidc-dexquickstart", Namespace: DexQuickstartNamespace},
+			Spec: authv1alpha1.DexQuickstartSpec{
+				Connector: authv1alpha1.DexQuickstartConnectorSpec{
+					Type:            authv1alpha1.ConnectorTypeOIDC,
+					ClientID:        "my-oidc-client-id",
+					ClientSecretRef: corev1.LocalObjectReference{Name: "my-oidc-client-secret"},
+				},
+			},
+		}
+		_, err := getQuickstartConnector(dexQuickstart, "")
+		Expect(err).ToNot(BeNil())
+
+		dexQuickstart.Spec.Connector.Issuer = "https://idp.example.com"
+		connector, err := getQuickstartConnector(dexQuickstart, "")
+		Expect(err).To(BeNil())
+		Expect(connector.OIDC.Issuer).To(Equal("https://idp.example.com"))
+		Expect(connector.OIDC.RedirectURI).To(BeEmpty())
+	})
+})
@@ -0,0 +1,903 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/dexconfig"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Strings that YAML would read as another type, or that need quoting or escaping
+var configStringPieces = []string{
+	"a", "dex", "y", "n", "yes", "off", "null", "~", "0x1F", "1e3", "-", ": ", " #", "\"", "'", "\\", "\n", "\t",
+	"{", "}", "[", "]", "*", "&", "!", "%", "@", "`", "|", ">", "é", "日本", "$HOME", "{{ .Issuer }}",
+}
+
+func randomConfigString(rnd *rand.Rand) string {
+	s := ""
+	for i := rnd.Intn(5); i >= 0; i-- {
+		s += configStringPieces[rnd.Intn(len(configStringPieces))]
+	}
+	return s
+}
+
+func randomConfigDuration(rnd *rand.Rand) *metav1.Duration {
+	if rnd.Intn(2) == 0 {
+		return nil
+	}
+	return &metav1.Duration{Duration: time.Duration(rnd.Intn(100000)+1) * time.Second}
+}
+
+// Generate a valid DexServer spec, and the connectors syncConfigMap would render for it
+func randomDexServer(rnd *rand.Rand, i int) (*authv1alpha1.DexServer, []DexConnectorSpec) {
+	listenAddresses := []string{"", "0.0.0.0", "::", "10.0.0.1", "fd00::1"}
+	dexServer := &authv1alpha1.DexServer{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("my-dexserver-%d", i), Namespace: "my-config-ns"},
+		Spec: authv1alpha1.DexServerSpec{
+			Issuer: fmt.Sprintf("https://dex-%d.testhost.com/%s", i, strings.Repeat("path/", rnd.Intn(3))),
+			Ports: authv1alpha1.PortsSpec{
+				HTTPS:         int32(rnd.Intn(64511) + 1024),
+				GRPC:          int32(rnd.Intn(64511) + 1024),
+				Telemetry:     int32(rnd.Intn(64511) + 1024),
+				ListenAddress: listenAddresses[rnd.Intn(len(listenAddresses))],
+			},
+			Telemetry: authv1alpha1.TelemetrySpec{
+				Enabled:   rnd.Intn(2) == 0,
+				RBACProxy: rnd.Intn(2) == 0,
+			},
+			Expiry: authv1alpha1.ExpirySpec{
+				IDTokens:       randomConfigDuration(rnd),
+				SigningKeys:    randomConfigDuration(rnd),
+				AuthRequests:   randomConfigDuration(rnd),
+				DeviceRequests: randomConfigDuration(rnd),
+				RefreshTokens: authv1alpha1.RefreshTokensSpec{
+					ValidIfNotUsedFor: randomConfigDuration(rnd),
+					AbsoluteLifetime:  randomConfigDuration(rnd),
+					ReuseInterval:     randomConfigDuration(rnd),
+					DisableRotation:   rnd.Intn(2) == 0,
+				},
+			},
+			OAuth2: authv1alpha1.OAuth2Spec{
+				AlwaysShowLoginScreen: rnd.Intn(2) == 0,
+			},
+		},
+	}
+	if rnd.Intn(2) == 0 {
+		dexServer.Spec.Service.TLSTermination = authv1alpha1.TLSTerminationLoadBalancer
+	}
+	if rnd.Intn(2) == 0 {
+		skipApprovalScreen := rnd.Intn(2) == 0
+		dexServer.Spec.OAuth2.SkipApprovalScreen = &skipApprovalScreen
+	}
+	for _, grantType := range []authv1alpha1.GrantType{authv1alpha1.GrantTypeAuthorizationCode, authv1alpha1.GrantTypeRefreshToken,
+		authv1alpha1.GrantTypeImplicit, authv1alpha1.GrantTypePassword, authv1alpha1.GrantTypeDeviceCode} {
+		if rnd.Intn(3) == 0 {
+			dexServer.Spec.OAuth2.GrantTypes = append(dexServer.Spec.OAuth2.GrantTypes, grantType)
+		}
+	}
+	if rnd.Intn(2) == 0 {
+		themes := []authv1alpha1.FrontendTheme{"", authv1alpha1.FrontendThemeLight, authv1alpha1.FrontendThemeDark}
+		dexServer.Spec.Frontend = authv1alpha1.FrontendSpec{
+			Issuer:  randomConfigString(rnd),
+			LogoURL: "https://logo.testhost.com/" + randomConfigString(rnd),
+			Theme:   themes[rnd.Intn(len(themes))],
+		}
+	}
+	if rnd.Intn(2) == 0 {
+		dexServer.Spec.Web.TemplatesConfigMapRef = &corev1.LocalObjectReference{Name: "my-templates"}
+	}
+	dexServer.Spec.Web.TLSMinVersion = []string{"", "1.2", "1.3"}[rnd.Intn(3)]
+	dexServer.Spec.GRPC.Reflection = rnd.Intn(2) == 0
+	if rnd.Intn(2) == 0 {
+		levels, formats := []string{"", "debug", "info", "error"}, []string{"", "text", "json"}
+		dexServer.Spec.Logger = authv1alpha1.LoggerSpec{
+			Level:  levels[rnd.Intn(len(levels))],
+			Format: formats[rnd.Intn(len(formats))],
+		}
+	}
+
+	connectors := []DexConnectorSpec{}
+	for j := rnd.Intn(5); j > 0; j-- {
+		connector := DexConnectorSpec{
+			Id:   fmt.Sprintf("connector-%d", j),
+			Name: randomConfigString(rnd),
+			Config: DexConnectorConfigSpec{
+				ClientID:     randomConfigString(rnd),
+				ClientSecret: fmt.Sprintf("$CLIENT_SECRET_%d", j),
+				RedirectURI:  fmt.Sprintf("https://dex-%d.testhost.com/callback", i),
+			},
+		}
+		switch rnd.Intn(6) {
+		case 0:
+			connector.Type = string(authv1alpha1.ConnectorTypeGitHub)
+			connector.Config.Org = randomConfigString(rnd)
+			connector.Config.Orgs = []authv1alpha1.Org{{Name: randomConfigString(rnd), Teams: []string{randomConfigString(rnd)}}}
+			connector.Config.TeamNameField = []string{"", "name", "slug", "both"}[rnd.Intn(4)]
+			connector.Config.LoadAllGroups = rnd.Intn(2) == 0
+			connector.Config.UseLoginAsID = rnd.Intn(2) == 0
+			connector.Config.PreferredEmailDomain = randomConfigString(rnd)
+		case 1:
+			connector.Type = string(authv1alpha1.ConnectorTypeMicrosoft)
+			connector.Config.Tenant = randomConfigString(rnd)
+		case 2:
+			connector.Type = string(authv1alpha1.ConnectorTypeLDAP)
+			connector.Config = DexConnectorConfigSpec{
+				Host:               "ldap.testhost.com:636",
+				InsecureNoSSL:      rnd.Intn(2) == 0,
+				InsecureSkipVerify: rnd.Intn(2) == 0,
+				StartTLS:           rnd.Intn(2) == 0,
+				RootCA:             "/etc/dex/ldapcerts/" + connector.Id + "/ca.crt",
+				ClientCert:         "/etc/dex/ldapcerts/" + connector.Id + "/tls.crt",
+				ClientKey:          "/etc/dex/ldapcerts/" + connector.Id + "/tls.key",
+				BindDN:             randomConfigString(rnd),
+				BindPW:             fmt.Sprintf("$BIND_PW_%d", j),
+				UsernamePrompt:     randomConfigString(rnd),
+				UserSearch: authv1alpha1.UserSearchSpec{
+					BaseDN:    randomConfigString(rnd),
+					Filter:    randomConfigString(rnd),
+					Username:  "uid",
+					Scope:     "sub",
+					IDAttr:    "uid",
+					EmailAttr: "mail",
+					NameAttr:  "cn",
+				},
+				GroupSearch: authv1alpha1.GroupSearchSpec{
+					BaseDN:       randomConfigString(rnd),
+					Filter:       randomConfigString(rnd),
+					Scope:        "sub",
+					UserMatchers: []authv1alpha1.UserMatcher{{UserAttr: "uid", GroupAttr: "member"}},
+					NameAttr:     "cn",
+				},
+			}
+		case 3:
+			connector.Type = string(authv1alpha1.ConnectorTypeOIDC)
+			connector.Config.Issuer = fmt.Sprintf("https://oidc-%d.testhost.com", j)
+			connector.Config.UserNameKey = randomConfigString(rnd)
+			connector.Config.ClaimMapping = DexClaimMappingSpec{
+				PreferredUsernameKey: randomConfigString(rnd),
+				EmailKey:             randomConfigString(rnd),
+			}
+		case 4:
+			connector.Type = DEX_BITBUCKET_CLOUD_CONNECTOR_TYPE
+			connector.Config.Teams = []string{randomConfigString(rnd)}
+			connector.Config.IncludeTeamGroups = rnd.Intn(2) == 0
+		case 5:
+			connector.Type = string(authv1alpha1.ConnectorTypeGitea)
+			connector.Config.BaseURL = fmt.Sprintf("https://gitea-%d.testhost.com", j)
+			connector.Config.Orgs = []authv1alpha1.Org{{Name: randomConfigString(rnd), Teams: []string{randomConfigString(rnd)}}}
+			connector.Config.LoadAllGroups = rnd.Intn(2) == 0
+			connector.Config.UseLoginAsID = rnd.Intn(2) == 0
+		}
+		connectors = append(connectors, connector)
+	}
+	if len(connectors) > 0 && rnd.Intn(2) == 0 {
+		dexServer.Spec.OAuth2.PasswordConnector = connectors[rnd.Intn(len(connectors))].Id
+	}
+	return dexServer, connectors
+}
+
+func loadDexConfig(dexServer *authv1alpha1.DexServer, connectors []DexConnectorSpec) *dexconfig.Config {
+	values, err := getDexConfigValues(dexServer, dexServer.Spec.Issuer, connectors, nil)
+	Expect(err).To(BeNil())
+	rendered, err := rDexServer.renderDexConfig(dexServer, values)
+	Expect(err).To(BeNil())
+	config, err := dexconfig.Load([]byte(rendered))
+	Expect(err).To(BeNil(), "rendered config.yaml:\n%s", rendered)
+	return config
+}
+
+var _ = Describe("Render the dex config.yaml", func() {
+	It("should render a config dex accepts for any DexServer spec", func() {
+		seed := GinkgoRandomSeed()
+		rnd := rand.New(rand.NewSource(seed))
+		for i := 0; i < 200; i++ {
+			dexServer, connectors := randomDexServer(rnd, i)
+			By(fmt.Sprintf("rendering DexServer %d of seed %d", i, seed))
+			config := loadDexConfig(dexServer, connectors)
+
+			Expect(config.Issuer).To(Equal(dexServer.Spec.Issuer))
+			Expect(config.OAuth2.PasswordConnector).To(Equal(dexServer.Spec.OAuth2.PasswordConnector))
+			Expect(config.OAuth2.AlwaysShowLoginScreen).To(Equal(dexServer.Spec.OAuth2.AlwaysShowLoginScreen))
+			Expect(config.Telemetry.HTTP != "").To(Equal(dexServer.Spec.Telemetry.Enabled))
+			Expect(config.Web.HTTP != "").To(Equal(isTLSTerminatedAtLoadBalancer(dexServer)))
+			Expect(config.Web.TLSMinVersion).To(Equal(dexServer.Spec.Web.TLSMinVersion))
+			Expect(config.Expiry.IDTokens).To(Equal(durationString(dexServer.Spec.Expiry.IDTokens)))
+			Expect(config.Expiry.DeviceRequests).To(Equal(durationString(dexServer.Spec.Expiry.DeviceRequests)))
+			for _, grantType := range dexServer.Spec.OAuth2.GrantTypes {
+				Expect(config.OAuth2.GrantTypes).To(ContainElement(string(grantType)))
+			}
+			if len(dexServer.Spec.OAuth2.GrantTypes) > 0 && dexServer.Spec.OAuth2.PasswordConnector != "" {
+				Expect(config.OAuth2.GrantTypes).To(ContainElement(string(authv1alpha1.GrantTypePassword)))
+			}
+			Expect(config.Frontend.Issuer).To(Equal(dexServer.Spec.Frontend.Issuer))
+			Expect(config.Frontend.Dir != "").To(Equal(dexServer.Spec.Web.TemplatesConfigMapRef != nil))
+			Expect(config.Logger.Level).To(Equal(dexServer.Spec.Logger.Level))
+			Expect(config.Logger.Format).To(Equal(dexServer.Spec.Logger.Format))
+			Expect(config.GRPC.Reflection).To(Equal(dexServer.Spec.GRPC.Reflection))
+			Expect(config.StaticConnectors).To(HaveLen(len(connectors)))
+			for j, connector := range connectors {
+				Expect(config.StaticConnectors[j].ID).To(Equal(connector.Id))
+				Expect(config.StaticConnectors[j].Name).To(Equal(connector.Name))
+			}
+		}
+	})
+	It("should render the connector settings under the keys dex reads", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-golden-dexserver", Namespace: "my-config-ns"},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://golden.testhost.com"},
+		}
+		connectors := []DexConnectorSpec{
+			{
+				Type: string(authv1alpha1.ConnectorTypeLDAP),
+				Id:   "ldap",
+				Name: "LDAP",
+				Config: DexConnectorConfigSpec{
+					Host:       "ldap.testhost.com:636",
+					ClientCert: "/etc/dex/ldapcerts/ldap/tls.crt",
+					ClientKey:  "/etc/dex/ldapcerts/ldap/tls.key",
+					BindPW:     "$BIND_PW",
+				},
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeOIDC),
+				Id:   "oidc",
+				Name: "OIDC",
+				Config: DexConnectorConfigSpec{
+					Issuer:                    "https://oidc.testhost.com",
+					ClientSecret:              "$CLIENT_SECRET",
+					UserNameKey:               "display_name",
+					ClaimMapping:              DexClaimMappingSpec{PreferredUsernameKey: "login", EmailKey: "mail", GroupsKey: "roles"},
+					Scopes:                    []string{"profile", "email", "groups"},
+					InsecureSkipEmailVerified: true,
+					InsecureEnableGroups:      true,
+					ClaimModifications: authv1alpha1.ClaimModificationsSpec{
+						NewGroupFromClaims: []authv1alpha1.NewGroupFromClaimsSpec{
+							{Claims: []string{"department", "role"}, Delimiter: ":", ClearDelimiter: true, Prefix: "team"},
+						},
+						FilterGroupClaims: authv1alpha1.FilterGroupClaimsSpec{GroupsFilter: "^dev-.*$"},
+					},
+				},
+			},
+			{
+				Type: DEX_BITBUCKET_CLOUD_CONNECTOR_TYPE,
+				Id:   "bitbucketcloud",
+				Name: "Bitbucket Cloud",
+				Config: DexConnectorConfigSpec{
+					ClientSecret:      "$BITBUCKET_CLOUD_CLIENT_SECRET",
+					Teams:             []string{"my-team"},
+					IncludeTeamGroups: true,
+				},
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeGitea),
+				Id:   "gitea",
+				Name: "Gitea",
+				Config: DexConnectorConfigSpec{
+					BaseURL:      "https://gitea.testhost.com",
+					ClientSecret: "$GITEA_CLIENT_SECRET",
+					Orgs:         []authv1alpha1.Org{{Name: "my-org", Teams: []string{"my-team"}}},
+				},
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeSAML),
+				Id:   "saml",
+				Name: "SAML",
+				Config: DexConnectorConfigSpec{
+					SSOURL:       "https://adfs.testhost.com/adfs/ls",
+					CA:           "/etc/dex/samlcerts/saml/ca.crt",
+					EntityIssuer: "https://config.testhost.com/callback",
+					UsernameAttr: "name",
+					EmailAttr:    "email",
+					GroupsAttr:   "groups",
+				},
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeGoogle),
+				Id:   "google",
+				Name: "Google",
+				Config: DexConnectorConfigSpec{
+					ClientSecret:           "$GOOGLE_CLIENT_SECRET",
+					HostedDomains:          []string{"example.com"},
+					Groups:                 []string{"admins@example.com"},
+					ServiceAccountFilePath: "/etc/dex/googlesa/google/service-account.json",
+					AdminEmail:             "admin@example.com",
+				},
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeOpenShift),
+				Id:   "openshift",
+				Name: "OpenShift",
+				Config: DexConnectorConfigSpec{
+					ClientID:     "dex-my-config-ns-my-dexserver-openshift",
+					ClientSecret: "$OPENSHIFT_CLIENT_SECRET",
+					Issuer:       OPENSHIFT_DEFAULT_ISSUER,
+					Groups:       []string{"cluster-admins"},
+					RootCA:       OPENSHIFT_DEFAULT_ROOT_CA,
+				},
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeAuthProxy),
+				Id:   "authproxy",
+				Name: "Corporate SSO",
+				Config: DexConnectorConfigSpec{
+					UserHeader:  "X-Forwarded-User",
+					EmailHeader: "X-Forwarded-Email",
+					Groups:      []string{"employees"},
+				},
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeMockCallback),
+				Id:   "mock",
+				Name: "Test user",
+			},
+			{
+				Type: string(authv1alpha1.ConnectorTypeMockPassword),
+				Id:   "mock-password",
+				Name: "Test password",
+				Config: DexConnectorConfigSpec{
+					Username: "kilgore",
+					Password: "$MOCK_PASSWORD",
+				},
+			},
+		}
+		config := loadDexConfig(dexServer, connectors)
+		Expect(config.Web.HTTPS).To(Equal(":5556"))
+		Expect(config.GRPC.Addr).To(Equal(":5557"))
+		Expect(config.Storage.Type).To(Equal("kubernetes"))
+		Expect(config.OAuth2.SkipApprovalScreen).To(BeTrue())
+
+		ldap := &dexconfig.LDAPConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[0].Config, ldap)).To(Succeed())
+		Expect(ldap.ClientCert).To(Equal("/etc/dex/ldapcerts/ldap/tls.crt"))
+		oidc := &dexconfig.OIDCConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[1].Config, oidc)).To(Succeed())
+		Expect(oidc.UserNameKey).To(Equal("display_name"))
+		Expect(oidc.ClaimMapping.PreferredUsernameKey).To(Equal("login"))
+		Expect(oidc.ClaimMapping.EmailKey).To(Equal("mail"))
+		Expect(oidc.Scopes).To(Equal([]string{"profile", "email", "groups"}))
+		Expect(oidc.InsecureSkipEmailVerified).To(BeTrue())
+		Expect(oidc.InsecureEnableGroups).To(BeTrue())
+		Expect(oidc.ClaimMapping.GroupsKey).To(Equal("roles"))
+		Expect(oidc.ClaimMutations.NewGroupFromClaims).To(Equal([]dexconfig.NewGroupFromClaims{
+			{Claims: []string{"department", "role"}, Delimiter: ":", ClearDelimiter: true, Prefix: "team"},
+		}))
+		Expect(oidc.ClaimMutations.FilterGroupClaims.GroupsFilter).To(Equal("^dev-.*$"))
+		bitbucketCloud := &dexconfig.BitbucketCloudConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[2].Config, bitbucketCloud)).To(Succeed())
+		Expect(bitbucketCloud.Teams).To(Equal([]string{"my-team"}))
+		Expect(bitbucketCloud.IncludeTeamGroups).To(BeTrue())
+		gitea := &dexconfig.GiteaConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[3].Config, gitea)).To(Succeed())
+		Expect(gitea.BaseURL).To(Equal("https://gitea.testhost.com"))
+		Expect(gitea.Orgs).To(Equal([]dexconfig.GiteaOrg{{Name: "my-org", Teams: []string{"my-team"}}}))
+		saml := &dexconfig.SAMLConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[4].Config, saml)).To(Succeed())
+		Expect(saml.SSOURL).To(Equal("https://adfs.testhost.com/adfs/ls"))
+		Expect(saml.CA).To(Equal("/etc/dex/samlcerts/saml/ca.crt"))
+		Expect(saml.EntityIssuer).To(Equal("https://config.testhost.com/callback"))
+		Expect(saml.UsernameAttr).To(Equal("name"))
+		Expect(saml.GroupsAttr).To(Equal("groups"))
+		google := &dexconfig.GoogleConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[5].Config, google)).To(Succeed())
+		Expect(google.HostedDomains).To(Equal([]string{"example.com"}))
+		Expect(google.Groups).To(Equal([]string{"admins@example.com"}))
+		Expect(google.ServiceAccountFilePath).To(Equal("/etc/dex/googlesa/google/service-account.json"))
+		Expect(google.AdminEmail).To(Equal("admin@example.com"))
+		openShift := &dexconfig.OpenShiftConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[6].Config, openShift)).To(Succeed())
+		Expect(openShift.Issuer).To(Equal("https://kubernetes.default.svc"))
+		Expect(openShift.ClientID).To(Equal("dex-my-config-ns-my-dexserver-openshift"))
+		Expect(openShift.Groups).To(Equal([]string{"cluster-admins"}))
+		Expect(openShift.RootCA).To(Equal("/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"))
+		authProxy := &dexconfig.AuthProxyConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[7].Config, authProxy)).To(Succeed())
+		Expect(authProxy.UserHeader).To(Equal("X-Forwarded-User"))
+		Expect(authProxy.EmailHeader).To(Equal("X-Forwarded-Email"))
+		Expect(authProxy.Groups).To(Equal([]string{"employees"}))
+		Expect(config.StaticConnectors[8].Type).To(Equal("mockCallback"))
+		mockPassword := &dexconfig.MockPasswordConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[9].Config, mockPassword)).To(Succeed())
+		Expect(mockPassword.Username).To(Equal("kilgore"))
+		Expect(mockPassword.Password).To(Equal("$MOCK_PASSWORD"))
+	})
+	It("should render the bitbucketcloud connector with the bitbucket-cloud type of dex", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-bitbucket-dexserver", Namespace: "my-bitbucket-ns"},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://bitbucket.testhost.com"},
+		}
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: dexServer.Namespace}})
+		Expect(err).To(BeNil())
+		err = k8sClient.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bitbucket-client", Namespace: dexServer.Namespace},
+			Data:       map[string][]byte{"clientSecret": []byte("my-bitbucket-secret")},
+		})
+		Expect(err).To(BeNil())
+		connector := authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeBitbucketCloud,
+			Id:   "bitbucket",
+			BitbucketCloud: authv1alpha1.BitbucketCloudConfigSpec{
+				ClientID:        "my-client-id",
+				ClientSecretRef: corev1.SecretReference{Name: "bitbucket-client", Namespace: dexServer.Namespace},
+				Teams:           []string{"my-team"},
+			},
+		}
+		rendered, err := rDexServer.renderBitbucketCloudConnector(dexServer, connector, context.TODO())
+		Expect(err).To(BeNil())
+		Expect(rendered.Type).To(Equal("bitbucket-cloud"))
+
+		config := loadDexConfig(dexServer, []DexConnectorSpec{rendered})
+		Expect(config.StaticConnectors[0].Type).To(Equal("bitbucket-cloud"))
+		bitbucketCloud := &dexconfig.BitbucketCloudConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[0].Config, bitbucketCloud)).To(Succeed())
+		Expect(bitbucketCloud.ClientID).To(Equal("my-client-id"))
+		Expect(bitbucketCloud.ClientSecret).To(Equal("$BITBUCKET_CLOUD_CLIENT_SECRET_" + getUniqueAlphanumericIdForConnector(connector)))
+	})
+	It("should reject the settings dex would ignore", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-invalid-dexserver", Namespace: "my-config-ns"},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:  "https://invalid.testhost.com",
+				Service: authv1alpha1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+			},
+		}
+		connectors := []DexConnectorSpec{
+			{
+				Type: string(authv1alpha1.ConnectorTypeLDAP),
+				Id:   "ldap",
+				Name: "LDAP",
+				// a GitHub setting, dex does not read it for an LDAP connector
+				Config: DexConnectorConfigSpec{Host: "ldap.testhost.com:636", Org: "my-org"},
+			},
+		}
+		values, err := getDexConfigValues(dexServer, dexServer.Spec.Issuer, connectors, nil)
+		Expect(err).To(BeNil())
+		rendered, err := rDexServer.renderDexConfig(dexServer, values)
+		Expect(err).To(BeNil())
+		_, err = dexconfig.Load([]byte(rendered))
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("config.Org"))
+	})
+	It("should refuse the team and org filters of another connector type", func() {
+		connector := &authv1alpha1.ConnectorSpec{
+			Type:           authv1alpha1.ConnectorTypeGitHub,
+			BitbucketCloud: authv1alpha1.BitbucketCloudConfigSpec{Teams: []string{"my-team", "my-team"}},
+			Gitea:          authv1alpha1.GiteaConfigSpec{Orgs: []authv1alpha1.Org{{Name: "my-org"}}},
+		}
+		errs := authv1alpha1.ValidateConnectorFilters(connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].bitbucketcloud.teams: Forbidden"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].bitbucketcloud.teams[1]: Duplicate value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].gitea.orgs: Forbidden"))
+
+		connector.Type = authv1alpha1.ConnectorTypeGitea
+		connector.BitbucketCloud.Teams = nil
+		connector.Gitea.BaseURL = "https://gitea.testhost.com"
+		Expect(authv1alpha1.ValidateConnectorFilters(connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+	})
+	It("should require the settings dex needs for a SAML connector", func() {
+		connector := &authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeSAML,
+			SAML: authv1alpha1.SAMLConfigSpec{SSOURL: "adfs.testhost.com", UsernameAttr: "name"},
+		}
+		errs := authv1alpha1.ValidateSAMLConnector(connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].saml.ssoURL: Invalid value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].saml.emailAttr: Required value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].saml.caRef: Required value"))
+
+		connector.SAML.SSOURL = "https://adfs.testhost.com/adfs/ls"
+		connector.SAML.EmailAttr = "email"
+		connector.SAML.CARef = corev1.SecretReference{Name: "my-saml-ca", Namespace: "my-config-ns"}
+		Expect(authv1alpha1.ValidateSAMLConnector(connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+	})
+	It("should require a service account to look up the groups of a Google connector", func() {
+		connector := &authv1alpha1.ConnectorSpec{
+			Type:   authv1alpha1.ConnectorTypeGoogle,
+			Google: authv1alpha1.GoogleConfigSpec{Groups: []string{"admins@example.com"}, AdminEmail: "admin@example.com"},
+		}
+		errs := authv1alpha1.ValidateGoogleConnector(connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].google.serviceAccountRef: Required value"))
+
+		connector.Google.ServiceAccountRef = corev1.SecretReference{Name: "my-google-sa", Namespace: "my-config-ns"}
+		Expect(authv1alpha1.ValidateGoogleConnector(connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+	})
+	It("should check the claim modifications of an OIDC connector", func() {
+		connector := &authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeOIDC,
+			OIDC: authv1alpha1.OIDCConfigSpec{
+				ClaimModifications: authv1alpha1.ClaimModificationsSpec{
+					NewGroupFromClaims: []authv1alpha1.NewGroupFromClaimsSpec{{Prefix: "team"}},
+					FilterGroupClaims:  authv1alpha1.FilterGroupClaimsSpec{GroupsFilter: "^dev-(.*$"},
+				},
+			},
+		}
+		errs := authv1alpha1.ValidateOIDCConnector(connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].oidc.claimModifications.newGroupFromClaims[0].claims: Required value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].oidc.claimModifications.filterGroupClaims.groupsFilter: Invalid value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].oidc.claimModifications.filterGroupClaims.groupsFilter: Forbidden"))
+
+		connector.OIDC.InsecureEnableGroups = true
+		connector.OIDC.ClaimModifications.NewGroupFromClaims[0].Claims = []string{"department"}
+		connector.OIDC.ClaimModifications.FilterGroupClaims.GroupsFilter = "^dev-.*$"
+		Expect(authv1alpha1.ValidateOIDCConnector(connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+	})
+	It("should mount the CA of a GitHub Enterprise server", func() {
+		connector := authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeGitHub,
+			Id:   "my-ghe",
+			GitHub: authv1alpha1.GitHubConfigSpec{
+				HostName:   "https://github.testhost.com",
+				RootCARef:  corev1.SecretReference{Name: "my-ghe-ca", Namespace: "my-config-ns"},
+				RootCAData: []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"),
+			},
+		}
+		errs := authv1alpha1.ValidateGitHubConnector(&connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].github.hostName: Invalid value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].github.rootCAData: Forbidden"))
+
+		connector.GitHub.HostName = "github.testhost.com"
+		connector.GitHub.RootCARef = corev1.SecretReference{}
+		Expect(authv1alpha1.ValidateGitHubConnector(&connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-ghe-dexserver", Namespace: "my-config-ns"},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:     "https://ghe.testhost.com",
+				Connectors: []authv1alpha1.ConnectorSpec{connector},
+			},
+		}
+		connectors := []DexConnectorSpec{{
+			Type: string(authv1alpha1.ConnectorTypeGitHub),
+			Id:   "my-ghe",
+			Name: "GitHub Enterprise",
+			Config: DexConnectorConfigSpec{
+				ClientSecret: "$GITHUB_CLIENT_SECRET",
+				HostName:     "github.testhost.com",
+				RootCA:       "/etc/dex/githubcerts/my-ghe/ca.crt",
+			},
+		}}
+		config := loadDexConfig(dexServer, connectors)
+		github := &dexconfig.GitHubConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[0].Config, github)).To(Succeed())
+		Expect(github.HostName).To(Equal("github.testhost.com"))
+		Expect(github.RootCA).To(Equal("/etc/dex/githubcerts/my-ghe/ca.crt"))
+
+		By("adding the inline CA to the ConfigMap of the dex config", func() {
+			values, err := getDexConfigValues(dexServer, dexServer.Spec.Issuer, connectors, nil)
+			Expect(err).To(BeNil())
+			applier, readerDeploy := rDexServer.getApplierAndReader(dexServer)
+			rendered, err := applier.MustTemplateAssets(readerDeploy, values, "", "dex-server/config_map.yaml")
+			Expect(err).To(BeNil())
+			configMap := &corev1.ConfigMap{}
+			Expect(yaml.Unmarshal([]byte(rendered[0]), configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("github-ca-6D792D676865.crt", string(connector.GitHub.RootCAData)))
+		})
+	})
+	It("should read the client ID of a GitHub connector from a secret", func() {
+		connector := authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeGitHub,
+			Id:   "my-github",
+			GitHub: authv1alpha1.GitHubConfigSpec{
+				ClientID:        "my-github-client-id",
+				ClientIDRef:     corev1.SecretReference{Name: "my-github-client", Namespace: "my-github-client-ns"},
+				ClientSecretRef: corev1.SecretReference{Name: "my-github-client", Namespace: "my-github-client-ns"},
+			},
+		}
+		errs := authv1alpha1.ValidateGitHubConnector(&connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].github.clientIDRef: Forbidden"))
+
+		connector.GitHub.ClientID = ""
+		Expect(authv1alpha1.ValidateGitHubConnector(&connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+		Expect(getConnectorSecretRefs(connector)).To(Equal([]corev1.SecretReference{connector.GitHub.ClientSecretRef, connector.GitHub.ClientIDRef}))
+
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-github-dexserver", Namespace: "my-github-client-ns"}}
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: dexServer.Namespace}})
+		Expect(err).To(BeNil())
+		By("waiting for the secret to be copied into the dex server namespace", func() {
+			envVariable, err := rDexServer.getGitHubClientIDEnvVariable(context.TODO(), dexServer, connector)
+			Expect(err).To(BeNil())
+			Expect(envVariable).To(Equal(corev1.EnvVar{}))
+		})
+		By("referencing the client ID in the copied secret", func() {
+			err := k8sClient.Create(context.TODO(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-github-client-ns-my-github-client", Namespace: dexServer.Namespace},
+				Data:       map[string][]byte{"clientID": []byte("my-github-client-id"), "clientSecret": []byte("my-github-client-secret")},
+			})
+			Expect(err).To(BeNil())
+			envVariable, err := rDexServer.getGitHubClientIDEnvVariable(context.TODO(), dexServer, connector)
+			Expect(err).To(BeNil())
+			Expect(envVariable.Name).To(Equal("GITHUB_CLIENT_ID_6D792D676974687562"))
+			Expect(envVariable.ValueFrom.SecretKeyRef.Name).To(Equal("my-github-client-ns-my-github-client"))
+			Expect(envVariable.ValueFrom.SecretKeyRef.Key).To(Equal("clientID"))
+		})
+	})
+	It("should reject the connectors reusing the id or name of a rendered connector", func() {
+		ids, names := map[string]int{"my-ldap": 0}, map[string]int{"LDAP": 0}
+		Expect(getDuplicateConnectorReason("my-github", "GitHub", ids, names)).To(BeEmpty())
+		Expect(getDuplicateConnectorReason("my-ldap", "Corporate LDAP", ids, names)).To(Equal(`the id "my-ldap" is already used by spec.connectors[0]`))
+		Expect(getDuplicateConnectorReason("my-other-ldap", "LDAP", ids, names)).To(Equal(`the name "LDAP" is already used by spec.connectors[0]`))
+
+		spec := &authv1alpha1.DexServerSpec{Connectors: []authv1alpha1.ConnectorSpec{
+			{Id: "my-ldap", Name: "LDAP"},
+			{Id: "my-github"},
+			{Id: "my-ldap"},
+			{Id: "LDAP"},
+		}}
+		errs := authv1alpha1.ValidateConnectorIds(spec, field.NewPath("spec", "connectors"))
+		Expect(errs).To(HaveLen(2))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring(`spec.connectors[2].id: Duplicate value: "my-ldap"`))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring(`spec.connectors[3].name: Duplicate value: "LDAP"`))
+	})
+	It("should require the OAuthClient of an OpenShift connector of another cluster", func() {
+		connector := &authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeOpenShift,
+			OpenShift: authv1alpha1.OpenShiftConfigSpec{
+				Issuer:          "https://api.other.testhost.com:6443",
+				ClientSecretRef: corev1.SecretReference{Name: "my-openshift-client", Namespace: "my-config-ns"},
+			},
+		}
+		errs := authv1alpha1.ValidateOpenShiftConnector(connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].openshift.clientID: Required value"))
+
+		connector.OpenShift.ClientID = "dex"
+		Expect(authv1alpha1.ValidateOpenShiftConnector(connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+	})
+	It("should route the callback of an authproxy connector to its proxy", func() {
+		connector := &authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeAuthProxy,
+			Id:   "sso",
+			AuthProxy: authv1alpha1.AuthProxyConfigSpec{
+				UserHeader:   "X Remote User",
+				ProxyService: &authv1alpha1.AuthProxyServiceSpec{Port: 8443},
+			},
+		}
+		unrouted := connector.DeepCopy()
+		unrouted.AuthProxy.ProxyService = nil
+		errs := authv1alpha1.ValidateAuthProxyConnector(unrouted, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].authproxy.proxyService: Required value"))
+
+		errs = authv1alpha1.ValidateAuthProxyConnector(connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].authproxy.userHeader: Invalid value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].authproxy.proxyService.name: Required value"))
+
+		connector.AuthProxy.UserHeader = "X-Remote-User"
+		connector.AuthProxy.ProxyService.Name = "sso-proxy"
+		Expect(authv1alpha1.ValidateAuthProxyConnector(connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-authproxy-dexserver", Namespace: "my-config-ns"},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:     "https://authproxy.testhost.com/dex",
+				Connectors: []authv1alpha1.ConnectorSpec{*connector, {Type: authv1alpha1.ConnectorTypeAuthProxy, Id: "unrouted"}},
+			},
+		}
+		Expect(getAuthProxyPaths(dexServer)).To(Equal([]authProxyPath{{Path: "/dex/callback/sso", Service: "sso-proxy", Port: 8443}}))
+		Expect(canRouteAuthProxyCallback(dexServer, true)).To(BeTrue())
+		dexServer.Spec.Route.WildcardPolicy = authv1alpha1.RouteWildcardPolicySubdomain
+		Expect(canRouteAuthProxyCallback(dexServer, true)).To(BeFalse())
+
+		dexServer.Status.RejectedConnectors = []authv1alpha1.RejectedConnectorStatus{{Id: "sso", Reason: "rejected"}}
+		Expect(getAuthProxyPaths(dexServer)).To(BeEmpty())
+	})
+	It("should only admit the proxy of an authproxy connector to dex", func() {
+		namespace := "my-authproxy-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		proxy := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "sso-proxy", Namespace: namespace},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "sso-proxy"},
+				Ports:    []corev1.ServicePort{{Port: 8443}},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), proxy)).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-authproxy-dexserver", Namespace: namespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://authproxy.testhost.com",
+				Connectors: []authv1alpha1.ConnectorSpec{{
+					Type: authv1alpha1.ConnectorTypeAuthProxy,
+					Id:   "sso",
+					AuthProxy: authv1alpha1.AuthProxyConfigSpec{
+						ProxyService: &authv1alpha1.AuthProxyServiceSpec{Name: "sso-proxy", Port: 8443},
+					},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		Expect(rDexServer.syncAuthProxyNetworkPolicy(dexServer, context.TODO())).To(Succeed())
+		policy := &networkingv1.NetworkPolicy{}
+		key := client.ObjectKey{Name: dexServer.Name + AUTHPROXY_POLICY_SUFFIX, Namespace: namespace}
+		Expect(k8sClient.Get(context.TODO(), key, policy)).To(Succeed())
+		Expect(metav1.IsControlledBy(policy, dexServer)).To(BeTrue())
+		Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"app": dexServer.Name}))
+		Expect(policy.Spec.Ingress).To(HaveLen(2))
+		By("admitting the proxy, the ingress controller and the operator to the HTTPS port", func() {
+			rule := policy.Spec.Ingress[0]
+			Expect(rule.Ports).To(HaveLen(1))
+			Expect(rule.Ports[0].Port.IntValue()).To(Equal(DEX_HTTPS_PORT))
+			Expect(rule.From).To(ContainElement(networkingv1.NetworkPolicyPeer{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "sso-proxy"}},
+			}))
+			Expect(rule.From).To(ContainElement(networkingv1.NetworkPolicyPeer{
+				NamespaceSelector: &metav1.LabelSelector{},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{OPERATOR_POD_LABEL: OPERATOR_POD_VALUE}},
+			}))
+			Expect(rule.From).To(HaveLen(3))
+		})
+		By("leaving the gRPC port open", func() {
+			rule := policy.Spec.Ingress[1]
+			Expect(rule.From).To(BeEmpty())
+			Expect(rule.Ports[0].Port.IntValue()).To(Equal(DEX_GRPC_PORT))
+		})
+		By("failing while the proxy Service is missing", func() {
+			missing := dexServer.DeepCopy()
+			missing.Spec.Connectors[0].AuthProxy.ProxyService.Name = "missing-proxy"
+			Expect(rDexServer.syncAuthProxyNetworkPolicy(missing, context.TODO())).NotTo(Succeed())
+		})
+		By("deleting the NetworkPolicy once the authproxy connector is removed", func() {
+			dexServer.Spec.Connectors = nil
+			Expect(rDexServer.syncAuthProxyNetworkPolicy(dexServer, context.TODO())).To(Succeed())
+			err := k8sClient.Get(context.TODO(), key, policy)
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+	It("should render the config of a raw connector verbatim", func() {
+		connector := &authv1alpha1.ConnectorSpec{
+			Type: authv1alpha1.ConnectorTypeRaw,
+			Id:   "gitlab",
+			Raw: authv1alpha1.RawConfigSpec{
+				ConnectorType: "gitlab",
+				Config:        "baseURL: https://gitlab.testhost.com\nclientID: dex\nclientSecret: $(CLIENT_SECRET)\ngroups: [$(GROUP)]\n",
+				Secrets: []authv1alpha1.RawConnectorSecretSpec{{
+					Placeholder: "CLIENT_SECRET",
+					SecretRef:   corev1.SecretReference{Name: "gitlab-client", Namespace: "my-config-ns"},
+					Key:         "clientSecret",
+				}},
+			},
+		}
+		errs := authv1alpha1.ValidateRawConnector(connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring(`spec.connectors[0].raw.config: Invalid value: "$(GROUP)": the placeholder has no secret`))
+
+		By("refusing the credentials that are not a placeholder", func() {
+			leaky := connector.DeepCopy()
+			leaky.Raw.Config = "baseURL: https://gitlab.testhost.com\nclientID: dex\nclientSecret: prefix-$(CLIENT_SECRET)\nldap:\n  bindPW: hunter2\n"
+			errs := authv1alpha1.ValidateRawConnector(leaky, field.NewPath("spec", "connectors").Index(0))
+			Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].raw.config.clientSecret: Forbidden"))
+			Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].raw.config.ldap.bindPW: Forbidden"))
+			Expect(errs.ToAggregate().Error()).ToNot(ContainSubstring("hunter2"))
+		})
+
+		connector.Raw.Config = "baseURL: https://gitlab.testhost.com\nclientID: dex\nclientSecret: $(CLIENT_SECRET)\n"
+		Expect(authv1alpha1.ValidateRawConnector(connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+
+		rawConfig, err := getRawConnectorConfig(*connector)
+		Expect(err).To(BeNil())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-raw-dexserver", Namespace: "my-config-ns"},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://raw.testhost.com"},
+		}
+		config := loadDexConfig(dexServer, []DexConnectorSpec{{Type: "gitlab", Id: "gitlab", Name: "GitLab", RawConfig: rawConfig}})
+		Expect(config.StaticConnectors[0].Type).To(Equal("gitlab"))
+		gitlab := map[string]interface{}{}
+		Expect(json.Unmarshal(config.StaticConnectors[0].Config, &gitlab)).To(Succeed())
+		Expect(gitlab).To(Equal(map[string]interface{}{
+			"baseURL":      "https://gitlab.testhost.com",
+			"clientID":     "dex",
+			"clientSecret": "${RAW_CLIENT_SECRET_" + getUniqueAlphanumericIdForConnector(*connector) + "}",
+		}))
+
+		By("reading the secrets without namespace from the copies of the DexServer namespace", func() {
+			rawServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-raw-dexserver", Namespace: "my-raw-ns"}}
+			err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: rawServer.Namespace}})
+			Expect(err).To(BeNil())
+			local := connector.DeepCopy()
+			local.Raw.Secrets[0].SecretRef.Namespace = ""
+			_, err = rDexServer.getRawConnectorEnvVariables(context.TODO(), rawServer, *local)
+			Expect(err).NotTo(BeNil())
+			_, err = getConnectorSecretFromRef(*local, rawServer, &rDexServer, context.TODO())
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+
+			err = k8sClient.Create(context.TODO(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "gitlab-client", Namespace: rawServer.Namespace},
+				Data:       map[string][]byte{"clientSecret": []byte("my-gitlab-secret")},
+			})
+			Expect(err).To(BeNil())
+			Expect(rDexServer.copySecretToDexServerNamespace(rawServer, getRawConnectorSecretRef(rawServer, local.Raw.Secrets[0]), context.TODO())).To(Succeed())
+			envVariables, err := rDexServer.getRawConnectorEnvVariables(context.TODO(), rawServer, *local)
+			Expect(err).To(BeNil())
+			Expect(envVariables).To(HaveLen(1))
+			Expect(envVariables[0].ValueFrom.SecretKeyRef.Name).To(Equal("my-raw-ns-gitlab-client"))
+			value, err := getConnectorSecretFromRef(*local, rawServer, &rDexServer, context.TODO())
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("my-gitlab-secret"))
+		})
+
+		By("checking the config of the connector types the operator models", func() {
+			connectors := []DexConnectorSpec{{Type: "github", Id: "github", Name: "GitHub", RawConfig: json.RawMessage(`{"clientID":"dex","teamNames":["admins"]}`)}}
+			values, err := getDexConfigValues(dexServer, dexServer.Spec.Issuer, connectors, nil)
+			Expect(err).To(BeNil())
+			rendered, err := rDexServer.renderDexConfig(dexServer, values)
+			Expect(err).To(BeNil())
+			_, err = dexconfig.Load([]byte(rendered))
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("config.teamNames is not a dex setting"))
+		})
+	})
+	It("should only allow the mock connectors with allowInsecureConnectors", func() {
+		spec := &authv1alpha1.DexServerSpec{}
+		connector := &authv1alpha1.ConnectorSpec{Type: authv1alpha1.ConnectorTypeMockPassword, Id: "mock"}
+		errs := authv1alpha1.ValidateMockConnector(spec, connector, field.NewPath("spec", "connectors").Index(0))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].type: Forbidden"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].mockPassword.username: Required value"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.connectors[0].mockPassword.passwordRef.name: Required value"))
+
+		spec.AllowInsecureConnectors = true
+		connector.MockPassword = authv1alpha1.MockPasswordConfigSpec{Username: "kilgore", PasswordRef: corev1.SecretReference{Name: "mock-password"}}
+		Expect(authv1alpha1.ValidateMockConnector(spec, connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+		connector.Type = authv1alpha1.ConnectorTypeMockCallback
+		Expect(authv1alpha1.ValidateMockConnector(spec, connector, field.NewPath("spec", "connectors").Index(0))).To(BeEmpty())
+
+		dexServer := &authv1alpha1.DexServer{Spec: authv1alpha1.DexServerSpec{Connectors: []authv1alpha1.ConnectorSpec{*connector}}}
+		Expect(validateGroupBinding(dexServer, authv1alpha1.GroupBinding{Connector: "mock", Group: MOCK_CALLBACK_GROUP})).To(Succeed())
+		Expect(validateGroupBinding(dexServer, authv1alpha1.GroupBinding{Connector: "mock", Group: "admins"})).NotTo(Succeed())
+	})
+	It("should render the storage with its password read from the environment", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-storage-dexserver", Namespace: "my-config-ns"},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://storage.testhost.com"},
+		}
+		config := loadDexConfig(dexServer, nil)
+		Expect(config.Storage.Type).To(Equal("kubernetes"))
+		Expect(string(config.Storage.Config)).To(MatchJSON(`{"inCluster":true}`))
+
+		dexServer.Spec.Storage = authv1alpha1.StorageSpec{
+			Type: authv1alpha1.StorageTypePostgres,
+			Postgres: &authv1alpha1.PostgresStorageSpec{
+				Host:        "postgres.testhost.com",
+				Database:    "dex",
+				User:        "dex",
+				PasswordRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "dex-postgres"}, Key: "password"},
+			},
+		}
+		Expect(authv1alpha1.ValidateStorage(&dexServer.Spec, field.NewPath("spec", "storage"))).To(BeEmpty())
+		config = loadDexConfig(dexServer, nil)
+		Expect(config.Storage.Type).To(Equal("postgres"))
+		Expect(string(config.Storage.Config)).To(MatchJSON(`{"host":"postgres.testhost.com","port":5432,"database":"dex","user":"dex",` +
+			`"password":"${` + STORAGE_PASSWORD_ENV_VAR + `}","ssl":{"mode":"verify-full"}}`))
+		Expect(getStorageEnvVariables(dexServer.Spec.Storage, STORAGE_PASSWORD_ENV_VAR)).To(Equal([]corev1.EnvVar{{
+			Name:      STORAGE_PASSWORD_ENV_VAR,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &dexServer.Spec.Storage.Postgres.PasswordRef},
+		}}))
+		Expect(getReferencedSecretKeys(dexServer)).To(ContainElement("my-config-ns/dex-postgres"))
+
+		By("refusing the features reading the kubernetes storage", func() {
+			dexServer.Spec.StorageCleanup.Enabled = true
+			dexServer.Spec.Replaces = &authv1alpha1.HandoverSpec{Name: "previous"}
+			errs := authv1alpha1.ValidateStorage(&dexServer.Spec, field.NewPath("spec", "storage"))
+			Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.storageCleanup.enabled: Forbidden"))
+			// the handover reads and writes the signing keys through the storages
+			Expect(errs.ToAggregate().Error()).ToNot(ContainSubstring("spec.replaces"))
+		})
+		By("requiring the settings of the storage type", func() {
+			storage := &authv1alpha1.StorageSpec{Type: authv1alpha1.StorageTypeEtcd, Postgres: dexServer.Spec.Storage.Postgres}
+			errs := authv1alpha1.ValidateStorageSpec(storage, field.NewPath("spec", "storage"))
+			Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.storage.etcd: Required value"))
+			Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.storage.postgres: Forbidden"))
+		})
+	})
+})
@@ -8,8 +8,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +21,7 @@ import (
 	deployUtil "github.com/openshift/cluster-resource-override-admission-operator/pkg/deploy"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -25,10 +30,14 @@ import (
 	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
 	"open-cluster-management.io/clusteradm/pkg/helpers/asset"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -43,6 +52,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/dexconfig"
+	"github.com/identitatem/dex-operator/controllers/diff"
+	"github.com/identitatem/dex-operator/controllers/failures"
+	"github.com/identitatem/dex-operator/controllers/tracing"
 	deploy "github.com/identitatem/dex-operator/deploy"
 )
 
@@ -50,11 +63,47 @@ const (
 	SECRET_MTLS_NAME            = "grpc-mtls"
 	SECRET_WEB_TLS_SUFFIX       = "-tls-secret"
 	SERVICE_ACCOUNT_NAME        = "dex-operator-dexsso"
+	DEX_HTTPS_PORT              = 5556
+	DEX_GRPC_PORT               = 5557
 	GRPC_SERVICE_NAME           = "grpc"
 	DEX_IMAGE_ENV_NAME          = "RELATED_IMAGE_DEX"
 	MTLS_CERT_EXPIRY_ANNOTATION = "auth.identitatem.io/expiry"
-	IDP_CREDENTIAL_LABEL        = "auth.identitatem.io/idp-credential"
-	DEXSERVER_FINALIZER         = "auth.identitatem.io/cleanup"
+	GRPC_MTLS_EXPIRY_ANNOTATION = "auth.identitatem.io/grpcMtlsExpiry"
+	// Time after which the previous CA is removed from the ca.crt trust bundle of the mtls secret
+	MTLS_CA_OVERLAP_EXPIRY_ANNOTATION = "auth.identitatem.io/caOverlapExpiry"
+	IDP_CREDENTIAL_LABEL              = "auth.identitatem.io/idp-credential"
+	// Labels set on every object managed for a DexServer, see getManagedLabels
+	MANAGED_BY_LABEL = "app.kubernetes.io/managed-by"
+	MANAGED_BY_VALUE = "dex-operator"
+	INSTANCE_LABEL   = "app.kubernetes.io/instance"
+	COMPONENT_LABEL  = "app.kubernetes.io/component"
+	VERSION_LABEL    = "app.kubernetes.io/version"
+	// Hash of the list of objects managed for a DexServer, see getInventoryHash
+	INVENTORY_HASH_ANNOTATION = "auth.identitatem.io/inventoryHash"
+	DEXSERVER_FINALIZER       = "auth.identitatem.io/cleanup"
+	// Keys under which the previous mTLS credentials are kept while the dex server rolls out a new certificate
+	MTLS_PREVIOUS_CA_KEY         = "previous-ca.crt"
+	MTLS_PREVIOUS_CLIENT_CRT_KEY = "previous-client.crt"
+	MTLS_PREVIOUS_CLIENT_KEY_KEY = "previous-client.key"
+	// Number of changed fields listed in an Event, all of them are logged at debug level
+	MAX_EVENT_DIFF_FIELDS = 10
+	// Namespace of the DexServer a ManifestWork distributing the issuer trust belongs to
+	DEXSERVER_NAMESPACE_LABEL = "auth.identitatem.io/dexserver-namespace"
+	// Set on a DexServer whose issuer is served by the DexServer replacing it, see spec.replaces
+	HANDED_OVER_ANNOTATION = "auth.identitatem.io/handedOverTo"
+	// Set by the owner of a DexServer to allow the DexServer <namespace>/<name> of another namespace to replace it
+	HANDOVER_ALLOWED_ANNOTATION = "auth.identitatem.io/handoverTo"
+	// Time between reconciles while an issuer is handed over
+	HANDOVER_REQUEUE_INTERVAL = 10 * time.Second
+	// Bounds of the time between reconciles while a connector secret is missing, see getSecretWaitBackoff
+	SECRET_WAIT_MIN_BACKOFF = 5 * time.Second
+	SECRET_WAIT_MAX_BACKOFF = 5 * time.Minute
+	// Key of the serviceAccountRef Secret of a Google connector holding the JSON key of the service account
+	GOOGLE_SERVICE_ACCOUNT_KEY = "service-account.json"
+	// Environment variable holding the client ID of a GitHub connector with a clientIDRef, suffixed like the client
+	// secret with the alphanumeric id of the connector, and the key of the clientIDRef Secret it is read from
+	GITHUB_CLIENT_ID_ENV_VAR = "GITHUB_CLIENT_ID"
+	GITHUB_CLIENT_ID_KEY     = "clientID"
 )
 
 type ConnectorSecret struct {
@@ -63,10 +112,22 @@ type ConnectorSecret struct {
 }
 
 var envVariableForConnector = map[authv1alpha1.ConnectorType]ConnectorSecret{
+	"bitbucketcloud": {
+		EnvVarName: "BITBUCKET_CLOUD_CLIENT_SECRET",
+		SecretKey:  "clientSecret",
+	},
+	"gitea": {
+		EnvVarName: "GITEA_CLIENT_SECRET",
+		SecretKey:  "clientSecret",
+	},
 	"github": {
 		EnvVarName: "GITHUB_CLIENT_SECRET",
 		SecretKey:  "clientSecret",
 	},
+	"google": {
+		EnvVarName: "GOOGLE_CLIENT_SECRET",
+		SecretKey:  "clientSecret",
+	},
 	"ldap": {
 		EnvVarName: "LDAP_BIND_PW",
 		SecretKey:  "bindPW",
@@ -75,10 +136,27 @@ var envVariableForConnector = map[authv1alpha1.ConnectorType]ConnectorSecret{
 		EnvVarName: "MICROSOFT_CLIENT_SECRET",
 		SecretKey:  "clientSecret",
 	},
+	"mockPassword": {
+		EnvVarName: "MOCK_PASSWORD",
+		SecretKey:  "password",
+	},
 	"oidc": {
 		EnvVarName: "OIDC_CLIENT_SECRET",
 		SecretKey:  "clientSecret",
 	},
+	"openshift": {
+		EnvVarName: "OPENSHIFT_CLIENT_SECRET",
+		SecretKey:  "clientSecret",
+	},
+}
+
+// Check the connector type is deployed by the operator. The SAML connectors have no credential secret, the
+// responses of the identity provider are signed, nor do the authproxy connectors, which trust their proxy, and the
+// mockCallback connectors. The raw connectors have a secret for each placeholder of their config.
+func isKnownConnectorType(connectorType authv1alpha1.ConnectorType) bool {
+	_, known := envVariableForConnector[connectorType]
+	return known || connectorType == authv1alpha1.ConnectorTypeSAML || connectorType == authv1alpha1.ConnectorTypeAuthProxy ||
+		connectorType == authv1alpha1.ConnectorTypeMockCallback || connectorType == authv1alpha1.ConnectorTypeRaw
 }
 
 // DexServerReconciler reconciles a DexServer object
@@ -88,6 +166,41 @@ type DexServerReconciler struct {
 	DynamicClient      dynamic.Interface
 	APIExtensionClient apiextensionsclient.Interface
 	Scheme             *runtime.Scheme
+	// OpenShift is set when the cluster serves the OpenShift APIs, see IsOpenShift
+	OpenShift bool
+	// PreProvisionedRBAC is set when the ClusterRole and ClusterRoleBindings of the dex servers are created by an
+	// administrator. The operator then validates they exist instead of creating them.
+	PreProvisionedRBAC bool
+	// ClusterRoleName is the name of the ClusterRole bound to the dex server service accounts, defaults to SERVICE_ACCOUNT_NAME
+	ClusterRoleName string
+	// Recorder emits the Events summarizing the changes made to the objects owned by a DexServer
+	Recorder record.EventRecorder
+	// FIPS is set when the generated certificates must only use FIPS approved algorithms and key sizes, the dex
+	// servers are then started in FIPS mode
+	FIPS bool
+	// RequireEncryptionAtRest is set when the credentials and dex configuration must only be written to an etcd
+	// verified to be encrypted at rest
+	RequireEncryptionAtRest bool
+	// KeyPool provides the pre-generated keys of the certificates, the keys are generated in the reconcile when nil
+	KeyPool *KeyPool
+	// Observe is set when the operator must not write, the DexServers are reconciled with dry runs and the changes
+	// they would make are reported, see OBSERVE_ANNOTATION
+	Observe bool
+	// NamespaceCleanupDryRun is set when the shared objects of the namespaces without DexServer must only be
+	// reported, see cleanupNamespace
+	NamespaceCleanupDryRun bool
+	// Resolver looks up the issuer hosts of the DexServers with spec.route.validateDNS, net.DefaultResolver when nil
+	Resolver HostResolver
+	// Notifier posts the Degraded transitions and the credentials about to expire to a webhook, disabled when nil
+	Notifier *Notifier
+	// IngressControllerNamespaces are admitted to the HTTPS port of the dex servers with authproxy connectors outside of
+	// OpenShift, DEFAULT_INGRESS_CONTROLLER_NAMESPACE when empty
+	IngressControllerNamespaces []string
+
+	// DryRun of the writes made through DynamicClient, set by dryRunReconciler
+	dryRun []string
+	// Set once the ClusterRole of dex is installed, see ensureClusterRole
+	clusterRoleInstalled bool
 }
 
 //+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexservers,verbs=get;list;watch;create;update;patch;delete
@@ -95,10 +208,15 @@ type DexServerReconciler struct {
 //+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexservers/finalizers,verbs=update
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;delete
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups=config.openshift.io,resources=ingresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=config.openshift.io,resources=apiservers,verbs=get;list;watch
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes/custom-host,verbs=create;patch
 //+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterroles},verbs=get;list;watch;create;update;patch;delete;escalate;bind
@@ -106,6 +224,16 @@ type DexServerReconciler struct {
 //+kubebuilder:rbac:groups="apiextensions.k8s.io",resources={customresourcedefinitions},verbs=get;list;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=dex.coreos.com,resources=signingkeies,verbs=get;create;update
+//+kubebuilder:rbac:groups=dex.coreos.com,resources=refreshtokens;offlinesessionses,verbs=list
+//+kubebuilder:rbac:groups=dex.coreos.com,resources=authcodes;authrequests;devicerequests;devicetokens,verbs=list;delete
+//+kubebuilder:rbac:groups=cluster.open-cluster-management.io,resources=managedclusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=work.open-cluster-management.io,resources=manifestworks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=user.openshift.io,resources=groups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=console.openshift.io,resources=consolelinks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=oauth.openshift.io,resources=oauthclients,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling.k8s.io,resources=verticalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -119,6 +247,8 @@ type DexServerReconciler struct {
 func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := ctrllog.FromContext(ctx)
 	log.V(1).Info("Reconciling...")
+	ctx, span := tracing.Start(ctx, "DexServer.Reconcile", "namespace", req.Namespace, "name", req.Name)
+	defer span.End()
 
 	// Fetch the DexServer instance
 	dexServer := &authv1alpha1.DexServer{}
@@ -128,9 +258,29 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		dexServer,
 	); err != nil {
 		log.Error(err, "failed to fetch DexServer instance")
+		if kubeerrors.IsNotFound(err) {
+			observedChanges.DeleteLabelValues(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if r.isObserved(dexServer) {
+		return r.observe(dexServer, ctx)
+	}
+	// The DexServer may have left the observe mode
+	observedChanges.DeleteLabelValues(dexServer.Namespace, dexServer.Name)
+	ctx, timer := withReconcileTimer(ctx)
+	defer timer.observe(dexServer)
+	wasDegraded := meta.IsStatusConditionTrue(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded)
+	result, err := r.reconcileDexServer(dexServer, ctx)
+	r.Notifier.notifyDegraded(dexServer, wasDegraded, ctx)
+	return result, err
+}
+
+// Reconcile the objects owned by the DexServer and its status, see observe for the dry runs of the observe mode
+func (r *DexServerReconciler) reconcileDexServer(dexServer *authv1alpha1.DexServer, ctx context.Context) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
 	// If a deletionTimestamp exists this means the dex server is being deleted, we need to also delete the associated ClusterRoleBinding
 	if dexServer.DeletionTimestamp != nil {
 		if err := r.processDexServerDeletion(dexServer, ctx); err != nil {
@@ -154,13 +304,59 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
+	// The objects of the layouts of the previous operator versions are upgraded before they are reconciled
+	if err := tracePhase(ctx, "migrateLayout", dexServer, r.migrateLayout); err != nil {
+		log.Error(err, "failed to migrate the layout")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "LayoutMigrationFailed"),
+			Message: fmt.Sprintf("failed to migrate the objects of the previous operator version. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Tear down the DexServer once its spec.ttl elapsed
+	if expired, err := r.checkTTL(dexServer, ctx); err != nil || expired {
+		if err != nil {
+			log.Error(err, "failed to delete the expired DexServer")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// The objects rendered from the templates are server-side applied while the DexServer is being repaired
+	var repair *repairReport
+	if isRepairRequested(dexServer) && getObserveReport(ctx) == nil {
+		log.Info("Repairing the DexServer objects")
+		ctx, repair = withRepairReport(ctx)
+	}
+
+	if err := tracePhase(ctx, "checkEncryptionAtRest", dexServer, r.checkEncryptionAtRest); err != nil {
+		log.Error(err, "failed to check the encryption at rest")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "EncryptionAtRestRequired"),
+			Message: fmt.Sprintf("failed to check the encryption at rest. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
 	// Prepare Mutual TLS for gRPC connection
-	if err := r.manageMTLSSecret(dexServer, ctx); err != nil {
+	if err := tracePhase(ctx, "manageMTLSSecret", dexServer, r.manageMTLSSecret); err != nil {
 		log.Error(err, "failed to manage mtls secret")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigMTLSSecretFailed",
+			Reason: recordFailure(dexServer, err, "ConfigMTLSSecretFailed"),
 			Message: fmt.Sprintf("failed to configure MTLS secret. error: %s",
 				err.Error()),
 		}
@@ -170,13 +366,13 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	if err := r.syncConfigMap(dexServer, ctx); err != nil {
-		log.Error(err, "failed to sync ConfigMap")
+	if err := tracePhase(ctx, "syncService", dexServer, r.syncService); err != nil {
+		log.Error(err, "failed to sync http service")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigMapFailed",
-			Message: fmt.Sprintf("failed to sync ConfigMap. error: %s",
+			Reason: recordFailure(dexServer, err, "ConfigHTTPServiceFailed"),
+			Message: fmt.Sprintf("failed to sync http service. error: %s",
 				err.Error()),
 		}
 		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
@@ -185,13 +381,46 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	if err := r.syncService(dexServer, ctx); err != nil {
-		log.Error(err, "failed to sync http service")
+	if !r.OpenShift {
+		// Without the OpenShift service serving certificates, the operator provides the dex web certificate
+		if err := tracePhase(ctx, "syncServingCertSecret", dexServer, r.syncServingCertSecret); err != nil {
+			log.Error(err, "failed to sync serving certificate secret")
+			cond := metav1.Condition{
+				Type:   authv1alpha1.DexServerConditionTypeApplied,
+				Status: metav1.ConditionFalse,
+				Reason: recordFailure(dexServer, err, "ConfigServingCertSecretFailed"),
+				Message: fmt.Sprintf("failed to sync serving certificate secret. error: %s",
+					err.Error()),
+			}
+			if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := tracePhase(ctx, "syncConfigMap", dexServer, r.syncConfigMap); err != nil {
+		var missing *missingSecretError
+		if errors.As(err, &missing) {
+			// the secret may be created later, for example by a GitOps tool
+			log.Info("waiting for connector secret", "Secret.Namespace", missing.Namespace, "Secret.Name", missing.Name)
+			cond := metav1.Condition{
+				Type:    authv1alpha1.DexServerConditionTypeApplied,
+				Status:  metav1.ConditionFalse,
+				Reason:  "WaitingForSecret",
+				Message: err.Error(),
+			}
+			if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: getSecretWaitBackoff(dexServer)}, nil
+		}
+		log.Error(err, "failed to sync ConfigMap")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigHTTPServiceFailed",
-			Message: fmt.Sprintf("failed to sync http service. error: %s",
+			Reason: recordFailure(dexServer, err, "ConfigMapFailed"),
+			Message: fmt.Sprintf("failed to sync ConfigMap. error: %s",
 				err.Error()),
 		}
 		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
@@ -200,12 +429,12 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	if err := r.syncServiceGrpc(dexServer, ctx); err != nil {
+	if err := tracePhase(ctx, "syncServiceGrpc", dexServer, r.syncServiceGrpc); err != nil {
 		log.Error(err, "failed to sync grpc Service")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigGRPCServiceFailed",
+			Reason: recordFailure(dexServer, err, "ConfigGRPCServiceFailed"),
 			Message: fmt.Sprintf("failed to sync grpc service. error: %s",
 				err.Error()),
 		}
@@ -216,12 +445,27 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	if err := r.syncServiceAccount(dexServer, ctx); err != nil {
+	if err := tracePhase(ctx, "syncServiceMetrics", dexServer, r.syncServiceMetrics); err != nil {
+		log.Error(err, "failed to sync metrics Service")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigMetricsServiceFailed"),
+			Message: fmt.Sprintf("failed to sync metrics service. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncServiceAccount", dexServer, r.syncServiceAccount); err != nil {
 		log.Error(err, "failed to sync ServiceAccount")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigServiceAccountFailed",
+			Reason: recordFailure(dexServer, err, "ConfigServiceAccountFailed"),
 			Message: fmt.Sprintf("failed to sync ServiceAccount. error: %s",
 				err.Error()),
 		}
@@ -231,12 +475,37 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	if err := r.syncClusterRoleBinding(dexServer, ctx); err != nil {
+	// conditions of the RBAC of dex, reported with the Applied condition
+	rbacCond := []metav1.Condition{}
+	var rbacEscalation *rbacEscalationError
+	if r.PreProvisionedRBAC {
+		if err := tracePhase(ctx, "validatePreProvisionedRBAC", dexServer, r.validatePreProvisionedRBAC); err != nil {
+			log.Error(err, "pre-provisioned RBAC is missing")
+			cond := metav1.Condition{
+				Type:   authv1alpha1.DexServerConditionTypeApplied,
+				Status: metav1.ConditionFalse,
+				Reason: recordFailure(dexServer, err, "PreProvisionedRBACMissing"),
+				Message: fmt.Sprintf("pre-provisioned RBAC is missing. error: %s",
+					err.Error()),
+			}
+			if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+	} else if err := tracePhase(ctx, "syncRBAC", dexServer, r.syncRBAC); errors.As(err, &rbacEscalation) {
+		// the other objects are still applied, the dex server runs once the permissions are granted
+		log.Error(err, "not allowed to grant the RBAC permissions of dex")
+		recordFailure(dexServer, err, "ConfigClusterRoleBindingFailed")
+		if err := updateDexServerStatusConditions(r.Client, dexServer, getRBACEscalationCondition(rbacEscalation)); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
 		log.Error(err, "failed to sync ClusterRoleBinding")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigClusterRoleBindingFailed",
+			Reason: recordFailure(dexServer, err, "ConfigClusterRoleBindingFailed"),
 			Message: fmt.Sprintf("failed to sync ClusterRoleBinding. error: %s",
 				err.Error()),
 		}
@@ -244,14 +513,31 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, err
+	} else {
+		rbacCond = append(rbacCond, getRBACEscalationCondition(nil))
+	}
+
+	if err := tracePhase(ctx, "importSigningKeys", dexServer, r.importSigningKeys); err != nil {
+		log.Error(err, "failed to import signing keys")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ImportSigningKeysFailed"),
+			Message: fmt.Sprintf("failed to import the signing keys of the replaced DexServer. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
 	}
 
-	if err := r.syncDeployment(dexServer, ctx); err != nil {
+	if err := tracePhase(ctx, "syncDeployment", dexServer, r.syncDeployment); err != nil {
 		log.Error(err, "failed to sync Deployment")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigDeploymentFailed",
+			Reason: recordFailure(dexServer, err, "ConfigDeploymentFailed"),
 			Message: fmt.Sprintf("failed to sync Deployment. error: %s",
 				err.Error()),
 		}
@@ -261,12 +547,42 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	if err := r.syncIngress(dexServer, ctx); err != nil {
+	if err := tracePhase(ctx, "syncVerticalAutoscaling", dexServer, r.syncVerticalAutoscaling); err != nil {
+		log.Error(err, "failed to sync the VerticalPodAutoscaler")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigVerticalAutoscalingFailed"),
+			Message: fmt.Sprintf("failed to sync the VerticalPodAutoscaler. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncAuthProxyNetworkPolicy", dexServer, r.syncAuthProxyNetworkPolicy); err != nil {
+		log.Error(err, "failed to sync the NetworkPolicy of the authproxy connectors")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigAuthProxyNetworkPolicyFailed"),
+			Message: fmt.Sprintf("failed to sync the NetworkPolicy of the authproxy connectors. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncIngress", dexServer, r.syncIngress); err != nil {
 		log.Error(err, "failed to sync Ingress")
 		cond := metav1.Condition{
 			Type:   authv1alpha1.DexServerConditionTypeApplied,
 			Status: metav1.ConditionFalse,
-			Reason: "ConfigIngressFailed",
+			Reason: recordFailure(dexServer, err, "ConfigIngressFailed"),
 			Message: fmt.Sprintf("failed to sync Ingress. error: %s",
 				err.Error()),
 		}
@@ -276,27 +592,253 @@ func (r *DexServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	if err := tracePhase(ctx, "syncTrustDistribution", dexServer, r.syncTrustDistribution); err != nil {
+		log.Error(err, "failed to sync trust distribution")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigTrustDistributionFailed"),
+			Message: fmt.Sprintf("failed to sync trust distribution. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncConnectionInfo", dexServer, r.syncConnectionInfo); err != nil {
+		log.Error(err, "failed to sync connection info")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigConnectionInfoFailed"),
+			Message: fmt.Sprintf("failed to sync connection info. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncGroupBindings", dexServer, r.syncGroupBindings); err != nil {
+		log.Error(err, "failed to sync group bindings")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigGroupBindingsFailed"),
+			Message: fmt.Sprintf("failed to sync group bindings. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncTeams", dexServer, r.syncTeams); err != nil {
+		log.Error(err, "failed to sync GitHub teams")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigTeamSyncFailed"),
+			Message: fmt.Sprintf("failed to sync GitHub teams. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncPasswordClient", dexServer, r.syncPasswordClient); err != nil {
+		log.Error(err, "failed to sync the password client")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigPasswordClientFailed"),
+			Message: fmt.Sprintf("failed to sync the password client. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncOAuthProxyClient", dexServer, r.syncOAuthProxyClient); err != nil {
+		log.Error(err, "failed to sync the oauth2-proxy client")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigOAuthProxyClientFailed"),
+			Message: fmt.Sprintf("failed to sync the oauth2-proxy client. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncConsoleLink", dexServer, r.syncConsoleLink); err != nil {
+		log.Error(err, "failed to sync the ConsoleLink")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigConsoleLinkFailed"),
+			Message: fmt.Sprintf("failed to sync the ConsoleLink. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := tracePhase(ctx, "syncOAuthClients", dexServer, r.syncOAuthClients); err != nil {
+		log.Error(err, "failed to sync the OAuthClients")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "ConfigOAuthClientFailed"),
+			Message: fmt.Sprintf("failed to sync the OAuthClients. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if issuer, err := r.getIssuer(dexServer, ctx); err == nil {
+		dexServer.Status.Issuer = issuer
+	}
+	dexServer.Status.RelatedObjects = r.getInventory(dexServer)
+	r.reportCredentialExpiry(dexServer, ctx)
+	r.reportCredentialRotation(dexServer, ctx)
+	r.reportSessions(dexServer, ctx)
+	r.cleanupStorage(dexServer, ctx)
 	cond := metav1.Condition{
 		Type:    authv1alpha1.DexServerConditionTypeApplied,
 		Status:  metav1.ConditionTrue,
 		Reason:  "Applied",
 		Message: "DexServer is applied",
 	}
-	if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+	if rbacEscalation != nil {
+		cond = metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeApplied,
+			Status:  metav1.ConditionFalse,
+			Reason:  string(failures.RBACEscalationDenied),
+			Message: "DexServer is applied except the RBAC of dex, see the RBACEscalationDenied condition",
+		}
+	}
+	if err := updateDexServerStatusConditions(r.Client, dexServer, append(rbacCond, cond)...); err != nil {
 		return ctrl.Result{}, err
 	}
+	if repair != nil {
+		if err := r.completeRepair(dexServer, ctx, repair); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 
 	log.Info("Checking for dexServer deployment status")
 	cond, err := r.getDexServerDeploymentCondition(dexServer)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	if err := updateDexServerStatusConditions(r.Client, dexServer, cond); err != nil {
+	handingOver, err := r.advanceHandover(dexServer, ctx, cond.Status == metav1.ConditionTrue)
+	if err != nil {
+		log.Error(err, "failed to hand over the issuer")
+		handoverCond := metav1.Condition{
+			Type:   authv1alpha1.DexServerConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: recordFailure(dexServer, err, "HandoverFailed"),
+			Message: fmt.Sprintf("failed to hand over the issuer. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerStatusConditions(r.Client, dexServer, cond, handoverCond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+	readyCond, err := r.getReadyCondition(dexServer, ctx, cond)
+	if err != nil {
+		log.Error(err, "failed to run the smoke test")
+		return ctrl.Result{}, err
+	}
+	if err := r.reportRollout(dexServer, ctx); err != nil {
+		log.Error(err, "failed to report the rollout of the Deployment")
+		return ctrl.Result{}, err
+	}
+	if err := r.syncDiscoveryCache(dexServer, ctx, cond.Status == metav1.ConditionTrue); err != nil {
+		log.Error(err, "failed to sync the discovery cache")
+		return ctrl.Result{}, err
+	}
+	if err := r.syncExternalIssuer(dexServer, ctx); err != nil {
+		log.Error(err, "failed to probe the external issuer")
 		return ctrl.Result{}, err
 	}
+	if err := updateDexServerStatusConditions(r.Client, dexServer, cond, readyCond); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Reconcile hourly to ensure grpc mtls certs are regenerated before expiry, and to report the credential expiry
+	// and the sessions
+	requeueAfter := 1 * time.Hour
+	if dexServer.Spec.ConnectorFailover.Enabled || hasLDAPReplicas(dexServer) {
+		// Probe the connectors, and the replicas of the LDAP connectors, again
+		if interval := getConnectorProbeInterval(dexServer); interval < requeueAfter {
+			requeueAfter = interval
+		}
+	}
+	if handingOver {
+		requeueAfter = HANDOVER_REQUEUE_INTERVAL
+	}
+	if rbacEscalation != nil && RBAC_ESCALATION_REQUEUE_INTERVAL < requeueAfter {
+		// check whether the permissions were granted to the operator
+		requeueAfter = RBAC_ESCALATION_REQUEUE_INTERVAL
+	}
+	if meta.IsStatusConditionTrue(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeWaitingForSecret) {
+		// connectors are skipped until their secret exists
+		if backoff := getSecretWaitBackoff(dexServer); backoff < requeueAfter {
+			requeueAfter = backoff
+		}
+	}
+	if dexServer.Spec.StorageCleanup.Enabled {
+		// delete the objects expired since the last cleanup
+		if cleanupRequeueAfter := getStorageCleanupRequeueAfter(dexServer, time.Now()); cleanupRequeueAfter > 0 && cleanupRequeueAfter < requeueAfter {
+			requeueAfter = cleanupRequeueAfter
+		}
+	}
+	if dexServer.Spec.DiscoveryCache.Enabled && DISCOVERY_CACHE_REFRESH_INTERVAL < requeueAfter {
+		// copy the rotated signing keys
+		requeueAfter = DISCOVERY_CACHE_REFRESH_INTERVAL
+	}
+	if dexServer.Spec.ExternalIssuer.Enabled {
+		// probe the issuer through the load balancer again
+		if interval := getExternalIssuerProbeInterval(dexServer); interval < requeueAfter {
+			requeueAfter = interval
+		}
+	}
+	if ttlRequeueAfter, ok := getTTLRequeueAfter(dexServer); ok && ttlRequeueAfter < requeueAfter {
+		// refresh the countdown of spec.ttl
+		requeueAfter = ttlRequeueAfter
+	}
+	return ctrl.Result{Requeue: true, RequeueAfter: requeueAfter}, nil
+}
 
-	// Reconcile hourly to ensure grpc mtls certs are regenerated before expiry
-	return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Hour}, nil
+// Run a reconcile phase in its own trace span
+func tracePhase(ctx context.Context, phase string, dexServer *authv1alpha1.DexServer, sync func(*authv1alpha1.DexServer, context.Context) error) error {
+	ctx, span := tracing.Start(ctx, phase)
+	defer span.End()
+	if certPhases[phase] {
+		defer timePhase(ctx, reconcilePhaseCert, time.Now())
+	}
+	err := sync(dexServer, ctx)
+	span.RecordError(err)
+	return err
 }
 
 // Get status (availability) of DexServer deployment
@@ -334,30 +876,39 @@ func (r *DexServerReconciler) getDexServerDeploymentCondition(dexServer *authv1a
 
 // Handle cleanup during DexServer deletion
 func (r *DexServerReconciler) processDexServerDeletion(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
-	log := ctrllog.FromContext(ctx)
-	clusterRoleBindingName := SERVICE_ACCOUNT_NAME + "-" + dexServer.Namespace
-	log.Info("processDexServerDeletion", "Clean up ClusterRoleBinding", clusterRoleBindingName)
-
-	// Delete ClusterRoleBinding
-	crb := &rbacv1.ClusterRoleBinding{}
-	err := r.Client.Get(context.TODO(), client.ObjectKey{Name: clusterRoleBindingName}, crb)
-	switch {
-	case err == nil:
-		if err := r.Client.Delete(context.TODO(), crb); err != nil {
-			log.Error(err, "failed to delete ClusterRoleBinding")
-			return err
-		}
-	case !kubeerrors.IsNotFound(err):
-		log.Error(err, "failed to fetch ClusterRoleBinding")
+	unencryptedCredentials.DeleteLabelValues(dexServer.Namespace, dexServer.Name)
+	deleteCredentialExpiryMetrics(dexServer)
+	deleteCredentialRotationMetrics(dexServer)
+	deleteSessionMetrics(dexServer)
+	deleteReconcileDurationMetrics(dexServer)
+	observedChanges.DeleteLabelValues(dexServer.Namespace, dexServer.Name)
+	// ManifestWorks are in the managed cluster namespaces, they are not garbage collected with the DexServer
+	if err := r.deleteTrustManifestWorks(dexServer, ctx, nil); err != nil {
 		return err
 	}
-	return nil
-}
-
-// Check if the secret already contains the required label "auth.identitatem.io/idp-credential"
-// and if it doesn't then add the label - this label allows us to watch specific secrets for updates
-func checkAndAddLabelToSecret(secret *corev1.Secret, r *DexServerReconciler, ctx context.Context) {
-	log := ctrllog.FromContext(ctx)
+	if err := r.deleteGroupBindings(dexServer, ctx); err != nil {
+		return err
+	}
+	if err := r.deleteTeamSyncGroups(dexServer, ctx, nil); err != nil {
+		return err
+	}
+	if err := r.deleteConsoleLink(dexServer, ctx); err != nil {
+		return err
+	}
+	if err := r.deleteOAuthClients(dexServer, ctx, nil); err != nil {
+		return err
+	}
+	if err := r.deleteOAuthProxySecretCopies(dexServer, ctx, nil); err != nil {
+		return err
+	}
+	// The shared objects of the namespace outlive the DexServers, they are deleted with the last one
+	return r.cleanupDexServerNamespace(dexServer, ctx)
+}
+
+// Check if the secret already contains the required label "auth.identitatem.io/idp-credential"
+// and if it doesn't then add the label - this label allows us to watch specific secrets for updates
+func checkAndAddLabelToSecret(secret *corev1.Secret, r *DexServerReconciler, ctx context.Context) {
+	log := ctrllog.FromContext(ctx)
 
 	if secret.Labels == nil {
 		secret.Labels = make(map[string]string)
@@ -374,6 +925,28 @@ func getConnectorSecretFromRef(connector authv1alpha1.ConnectorSpec, m *authv1al
 	var secretNamespace, secretName string
 
 	switch connector.Type {
+	case authv1alpha1.ConnectorTypeBitbucketCloud:
+		secretName = connector.BitbucketCloud.ClientSecretRef.Name
+		if secretNamespace = connector.BitbucketCloud.ClientSecretRef.Namespace; secretNamespace == "" {
+			secretNamespace = m.Namespace
+		}
+		resource := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil && kubeerrors.IsNotFound(err) {
+			return "", err
+		}
+		checkAndAddLabelToSecret(resource, r, ctx)
+		return string(resource.Data["clientSecret"]), nil
+	case authv1alpha1.ConnectorTypeGitea:
+		secretName = connector.Gitea.ClientSecretRef.Name
+		if secretNamespace = connector.Gitea.ClientSecretRef.Namespace; secretNamespace == "" {
+			secretNamespace = m.Namespace
+		}
+		resource := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil && kubeerrors.IsNotFound(err) {
+			return "", err
+		}
+		checkAndAddLabelToSecret(resource, r, ctx)
+		return string(resource.Data["clientSecret"]), nil
 	case authv1alpha1.ConnectorTypeGitHub:
 		secretName = connector.GitHub.ClientSecretRef.Name
 		if secretNamespace = connector.GitHub.ClientSecretRef.Namespace; secretNamespace == "" {
@@ -384,6 +957,30 @@ func getConnectorSecretFromRef(connector authv1alpha1.ConnectorSpec, m *authv1al
 			return "", err
 		}
 		checkAndAddLabelToSecret(resource, r, ctx)
+		value := string(resource.Data["clientSecret"])
+		// the client ID read from a secret is also passed to dex in its environment
+		if connector.GitHub.ClientIDRef.Name != "" {
+			if secretNamespace = connector.GitHub.ClientIDRef.Namespace; secretNamespace == "" {
+				secretNamespace = m.Namespace
+			}
+			resource := &corev1.Secret{}
+			if err := r.Get(ctx, types.NamespacedName{Name: connector.GitHub.ClientIDRef.Name, Namespace: secretNamespace}, resource); err != nil && kubeerrors.IsNotFound(err) {
+				return "", err
+			}
+			checkAndAddLabelToSecret(resource, r, ctx)
+			value += string(resource.Data[GITHUB_CLIENT_ID_KEY])
+		}
+		return value, nil
+	case authv1alpha1.ConnectorTypeGoogle:
+		secretName = connector.Google.ClientSecretRef.Name
+		if secretNamespace = connector.Google.ClientSecretRef.Namespace; secretNamespace == "" {
+			secretNamespace = m.Namespace
+		}
+		resource := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil && kubeerrors.IsNotFound(err) {
+			return "", err
+		}
+		checkAndAddLabelToSecret(resource, r, ctx)
 		return string(resource.Data["clientSecret"]), nil
 	case authv1alpha1.ConnectorTypeMicrosoft:
 		secretName = connector.Microsoft.ClientSecretRef.Name
@@ -407,6 +1004,17 @@ func getConnectorSecretFromRef(connector authv1alpha1.ConnectorSpec, m *authv1al
 		}
 		checkAndAddLabelToSecret(resource, r, ctx)
 		return string(resource.Data["bindPW"]), nil
+	case authv1alpha1.ConnectorTypeMockPassword:
+		secretName = connector.MockPassword.PasswordRef.Name
+		if secretNamespace = connector.MockPassword.PasswordRef.Namespace; secretNamespace == "" {
+			secretNamespace = m.Namespace
+		}
+		resource := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil && kubeerrors.IsNotFound(err) {
+			return "", err
+		}
+		checkAndAddLabelToSecret(resource, r, ctx)
+		return string(resource.Data["password"]), nil
 	case authv1alpha1.ConnectorTypeOIDC:
 		secretName = connector.OIDC.ClientSecretRef.Name
 		if secretNamespace = connector.OIDC.ClientSecretRef.Namespace; secretNamespace == "" {
@@ -418,12 +1026,367 @@ func getConnectorSecretFromRef(connector authv1alpha1.ConnectorSpec, m *authv1al
 		}
 		checkAndAddLabelToSecret(resource, r, ctx)
 		return string(resource.Data["clientSecret"]), nil
+	case authv1alpha1.ConnectorTypeOpenShift:
+		secretName = connector.OpenShift.ClientSecretRef.Name
+		if secretNamespace = connector.OpenShift.ClientSecretRef.Namespace; secretNamespace == "" {
+			secretNamespace = m.Namespace
+		}
+		resource := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil && kubeerrors.IsNotFound(err) {
+			return "", err
+		}
+		checkAndAddLabelToSecret(resource, r, ctx)
+		return string(resource.Data["clientSecret"]), nil
+	case authv1alpha1.ConnectorTypeRaw:
+		// the values of all the placeholders
+		values := ""
+		for _, secret := range connector.Raw.Secrets {
+			ref := getRawConnectorSecretRef(m, secret)
+			resource := &corev1.Secret{}
+			if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, resource); err != nil {
+				return "", err
+			}
+			checkAndAddLabelToSecret(resource, r, ctx)
+			values += string(resource.Data[secret.Key])
+		}
+		return values, nil
 	default:
 		return "", fmt.Errorf("could not retrieve secret")
 	}
 
 }
 
+// References to the secrets a connector needs to be rendered in the dex config
+func getConnectorSecretRefs(connector authv1alpha1.ConnectorSpec) []corev1.SecretReference {
+	switch connector.Type {
+	case authv1alpha1.ConnectorTypeBitbucketCloud:
+		return []corev1.SecretReference{connector.BitbucketCloud.ClientSecretRef}
+	case authv1alpha1.ConnectorTypeGitea:
+		return []corev1.SecretReference{connector.Gitea.ClientSecretRef}
+	case authv1alpha1.ConnectorTypeGitHub:
+		refs := []corev1.SecretReference{connector.GitHub.ClientSecretRef}
+		if connector.GitHub.ClientIDRef.Name != "" {
+			refs = append(refs, connector.GitHub.ClientIDRef)
+		}
+		if connector.GitHub.RootCARef.Name != "" {
+			refs = append(refs, connector.GitHub.RootCARef)
+		}
+		return refs
+	case authv1alpha1.ConnectorTypeGoogle:
+		refs := []corev1.SecretReference{connector.Google.ClientSecretRef}
+		if connector.Google.ServiceAccountRef.Name != "" {
+			refs = append(refs, connector.Google.ServiceAccountRef)
+		}
+		return refs
+	case authv1alpha1.ConnectorTypeMicrosoft:
+		return []corev1.SecretReference{connector.Microsoft.ClientSecretRef}
+	case authv1alpha1.ConnectorTypeLDAP:
+		refs := []corev1.SecretReference{connector.LDAP.BindPWRef}
+		if connector.LDAP.RootCARef.Name != "" {
+			refs = append(refs, connector.LDAP.RootCARef)
+		}
+		return refs
+	case authv1alpha1.ConnectorTypeMockPassword:
+		return []corev1.SecretReference{connector.MockPassword.PasswordRef}
+	case authv1alpha1.ConnectorTypeOIDC:
+		return []corev1.SecretReference{connector.OIDC.ClientSecretRef}
+	case authv1alpha1.ConnectorTypeOpenShift:
+		refs := []corev1.SecretReference{connector.OpenShift.ClientSecretRef}
+		if connector.OpenShift.RootCARef.Name != "" {
+			refs = append(refs, connector.OpenShift.RootCARef)
+		}
+		return refs
+	case authv1alpha1.ConnectorTypeRaw:
+		refs := []corev1.SecretReference{}
+		for _, secret := range connector.Raw.Secrets {
+			refs = append(refs, secret.SecretRef)
+		}
+		return refs
+	case authv1alpha1.ConnectorTypeSAML:
+		if connector.SAML.CARef.Name != "" {
+			return []corev1.SecretReference{connector.SAML.CARef}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Field index of the DexServers by the <namespace>/<name> of the Secrets they reference, see getReferencedSecretKeys
+const DEXSERVER_SECRET_INDEX = "dexserver.secretRefs"
+
+// Get the <namespace>/<name> of the Secrets referenced by the connectors, the team sync, the smoke test and the storage
+// of the DexServer. The references without namespace are in the DexServer namespace.
+func getReferencedSecretKeys(dexServer *authv1alpha1.DexServer) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	add := func(namespace string, name string) {
+		if name == "" {
+			return
+		}
+		if namespace == "" {
+			namespace = dexServer.Namespace
+		}
+		if key := namespace + "/" + name; !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, connector := range dexServer.Spec.Connectors {
+		for _, secretRef := range getConnectorSecretRefs(connector) {
+			add(secretRef.Namespace, secretRef.Name)
+		}
+	}
+	if ref := dexServer.Spec.TeamSync.TokenRef; ref != nil {
+		add("", ref.Name)
+	}
+	if ref := dexServer.Spec.SmokeTest.ClientSecretRef; ref != nil {
+		add("", ref.Name)
+	}
+	if ref := getStoragePasswordRef(dexServer.Spec.Storage); ref != nil {
+		add("", ref.Name)
+	}
+	return keys
+}
+
+// Get the requests of the DexServers referencing a Secret, through the DEXSERVER_SECRET_INDEX index of the cache
+func getDexServersForSecret(c client.Client, secret client.Object) []reconcile.Request {
+	var dexServerList authv1alpha1.DexServerList
+	key := secret.GetNamespace() + "/" + secret.GetName()
+	if err := c.List(context.TODO(), &dexServerList, client.MatchingFields{DEXSERVER_SECRET_INDEX: key}); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, dexServer := range dexServerList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace},
+		})
+	}
+	return requests
+}
+
+// Get the connectors rendered in the dex config, sorted by display order. Connectors of an unknown type, reusing
+// the id or name of a previous connector, or whose proxy conflicts with a previous connector, are rejected. A
+// connector whose secret is missing fails the sync with the FailClosed error policy, and is rejected with the
+// FailOpen policy.
+func (r *DexServerReconciler) getRenderedConnectors(dexServer *authv1alpha1.DexServer, ctx context.Context) ([]authv1alpha1.ConnectorSpec, []authv1alpha1.RejectedConnectorStatus, error) {
+	connectors := []authv1alpha1.ConnectorSpec{}
+	rejected := []authv1alpha1.RejectedConnectorStatus{}
+	// index of the connector rendered with each id and name
+	ids := map[string]int{}
+	names := map[string]int{}
+	proxies := newConnectorProxies()
+	for i, connector := range dexServer.Spec.Connectors {
+		if !isKnownConnectorType(connector.Type) {
+			rejected = append(rejected, authv1alpha1.RejectedConnectorStatus{
+				Id:     connector.Id,
+				Type:   connector.Type,
+				Reason: fmt.Sprintf("unknown connector type %q", connector.Type),
+			})
+			continue
+		}
+		// without the validating webhook, a connector whose users can't be restricted as requested, or that dex
+		// would refuse, is left out
+		fldPath := field.NewPath("spec", "connectors").Index(i)
+		errs := append(authv1alpha1.ValidateConnectorFilters(&connector, fldPath), authv1alpha1.ValidateSAMLConnector(&connector, fldPath)...)
+		errs = append(errs, authv1alpha1.ValidateGitHubConnector(&connector, fldPath)...)
+		errs = append(errs, authv1alpha1.ValidateGoogleConnector(&connector, fldPath)...)
+		errs = append(errs, authv1alpha1.ValidateOIDCConnector(&connector, fldPath)...)
+		errs = append(errs, authv1alpha1.ValidateOpenShiftConnector(&connector, fldPath)...)
+		errs = append(errs, authv1alpha1.ValidateAuthProxyConnector(&connector, fldPath)...)
+		errs = append(errs, authv1alpha1.ValidateMockConnector(&dexServer.Spec, &connector, fldPath)...)
+		errs = append(errs, authv1alpha1.ValidateRawConnector(&connector, fldPath)...)
+		if connector.Type == authv1alpha1.ConnectorTypeRaw && connector.Raw.ConnectorType != "" && !dexconfig.IsConnectorType(connector.Raw.ConnectorType) {
+			errs = append(errs, field.NotSupported(fldPath.Child("raw", "connectorType"), connector.Raw.ConnectorType, nil))
+		}
+		if connector.Type == authv1alpha1.ConnectorTypeOpenShift && connector.OpenShift.Issuer == "" && !r.OpenShift {
+			errs = append(errs, field.Required(fldPath.Child("openshift", "issuer"), "the DexServer does not run on OpenShift"))
+		}
+		if connector.Type == authv1alpha1.ConnectorTypeAuthProxy && connector.AuthProxy.ProxyService != nil && !canRouteAuthProxyCallback(dexServer, r.OpenShift) {
+			errs = append(errs, field.Forbidden(fldPath.Child("authproxy", "proxyService"), "the callback can't be routed to the proxy when dex is exposed by its Service or a wildcard route"))
+		}
+		if len(errs) > 0 {
+			rejected = append(rejected, authv1alpha1.RejectedConnectorStatus{
+				Id:     connector.Id,
+				Type:   connector.Type,
+				Reason: errs.ToAggregate().Error(),
+			})
+			continue
+		}
+		// dex fails to start with duplicate ids, and duplicate names can't be told apart on the login page
+		name := getConnectorDisplayName(connector)
+		if reason := getDuplicateConnectorReason(connector.Id, name, ids, names); reason != "" {
+			rejected = append(rejected, authv1alpha1.RejectedConnectorStatus{
+				Id:     connector.Id,
+				Type:   connector.Type,
+				Reason: reason,
+			})
+			continue
+		}
+		ids[connector.Id] = i
+		names[name] = i
+		missing, err := r.findMissingSecret(dexServer, connector, ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if missing == nil {
+			// dex has one proxy for all the connectors
+			if err := proxies.add(connector); err != nil {
+				rejected = append(rejected, authv1alpha1.RejectedConnectorStatus{
+					Id:     connector.Id,
+					Type:   connector.Type,
+					Reason: err.Error(),
+				})
+				continue
+			}
+			connectors = append(connectors, connector)
+			continue
+		}
+		if dexServer.Spec.ErrorPolicy != authv1alpha1.ErrorPolicyFailOpen {
+			return nil, nil, missing
+		}
+		rejected = append(rejected, authv1alpha1.RejectedConnectorStatus{
+			Id:     connector.Id,
+			Type:   connector.Type,
+			Reason: missing.Error(),
+		})
+	}
+	sort.SliceStable(connectors, func(i, j int) bool {
+		return connectors[i].DisplayOrder < connectors[j].DisplayOrder
+	})
+	return connectors, rejected, nil
+}
+
+// Get the name displayed on the login button of the connector
+func getConnectorDisplayName(connector authv1alpha1.ConnectorSpec) string {
+	if connector.Name != "" {
+		return connector.Name
+	}
+	return connector.Id
+}
+
+// missingSecretError is returned when a secret referenced by a connector does not exist
+type missingSecretError struct {
+	Namespace   string
+	Name        string
+	ConnectorId string
+}
+
+func (e *missingSecretError) Error() string {
+	return fmt.Sprintf("secret %s/%s of connector %s not found", e.Namespace, e.Name, e.ConnectorId)
+}
+
+func (e *missingSecretError) FailureClass() failures.Class {
+	return failures.SecretMissing
+}
+
+// findMissingSecret returns the first secret of the connector that does not exist, nil when they all exist
+func (r *DexServerReconciler) findMissingSecret(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (*missingSecretError, error) {
+	for _, secretRef := range getConnectorSecretRefs(connector) {
+		secretNamespace := secretRef.Namespace
+		if secretNamespace == "" {
+			secretNamespace = dexServer.Namespace
+		}
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretRef.Name, Namespace: secretNamespace}, secret); err != nil {
+			if !kubeerrors.IsNotFound(err) {
+				return nil, err
+			}
+			return &missingSecretError{Namespace: secretNamespace, Name: secretRef.Name, ConnectorId: connector.Id}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Report the missing connector secrets in the WaitingForSecret condition
+func (r *DexServerReconciler) getWaitingForSecretCondition(dexServer *authv1alpha1.DexServer, ctx context.Context) (metav1.Condition, error) {
+	missingSecrets := []string{}
+	for _, connector := range dexServer.Spec.Connectors {
+		missing, err := r.findMissingSecret(dexServer, connector, ctx)
+		if err != nil {
+			return metav1.Condition{}, err
+		}
+		if missing != nil {
+			missingSecrets = append(missingSecrets, missing.Error())
+		}
+	}
+	if len(missingSecrets) == 0 {
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeWaitingForSecret,
+			Status:  metav1.ConditionFalse,
+			Reason:  "SecretsFound",
+			Message: "the secrets of all the connectors exist",
+		}, nil
+	}
+	return metav1.Condition{
+		Type:    authv1alpha1.DexServerConditionTypeWaitingForSecret,
+		Status:  metav1.ConditionTrue,
+		Reason:  "SecretNotFound",
+		Message: strings.Join(missingSecrets, ", "),
+	}, nil
+}
+
+// getSecretWaitBackoff returns the time until the next reconcile while a connector secret is missing. The next
+// reconcile is after as long as the DexServer has already been waiting, doubling the time between retries, unless the
+// secret is created before.
+func getSecretWaitBackoff(dexServer *authv1alpha1.DexServer) time.Duration {
+	cond := meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeWaitingForSecret)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		return SECRET_WAIT_MIN_BACKOFF
+	}
+	backoff := time.Since(cond.LastTransitionTime.Time)
+	if backoff < SECRET_WAIT_MIN_BACKOFF {
+		return SECRET_WAIT_MIN_BACKOFF
+	}
+	if backoff > SECRET_WAIT_MAX_BACKOFF {
+		return SECRET_WAIT_MAX_BACKOFF
+	}
+	return backoff
+}
+
+func isConnectorRejected(rejected []authv1alpha1.RejectedConnectorStatus, id string) bool {
+	for _, connector := range rejected {
+		if connector.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the reason a connector is rejected for reusing the id or name of a connector already rendered, naming the
+// rendered connector, so that the rejected duplicate can be told apart from it in status.rejectedConnectors
+func getDuplicateConnectorReason(id string, name string, ids map[string]int, names map[string]int) string {
+	if i, ok := ids[id]; ok {
+		return fmt.Sprintf("the id %q is already used by spec.connectors[%d]", id, i)
+	}
+	if i, ok := names[name]; ok {
+		return fmt.Sprintf("the name %q is already used by spec.connectors[%d]", name, i)
+	}
+	return ""
+}
+
+// Report the connectors left out of the dex config in the ConnectorsSkipped condition
+func getConnectorsSkippedCondition(rejected []authv1alpha1.RejectedConnectorStatus) metav1.Condition {
+	if len(rejected) == 0 {
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeConnectorsSkipped,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AllConnectorsRendered",
+			Message: "all connectors are rendered in the dex config",
+		}
+	}
+	ids := make([]string, 0, len(rejected))
+	for _, connector := range rejected {
+		ids = append(ids, connector.Id)
+	}
+	return metav1.Condition{
+		Type:    authv1alpha1.DexServerConditionTypeConnectorsSkipped,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ConnectorsRejected",
+		Message: fmt.Sprintf("connectors %s are skipped, see status.rejectedConnectors", strings.Join(ids, ", ")),
+	}
+}
+
 // Define the secret for grpc Mutual TLS. This secret is volume mounted on the dex instance pod. The client cert should be loaded by the gRPC client code.
 func (r *DexServerReconciler) defineMTLSSecret(m *authv1alpha1.DexServer, mtlsCerts *MTLSCerts) *corev1.Secret {
 	labels := map[string]string{
@@ -432,6 +1395,7 @@ func (r *DexServerReconciler) defineMTLSSecret(m *authv1alpha1.DexServer, mtlsCe
 	annotations := map[string]string{
 		MTLS_CERT_EXPIRY_ANNOTATION: mtlsCerts.expiry.UTC().Format(time.RFC3339),
 	}
+	addSharedMetadata(componentGRPC, labels, annotations)
 	secretSpec := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        SECRET_MTLS_NAME,
@@ -490,9 +1454,16 @@ func (r *DexServerReconciler) manageMTLSSecret(dexServer *authv1alpha1.DexServer
 			}
 
 		}
+		if r.FIPS && !isFIPSCompliantCertificate(secret.Data["tls.crt"]) {
+			log.Info("mtls cert is not FIPS compliant... regenerate")
+			regenerate = true
+		}
 	}
 	if !secretExists || regenerate {
-		mTLSCerts, err := generateMTLSCerts(dexServer.Namespace)
+		_, span := tracing.Start(ctx, "generateMTLSCerts")
+		mTLSCerts, err := generateMTLSCerts(dexServer.Namespace, r.FIPS, r.KeyPool)
+		span.RecordError(err)
+		span.End()
 		if err != nil {
 			return errors.Wrap(err, "error generating mtls certs")
 		}
@@ -503,6 +1474,12 @@ func (r *DexServerReconciler) manageMTLSSecret(dexServer *authv1alpha1.DexServer
 				return errors.Wrap(err, "error creating mtls secret")
 			}
 		} else {
+			// Keep the previous CA and client credentials until the dex server deployment has rolled out the new
+			// certificate, so that gRPC consumers can still reach the replicas serving the previous certificate
+			carryForwardPreviousMTLSCerts(secret, spec)
+			// Trust both the new and the previous CA for the overlap window, so that consumers holding either
+			// the new or the previous credentials keep working while the CA is rolled over
+			addPreviousCAToTrustBundle(dexServer, secret, spec)
 			log.Info("Updating MTLS Secret", "Secret.Namespace", spec.Namespace, "Secret.Name", spec.Name)
 			if err := r.Update(ctx, spec); err != nil {
 				return errors.Wrap(err, "error updating mtls secret")
@@ -511,10 +1488,112 @@ func (r *DexServerReconciler) manageMTLSSecret(dexServer *authv1alpha1.DexServer
 
 	} else {
 		log.V(1).Info("mtls cert found and does not require renewal")
+		if err := r.prunePreviousMTLSCerts(dexServer, secret, ctx); err != nil {
+			return err
+		}
+		return r.removeInstanceMetadata(ctx, secret)
 	}
 	return nil
 }
 
+// Remove the previous mtls credentials once they are no longer needed: the previous client credentials once the dex
+// server deployment has rolled out the new certificate, and the previous CA once the CA overlap window has passed
+func (r *DexServerReconciler) prunePreviousMTLSCerts(dexServer *authv1alpha1.DexServer, secret *corev1.Secret, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	updated := false
+
+	if hasPreviousMTLSCerts(secret) {
+		rolledOut, err := r.isDeploymentRolledOut(dexServer, secret, ctx)
+		if err != nil {
+			return errors.Wrap(err, "error checking dex server deployment rollout")
+		}
+		if rolledOut {
+			log.Info("Dex server rollout complete, removing previous mtls credentials", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+			delete(secret.Data, MTLS_PREVIOUS_CA_KEY)
+			delete(secret.Data, MTLS_PREVIOUS_CLIENT_CRT_KEY)
+			delete(secret.Data, MTLS_PREVIOUS_CLIENT_KEY_KEY)
+			updated = true
+		}
+	}
+
+	if overlapExpiry, ok := secret.Annotations[MTLS_CA_OVERLAP_EXPIRY_ANNOTATION]; ok {
+		expiryTime, err := time.Parse(time.RFC3339, overlapExpiry)
+		if err != nil {
+			// something unexpected found in the annotation ... stop trusting the previous CA
+			log.Error(err, "ca overlap expiry could not be parsed")
+		}
+		if err != nil || time.Now().After(expiryTime) {
+			log.Info("CA overlap window has passed, removing previous CA from the trust bundle", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+			secret.Data["ca.crt"] = firstPEMCertificate(secret.Data["ca.crt"])
+			delete(secret.Annotations, MTLS_CA_OVERLAP_EXPIRY_ANNOTATION)
+			updated = true
+		}
+	}
+
+	if updated {
+		if err := r.Update(ctx, secret); err != nil {
+			return errors.Wrap(err, "error updating mtls secret")
+		}
+	}
+	return nil
+}
+
+// Append the CA of an existing mtls secret to the ca.crt trust bundle of its replacement for the configured overlap window
+func addPreviousCAToTrustBundle(dexServer *authv1alpha1.DexServer, existing *corev1.Secret, replacement *corev1.Secret) {
+	previousCA := firstPEMCertificate(existing.Data["ca.crt"])
+	if len(previousCA) == 0 {
+		return
+	}
+	overlapWindow := defaultCAOverlapWindow
+	if dexServer.Spec.MTLS.CAOverlapWindow != nil {
+		overlapWindow = dexServer.Spec.MTLS.CAOverlapWindow.Duration
+	}
+	if overlapWindow <= 0 {
+		return
+	}
+	replacement.Data["ca.crt"] = append(replacement.Data["ca.crt"], previousCA...)
+	replacement.Annotations[MTLS_CA_OVERLAP_EXPIRY_ANNOTATION] = time.Now().Add(overlapWindow).UTC().Format(time.RFC3339)
+}
+
+// Copy the current CA and client credentials of an existing mtls secret into the previous credential keys of its replacement
+func carryForwardPreviousMTLSCerts(existing *corev1.Secret, replacement *corev1.Secret) {
+	if len(existing.Data["ca.crt"]) == 0 {
+		return
+	}
+	replacement.Data[MTLS_PREVIOUS_CA_KEY] = existing.Data["ca.crt"]
+	replacement.Data[MTLS_PREVIOUS_CLIENT_CRT_KEY] = existing.Data["client.crt"]
+	replacement.Data[MTLS_PREVIOUS_CLIENT_KEY_KEY] = existing.Data["client.key"]
+}
+
+func hasPreviousMTLSCerts(secret *corev1.Secret) bool {
+	return len(secret.Data[MTLS_PREVIOUS_CA_KEY]) > 0
+}
+
+// Check that every replica of the dex server deployment has been updated to the certificate in the given mtls secret
+// and is available. Until then, replicas serving the previous certificate may still be receiving gRPC requests.
+func (r *DexServerReconciler) isDeploymentRolledOut(dexServer *authv1alpha1.DexServer, mtlsSecret *corev1.Secret, ctx context.Context) (bool, error) {
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, deployment); err != nil {
+		if kubeerrors.IsNotFound(err) {
+			// Nothing is serving the previous certificate
+			return true, nil
+		}
+		return false, err
+	}
+	if deployment.Spec.Template.ObjectMeta.Annotations[GRPC_MTLS_EXPIRY_ANNOTATION] != mtlsSecret.Annotations[MTLS_CERT_EXPIRY_ANNOTATION] {
+		return false, nil
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == replicas &&
+		status.Replicas == replicas &&
+		status.AvailableReplicas == replicas, nil
+}
+
 func (r *DexServerReconciler) syncServiceAccount(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
 	log := ctrllog.FromContext(ctx)
 	log.Info("syncServiceAccount", "ServiceAccount.Name", SERVICE_ACCOUNT_NAME)
@@ -532,12 +1611,12 @@ func (r *DexServerReconciler) syncServiceAccount(dexServer *authv1alpha1.DexServ
 	}
 
 	applier, readerDeploy := r.getApplierAndReader(dexServer)
-	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	err := r.applyWithDiff(dexServer, ctx, applier, applier.ApplyDirectly, readerDeploy, values, files...)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.removeInstanceMetadata(ctx, &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: SERVICE_ACCOUNT_NAME, Namespace: dexServer.Namespace}})
 }
 
 func (r *DexServerReconciler) syncClusterRoleBinding(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
@@ -551,7 +1630,7 @@ func (r *DexServerReconciler) syncClusterRoleBinding(dexServer *authv1alpha1.Dex
 		ClusterRoleBindingName string
 		DexServer              *authv1alpha1.DexServer
 	}{
-		ClusterRoleName:        SERVICE_ACCOUNT_NAME,
+		ClusterRoleName:        r.getClusterRoleName(),
 		ServiceAccountName:     SERVICE_ACCOUNT_NAME,
 		ClusterRoleBindingName: clusterRoleBindingName,
 		DexServer:              dexServer,
@@ -562,12 +1641,148 @@ func (r *DexServerReconciler) syncClusterRoleBinding(dexServer *authv1alpha1.Dex
 	}
 
 	applier, readerDeploy := r.getApplierAndReader(dexServer)
-	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	err := r.applyWithDiff(dexServer, ctx, applier, applier.ApplyDirectly, readerDeploy, values, files...)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.removeInstanceMetadata(ctx, &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: clusterRoleBindingName}})
+}
+
+// Check that the ClusterRole exists and is bound to the service account of the dex server
+func (r *DexServerReconciler) validatePreProvisionedRBAC(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	clusterRoleName := r.getClusterRoleName()
+	log.Info("validatePreProvisionedRBAC", "ClusterRole.Name", clusterRoleName)
+
+	clusterRole := &rbacv1.ClusterRole{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: clusterRoleName}, clusterRole); err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return fmt.Errorf("ClusterRole %s not found, it must be created when the operator runs with pre-provisioned RBAC", clusterRoleName)
+		}
+		return err
+	}
+
+	clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
+	if err := r.Client.List(ctx, clusterRoleBindings); err != nil {
+		return err
+	}
+	for _, crb := range clusterRoleBindings.Items {
+		if crb.RoleRef.Kind != "ClusterRole" || crb.RoleRef.Name != clusterRoleName {
+			continue
+		}
+		for _, subject := range crb.Subjects {
+			if subject.Kind == rbacv1.ServiceAccountKind && subject.Name == SERVICE_ACCOUNT_NAME && subject.Namespace == dexServer.Namespace {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no ClusterRoleBinding binds ClusterRole %s to the service account %s/%s", clusterRoleName, dexServer.Namespace, SERVICE_ACCOUNT_NAME)
+}
+
+func (r *DexServerReconciler) getClusterRoleName() string {
+	if r.ClusterRoleName != "" {
+		return r.ClusterRoleName
+	}
+	return SERVICE_ACCOUNT_NAME
+}
+
+// Get the ports dex listens on in its container
+func getDexPorts(dexServer *authv1alpha1.DexServer) (httpsPort int32, grpcPort int32) {
+	httpsPort, grpcPort = DEX_HTTPS_PORT, DEX_GRPC_PORT
+	if dexServer.Spec.Ports.HTTPS != 0 {
+		httpsPort = dexServer.Spec.Ports.HTTPS
+	}
+	if dexServer.Spec.Ports.GRPC != 0 {
+		grpcPort = dexServer.Spec.Ports.GRPC
+	}
+	return httpsPort, grpcPort
+}
+
+// Get the addresses the dex web server and gRPC API listen on. Without a listen address, dex listens on all the
+// addresses of every IP family of the pod, so that it is reachable on IPv4, IPv6 and dual-stack clusters.
+func getDexListenAddresses(dexServer *authv1alpha1.DexServer) (webAddress string, grpcAddress string, err error) {
+	host := dexServer.Spec.Ports.ListenAddress
+	if host != "" && net.ParseIP(host) == nil {
+		return "", "", fmt.Errorf("listen address %q is not an IP address", host)
+	}
+	httpsPort, grpcPort := getDexPorts(dexServer)
+	return net.JoinHostPort(host, strconv.Itoa(int(httpsPort))), net.JoinHostPort(host, strconv.Itoa(int(grpcPort))), nil
+}
+
+// Get the seccomp profile of the dex pod, defaults to RuntimeDefault
+func getSeccompProfile(dexServer *authv1alpha1.DexServer) *authv1alpha1.SecurityProfile {
+	if dexServer.Spec.SecurityProfiles.Seccomp != nil {
+		return dexServer.Spec.SecurityProfiles.Seccomp
+	}
+	return &authv1alpha1.SecurityProfile{Type: authv1alpha1.SecurityProfileTypeRuntimeDefault}
+}
+
+// Mount the root filesystem of the dex containers read-only, unless disabled in the spec
+func isReadOnlyRootFilesystem(dexServer *authv1alpha1.DexServer) bool {
+	if dexServer.Spec.Filesystem.ReadOnlyRootFilesystem != nil {
+		return *dexServer.Spec.Filesystem.ReadOnlyRootFilesystem
+	}
+	return true
+}
+
+// Get the writable emptyDir volumes of the dex container, defaults to a volume mounted on /tmp
+func getScratchVolumes(dexServer *authv1alpha1.DexServer) ([]corev1.Volume, []corev1.VolumeMount, error) {
+	scratchVolumes := dexServer.Spec.Filesystem.ScratchVolumes
+	if scratchVolumes == nil {
+		scratchVolumes = []authv1alpha1.ScratchVolume{{Name: "tmp", MountPath: "/tmp"}}
+	}
+	volumes := []corev1.Volume{}
+	volumeMounts := []corev1.VolumeMount{}
+	mountPaths := map[string]bool{}
+	for _, scratchVolume := range scratchVolumes {
+		mountPath := path.Clean(scratchVolume.MountPath)
+		switch {
+		case !path.IsAbs(scratchVolume.MountPath):
+			return nil, nil, fmt.Errorf("mount path %q of scratch volume %q is not absolute", scratchVolume.MountPath, scratchVolume.Name)
+		case mountPath == "/" || mountPath == "/etc/dex" || strings.HasPrefix(mountPath, "/etc/dex/"):
+			// the configuration and certificates of dex are mounted in /etc/dex
+			return nil, nil, fmt.Errorf("scratch volume %q can't be mounted on %s", scratchVolume.Name, mountPath)
+		case mountPaths[mountPath]:
+			return nil, nil, fmt.Errorf("several scratch volumes are mounted on %s", mountPath)
+		}
+		mountPaths[mountPath] = true
+		if scratchVolume.Medium != corev1.StorageMediumDefault && scratchVolume.Medium != corev1.StorageMediumMemory {
+			return nil, nil, fmt.Errorf("unsupported medium %q of scratch volume %q", scratchVolume.Medium, scratchVolume.Name)
+		}
+		name := "scratch-" + scratchVolume.Name
+		volumes = append(volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium:    scratchVolume.Medium,
+					SizeLimit: scratchVolume.SizeLimit,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      name,
+			MountPath: mountPath,
+		})
+	}
+	return volumes, volumeMounts, nil
+}
+
+// Get the annotation value selecting a seccomp or AppArmor profile, for the clusters that predate the
+// security context fields. Both annotations use the same format.
+func getSecurityProfileAnnotation(profile *authv1alpha1.SecurityProfile) (string, error) {
+	switch profile.Type {
+	case authv1alpha1.SecurityProfileTypeRuntimeDefault:
+		return "runtime/default", nil
+	case authv1alpha1.SecurityProfileTypeUnconfined:
+		return "unconfined", nil
+	case authv1alpha1.SecurityProfileTypeLocalhost:
+		if profile.LocalhostProfile == "" {
+			return "", fmt.Errorf("localhostProfile is required for a Localhost profile")
+		}
+		return "localhost/" + profile.LocalhostProfile, nil
+	}
+	return "", fmt.Errorf("unsupported profile type %q", profile.Type)
 }
 
 func getDexImagePullSpec() (string, error) {
@@ -594,14 +1809,129 @@ func (r *DexServerReconciler) syncDeployment(dexServer *authv1alpha1.DexServer,
 	var additionalEnvVariablesYaml []byte
 	var rootCAHash, connectorCredsHash string
 
+	renderedConnectors, _, err := r.getRenderedConnectors(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+
 	// Update Volume Mounts based on rootCA secret refs for LDAP connectors (Trusted Root CA and optionally client cert and key files)
 	// Iterate over connectors defined in the DexServer to create the dex configuration for connectors
-	for _, connector := range dexServer.Spec.Connectors {
+	for _, connector := range renderedConnectors {
 		var secretName string
 		switch connector.Type {
+		case authv1alpha1.ConnectorTypeBitbucketCloud:
+			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+			secretName = connector.BitbucketCloud.ClientSecretRef.Namespace + "-" + connector.BitbucketCloud.ClientSecretRef.Name
+		case authv1alpha1.ConnectorTypeGitea:
+			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+			secretName = connector.Gitea.ClientSecretRef.Namespace + "-" + connector.Gitea.ClientSecretRef.Name
 		case authv1alpha1.ConnectorTypeGitHub:
 			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
-			secretName = connector.GitHub.ClientSecretRef.Namespace + "-" + connector.GitHub.ClientSecretRef.Name
+			secretName = connector.GitHub.ClientSecretRef.Namespace + "-" + connector.GitHub.ClientSecretRef.Name
+
+			// The client ID read from a secret has its own environment variable
+			if connector.GitHub.ClientIDRef.Name != "" {
+				clientIDEnvVariable, err := r.getGitHubClientIDEnvVariable(ctx, dexServer, connector)
+				if err != nil {
+					return err
+				}
+				if clientIDEnvVariable != (corev1.EnvVar{}) {
+					additionalEnvVariables = append(additionalEnvVariables, clientIDEnvVariable)
+				}
+			}
+
+			// The root CA is only used by dex for a GitHub Enterprise server
+			if connector.GitHub.HostName != "" && len(connector.GitHub.RootCAData) > 0 {
+				// The inline CA is held by the ConfigMap of the dex config, whose checksum triggers the rolling restarts
+				additionalVolumes = append(additionalVolumes, corev1.Volume{
+					Name: "githubcerts-" + connector.Id,
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: dexServer.Name},
+							Items:                []corev1.KeyToPath{{Key: getGitHubRootCAKey(connector), Path: "ca.crt"}},
+						},
+					},
+				})
+				additionalVolumeMounts = append(additionalVolumeMounts, corev1.VolumeMount{
+					Name:      "githubcerts-" + connector.Id,
+					MountPath: "/etc/dex/githubcerts/" + connector.Id,
+				})
+			} else if connector.GitHub.HostName != "" && connector.GitHub.RootCARef.Name != "" {
+				// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+				secretName := connector.GitHub.RootCARef.Namespace + "-" + connector.GitHub.RootCARef.Name
+				rootCASecret := &corev1.Secret{}
+
+				// Add the root CA secret's sha256 checksum to the Deployment to trigger rolling restarts when the secret changes
+				if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: secretName, Namespace: dexServer.Namespace}, rootCASecret); err != nil {
+					// If the secret is not yet found, the annotation will be omitted, and will be added once the secret is created
+					if !kubeerrors.IsNotFound(err) {
+						log.Error(err, "error getting secret containing GitHub root CA")
+						return err
+					}
+				} else {
+					jsonData, err := json.Marshal(rootCASecret)
+					if err != nil {
+						log.Error(err, "failed to marshal GitHub root CA JSON")
+						return err
+					}
+					h := sha256.New()
+					h.Write([]byte(jsonData))
+					rootCAHash = rootCAHash + fmt.Sprintf("%x", h.Sum(nil))
+
+					additionalVolumes = append(additionalVolumes, corev1.Volume{
+						Name: "githubcerts-" + connector.Id,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: secretName,
+							},
+						},
+					})
+					additionalVolumeMounts = append(additionalVolumeMounts, corev1.VolumeMount{
+						Name:      "githubcerts-" + connector.Id,
+						MountPath: "/etc/dex/githubcerts/" + connector.Id,
+					})
+				}
+			}
+		case authv1alpha1.ConnectorTypeGoogle:
+			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+			secretName = connector.Google.ClientSecretRef.Namespace + "-" + connector.Google.ClientSecretRef.Name
+
+			if connector.Google.ServiceAccountRef.Name != "" {
+				// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+				secretName := connector.Google.ServiceAccountRef.Namespace + "-" + connector.Google.ServiceAccountRef.Name
+				serviceAccountSecret := &corev1.Secret{}
+
+				// Add the service account secret's sha256 checksum to the Deployment to trigger rolling restarts when the secret changes
+				if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: secretName, Namespace: dexServer.Namespace}, serviceAccountSecret); err != nil {
+					// If the secret is not yet found, the annotation will be omitted, and will be added once the secret is created
+					if !kubeerrors.IsNotFound(err) {
+						log.Error(err, "error getting secret containing Google service account")
+						return err
+					}
+				} else {
+					jsonData, err := json.Marshal(serviceAccountSecret)
+					if err != nil {
+						log.Error(err, "failed to marshal Google service account JSON")
+						return err
+					}
+					h := sha256.New()
+					h.Write([]byte(jsonData))
+					rootCAHash = rootCAHash + fmt.Sprintf("%x", h.Sum(nil))
+
+					additionalVolumes = append(additionalVolumes, corev1.Volume{
+						Name: "googlesa-" + connector.Id,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: secretName,
+							},
+						},
+					})
+					additionalVolumeMounts = append(additionalVolumeMounts, corev1.VolumeMount{
+						Name:      "googlesa-" + connector.Id,
+						MountPath: "/etc/dex/googlesa/" + connector.Id,
+					})
+				}
+			}
 		case authv1alpha1.ConnectorTypeMicrosoft:
 			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
 			secretName = connector.Microsoft.ClientSecretRef.Namespace + "-" + connector.Microsoft.ClientSecretRef.Name
@@ -649,11 +1979,114 @@ func (r *DexServerReconciler) syncDeployment(dexServer *authv1alpha1.DexServer,
 					additionalVolumes = append(additionalVolumes, newVolume)
 				}
 			}
+		case authv1alpha1.ConnectorTypeMockPassword:
+			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+			secretName = connector.MockPassword.PasswordRef.Namespace + "-" + connector.MockPassword.PasswordRef.Name
 		case authv1alpha1.ConnectorTypeOIDC:
 			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
 			secretName = connector.OIDC.ClientSecretRef.Namespace + "-" + connector.OIDC.ClientSecretRef.Name
+		case authv1alpha1.ConnectorTypeOpenShift:
+			// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+			secretName = connector.OpenShift.ClientSecretRef.Namespace + "-" + connector.OpenShift.ClientSecretRef.Name
+
+			if connector.OpenShift.RootCARef.Name != "" {
+				// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+				secretName := connector.OpenShift.RootCARef.Namespace + "-" + connector.OpenShift.RootCARef.Name
+				rootCASecret := &corev1.Secret{}
+
+				// Add the root CA secret's sha256 checksum to the Deployment to trigger rolling restarts when the secret changes
+				if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: secretName, Namespace: dexServer.Namespace}, rootCASecret); err != nil {
+					// If the secret is not yet found, the annotation will be omitted, and will be added once the secret is created
+					if !kubeerrors.IsNotFound(err) {
+						log.Error(err, "error getting secret containing OpenShift root CA")
+						return err
+					}
+				} else {
+					jsonData, err := json.Marshal(rootCASecret)
+					if err != nil {
+						log.Error(err, "failed to marshal OpenShift root CA JSON")
+						return err
+					}
+					h := sha256.New()
+					h.Write([]byte(jsonData))
+					rootCAHash = rootCAHash + fmt.Sprintf("%x", h.Sum(nil))
+
+					additionalVolumes = append(additionalVolumes, corev1.Volume{
+						Name: "openshiftcerts-" + connector.Id,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: secretName,
+							},
+						},
+					})
+					additionalVolumeMounts = append(additionalVolumeMounts, corev1.VolumeMount{
+						Name:      "openshiftcerts-" + connector.Id,
+						MountPath: "/etc/dex/openshiftcerts/" + connector.Id,
+					})
+				}
+			}
+		case authv1alpha1.ConnectorTypeSAML:
+			if connector.SAML.CARef.Name != "" {
+				// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+				secretName := connector.SAML.CARef.Namespace + "-" + connector.SAML.CARef.Name
+				caSecret := &corev1.Secret{}
+
+				// Add the signing certificate secret's sha256 checksum to the Deployment to trigger rolling restarts when the secret changes
+				if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: secretName, Namespace: dexServer.Namespace}, caSecret); err != nil {
+					// If the secret is not yet found, the annotation will be omitted, and will be added once the secret is created
+					if !kubeerrors.IsNotFound(err) {
+						log.Error(err, "error getting secret containing SAML signing certificate")
+						return err
+					}
+				} else {
+					jsonData, err := json.Marshal(caSecret)
+					if err != nil {
+						log.Error(err, "failed to marshal SAML signing certificate JSON")
+						return err
+					}
+					h := sha256.New()
+					h.Write([]byte(jsonData))
+					rootCAHash = rootCAHash + fmt.Sprintf("%x", h.Sum(nil))
+
+					additionalVolumes = append(additionalVolumes, corev1.Volume{
+						Name: "samlcerts-" + connector.Id,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: secretName,
+							},
+						},
+					})
+					additionalVolumeMounts = append(additionalVolumeMounts, corev1.VolumeMount{
+						Name:      "samlcerts-" + connector.Id,
+						MountPath: "/etc/dex/samlcerts/" + connector.Id,
+					})
+				}
+			}
+			// no credential is passed to dex in its environment
+			continue
+		case authv1alpha1.ConnectorTypeAuthProxy, authv1alpha1.ConnectorTypeMockCallback:
+			// no credential is passed to dex in its environment
+			continue
+		case authv1alpha1.ConnectorTypeRaw:
+			// each placeholder of the config has its environment variable
+			envVariables, err := r.getRawConnectorEnvVariables(ctx, dexServer, connector)
+			if err != nil {
+				return err
+			}
+			additionalEnvVariables = append(additionalEnvVariables, envVariables...)
+
+			connectorSecretCred, err := getConnectorSecretFromRef(connector, dexServer, r, ctx)
+			if err != nil {
+				log.Error(err, "Error getting connector credential secret")
+				return err
+			}
+			h := sha256.New()
+			h.Write([]byte(connectorSecretCred))
+			connectorCredsHash = connectorCredsHash + fmt.Sprintf("%x", h.Sum(nil))
+			continue
 		default:
-			return nil
+			// rejected by getRenderedConnectors
+			continue
 		}
 
 		// Get environment variable corresponding to the secret for this connector
@@ -676,6 +2109,15 @@ func (r *DexServerReconciler) syncDeployment(dexServer *authv1alpha1.DexServer,
 		connectorCredsHash = connectorCredsHash + fmt.Sprintf("%x", h.Sum(nil)) // If there are multiple connectors, the hashes for the credentials will be concatenated
 
 	}
+	additionalEnvVariables = append(additionalEnvVariables, getProxyEnvVariables(renderedConnectors)...)
+	additionalEnvVariables = append(additionalEnvVariables, getStorageEnvVariables(dexServer.Spec.Storage, STORAGE_PASSWORD_ENV_VAR)...)
+	scratchVolumes, scratchVolumeMounts, err := getScratchVolumes(dexServer)
+	if err != nil {
+		return errors.Wrap(err, "invalid spec.filesystem.scratchVolumes")
+	}
+	additionalVolumes = append(additionalVolumes, scratchVolumes...)
+	additionalVolumeMounts = append(additionalVolumeMounts, scratchVolumeMounts...)
+
 	if len(additionalVolumeMounts) > 0 {
 		// Get yaml representation of additional volumeMounts and volumes
 		additionalVolumeMountsYaml, err = yaml.Marshal(&additionalVolumeMounts)
@@ -716,7 +2158,7 @@ func (r *DexServerReconciler) syncDeployment(dexServer *authv1alpha1.DexServer,
 		dexConfigMapHash = fmt.Sprintf("%x", h.Sum(nil))
 		// log.Info("computed hash", "dexConfigMapHash", dexConfigMapHash)
 	}
-	var mtlsSecretExpiry string
+	var mtlsSecretExpiry, mtlsCAHash string
 	if mtlsSecret, err := r.getMTLSSecret(dexServer, ctx); err != nil {
 		// If mtls secret is not yet found, the annotation will be omitted, and will be added once the secret is created
 		if !kubeerrors.IsNotFound(err) {
@@ -724,21 +2166,98 @@ func (r *DexServerReconciler) syncDeployment(dexServer *authv1alpha1.DexServer,
 		}
 	} else {
 		mtlsSecretExpiry = mtlsSecret.Annotations[MTLS_CERT_EXPIRY_ANNOTATION]
+		// Restart dex when the CA trust bundle changes, dex only loads the client CA on startup
+		h := sha256.New()
+		h.Write(mtlsSecret.Data["ca.crt"])
+		mtlsCAHash = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	webTemplatesHash, err := r.getWebTemplatesHash(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+
+	httpsPort, grpcPort := getDexPorts(dexServer)
+
+	var telemetryPort int32
+	if dexServer.Spec.Telemetry.Enabled {
+		telemetryPort = getTelemetryPort(dexServer)
+	}
+	var rbacProxyImage, metricsTLSSecretName string
+	if isRBACProxyEnabled(dexServer) {
+		if rbacProxyImage, err = getRBACProxyImagePullSpec(); err != nil {
+			return err
+		}
+		if r.OpenShift {
+			metricsTLSSecretName = dexServer.Name + SECRET_METRICS_TLS_SUFFIX
+		}
+	}
+
+	// The kubelet probes the primary IP of the pod, unless dex only listens on one of its addresses
+	var probeHost string
+	if ip := net.ParseIP(dexServer.Spec.Ports.ListenAddress); ip != nil && !ip.IsUnspecified() {
+		probeHost = ip.String()
+	}
+
+	probeScheme := corev1.URISchemeHTTPS
+	if isTLSTerminatedAtLoadBalancer(dexServer) {
+		probeScheme = corev1.URISchemeHTTP
+	}
+
+	seccompProfile := getSeccompProfile(dexServer)
+	seccompAnnotation, err := getSecurityProfileAnnotation(seccompProfile)
+	if err != nil {
+		return errors.Wrap(err, "invalid spec.securityProfiles.seccomp")
+	}
+	var appArmorAnnotation string
+	if dexServer.Spec.SecurityProfiles.AppArmor != nil {
+		appArmorAnnotation, err = getSecurityProfileAnnotation(dexServer.Spec.SecurityProfiles.AppArmor)
+		if err != nil {
+			return errors.Wrap(err, "invalid spec.securityProfiles.appArmor")
+		}
+	}
+
+	resourcesYaml, err := yaml.Marshal(getDexResources(dexServer))
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the resources of dex")
+	}
+	terminationGracePeriodSeconds, err := getTerminationGracePeriodSeconds(dexServer)
+	if err != nil {
+		return err
 	}
 
 	values := struct {
-		DexImage                 string
-		DexConfigMapHash         string
-		RootCAHash               string
-		ConnectorCredentialsHash string
-		ServiceAccountName       string
-		TlsSecretName            string
-		MtlsSecretName           string
-		MtlsSecretExpiry         string
-		DexServer                *authv1alpha1.DexServer
-		AdditionalEnvVariables   string
-		AdditionalVolumeMounts   string
-		AdditionalVolumes        string
+		DexImage                      string
+		DexConfigMapHash              string
+		RootCAHash                    string
+		ConnectorCredentialsHash      string
+		ServiceAccountName            string
+		TlsSecretName                 string
+		MtlsSecretName                string
+		MtlsSecretExpiry              string
+		MtlsCAHash                    string
+		WebTemplatesHash              string
+		HTTPSPort                     int32
+		GRPCPort                      int32
+		TelemetryPort                 int32
+		RBACProxyImage                string
+		RBACProxyPort                 int32
+		MetricsTLSSecretName          string
+		ProbeHost                     string
+		ProbeScheme                   corev1.URIScheme
+		HealthCheckPath               string
+		DexServer                     *authv1alpha1.DexServer
+		FIPS                          bool
+		SeccompProfile                *authv1alpha1.SecurityProfile
+		SeccompAnnotation             string
+		AppArmorAnnotation            string
+		ReadOnlyRootFilesystem        bool
+		AdditionalEnvVariables        string
+		AdditionalVolumeMounts        string
+		AdditionalVolumes             string
+		Replicas                      int32
+		Resources                     string
+		PreStopDelaySeconds           int64
+		TerminationGracePeriodSeconds int64
 	}{
 		DexImage:                 dexImage,
 		DexConfigMapHash:         dexConfigMapHash,
@@ -750,12 +2269,32 @@ func (r *DexServerReconciler) syncDeployment(dexServer *authv1alpha1.DexServer,
 		TlsSecretName: fmt.Sprintf(dexServer.Name + SECRET_WEB_TLS_SUFFIX),
 		// This secret is generated by this controller, here we load the server side cert and ca
 		// service.beta.openshift.io/serving-cert-secret-name: dexServer.Name-mtls-secret
-		MtlsSecretName:         SECRET_MTLS_NAME,
-		MtlsSecretExpiry:       mtlsSecretExpiry,
-		DexServer:              dexServer,
-		AdditionalEnvVariables: string(additionalEnvVariablesYaml),
-		AdditionalVolumeMounts: string(additionalVolumeMountsYaml),
-		AdditionalVolumes:      string(additionalVolumesYaml),
+		MtlsSecretName:                SECRET_MTLS_NAME,
+		MtlsSecretExpiry:              mtlsSecretExpiry,
+		MtlsCAHash:                    mtlsCAHash,
+		WebTemplatesHash:              webTemplatesHash,
+		HTTPSPort:                     httpsPort,
+		GRPCPort:                      grpcPort,
+		TelemetryPort:                 telemetryPort,
+		RBACProxyImage:                rbacProxyImage,
+		RBACProxyPort:                 RBAC_PROXY_PORT,
+		MetricsTLSSecretName:          metricsTLSSecretName,
+		ProbeHost:                     probeHost,
+		ProbeScheme:                   probeScheme,
+		HealthCheckPath:               getHealthCheckPath(dexServer),
+		DexServer:                     dexServer,
+		FIPS:                          r.FIPS,
+		SeccompProfile:                seccompProfile,
+		SeccompAnnotation:             seccompAnnotation,
+		AppArmorAnnotation:            appArmorAnnotation,
+		ReadOnlyRootFilesystem:        isReadOnlyRootFilesystem(dexServer),
+		AdditionalEnvVariables:        string(additionalEnvVariablesYaml),
+		AdditionalVolumeMounts:        string(additionalVolumeMountsYaml),
+		AdditionalVolumes:             string(additionalVolumesYaml),
+		Replicas:                      getDexReplicas(dexServer),
+		Resources:                     string(resourcesYaml),
+		PreStopDelaySeconds:           getPreStopDelaySeconds(dexServer),
+		TerminationGracePeriodSeconds: terminationGracePeriodSeconds,
 	}
 
 	files := []string{
@@ -763,7 +2302,7 @@ func (r *DexServerReconciler) syncDeployment(dexServer *authv1alpha1.DexServer,
 	}
 
 	applier, readerDeploy := r.getApplierAndReader(dexServer)
-	_, err = applier.ApplyDeployments(readerDeploy, values, false, "", files...)
+	err = r.applyWithDiff(dexServer, ctx, applier, applier.ApplyDeployments, readerDeploy, values, files...)
 	if err != nil {
 		return err
 	}
@@ -815,6 +2354,28 @@ func (r *DexServerReconciler) getEnvironmentVariableForSecret(ctx context.Contex
 	}
 }
 
+// Get the environment variable holding the client ID of a GitHub connector, read from the clientIDRef secret copied
+// into the dex server namespace. The environment variable is added once the secret is created.
+func (r *DexServerReconciler) getGitHubClientIDEnvVariable(ctx context.Context, dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec) (corev1.EnvVar, error) {
+	// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+	secretName := connector.GitHub.ClientIDRef.Namespace + "-" + connector.GitHub.ClientIDRef.Name
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: secretName, Namespace: dexServer.Namespace}, &corev1.Secret{}); err != nil {
+		if !kubeerrors.IsNotFound(err) {
+			return corev1.EnvVar{}, err
+		}
+		return corev1.EnvVar{}, nil
+	}
+	return corev1.EnvVar{
+		Name: GITHUB_CLIENT_ID_ENV_VAR + "_" + getUniqueAlphanumericIdForConnector(connector),
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  GITHUB_CLIENT_ID_KEY,
+			},
+		},
+	}, nil
+}
+
 // Copy a secret from its original namespace into the Dex Server namespace
 func (r *DexServerReconciler) copySecretToDexServerNamespace(dexServer *authv1alpha1.DexServer, secretRef corev1.SecretReference, ctx context.Context) error {
 	log := ctrllog.FromContext(ctx)
@@ -842,6 +2403,13 @@ func (r *DexServerReconciler) copySecretToDexServerNamespace(dexServer *authv1al
 	case err == nil:
 		// Secret already exists in the dex server ns, update it
 		secretInDexServerNS.Data = originalSecret.Data
+		if secretInDexServerNS.Labels == nil {
+			secretInDexServerNS.Labels = map[string]string{}
+		}
+		if secretInDexServerNS.Annotations == nil {
+			secretInDexServerNS.Annotations = map[string]string{}
+		}
+		r.addManagedMetadata(dexServer, componentConfig, secretInDexServerNS.Labels, secretInDexServerNS.Annotations)
 		if err := r.Client.Update(context.TODO(), secretInDexServerNS); err != nil {
 			log.Error(err, "Error updating secret in dexserver namespace", "name", secretRef.Name)
 			return err
@@ -850,12 +2418,15 @@ func (r *DexServerReconciler) copySecretToDexServerNamespace(dexServer *authv1al
 		// Create secret in the dex server ns
 		secretInDexServerNS = &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      secretName,
-				Namespace: dexServer.Namespace,
+				Name:        secretName,
+				Namespace:   dexServer.Namespace,
+				Labels:      map[string]string{},
+				Annotations: map[string]string{},
 			},
 			Type: corev1.SecretTypeOpaque,
 			Data: originalSecret.Data,
 		}
+		r.addManagedMetadata(dexServer, componentConfig, secretInDexServerNS.Labels, secretInDexServerNS.Annotations)
 		if err := r.Client.Create(context.TODO(), secretInDexServerNS); err != nil {
 			log.Error(err, "Error creating secret in dexserver namespace", "name", secretRef.Name)
 			return err
@@ -872,11 +2443,54 @@ func (r *DexServerReconciler) syncService(dexServer *authv1alpha1.DexServer, ctx
 	log := ctrllog.FromContext(ctx)
 	log.Info("syncService", "DexServer.Name", dexServer.Name, "DexServer.Namespace", dexServer.Namespace)
 
+	// The serving certificate is only requested from OpenShift, otherwise syncServingCertSecret provides it
+	var servingCertSecretName string
+	if r.OpenShift {
+		servingCertSecretName = dexServer.Name + SECRET_WEB_TLS_SUFFIX
+	}
+	serviceType := dexServer.Spec.Service.Type
+	if serviceType == "" {
+		serviceType = corev1.ServiceTypeClusterIP
+	}
+	var nodePort int32
+	if serviceType != corev1.ServiceTypeClusterIP {
+		nodePort = dexServer.Spec.Service.NodePort
+	}
+
+	if err := validateServiceTLSTermination(dexServer); err != nil {
+		return err
+	}
+
+	httpsPort, _ := getDexPorts(dexServer)
+	ipFamiliesYaml, err := getServiceIPFamiliesYaml(dexServer)
+	if err != nil {
+		return err
+	}
+	var annotationsYaml []byte
+	if annotations := mergePresetAnnotations(getServiceHealthCheckAnnotations(dexServer), dexServer.Spec.Service.Annotations); len(annotations) > 0 {
+		annotationsYaml, err = yaml.Marshal(annotations)
+		if err != nil {
+			return err
+		}
+	}
+
 	values := struct {
 		ServingCertSecretName string
+		ServiceType           corev1.ServiceType
+		NodePort              int32
+		Port                  int32
+		HTTPSPort             int32
+		IPFamiliesYaml        string
+		AnnotationsYaml       string
 		DexServer             *authv1alpha1.DexServer
 	}{
-		ServingCertSecretName: fmt.Sprintf(dexServer.Name + SECRET_WEB_TLS_SUFFIX),
+		ServingCertSecretName: servingCertSecretName,
+		ServiceType:           serviceType,
+		NodePort:              nodePort,
+		Port:                  getWebServicePort(dexServer),
+		HTTPSPort:             httpsPort,
+		IPFamiliesYaml:        ipFamiliesYaml,
+		AnnotationsYaml:       string(annotationsYaml),
 		DexServer:             dexServer,
 	}
 
@@ -885,34 +2499,339 @@ func (r *DexServerReconciler) syncService(dexServer *authv1alpha1.DexServer, ctx
 	}
 
 	applier, readerDeploy := r.getApplierAndReader(dexServer)
-	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	err = r.applyWithDiff(dexServer, ctx, applier, applier.ApplyDirectly, readerDeploy, values, files...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Annotations enabling the PROXY protocol on the cloud load balancers. Dex does not accept the PROXY protocol header.
+var proxyProtocolAnnotations = []string{
+	"service.beta.kubernetes.io/aws-load-balancer-proxy-protocol",
+	"service.beta.kubernetes.io/azure-pls-proxy-protocol",
+}
+
+// Dex serves plain HTTP when the TLS connections are terminated by the cloud load balancer
+func isTLSTerminatedAtLoadBalancer(dexServer *authv1alpha1.DexServer) bool {
+	return dexServer.Spec.Service.TLSTermination == authv1alpha1.TLSTerminationLoadBalancer
+}
+
+// Port of the dex web Service. A load balancer terminating TLS listens on the default HTTPS port of the issuer.
+func getWebServicePort(dexServer *authv1alpha1.DexServer) int32 {
+	if isTLSTerminatedAtLoadBalancer(dexServer) {
+		return 443
+	}
+	return DEX_HTTPS_PORT
+}
+
+func validateServiceTLSTermination(dexServer *authv1alpha1.DexServer) error {
+	if isTLSTerminatedAtLoadBalancer(dexServer) && dexServer.Spec.Service.Type != corev1.ServiceTypeLoadBalancer {
+		return fmt.Errorf("spec.service.tlsTermination LoadBalancer requires spec.service.type LoadBalancer")
+	}
+	for _, annotation := range proxyProtocolAnnotations {
+		if _, ok := dexServer.Spec.Service.Annotations[annotation]; ok {
+			return fmt.Errorf("annotation %s is not supported, dex does not accept the PROXY protocol", annotation)
+		}
+	}
+	return nil
+}
+
+// Get the ipFamilies and ipFamilyPolicy of the dex Services. When they are not set, the cluster defaults apply:
+// single-stack Services of the primary IP family of the cluster.
+func getServiceIPFamiliesYaml(dexServer *authv1alpha1.DexServer) (string, error) {
+	if len(dexServer.Spec.Service.IPFamilies) == 0 && dexServer.Spec.Service.IPFamilyPolicy == nil {
+		return "", nil
+	}
+	b, err := yaml.Marshal(&struct {
+		IPFamilies     []corev1.IPFamily          `json:"ipFamilies,omitempty"`
+		IPFamilyPolicy *corev1.IPFamilyPolicyType `json:"ipFamilyPolicy,omitempty"`
+	}{
+		IPFamilies:     dexServer.Spec.Service.IPFamilies,
+		IPFamilyPolicy: dexServer.Spec.Service.IPFamilyPolicy,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Get the issuer of the dex server. This is spec.issuer, or when requested for a NodePort Service, the address of a
+// cluster node and the node port of the dex web Service.
+func (r *DexServerReconciler) getIssuer(dexServer *authv1alpha1.DexServer, ctx context.Context) (string, error) {
+	if dexServer.Spec.Issuer != "" {
+		return dexServer.Spec.Issuer, nil
+	}
+	if !dexServer.Spec.Service.IssuerFromNodeAddress || dexServer.Spec.Service.Type != corev1.ServiceTypeNodePort {
+		return r.getGeneratedIssuer(dexServer, ctx)
+	}
+
+	service := &corev1.Service{}
+	if err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, service); err != nil {
+		return "", errors.Wrap(err, "error getting dex web service to derive the issuer")
+	}
+	if len(service.Spec.Ports) == 0 || service.Spec.Ports[0].NodePort == 0 {
+		return "", fmt.Errorf("no node port allocated yet for service %s/%s", service.Namespace, service.Name)
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return "", errors.Wrap(err, "error listing nodes to derive the issuer")
+	}
+	address := getNodeAddress(nodes.Items)
+	if address == "" {
+		return "", fmt.Errorf("no node address found to derive the issuer")
+	}
+	return "https://" + net.JoinHostPort(address, strconv.Itoa(int(service.Spec.Ports[0].NodePort))), nil
+}
+
+// Generate the issuer of a DexServer exposed by a route from the cluster ingress domain, as the OpenShift router
+// would name the host of the route. The issuer is empty when the cluster has no ingress domain.
+func (r *DexServerReconciler) getGeneratedIssuer(dexServer *authv1alpha1.DexServer, ctx context.Context) (string, error) {
+	if !r.OpenShift || dexServer.Spec.Service.Type == corev1.ServiceTypeNodePort ||
+		dexServer.Spec.Service.Type == corev1.ServiceTypeLoadBalancer {
+		return "", nil
+	}
+	domain, err := clusterIngressDomain.get(ctx, r.DynamicClient)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the cluster ingress domain to generate the issuer")
+	}
+	if domain == "" {
+		return "", nil
+	}
+	label := dexServer.Name + "-" + dexServer.Namespace
+	if len(label) > validation.DNS1123LabelMaxLength {
+		return "", fmt.Errorf("the generated issuer host %s.%s is not a valid DNS name, its first label is longer than %d characters, set spec.issuer",
+			label, domain, validation.DNS1123LabelMaxLength)
+	}
+	return "https://" + label + "." + strings.TrimPrefix(domain, ".") + dexServer.Spec.Route.Path, nil
+}
+
+// Get the address of the first node that has one, preferring external addresses
+func getNodeAddress(nodes []corev1.Node) string {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, addressType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+		for _, node := range nodes {
+			for _, address := range node.Status.Addresses {
+				if address.Type == addressType && address.Address != "" {
+					return address.Address
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Generate the dex web certificate when the cluster does not provide service serving certificates, and renew it before it expires
+func (r *DexServerReconciler) syncServingCertSecret(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	secretName := dexServer.Name + SECRET_WEB_TLS_SUFFIX
+	log.Info("syncServingCertSecret", "Secret.Name", secretName)
+
+	dnsNames := []string{
+		fmt.Sprintf("%s.%s.svc", dexServer.Name, dexServer.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", dexServer.Name, dexServer.Namespace),
+	}
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	if u, err := url.Parse(issuer); err == nil && u.Hostname() != "" {
+		dnsNames = append(dnsNames, u.Hostname())
+	}
+	// the hosts of spec.additionalHosts are validated by syncIngress
+	dnsNames = append(dnsNames, dexServer.Spec.AdditionalHosts...)
+	if dexServer.Spec.ExternalIssuer.Enabled {
+		// a missing route host is reported by syncIngress
+		if routeHost, err := r.getExternalIssuerRouteHost(dexServer, ctx); err == nil {
+			dnsNames = append(dnsNames, routeHost)
+		}
+	}
+
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: dexServer.Namespace}, secret)
+	secretExists := err == nil
+	switch {
+	case err == nil:
+		if r.FIPS && !isFIPSCompliantCertificate(secret.Data["tls.crt"]) {
+			log.Info("serving certificate is not FIPS compliant... regenerate")
+			break
+		}
+		if !isCertificateForHosts(secret.Data["tls.crt"], dnsNames) {
+			log.Info("serving certificate does not match the hosts of dex... regenerate")
+			break
+		}
+		if expiryTime, err := time.Parse(time.RFC3339, secret.Annotations[MTLS_CERT_EXPIRY_ANNOTATION]); err == nil && !inCertRenewalWindow(expiryTime) {
+			return nil
+		}
+		log.Info("serving certificate is missing its expiry or nearing expiration... regenerate")
+	case !kubeerrors.IsNotFound(err):
+		return errors.Wrap(err, "error getting serving certificate secret")
+	}
+
+	certPEM, keyPEM, expiry, err := generateServingCert(dnsNames, r.FIPS, r.KeyPool)
+	if err != nil {
+		return errors.Wrap(err, "error generating serving certificate")
+	}
+
+	spec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: dexServer.Namespace,
+			Labels: map[string]string{
+				"app": dexServer.Name,
+			},
+			Annotations: map[string]string{
+				MTLS_CERT_EXPIRY_ANNOTATION: expiry.UTC().Format(time.RFC3339),
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM.Bytes(),
+			corev1.TLSPrivateKeyKey: keyPEM.Bytes(),
+		},
+	}
+	r.addManagedMetadata(dexServer, componentWeb, spec.Labels, spec.Annotations)
+	ctrl.SetControllerReference(dexServer, spec, r.Scheme)
+
+	if !secretExists {
+		log.Info("Creating a new serving certificate Secret", "Secret.Namespace", spec.Namespace, "Secret.Name", spec.Name)
+		if err := r.Create(ctx, spec); err != nil {
+			return errors.Wrap(err, "error creating serving certificate secret")
+		}
+		return nil
+	}
+	log.Info("Updating serving certificate Secret", "Secret.Namespace", spec.Namespace, "Secret.Name", spec.Name)
+	if err := r.Update(ctx, spec); err != nil {
+		return errors.Wrap(err, "error updating serving certificate secret")
+	}
+	return nil
+}
+
+func (r *DexServerReconciler) getApplierAndReader(dexServer *authv1alpha1.DexServer) (clusteradmapply.Applier, asset.ScenarioReader) {
+	applierBuilder := &clusteradmapply.ApplierBuilder{}
+	applier := applierBuilder.
+		WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).
+		WithOwner(dexServer, true, true, r.Scheme).
+		WithTemplateFuncMap(r.getTemplateFuncMap(dexServer)).
+		Build()
+
+	readerDeploy := deploy.GetScenarioResourcesReader()
+	return applier, readerDeploy
+}
+
+type applyFunc func(reader asset.ScenarioReader, values interface{}, dryRun bool, headerFile string, files ...string) ([]string, error)
+
+// Apply the templates with the given applier method. The fields changed on the objects that already existed are
+// logged at debug level and recorded in an Event on the DexServer. During a repair, the objects are server-side
+// applied instead, and the changes are collected in the repair report.
+func (r *DexServerReconciler) applyWithDiff(dexServer *authv1alpha1.DexServer, ctx context.Context, applier clusteradmapply.Applier, apply applyFunc, reader asset.ScenarioReader, values interface{}, files ...string) error {
+	log := ctrllog.FromContext(ctx)
+
+	start := time.Now()
+	objects := []*unstructured.Unstructured{}
+	objectFiles := []string{}
+	for _, name := range files {
+		objYaml, err := applier.MustTempalteAsset(reader, values, "", name)
+		if err != nil {
+			if clusteradmapply.IsEmptyAsset(err) {
+				continue
+			}
+			return failures.Wrap(failures.RenderFailure, err)
+		}
+		objJson, err := yaml.YAMLToJSON(objYaml)
+		if err != nil {
+			return failures.Wrap(failures.RenderFailure, err)
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(objJson); err != nil {
+			return failures.Wrap(failures.RenderFailure, err)
+		}
+		objects = append(objects, obj)
+		objectFiles = append(objectFiles, name)
+	}
+	// The applier renders the objects adjusted by the registered mutators
+	reader, err := mutateObjects(ctx, dexServer, reader, objectFiles, objects)
+	timePhase(ctx, reconcilePhaseRender, start)
 	if err != nil {
 		return err
 	}
+	defer timePhase(ctx, reconcilePhaseApply, time.Now())
+
+	// The changes are only reported for an observed DexServer
+	if observe := getObserveReport(ctx); observe != nil {
+		return r.observeObjects(ctx, observe, objects)
+	}
+
+	existingObjects := []*unstructured.Unstructured{}
+	missingObjects := []*unstructured.Unstructured{}
+	for _, obj := range objects {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(obj.GroupVersionKind())
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+			if !kubeerrors.IsNotFound(err) {
+				return err
+			}
+			missingObjects = append(missingObjects, obj)
+			continue
+		}
+		existingObjects = append(existingObjects, existing)
+	}
+
+	repair := getRepairReport(ctx)
+	if repair != nil {
+		if err := r.forceApply(ctx, objects); err != nil {
+			return err
+		}
+		for _, obj := range missingObjects {
+			repair.add(obj, "recreated")
+		}
+	} else if _, err := apply(reader, values, false, "", files...); err != nil {
+		return err
+	}
 
+	for _, existing := range existingObjects {
+		updated := &unstructured.Unstructured{}
+		updated.SetGroupVersionKind(existing.GroupVersionKind())
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(existing), updated); err != nil {
+			return err
+		}
+		changed := diff.Fields(existing.Object, updated.Object)
+		if len(changed) == 0 {
+			continue
+		}
+		log.V(1).Info("updated owned object", "Kind", existing.GetKind(), "Name", existing.GetName(), "Namespace", existing.GetNamespace(), "ChangedFields", changed)
+		if repair != nil {
+			repair.add(existing, diff.Summary(changed, MAX_EVENT_DIFF_FIELDS))
+		}
+		if r.Recorder != nil {
+			r.Recorder.Eventf(dexServer, corev1.EventTypeNormal, "Updated", "Updated %s %s: %s",
+				existing.GetKind(), existing.GetName(), diff.Summary(changed, MAX_EVENT_DIFF_FIELDS))
+		}
+	}
 	return nil
 }
 
-func (r *DexServerReconciler) getApplierAndReader(dexServer *authv1alpha1.DexServer) (clusteradmapply.Applier, asset.ScenarioReader) {
-	applierBuilder := &clusteradmapply.ApplierBuilder{}
-	applier := applierBuilder.
-		WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).
-		WithOwner(dexServer, true, true, r.Scheme).
-		Build()
-
-	readerDeploy := deploy.GetScenarioResourcesReader()
-	return applier, readerDeploy
-}
-
 func (r *DexServerReconciler) syncServiceGrpc(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
 	log := ctrllog.FromContext(ctx)
 	log.Info("syncServiceGrpc", "DexServer.Name", dexServer.Name, "DexServer.Namespace", dexServer.Namespace)
 
+	_, grpcPort := getDexPorts(dexServer)
+	ipFamiliesYaml, err := getServiceIPFamiliesYaml(dexServer)
+	if err != nil {
+		return err
+	}
+
 	values := struct {
 		GrpcServiceName string
+		GRPCPort        int32
+		IPFamiliesYaml  string
 		DexServer       *authv1alpha1.DexServer
 	}{
 		GrpcServiceName: GRPC_SERVICE_NAME,
+		GRPCPort:        grpcPort,
+		IPFamiliesYaml:  ipFamiliesYaml,
 		DexServer:       dexServer,
 	}
 
@@ -921,12 +2840,12 @@ func (r *DexServerReconciler) syncServiceGrpc(dexServer *authv1alpha1.DexServer,
 	}
 
 	applier, readerDeploy := r.getApplierAndReader(dexServer)
-	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	err = r.applyWithDiff(dexServer, ctx, applier, applier.ApplyDirectly, readerDeploy, values, files...)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.removeInstanceMetadata(ctx, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: GRPC_SERVICE_NAME, Namespace: dexServer.Namespace}})
 }
 
 type DexConnectorConfigSpec struct {
@@ -936,24 +2855,30 @@ type DexConnectorConfigSpec struct {
 	RedirectURI  string `yaml:"redirectURI,omitempty"`
 
 	// Github configuration
-	Org           string             `yaml:"org,omitempty"`
-	Orgs          []authv1alpha1.Org `yaml:"orgs,omitempty"`
-	HostName      string             `yaml:"hostName,omitempty"`
-	TeamNameField string             `yaml:"teamNameField,omitempty"`
-	LoadAllGroups bool               `yaml:"loadAllGroups,omitempty"`
-	UseLoginAsID  bool               `yaml:"useLoginAsID,omitempty"`
-
-	// Microsoft configuration
+	Org                  string             `yaml:"org,omitempty"`
+	Orgs                 []authv1alpha1.Org `yaml:"orgs,omitempty"`
+	HostName             string             `yaml:"hostName,omitempty"`
+	TeamNameField        string             `yaml:"teamNameField,omitempty"`
+	LoadAllGroups        bool               `yaml:"loadAllGroups,omitempty"`
+	UseLoginAsID         bool               `yaml:"useLoginAsID,omitempty"`
+	PreferredEmailDomain string             `yaml:"preferredEmailDomain,omitempty"`
+
+	// Microsoft configuration, Groups is shared with AuthProxy, Google and OpenShift
 	Tenant             string   `yaml:"tenant,omitempty"`
 	OnlySecurityGroups bool     `yaml:"onlySecurityGroups,omitempty"`
 	Groups             []string `yaml:"groups,omitempty"`
 
+	// Google configuration
+	HostedDomains          []string `yaml:"hostedDomains,omitempty"`
+	ServiceAccountFilePath string   `yaml:"serviceAccountFilePath,omitempty"`
+	AdminEmail             string   `yaml:"adminEmail,omitempty"`
+
 	// LDAP configuration
 	Host               string                       `yaml:"host,omitempty"`
 	InsecureNoSSL      bool                         `yaml:"insecureNoSSL,omitempty"`
 	InsecureSkipVerify bool                         `yaml:"insecureSkipVerify,omitempty"`
 	StartTLS           bool                         `yaml:"startTLS,omitempty"`
-	ClientCA           string                       `yaml:"clientCA,omitempty"`
+	ClientCert         string                       `yaml:"clientCert,omitempty"`
 	ClientKey          string                       `yaml:"clientKey,omitempty"`
 	RootCAData         []byte                       `yaml:"rootCAData,omitempty"`
 	BindDN             string                       `yaml:"bindDN,omitempty"`
@@ -962,20 +2887,135 @@ type DexConnectorConfigSpec struct {
 	UserSearch         authv1alpha1.UserSearchSpec  `yaml:"userSearch,omitempty"`
 	GroupSearch        authv1alpha1.GroupSearchSpec `yaml:"groupSearch,omitempty"`
 
-	//OpenID configuration
-	Issuer       string                        `yaml:"issuer,omitempty"`
-	ClaimMapping authv1alpha1.ClaimMappingSpec `yaml:"claimMapping,omitempty"`
+	// Bitbucket Cloud configuration
+	Teams             []string `yaml:"teams,omitempty"`
+	IncludeTeamGroups bool     `yaml:"includeTeamGroups,omitempty"`
+
+	// Gitea configuration, with Orgs, LoadAllGroups and UseLoginAsID
+	BaseURL string `yaml:"baseURL,omitempty"`
 
-	// Common field between GitHub and LDAP configs
+	//OpenID configuration
+	Issuer                    string                              `yaml:"issuer,omitempty"`
+	UserNameKey               string                              `yaml:"userNameKey,omitempty"`
+	ClaimMapping              DexClaimMappingSpec                 `yaml:"claimMapping,omitempty"`
+	Scopes                    []string                            `yaml:"scopes,omitempty"`
+	InsecureSkipEmailVerified bool                                `yaml:"insecureSkipEmailVerified,omitempty"`
+	InsecureEnableGroups      bool                                `yaml:"insecureEnableGroups,omitempty"`
+	ClaimModifications        authv1alpha1.ClaimModificationsSpec `yaml:"claimModifications,omitempty"`
+
+	// SAML configuration
+	SSOURL       string `yaml:"ssoURL,omitempty"`
+	CA           string `yaml:"ca,omitempty"`
+	CAData       []byte `yaml:"caData,omitempty"`
+	EntityIssuer string `yaml:"entityIssuer,omitempty"`
+	SSOIssuer    string `yaml:"ssoIssuer,omitempty"`
+	UsernameAttr string `yaml:"usernameAttr,omitempty"`
+	EmailAttr    string `yaml:"emailAttr,omitempty"`
+	GroupsAttr   string `yaml:"groupsAttr,omitempty"`
+
+	// AuthProxy configuration
+	UserHeader  string `yaml:"userHeader,omitempty"`
+	EmailHeader string `yaml:"emailHeader,omitempty"`
+	GroupHeader string `yaml:"groupHeader,omitempty"`
+
+	// mockPassword configuration
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// Common field between GitHub, LDAP and OpenShift configs
 	RootCA string `json:"rootCA,omitempty"`
 }
 
+// Claim mapping of the dex OpenID connector, the display name claim is the userNameKey of the connector
+type DexClaimMappingSpec struct {
+	PreferredUsernameKey string `json:"preferred_username,omitempty"`
+	EmailKey             string `json:"email,omitempty"`
+	GroupsKey            string `json:"groups,omitempty"`
+}
+
 type DexConnectorSpec struct {
 	// +kubebuilder:validation:Enum=github;ldap
 	Type   string                 `yaml:"type,omitempty"`
 	Id     string                 `yaml:"id,omitempty"`
 	Name   string                 `yaml:"name,omitempty"`
 	Config DexConnectorConfigSpec `yaml:"config,omitempty"`
+	// Config of the raw connectors, rendered verbatim instead of Config
+	RawConfig json.RawMessage `json:"-"`
+}
+
+// MarshalJSON renders the config of the raw connectors verbatim
+func (spec DexConnectorSpec) MarshalJSON() ([]byte, error) {
+	type dexConnectorSpec DexConnectorSpec
+	if spec.RawConfig == nil {
+		return json.Marshal(dexConnectorSpec(spec))
+	}
+	return json.Marshal(struct {
+		Type   string          `yaml:"type,omitempty"`
+		Id     string          `yaml:"id,omitempty"`
+		Name   string          `yaml:"name,omitempty"`
+		Config json.RawMessage `yaml:"config,omitempty"`
+	}{spec.Type, spec.Id, spec.Name, spec.RawConfig})
+}
+
+// Expiry section of the dex config
+type DexExpirySpec struct {
+	SigningKeys    string                `json:"signingKeys,omitempty"`
+	IDTokens       string                `json:"idTokens,omitempty"`
+	AuthRequests   string                `json:"authRequests,omitempty"`
+	DeviceRequests string                `json:"deviceRequests,omitempty"`
+	RefreshTokens  *DexRefreshTokensSpec `json:"refreshTokens,omitempty"`
+}
+
+// Frontend section of the dex config, the branding of spec.frontend and the directory of the custom web content
+type DexFrontendSpec struct {
+	authv1alpha1.FrontendSpec
+	Dir string `json:"dir,omitempty"`
+}
+
+type DexRefreshTokensSpec struct {
+	ValidIfNotUsedFor string `json:"validIfNotUsedFor,omitempty"`
+	AbsoluteLifetime  string `json:"absoluteLifetime,omitempty"`
+	ReuseInterval     string `json:"reuseInterval,omitempty"`
+	DisableRotation   bool   `json:"disableRotation,omitempty"`
+}
+
+// Get the expiry section of the dex config, nil when the dex defaults apply
+func getDexExpiry(expiry authv1alpha1.ExpirySpec) *DexExpirySpec {
+	dexExpiry := &DexExpirySpec{
+		SigningKeys:    durationString(expiry.SigningKeys),
+		IDTokens:       durationString(expiry.IDTokens),
+		AuthRequests:   durationString(expiry.AuthRequests),
+		DeviceRequests: durationString(expiry.DeviceRequests),
+	}
+	refreshTokens := DexRefreshTokensSpec{
+		ValidIfNotUsedFor: durationString(expiry.RefreshTokens.ValidIfNotUsedFor),
+		AbsoluteLifetime:  durationString(expiry.RefreshTokens.AbsoluteLifetime),
+		ReuseInterval:     durationString(expiry.RefreshTokens.ReuseInterval),
+		DisableRotation:   expiry.RefreshTokens.DisableRotation,
+	}
+	if refreshTokens != (DexRefreshTokensSpec{}) {
+		dexExpiry.RefreshTokens = &refreshTokens
+	}
+	if *dexExpiry == (DexExpirySpec{}) {
+		return nil
+	}
+	return dexExpiry
+}
+
+func hasGrantType(grantTypes []authv1alpha1.GrantType, grantType authv1alpha1.GrantType) bool {
+	for _, g := range grantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+func durationString(d *metav1.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.Duration.String()
 }
 
 func (r *DexServerReconciler) syncConfigMap(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
@@ -984,180 +3024,145 @@ func (r *DexServerReconciler) syncConfigMap(dexServer *authv1alpha1.DexServer, c
 
 	connectors := []DexConnectorSpec{}
 
-	// Iterate over connectors defined in the DexServer to create the dex configuration for connectors
+	waitingForSecret, err := r.getWaitingForSecretCondition(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	if err := updateDexServerStatusConditions(r.Client, dexServer, waitingForSecret); err != nil {
+		return err
+	}
 
-	for _, connector := range dexServer.Spec.Connectors {
-		// get an alphanumeric ID for the connector that can be used as a suffix in the env variable name containing the secret for this connector
-		connectorAlphanumericId := getUniqueAlphanumericIdForConnector(connector)
+	renderedConnectors, rejected, err := r.getRenderedConnectors(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	dexServer.Status.RejectedConnectors = rejected
+	if len(rejected) == 0 {
+		dexServer.Status.RejectedConnectors = nil
+	}
+	if err := updateDexServerStatusConditions(r.Client, dexServer, getConnectorsSkippedCondition(rejected)); err != nil {
+		return err
+	}
 
+	// Iterate over connectors defined in the DexServer to create the dex configuration for connectors
+
+	ldapHosts := []authv1alpha1.LDAPHostsStatus{}
+	for _, connector := range renderedConnectors {
 		var newConnector DexConnectorSpec
 		switch connector.Type {
+		case authv1alpha1.ConnectorTypeBitbucketCloud:
+			newConnector, err = r.renderBitbucketCloudConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeGitea:
+			newConnector, err = r.renderGiteaConnector(dexServer, connector, ctx)
 		case authv1alpha1.ConnectorTypeGitHub:
-			// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
-			err := r.copySecretToDexServerNamespace(dexServer, connector.GitHub.ClientSecretRef, ctx)
-			if err != nil {
-				return err
-			}
-
-			// Environment variable that references the GitHub client secret copied into the dexserver ns
-			// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple GitHub connectors
-			clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + connectorAlphanumericId
-
-			newConnector = DexConnectorSpec{
-				Type: string(authv1alpha1.ConnectorTypeGitHub),
-				Id:   connector.Id,
-				Name: connector.Name,
-				Config: DexConnectorConfigSpec{
-					ClientID:      connector.GitHub.ClientID,
-					ClientSecret:  clientSecretEnvVariable,
-					RedirectURI:   connector.GitHub.RedirectURI,
-					Org:           connector.GitHub.Org,
-					Orgs:          connector.GitHub.Orgs,
-					LoadAllGroups: connector.GitHub.LoadAllGroups,
-				},
-			}
+			newConnector, err = r.renderGitHubConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeGoogle:
+			newConnector, err = r.renderGoogleConnector(dexServer, connector, ctx)
 		case authv1alpha1.ConnectorTypeMicrosoft:
-			// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
-			err := r.copySecretToDexServerNamespace(dexServer, connector.Microsoft.ClientSecretRef, ctx)
-			if err != nil {
-				return err
-			}
-
-			// Environment variable that references the Microsoft OAuth client secret copied into the dexserver ns
-			// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple Microsoft connectors
-			clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + connectorAlphanumericId
-
-			newConnector = DexConnectorSpec{
-				Type: string(authv1alpha1.ConnectorTypeMicrosoft),
-				Id:   connector.Id,
-				Name: connector.Name,
-				Config: DexConnectorConfigSpec{
-					ClientID:     connector.Microsoft.ClientID,
-					ClientSecret: clientSecretEnvVariable,
-					RedirectURI:  connector.Microsoft.RedirectURI,
-					Tenant:       connector.Microsoft.Tenant,
-				},
-			}
+			newConnector, err = r.renderMicrosoftConnector(dexServer, connector, ctx)
 		case authv1alpha1.ConnectorTypeLDAP:
-			// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
-			err := r.copySecretToDexServerNamespace(dexServer, connector.LDAP.BindPWRef, ctx)
-			if err != nil {
-				return err
+			var status *authv1alpha1.LDAPHostsStatus
+			newConnector, status, err = r.renderLDAPConnector(dexServer, connector, ctx)
+			if status != nil {
+				ldapHosts = append(ldapHosts, *status)
 			}
+		case authv1alpha1.ConnectorTypeOIDC:
+			newConnector, err = r.renderOIDCConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeOpenShift:
+			newConnector, err = r.renderOpenShiftConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeAuthProxy:
+			newConnector, err = r.renderAuthProxyConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeRaw:
+			newConnector, err = r.renderRawConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeMockCallback:
+			newConnector, err = r.renderMockCallbackConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeMockPassword:
+			newConnector, err = r.renderMockPasswordConnector(dexServer, connector, ctx)
+		case authv1alpha1.ConnectorTypeSAML:
+			newConnector, err = r.renderSAMLConnector(dexServer, connector, ctx)
+		default:
+			// rejected by getRenderedConnectors
+			continue
+		}
+		if err != nil {
+			return err
+		}
 
-			// Environment variable that references the LDAP Bind Password secret copied into the dexserver ns
-			// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between bind passwords for multiple connectors
-			bindPWEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + connectorAlphanumericId
+		// Add connector to list
+		connectors = append(connectors, newConnector)
+	}
+	dexServer.Status.LDAPHosts = ldapHosts
+	if len(ldapHosts) == 0 {
+		dexServer.Status.LDAPHosts = nil
+	}
 
-			// If there is a secret reference to the trusted Root CA
-			var rootCAPath, clientCAPath, clientKeyPath string
-			if connector.LDAP.RootCARef.Name != "" {
-				err := r.copySecretToDexServerNamespace(dexServer, connector.LDAP.RootCARef, ctx)
-				if err != nil {
-					return err
-				}
-				// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
-				secretName := connector.LDAP.RootCARef.Namespace + "-" + connector.LDAP.RootCARef.Name
-				secretNamespace := dexServer.Namespace
-				resource := &corev1.Secret{}
+	if dexServer.Spec.ConnectorFailover.Enabled {
+		healthy := probeConnectors(dexServer, ctx)
+		connectors = applyConnectorFailover(dexServer, connectors, healthy)
+	} else {
+		dexServer.Status.ConnectorHealth = nil
+	}
 
-				if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil {
-					// Error getting secret
-					log.Error(err, "Error getting root CA secret in dex server ns")
-					return err
-				}
+	// The ConfigMap is not secret, credentials must only be referenced by the environment variables expanded by dex
+	if err := checkConnectorCredentialsAreReferences(connectors); err != nil {
+		return err
+	}
 
-				if string(resource.Data["ca.crt"]) != "" {
-					rootCAPath = "/etc/dex/ldapcerts/" + connector.Id + "/ca.crt"
-				}
-				if string(resource.Data["tls.crt"]) != "" {
-					clientCAPath = "/etc/dex/ldapcerts/" + connector.Id + "/tls.crt"
-				}
-				if string(resource.Data["tls.key"]) != "" {
-					clientKeyPath = "/etc/dex/ldapcerts/" + connector.Id + "/tls.key"
-				}
-			}
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return err
+	}
 
-			newConnector = DexConnectorSpec{
-				Type: string(authv1alpha1.ConnectorTypeLDAP),
-				Id:   connector.Id,
-				Name: connector.Name,
-				Config: DexConnectorConfigSpec{
-					Host:               connector.LDAP.Host,
-					InsecureNoSSL:      connector.LDAP.InsecureNoSSL,
-					InsecureSkipVerify: connector.LDAP.InsecureSkipVerify,
-					StartTLS:           connector.LDAP.StartTLS,
-					RootCA:             rootCAPath,
-					ClientCA:           clientCAPath,
-					ClientKey:          clientKeyPath,
-					BindDN:             connector.LDAP.BindDN,
-					BindPW:             bindPWEnvVariable,
-					UsernamePrompt:     connector.LDAP.UsernamePrompt,
-				},
-			}
+	setDefaultRedirectURIs(connectors, issuer)
+	values, err := getDexConfigValues(dexServer, issuer, connectors, rejected)
+	if err != nil {
+		log.Error(err, "failed to render dex config.yaml")
+		return err
+	}
+	// Keep the running dex on its current configuration rather than applying one it would reject or misread
+	config, err := r.renderDexConfig(dexServer, values)
+	if err != nil {
+		return err
+	}
+	if _, err := dexconfig.Load([]byte(config)); err != nil {
+		return errors.Wrap(err, "the rendered dex config.yaml is not valid")
+	}
 
-			if connector.LDAP.UserSearch.BaseDN != "" {
-				newConnector.Config.UserSearch.BaseDN = connector.LDAP.UserSearch.BaseDN
-				newConnector.Config.UserSearch.Filter = connector.LDAP.UserSearch.Filter
-				newConnector.Config.UserSearch.Username = connector.LDAP.UserSearch.Username
-				newConnector.Config.UserSearch.Scope = connector.LDAP.UserSearch.Scope
-				newConnector.Config.UserSearch.IDAttr = connector.LDAP.UserSearch.IDAttr
-				newConnector.Config.UserSearch.EmailAttr = connector.LDAP.UserSearch.EmailAttr
-				newConnector.Config.UserSearch.NameAttr = connector.LDAP.UserSearch.NameAttr
-				newConnector.Config.UserSearch = authv1alpha1.UserSearchSpec{
-					BaseDN:    connector.LDAP.UserSearch.BaseDN,
-					Filter:    connector.LDAP.UserSearch.Filter,
-					Username:  connector.LDAP.UserSearch.Username,
-					Scope:     connector.LDAP.UserSearch.Scope,
-					IDAttr:    connector.LDAP.UserSearch.IDAttr,
-					EmailAttr: connector.LDAP.UserSearch.EmailAttr,
-					NameAttr:  connector.LDAP.UserSearch.NameAttr,
-				}
-			}
+	files := []string{
+		"dex-server/config_map.yaml",
+	}
 
-			if connector.LDAP.GroupSearch.BaseDN != "" {
-				newConnector.Config.GroupSearch = authv1alpha1.GroupSearchSpec{
-					BaseDN:       connector.LDAP.GroupSearch.BaseDN,
-					Filter:       connector.LDAP.GroupSearch.Filter,
-					Scope:        connector.LDAP.GroupSearch.Scope,
-					UserMatchers: connector.LDAP.GroupSearch.UserMatchers,
-					NameAttr:     connector.LDAP.GroupSearch.NameAttr,
-				}
-			}
-		case authv1alpha1.ConnectorTypeOIDC:
-			// Check if secret is in the dex server namespace and copy it into the dexserver ns
-			// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
-			if secretNamespace := connector.OIDC.ClientSecretRef.Namespace; secretNamespace != dexServer.Namespace {
-				err := r.copySecretToDexServerNamespace(dexServer, connector.OIDC.ClientSecretRef, ctx)
-				if err != nil {
-					return err
-				}
-			}
+	applier, readerDeploy := r.getApplierAndReader(dexServer)
+	err = r.applyWithDiff(dexServer, ctx, applier, applier.ApplyDirectly, readerDeploy, values, files...)
+	if err != nil {
+		return err
+	}
 
-			// Environment variable that references the GitHub client secret copied into the dexserver ns
-			// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple GitHub connectors
-			clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + connectorAlphanumericId
-
-			newConnector = DexConnectorSpec{
-				Type: string(authv1alpha1.ConnectorTypeOIDC),
-				Id:   connector.Id,
-				Name: connector.Name,
-				Config: DexConnectorConfigSpec{
-					ClientID:     connector.OIDC.ClientID,
-					ClientSecret: clientSecretEnvVariable,
-					RedirectURI:  connector.OIDC.RedirectURI,
-					Issuer:       connector.OIDC.Issuer,
-					ClaimMapping: connector.OIDC.ClaimMapping,
-				},
-			}
-		default:
-			return nil
-		}
+	return nil
+}
 
-		// Add connector to list
-		connectors = append(connectors, newConnector)
-	}
+// dexConfigValues are the values of the dex config.yaml template, in the dex-server/config_map.yaml ConfigMap
+type dexConfigValues struct {
+	Issuer             string
+	StorageYaml        string
+	ConnectorsYaml     string
+	ExpiryYaml         string
+	FrontendYaml       string
+	SkipApprovalScreen bool
+	PasswordConnector  string
+	GrantTypes         []authv1alpha1.GrantType
+	WebAddress         string
+	GRPCAddress        string
+	TelemetryAddress   string
+	WebHTTP            bool
+	// Inline CAs of the connectors, added to the ConfigMap next to config.yaml and mounted in the dex pod
+	ConnectorCAs map[string]string
+	DexServer    *authv1alpha1.DexServer
+}
 
+// Get the values of the dex config.yaml template from the DexServer and its rendered connectors. This does not
+// read the cluster, so that any DexServer spec can be rendered and validated in tests.
+func getDexConfigValues(dexServer *authv1alpha1.DexServer, issuer string, connectors []DexConnectorSpec, rejected []authv1alpha1.RejectedConnectorStatus) (*dexConfigValues, error) {
 	connectorYamlSpec := struct {
 		Connectors []DexConnectorSpec `json:"connectors,omitempty"`
 	}{
@@ -1166,60 +3171,275 @@ func (r *DexServerReconciler) syncConfigMap(dexServer *authv1alpha1.DexServer, c
 
 	// Get yaml representation of configYamlData
 	connectorYaml, err := yaml.Marshal(&connectorYamlSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal dex config.yaml connectors")
+	}
 
+	storageYamlSpec := struct {
+		Storage DexStorageSpec `json:"storage"`
+	}{
+		Storage: getDexStorage(dexServer.Spec.Storage, STORAGE_PASSWORD_ENV_VAR),
+	}
+	storageYaml, err := yaml.Marshal(&storageYamlSpec)
 	if err != nil {
-		log.Error(err, "failed to marshal dex config.yaml")
-		return err
+		return nil, errors.Wrap(err, "failed to marshal dex config.yaml storage")
 	}
 
-	values := struct {
-		Issuer         string
-		ConnectorsYaml string
-		DexServer      *authv1alpha1.DexServer
+	expiryYamlSpec := struct {
+		Expiry *DexExpirySpec `json:"expiry,omitempty"`
 	}{
-		Issuer:         dexServer.Spec.Issuer,
-		ConnectorsYaml: string(connectorYaml),
-		DexServer:      dexServer,
+		Expiry: getDexExpiry(getDexServerExpiry(dexServer)),
+	}
+	expiryYaml := []byte{}
+	if expiryYamlSpec.Expiry != nil {
+		expiryYaml, err = yaml.Marshal(&expiryYamlSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal dex config.yaml expiry")
+		}
 	}
 
-	files := []string{
-		"dex-server/config_map.yaml",
+	frontend := DexFrontendSpec{FrontendSpec: dexServer.Spec.Frontend}
+	if dexServer.Spec.Web.TemplatesConfigMapRef != nil {
+		frontend.Dir = DEX_WEB_DIR
 	}
+	frontendYaml := []byte{}
+	if frontend != (DexFrontendSpec{}) {
+		frontendYamlSpec := struct {
+			Frontend DexFrontendSpec `json:"frontend"`
+		}{
+			Frontend: frontend,
+		}
+		frontendYaml, err = yaml.Marshal(&frontendYamlSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal dex config.yaml frontend")
+		}
+	}
+
+	webAddress, grpcAddress, err := getDexListenAddresses(dexServer)
+	if err != nil {
+		return nil, err
+	}
+	telemetryAddress, err := getTelemetryAddress(dexServer)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordConnector := dexServer.Spec.OAuth2.PasswordConnector
+	if passwordConnector != "" && !hasConnectorWithId(connectors, passwordConnector) {
+		if !isConnectorRejected(rejected, passwordConnector) {
+			return nil, fmt.Errorf("password connector %q does not match the id of a connector", passwordConnector)
+		}
+		// the password grant is disabled until the connector is rendered again
+		passwordConnector = ""
+	}
+	skipApprovalScreen := true
+	if dexServer.Spec.OAuth2.SkipApprovalScreen != nil {
+		skipApprovalScreen = *dexServer.Spec.OAuth2.SkipApprovalScreen
+	}
+	// dex only enables the password grant of the password connector when it is listed
+	grantTypes := dexServer.Spec.OAuth2.GrantTypes
+	if len(grantTypes) > 0 && passwordConnector != "" && !hasGrantType(grantTypes, authv1alpha1.GrantTypePassword) {
+		grantTypes = append(append([]authv1alpha1.GrantType{}, grantTypes...), authv1alpha1.GrantTypePassword)
+	}
+
+	return &dexConfigValues{
+		Issuer:             issuer,
+		StorageYaml:        string(storageYaml),
+		ConnectorsYaml:     string(connectorYaml),
+		ExpiryYaml:         string(expiryYaml),
+		FrontendYaml:       string(frontendYaml),
+		SkipApprovalScreen: skipApprovalScreen,
+		PasswordConnector:  passwordConnector,
+		GrantTypes:         grantTypes,
+		WebAddress:         webAddress,
+		GRPCAddress:        grpcAddress,
+		TelemetryAddress:   telemetryAddress,
+		WebHTTP:            isTLSTerminatedAtLoadBalancer(dexServer),
+		ConnectorCAs:       getConnectorCAs(dexServer),
+		DexServer:          dexServer,
+	}, nil
+}
+
+// Get the inline CAs of the connectors by key of the ConfigMap of the dex config. dex only reads the CA of the
+// GitHub connector from a file.
+func getConnectorCAs(dexServer *authv1alpha1.DexServer) map[string]string {
+	cas := map[string]string{}
+	for _, connector := range dexServer.Spec.Connectors {
+		if connector.Type == authv1alpha1.ConnectorTypeGitHub && connector.GitHub.HostName != "" && len(connector.GitHub.RootCAData) > 0 {
+			cas[getGitHubRootCAKey(connector)] = string(connector.GitHub.RootCAData)
+		}
+	}
+	return cas
+}
 
+// Render the dex config.yaml of the ConfigMap of the DexServer
+func (r *DexServerReconciler) renderDexConfig(dexServer *authv1alpha1.DexServer, values *dexConfigValues) (string, error) {
 	applier, readerDeploy := r.getApplierAndReader(dexServer)
-	_, err = applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	rendered, err := applier.MustTemplateAssets(readerDeploy, values, "", "dex-server/config_map.yaml")
 	if err != nil {
-		return err
+		return "", failures.Wrap(failures.RenderFailure, err)
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := yaml.Unmarshal([]byte(rendered[0]), configMap); err != nil {
+		return "", failures.Wrap(failures.RenderFailure, errors.Wrap(err, "the rendered dex ConfigMap is not valid yaml"))
+	}
+	return configMap.Data["config.yaml"], nil
+}
+
+func hasConnectorWithId(connectors []DexConnectorSpec, id string) bool {
+	for _, connector := range connectors {
+		if connector.Id == id {
+			return true
+		}
 	}
+	return false
+}
 
+func checkConnectorCredentialsAreReferences(connectors []DexConnectorSpec) error {
+	for _, connector := range connectors {
+		for _, credential := range []string{connector.Config.ClientSecret, connector.Config.BindPW} {
+			if credential != "" && !strings.HasPrefix(credential, "$") {
+				return fmt.Errorf("connector %q has a credential that is not an environment variable reference", connector.Id)
+			}
+		}
+	}
 	return nil
 }
 
 func (r *DexServerReconciler) syncIngress(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
 	log := ctrllog.FromContext(ctx)
-	u, _ := url.Parse(dexServer.Spec.Issuer)
+	if serviceType := dexServer.Spec.Service.Type; serviceType == corev1.ServiceTypeNodePort || serviceType == corev1.ServiceTypeLoadBalancer {
+		// dex is exposed by the Service itself
+		log.V(1).Info("syncIngress skipped", "ServiceType", serviceType)
+		return nil
+	}
+	if isIssuerHandedOver(dexServer) {
+		return r.deleteHandedOverIngress(dexServer, ctx)
+	}
+	if isWaitingForHandover(dexServer) {
+		// the issuer is still served by the replaced DexServer
+		log.Info("syncIngress skipped until the issuer is handed over")
+		return nil
+	}
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	u, _ := url.Parse(issuer)
+	if dexServer.Spec.ExternalIssuer.Enabled {
+		// the global load balancer forwards to the host of this cluster
+		routeHost, err := r.getExternalIssuerRouteHost(dexServer, ctx)
+		if err != nil {
+			return err
+		}
+		u.Host = routeHost
+	}
 	routeHost := u.Host
-	log.Info("syncIngress", "Host", routeHost)
+	log.Info("syncIngress", "Host", routeHost, "Path", getRoutePath(dexServer))
+	if errs := authv1alpha1.ValidateRoute(&dexServer.Spec, field.NewPath("spec", "route")); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	additionalHosts, err := getAdditionalHosts(dexServer, u.Hostname())
+	if err != nil {
+		return err
+	}
+	if err := r.deleteUnusedRoute(dexServer, ctx); err != nil {
+		return errors.Wrap(err, "error deleting the unused route of dex")
+	}
+
+	if r.OpenShift && len(dexServer.Spec.Ingress.TLS.CipherSuites) > 0 {
+		return fmt.Errorf("spec.ingress.tls.cipherSuites is not supported on OpenShift, set the tlsSecurityProfile of the IngressController")
+	}
+	if r.OpenShift && !dexServer.Spec.Route.AllowExternalHost {
+		domain, err := clusterIngressDomain.get(ctx, r.DynamicClient)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the cluster ingress domain")
+		}
+		servedHosts, err := r.getServedHosts(dexServer, ctx)
+		if err != nil {
+			return errors.Wrap(err, "error reading the hosts served by dex")
+		}
+		if domain != "" && !isHostInIngressDomain(routeHost, domain) && !servedHosts[u.Hostname()] {
+			return failures.New(failures.RouteNotAdmitted, "issuer host %s is not in the cluster ingress domain %s, set spec.route.allowExternalHost to allow it", routeHost, domain)
+		}
+		for _, host := range additionalHosts {
+			if domain != "" && !isHostInIngressDomain(host, domain) && !servedHosts[host] {
+				return failures.New(failures.RouteNotAdmitted, "additional host %s is not in the cluster ingress domain %s, set spec.route.allowExternalHost to allow it", host, domain)
+			}
+		}
+	}
+	if dexServer.Spec.Route.ValidateDNS {
+		domain := ""
+		if r.OpenShift {
+			if domain, err = clusterIngressDomain.get(ctx, r.DynamicClient); err != nil {
+				return errors.Wrap(err, "failed to read the cluster ingress domain")
+			}
+		}
+		if err := r.validateIssuerDNS(ctx, append([]string{u.Hostname()}, additionalHosts...), domain); err != nil {
+			return err
+		}
+	}
 
-	ingressCertificateRefName := dexServer.Spec.IngressCertificateRef.Name
+	annotations, err := getIngressAnnotations(dexServer, append([]string{u.Hostname()}, additionalHosts...))
+	if err != nil {
+		return err
+	}
+	rateLimitAnnotations, err := getRateLimitAnnotations(dexServer, r.OpenShift)
+	if err != nil {
+		return err
+	}
+	for key, value := range rateLimitAnnotations {
+		annotations[key] = value
+	}
+	var annotationsYaml []byte
+	if len(annotations) > 0 {
+		annotationsYaml, err = yaml.Marshal(annotations)
+		if err != nil {
+			return err
+		}
+	}
 
 	values := struct {
 		Host                   string
+		Path                   string
+		AdditionalHosts        []string
 		DexServer              *authv1alpha1.DexServer
 		IngressCertificateName string
+		IngressClassName       string
+		AnnotationsYaml        string
+		OpenShift              bool
+		Certificate            string
+		Key                    string
+		AuthProxyPaths         []authProxyPath
 	}{
 		Host:                   routeHost,
+		Path:                   getRoutePath(dexServer),
+		AdditionalHosts:        additionalHosts,
 		DexServer:              dexServer,
-		IngressCertificateName: ingressCertificateRefName,
+		IngressCertificateName: getIngressTLSSecretName(dexServer),
+		IngressClassName:       dexServer.Spec.Ingress.ClassName,
+		AnnotationsYaml:        string(annotationsYaml),
+		OpenShift:              r.OpenShift,
+		AuthProxyPaths:         getAuthProxyPaths(dexServer),
 	}
 
 	files := []string{
 		"dex-server/ingress.yaml",
 	}
+	if isWildcardRoute(dexServer, r.OpenShift) {
+		// an Ingress can't request a wildcard route, the Route is created instead
+		values.Certificate, values.Key, err = r.getRouteCertificate(dexServer, ctx)
+		if err != nil {
+			return err
+		}
+		files = []string{
+			"dex-server/route.yaml",
+		}
+	}
 
 	applier, readerDeploy := r.getApplierAndReader(dexServer)
 	// TODO: ApplyCustomResources is a hack... no support currently for applying a route or ingress and this seems to work
-	_, err := applier.ApplyCustomResources(readerDeploy, values, false, "", files...)
+	err = r.applyWithDiff(dexServer, ctx, applier, applier.ApplyCustomResources, readerDeploy, values, files...)
 
 	if err != nil {
 		return err
@@ -1291,6 +3511,7 @@ func mergeStatusConditions(conditions []metav1.Condition, newConditions ...metav
 
 func updateDexServerStatusConditions(c client.Client, dexServer *authv1alpha1.DexServer, newConditions ...metav1.Condition) error {
 	dexServer.Status.Conditions = mergeStatusConditions(dexServer.Status.Conditions, newConditions...)
+	setHealthStatus(dexServer)
 	return c.Status().Update(context.TODO(), dexServer)
 }
 
@@ -1298,7 +3519,7 @@ func (r *DexServerReconciler) installClusterRole() error {
 	values := struct {
 		ClusterRoleName string
 	}{
-		ClusterRoleName: SERVICE_ACCOUNT_NAME,
+		ClusterRoleName: r.getClusterRoleName(),
 	}
 
 	files := []string{
@@ -1322,9 +3543,16 @@ func (r *DexServerReconciler) installClusterRole() error {
 // SetupWithManager sets up the controller with the Manager.
 func (r *DexServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
-	// Set up the Cluster Role
-	if err := r.installClusterRole(); err != nil {
-		return err
+	// Set up the Cluster Role, unless it is provisioned by an administrator or the operator must not write
+	if !r.PreProvisionedRBAC && !r.Observe {
+		if err := r.ensureClusterRole(nil, context.TODO()); err != nil {
+			var rbacEscalation *rbacEscalationError
+			if !errors.As(err, &rbacEscalation) {
+				return err
+			}
+			// the DexServers report the missing permissions, the ClusterRole is installed again by their reconciles
+			ctrllog.Log.Error(err, "not allowed to install the ClusterRole of dex")
+		}
 	}
 
 	deploymentOwnsOpts := []builder.OwnsOption{
@@ -1338,17 +3566,33 @@ func (r *DexServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			dexServerOld := e.ObjectOld.(*authv1alpha1.DexServer)
 			dexServerNew := e.ObjectNew.(*authv1alpha1.DexServer)
-			// only handle the Finalizer, DeletionStamp and Spec changes
+			// only handle the Finalizer, DeletionStamp, handover, repair, observe and storage migration annotations and
+			// Spec changes
 			return !equality.Semantic.DeepEqual(e.ObjectOld.GetFinalizers(), e.ObjectNew.GetFinalizers()) ||
 				!equality.Semantic.DeepEqual(e.ObjectOld.GetDeletionTimestamp(), e.ObjectNew.GetDeletionTimestamp()) ||
+				e.ObjectOld.GetAnnotations()[HANDED_OVER_ANNOTATION] != e.ObjectNew.GetAnnotations()[HANDED_OVER_ANNOTATION] ||
+				e.ObjectOld.GetAnnotations()[STORAGE_MIGRATION_ANNOTATION] != e.ObjectNew.GetAnnotations()[STORAGE_MIGRATION_ANNOTATION] ||
+				isRepairRequested(dexServerNew) && !isRepairRequested(dexServerOld) ||
+				e.ObjectOld.GetAnnotations()[OBSERVE_ANNOTATION] != e.ObjectNew.GetAnnotations()[OBSERVE_ANNOTATION] ||
 				!equality.Semantic.DeepEqual(dexServerOld.Spec, dexServerNew.Spec)
 
 		},
 	})
 
+	// The DexServers are indexed by the Secrets they reference, to only map the events of these Secrets
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &authv1alpha1.DexServer{}, DEXSERVER_SECRET_INDEX, func(obj client.Object) []string {
+		return getReferencedSecretKeys(obj.(*authv1alpha1.DexServer))
+	}); err != nil {
+		return err
+	}
+
 	// Watch for updates to the secrets containing credentials for IDP connectors (example: Github client secret, LDAP bind password etc)
 	// These secrets are labelled with auth.identitatem.io/idp-credential=""
 	secretPredicate := predicate.Funcs{
+		// A connector secret that was missing may have been created
+		CreateFunc: func(e event.CreateEvent) bool {
+			return len(getDexServersForSecret(mgr.GetClient(), e.Object)) > 0
+		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			if _, ok := e.ObjectNew.GetLabels()[IDP_CREDENTIAL_LABEL]; ok {
 				return true
@@ -1357,6 +3601,16 @@ func (r *DexServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		},
 	}
 
+	// Watch the Services labelled for an oauth2-proxy client, and the removal of their label
+	oauthProxyServicePredicate := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetLabels()[OAUTH_PROXY_LABEL] != "" || e.ObjectNew.GetLabels()[OAUTH_PROXY_LABEL] != ""
+		},
+		CreateFunc:  func(e event.CreateEvent) bool { return e.Object.GetLabels()[OAUTH_PROXY_LABEL] != "" },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return e.Object.GetLabels()[OAUTH_PROXY_LABEL] != "" },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&authv1alpha1.DexServer{}, builder.WithPredicates(dexServerPredicate)).
 		Owns(&corev1.ConfigMap{}).
@@ -1365,24 +3619,23 @@ func (r *DexServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Secret{}).
 		Owns(&appsv1.Deployment{}, deploymentOwnsOpts...).
 		Owns(&networkingv1.Ingress{}).
-		Watches(&source.Kind{Type: &corev1.Secret{}}, // Since the IDP credential secrets are not generated by this controller, updates to them will not trigger the reconcile loop. We need map them to a resource (dexserver) that is managed by this controller.
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&batchv1.Job{}).
+		Owns(&authv1alpha1.DexClient{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, // Since the IDP credential secrets are not generated by this controller, updates to them will not trigger the reconcile loop. We need map them to the DexServers referencing them.
 			handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
-				var dexServerList authv1alpha1.DexServerList
-				_ = mgr.GetClient().List(context.TODO(), &dexServerList)
-
-				var requests = []reconcile.Request{}
-
-				for _, dexServer := range dexServerList.Items {
-					requests = append(requests, reconcile.Request{
-						NamespacedName: types.NamespacedName{
-							Name:      dexServer.Name,
-							Namespace: dexServer.Namespace,
-						},
-					})
-				}
-				return requests // Events from the watched secrets mapped to the DexServer resource
+				return getDexServersForSecret(mgr.GetClient(), a) // Events from the watched secrets mapped to the DexServer resources
 			}),
 			builder.WithPredicates(secretPredicate)). // Predicate to ensure we're only watching secrets that have the label "auth.identitatem.io/idp-credential" on them
+		// The web templates ConfigMaps are created by users, map their updates to the DexServers using them
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
+				return getDexServersForWebTemplates(mgr.GetClient(), a)
+			})).
+		// The Services protected by oauth2-proxy are labelled by their owners, map them to the DexServer of the label
+		Watches(&source.Kind{Type: &corev1.Service{}},
+			handler.EnqueueRequestsFromMapFunc(getDexServerForOAuthProxyService),
+			builder.WithPredicates(oauthProxyServicePredicate)).
 		Complete(r)
 }
 
@@ -3,26 +3,44 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ghodss/yaml"
 	dexoperatorconfig "github.com/identitatem/dex-operator/config"
+	"github.com/identitatem/dex-operator/controllers/dexconfig"
+	"github.com/identitatem/dex-operator/controllers/dexstorage"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
 	clusteradmasset "open-cluster-management.io/clusteradm/pkg/helpers/asset"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -39,6 +57,18 @@ var _ = Describe("Setup Dex", func() {
 
 		_, err = getCRD(readerDex, "crd/bases/auth.identitatem.io_dexservers.yaml")
 		Expect(err).Should(BeNil())
+
+		_, err = getCRD(readerDex, "crd/bases/auth.identitatem.io_dexstoragemigrations.yaml")
+		Expect(err).Should(BeNil())
+
+		_, err = getCRD(readerDex, "crd/bases/auth.identitatem.io_clusterdexservers.yaml")
+		Expect(err).Should(BeNil())
+
+		_, err = getCRD(readerDex, "crd/bases/auth.identitatem.io_dexquickstarts.yaml")
+		Expect(err).Should(BeNil())
+
+		_, err = getCRD(readerDex, "crd/bases/auth.identitatem.io_dexserversets.yaml")
+		Expect(err).Should(BeNil())
 	})
 })
 
@@ -115,7 +145,10 @@ var _ = Describe("Process DexServer CR", func() {
 									Name:      MyGithubAppClientSecretName,
 									Namespace: AuthRealmNameSpace,
 								},
-								LoadAllGroups: true,
+								TeamNameField:        "name",
+								LoadAllGroups:        true,
+								UseLoginAsID:         true,
+								PreferredEmailDomain: "example.com",
 							},
 						},
 					},
@@ -149,11 +182,47 @@ var _ = Describe("Process DexServer CR", func() {
 		Expect(controllerutil.ContainsFinalizer(dexServer, "auth.identitatem.io/cleanup")).To(BeTrue())
 		Expect(err).Should(BeNil())
 	})
+	It("should report that the encryption at rest can't be verified", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+		Expect(err).Should(BeNil())
+		// the test cluster has no OpenShift API server config
+		cond := meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeSecretsEncryptedAtRest)
+		Expect(cond).ToNot(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionUnknown))
+		Expect(cond.Reason).To(Equal("EncryptionUnknown"))
+	})
+	It("should record a warning Event once when etcd is not encrypted at rest", func() {
+		previous := clusterEncryption
+		clusterEncryption = &encryptionCache{found: true, typ: "identity", expires: time.Now().Add(time.Hour)}
+		defer func() { clusterEncryption = previous }()
+		recorder := record.NewFakeRecorder(10)
+		r := rDexServer
+		r.Recorder = recorder
+
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "unencrypted-dexserver", Namespace: DexServerNamespace}}
+		Expect(r.checkEncryptionAtRest(dexServer, context.TODO())).To(Succeed())
+		cond := meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeSecretsEncryptedAtRest)
+		Expect(cond).ToNot(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal("EncryptionDisabled"))
+		Expect(testutil.ToFloat64(unencryptedCredentials.WithLabelValues(DexServerNamespace, "unencrypted-dexserver"))).To(Equal(1.0))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning EncryptionDisabled")))
+		By("not recording it again on the next reconciles", func() {
+			Expect(r.checkEncryptionAtRest(dexServer, context.TODO())).To(Succeed())
+			Expect(recorder.Events).ToNot(Receive())
+		})
+	})
 	It("should create a service account", func() {
 		serviceAccount := &corev1.ServiceAccount{}
 		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: SERVICE_ACCOUNT_NAME, Namespace: DexServerNamespace}, serviceAccount)
 		Expect(err).Should(BeNil())
 		Expect(serviceAccount.Labels["app"]).To(Equal(DexServerName))
+		Expect(serviceAccount.Labels["app.kubernetes.io/managed-by"]).To(Equal("dex-operator"))
+		// the service account is shared by the DexServers of the namespace
+		Expect(serviceAccount.Labels).ToNot(HaveKey("app.kubernetes.io/instance"))
+		Expect(serviceAccount.Annotations).ToNot(HaveKey("auth.identitatem.io/inventoryHash"))
+		Expect(serviceAccount.Annotations).To(HaveKey("auth.identitatem.io/layoutVersion"))
 	})
 	It("should create http service for the dex server (with the default ingress certificate)", func() {
 		const SECRET_WEB_TLS_SUFFIX = "-tls-secret"
@@ -173,6 +242,41 @@ var _ = Describe("Process DexServer CR", func() {
 		Expect(grpcService).ShouldNot(BeNil())
 		Expect(grpcService.Spec.Ports[0].Name).To(Equal("grpc"))
 		Expect(grpcService.Spec.Ports[0].Port).To(Equal(int32(5557)))
+		Expect(grpcService.Labels["app.kubernetes.io/managed-by"]).To(Equal("dex-operator"))
+		Expect(grpcService.Labels).ToNot(HaveKey("app.kubernetes.io/instance"))
+		Expect(grpcService.Annotations).ToNot(HaveKey("auth.identitatem.io/inventoryHash"))
+
+		By("removing the instance metadata set by the previous versions", func() {
+			grpcService.Labels["app.kubernetes.io/instance"] = DexServerName
+			grpcService.Annotations["auth.identitatem.io/inventoryHash"] = "previous"
+			err := k8sClient.Update(context.TODO(), grpcService)
+			Expect(err).Should(BeNil())
+			Eventually(func() (map[string]string, error) {
+				req := ctrl.Request{}
+				req.Name = DexServerName
+				req.Namespace = DexServerNamespace
+				if _, err := rDexServer.Reconcile(context.TODO(), req); err != nil {
+					return nil, err
+				}
+				err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: GRPC_SERVICE_NAME, Namespace: DexServerNamespace}, grpcService)
+				return grpcService.Labels, err
+			}, 30, 1).ShouldNot(HaveKey("app.kubernetes.io/instance"))
+			Expect(grpcService.Annotations).ToNot(HaveKey("auth.identitatem.io/inventoryHash"))
+		})
+	})
+	It("should publish the connection info of the dex server", func() {
+		connectionInfo := &corev1.ConfigMap{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName + "-connection", Namespace: DexServerNamespace}, connectionInfo)
+		Expect(err).Should(BeNil())
+		mtlsSecret := &corev1.Secret{}
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: SECRET_MTLS_NAME, Namespace: DexServerNamespace}, mtlsSecret)
+		Expect(err).Should(BeNil())
+		Expect(connectionInfo.Data["issuer"]).To(Equal(DexServerIssuer))
+		Expect(connectionInfo.Data["grpc-endpoint"]).To(Equal("grpc." + DexServerNamespace + ".svc.cluster.local:5557"))
+		Expect(connectionInfo.Data["grpc-ca.crt"]).To(Equal(string(mtlsSecret.Data["ca.crt"])))
+		Expect(connectionInfo.Data["grpc-client-secret"]).To(Equal(SECRET_MTLS_NAME))
+		Expect(connectionInfo.Data["hash"]).To(Equal(getConnectionInfoHash(connectionInfo.Data)))
+		Expect(connectionInfo.Data["generation"]).To(Equal("1"))
 	})
 	It("should create ingress for the dex server", func() {
 		ingress := &v1beta1.Ingress{}
@@ -205,6 +309,91 @@ var _ = Describe("Process DexServer CR", func() {
 		Expect(err).Should(BeNil())
 		Expect(ingress).ShouldNot(BeNil())
 		Expect(ingress.Spec.TLS[0].SecretName).To(Equal("customcert"))
+		By("requesting the certificate from cert-manager and the DNS record from external-dns", func() {
+			certManagerDexServer := updatedDexServer.DeepCopy()
+			certManagerDexServer.Spec.Ingress = authv1alpha1.IngressSpec{
+				ClassName:   "nginx",
+				Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-buffer-size": "16k"},
+				ExternalDNS: true,
+				TLS: authv1alpha1.IngressTLSSpec{
+					Strategy:  authv1alpha1.IngressTLSCertManager,
+					IssuerRef: authv1alpha1.CertManagerIssuerReference{Name: "letsencrypt", Kind: "ClusterIssuer"},
+				},
+			}
+			Expect(rDexServer.syncIngress(certManagerDexServer, ctx)).To(Succeed())
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, ingress)
+			Expect(err).Should(BeNil())
+			Expect(ingress.Annotations).To(HaveKeyWithValue(CERT_MANAGER_CLUSTER_ISSUER_ANNOTATION, "letsencrypt"))
+			Expect(ingress.Annotations).To(HaveKeyWithValue(EXTERNAL_DNS_HOSTNAME_ANNOTATION, ingress.Spec.Rules[0].Host))
+			Expect(ingress.Annotations).To(HaveKeyWithValue("nginx.ingress.kubernetes.io/proxy-buffer-size", "16k"))
+			Expect(*ingress.Spec.IngressClassName).To(Equal("nginx"))
+			Expect(ingress.Spec.TLS[0].SecretName).To(Equal(DexServerName + INGRESS_TLS_SUFFIX))
+		})
+		By("rejecting the annotations set by the operator", func() {
+			invalidDexServer := updatedDexServer.DeepCopy()
+			invalidDexServer.Spec.Ingress.Annotations = map[string]string{"route.openshift.io/termination": "edge"}
+			Expect(rDexServer.syncIngress(invalidDexServer, ctx)).NotTo(Succeed())
+			invalidDexServer.Spec.Ingress = authv1alpha1.IngressSpec{TLS: authv1alpha1.IngressTLSSpec{Strategy: authv1alpha1.IngressTLSCertManager}}
+			Expect(rDexServer.syncIngress(invalidDexServer, ctx)).NotTo(Succeed())
+			// the cipher suites of the OpenShift router are not set per Route
+			invalidDexServer.Spec.Ingress = authv1alpha1.IngressSpec{TLS: authv1alpha1.IngressTLSSpec{CipherSuites: []string{"ECDHE-RSA-AES128-GCM-SHA256"}}}
+			Expect(rDexServer.syncIngress(invalidDexServer, ctx)).NotTo(Succeed())
+		})
+		By("serving the additional hosts", func() {
+			aliasDexServer := updatedDexServer.DeepCopy()
+			aliasDexServer.Spec.AdditionalHosts = []string{"dex-old.example.com"}
+			Expect(rDexServer.syncIngress(aliasDexServer, ctx)).To(Succeed())
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, ingress)
+			Expect(err).Should(BeNil())
+			Expect(ingress.Spec.Rules).To(HaveLen(2))
+			Expect(ingress.Spec.Rules[1].Host).To(Equal("dex-old.example.com"))
+			Expect(ingress.Spec.TLS[0].Hosts).To(ContainElements(ingress.Spec.Rules[0].Host, "dex-old.example.com"))
+			By("rejecting the hosts repeating the host of the issuer", func() {
+				aliasDexServer.Spec.AdditionalHosts = []string{ingress.Spec.Rules[0].Host}
+				Expect(rDexServer.syncIngress(aliasDexServer, ctx)).NotTo(Succeed())
+			})
+		})
+		By("routing the path of the issuer", func() {
+			pathDexServer := updatedDexServer.DeepCopy()
+			pathDexServer.Spec.Issuer = "https://" + ingress.Spec.Rules[0].Host + "/dex/"
+			Expect(rDexServer.syncIngress(pathDexServer, ctx)).To(Succeed())
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, ingress)
+			Expect(err).Should(BeNil())
+			Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Path).To(Equal("/dex"))
+			By("rejecting a route path that is not the path of the issuer", func() {
+				pathDexServer.Spec.Route.Path = "/sso"
+				Expect(rDexServer.syncIngress(pathDexServer, ctx)).NotTo(Succeed())
+			})
+		})
+		By("rejecting the new hosts outside of the cluster ingress domain", func() {
+			previousIngressDomain := clusterIngressDomain
+			clusterIngressDomain = &ingressDomainCache{domain: "apps.example.com", expires: time.Now().Add(time.Hour)}
+			defer func() { clusterIngressDomain = previousIngressDomain }()
+			// the issuer host served by the Ingress is kept
+			Expect(rDexServer.syncIngress(updatedDexServer, ctx)).To(Succeed())
+
+			externalDexServer := updatedDexServer.DeepCopy()
+			externalDexServer.Spec.AdditionalHosts = []string{"dex-new.example.org"}
+			err := rDexServer.syncIngress(externalDexServer, ctx)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("additional host dex-new.example.org is not in the cluster ingress domain apps.example.com"))
+			externalDexServer.Spec.AdditionalHosts = []string{"dex-new.apps.example.com"}
+			Expect(rDexServer.syncIngress(externalDexServer, ctx)).To(Succeed())
+
+			By("rejecting the issuer host of a DexServer without Ingress", func() {
+				newDexServer := updatedDexServer.DeepCopy()
+				newDexServer.Name = "my-new-external-dexserver"
+				err := rDexServer.syncIngress(newDexServer, ctx)
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("is not in the cluster ingress domain apps.example.com"))
+				newDexServer.Spec.Route.AllowExternalHost = true
+				newDexServer.Spec.AdditionalHosts = nil
+				Expect(rDexServer.syncIngress(newDexServer, ctx)).To(Succeed())
+				err = k8sClient.Delete(ctx, &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: newDexServer.Name, Namespace: DexServerNamespace}})
+				Expect(err).Should(BeNil())
+			})
+		})
+		Expect(rDexServer.syncIngress(updatedDexServer, ctx)).To(Succeed())
 	})
 	It("should create ClusterRoleBinding", func() {
 		crb := &rbacv1.ClusterRoleBinding{}
@@ -215,6 +404,40 @@ var _ = Describe("Process DexServer CR", func() {
 		Expect(len(crb.Subjects)).To(Equal(1))
 		Expect(crb.Subjects[0].Namespace).To(Equal(DexServerNamespace))
 	})
+	It("should only validate the ClusterRole and ClusterRoleBinding provisioned by an administrator", func() {
+		r := rDexServer
+		r.PreProvisionedRBAC = true
+		r.ClusterRoleName = "my-pre-provisioned-dexsso"
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-pre-provisioned-dexserver", Namespace: "my-pre-provisioned-ns"}}
+
+		err := r.validatePreProvisionedRBAC(dexServer, context.TODO())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("ClusterRole my-pre-provisioned-dexsso not found"))
+
+		clusterRole := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "my-pre-provisioned-dexsso"}}
+		Expect(k8sClient.Create(context.TODO(), clusterRole)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), clusterRole)
+		err = r.validatePreProvisionedRBAC(dexServer, context.TODO())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("no ClusterRoleBinding binds ClusterRole my-pre-provisioned-dexsso"))
+
+		By("ignoring the bindings of the service accounts of other namespaces", func() {
+			crb := &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-pre-provisioned-dexsso"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "my-pre-provisioned-dexsso"},
+				Subjects: []rbacv1.Subject{
+					{Kind: rbacv1.ServiceAccountKind, Name: SERVICE_ACCOUNT_NAME, Namespace: "my-other-ns"},
+				},
+			}
+			Expect(k8sClient.Create(context.TODO(), crb)).To(Succeed())
+			Expect(r.validatePreProvisionedRBAC(dexServer, context.TODO())).ToNot(Succeed())
+
+			crb.Subjects = append(crb.Subjects, rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: SERVICE_ACCOUNT_NAME, Namespace: "my-pre-provisioned-ns"})
+			Expect(k8sClient.Update(context.TODO(), crb)).To(Succeed())
+			Expect(r.validatePreProvisionedRBAC(dexServer, context.TODO())).To(Succeed())
+			Expect(k8sClient.Delete(context.TODO(), crb)).To(Succeed())
+		})
+	})
 	It("should create ConfigMap for dex", func() {
 		dexConfigMap := &corev1.ConfigMap{}
 		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexConfigMap)
@@ -230,9 +453,138 @@ var _ = Describe("Process DexServer CR", func() {
 		connectors := configMapData["connectors"].([]interface{})
 		connector := connectors[0].(map[string]interface{})
 		Expect(connector["Type"]).To(Equal("github"))
+		Expect(connector["Name"]).To(Equal("my-github"))
 		connectorConfig := connector["Config"].(map[string]interface{})
 		Expect(connectorConfig["ClientID"]).To(Equal(MyGithubAppClientID))
 		Expect(connectorConfig["LoadAllGroups"]).To(Equal(true))
+		Expect(connectorConfig["TeamNameField"]).To(Equal("name"))
+		Expect(connectorConfig["UseLoginAsID"]).To(Equal(true))
+		Expect(connectorConfig["PreferredEmailDomain"]).To(Equal("example.com"))
+		// The login page keeps the dex branding
+		Expect(configMapData).ShouldNot(HaveKey("frontend"))
+		// dex listens on every IP family
+		web := configMapData["web"].(map[string]interface{})
+		Expect(web["https"]).To(Equal(":5556"))
+		// The metrics are only served when the telemetry is enabled
+		Expect(configMapData).ShouldNot(HaveKey("telemetry"))
+		// Verify the default oauth2 configuration
+		oauth2 := configMapData["oauth2"].(map[string]interface{})
+		Expect(oauth2["skipApprovalScreen"]).To(Equal(true))
+		Expect(oauth2["alwaysShowLoginScreen"]).To(Equal(false))
+		Expect(oauth2).ShouldNot(HaveKey("passwordConnector"))
+	})
+	It("should configure the password grant and the login screens of dex", func() {
+		namespace := "my-oauth2-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "oauth2-ldap-bindpw", Namespace: namespace},
+			StringData: map[string]string{"bindPW": "BogusPassword"},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		skipApprovalScreen := false
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-oauth2-dexserver", Namespace: namespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://oauth2.testhost.com",
+				Connectors: []authv1alpha1.ConnectorSpec{{
+					Type: authv1alpha1.ConnectorTypeLDAP,
+					Id:   "ldap",
+					Name: "LDAP",
+					LDAP: authv1alpha1.LDAPConfigSpec{
+						Host:      "ldap.testhost.com:636",
+						BindPWRef: corev1.SecretReference{Name: "oauth2-ldap-bindpw", Namespace: namespace},
+					},
+				}},
+				OAuth2: authv1alpha1.OAuth2Spec{
+					PasswordConnector:     "ldap",
+					SkipApprovalScreen:    &skipApprovalScreen,
+					AlwaysShowLoginScreen: true,
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		// the ConfigMap is also managed by the reconcile of the manager
+		Eventually(func() error {
+			if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), dexServer); err != nil {
+				return err
+			}
+			return rDexServer.syncConfigMap(dexServer, context.TODO())
+		}, 10, 1).Should(Succeed())
+		dexConfigMap := &corev1.ConfigMap{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: "my-oauth2-dexserver", Namespace: namespace}, dexConfigMap)
+		Expect(err).Should(BeNil())
+		var configMapData map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(dexConfigMap.Data["config.yaml"]), &configMapData)).To(Succeed())
+		oauth2 := configMapData["oauth2"].(map[string]interface{})
+		Expect(oauth2["passwordConnector"]).To(Equal("ldap"))
+		Expect(oauth2["skipApprovalScreen"]).To(Equal(false))
+		Expect(oauth2["alwaysShowLoginScreen"]).To(Equal(true))
+
+		By("refusing a password connector that is not configured", func() {
+			Eventually(func() string {
+				if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), dexServer); err != nil {
+					return err.Error()
+				}
+				dexServer.Spec.OAuth2.PasswordConnector = "unknown"
+				if err := rDexServer.syncConfigMap(dexServer, context.TODO()); err != nil {
+					return err.Error()
+				}
+				return ""
+			}, 10, 1).Should(ContainSubstring(`password connector "unknown" does not match`))
+		})
+	})
+	It("should render the token lifetimes and the refresh token policy", func() {
+		By("leaving the expiry to the defaults of dex", func() {
+			Expect(getDexExpiry(authv1alpha1.ExpirySpec{})).To(BeNil())
+		})
+		By("rendering the refresh token policy alone", func() {
+			expiry := authv1alpha1.ExpirySpec{RefreshTokens: authv1alpha1.RefreshTokensSpec{DisableRotation: true}}
+			Expect(getDexExpiry(expiry)).To(Equal(&DexExpirySpec{RefreshTokens: &DexRefreshTokensSpec{DisableRotation: true}}))
+		})
+
+		namespace := "my-expiry-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-expiry-dexserver", Namespace: namespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://expiry.testhost.com",
+				Expiry: authv1alpha1.ExpirySpec{
+					IDTokens:     &metav1.Duration{Duration: time.Hour},
+					SigningKeys:  &metav1.Duration{Duration: 12 * time.Hour},
+					AuthRequests: &metav1.Duration{Duration: 10 * time.Minute},
+					RefreshTokens: authv1alpha1.RefreshTokensSpec{
+						ValidIfNotUsedFor: &metav1.Duration{Duration: 7 * 24 * time.Hour},
+						AbsoluteLifetime:  &metav1.Duration{Duration: 30 * 24 * time.Hour},
+						ReuseInterval:     &metav1.Duration{Duration: 5 * time.Second},
+						DisableRotation:   true,
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		// the ConfigMap is also managed by the reconcile of the manager
+		Eventually(func() error {
+			if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), dexServer); err != nil {
+				return err
+			}
+			return rDexServer.syncConfigMap(dexServer, context.TODO())
+		}, 10, 1).Should(Succeed())
+		dexConfigMap := &corev1.ConfigMap{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: "my-expiry-dexserver", Namespace: namespace}, dexConfigMap)
+		Expect(err).Should(BeNil())
+		var configMapData map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(dexConfigMap.Data["config.yaml"]), &configMapData)).To(Succeed())
+		Expect(configMapData["expiry"]).To(Equal(map[string]interface{}{
+			"idTokens":     "1h0m0s",
+			"signingKeys":  "12h0m0s",
+			"authRequests": "10m0s",
+			"refreshTokens": map[string]interface{}{
+				"validIfNotUsedFor": "168h0m0s",
+				"absoluteLifetime":  "720h0m0s",
+				"reuseInterval":     "5s",
+				"disableRotation":   true,
+			},
+		}))
 	})
 	It("should provide client secret as an environment variable in the ConfigMap for dex", func() {
 		dexConfigMap := &corev1.ConfigMap{}
@@ -260,6 +612,27 @@ var _ = Describe("Process DexServer CR", func() {
 		By("using the dex image for the deployment", func() {
 			Expect(dsDeployment.Spec.Template.Spec.Containers[0].Image).To(Equal("dex_image"))
 		})
+		By("defaulting the seccomp profile to RuntimeDefault", func() {
+			Expect(dsDeployment.Spec.Template.Spec.SecurityContext.SeccompProfile).ToNot(BeNil())
+			Expect(dsDeployment.Spec.Template.Spec.SecurityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeRuntimeDefault))
+			Expect(dsDeployment.Spec.Template.ObjectMeta.Annotations["seccomp.security.alpha.kubernetes.io/pod"]).To(Equal("runtime/default"))
+		})
+		By("mounting a read-only root filesystem with a writable /tmp", func() {
+			container := dsDeployment.Spec.Template.Spec.Containers[0]
+			Expect(container.SecurityContext.ReadOnlyRootFilesystem).ToNot(BeNil())
+			Expect(*container.SecurityContext.ReadOnlyRootFilesystem).To(BeTrue())
+			Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "scratch-tmp", MountPath: "/tmp"}))
+			Expect(dsDeployment.Spec.Template.Spec.Volumes).To(ContainElement(corev1.Volume{
+				Name:         "scratch-tmp",
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}))
+		})
+		By("draining the dex pods before they are stopped", func() {
+			container := dsDeployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Lifecycle).ToNot(BeNil())
+			Expect(container.Lifecycle.PreStop.Exec.Command).To(Equal([]string{"/bin/sleep", "10"}))
+			Expect(*dsDeployment.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(40)))
+		})
 		By("setting the configHash in the deployment", func() {
 			// Get ConfigMap
 			dexConfigMap := &corev1.ConfigMap{}
@@ -282,6 +655,85 @@ var _ = Describe("Process DexServer CR", func() {
 			currentTime := time.Now()
 			Expect(t.After(currentTime)).To(BeTrue())
 		})
+		By("keeping the current replica available during rollouts", func() {
+			Expect(dsDeployment.Spec.Strategy.Type).To(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+			Expect(dsDeployment.Spec.Strategy.RollingUpdate.MaxUnavailable.IntValue()).To(Equal(0))
+		})
+	})
+	It("should run dex in the host network on the configured ports", func() {
+		hostNetworkNamespace := "my-host-network-ns"
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: hostNetworkNamespace}})
+		Expect(err).Should(BeNil())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-host-network-dexserver", Namespace: hostNetworkNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:      "https://my-host-network-dexserver.testhost.com",
+				HostNetwork: true,
+				Ports:       authv1alpha1.PortsSpec{HTTPS: 8556, GRPC: 8557},
+			},
+		}
+		err = k8sClient.Create(context.TODO(), dexServer)
+		Expect(err).Should(BeNil())
+
+		// the objects are created by the reconcile of the manager
+		deployment := &appsv1.Deployment{}
+		Eventually(func() error {
+			return k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), deployment)
+		}, 30, 1).Should(Succeed())
+		By("binding the ports on the node", func() {
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.HostNetwork).To(BeTrue())
+			Expect(podSpec.DNSPolicy).To(Equal(corev1.DNSClusterFirstWithHostNet))
+			Expect(podSpec.Containers[0].Ports).To(ContainElements(
+				corev1.ContainerPort{Name: "https", ContainerPort: 8556, HostPort: 8556, Protocol: corev1.ProtocolTCP},
+				corev1.ContainerPort{Name: "grpc", ContainerPort: 8557, HostPort: 8557, Protocol: corev1.ProtocolTCP},
+			))
+			Expect(podSpec.Containers[0].ReadinessProbe.HTTPGet.Port.IntVal).To(Equal(int32(8556)))
+		})
+		By("replacing the replicas in place", func() {
+			rollingUpdate := deployment.Spec.Strategy.RollingUpdate
+			Expect(rollingUpdate.MaxSurge.IntValue()).To(Equal(0))
+			Expect(rollingUpdate.MaxUnavailable.IntValue()).To(Equal(1))
+		})
+		By("targeting the ports of dex from the Services", func() {
+			service := &corev1.Service{}
+			Eventually(func() error {
+				return k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), service)
+			}, 30, 1).Should(Succeed())
+			Expect(service.Spec.Ports[0].TargetPort.IntVal).To(Equal(int32(8556)))
+			grpcService := &corev1.Service{}
+			Eventually(func() error {
+				return k8sClient.Get(context.TODO(), client.ObjectKey{Name: GRPC_SERVICE_NAME, Namespace: hostNetworkNamespace}, grpcService)
+			}, 30, 1).Should(Succeed())
+			Expect(grpcService.Spec.Ports[0].TargetPort.IntVal).To(Equal(int32(8557)))
+		})
+	})
+	It("should make the CRDs discoverable with kubectl", func() {
+		for _, test := range []struct {
+			name      string
+			shortName string
+		}{
+			{name: "dexservers.auth.identitatem.io", shortName: "dexsrv"},
+			{name: "dexclients.auth.identitatem.io", shortName: "dexcl"},
+		} {
+			crd, err := rDexServer.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), test.name, metav1.GetOptions{})
+			Expect(err).To(BeNil())
+			Expect(crd.Spec.Names.ShortNames).To(Equal([]string{test.shortName}), test.name)
+			Expect(crd.Spec.Names.Categories).To(ConsistOf("auth", "all"), test.name)
+			columns := []string{}
+			for _, column := range crd.Spec.Versions[0].AdditionalPrinterColumns {
+				columns = append(columns, column.Name)
+			}
+			Expect(columns).To(ContainElements("Applied", "Age"), test.name)
+		}
+		By("selecting the objects of a dex server by its instance label", func() {
+			deployments := &appsv1.DeploymentList{}
+			Eventually(func() ([]appsv1.Deployment, error) {
+				err := k8sClient.List(context.TODO(), deployments, client.InNamespace(DexServerNamespace), client.MatchingLabels{INSTANCE_LABEL: DexServerName})
+				return deployments.Items, err
+			}, 30, 1).Should(HaveLen(1))
+			Expect(deployments.Items[0].Name).To(Equal(DexServerName))
+		})
 	})
 	It("should process an updated DexServer CR with LDAP", func() {
 		dexServer := &authv1alpha1.DexServer{}
@@ -537,6 +989,1209 @@ var _ = Describe("Process DexServer CR", func() {
 			Expect(dsDeployment.Spec.Template.ObjectMeta.Annotations["auth.identitatem.io/configHash"]).ToNot(Equal(configHashWithGitHub))
 		})
 	})
+	It("should serve the login page templates of a ConfigMap", func() {
+		reconcileDexServer := func() {
+			Eventually(func() bool {
+				req := ctrl.Request{}
+				req.Name = DexServerName
+				req.Namespace = DexServerNamespace
+				_, err := rDexServer.Reconcile(context.TODO(), req)
+				return err == nil
+			}, 10, 1).Should(BeTrue())
+		}
+		templates := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-web-templates",
+				Namespace: DexServerNamespace,
+			},
+			Data: map[string]string{
+				"login.html": "<h2>Connexion</h2>",
+			},
+		}
+		By("referencing a templates ConfigMap in the DexServer", func() {
+			Expect(k8sClient.Create(context.TODO(), templates)).To(Succeed())
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			dexServer.Spec.Web.TemplatesConfigMapRef = &corev1.LocalObjectReference{Name: templates.Name}
+			Expect(k8sClient.Update(context.TODO(), dexServer)).To(Succeed())
+			reconcileDexServer()
+		})
+		By("setting the web directory in the dex config", func() {
+			dexConfigMap := &corev1.ConfigMap{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexConfigMap)
+			Expect(err).Should(BeNil())
+			var configMapData map[string]interface{}
+			err = yaml.Unmarshal([]byte(dexConfigMap.Data["config.yaml"]), &configMapData)
+			Expect(err).Should(BeNil())
+			Expect(configMapData["frontend"]).To(HaveKeyWithValue("dir", DEX_WEB_DIR))
+		})
+		var webTemplatesHash string
+		By("copying the templates in the web directory before dex starts", func() {
+			dsDeployment := &appsv1.Deployment{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dsDeployment)
+			Expect(err).Should(BeNil())
+			Expect(dsDeployment.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+			Expect(dsDeployment.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "web", MountPath: DEX_WEB_DIR, ReadOnly: true}))
+			webTemplatesHash = dsDeployment.Spec.Template.ObjectMeta.Annotations["auth.identitatem.io/webTemplatesHash"]
+			Expect(webTemplatesHash).ToNot(BeEmpty())
+		})
+		By("restarting dex when the templates change", func() {
+			templates.Data["login.html"] = "<h2>Anmeldung</h2>"
+			Expect(k8sClient.Update(context.TODO(), templates)).To(Succeed())
+			reconcileDexServer()
+			dsDeployment := &appsv1.Deployment{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dsDeployment)
+			Expect(err).Should(BeNil())
+			Expect(dsDeployment.Spec.Template.ObjectMeta.Annotations["auth.identitatem.io/webTemplatesHash"]).ToNot(Equal(webTemplatesHash))
+		})
+	})
+	It("should bind cluster roles to the groups of the connectors", func() {
+		updateGroupBindings := func(groupBindings authv1alpha1.GroupBindingsSpec) error {
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			dexServer.Spec.GroupBindings = groupBindings
+			Expect(k8sClient.Update(context.TODO(), dexServer)).To(Succeed())
+			req := ctrl.Request{}
+			req.Name = DexServerName
+			req.Namespace = DexServerNamespace
+			_, err = rDexServer.Reconcile(context.TODO(), req)
+			return err
+		}
+		groupBindingLabels := client.MatchingLabels{
+			"dexconfig_name":      DexServerName,
+			"dexconfig_namespace": DexServerNamespace,
+		}
+		By("suggesting the ClusterRoleBindings in a ConfigMap", func() {
+			err := updateGroupBindings(authv1alpha1.GroupBindingsSpec{
+				GroupsPrefix: "dex:",
+				Bindings:     []authv1alpha1.GroupBinding{{Connector: "my-github", Group: "my-org:admins", ClusterRole: "view"}},
+			})
+			Expect(err).Should(BeNil())
+			configMap := &corev1.ConfigMap{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName + GROUP_BINDINGS_SUFFIX, Namespace: DexServerNamespace}, configMap)
+			Expect(err).Should(BeNil())
+			Expect(configMap.Data[GROUP_BINDINGS_KEY]).To(ContainSubstring("name: dex:my-org:admins"))
+			crbs := &rbacv1.ClusterRoleBindingList{}
+			Expect(k8sClient.List(context.TODO(), crbs, groupBindingLabels)).To(Succeed())
+			Expect(crbs.Items).To(BeEmpty())
+		})
+		By("creating the ClusterRoleBindings", func() {
+			err := updateGroupBindings(authv1alpha1.GroupBindingsSpec{
+				Create:       true,
+				GroupsPrefix: "dex:",
+				Bindings:     []authv1alpha1.GroupBinding{{Connector: "my-github", Group: "my-org:admins", ClusterRole: "view"}},
+			})
+			Expect(err).Should(BeNil())
+			crbs := &rbacv1.ClusterRoleBindingList{}
+			Expect(k8sClient.List(context.TODO(), crbs, groupBindingLabels)).To(Succeed())
+			Expect(crbs.Items).To(HaveLen(1))
+			Expect(crbs.Items[0].RoleRef.Name).To(Equal("view"))
+			Expect(crbs.Items[0].Subjects).To(Equal([]rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "dex:my-org:admins"}}))
+		})
+		By("rejecting the groups of a connector without groups", func() {
+			err := updateGroupBindings(authv1alpha1.GroupBindingsSpec{
+				Create:   true,
+				Bindings: []authv1alpha1.GroupBinding{{Connector: "my-oidc", Group: "admins", ClusterRole: "view"}},
+			})
+			Expect(err).ShouldNot(BeNil())
+			dexServer := &authv1alpha1.DexServer{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			cond := meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeApplied)
+			Expect(cond).ToNot(BeNil())
+			Expect(cond.Reason).To(Equal("ConfigGroupBindingsFailed"))
+		})
+		By("deleting the ClusterRoleBindings removed from the spec", func() {
+			Expect(updateGroupBindings(authv1alpha1.GroupBindingsSpec{})).To(Succeed())
+			crbs := &rbacv1.ClusterRoleBindingList{}
+			Expect(k8sClient.List(context.TODO(), crbs, groupBindingLabels)).To(Succeed())
+			Expect(crbs.Items).To(BeEmpty())
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName + GROUP_BINDINGS_SUFFIX, Namespace: DexServerNamespace}, &corev1.ConfigMap{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+	It("should report the expiry of the credentials", func() {
+		Eventually(func() bool {
+			req := ctrl.Request{}
+			req.Name = DexServerName
+			req.Namespace = DexServerNamespace
+			_, err := rDexServer.Reconcile(context.TODO(), req)
+			return err == nil
+		}, 10, 1).Should(BeTrue())
+		dexServer := &authv1alpha1.DexServer{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+		Expect(err).Should(BeNil())
+		names := []string{}
+		for _, status := range dexServer.Status.CredentialExpiry {
+			names = append(names, status.Name)
+			if status.SecretName == SECRET_MTLS_NAME {
+				Expect(status.Renewed).To(BeTrue())
+				Expect(status.NotAfter.Time).To(BeTemporally(">", time.Now()))
+			}
+		}
+		Expect(names).To(ContainElements("grpc-ca", "grpc-client", "grpc-server"))
+		By("leaving out the root CAs that can't be parsed", func() {
+			Expect(names).ToNot(ContainElement("connector/my-ldap"))
+		})
+	})
+	It("should generate the issuer from the cluster ingress domain", func() {
+		previousIngressDomain := clusterIngressDomain
+		clusterIngressDomain = &ingressDomainCache{domain: "apps.example.com", expires: time.Now().Add(time.Hour)}
+		defer func() { clusterIngressDomain = previousIngressDomain }()
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-dex", Namespace: "my-ns"}}
+		issuer, err := rDexServer.getIssuer(dexServer, context.TODO())
+		Expect(err).Should(BeNil())
+		Expect(issuer).To(Equal("https://my-dex-my-ns.apps.example.com"))
+		By("keeping spec.issuer", func() {
+			dexServer := dexServer.DeepCopy()
+			dexServer.Spec.Issuer = "https://dex.example.com"
+			issuer, err := rDexServer.getIssuer(dexServer, context.TODO())
+			Expect(err).Should(BeNil())
+			Expect(issuer).To(Equal("https://dex.example.com"))
+		})
+		By("not generating the issuer of a load balancer", func() {
+			dexServer := dexServer.DeepCopy()
+			dexServer.Spec.Service.Type = corev1.ServiceTypeLoadBalancer
+			issuer, err := rDexServer.getIssuer(dexServer, context.TODO())
+			Expect(err).Should(BeNil())
+			Expect(issuer).To(BeEmpty())
+		})
+		By("rejecting the hosts that are not valid DNS names", func() {
+			dexServer := dexServer.DeepCopy()
+			dexServer.Name = strings.Repeat("a", 60)
+			_, err := rDexServer.getIssuer(dexServer, context.TODO())
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+	It("should regenerate the serving certificate when the node address of the issuer changes", func() {
+		nodePortNamespace := "my-nodeport-ns"
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nodePortNamespace}})
+		Expect(err).Should(BeNil())
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "my-nodeport-node"}}
+		err = k8sClient.Create(context.TODO(), node)
+		Expect(err).Should(BeNil())
+		defer func() {
+			Expect(k8sClient.Delete(context.TODO(), node)).To(Succeed())
+		}()
+		setNodeAddress := func(address string) {
+			node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeExternalIP, Address: address}}
+			Expect(k8sClient.Status().Update(context.TODO(), node)).To(Succeed())
+		}
+		setNodeAddress("10.0.0.1")
+
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-nodeport-dexserver", Namespace: nodePortNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Service: authv1alpha1.ServiceSpec{Type: corev1.ServiceTypeNodePort, IssuerFromNodeAddress: true},
+			},
+		}
+		err = k8sClient.Create(context.TODO(), dexServer)
+		Expect(err).Should(BeNil())
+		getServingCertificate := func() *x509.Certificate {
+			secret := &corev1.Secret{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: dexServer.Name + SECRET_WEB_TLS_SUFFIX, Namespace: nodePortNamespace}, secret)
+			Expect(err).Should(BeNil())
+			block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+			Expect(block).ToNot(BeNil())
+			cert, err := x509.ParseCertificate(block.Bytes)
+			Expect(err).Should(BeNil())
+			return cert
+		}
+		// the node port of the web Service is allocated once the Service is created by the reconcile of the manager
+		Eventually(func() error {
+			return rDexServer.syncServingCertSecret(dexServer, context.TODO())
+		}, 30, 1).Should(Succeed())
+		cert := getServingCertificate()
+		Expect(cert.IPAddresses).To(HaveLen(1))
+		Expect(cert.IPAddresses[0].String()).To(Equal("10.0.0.1"))
+
+		By("keeping the certificate while the hosts of dex are the same", func() {
+			Expect(rDexServer.syncServingCertSecret(dexServer, context.TODO())).To(Succeed())
+			Expect(getServingCertificate().SerialNumber).To(Equal(cert.SerialNumber))
+		})
+		By("removing the previous address of the node from the certificate", func() {
+			setNodeAddress("10.0.0.2")
+			Expect(rDexServer.syncServingCertSecret(dexServer, context.TODO())).To(Succeed())
+			cert := getServingCertificate()
+			Expect(cert.IPAddresses).To(HaveLen(1))
+			Expect(cert.IPAddresses[0].String()).To(Equal("10.0.0.2"))
+		})
+	})
+	It("should trust both CAs of the mtls secret for the overlap window of a rotation", func() {
+		rotationNamespace := "my-mtls-rotation-ns"
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: rotationNamespace}})
+		Expect(err).Should(BeNil())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-rotated-dexserver", Namespace: rotationNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://my-rotated-dexserver.testhost.com",
+				MTLS:   authv1alpha1.MTLSSpec{CAOverlapWindow: &metav1.Duration{Duration: 2 * time.Hour}},
+			},
+		}
+		err = k8sClient.Create(context.TODO(), dexServer)
+		Expect(err).Should(BeNil())
+		getCAs := func(secret *corev1.Secret) []string {
+			cas := []string{}
+			for rest := secret.Data["ca.crt"]; ; {
+				var block *pem.Block
+				if block, rest = pem.Decode(rest); block == nil {
+					return cas
+				}
+				cas = append(cas, string(pem.EncodeToMemory(block)))
+			}
+		}
+		secretKey := client.ObjectKey{Name: SECRET_MTLS_NAME, Namespace: rotationNamespace}
+		secret := &corev1.Secret{}
+		// the secret is also managed by the reconcile of the manager
+		Eventually(func() error {
+			if err := rDexServer.manageMTLSSecret(dexServer, context.TODO()); err != nil {
+				return err
+			}
+			return k8sClient.Get(context.TODO(), secretKey, secret)
+		}, 10, 1).Should(Succeed())
+		Expect(getCAs(secret)).To(HaveLen(1))
+		previousCA := getCAs(secret)[0]
+
+		By("trusting the new and the previous CA once the certificates are renewed", func() {
+			Eventually(func() ([]string, error) {
+				if err := k8sClient.Get(context.TODO(), secretKey, secret); err != nil {
+					return nil, err
+				}
+				if cas := getCAs(secret); len(cas) == 2 {
+					return cas, nil
+				}
+				secret.Annotations[MTLS_CERT_EXPIRY_ANNOTATION] = time.Now().UTC().Format(time.RFC3339)
+				if err := k8sClient.Update(context.TODO(), secret); err != nil {
+					return nil, err
+				}
+				return nil, rDexServer.manageMTLSSecret(dexServer, context.TODO())
+			}, 10, 1).Should(HaveLen(2))
+			// the new CA signs the certificates, the previous one is only trusted
+			Expect(getCAs(secret)[0]).ToNot(Equal(previousCA))
+			Expect(getCAs(secret)[1]).To(Equal(previousCA))
+			overlapExpiry, err := time.Parse(time.RFC3339, secret.Annotations[MTLS_CA_OVERLAP_EXPIRY_ANNOTATION])
+			Expect(err).Should(BeNil())
+			Expect(overlapExpiry).To(BeTemporally("~", time.Now().Add(2*time.Hour), time.Minute))
+		})
+		By("keeping the previous CA during the overlap window", func() {
+			Expect(rDexServer.manageMTLSSecret(dexServer, context.TODO())).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), secretKey, secret)).To(Succeed())
+			Expect(getCAs(secret)).To(ContainElement(previousCA))
+		})
+		By("removing the previous CA once the overlap window has passed", func() {
+			Eventually(func() ([]string, error) {
+				if err := k8sClient.Get(context.TODO(), secretKey, secret); err != nil {
+					return nil, err
+				}
+				if _, ok := secret.Annotations[MTLS_CA_OVERLAP_EXPIRY_ANNOTATION]; !ok {
+					return getCAs(secret), nil
+				}
+				secret.Annotations[MTLS_CA_OVERLAP_EXPIRY_ANNOTATION] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+				if err := k8sClient.Update(context.TODO(), secret); err != nil {
+					return nil, err
+				}
+				return nil, rDexServer.manageMTLSSecret(dexServer, context.TODO())
+			}, 10, 1).Should(And(HaveLen(1), Not(ContainElement(previousCA))))
+		})
+	})
+	It("should only generate FIPS compliant certificates in FIPS mode", func() {
+		for _, fips := range []bool{false, true} {
+			mtlsCerts, err := generateMTLSCerts("my-fips-ns", fips, nil)
+			Expect(err).Should(BeNil())
+			for _, bundle := range []*bytes.Buffer{mtlsCerts.caPEM, mtlsCerts.certPEM, mtlsCerts.clientPEM} {
+				Expect(isFIPSCompliantCertificate(bundle.Bytes())).To(Equal(fips))
+			}
+			certPEM, _, _, err := generateServingCert([]string{"my-fips-dexserver.testhost.com"}, fips, nil)
+			Expect(err).Should(BeNil())
+			Expect(isFIPSCompliantCertificate(certPEM.Bytes())).To(Equal(fips))
+		}
+		By("accepting the ECDSA keys on the approved curves", func() {
+			for _, test := range []struct {
+				curve     elliptic.Curve
+				compliant bool
+			}{
+				{curve: elliptic.P224(), compliant: false},
+				{curve: elliptic.P256(), compliant: true},
+				{curve: elliptic.P384(), compliant: true},
+			} {
+				key, err := ecdsa.GenerateKey(test.curve, rand.Reader)
+				Expect(err).Should(BeNil())
+				template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+				der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+				Expect(err).Should(BeNil())
+				bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+				Expect(isFIPSCompliantCertificate(bundle)).To(Equal(test.compliant), test.curve.Params().Name)
+			}
+			Expect(isFIPSCompliantCertificate([]byte("not a certificate"))).To(BeFalse())
+		})
+
+		fipsNamespace := "my-fips-ns"
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fipsNamespace}})
+		Expect(err).Should(BeNil())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-fips-dexserver", Namespace: fipsNamespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://my-fips-dexserver.testhost.com"},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		r := rDexServer
+		r.FIPS = true
+		By("regenerating the mtls certificates generated without FIPS mode", func() {
+			secret := &corev1.Secret{}
+			// the secret is created by the reconcile of the manager
+			Eventually(func() error {
+				return k8sClient.Get(context.TODO(), client.ObjectKey{Name: SECRET_MTLS_NAME, Namespace: fipsNamespace}, secret)
+			}, 30, 1).Should(Succeed())
+			Expect(isFIPSCompliantCertificate(secret.Data["tls.crt"])).To(BeFalse())
+
+			Expect(r.manageMTLSSecret(dexServer, context.TODO())).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+			Expect(isFIPSCompliantCertificate(secret.Data["tls.crt"])).To(BeTrue())
+			Expect(isFIPSCompliantCertificate(secret.Data["ca.crt"])).To(BeTrue())
+		})
+		By("running dex in FIPS mode", func() {
+			deployment := &appsv1.Deployment{}
+			// the Deployment is also synced by the reconcile of the manager, without FIPS mode
+			Eventually(func() ([]corev1.EnvVar, error) {
+				if err := r.syncDeployment(dexServer, context.TODO()); err != nil {
+					return nil, err
+				}
+				if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), deployment); err != nil {
+					return nil, err
+				}
+				return deployment.Spec.Template.Spec.Containers[0].Env, nil
+			}, 30, 1).Should(ContainElements(
+				corev1.EnvVar{Name: "GOLANG_FIPS", Value: "1"},
+				corev1.EnvVar{Name: "GODEBUG", Value: "fips140=on"},
+				corev1.EnvVar{Name: "OPENSSL_FORCE_FIPS_MODE", Value: "1"},
+			))
+		})
+	})
+	It("should serve plain HTTP behind a load balancer terminating TLS", func() {
+		lbNamespace := "my-lb-tls-ns"
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: lbNamespace}})
+		Expect(err).Should(BeNil())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-lb-tls-dexserver", Namespace: lbNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://my-lb-tls-dexserver.testhost.com",
+				Service: authv1alpha1.ServiceSpec{
+					Type:           corev1.ServiceTypeLoadBalancer,
+					TLSTermination: authv1alpha1.TLSTerminationLoadBalancer,
+					Annotations:    map[string]string{"service.beta.kubernetes.io/aws-load-balancer-ssl-cert": "arn:aws:acm:us-east-1:123456789012:certificate/my-cert"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+
+		// the objects are created by the reconcile of the manager
+		By("listening on the HTTPS port of the issuer with the managed certificate", func() {
+			service := &corev1.Service{}
+			Eventually(func() error {
+				return k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), service)
+			}, 30, 1).Should(Succeed())
+			Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(443)))
+			Expect(service.Annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-ssl-cert", "arn:aws:acm:us-east-1:123456789012:certificate/my-cert"))
+		})
+		By("serving plain HTTP from dex", func() {
+			configMap := &corev1.ConfigMap{}
+			Eventually(func() error {
+				return k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), configMap)
+			}, 30, 1).Should(Succeed())
+			config, err := dexconfig.Load([]byte(configMap.Data["config.yaml"]))
+			Expect(err).Should(BeNil())
+			Expect(config.Web.HTTP).ToNot(BeEmpty())
+			Expect(config.Web.HTTPS).To(BeEmpty())
+			Expect(config.Web.TLSCert).To(BeEmpty())
+
+			deployment := &appsv1.Deployment{}
+			Eventually(func() error {
+				return k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), deployment)
+			}, 30, 1).Should(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].LivenessProbe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTP))
+			Expect(deployment.Spec.Template.Spec.Containers[0].ReadinessProbe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTP))
+		})
+		By("refusing the settings the load balancer can't be configured with", func() {
+			other := dexServer.DeepCopy()
+			other.Spec.Service.Type = corev1.ServiceTypeClusterIP
+			Expect(validateServiceTLSTermination(other)).To(MatchError("spec.service.tlsTermination LoadBalancer requires spec.service.type LoadBalancer"))
+			other = dexServer.DeepCopy()
+			other.Spec.Service.Annotations["service.beta.kubernetes.io/aws-load-balancer-proxy-protocol"] = "*"
+			Expect(validateServiceTLSTermination(other)).NotTo(Succeed())
+			Expect(validateServiceTLSTermination(dexServer)).To(Succeed())
+		})
+	})
+	It("should let the registered mutators adjust the objects", func() {
+		RegisterResourceMutator("test", ResourceMutatorFunc(func(ctx context.Context, dexServer *authv1alpha1.DexServer, obj *unstructured.Unstructured) error {
+			if dexServer.Name != DexServerName || obj.GetKind() != "Deployment" {
+				return nil
+			}
+			labels := obj.GetLabels()
+			labels["example.com/distribution"] = "test"
+			obj.SetLabels(labels)
+			return nil
+		}))
+		Eventually(func() bool {
+			req := ctrl.Request{}
+			req.Name = DexServerName
+			req.Namespace = DexServerNamespace
+			_, err := rDexServer.Reconcile(context.TODO(), req)
+			return err == nil
+		}, 10, 1).Should(BeTrue())
+		deployment := &appsv1.Deployment{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, deployment)
+		Expect(err).Should(BeNil())
+		Expect(deployment.Labels).To(HaveKeyWithValue("example.com/distribution", "test"))
+		By("keeping the objects the mutators don't change", func() {
+			service := &corev1.Service{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, service)
+			Expect(err).Should(BeNil())
+			Expect(service.Labels).ToNot(HaveKey("example.com/distribution"))
+		})
+	})
+	It("should delete a DexServer once its TTL elapsed", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "ttl-dexserver", Namespace: DexServerNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				TTL: &metav1.Duration{Duration: time.Hour},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		By("counting down", func() {
+			expired, err := rDexServer.checkTTL(dexServer, context.TODO())
+			Expect(err).Should(BeNil())
+			Expect(expired).To(BeFalse())
+			Expect(dexServer.Status.TTL).ToNot(BeNil())
+			Expect(dexServer.Status.TTL.ExpiresAt.Time).To(BeTemporally("~", dexServer.CreationTimestamp.Add(time.Hour)))
+			requeueAfter, ok := getTTLRequeueAfter(dexServer)
+			Expect(ok).To(BeTrue())
+			Expect(requeueAfter).To(Equal(TTL_COUNTDOWN_INTERVAL))
+		})
+		By("deleting the DexServer", func() {
+			dexServer.Spec.TTL = &metav1.Duration{Duration: time.Second}
+			Eventually(func() bool {
+				expired, err := rDexServer.checkTTL(dexServer, context.TODO())
+				return err == nil && expired
+			}, 10, 1).Should(BeTrue())
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: "ttl-dexserver", Namespace: DexServerNamespace}, &authv1alpha1.DexServer{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+	It("should repair the objects of an annotated DexServer", func() {
+		dexConfigMap := &corev1.ConfigMap{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexConfigMap)
+		Expect(err).Should(BeNil())
+		dexConfig := dexConfigMap.Data["config.yaml"]
+		By("editing an object and requesting a repair", func() {
+			dexConfigMap.Data["config.yaml"] = "issuer: https://edited.testhost.com"
+			Expect(k8sClient.Update(context.TODO(), dexConfigMap)).To(Succeed())
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			if dexServer.Annotations == nil {
+				dexServer.Annotations = map[string]string{}
+			}
+			dexServer.Annotations[REPAIR_ANNOTATION] = ""
+			Expect(k8sClient.Update(context.TODO(), dexServer)).To(Succeed())
+		})
+		By("reasserting the object", func() {
+			Eventually(func() bool {
+				req := ctrl.Request{}
+				req.Name = DexServerName
+				req.Namespace = DexServerNamespace
+				_, err := rDexServer.Reconcile(context.TODO(), req)
+				return err == nil
+			}, 10, 1).Should(BeTrue())
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexConfigMap)
+			Expect(err).Should(BeNil())
+			Expect(dexConfigMap.Data["config.yaml"]).To(Equal(dexConfig))
+		})
+		By("removing the repair annotation", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			Expect(dexServer.Annotations).ToNot(HaveKey(REPAIR_ANNOTATION))
+		})
+	})
+	It("should only report the changes to an observed DexServer", func() {
+		dexConfigMap := &corev1.ConfigMap{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexConfigMap)
+		Expect(err).Should(BeNil())
+		dexConfig := dexConfigMap.Data["config.yaml"]
+		reconcileDexServer := func() bool {
+			req := ctrl.Request{}
+			req.Name = DexServerName
+			req.Namespace = DexServerNamespace
+			_, err := rDexServer.Reconcile(context.TODO(), req)
+			return err == nil
+		}
+		By("editing an object of an observed DexServer", func() {
+			dexConfigMap.Data["config.yaml"] = "issuer: https://edited.testhost.com"
+			Expect(k8sClient.Update(context.TODO(), dexConfigMap)).To(Succeed())
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			if dexServer.Annotations == nil {
+				dexServer.Annotations = map[string]string{}
+			}
+			dexServer.Annotations[OBSERVE_ANNOTATION] = "true"
+			Expect(k8sClient.Update(context.TODO(), dexServer)).To(Succeed())
+		})
+		By("reporting the change without making it", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			Eventually(reconcileDexServer, 10, 1).Should(BeTrue())
+			Expect(testutil.ToFloat64(observedChanges.WithLabelValues(DexServerNamespace, DexServerName))).To(BeNumerically(">=", 1))
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexConfigMap)
+			Expect(err).Should(BeNil())
+			Expect(dexConfigMap.Data["config.yaml"]).To(Equal("issuer: https://edited.testhost.com"))
+			observed := &authv1alpha1.DexServer{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, observed)
+			Expect(err).Should(BeNil())
+			Expect(observed.ResourceVersion).To(Equal(dexServer.ResourceVersion))
+		})
+		By("leaving out the notifications of the dry runs", func() {
+			notifying := rDexServer
+			notifying.Notifier = NewNotifier("https://notifications.testhost.com")
+			Expect(notifying.dryRunReconciler().Notifier).To(BeNil())
+		})
+		observedSeries := testutil.CollectAndCount(observedChanges)
+		By("making the change once the DexServer is no longer observed", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexServer)
+			Expect(err).Should(BeNil())
+			delete(dexServer.Annotations, OBSERVE_ANNOTATION)
+			Expect(k8sClient.Update(context.TODO(), dexServer)).To(Succeed())
+			Eventually(reconcileDexServer, 10, 1).Should(BeTrue())
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerName, Namespace: DexServerNamespace}, dexConfigMap)
+			Expect(err).Should(BeNil())
+			Expect(dexConfigMap.Data["config.yaml"]).To(Equal(dexConfig))
+			Expect(testutil.CollectAndCount(observedChanges)).To(Equal(observedSeries - 1))
+		})
+	})
+	It("should sync the members of the GitHub teams", func() {
+		// the next response of the GitHub server, when set
+		var mu sync.Mutex
+		var nextResponse func(w http.ResponseWriter)
+		github := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			respond := nextResponse
+			nextResponse = nil
+			mu.Unlock()
+			if respond != nil {
+				respond(w)
+				return
+			}
+			if req.Header.Get("Authorization") != "token my-github-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch req.URL.Path {
+			case "/api/v3/orgs/my-org/teams":
+				fmt.Fprint(w, `[{"name": "Cluster Admins", "slug": "cluster-admins"}, {"name": "Developers", "slug": "developers"}]`)
+			case "/api/v3/orgs/my-org/teams/cluster-admins/members":
+				fmt.Fprint(w, `[{"login": "alice"}, {"login": "bob"}]`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer github.Close()
+		respondOnce := func(respond func(w http.ResponseWriter)) {
+			mu.Lock()
+			defer mu.Unlock()
+			nextResponse = respond
+		}
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "teams-github", Namespace: DexServerNamespace},
+			Data:       map[string][]byte{"clientSecret": []byte("BogusSecret"), TEAM_SYNC_TOKEN_KEY: []byte("my-github-token")},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "teams-dexserver", Namespace: DexServerNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Connectors: []authv1alpha1.ConnectorSpec{{
+					Type: authv1alpha1.ConnectorTypeGitHub,
+					Id:   "my-github",
+					Name: "my-github",
+					GitHub: authv1alpha1.GitHubConfigSpec{
+						ClientID:        "my-client",
+						ClientSecretRef: corev1.SecretReference{Name: "teams-github", Namespace: DexServerNamespace},
+						HostName:        strings.TrimPrefix(github.URL, "https://"),
+						// the GitHub Enterprise server is only trusted through the root CA of the connector
+						RootCAData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: github.Certificate().Raw}),
+						Orgs:       []authv1alpha1.Org{{Name: "my-org", Teams: []string{"Cluster Admins"}}},
+					},
+				}},
+				GroupBindings: authv1alpha1.GroupBindingsSpec{GroupsPrefix: "dex:"},
+				TeamSync:      authv1alpha1.TeamSyncSpec{Connector: "my-github", UsernamePrefix: "dex:"},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		Expect(rDexServer.syncTeams(dexServer, context.TODO())).To(Succeed())
+		Expect(dexServer.Status.TeamSync).To(BeNil())
+		Expect(rDexServer.syncTeamMembers(dexServer, context.TODO())).To(Succeed())
+		Expect(dexServer.Status.TeamSync).ToNot(BeNil())
+		Expect(dexServer.Status.TeamSync.Message).To(BeEmpty())
+		Expect(dexServer.Status.TeamSync.Groups).To(Equal([]string{"dex:my-org:Cluster Admins"}))
+		configMap := &corev1.ConfigMap{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: "teams-dexserver" + TEAM_SYNC_SUFFIX, Namespace: DexServerNamespace}, configMap)
+		Expect(err).Should(BeNil())
+		groups := map[string][]string{}
+		Expect(yaml.Unmarshal([]byte(configMap.Data[TEAM_SYNC_KEY]), &groups)).To(Succeed())
+		Expect(groups).To(Equal(map[string][]string{"dex:my-org:Cluster Admins": {"dex:alice", "dex:bob"}}))
+		By("waiting for the interval before syncing again", func() {
+			Expect(getTeamSyncRequeueAfter(dexServer, time.Now())).To(BeNumerically("~", defaultTeamSyncInterval, time.Minute))
+		})
+		By("not trusting the system CAs for the GitHub Enterprise server", func() {
+			untrusted := dexServer.DeepCopy()
+			untrusted.Spec.Connectors[0].GitHub.RootCAData = nil
+			untrusted.Status.TeamSync = nil
+			Expect(rDexServer.syncTeamMembers(untrusted, context.TODO())).To(Succeed())
+			Expect(untrusted.Status.TeamSync.Message).To(ContainSubstring("certificate"))
+		})
+		By("retrying once the rate limit of GitHub resets", func() {
+			respondOnce(func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Unix()))
+				w.WriteHeader(http.StatusForbidden)
+			})
+			Expect(rDexServer.syncTeamMembers(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.TeamSync.Message).To(BeEmpty())
+			Expect(dexServer.Status.TeamSync.Groups).To(Equal([]string{"dex:my-org:Cluster Admins"}))
+		})
+		By("not waiting past the deadline of the sync for the rate limit", func() {
+			respondOnce(func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusTooManyRequests)
+			})
+			Expect(rDexServer.syncTeamMembers(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.TeamSync.Message).To(ContainSubstring("deadline"))
+			Expect(dexServer.Status.TeamSync.Groups).To(Equal([]string{"dex:my-org:Cluster Admins"}))
+		})
+		By("keeping the groups when GitHub fails", func() {
+			secret.Data[TEAM_SYNC_TOKEN_KEY] = []byte("revoked-token")
+			Expect(k8sClient.Update(context.TODO(), secret)).To(Succeed())
+			Eventually(func() string {
+				Expect(rDexServer.syncTeamMembers(dexServer, context.TODO())).To(Succeed())
+				return dexServer.Status.TeamSync.Message
+			}, 10, 1).Should(ContainSubstring("401"))
+			Expect(dexServer.Status.TeamSync.Groups).To(Equal([]string{"dex:my-org:Cluster Admins"}))
+			secret.Data[TEAM_SYNC_TOKEN_KEY] = []byte("my-github-token")
+			Expect(k8sClient.Update(context.TODO(), secret)).To(Succeed())
+		})
+		By("syncing the teams due to sync in the background", func() {
+			Eventually(func() (*authv1alpha1.TeamSyncStatus, error) {
+				stored := &authv1alpha1.DexServer{}
+				if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), stored); err != nil {
+					return nil, err
+				}
+				if stored.Status.TeamSync == nil || stored.Status.TeamSync.Message != "" {
+					// due to sync
+					stored.Status.TeamSync = &authv1alpha1.TeamSyncStatus{Message: "pending"}
+					if err := k8sClient.Status().Update(context.TODO(), stored); err != nil {
+						return nil, err
+					}
+				}
+				rDexServer.syncDueTeams(context.TODO())
+				err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), stored)
+				return stored.Status.TeamSync, err
+			}, 10, 1).Should(And(Not(BeNil()), HaveField("Message", BeEmpty()), HaveField("Groups", Equal([]string{"dex:my-org:Cluster Admins"}))))
+		})
+		By("rejecting the connectors without GitHub teams", func() {
+			invalid := dexServer.DeepCopy()
+			invalid.Spec.TeamSync.Connector = "unknown"
+			Expect(rDexServer.syncTeams(invalid, context.TODO())).NotTo(Succeed())
+		})
+		By("deleting the ConfigMap once the sync is disabled", func() {
+			dexServer.Spec.TeamSync = authv1alpha1.TeamSyncSpec{}
+			Expect(rDexServer.syncTeams(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.TeamSync).To(BeNil())
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: "teams-dexserver" + TEAM_SYNC_SUFFIX, Namespace: DexServerNamespace}, configMap)
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+	It("should retry the requests of the GitHub API rate limited or failing on the server side", func() {
+		now := time.Now()
+		for _, test := range []struct {
+			status  int
+			headers map[string]string
+			attempt int
+			delay   time.Duration
+			retry   bool
+		}{
+			{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": fmt.Sprint(now.Add(time.Minute).Unix())}, delay: time.Minute + time.Second, retry: true},
+			{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": fmt.Sprint(now.Add(-time.Minute).Unix())}, delay: time.Second, retry: true},
+			{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "30"}, delay: 30 * time.Second, retry: true},
+			{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "30"}, delay: 30 * time.Second, retry: true},
+			{status: http.StatusTooManyRequests, delay: githubRetryBackoff, retry: true},
+			{status: http.StatusBadGateway, attempt: 2, delay: 4 * githubRetryBackoff, retry: true},
+			{status: http.StatusBadGateway, attempt: githubMaxRetries},
+			// a token without the permission
+			{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "4999"}},
+			{status: http.StatusUnauthorized},
+		} {
+			resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+			for key, value := range test.headers {
+				resp.Header.Set(key, value)
+			}
+			delay, retry := getGitHubRetryDelay(resp, test.attempt, now)
+			Expect(retry).To(Equal(test.retry), "status %d, headers %v", test.status, test.headers)
+			Expect(delay).To(BeNumerically("~", test.delay, time.Second), "status %d, headers %v", test.status, test.headers)
+		}
+	})
+	It("should only map the referenced secrets to the DexServers referencing them", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-refs-dexserver", Namespace: DexServerNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Connectors: []authv1alpha1.ConnectorSpec{{
+					Type: authv1alpha1.ConnectorTypeLDAP,
+					Id:   "my-ldap",
+					Name: "my-ldap",
+					LDAP: authv1alpha1.LDAPConfigSpec{
+						BindPWRef: corev1.SecretReference{Name: "secret-refs-bindpw", Namespace: AuthRealmNameSpace},
+						RootCARef: corev1.SecretReference{Name: "secret-refs-ca"},
+					},
+				}},
+			},
+		}
+		Expect(getReferencedSecretKeys(dexServer)).To(Equal([]string{
+			AuthRealmNameSpace + "/secret-refs-bindpw",
+			DexServerNamespace + "/secret-refs-ca",
+		}))
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		request := ctrl.Request{}
+		request.Name = dexServer.Name
+		request.Namespace = DexServerNamespace
+		Eventually(func() []ctrl.Request {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret-refs-ca", Namespace: DexServerNamespace}}
+			return getDexServersForSecret(k8sCachedClient, secret)
+		}, 10, 1).Should(Equal([]ctrl.Request{request}))
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret-refs-ca", Namespace: AuthRealmNameSpace}}
+		Expect(getDexServersForSecret(k8sCachedClient, secret)).To(BeEmpty())
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unreferenced", Namespace: DexServerNamespace}}
+		Expect(getDexServersForSecret(k8sCachedClient, secret)).To(BeEmpty())
+	})
+	It("should leave out the connectors whose secret is missing with the FailOpen error policy", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "error-policy-github", Namespace: DexServerNamespace},
+			Data:       map[string][]byte{"clientSecret": []byte("BogusSecret")},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		githubConnector := func(id string, secretName string) authv1alpha1.ConnectorSpec {
+			return authv1alpha1.ConnectorSpec{
+				Type: authv1alpha1.ConnectorTypeGitHub,
+				Id:   id,
+				Name: id,
+				GitHub: authv1alpha1.GitHubConfigSpec{
+					ClientID:        "my-client",
+					ClientSecretRef: corev1.SecretReference{Name: secretName, Namespace: DexServerNamespace},
+				},
+			}
+		}
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "error-policy-dexserver", Namespace: DexServerNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Connectors: []authv1alpha1.ConnectorSpec{
+					githubConnector("my-github", "error-policy-github"),
+					githubConnector("my-missing-github", "error-policy-missing"),
+				},
+			},
+		}
+		By("refusing to render the connectors with the FailClosed error policy", func() {
+			_, _, err := rDexServer.getRenderedConnectors(dexServer, context.TODO())
+			Expect(err).To(MatchError("secret " + DexServerNamespace + "/error-policy-missing of connector my-missing-github not found"))
+			Expect(err).To(BeAssignableToTypeOf(&missingSecretError{}))
+		})
+		By("rendering the connectors whose secrets exist with the FailOpen error policy", func() {
+			dexServer.Spec.ErrorPolicy = authv1alpha1.ErrorPolicyFailOpen
+			connectors, rejected, err := rDexServer.getRenderedConnectors(dexServer, context.TODO())
+			Expect(err).To(BeNil())
+			Expect(connectors).To(HaveLen(1))
+			Expect(connectors[0].Id).To(Equal("my-github"))
+			Expect(rejected).To(Equal([]authv1alpha1.RejectedConnectorStatus{{
+				Id:     "my-missing-github",
+				Type:   authv1alpha1.ConnectorTypeGitHub,
+				Reason: "secret " + DexServerNamespace + "/error-policy-missing of connector my-missing-github not found",
+			}}))
+			cond := getConnectorsSkippedCondition(rejected)
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal("ConnectorsRejected"))
+			Expect(cond.Message).To(ContainSubstring("my-missing-github"))
+		})
+		By("disabling the password grant while its connector is skipped", func() {
+			dexServer.Spec.Issuer = "https://error-policy-dexserver.testhost.com"
+			dexServer.Spec.OAuth2.PasswordConnector = "my-missing-github"
+			Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+			// the ConfigMap is also managed by the reconcile of the manager
+			Eventually(func() error {
+				if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), dexServer); err != nil {
+					return err
+				}
+				return rDexServer.syncConfigMap(dexServer, context.TODO())
+			}, 10, 1).Should(Succeed())
+			dexConfigMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), dexConfigMap)).To(Succeed())
+			var configMapData map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(dexConfigMap.Data["config.yaml"]), &configMapData)).To(Succeed())
+			Expect(configMapData["oauth2"]).ShouldNot(HaveKey("passwordConnector"))
+		})
+		By("rendering all the connectors once the secret is created", func() {
+			Expect(k8sClient.Create(context.TODO(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "error-policy-missing", Namespace: DexServerNamespace},
+				Data:       map[string][]byte{"clientSecret": []byte("BogusSecret")},
+			})).To(Succeed())
+			connectors, rejected, err := rDexServer.getRenderedConnectors(dexServer, context.TODO())
+			Expect(err).To(BeNil())
+			Expect(connectors).To(HaveLen(2))
+			Expect(rejected).To(BeEmpty())
+			cond := getConnectorsSkippedCondition(rejected)
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal("AllConnectorsRendered"))
+		})
+	})
+	It("should only reject the connectors that can't be rendered", func() {
+		Expect(k8sClient.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "rejected-connectors-github", Namespace: DexServerNamespace},
+			Data:       map[string][]byte{"clientSecret": []byte("BogusSecret")},
+		})).To(Succeed())
+		github := authv1alpha1.GitHubConfigSpec{
+			ClientID:        "my-client",
+			ClientSecretRef: corev1.SecretReference{Name: "rejected-connectors-github", Namespace: DexServerNamespace},
+		}
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "rejected-connectors-dexserver", Namespace: DexServerNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Connectors: []authv1alpha1.ConnectorSpec{
+					{Type: "gitlab", Id: "my-gitlab", Name: "GitLab"},
+					{Type: authv1alpha1.ConnectorTypeGitHub, Id: "my-github", Name: "GitHub", GitHub: github},
+					{Type: authv1alpha1.ConnectorTypeGitHub, Id: "my-github", Name: "Other GitHub", GitHub: github},
+				},
+			},
+		}
+		connectors, rejected, err := rDexServer.getRenderedConnectors(dexServer, context.TODO())
+		Expect(err).To(BeNil())
+		Expect(connectors).To(HaveLen(1))
+		Expect(connectors[0].Name).To(Equal("GitHub"))
+		Expect(rejected).To(Equal([]authv1alpha1.RejectedConnectorStatus{
+			{Id: "my-gitlab", Type: "gitlab", Reason: `unknown connector type "gitlab"`},
+			{Id: "my-github", Type: authv1alpha1.ConnectorTypeGitHub, Reason: `the id "my-github" is already used by spec.connectors[1]`},
+		}))
+		Expect(isConnectorRejected(rejected, "my-gitlab")).To(BeTrue())
+		Expect(isConnectorRejected(rejected, "my-ldap")).To(BeFalse())
+		cond := getConnectorsSkippedCondition(rejected)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Message).To(Equal("connectors my-gitlab, my-github are skipped, see status.rejectedConnectors"))
+	})
+	It("should distribute the issuer trust to the selected managed clusters", func() {
+		// the ACM APIs are not installed in the test environment
+		preserveUnknownFields := true
+		for _, gvr := range []struct {
+			group string
+			kind  string
+			scope apiextensionsv1.ResourceScope
+		}{
+			{group: managedClusterGVR.Group, kind: "ManagedCluster", scope: apiextensionsv1.ClusterScoped},
+			{group: manifestWorkGVR.Group, kind: "ManifestWork", scope: apiextensionsv1.NamespaceScoped},
+		} {
+			plural := strings.ToLower(gvr.kind) + "s"
+			crd := &apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: plural + "." + gvr.group},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Group: gvr.group,
+					Names: apiextensionsv1.CustomResourceDefinitionNames{
+						Plural:   plural,
+						Singular: strings.ToLower(gvr.kind),
+						Kind:     gvr.kind,
+						ListKind: gvr.kind + "List",
+					},
+					Scope: gvr.scope,
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+						Name:    "v1",
+						Served:  true,
+						Storage: true,
+						Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type:                   "object",
+							XPreserveUnknownFields: &preserveUnknownFields,
+						}},
+					}},
+				},
+			}
+			_, err := rDexServer.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Create(context.TODO(), crd, metav1.CreateOptions{})
+			Expect(err).To(BeNil())
+		}
+		Eventually(func() error {
+			_, err := rDexServer.DynamicClient.Resource(manifestWorkGVR).List(context.TODO(), metav1.ListOptions{})
+			return err
+		}, 30, 1).Should(Succeed())
+		for name, env := range map[string]string{"my-prod-cluster": "prod", "my-dev-cluster": "dev"} {
+			Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+			cluster := &unstructured.Unstructured{}
+			cluster.SetAPIVersion("cluster.open-cluster-management.io/v1")
+			cluster.SetKind("ManagedCluster")
+			cluster.SetName(name)
+			cluster.SetLabels(map[string]string{"env": env})
+			Eventually(func() error {
+				_, err := rDexServer.DynamicClient.Resource(managedClusterGVR).Create(context.TODO(), cluster, metav1.CreateOptions{})
+				return err
+			}, 30, 1).Should(Succeed())
+		}
+
+		trustNamespace := "my-trust-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: trustNamespace}})).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-issuer-ca", Namespace: trustNamespace},
+			Data:       map[string]string{"ca.crt": "my-ca-bundle"},
+		})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-trusted-dexserver", Namespace: trustNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://my-trusted-dexserver.testhost.com",
+				TrustDistribution: authv1alpha1.TrustDistributionSpec{
+					Enabled:         true,
+					ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+					CABundleRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "my-issuer-ca"},
+						Key:                  "ca.crt",
+					},
+					ClientID: "my-cluster-login",
+				},
+			},
+		}
+		getWork := func(cluster string) (*unstructured.Unstructured, error) {
+			return rDexServer.DynamicClient.Resource(manifestWorkGVR).Namespace(cluster).Get(context.TODO(), getTrustManifestWorkName(dexServer), metav1.GetOptions{})
+		}
+
+		Expect(rDexServer.syncTrustDistribution(dexServer, context.TODO())).To(Succeed())
+		Expect(dexServer.Status.TrustDistributedClusters).To(Equal([]string{"my-prod-cluster"}))
+		By("delivering the issuer and its CA bundle in a ManifestWork", func() {
+			work, err := getWork("my-prod-cluster")
+			Expect(err).To(BeNil())
+			Expect(work.GetLabels()).To(Equal(map[string]string{
+				MANAGED_BY_LABEL:          MANAGED_BY_VALUE,
+				INSTANCE_LABEL:            dexServer.Name,
+				DEXSERVER_NAMESPACE_LABEL: trustNamespace,
+			}))
+			manifests, _, err := unstructured.NestedSlice(work.Object, "spec", "workload", "manifests")
+			Expect(err).To(BeNil())
+			Expect(manifests).To(HaveLen(1))
+			configMap := manifests[0].(map[string]interface{})
+			Expect(configMap["metadata"]).To(HaveKeyWithValue("namespace", "openshift-config"))
+			Expect(configMap["data"]).To(Equal(map[string]interface{}{
+				"issuer":   "https://my-trusted-dexserver.testhost.com",
+				"ca.crt":   "my-ca-bundle",
+				"clientID": "my-cluster-login",
+			}))
+			_, err = getWork("my-dev-cluster")
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+		By("distributing the trust to all the managed clusters without a selector", func() {
+			dexServer.Spec.TrustDistribution.ClusterSelector = nil
+			Expect(rDexServer.syncTrustDistribution(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.TrustDistributedClusters).To(Equal([]string{"my-dev-cluster", "my-prod-cluster"}))
+			_, err := getWork("my-dev-cluster")
+			Expect(err).To(BeNil())
+		})
+		By("deleting the ManifestWorks of the clusters no longer selected", func() {
+			dexServer.Spec.TrustDistribution.ClusterSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+			Expect(rDexServer.syncTrustDistribution(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.TrustDistributedClusters).To(Equal([]string{"my-prod-cluster"}))
+			_, err := getWork("my-dev-cluster")
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+		By("deleting all the ManifestWorks once the distribution is disabled", func() {
+			dexServer.Spec.TrustDistribution.Enabled = false
+			Expect(rDexServer.syncTrustDistribution(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.TrustDistributedClusters).To(BeNil())
+			_, err := getWork("my-prod-cluster")
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+	It("should only replace the DexServer of another namespace with the consent of its owner", func() {
+		namespace := "my-replaced-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		replaced := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "replaced-dexserver", Namespace: namespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://replaced.testhost.com"},
+		}
+		Expect(k8sClient.Create(context.TODO(), replaced)).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "replacing-dexserver", Namespace: DexServerNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:   "https://replaced.testhost.com",
+				Replaces: &authv1alpha1.HandoverSpec{Name: "replaced-dexserver", Namespace: namespace},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		By("rejecting the import of the signing keys", func() {
+			err := rDexServer.importSigningKeys(dexServer, context.TODO())
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring(HANDOVER_ALLOWED_ANNOTATION))
+			Expect(dexServer.Status.Handover.Phase).To(Equal(authv1alpha1.HandoverPhaseImportingKeys))
+		})
+		By("rejecting the switch of the route", func() {
+			setHandoverPhase(dexServer, authv1alpha1.HandoverPhaseWaitingForAvailable, "")
+			handingOver, err := rDexServer.advanceHandover(dexServer, context.TODO(), true)
+			Expect(err).ToNot(BeNil())
+			Expect(handingOver).To(BeTrue())
+			err = k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(replaced), replaced)
+			Expect(err).Should(BeNil())
+			Expect(replaced.Annotations).ToNot(HaveKey(HANDED_OVER_ANNOTATION))
+		})
+		By("accepting the DexServer named by the owner of the replaced DexServer", func() {
+			replaced.Annotations = map[string]string{HANDOVER_ALLOWED_ANNOTATION: DexServerNamespace + "/replacing-dexserver"}
+			Expect(k8sClient.Update(context.TODO(), replaced)).To(Succeed())
+			Expect(checkHandoverAllowed(dexServer, replaced)).To(Succeed())
+			other := dexServer.DeepCopy()
+			other.Name = "other-dexserver"
+			Expect(checkHandoverAllowed(other, replaced)).NotTo(Succeed())
+		})
+	})
+	It("should import the signing keys through the storage of the new DexServer", func() {
+		namespace := "my-storage-handover-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-dexserver", Namespace: namespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://storage-handover.testhost.com"},
+		})).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "dex-postgres", Namespace: namespace},
+			Data:       map[string][]byte{"password": []byte("my-postgres-password")},
+		})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "postgres-dexserver", Namespace: namespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:   "https://storage-handover.testhost.com",
+				Replaces: &authv1alpha1.HandoverSpec{Name: "kubernetes-dexserver", Namespace: namespace},
+				Storage: authv1alpha1.StorageSpec{
+					Type: authv1alpha1.StorageTypePostgres,
+					Postgres: &authv1alpha1.PostgresStorageSpec{
+						Host:        "postgres.testhost.com",
+						Database:    "dex",
+						User:        "dex",
+						PasswordRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "dex-postgres"}, Key: "password"},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		// the replaced dex server stores its keys in the kubernetes storage of its namespace
+		r := &DexServerReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			DynamicClient: newDexStorageClient(`{"apiVersion": "dex.coreos.com/v1", "kind": "SigningKey",
+				"metadata": {"name": "openid-connect-keys", "namespace": "` + namespace + `"},
+				"signingKey": {"kty": "RSA", "kid": "my-replaced-key", "n": "AQAB", "e": "AQAB", "d": "AQAB"},
+				"signingKeyPub": {"kty": "RSA", "kid": "my-replaced-key", "n": "AQAB", "e": "AQAB"},
+				"nextRotation": "2026-10-02T14:00:00Z"}`),
+		}
+		dexstorage.PostgresDriver = "recording"
+		defer func() { dexstorage.PostgresDriver = "postgres" }()
+		sqlRecorder.reset()
+
+		Expect(r.importSigningKeys(dexServer, context.TODO())).To(Succeed())
+		Expect(dexServer.Status.Handover.Phase).To(Equal(authv1alpha1.HandoverPhaseWaitingForAvailable))
+		Expect(sqlRecorder.dsn).To(ContainSubstring("password='my-postgres-password'"))
+		keys := sqlRecorder.inserts("keys")
+		Expect(keys).To(HaveLen(1))
+		signingKey := map[string]interface{}{}
+		Expect(json.Unmarshal(keys[0][1].([]byte), &signingKey)).To(Succeed())
+		Expect(signingKey).To(HaveKeyWithValue("kid", "my-replaced-key"))
+		Expect(sqlRecorder.inserts("client")).To(BeEmpty())
+		Expect(sqlRecorder.inserts("refresh_token")).To(BeEmpty())
+	})
+	It("should render the teams of a Bitbucket Cloud connector in the dex ConfigMap", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bitbucket-client", Namespace: DexServerNamespace},
+			Data:       map[string][]byte{"clientSecret": []byte("BogusSecret")},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "bitbucket-dexserver", Namespace: DexServerNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://bitbucket-dexserver.testhost.com",
+				Connectors: []authv1alpha1.ConnectorSpec{{
+					Type: authv1alpha1.ConnectorTypeBitbucketCloud,
+					Id:   "my-bitbucket",
+					Name: "my-bitbucket",
+					BitbucketCloud: authv1alpha1.BitbucketCloudConfigSpec{
+						ClientID:          "my-client",
+						ClientSecretRef:   corev1.SecretReference{Name: "bitbucket-client", Namespace: DexServerNamespace},
+						Teams:             []string{"my-team", "my-other-team"},
+						IncludeTeamGroups: true,
+					},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		Eventually(func() error {
+			if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), dexServer); err != nil {
+				return err
+			}
+			return rDexServer.syncConfigMap(dexServer, context.TODO())
+		}, 10, 1).Should(Succeed())
+
+		configMap := &corev1.ConfigMap{}
+		err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: "bitbucket-dexserver", Namespace: DexServerNamespace}, configMap)
+		Expect(err).Should(BeNil())
+		config, err := dexconfig.Load([]byte(configMap.Data["config.yaml"]))
+		Expect(err).Should(BeNil())
+		Expect(config.StaticConnectors).To(HaveLen(1))
+		Expect(config.StaticConnectors[0].Type).To(Equal("bitbucket-cloud"))
+		bitbucketCloud := &dexconfig.BitbucketCloudConfig{}
+		Expect(json.Unmarshal(config.StaticConnectors[0].Config, bitbucketCloud)).To(Succeed())
+		Expect(bitbucketCloud.ClientSecret).To(HavePrefix("$BITBUCKET_CLOUD_CLIENT_SECRET_"))
+		Expect(bitbucketCloud.Teams).To(Equal([]string{"my-team", "my-other-team"}))
+		Expect(bitbucketCloud.IncludeTeamGroups).To(BeTrue())
+	})
+	It("should migrate the objects of the previous layouts", func() {
+		namespace := "my-legacy-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		// the gRPC objects of the operators before the layout versions
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: GRPC_SERVICE_NAME, Namespace: namespace},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "grpc", Port: 5557}}},
+		}
+		Expect(k8sClient.Create(context.TODO(), service)).To(Succeed())
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: SECRET_MTLS_NAME, Namespace: namespace},
+			Data:       map[string][]byte{"ca.crt": []byte("legacy")},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-legacy-dexserver", Namespace: namespace}}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+
+		Expect(rDexServer.MigrateLayouts(context.TODO())).To(Succeed())
+		for _, obj := range []client.Object{service, secret} {
+			err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj)
+			Expect(err).Should(BeNil())
+			Expect(obj.GetLabels()).To(HaveKeyWithValue(MANAGED_BY_LABEL, MANAGED_BY_VALUE))
+			Expect(obj.GetAnnotations()).To(HaveKeyWithValue(LAYOUT_VERSION_ANNOTATION, fmt.Sprint(getLayoutVersion())))
+			Expect(metav1.IsControlledBy(obj, dexServer)).To(BeTrue())
+		}
+		err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(dexServer), dexServer)
+		Expect(err).Should(BeNil())
+		Expect(dexServer.Annotations).To(HaveKeyWithValue(LAYOUT_VERSION_ANNOTATION, fmt.Sprint(getLayoutVersion())))
+		By("migrating another DexServer of the namespace without taking over the shared objects", func() {
+			other := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-other-dexserver", Namespace: namespace}}
+			Expect(k8sClient.Create(context.TODO(), other)).To(Succeed())
+			Expect(rDexServer.migrateLayout(other, context.TODO())).To(Succeed())
+			Expect(other.Annotations).To(HaveKeyWithValue(LAYOUT_VERSION_ANNOTATION, fmt.Sprint(getLayoutVersion())))
+			for _, obj := range []client.Object{service, secret} {
+				err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj)
+				Expect(err).Should(BeNil())
+				Expect(metav1.IsControlledBy(obj, dexServer)).To(BeTrue())
+				Expect(obj.GetLabels()).To(HaveKeyWithValue("app", dexServer.Name))
+			}
+		})
+		By("refusing the layouts of a newer operator", func() {
+			dexServer.Annotations[LAYOUT_VERSION_ANNOTATION] = fmt.Sprint(getLayoutVersion() + 1)
+			Expect(rDexServer.migrateLayout(dexServer, context.TODO())).NotTo(Succeed())
+		})
+	})
 })
 
 func getCRD(reader *clusteradmasset.ScenarioResourcesReader, file string) (*apiextensionsv1.CustomResourceDefinition, error) {
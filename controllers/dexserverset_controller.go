@@ -0,0 +1,320 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/tracing"
+)
+
+const (
+	// Name of the DexServerSet a DexServer is stamped out by
+	DEXSERVER_SET_LABEL = "auth.identitatem.io/dexserverset"
+	// Label of the namespaces opting in to the DexServer of the DexServerSet named by DexEnabledNamespacesSet
+	DEX_ENABLED_NAMESPACE_LABEL = "auth.identitatem.io/dex"
+	DEX_ENABLED_NAMESPACE_VALUE = "enabled"
+)
+
+// DexServerSetReconciler reconciles a DexServerSet object. The DexServers of a DexServerSet are created with the
+// name of the DexServerSet in the selected namespaces and are reconciled by the DexServerReconciler like any other
+// DexServer.
+type DexServerSetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// DexEnabledNamespacesSet is the name of the DexServerSet whose DexServer the namespaces opt in to with the
+	// DEX_ENABLED_NAMESPACE_LABEL, for self-service SSO. Its namespaceSelector only restricts the namespaces that
+	// can opt in. No DexServerSet is selected by the label when empty.
+	DexEnabledNamespacesSet string
+}
+
+// Values of spec.issuerTemplate
+type dexServerSetIssuerValues struct {
+	Name      string
+	Namespace string
+}
+
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexserversets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexserversets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexserversets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Reconcile creates or updates the DexServer of each namespace selected by a DexServerSet, deletes the DexServers
+// of the namespaces no longer selected, and reports the DexServers in the status. The DexServers are owned by the
+// DexServerSet and are garbage collected with it, or with their namespace.
+func (r *DexServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	log.V(1).Info("Reconciling...")
+	ctx, span := tracing.Start(ctx, "DexServerSet.Reconcile", "name", req.Name)
+	defer span.End()
+
+	dexServerSet := &authv1alpha1.DexServerSet{}
+	if err := r.Get(ctx, req.NamespacedName, dexServerSet); err != nil {
+		log.Error(err, "failed to fetch DexServerSet instance")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if dexServerSet.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	namespaces, err := r.getSelectedNamespaces(dexServerSet, ctx)
+	if err != nil {
+		log.Error(err, "failed to list the selected namespaces")
+		cond := metav1.Condition{
+			Type:   authv1alpha1.DexServerSetConditionTypeApplied,
+			Status: metav1.ConditionFalse,
+			Reason: "InvalidNamespaceSelector",
+			Message: fmt.Sprintf("failed to list the selected namespaces. error: %s",
+				err.Error()),
+		}
+		if err := updateDexServerSetStatusConditions(r.Client, dexServerSet, cond); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	members := []authv1alpha1.DexServerSetMemberStatus{}
+	conflicts := []string{}
+	for _, namespace := range namespaces {
+		dexServer, err := r.syncDexServer(dexServerSet, namespace, ctx)
+		if err != nil {
+			log.Error(err, "failed to sync DexServer", "DexServer.Namespace", namespace)
+			cond := metav1.Condition{
+				Type:   authv1alpha1.DexServerSetConditionTypeApplied,
+				Status: metav1.ConditionFalse,
+				Reason: "ConfigDexServerFailed",
+				Message: fmt.Sprintf("failed to sync DexServer in namespace %s. error: %s",
+					namespace, err.Error()),
+			}
+			if err := updateDexServerSetStatusConditions(r.Client, dexServerSet, cond); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+		if dexServer == nil {
+			conflicts = append(conflicts, namespace)
+			continue
+		}
+		members = append(members, authv1alpha1.DexServerSetMemberStatus{
+			Namespace: namespace,
+			Issuer:    dexServer.Status.Issuer,
+			Ready:     meta.IsStatusConditionTrue(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeReady),
+		})
+	}
+
+	if err := r.deleteUnselectedDexServers(dexServerSet, namespaces, ctx); err != nil {
+		log.Error(err, "failed to delete the DexServers of the namespaces no longer selected")
+		return ctrl.Result{}, err
+	}
+
+	dexServerSet.Status.DexServers = members
+	cond := metav1.Condition{
+		Type:    authv1alpha1.DexServerSetConditionTypeApplied,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: fmt.Sprintf("%d DexServers are applied", len(members)),
+	}
+	if len(conflicts) > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "DexServerConflict"
+		cond.Message = fmt.Sprintf("DexServer %s already exists and is not managed by the DexServerSet in namespaces %s",
+			dexServerSet.Name, strings.Join(conflicts, ", "))
+	}
+	if err := updateDexServerSetStatusConditions(r.Client, dexServerSet, cond); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Get the names of the namespaces selected by the DexServerSet, sorted. The namespaces being deleted are left out,
+// their DexServer is deleted with them. The DexServerSet of DexEnabledNamespacesSet only selects the namespaces
+// labeled with DEX_ENABLED_NAMESPACE_LABEL.
+func (r *DexServerSetReconciler) getSelectedNamespaces(dexServerSet *authv1alpha1.DexServerSet, ctx context.Context) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&dexServerSet.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing spec.namespaceSelector")
+	}
+	if r.DexEnabledNamespacesSet != "" && dexServerSet.Name == r.DexEnabledNamespacesSet {
+		enabled, err := labels.NewRequirement(DEX_ENABLED_NAMESPACE_LABEL, selection.Equals, []string{DEX_ENABLED_NAMESPACE_VALUE})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*enabled)
+	}
+	namespaceList := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	namespaces := []string{}
+	for _, namespace := range namespaceList.Items {
+		if namespace.DeletionTimestamp != nil || namespace.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		namespaces = append(namespaces, namespace.Name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// Get the spec of the DexServer of the DexServerSet in a namespace. The issuer is rendered from
+// spec.issuerTemplate, so that each DexServer serves its own host. The ttl of the template is cleared, the
+// DexServerSet would create again a DexServer deleted by its ttl.
+func getDexServerSetDexServerSpec(dexServerSet *authv1alpha1.DexServerSet, namespace string) (*authv1alpha1.DexServerSpec, error) {
+	spec := dexServerSet.Spec.Template.Spec.DeepCopy()
+	spec.TTL = nil
+	if dexServerSet.Spec.IssuerTemplate == "" {
+		return spec, nil
+	}
+	tmpl, err := template.New("issuer").Option("missingkey=error").Parse(dexServerSet.Spec.IssuerTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing spec.issuerTemplate")
+	}
+	issuer := &bytes.Buffer{}
+	if err := tmpl.Execute(issuer, dexServerSetIssuerValues{Name: dexServerSet.Name, Namespace: namespace}); err != nil {
+		return nil, errors.Wrap(err, "error executing spec.issuerTemplate")
+	}
+	spec.Issuer = issuer.String()
+	return spec, nil
+}
+
+// Create or update the DexServer of the DexServerSet in a namespace. Returns nil when a DexServer of the same name
+// not managed by the DexServerSet exists, it is left untouched.
+func (r *DexServerSetReconciler) syncDexServer(dexServerSet *authv1alpha1.DexServerSet, namespace string, ctx context.Context) (*authv1alpha1.DexServer, error) {
+	log := ctrllog.FromContext(ctx)
+	log.Info("syncDexServer", "DexServer.Namespace", namespace, "DexServer.Name", dexServerSet.Name)
+
+	spec, err := getDexServerSetDexServerSpec(dexServerSet, namespace)
+	if err != nil {
+		return nil, err
+	}
+	dexServerLabels := map[string]string{}
+	for key, value := range dexServerSet.Spec.Template.Labels {
+		dexServerLabels[key] = value
+	}
+	dexServerLabels[MANAGED_BY_LABEL] = MANAGED_BY_VALUE
+	dexServerLabels[DEXSERVER_SET_LABEL] = dexServerSet.Name
+
+	dexServer := &authv1alpha1.DexServer{}
+	err = r.Get(ctx, types.NamespacedName{Name: dexServerSet.Name, Namespace: namespace}, dexServer)
+	switch {
+	case err == nil:
+		if !metav1.IsControlledBy(dexServer, dexServerSet) {
+			log.Info("DexServer already exists and is not managed by the DexServerSet", "DexServer.Namespace", namespace, "DexServer.Name", dexServer.Name)
+			return nil, nil
+		}
+		if equality.Semantic.DeepEqual(dexServer.Spec, *spec) && labels.SelectorFromSet(dexServerLabels).Matches(labels.Set(dexServer.Labels)) {
+			return dexServer, nil
+		}
+		dexServer.Spec = *spec
+		if dexServer.Labels == nil {
+			dexServer.Labels = map[string]string{}
+		}
+		for key, value := range dexServerLabels {
+			dexServer.Labels[key] = value
+		}
+		log.Info("Updating DexServer", "DexServer.Namespace", namespace, "DexServer.Name", dexServer.Name)
+		if err := r.Update(ctx, dexServer); err != nil {
+			return nil, err
+		}
+		return dexServer, nil
+	case !kubeerrors.IsNotFound(err):
+		return nil, err
+	}
+
+	dexServer = &authv1alpha1.DexServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dexServerSet.Name,
+			Namespace: namespace,
+			Labels:    dexServerLabels,
+		},
+		Spec: *spec,
+	}
+	if err := ctrl.SetControllerReference(dexServerSet, dexServer, r.Scheme); err != nil {
+		return nil, err
+	}
+	log.Info("Creating a new DexServer", "DexServer.Namespace", namespace, "DexServer.Name", dexServer.Name)
+	if err := r.Create(ctx, dexServer); err != nil {
+		return nil, err
+	}
+	return dexServer, nil
+}
+
+// Delete the DexServers of the DexServerSet in the namespaces no longer selected, e.g. when the preview of a
+// closed pull request is unlabeled before its namespace is deleted
+func (r *DexServerSetReconciler) deleteUnselectedDexServers(dexServerSet *authv1alpha1.DexServerSet, namespaces []string, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	selected := map[string]bool{}
+	for _, namespace := range namespaces {
+		selected[namespace] = true
+	}
+	dexServers := &authv1alpha1.DexServerList{}
+	if err := r.List(ctx, dexServers, client.MatchingLabels{DEXSERVER_SET_LABEL: dexServerSet.Name}); err != nil {
+		return err
+	}
+	for i := range dexServers.Items {
+		dexServer := &dexServers.Items[i]
+		if selected[dexServer.Namespace] || !metav1.IsControlledBy(dexServer, dexServerSet) || dexServer.DeletionTimestamp != nil {
+			continue
+		}
+		log.Info("Deleting DexServer of a namespace no longer selected", "DexServer.Namespace", dexServer.Namespace, "DexServer.Name", dexServer.Name)
+		if err := r.Delete(ctx, dexServer); err != nil && !kubeerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateDexServerSetStatusConditions(c client.Client, dexServerSet *authv1alpha1.DexServerSet, newConditions ...metav1.Condition) error {
+	dexServerSet.Status.Conditions = mergeStatusConditions(dexServerSet.Status.Conditions, newConditions...)
+	return c.Status().Update(context.TODO(), dexServerSet)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DexServerSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// only handle spec changes, the status is updated by this controller
+		For(&authv1alpha1.DexServerSet{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the status of the DexServers is reported in the DexServerSet status
+		Owns(&authv1alpha1.DexServer{}).
+		// the namespaces of the previews come and go, and are selected by their labels, like the namespaces opting in
+		// to the DexServerSet of DexEnabledNamespacesSet
+		Watches(&source.Kind{Type: &corev1.Namespace{}},
+			handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
+				var dexServerSetList authv1alpha1.DexServerSetList
+				_ = mgr.GetClient().List(context.TODO(), &dexServerSetList)
+
+				var requests = []reconcile.Request{}
+				for _, dexServerSet := range dexServerSetList.Items {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: dexServerSet.Name},
+					})
+				}
+				return requests
+			})).
+		Complete(r)
+}
@@ -0,0 +1,146 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Process DexServerSet CR", func() {
+	DexServerSetName := "my-dexserverset"
+	PreviewNamespaces := []string{"my-preview-pr-1", "my-preview-pr-2"}
+	ConflictNamespace := "my-preview-pr-3"
+
+	It("should stamp out a DexServer in each selected namespace", func() {
+		for _, namespace := range append(PreviewNamespaces, ConflictNamespace, "my-preview-unlabeled") {
+			labels := map[string]string{"my-preview": "true"}
+			if namespace == "my-preview-unlabeled" {
+				labels = nil
+			}
+			err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: labels}})
+			Expect(err).To(BeNil())
+		}
+		err := k8sClient.Create(context.TODO(), &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: DexServerSetName, Namespace: ConflictNamespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://unmanaged.testhost.com"},
+		})
+		Expect(err).To(BeNil())
+		dexServerSet := &authv1alpha1.DexServerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: DexServerSetName},
+			Spec: authv1alpha1.DexServerSetSpec{
+				NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"my-preview": "true"}},
+				IssuerTemplate:    "https://dex-{{ .Namespace }}.testhost.com",
+				Template: authv1alpha1.DexServerTemplateSpec{
+					Labels: map[string]string{"team": "web"},
+					Spec: authv1alpha1.DexServerSpec{
+						Issuer: "https://ignored.testhost.com",
+						TTL:    &metav1.Duration{Duration: time.Hour},
+					},
+				},
+			},
+		}
+		err = k8sClient.Create(context.TODO(), dexServerSet)
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = DexServerSetName
+		_, err = rDexServerSet.Reconcile(context.TODO(), req)
+		Expect(err).To(BeNil())
+
+		By("creating the DexServers owned by the DexServerSet with their own issuer", func() {
+			for _, namespace := range PreviewNamespaces {
+				dexServer := &authv1alpha1.DexServer{}
+				err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerSetName, Namespace: namespace}, dexServer)
+				Expect(err).To(BeNil())
+				Expect(dexServer.Spec.Issuer).To(Equal("https://dex-" + namespace + ".testhost.com"))
+				Expect(dexServer.Spec.TTL).To(BeNil())
+				Expect(metav1.IsControlledBy(dexServer, dexServerSet)).To(BeTrue())
+				Expect(dexServer.Labels).To(HaveKeyWithValue(DEXSERVER_SET_LABEL, DexServerSetName))
+				Expect(dexServer.Labels).To(HaveKeyWithValue("team", "web"))
+			}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerSetName, Namespace: "my-preview-unlabeled"}, &authv1alpha1.DexServer{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+		By("leaving the DexServer not owned by the DexServerSet untouched", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerSetName, Namespace: ConflictNamespace}, dexServer)
+			Expect(err).To(BeNil())
+			Expect(dexServer.Spec.Issuer).To(Equal("https://unmanaged.testhost.com"))
+
+			updated := &authv1alpha1.DexServerSet{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerSetName}, updated)
+			Expect(err).To(BeNil())
+			cond := meta.FindStatusCondition(updated.Status.Conditions, authv1alpha1.DexServerSetConditionTypeApplied)
+			Expect(cond).ToNot(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal("DexServerConflict"))
+			Expect(updated.Status.DexServers).To(HaveLen(2))
+			Expect(updated.Status.DexServers[0].Namespace).To(Equal(PreviewNamespaces[0]))
+		})
+		By("deleting the DexServer of a namespace no longer selected", func() {
+			namespace := &corev1.Namespace{}
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: PreviewNamespaces[1]}, namespace)
+			Expect(err).To(BeNil())
+			delete(namespace.Labels, "my-preview")
+			Expect(k8sClient.Update(context.TODO(), namespace)).To(Succeed())
+
+			// the DexServers are also updated by the DexServerReconciler of the manager
+			Eventually(func() error {
+				_, err := rDexServerSet.Reconcile(context.TODO(), req)
+				return err
+			}, 10, 1).Should(Succeed())
+			// the finalizer of the DexServer is removed by the DexServerReconciler of the manager
+			Eventually(func() bool {
+				err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerSetName, Namespace: PreviewNamespaces[1]}, &authv1alpha1.DexServer{})
+				return kubeerrors.IsNotFound(err)
+			}, 10, 1).Should(BeTrue())
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: DexServerSetName, Namespace: PreviewNamespaces[0]}, &authv1alpha1.DexServer{})
+			Expect(err).To(BeNil())
+		})
+	})
+	It("should only stamp out a DexServer in the namespaces opting in with the dex label", func() {
+		namespaces := map[string]map[string]string{
+			"my-team-enabled":     {"my-tenant": "true", DEX_ENABLED_NAMESPACE_LABEL: DEX_ENABLED_NAMESPACE_VALUE},
+			"my-team-not-enabled": {"my-tenant": "true"},
+			"my-system-enabled":   {DEX_ENABLED_NAMESPACE_LABEL: DEX_ENABLED_NAMESPACE_VALUE},
+			"my-team-other-value": {"my-tenant": "true", DEX_ENABLED_NAMESPACE_LABEL: "disabled"},
+		}
+		for namespace, labels := range namespaces {
+			err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: labels}})
+			Expect(err).To(BeNil())
+		}
+		err := k8sClient.Create(context.TODO(), &authv1alpha1.DexServerSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-team-sso"},
+			Spec: authv1alpha1.DexServerSetSpec{
+				NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"my-tenant": "true"}},
+				IssuerTemplate:    "https://dex-{{ .Namespace }}.testhost.com",
+			},
+		})
+		Expect(err).To(BeNil())
+
+		r := DexServerSetReconciler{Client: k8sClient, Scheme: rDexServerSet.Scheme, DexEnabledNamespacesSet: "my-team-sso"}
+		req := ctrl.Request{}
+		req.Name = "my-team-sso"
+		_, err = r.Reconcile(context.TODO(), req)
+		Expect(err).To(BeNil())
+
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: "my-team-sso", Namespace: "my-team-enabled"}, &authv1alpha1.DexServer{})
+		Expect(err).To(BeNil())
+		for _, namespace := range []string{"my-team-not-enabled", "my-system-enabled", "my-team-other-value"} {
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: "my-team-sso", Namespace: namespace}, &authv1alpha1.DexServer{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		}
+	})
+})
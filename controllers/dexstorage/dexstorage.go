@@ -0,0 +1,165 @@
+// Copyright Red Hat
+
+// Package dexstorage copies the objects of a dex storage to another storage. The objects are read and written in the
+// formats of the dex v2.30 kubernetes, postgres and etcd storages (github.com/dexidp/dex storage, Apache License
+// 2.0), without depending on the dex server packages. The auth requests, auth codes and device requests and tokens
+// expire within minutes and are not copied.
+package dexstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+)
+
+// Config is the storage section of a dex config, whose config is decoded by the type of the storage
+type Config struct {
+	Type   string          `json:"type"`
+	Config json.RawMessage `json:"config"`
+}
+
+// Client is an OAuth2 client registered in dex
+type Client struct {
+	ID           string   `json:"id,omitempty"`
+	Secret       string   `json:"secret,omitempty"`
+	RedirectURIs []string `json:"redirectURIs,omitempty"`
+	TrustedPeers []string `json:"trustedPeers,omitempty"`
+	Public       bool     `json:"public"`
+	Name         string   `json:"name,omitempty"`
+	LogoURL      string   `json:"logoURL,omitempty"`
+}
+
+// Claims of the user a refresh token is issued to
+type Claims struct {
+	UserID            string   `json:"userID"`
+	Username          string   `json:"username"`
+	PreferredUsername string   `json:"preferredUsername"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"emailVerified"`
+	Groups            []string `json:"groups,omitempty"`
+}
+
+// RefreshToken is a refresh token issued by dex
+type RefreshToken struct {
+	ID            string
+	Token         string
+	ObsoleteToken string
+	CreatedAt     time.Time
+	LastUsed      time.Time
+	ClientID      string
+	ConnectorID   string
+	ConnectorData []byte
+	Claims        Claims
+	Scopes        []string
+	Nonce         string
+}
+
+// RefreshTokenRef references the refresh token of a client in the offline sessions of a user. dex stores it without
+// JSON tags in all its storages.
+type RefreshTokenRef struct {
+	ID        string
+	ClientID  string
+	CreatedAt time.Time
+	LastUsed  time.Time
+}
+
+// OfflineSessions are the refresh tokens of a user of a connector
+type OfflineSessions struct {
+	UserID        string
+	ConnID        string
+	Refresh       map[string]*RefreshTokenRef
+	ConnectorData []byte
+}
+
+// Password is a user of the static passwords of dex
+type Password struct {
+	Email    string `json:"email"`
+	Hash     []byte `json:"hash"`
+	Username string `json:"username"`
+	UserID   string `json:"userID"`
+}
+
+// Connector is a connector created through the dex API
+type Connector struct {
+	ID              string
+	Type            string
+	Name            string
+	ResourceVersion string
+	Config          []byte
+}
+
+// Keys are the keys dex signs and verifies the ID tokens with. The JSON web keys are copied as they are stored.
+type Keys struct {
+	SigningKey       json.RawMessage
+	SigningKeyPub    json.RawMessage
+	VerificationKeys []VerificationKey
+	NextRotation     time.Time
+}
+
+// VerificationKey is a rotated signing key, still used to verify the tokens it signed until it expires
+type VerificationKey struct {
+	PublicKey json.RawMessage `json:"publicKey"`
+	Expiry    time.Time       `json:"expiry"`
+}
+
+// Objects are the objects copied from a storage to another, Keys is nil when the storage has no keys
+type Objects struct {
+	Clients         []Client
+	RefreshTokens   []RefreshToken
+	OfflineSessions []OfflineSessions
+	Passwords       []Password
+	Connectors      []Connector
+	Keys            *Keys
+}
+
+// Storage is a dex storage the objects are exported from or imported into
+type Storage interface {
+	// Export reads all the objects of the storage
+	Export(ctx context.Context) (*Objects, error)
+	// ExportKeys reads the signing keys of the storage, nil when dex did not generate them yet
+	ExportKeys(ctx context.Context) (*Keys, error)
+	// Import writes the objects into the storage, replacing the objects with the same IDs
+	Import(ctx context.Context, objects *Objects) error
+	Close() error
+}
+
+// Open opens the storage of the storage section of a dex config. The environment variables referenced by the
+// password, e.g. ${DEX_STORAGE_PASSWORD}, are expanded with getenv. The kubernetes storage is read and written through
+// dynamicClient in namespace, the namespace of the dex pod.
+func Open(storage Config, getenv func(string) string, dynamicClient dynamic.Interface, namespace string) (Storage, error) {
+	switch storage.Type {
+	case "kubernetes":
+		return &kubernetesStorage{client: dynamicClient, namespace: namespace}, nil
+	case "postgres":
+		config := PostgresConfig{}
+		if err := json.Unmarshal(storage.Config, &config); err != nil {
+			return nil, fmt.Errorf("invalid postgres storage config: %v", err)
+		}
+		config.Password = os.Expand(config.Password, getenv)
+		return openPostgres(config)
+	case "etcd":
+		config := EtcdConfig{}
+		if err := json.Unmarshal(storage.Config, &config); err != nil {
+			return nil, fmt.Errorf("invalid etcd storage config: %v", err)
+		}
+		config.Password = os.Expand(config.Password, getenv)
+		return openEtcd(config)
+	}
+	return nil, fmt.Errorf("unsupported storage type %q", storage.Type)
+}
+
+// Copy copies all the objects of source to target
+func Copy(ctx context.Context, source Storage, target Storage) error {
+	objects, err := source.Export(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading the source storage: %v", err)
+	}
+	if err := target.Import(ctx, objects); err != nil {
+		return fmt.Errorf("error writing the target storage: %v", err)
+	}
+	return nil
+}
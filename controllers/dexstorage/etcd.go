@@ -0,0 +1,296 @@
+// Copyright Red Hat
+
+package dexstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Keys of the objects of the dex etcd storage, under the namespace of the storage
+const (
+	etcdClientPrefix          = "client/"
+	etcdRefreshTokenPrefix    = "refresh_token/"
+	etcdPasswordPrefix        = "password/"
+	etcdOfflineSessionsPrefix = "offline_session/"
+	etcdConnectorPrefix       = "connector/"
+	etcdKeysName              = "openid-connect-keys"
+)
+
+// EtcdConfig is the config of the dex etcd storage
+type EtcdConfig struct {
+	Endpoints []string `json:"endpoints"`
+	Namespace string   `json:"namespace"`
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+}
+
+type etcdRefreshToken struct {
+	ID            string    `json:"id"`
+	Token         string    `json:"token"`
+	ObsoleteToken string    `json:"obsolete_token"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsed      time.Time `json:"last_used"`
+	ClientID      string    `json:"client_id"`
+	ConnectorID   string    `json:"connector_id"`
+	ConnectorData []byte    `json:"connector_data"`
+	Claims        Claims    `json:"claims"`
+	Scopes        []string  `json:"scopes"`
+	Nonce         string    `json:"nonce"`
+}
+
+type etcdOfflineSessions struct {
+	UserID        string                      `json:"user_id,omitempty"`
+	ConnID        string                      `json:"conn_id,omitempty"`
+	Refresh       map[string]*RefreshTokenRef `json:"refresh,omitempty"`
+	ConnectorData []byte                      `json:"connectorData,omitempty"`
+}
+
+type etcdConnector struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+	// dex stores the config of the connectors under the email key
+	Config []byte `json:"email"`
+}
+
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+// etcdStorage stores the objects as JSON values of etcd. They are read and written through the JSON gateway of the
+// etcd v3 API, which etcd serves on its client endpoints.
+type etcdStorage struct {
+	config EtcdConfig
+	client *http.Client
+	token  string
+}
+
+func openEtcd(config EtcdConfig) (*etcdStorage, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("the etcd storage has no endpoints")
+	}
+	return &etcdStorage{config: config, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (s *etcdStorage) Export(ctx context.Context) (*Objects, error) {
+	objects := &Objects{}
+
+	values, err := s.list(ctx, etcdClientPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		c := Client{}
+		if err := json.Unmarshal(v, &c); err != nil {
+			return nil, fmt.Errorf("error decoding a client: %v", err)
+		}
+		objects.Clients = append(objects.Clients, c)
+	}
+
+	if values, err = s.list(ctx, etcdRefreshTokenPrefix); err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		t := etcdRefreshToken{}
+		if err := json.Unmarshal(v, &t); err != nil {
+			return nil, fmt.Errorf("error decoding a refresh token: %v", err)
+		}
+		objects.RefreshTokens = append(objects.RefreshTokens, RefreshToken(t))
+	}
+
+	if values, err = s.list(ctx, etcdOfflineSessionsPrefix); err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		o := etcdOfflineSessions{}
+		if err := json.Unmarshal(v, &o); err != nil {
+			return nil, fmt.Errorf("error decoding offline sessions: %v", err)
+		}
+		objects.OfflineSessions = append(objects.OfflineSessions, OfflineSessions(o))
+	}
+
+	if values, err = s.list(ctx, etcdPasswordPrefix); err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		p := Password{}
+		if err := json.Unmarshal(v, &p); err != nil {
+			return nil, fmt.Errorf("error decoding a password: %v", err)
+		}
+		objects.Passwords = append(objects.Passwords, p)
+	}
+
+	if values, err = s.list(ctx, etcdConnectorPrefix); err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		c := etcdConnector{}
+		if err := json.Unmarshal(v, &c); err != nil {
+			return nil, fmt.Errorf("error decoding a connector: %v", err)
+		}
+		objects.Connectors = append(objects.Connectors, Connector(c))
+	}
+
+	keys, err := s.ExportKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objects.Keys = keys
+	return objects, nil
+}
+
+func (s *etcdStorage) ExportKeys(ctx context.Context) (*Keys, error) {
+	kvs, err := s.rangeKeys(ctx, []byte(s.config.Namespace+etcdKeysName), nil)
+	if err != nil || len(kvs) == 0 {
+		return nil, err
+	}
+	keys := &Keys{}
+	if err := json.Unmarshal(kvs[0].Value, keys); err != nil {
+		return nil, fmt.Errorf("error decoding the signing keys: %v", err)
+	}
+	return keys, nil
+}
+
+func (s *etcdStorage) Import(ctx context.Context, objects *Objects) error {
+	for _, c := range objects.Clients {
+		if err := s.put(ctx, etcdClientPrefix+c.ID, c); err != nil {
+			return err
+		}
+	}
+	for _, t := range objects.RefreshTokens {
+		if err := s.put(ctx, etcdRefreshTokenPrefix+t.ID, etcdRefreshToken(t)); err != nil {
+			return err
+		}
+	}
+	for _, o := range objects.OfflineSessions {
+		if err := s.put(ctx, etcdOfflineSessionsPrefix+strings.ToLower(o.UserID+"|"+o.ConnID), etcdOfflineSessions(o)); err != nil {
+			return err
+		}
+	}
+	for _, p := range objects.Passwords {
+		p.Email = strings.ToLower(p.Email)
+		if err := s.put(ctx, etcdPasswordPrefix+p.Email, p); err != nil {
+			return err
+		}
+	}
+	for _, c := range objects.Connectors {
+		if err := s.put(ctx, etcdConnectorPrefix+c.ID, etcdConnector(c)); err != nil {
+			return err
+		}
+	}
+	if objects.Keys != nil {
+		if err := s.put(ctx, etcdKeysName, objects.Keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *etcdStorage) Close() error {
+	return nil
+}
+
+// list returns the values of the keys starting with prefix
+func (s *etcdStorage) list(ctx context.Context, prefix string) ([][]byte, error) {
+	key := []byte(s.config.Namespace + prefix)
+	// the range ends after the last key starting with the prefix, like clientv3.WithPrefix
+	end := append([]byte{}, key...)
+	end[len(end)-1]++
+	kvs, err := s.rangeKeys(ctx, key, end)
+	if err != nil {
+		return nil, err
+	}
+	values := [][]byte{}
+	for _, kv := range kvs {
+		values = append(values, kv.Value)
+	}
+	return values, nil
+}
+
+// rangeKeys returns the key values from key to end, or the value of key when end is nil
+func (s *etcdStorage) rangeKeys(ctx context.Context, key []byte, end []byte) ([]etcdKeyValue, error) {
+	req := map[string][]byte{"key": key}
+	if end != nil {
+		req["range_end"] = end
+	}
+	resp := struct {
+		Kvs []etcdKeyValue `json:"kvs"`
+	}{}
+	if err := s.call(ctx, "/v3/kv/range", req, &resp); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", key, err)
+	}
+	return resp.Kvs, nil
+}
+
+func (s *etcdStorage) put(ctx context.Context, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	key = s.config.Namespace + key
+	if err := s.call(ctx, "/v3/kv/put", etcdKeyValue{Key: []byte(key), Value: data}, nil); err != nil {
+		return fmt.Errorf("error writing %s: %v", key, err)
+	}
+	return nil
+}
+
+// call posts req to the JSON gateway, after authenticating when the storage has a username
+func (s *etcdStorage) call(ctx context.Context, path string, req interface{}, resp interface{}) error {
+	if s.config.Username != "" && s.token == "" {
+		auth := struct {
+			Token string `json:"token"`
+		}{}
+		creds := map[string]string{"name": s.config.Username, "password": s.config.Password}
+		if err := s.post(ctx, "/v3/auth/authenticate", creds, &auth); err != nil {
+			return fmt.Errorf("error authenticating as %s: %v", s.config.Username, err)
+		}
+		s.token = auth.Token
+	}
+	return s.post(ctx, path, req, resp)
+}
+
+// post sends req to the first endpoint that answers and decodes its response into resp
+func (s *etcdStorage) post(ctx context.Context, path string, req interface{}, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, endpoint := range s.config.Endpoints {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		if s.token != "" {
+			httpReq.Header.Set("Authorization", s.token)
+		}
+		httpResp, err := s.client.Do(httpReq)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		data, err := ioutil.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if httpResp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: %s", httpResp.Status, strings.TrimSpace(string(data)))
+		}
+		if resp == nil {
+			return nil
+		}
+		return json.Unmarshal(data, resp)
+	}
+	return lastErr
+}
@@ -0,0 +1,314 @@
+// Copyright Red Hat
+
+package dexstorage
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const kubernetesKeysName = "openid-connect-keys"
+
+// Resources of the custom resources of the dex kubernetes storage
+var (
+	kubernetesClientsGVR         = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "oauth2clients"}
+	kubernetesRefreshTokensGVR   = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "refreshtokens"}
+	kubernetesOfflineSessionsGVR = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "offlinesessionses"}
+	kubernetesPasswordsGVR       = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "passwords"}
+	kubernetesConnectorsGVR      = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "connectors"}
+	kubernetesSigningKeysGVR     = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "signingkeies"}
+)
+
+// Encoding of the object names dex derives from the IDs that are not valid kubernetes names
+var kubernetesNameEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567")
+
+func kubernetesName(id string) string {
+	return strings.TrimRight(kubernetesNameEncoding.EncodeToString(fnv.New64().Sum([]byte(id))), "=")
+}
+
+func kubernetesOfflineSessionsName(userID string, connID string) string {
+	h := fnv.New64()
+	h.Write([]byte(userID))
+	h.Write([]byte(connID))
+	return strings.TrimRight(kubernetesNameEncoding.EncodeToString(h.Sum(nil)), "=")
+}
+
+type kubernetesClient struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Client            `json:",inline"`
+}
+
+type kubernetesRefreshToken struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	CreatedAt time.Time
+	LastUsed  time.Time
+
+	ClientID      string   `json:"clientID"`
+	Scopes        []string `json:"scopes,omitempty"`
+	Token         string   `json:"token,omitempty"`
+	ObsoleteToken string   `json:"obsoleteToken,omitempty"`
+	Nonce         string   `json:"nonce,omitempty"`
+	Claims        Claims   `json:"claims,omitempty"`
+	ConnectorID   string   `json:"connectorID,omitempty"`
+	ConnectorData []byte   `json:"connectorData,omitempty"`
+}
+
+type kubernetesOfflineSessions struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	UserID        string                      `json:"userID,omitempty"`
+	ConnID        string                      `json:"connID,omitempty"`
+	Refresh       map[string]*RefreshTokenRef `json:"refresh,omitempty"`
+	ConnectorData []byte                      `json:"connectorData,omitempty"`
+}
+
+type kubernetesPassword struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Password          `json:",inline"`
+}
+
+type kubernetesConnector struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	ID     string `json:"id,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Config []byte `json:"config,omitempty"`
+}
+
+type kubernetesKeys struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	SigningKey       json.RawMessage   `json:"signingKey,omitempty"`
+	SigningKeyPub    json.RawMessage   `json:"signingKeyPub,omitempty"`
+	VerificationKeys []VerificationKey `json:"verificationKeys,omitempty"`
+	NextRotation     time.Time         `json:"nextRotation"`
+}
+
+// kubernetesStorage stores the objects as custom resources of the namespace of dex
+type kubernetesStorage struct {
+	client    dynamic.Interface
+	namespace string
+}
+
+func (s *kubernetesStorage) Export(ctx context.Context) (*Objects, error) {
+	objects := &Objects{}
+
+	clients := []kubernetesClient{}
+	if err := s.list(ctx, kubernetesClientsGVR, &clients); err != nil {
+		return nil, err
+	}
+	for _, c := range clients {
+		objects.Clients = append(objects.Clients, c.Client)
+	}
+
+	refreshTokens := []kubernetesRefreshToken{}
+	if err := s.list(ctx, kubernetesRefreshTokensGVR, &refreshTokens); err != nil {
+		return nil, err
+	}
+	for _, t := range refreshTokens {
+		objects.RefreshTokens = append(objects.RefreshTokens, RefreshToken{
+			ID:            t.Name,
+			Token:         t.Token,
+			ObsoleteToken: t.ObsoleteToken,
+			CreatedAt:     t.CreatedAt,
+			LastUsed:      t.LastUsed,
+			ClientID:      t.ClientID,
+			ConnectorID:   t.ConnectorID,
+			ConnectorData: t.ConnectorData,
+			Claims:        t.Claims,
+			Scopes:        t.Scopes,
+			Nonce:         t.Nonce,
+		})
+	}
+
+	offlineSessions := []kubernetesOfflineSessions{}
+	if err := s.list(ctx, kubernetesOfflineSessionsGVR, &offlineSessions); err != nil {
+		return nil, err
+	}
+	for _, o := range offlineSessions {
+		objects.OfflineSessions = append(objects.OfflineSessions, OfflineSessions{
+			UserID:        o.UserID,
+			ConnID:        o.ConnID,
+			Refresh:       o.Refresh,
+			ConnectorData: o.ConnectorData,
+		})
+	}
+
+	passwords := []kubernetesPassword{}
+	if err := s.list(ctx, kubernetesPasswordsGVR, &passwords); err != nil {
+		return nil, err
+	}
+	for _, p := range passwords {
+		objects.Passwords = append(objects.Passwords, p.Password)
+	}
+
+	connectors := []kubernetesConnector{}
+	if err := s.list(ctx, kubernetesConnectorsGVR, &connectors); err != nil {
+		return nil, err
+	}
+	for _, c := range connectors {
+		objects.Connectors = append(objects.Connectors, Connector{
+			ID:              c.ID,
+			Type:            c.Type,
+			Name:            c.Name,
+			ResourceVersion: c.ResourceVersion,
+			Config:          c.Config,
+		})
+	}
+
+	keys, err := s.ExportKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objects.Keys = keys
+	return objects, nil
+}
+
+func (s *kubernetesStorage) ExportKeys(ctx context.Context) (*Keys, error) {
+	u, err := s.client.Resource(kubernetesSigningKeysGVR).Namespace(s.namespace).Get(ctx, kubernetesKeysName, metav1.GetOptions{})
+	switch {
+	case kubeerrors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("error getting the signing keys: %v", err)
+	}
+	keys := kubernetesKeys{}
+	if err := fromUnstructured(u, &keys); err != nil {
+		return nil, err
+	}
+	return &Keys{
+		SigningKey:       keys.SigningKey,
+		SigningKeyPub:    keys.SigningKeyPub,
+		VerificationKeys: keys.VerificationKeys,
+		NextRotation:     keys.NextRotation,
+	}, nil
+}
+
+func (s *kubernetesStorage) Import(ctx context.Context, objects *Objects) error {
+	for _, c := range objects.Clients {
+		if err := s.put(ctx, kubernetesClientsGVR, "OAuth2Client", kubernetesName(c.ID), &kubernetesClient{Client: c}); err != nil {
+			return err
+		}
+	}
+	for _, t := range objects.RefreshTokens {
+		if err := s.put(ctx, kubernetesRefreshTokensGVR, "RefreshToken", t.ID, &kubernetesRefreshToken{
+			CreatedAt:     t.CreatedAt,
+			LastUsed:      t.LastUsed,
+			ClientID:      t.ClientID,
+			Scopes:        t.Scopes,
+			Token:         t.Token,
+			ObsoleteToken: t.ObsoleteToken,
+			Nonce:         t.Nonce,
+			Claims:        t.Claims,
+			ConnectorID:   t.ConnectorID,
+			ConnectorData: t.ConnectorData,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, o := range objects.OfflineSessions {
+		if err := s.put(ctx, kubernetesOfflineSessionsGVR, "OfflineSessions", kubernetesOfflineSessionsName(o.UserID, o.ConnID), &kubernetesOfflineSessions{
+			UserID:        o.UserID,
+			ConnID:        o.ConnID,
+			Refresh:       o.Refresh,
+			ConnectorData: o.ConnectorData,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, p := range objects.Passwords {
+		p.Email = strings.ToLower(p.Email)
+		if err := s.put(ctx, kubernetesPasswordsGVR, "Password", kubernetesName(p.Email), &kubernetesPassword{Password: p}); err != nil {
+			return err
+		}
+	}
+	for _, c := range objects.Connectors {
+		if err := s.put(ctx, kubernetesConnectorsGVR, "Connector", c.ID, &kubernetesConnector{
+			ID:     c.ID,
+			Type:   c.Type,
+			Name:   c.Name,
+			Config: c.Config,
+		}); err != nil {
+			return err
+		}
+	}
+	if objects.Keys != nil {
+		if err := s.put(ctx, kubernetesSigningKeysGVR, "SigningKey", kubernetesKeysName, &kubernetesKeys{
+			SigningKey:       objects.Keys.SigningKey,
+			SigningKeyPub:    objects.Keys.SigningKeyPub,
+			VerificationKeys: objects.Keys.VerificationKeys,
+			NextRotation:     objects.Keys.NextRotation,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *kubernetesStorage) Close() error {
+	return nil
+}
+
+// list decodes the custom resources of gvr into items, a pointer to a slice
+func (s *kubernetesStorage) list(ctx context.Context, gvr schema.GroupVersionResource, items interface{}) error {
+	list, err := s.client.Resource(gvr).Namespace(s.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing the %s: %v", gvr.Resource, err)
+	}
+	data, err := json.Marshal(list.UnstructuredContent()["items"])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, items)
+}
+
+// put creates the custom resource name of gvr, or replaces it when it exists
+func (s *kubernetesStorage) put(ctx context.Context, gvr schema.GroupVersionResource, kind string, name string, object interface{}) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	if err := json.Unmarshal(data, &u.Object); err != nil {
+		return err
+	}
+	u.SetAPIVersion(gvr.GroupVersion().String())
+	u.SetKind(kind)
+	u.SetName(name)
+	u.SetNamespace(s.namespace)
+
+	client := s.client.Resource(gvr).Namespace(s.namespace)
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case kubeerrors.IsNotFound(err):
+		_, err = client.Create(ctx, u, metav1.CreateOptions{})
+	case err == nil:
+		u.SetResourceVersion(existing.GetResourceVersion())
+		_, err = client.Update(ctx, u, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error writing the %s %s: %v", gvr.Resource, name, err)
+	}
+	return nil
+}
+
+func fromUnstructured(u *unstructured.Unstructured, object interface{}) error {
+	data, err := u.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, object)
+}
@@ -0,0 +1,546 @@
+// Copyright Red Hat
+
+package dexstorage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	// registers the postgres driver of database/sql
+	_ "github.com/lib/pq"
+)
+
+// PostgresDriver is the database/sql driver the postgres storages are opened with
+var PostgresDriver = "postgres"
+
+// PostgresConfig is the config of the dex postgres storage
+type PostgresConfig struct {
+	Host     string `json:"host"`
+	Port     int32  `json:"port"`
+	Database string `json:"database"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	SSL      struct {
+		Mode string `json:"mode"`
+	} `json:"ssl"`
+}
+
+// The migrations of the postgres schema of dex v2.30, copied from dex storage/sql/migrate.go. They create the tables
+// of an empty database before the objects are imported. dex numbers them in the migrations table and applies the
+// migrations of its later versions on startup.
+var postgresMigrations = [][]string{
+	{
+		`
+		create table client (
+			id text not null primary key,
+			secret text not null,
+			redirect_uris bytea not null, -- JSON array of strings
+			trusted_peers bytea not null, -- JSON array of strings
+			public boolean not null,
+			name text not null,
+			logo_url text not null
+		);`,
+		`
+		create table auth_request (
+			id text not null primary key,
+			client_id text not null,
+			response_types bytea not null, -- JSON array of strings
+			scopes bytea not null,         -- JSON array of strings
+			redirect_uri text not null,
+			nonce text not null,
+			state text not null,
+			force_approval_prompt boolean not null,
+
+			logged_in boolean not null,
+
+			claims_user_id text not null,
+			claims_username text not null,
+			claims_email text not null,
+			claims_email_verified boolean not null,
+			claims_groups bytea not null, -- JSON array of strings
+
+			connector_id text not null,
+			connector_data bytea,
+
+			expiry timestamptz not null
+		);`,
+		`
+		create table auth_code (
+			id text not null primary key,
+			client_id text not null,
+			scopes bytea not null, -- JSON array of strings
+			nonce text not null,
+			redirect_uri text not null,
+
+			claims_user_id text not null,
+			claims_username text not null,
+			claims_email text not null,
+			claims_email_verified boolean not null,
+			claims_groups bytea not null, -- JSON array of strings
+
+			connector_id text not null,
+			connector_data bytea,
+
+			expiry timestamptz not null
+		);`,
+		`
+		create table refresh_token (
+			id text not null primary key,
+			client_id text not null,
+			scopes bytea not null, -- JSON array of strings
+			nonce text not null,
+
+			claims_user_id text not null,
+			claims_username text not null,
+			claims_email text not null,
+			claims_email_verified boolean not null,
+			claims_groups bytea not null, -- JSON array of strings
+
+			connector_id text not null,
+			connector_data bytea
+		);`,
+		`
+		create table password (
+			email text not null primary key,
+			hash bytea not null,
+			username text not null,
+			user_id text not null
+		);`,
+		`
+		-- keys is a weird table because we only ever expect there to be a single row
+		create table keys (
+			id text not null primary key,
+			verification_keys bytea not null, -- JSON array
+			signing_key bytea not null,       -- JSON object
+			signing_key_pub bytea not null,   -- JSON object
+			next_rotation timestamptz not null
+		);`,
+	},
+	{
+		`
+		alter table refresh_token
+			add column token text not null default '';`,
+		`
+		alter table refresh_token
+			add column created_at timestamptz not null default '0001-01-01 00:00:00 UTC';`,
+		`
+		alter table refresh_token
+			add column last_used timestamptz not null default '0001-01-01 00:00:00 UTC';`,
+	},
+	{
+		`
+		create table offline_session (
+			user_id text not null,
+			conn_id text not null,
+			refresh bytea not null,
+			PRIMARY KEY (user_id, conn_id)
+		);`,
+	},
+	{
+		`
+		create table connector (
+			id text not null primary key,
+			type text not null,
+			name text not null,
+			resource_version text not null,
+			config bytea
+		);`,
+	},
+	{
+		`
+		alter table auth_code
+			add column claims_preferred_username text not null default '';`,
+		`
+		alter table auth_request
+			add column claims_preferred_username text not null default '';`,
+		`
+		alter table refresh_token
+			add column claims_preferred_username text not null default '';`,
+	},
+	{
+		`
+		alter table offline_session
+			add column connector_data bytea;
+		`,
+	},
+	{
+		`
+		create table device_request (
+			user_code text not null primary key,
+			device_code text not null,
+			client_id text not null,
+			client_secret text ,
+			scopes bytea not null, -- JSON array of strings
+			expiry timestamptz not null
+		);`,
+		`
+		create table device_token (
+			device_code text not null primary key,
+			status text not null,
+			token bytea,
+			expiry timestamptz not null,
+			last_request timestamptz not null,
+			poll_interval integer not null
+		);`,
+	},
+	{
+		`
+		alter table auth_request
+			add column code_challenge text not null default '';`,
+		`
+		alter table auth_request
+			add column code_challenge_method text not null default '';`,
+		`
+		alter table auth_code
+			add column code_challenge text not null default '';`,
+		`
+		alter table auth_code
+			add column code_challenge_method text not null default '';`,
+	},
+	{
+		`
+		alter table refresh_token
+			add column obsolete_token text default '';`,
+	},
+}
+
+// postgresStorage stores the objects in the tables of the dex postgres storage
+type postgresStorage struct {
+	db *sql.DB
+}
+
+func openPostgres(config PostgresConfig) (*postgresStorage, error) {
+	// the values are quoted the way lib/pq parses them, see https://www.postgresql.org/docs/current/libpq-connect.html
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	}
+	dsn := []string{"connect_timeout=15"}
+	for _, kv := range [][2]string{
+		{"host", config.Host},
+		{"dbname", config.Database},
+		{"user", config.User},
+		{"password", config.Password},
+		{"sslmode", config.SSL.Mode},
+	} {
+		if kv[1] != "" {
+			dsn = append(dsn, kv[0]+"="+quote(kv[1]))
+		}
+	}
+	if config.Port != 0 {
+		dsn = append(dsn, fmt.Sprintf("port=%d", config.Port))
+	}
+	db, err := sql.Open(PostgresDriver, strings.Join(dsn, " "))
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStorage{db: db}, nil
+}
+
+func (s *postgresStorage) Export(ctx context.Context) (*Objects, error) {
+	objects := &Objects{}
+
+	rows, err := s.db.QueryContext(ctx, `select id, secret, redirect_uris, trusted_peers, public, name, logo_url from client;`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the clients: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		c := Client{}
+		var redirectURIs, trustedPeers []byte
+		if err := rows.Scan(&c.ID, &c.Secret, &redirectURIs, &trustedPeers, &c.Public, &c.Name, &c.LogoURL); err != nil {
+			return err
+		}
+		if err := decodeJSON(redirectURIs, &c.RedirectURIs); err != nil {
+			return err
+		}
+		if err := decodeJSON(trustedPeers, &c.TrustedPeers); err != nil {
+			return err
+		}
+		objects.Clients = append(objects.Clients, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the clients: %v", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		select
+			id, client_id, scopes, nonce,
+			claims_user_id, claims_username, claims_preferred_username,
+			claims_email, claims_email_verified, claims_groups,
+			connector_id, connector_data,
+			token, obsolete_token, created_at, last_used
+		from refresh_token;`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the refresh tokens: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		t := RefreshToken{}
+		var scopes, groups []byte
+		var obsoleteToken sql.NullString
+		if err := rows.Scan(&t.ID, &t.ClientID, &scopes, &t.Nonce,
+			&t.Claims.UserID, &t.Claims.Username, &t.Claims.PreferredUsername,
+			&t.Claims.Email, &t.Claims.EmailVerified, &groups,
+			&t.ConnectorID, &t.ConnectorData,
+			&t.Token, &obsoleteToken, &t.CreatedAt, &t.LastUsed); err != nil {
+			return err
+		}
+		t.ObsoleteToken = obsoleteToken.String
+		if err := decodeJSON(scopes, &t.Scopes); err != nil {
+			return err
+		}
+		if err := decodeJSON(groups, &t.Claims.Groups); err != nil {
+			return err
+		}
+		objects.RefreshTokens = append(objects.RefreshTokens, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the refresh tokens: %v", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `select user_id, conn_id, refresh, connector_data from offline_session;`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the offline sessions: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		o := OfflineSessions{}
+		var refresh []byte
+		if err := rows.Scan(&o.UserID, &o.ConnID, &refresh, &o.ConnectorData); err != nil {
+			return err
+		}
+		if err := decodeJSON(refresh, &o.Refresh); err != nil {
+			return err
+		}
+		objects.OfflineSessions = append(objects.OfflineSessions, o)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the offline sessions: %v", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `select email, hash, username, user_id from password;`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the passwords: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		p := Password{}
+		if err := rows.Scan(&p.Email, &p.Hash, &p.Username, &p.UserID); err != nil {
+			return err
+		}
+		objects.Passwords = append(objects.Passwords, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the passwords: %v", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `select id, type, name, resource_version, config from connector;`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the connectors: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		c := Connector{}
+		if err := rows.Scan(&c.ID, &c.Type, &c.Name, &c.ResourceVersion, &c.Config); err != nil {
+			return err
+		}
+		objects.Connectors = append(objects.Connectors, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the connectors: %v", err)
+	}
+
+	keys, err := s.ExportKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objects.Keys = keys
+	return objects, nil
+}
+
+func (s *postgresStorage) ExportKeys(ctx context.Context) (*Keys, error) {
+	var keys *Keys
+	rows, err := s.db.QueryContext(ctx, `select verification_keys, signing_key, signing_key_pub, next_rotation from keys where id = 'keys';`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the signing keys: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		keys = &Keys{}
+		var verificationKeys, signingKey, signingKeyPub []byte
+		if err := rows.Scan(&verificationKeys, &signingKey, &signingKeyPub, &keys.NextRotation); err != nil {
+			return err
+		}
+		keys.SigningKey = signingKey
+		keys.SigningKeyPub = signingKeyPub
+		return decodeJSON(verificationKeys, &keys.VerificationKeys)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the signing keys: %v", err)
+	}
+	return keys, nil
+}
+
+// Import creates the tables of dex when the database is empty, then writes the objects in a single transaction
+func (s *postgresStorage) Import(ctx context.Context, objects *Objects) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := migratePostgres(ctx, tx); err != nil {
+		return err
+	}
+
+	for _, c := range objects.Clients {
+		_, err := tx.ExecContext(ctx, `
+			insert into client (id, secret, redirect_uris, trusted_peers, public, name, logo_url)
+			values ($1, $2, $3, $4, $5, $6, $7)
+			on conflict (id) do update set
+				secret = excluded.secret, redirect_uris = excluded.redirect_uris, trusted_peers = excluded.trusted_peers,
+				public = excluded.public, name = excluded.name, logo_url = excluded.logo_url;`,
+			c.ID, c.Secret, encodeJSON(c.RedirectURIs), encodeJSON(c.TrustedPeers), c.Public, c.Name, c.LogoURL)
+		if err != nil {
+			return fmt.Errorf("error writing the client %s: %v", c.ID, err)
+		}
+	}
+	for _, t := range objects.RefreshTokens {
+		_, err := tx.ExecContext(ctx, `
+			insert into refresh_token (
+				id, client_id, scopes, nonce,
+				claims_user_id, claims_username, claims_preferred_username,
+				claims_email, claims_email_verified, claims_groups,
+				connector_id, connector_data,
+				token, obsolete_token, created_at, last_used
+			)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			on conflict (id) do update set
+				client_id = excluded.client_id, scopes = excluded.scopes, nonce = excluded.nonce,
+				claims_user_id = excluded.claims_user_id, claims_username = excluded.claims_username,
+				claims_preferred_username = excluded.claims_preferred_username,
+				claims_email = excluded.claims_email, claims_email_verified = excluded.claims_email_verified,
+				claims_groups = excluded.claims_groups,
+				connector_id = excluded.connector_id, connector_data = excluded.connector_data,
+				token = excluded.token, obsolete_token = excluded.obsolete_token,
+				created_at = excluded.created_at, last_used = excluded.last_used;`,
+			t.ID, t.ClientID, encodeJSON(t.Scopes), t.Nonce,
+			t.Claims.UserID, t.Claims.Username, t.Claims.PreferredUsername,
+			t.Claims.Email, t.Claims.EmailVerified, encodeJSON(t.Claims.Groups),
+			t.ConnectorID, t.ConnectorData,
+			t.Token, t.ObsoleteToken, t.CreatedAt, t.LastUsed)
+		if err != nil {
+			return fmt.Errorf("error writing the refresh token %s: %v", t.ID, err)
+		}
+	}
+	for _, o := range objects.OfflineSessions {
+		_, err := tx.ExecContext(ctx, `
+			insert into offline_session (user_id, conn_id, refresh, connector_data)
+			values ($1, $2, $3, $4)
+			on conflict (user_id, conn_id) do update set
+				refresh = excluded.refresh, connector_data = excluded.connector_data;`,
+			o.UserID, o.ConnID, encodeJSON(o.Refresh), o.ConnectorData)
+		if err != nil {
+			return fmt.Errorf("error writing the offline sessions of %s: %v", o.UserID, err)
+		}
+	}
+	for _, p := range objects.Passwords {
+		_, err := tx.ExecContext(ctx, `
+			insert into password (email, hash, username, user_id)
+			values ($1, $2, $3, $4)
+			on conflict (email) do update set
+				hash = excluded.hash, username = excluded.username, user_id = excluded.user_id;`,
+			strings.ToLower(p.Email), p.Hash, p.Username, p.UserID)
+		if err != nil {
+			return fmt.Errorf("error writing the password of %s: %v", p.Email, err)
+		}
+	}
+	for _, c := range objects.Connectors {
+		_, err := tx.ExecContext(ctx, `
+			insert into connector (id, type, name, resource_version, config)
+			values ($1, $2, $3, $4, $5)
+			on conflict (id) do update set
+				type = excluded.type, name = excluded.name, resource_version = excluded.resource_version,
+				config = excluded.config;`,
+			c.ID, c.Type, c.Name, c.ResourceVersion, c.Config)
+		if err != nil {
+			return fmt.Errorf("error writing the connector %s: %v", c.ID, err)
+		}
+	}
+	if keys := objects.Keys; keys != nil {
+		_, err := tx.ExecContext(ctx, `
+			insert into keys (id, verification_keys, signing_key, signing_key_pub, next_rotation)
+			values ('keys', $1, $2, $3, $4)
+			on conflict (id) do update set
+				verification_keys = excluded.verification_keys, signing_key = excluded.signing_key,
+				signing_key_pub = excluded.signing_key_pub, next_rotation = excluded.next_rotation;`,
+			encodeJSON(keys.VerificationKeys), encodeJSON(keys.SigningKey), encodeJSON(keys.SigningKeyPub), keys.NextRotation)
+		if err != nil {
+			return fmt.Errorf("error writing the signing keys: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *postgresStorage) Close() error {
+	return s.db.Close()
+}
+
+// migratePostgres applies the migrations of dex v2.30 that are not applied yet, the way dex does on startup
+func migratePostgres(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		create table if not exists migrations (
+			num integer not null,
+			at timestamptz not null
+		);`)
+	if err != nil {
+		return fmt.Errorf("error creating the migrations table: %v", err)
+	}
+	var num sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `select max(num) from migrations;`).Scan(&num); err != nil {
+		return fmt.Errorf("error reading the migrations table: %v", err)
+	}
+	for n := int(num.Int64); n < len(postgresMigrations); n++ {
+		for i, stmt := range postgresMigrations[n] {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("migration %d statement %d failed: %v", n+1, i+1, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `insert into migrations (num, at) values ($1, now());`, n+1); err != nil {
+			return fmt.Errorf("error updating the migrations table: %v", err)
+		}
+	}
+	return nil
+}
+
+func scanRows(rows *sql.Rows, scan func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// jsonValue is the value of a JSON column, encoded like dex does
+type jsonValue struct {
+	v interface{}
+}
+
+func (j jsonValue) Value() (driver.Value, error) {
+	return json.Marshal(j.v)
+}
+
+func encodeJSON(v interface{}) jsonValue {
+	return jsonValue{v}
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,247 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/dexstorage"
+)
+
+// recordingDriver is a database/sql driver recording the statements run on a postgres storage. The migrations table
+// is empty and the queries return no rows.
+type recordingDriver struct {
+	mu         sync.Mutex
+	dsn        string
+	statements []recordedStatement
+}
+
+type recordedStatement struct {
+	query string
+	args  []driver.Value
+}
+
+var sqlRecorder = &recordingDriver{}
+
+func init() {
+	sql.Register("recording", sqlRecorder)
+}
+
+func (d *recordingDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsn = dsn
+	return &recordingConn{d}, nil
+}
+
+func (d *recordingDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsn = ""
+	d.statements = nil
+}
+
+// inserts returns the arguments of the inserts into table
+func (d *recordingDriver) inserts(table string) [][]driver.Value {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	inserts := [][]driver.Value{}
+	for _, s := range d.statements {
+		if strings.HasPrefix(strings.TrimSpace(s.query), "insert into "+table+" ") {
+			inserts = append(inserts, s.args)
+		}
+	}
+	return inserts
+}
+
+func (d *recordingDriver) executed(query string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, s := range d.statements {
+		if strings.Contains(s.query, query) {
+			return true
+		}
+	}
+	return false
+}
+
+type recordingConn struct {
+	d *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *recordingConn) Close() error                              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *recordingConn) Commit() error                             { return nil }
+func (c *recordingConn) Rollback() error                           { return nil }
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := []driver.Value{}
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.statements = append(c.d.statements, recordedStatement{query: query, args: values})
+	return driver.RowsAffected(1), nil
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "select max(num) from migrations") {
+		return &recordingRows{columns: []string{"max"}, rows: [][]driver.Value{{nil}}}, nil
+	}
+	return &recordingRows{}, nil
+}
+
+type recordingRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string { return r.columns }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// newDexStorageClient returns a fake dynamic client serving the custom resources of the dex kubernetes storage
+func newDexStorageClient(objects ...string) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for resource, kind := range map[string]string{
+		"oauth2clients":     "OAuth2ClientList",
+		"refreshtokens":     "RefreshTokenList",
+		"offlinesessionses": "OfflineSessionsList",
+		"passwords":         "PasswordList",
+		"connectors":        "ConnectorList",
+		"signingkeies":      "SigningKeyList",
+	} {
+		listKinds[schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: resource}] = kind
+	}
+	objs := []runtime.Object{}
+	for _, o := range objects {
+		u := &unstructured.Unstructured{}
+		Expect(u.UnmarshalJSON([]byte(o))).To(Succeed())
+		objs = append(objs, u)
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objs...)
+}
+
+var _ = Describe("Copy the objects of a dex storage", func() {
+	It("should copy the objects of the kubernetes storage to a postgres storage", func() {
+		ns := "my-copied-storage-ns"
+		kubeClient := newDexStorageClient(
+			`{"apiVersion": "dex.coreos.com/v1", "kind": "OAuth2Client",
+			  "metadata": {"name": "nvxwyztdnfwgsztooqaphylx", "namespace": "`+ns+`"},
+			  "id": "my-client", "secret": "my-client-secret", "redirectURIs": ["https://my-app.testhost.com/callback"],
+			  "public": false, "name": "My app"}`,
+			`{"apiVersion": "dex.coreos.com/v1", "kind": "RefreshToken",
+			  "metadata": {"name": "my-refresh-token", "namespace": "`+ns+`"},
+			  "CreatedAt": "2026-10-01T08:00:00Z", "LastUsed": "2026-10-02T08:00:00Z",
+			  "clientID": "my-client", "scopes": ["openid", "offline_access"], "token": "my-token",
+			  "claims": {"userID": "my-user-id", "username": "jane", "preferredUsername": "", "email": "jane@testhost.com",
+			             "emailVerified": true, "groups": ["admins"]},
+			  "connectorID": "github", "connectorData": "e30="}`,
+			`{"apiVersion": "dex.coreos.com/v1", "kind": "Password",
+			  "metadata": {"name": "my-password", "namespace": "`+ns+`"},
+			  "email": "Jane@testhost.com", "hash": "JDJhJDEwJA==", "username": "jane", "userID": "my-user-id"}`,
+			`{"apiVersion": "dex.coreos.com/v1", "kind": "SigningKey",
+			  "metadata": {"name": "openid-connect-keys", "namespace": "`+ns+`"},
+			  "signingKey": {"kty": "RSA", "kid": "my-key", "n": "AQAB", "e": "AQAB", "d": "AQAB"},
+			  "signingKeyPub": {"kty": "RSA", "kid": "my-key", "n": "AQAB", "e": "AQAB"},
+			  "verificationKeys": [{"publicKey": {"kty": "RSA", "kid": "my-old-key", "n": "AQAB", "e": "AQAB"},
+			                        "expiry": "2026-10-03T08:00:00Z"}],
+			  "nextRotation": "2026-10-02T14:00:00Z"}`,
+		)
+
+		// the Job reads the storage configs of the ConfigMap of the migration
+		configMap, err := getStorageMigrationConfigMap(&authv1alpha1.DexStorageMigration{
+			Spec: authv1alpha1.DexStorageMigrationSpec{
+				Target: authv1alpha1.StorageSpec{
+					Type:     authv1alpha1.StorageTypePostgres,
+					Postgres: &authv1alpha1.PostgresStorageSpec{Host: "postgres.testhost.com", Database: "dex", User: "dex"},
+				},
+			},
+			Status: authv1alpha1.DexStorageMigrationStatus{Source: &authv1alpha1.StorageSpec{}},
+		})
+		Expect(err).To(BeNil())
+		dir, err := ioutil.TempDir("", "dex-migration")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		for file, env := range map[string]string{"source.yaml": "DEX_MIGRATION_SOURCE", "target.yaml": "DEX_MIGRATION_TARGET"} {
+			Expect(ioutil.WriteFile(filepath.Join(dir, file), []byte(configMap.Data[file]), 0600)).To(Succeed())
+			os.Setenv(env, filepath.Join(dir, file))
+			defer os.Unsetenv(env)
+		}
+		os.Setenv(STORAGE_MIGRATION_TARGET_PASSWORD_ENV_VAR, "my-postgres-password")
+		defer os.Unsetenv(STORAGE_MIGRATION_TARGET_PASSWORD_ENV_VAR)
+		dexstorage.PostgresDriver = "recording"
+		defer func() { dexstorage.PostgresDriver = "postgres" }()
+		sqlRecorder.reset()
+
+		err = RunStorageMigration(context.TODO(), kubeClient, ns)
+		Expect(err).To(BeNil())
+
+		Expect(sqlRecorder.dsn).To(ContainSubstring("host='postgres.testhost.com'"))
+		Expect(sqlRecorder.dsn).To(ContainSubstring("password='my-postgres-password'"))
+		Expect(sqlRecorder.dsn).To(ContainSubstring("sslmode='verify-full'"))
+		By("creating the tables of dex", func() {
+			Expect(sqlRecorder.executed("create table client")).To(BeTrue())
+			Expect(sqlRecorder.executed("add column obsolete_token")).To(BeTrue())
+			Expect(sqlRecorder.inserts("migrations")).To(HaveLen(9))
+		})
+		By("copying the clients", func() {
+			clients := sqlRecorder.inserts("client")
+			Expect(clients).To(HaveLen(1))
+			Expect(clients[0][:3]).To(Equal([]driver.Value{"my-client", "my-client-secret", []byte(`["https://my-app.testhost.com/callback"]`)}))
+			Expect(clients[0][5]).To(Equal("My app"))
+		})
+		By("copying the refresh tokens", func() {
+			tokens := sqlRecorder.inserts("refresh_token")
+			Expect(tokens).To(HaveLen(1))
+			Expect(tokens[0][:4]).To(Equal([]driver.Value{"my-refresh-token", "my-client", []byte(`["openid","offline_access"]`), ""}))
+			Expect(tokens[0][4:10]).To(Equal([]driver.Value{"my-user-id", "jane", "", "jane@testhost.com", true, []byte(`["admins"]`)}))
+			Expect(tokens[0][10:13]).To(Equal([]driver.Value{"github", []byte("{}"), "my-token"}))
+		})
+		By("copying the passwords with their lower case email", func() {
+			passwords := sqlRecorder.inserts("password")
+			Expect(passwords).To(HaveLen(1))
+			Expect(passwords[0]).To(Equal([]driver.Value{"jane@testhost.com", []byte("$2a$10$"), "jane", "my-user-id"}))
+		})
+		By("copying the signing keys", func() {
+			keys := sqlRecorder.inserts("keys")
+			Expect(keys).To(HaveLen(1))
+			verificationKeys := []map[string]interface{}{}
+			Expect(json.Unmarshal(keys[0][0].([]byte), &verificationKeys)).To(Succeed())
+			Expect(verificationKeys).To(HaveLen(1))
+			Expect(verificationKeys[0]["publicKey"]).To(HaveKeyWithValue("kid", "my-old-key"))
+			signingKey := map[string]interface{}{}
+			Expect(json.Unmarshal(keys[0][1].([]byte), &signingKey)).To(Succeed())
+			Expect(signingKey).To(HaveKeyWithValue("d", "AQAB"))
+		})
+		Expect(sqlRecorder.inserts("offline_session")).To(BeEmpty())
+		Expect(sqlRecorder.inserts("connector")).To(BeEmpty())
+	})
+})
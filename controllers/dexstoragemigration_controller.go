@@ -0,0 +1,398 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/dexstorage"
+	"github.com/identitatem/dex-operator/controllers/tracing"
+)
+
+const (
+	// Finalizer restarting dex when a DexStorageMigration is deleted before it is over
+	STORAGE_MIGRATION_FINALIZER = "auth.identitatem.io/storagemigration-cleanup"
+	// Suffix of the names of the ConfigMap and the Job of a DexStorageMigration
+	STORAGE_MIGRATION_SUFFIX = "-storage-migration"
+	// Directory the storage configs of the ConfigMap are mounted in the Job
+	STORAGE_MIGRATION_DIR = "/etc/dex-migration"
+	// Environment variables of the passwords of the storages, referenced by their configs
+	STORAGE_MIGRATION_SOURCE_PASSWORD_ENV_VAR = "DEX_SOURCE_STORAGE_PASSWORD"
+	STORAGE_MIGRATION_TARGET_PASSWORD_ENV_VAR = "DEX_TARGET_STORAGE_PASSWORD"
+	// Interval the stop of the dex pods is checked at
+	STORAGE_MIGRATION_STOPPING_INTERVAL = 5 * time.Second
+	// Command of the operator copying the objects in the Job, see RunStorageMigration
+	STORAGE_MIGRATION_COMMAND = "migrate-storage"
+)
+
+// DexStorageMigrationReconciler reconciles a DexStorageMigration object. The objects are copied by a Job running
+// Image, which reads the storage section of a dex config for each storage: see the README for the contract of the
+// image.
+type DexStorageMigrationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Image of the Job copying the objects, the image of the operator unless --storage-migration-image is set. The
+	// migrations fail when it is not set.
+	Image string
+	// Command of the Job, the migrate-storage command of the operator when Image is the operator image. The
+	// entrypoint of Image is run when nil.
+	Command []string
+}
+
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexstoragemigrations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexstoragemigrations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=auth.identitatem.io,resources=dexstoragemigrations/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+// Reconcile moves a DexStorageMigration through its phases: the dex pods of the DexServer are stopped, the Job copies
+// the objects of the storage of the DexServer to the target storage, then the DexServer is switched to the target
+// storage and dex is started again. The DexServer keeps its storage when the Job fails.
+func (r *DexStorageMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	log.V(1).Info("Reconciling...")
+	ctx, span := tracing.Start(ctx, "DexStorageMigration.Reconcile", "namespace", req.Namespace, "name", req.Name)
+	defer span.End()
+
+	migration := &authv1alpha1.DexStorageMigration{}
+	if err := r.Get(ctx, req.NamespacedName, migration); err != nil {
+		log.Error(err, "failed to fetch DexStorageMigration instance")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if migration.DeletionTimestamp != nil {
+		if err := r.resumeDexServer(migration, ctx, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(migration, STORAGE_MIGRATION_FINALIZER)
+		return ctrl.Result{}, r.Update(ctx, migration)
+	}
+	switch migration.Status.Phase {
+	case authv1alpha1.DexStorageMigrationPhaseSucceeded, authv1alpha1.DexStorageMigrationPhaseFailed:
+		return ctrl.Result{}, nil
+	}
+	if !controllerutil.ContainsFinalizer(migration, STORAGE_MIGRATION_FINALIZER) {
+		controllerutil.AddFinalizer(migration, STORAGE_MIGRATION_FINALIZER)
+		if err := r.Update(ctx, migration); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	dexServer := &authv1alpha1.DexServer{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migration.Spec.DexServerName, Namespace: migration.Namespace}, dexServer); err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return ctrl.Result{}, r.failMigration(migration, ctx, "DexServerNotFound",
+				fmt.Sprintf("DexServer %s not found", migration.Spec.DexServerName))
+		}
+		return ctrl.Result{}, err
+	}
+
+	switch migration.Status.Phase {
+	case "":
+		return r.startMigration(migration, dexServer, ctx)
+	case authv1alpha1.DexStorageMigrationPhaseStopping:
+		return r.runMigration(migration, dexServer, ctx)
+	default:
+		return r.completeMigration(migration, dexServer, ctx)
+	}
+}
+
+// startMigration records the storage of the DexServer as the source of the migration, and stops dex
+func (r *DexStorageMigrationReconciler) startMigration(migration *authv1alpha1.DexStorageMigration, dexServer *authv1alpha1.DexServer, ctx context.Context) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	if r.Image == "" {
+		return ctrl.Result{}, r.failMigration(migration, ctx, "MigrationImageNotConfigured",
+			"the image of the operator pod is not found, and the operator is not started with --storage-migration-image")
+	}
+	if errs := authv1alpha1.ValidateStorageSpec(&migration.Spec.Target, field.NewPath("spec", "target")); len(errs) > 0 {
+		return ctrl.Result{}, r.failMigration(migration, ctx, "InvalidTarget", errs.ToAggregate().Error())
+	}
+	if other := dexServer.Annotations[STORAGE_MIGRATION_ANNOTATION]; other != "" && other != migration.Name {
+		return ctrl.Result{}, r.failMigration(migration, ctx, "MigrationInProgress",
+			fmt.Sprintf("the storage of DexServer %s is being migrated by %s", dexServer.Name, other))
+	}
+	if equality.Semantic.DeepEqual(getDexStorage(dexServer.Spec.Storage, ""), getDexStorage(migration.Spec.Target, "")) {
+		return ctrl.Result{}, r.failMigration(migration, ctx, "SameStorage",
+			fmt.Sprintf("DexServer %s already uses the target storage", dexServer.Name))
+	}
+
+	log.Info("Stopping dex to migrate its storage", "DexServer.Name", dexServer.Name)
+	patch := client.MergeFrom(dexServer.DeepCopy())
+	if dexServer.Annotations == nil {
+		dexServer.Annotations = map[string]string{}
+	}
+	dexServer.Annotations[STORAGE_MIGRATION_ANNOTATION] = migration.Name
+	if err := r.Patch(ctx, dexServer, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	now := metav1.Now()
+	migration.Status.Phase = authv1alpha1.DexStorageMigrationPhaseStopping
+	migration.Status.Source = dexServer.Spec.Storage.DeepCopy()
+	migration.Status.StartTime = &now
+	if err := r.Status().Update(ctx, migration); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: STORAGE_MIGRATION_STOPPING_INTERVAL}, nil
+}
+
+// runMigration creates the Job copying the objects once the dex pods are stopped
+func (r *DexStorageMigrationReconciler) runMigration(migration *authv1alpha1.DexStorageMigration, dexServer *authv1alpha1.DexServer, ctx context.Context) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	deployment := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, deployment)
+	if err != nil && !kubeerrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil && (deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 0 || deployment.Status.Replicas != 0) {
+		log.Info("Waiting for the dex pods to stop", "DexServer.Name", dexServer.Name)
+		return ctrl.Result{RequeueAfter: STORAGE_MIGRATION_STOPPING_INTERVAL}, nil
+	}
+
+	configMap, err := getStorageMigrationConfigMap(migration)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := ctrl.SetControllerReference(migration, configMap, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, configMap); err != nil && !kubeerrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, err
+	}
+	job := getStorageMigrationJob(migration, r.Image, r.Command)
+	if err := ctrl.SetControllerReference(migration, job, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("Creating storage migration Job", "Job.Name", job.Name)
+	if err := r.Create(ctx, job); err != nil && !kubeerrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, err
+	}
+	migration.Status.Phase = authv1alpha1.DexStorageMigrationPhaseRunning
+	migration.Status.JobName = job.Name
+	return ctrl.Result{}, r.Status().Update(ctx, migration)
+}
+
+// completeMigration switches the DexServer to the target storage once the Job succeeded, and restarts dex
+func (r *DexStorageMigrationReconciler) completeMigration(migration *authv1alpha1.DexStorageMigration, dexServer *authv1alpha1.DexServer, ctx context.Context) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migration.Status.JobName, Namespace: migration.Namespace}, job); err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return ctrl.Result{}, r.failMigration(migration, ctx, "JobNotFound",
+				fmt.Sprintf("the Job %s was deleted before it completed", migration.Status.JobName))
+		}
+		return ctrl.Result{}, err
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			log.Info("Switching the DexServer to the target storage", "DexServer.Name", dexServer.Name)
+			if err := r.resumeDexServer(migration, ctx, &migration.Spec.Target); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, r.setMigrationPhase(migration, ctx, authv1alpha1.DexStorageMigrationPhaseSucceeded, metav1.Condition{
+				Type:    authv1alpha1.DexStorageMigrationConditionTypeComplete,
+				Status:  metav1.ConditionTrue,
+				Reason:  "Migrated",
+				Message: fmt.Sprintf("DexServer %s uses the target storage", dexServer.Name),
+			})
+		case batchv1.JobFailed:
+			return ctrl.Result{}, r.failMigration(migration, ctx, "JobFailed",
+				fmt.Sprintf("the objects were not copied: %s, see kubectl logs -n %s job/%s", cond.Message, job.Namespace, job.Name))
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// resumeDexServer removes the annotation stopping dex, and sets the storage of the DexServer when storage is set.
+// Nothing is done when the DexServer is not stopped by this migration.
+func (r *DexStorageMigrationReconciler) resumeDexServer(migration *authv1alpha1.DexStorageMigration, ctx context.Context, storage *authv1alpha1.StorageSpec) error {
+	dexServer := &authv1alpha1.DexServer{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migration.Spec.DexServerName, Namespace: migration.Namespace}, dexServer); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if dexServer.Annotations[STORAGE_MIGRATION_ANNOTATION] != migration.Name {
+		return nil
+	}
+	delete(dexServer.Annotations, STORAGE_MIGRATION_ANNOTATION)
+	if storage != nil {
+		dexServer.Spec.Storage = *storage.DeepCopy()
+	}
+	return r.Update(ctx, dexServer)
+}
+
+// failMigration restarts dex on its storage and reports the reason of the failure
+func (r *DexStorageMigrationReconciler) failMigration(migration *authv1alpha1.DexStorageMigration, ctx context.Context, reason string, message string) error {
+	log := ctrllog.FromContext(ctx)
+	log.Info("The storage migration failed", "Reason", reason, "Message", message)
+	if err := r.resumeDexServer(migration, ctx, nil); err != nil {
+		return err
+	}
+	return r.setMigrationPhase(migration, ctx, authv1alpha1.DexStorageMigrationPhaseFailed, metav1.Condition{
+		Type:    authv1alpha1.DexStorageMigrationConditionTypeComplete,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+func (r *DexStorageMigrationReconciler) setMigrationPhase(migration *authv1alpha1.DexStorageMigration, ctx context.Context, phase authv1alpha1.DexStorageMigrationPhase, cond metav1.Condition) error {
+	now := metav1.Now()
+	migration.Status.Phase = phase
+	migration.Status.CompletionTime = &now
+	meta.SetStatusCondition(&migration.Status.Conditions, cond)
+	return r.Status().Update(ctx, migration)
+}
+
+// Get the ConfigMap of the storage sections of the dex configs of the source and target storages, mounted in the Job
+func getStorageMigrationConfigMap(migration *authv1alpha1.DexStorageMigration) (*corev1.ConfigMap, error) {
+	source := authv1alpha1.StorageSpec{}
+	if migration.Status.Source != nil {
+		source = *migration.Status.Source
+	}
+	sourceYaml, err := yaml.Marshal(getDexStorage(source, STORAGE_MIGRATION_SOURCE_PASSWORD_ENV_VAR))
+	if err != nil {
+		return nil, err
+	}
+	targetYaml, err := yaml.Marshal(getDexStorage(migration.Spec.Target, STORAGE_MIGRATION_TARGET_PASSWORD_ENV_VAR))
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      migration.Name + STORAGE_MIGRATION_SUFFIX,
+			Namespace: migration.Namespace,
+			Labels:    getStorageMigrationLabels(migration),
+		},
+		Data: map[string]string{
+			"source.yaml": string(sourceYaml),
+			"target.yaml": string(targetYaml),
+		},
+	}, nil
+}
+
+// Get the Job copying the objects. It runs once with the service account of dex, which can read and write the
+// objects of the kubernetes storage of the namespace: a failed copy is not retried over a partially written target.
+func getStorageMigrationJob(migration *authv1alpha1.DexStorageMigration, image string, command []string) *batchv1.Job {
+	backoffLimit := int32(0)
+	source := authv1alpha1.StorageSpec{}
+	if migration.Status.Source != nil {
+		source = *migration.Status.Source
+	}
+	env := []corev1.EnvVar{
+		{Name: "DEX_MIGRATION_SOURCE", Value: STORAGE_MIGRATION_DIR + "/source.yaml"},
+		{Name: "DEX_MIGRATION_TARGET", Value: STORAGE_MIGRATION_DIR + "/target.yaml"},
+	}
+	env = append(env, getStorageEnvVariables(source, STORAGE_MIGRATION_SOURCE_PASSWORD_ENV_VAR)...)
+	env = append(env, getStorageEnvVariables(migration.Spec.Target, STORAGE_MIGRATION_TARGET_PASSWORD_ENV_VAR)...)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      migration.Name + STORAGE_MIGRATION_SUFFIX,
+			Namespace: migration.Namespace,
+			Labels:    getStorageMigrationLabels(migration),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: getStorageMigrationLabels(migration),
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: SERVICE_ACCOUNT_NAME,
+					Containers: []corev1.Container{{
+						Name:    "storage-migration",
+						Image:   image,
+						Command: command,
+						Env:     env,
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "storage-configs",
+							MountPath: STORAGE_MIGRATION_DIR,
+							ReadOnly:  true,
+						}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "storage-configs",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: migration.Name + STORAGE_MIGRATION_SUFFIX},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// RunStorageMigration copies the objects of the source storage to the target storage of the Job of a
+// DexStorageMigration. It is the migrate-storage command of the operator, run in the Job with the service account of
+// dex: the kubernetes storages are read and written through dynamicClient in namespace, the namespace of the Job.
+func RunStorageMigration(ctx context.Context, dynamicClient dynamic.Interface, namespace string) error {
+	storages := []dexstorage.Storage{}
+	defer func() {
+		for _, s := range storages {
+			s.Close()
+		}
+	}()
+	for _, env := range []string{"DEX_MIGRATION_SOURCE", "DEX_MIGRATION_TARGET"} {
+		data, err := ioutil.ReadFile(os.Getenv(env))
+		if err != nil {
+			return errors.Wrapf(err, "error reading the storage config of %s", env)
+		}
+		config := dexstorage.Config{}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return errors.Wrapf(err, "error parsing the storage config of %s", env)
+		}
+		s, err := dexstorage.Open(config, os.Getenv, dynamicClient, namespace)
+		if err != nil {
+			return errors.Wrapf(err, "error opening the storage of %s", env)
+		}
+		storages = append(storages, s)
+	}
+	return dexstorage.Copy(ctx, storages[0], storages[1])
+}
+
+func getStorageMigrationLabels(migration *authv1alpha1.DexStorageMigration) map[string]string {
+	return map[string]string{
+		"app":            migration.Spec.DexServerName,
+		MANAGED_BY_LABEL: MANAGED_BY_VALUE,
+		INSTANCE_LABEL:   migration.Spec.DexServerName,
+		COMPONENT_LABEL:  componentStorageMigration,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DexStorageMigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// only handle spec changes and the deletion, the status is updated by this controller
+		For(&authv1alpha1.DexStorageMigration{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the completion of the Job completes the migration
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
@@ -0,0 +1,177 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Process DexStorageMigration CR", func() {
+	MigrationNamespace := "my-storage-migration-ns"
+	postgresTarget := authv1alpha1.StorageSpec{
+		Type: authv1alpha1.StorageTypePostgres,
+		Postgres: &authv1alpha1.PostgresStorageSpec{
+			Host:        "postgres.testhost.com",
+			Database:    "dex",
+			User:        "dex",
+			PasswordRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "dex-postgres"}, Key: "password"},
+		},
+	}
+
+	// createMigration creates a DexServer and the DexStorageMigration of its storage to postgres, and runs the
+	// migration until its Job is created
+	createMigration := func(name string) (ctrl.Request, *batchv1.Job) {
+		err := k8sClient.Create(context.TODO(), &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: MigrationNamespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://" + name + ".testhost.com"},
+		})
+		Expect(err).To(BeNil())
+		err = k8sClient.Create(context.TODO(), &authv1alpha1.DexStorageMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: MigrationNamespace},
+			Spec:       authv1alpha1.DexStorageMigrationSpec{DexServerName: name, Target: postgresTarget},
+		})
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = name
+		req.Namespace = MigrationNamespace
+		_, err = rStorageMigration.Reconcile(context.TODO(), req)
+		Expect(err).To(BeNil())
+
+		By("stopping dex", func() {
+			migration := &authv1alpha1.DexStorageMigration{}
+			err := k8sClient.Get(context.TODO(), req.NamespacedName, migration)
+			Expect(err).To(BeNil())
+			Expect(migration.Status.Phase).To(Equal(authv1alpha1.DexStorageMigrationPhaseStopping))
+			Expect(migration.Status.Source).To(Equal(&authv1alpha1.StorageSpec{}))
+			dexServer := &authv1alpha1.DexServer{}
+			err = k8sClient.Get(context.TODO(), req.NamespacedName, dexServer)
+			Expect(err).To(BeNil())
+			Expect(dexServer.Annotations[STORAGE_MIGRATION_ANNOTATION]).To(Equal(name))
+			Expect(getDexReplicas(dexServer)).To(Equal(int32(0)))
+		})
+
+		job := &batchv1.Job{}
+		By("copying the objects once dex is stopped", func() {
+			// the Deployment is scaled down by the DexServerReconciler of the manager
+			Eventually(func() (authv1alpha1.DexStorageMigrationPhase, error) {
+				if _, err := rStorageMigration.Reconcile(context.TODO(), req); err != nil {
+					return "", err
+				}
+				migration := &authv1alpha1.DexStorageMigration{}
+				err := k8sClient.Get(context.TODO(), req.NamespacedName, migration)
+				return migration.Status.Phase, err
+			}, 30, 1).Should(Equal(authv1alpha1.DexStorageMigrationPhaseRunning))
+
+			err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: name + STORAGE_MIGRATION_SUFFIX, Namespace: MigrationNamespace}, job)
+			Expect(err).To(BeNil())
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal(rStorageMigration.Image))
+			Expect(container.Command).To(Equal([]string{"/manager", STORAGE_MIGRATION_COMMAND}))
+			Expect(container.Env).To(ContainElement(corev1.EnvVar{
+				Name:      STORAGE_MIGRATION_TARGET_PASSWORD_ENV_VAR,
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &postgresTarget.Postgres.PasswordRef},
+			}))
+
+			configMap := &corev1.ConfigMap{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: name + STORAGE_MIGRATION_SUFFIX, Namespace: MigrationNamespace}, configMap)
+			Expect(err).To(BeNil())
+			Expect(configMap.Data["source.yaml"]).To(ContainSubstring("type: kubernetes"))
+			Expect(configMap.Data["target.yaml"]).To(ContainSubstring("type: postgres"))
+			Expect(configMap.Data["target.yaml"]).To(ContainSubstring("${" + STORAGE_MIGRATION_TARGET_PASSWORD_ENV_VAR + "}"))
+		})
+		return req, job
+	}
+
+	// completeJob sets the final condition of a Job, as the Job controller would
+	completeJob := func(job *batchv1.Job, conditionType batchv1.JobConditionType) {
+		job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{
+			Type:   conditionType,
+			Status: corev1.ConditionTrue,
+		})
+		err := k8sClient.Status().Update(context.TODO(), job)
+		Expect(err).To(BeNil())
+	}
+
+	// reconcileUntilUpdated reconciles a DexStorageMigration, retrying the conflicts with the updates of the DexServer
+	// by the DexServerReconciler of the manager
+	reconcileUntilUpdated := func(req ctrl.Request) *authv1alpha1.DexStorageMigration {
+		migration := &authv1alpha1.DexStorageMigration{}
+		Eventually(func() error {
+			_, err := rStorageMigration.Reconcile(context.TODO(), req)
+			return err
+		}, 10, 1).Should(Succeed())
+		err := k8sClient.Get(context.TODO(), req.NamespacedName, migration)
+		Expect(err).To(BeNil())
+		return migration
+	}
+
+	It("should switch the DexServer to the target storage once the objects are copied", func() {
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: MigrationNamespace}})
+		Expect(err).To(BeNil())
+		req, job := createMigration("my-migrated-dexserver")
+		completeJob(job, batchv1.JobComplete)
+
+		migration := reconcileUntilUpdated(req)
+		Expect(migration.Status.Phase).To(Equal(authv1alpha1.DexStorageMigrationPhaseSucceeded))
+		Expect(meta.IsStatusConditionTrue(migration.Status.Conditions, authv1alpha1.DexStorageMigrationConditionTypeComplete)).To(BeTrue())
+		dexServer := &authv1alpha1.DexServer{}
+		err = k8sClient.Get(context.TODO(), req.NamespacedName, dexServer)
+		Expect(err).To(BeNil())
+		Expect(dexServer.Spec.Storage).To(Equal(postgresTarget))
+		Expect(dexServer.Annotations).ToNot(HaveKey(STORAGE_MIGRATION_ANNOTATION))
+	})
+	It("should restart dex on its storage when the objects are not copied", func() {
+		req, job := createMigration("my-unmigrated-dexserver")
+		completeJob(job, batchv1.JobFailed)
+
+		migration := reconcileUntilUpdated(req)
+		Expect(migration.Status.Phase).To(Equal(authv1alpha1.DexStorageMigrationPhaseFailed))
+		cond := meta.FindStatusCondition(migration.Status.Conditions, authv1alpha1.DexStorageMigrationConditionTypeComplete)
+		Expect(cond).ToNot(BeNil())
+		Expect(cond.Reason).To(Equal("JobFailed"))
+		dexServer := &authv1alpha1.DexServer{}
+		err := k8sClient.Get(context.TODO(), req.NamespacedName, dexServer)
+		Expect(err).To(BeNil())
+		Expect(dexServer.Spec.Storage).To(Equal(authv1alpha1.StorageSpec{}))
+		Expect(dexServer.Annotations).ToNot(HaveKey(STORAGE_MIGRATION_ANNOTATION))
+	})
+	It("should not stop dex to migrate it to the storage it uses", func() {
+		name := "my-unchanged-dexserver"
+		err := k8sClient.Create(context.TODO(), &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: MigrationNamespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://" + name + ".testhost.com"},
+		})
+		Expect(err).To(BeNil())
+		err = k8sClient.Create(context.TODO(), &authv1alpha1.DexStorageMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: MigrationNamespace},
+			Spec: authv1alpha1.DexStorageMigrationSpec{
+				DexServerName: name,
+				Target:        authv1alpha1.StorageSpec{Type: authv1alpha1.StorageTypeKubernetes},
+			},
+		})
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = name
+		req.Namespace = MigrationNamespace
+		migration := reconcileUntilUpdated(req)
+		Expect(migration.Status.Phase).To(Equal(authv1alpha1.DexStorageMigrationPhaseFailed))
+		Expect(meta.FindStatusCondition(migration.Status.Conditions, authv1alpha1.DexStorageMigrationConditionTypeComplete).Reason).To(Equal("SameStorage"))
+		dexServer := &authv1alpha1.DexServer{}
+		err = k8sClient.Get(context.TODO(), req.NamespacedName, dexServer)
+		Expect(err).To(BeNil())
+		Expect(dexServer.Annotations).ToNot(HaveKey(STORAGE_MIGRATION_ANNOTATION))
+	})
+})
@@ -0,0 +1,81 @@
+// Copyright Red Hat
+
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Fields that change on every write, or are not owned by the operator, and are left out of the diff
+var ignoredFields = map[string]bool{
+	"metadata.resourceVersion":   true,
+	"metadata.generation":        true,
+	"metadata.managedFields":     true,
+	"metadata.creationTimestamp": true,
+	"metadata.uid":               true,
+	"metadata.selfLink":          true,
+	"status":                     true,
+}
+
+// Fields returns the sorted paths of the fields that differ between two objects in their unstructured form,
+// for example "spec.template.spec.containers[0].image". Lists of different lengths are reported as a whole.
+func Fields(before map[string]interface{}, after map[string]interface{}) []string {
+	changed := []string{}
+	walk("", before, after, &changed)
+	sort.Strings(changed)
+	return changed
+}
+
+// Summary formats the changed fields on a single line, listing at most max fields
+func Summary(changed []string, max int) string {
+	if len(changed) <= max {
+		return strings.Join(changed, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(changed[:max], ", "), len(changed)-max)
+}
+
+func walk(path string, before interface{}, after interface{}, changed *[]string) {
+	if ignoredFields[path] {
+		return
+	}
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range b {
+			keys[k] = true
+		}
+		for k := range a {
+			keys[k] = true
+		}
+		for k := range keys {
+			walk(join(path, k), b[k], a[k], changed)
+		}
+		return
+	case []interface{}:
+		a, ok := after.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		for i := range b {
+			walk(fmt.Sprintf("%s[%d]", path, i), b[i], a[i], changed)
+		}
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changed = append(*changed, path)
+	}
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,97 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"github.com/identitatem/dex-operator/controllers/diff"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff the owned objects", func() {
+	It("should list the paths of the changed fields", func() {
+		for _, test := range []struct {
+			name    string
+			before  map[string]interface{}
+			after   map[string]interface{}
+			changed []string
+		}{
+			{
+				name:    "identical objects",
+				before:  map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+				after:   map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+				changed: []string{},
+			},
+			{
+				name:    "changed nested field",
+				before:  map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "paused": false}},
+				after:   map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "paused": false}},
+				changed: []string{"spec.replicas"},
+			},
+			{
+				name:    "added and removed fields",
+				before:  map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"old": "a"}}},
+				after:   map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"new": "b"}}},
+				changed: []string{"metadata.labels.new", "metadata.labels.old"},
+			},
+			{
+				name: "changed element of a list",
+				before: map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "dex", "image": "dex:v2.30.2"},
+					map[string]interface{}{"name": "proxy", "image": "proxy:v1"},
+				}},
+				after: map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "dex", "image": "dex:v2.31.0"},
+					map[string]interface{}{"name": "proxy", "image": "proxy:v1"},
+				}},
+				changed: []string{"containers[0].image"},
+			},
+			{
+				name:    "list of another length",
+				before:  map[string]interface{}{"spec": map[string]interface{}{"ports": []interface{}{"http"}}},
+				after:   map[string]interface{}{"spec": map[string]interface{}{"ports": []interface{}{"http", "grpc"}}},
+				changed: []string{"spec.ports"},
+			},
+			{
+				name:    "field of another type",
+				before:  map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+				after:   map[string]interface{}{"data": "value"},
+				changed: []string{"data"},
+			},
+			{
+				name: "fields not owned by the operator",
+				before: map[string]interface{}{
+					"metadata": map[string]interface{}{"resourceVersion": "1", "generation": int64(1), "uid": "a"},
+					"status":   map[string]interface{}{"replicas": int64(1)},
+				},
+				after: map[string]interface{}{
+					"metadata": map[string]interface{}{"resourceVersion": "2", "generation": int64(2), "managedFields": []interface{}{}},
+					"status":   map[string]interface{}{"replicas": int64(2)},
+				},
+				changed: []string{},
+			},
+			{
+				name:    "sorted paths",
+				before:  map[string]interface{}{"b": "1", "a": "1", "c": map[string]interface{}{"d": "1"}},
+				after:   map[string]interface{}{"b": "2", "a": "2", "c": map[string]interface{}{"d": "2"}},
+				changed: []string{"a", "b", "c.d"},
+			},
+		} {
+			Expect(diff.Fields(test.before, test.after)).To(Equal(test.changed), test.name)
+		}
+	})
+	It("should summarize the changed fields on a single line", func() {
+		for _, test := range []struct {
+			changed []string
+			max     int
+			summary string
+		}{
+			{changed: []string{}, max: 2, summary: ""},
+			{changed: []string{"spec.replicas"}, max: 2, summary: "spec.replicas"},
+			{changed: []string{"data", "spec.replicas"}, max: 2, summary: "data, spec.replicas"},
+			{changed: []string{"data", "metadata.labels.app", "spec.replicas"}, max: 2, summary: "data, metadata.labels.app and 1 more"},
+		} {
+			Expect(diff.Summary(test.changed, test.max)).To(Equal(test.summary), "%v", test.changed)
+		}
+	})
+})
@@ -0,0 +1,230 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Secret or ConfigMap holding the copy of the discovery document and signing keys of a dex server
+	DISCOVERY_CACHE_SUFFIX = "-discovery"
+
+	DISCOVERY_CACHE_DISCOVERY_KEY = "openid-configuration"
+	DISCOVERY_CACHE_KEYS_KEY      = "keys"
+
+	// dex signs the tokens with a new key as soon as it rotates, the copy follows within this interval
+	DISCOVERY_CACHE_REFRESH_INTERVAL = 5 * time.Minute
+
+	// CA of the serving certificates issued by the OpenShift service CA, mounted in the pods of the operator
+	serviceCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+)
+
+// Get the in-cluster URL of the issuer, through the Service of dex rather than the Route or Ingress
+func (r *DexServerReconciler) getInClusterIssuerURL(dexServer *authv1alpha1.DexServer, ctx context.Context) (string, string, error) {
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return "", "", err
+	}
+	issuerURL, err := url.Parse(issuer)
+	if err != nil {
+		return "", "", err
+	}
+	// The Service of a load balancer terminating TLS forwards plain HTTP to dex
+	scheme := "https"
+	if isTLSTerminatedAtLoadBalancer(dexServer) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s.%s.svc:%d%s", scheme, dexServer.Name, dexServer.Namespace, getWebServicePort(dexServer), issuerURL.Path), issuer, nil
+}
+
+// Get an HTTP client trusting the serving certificate of dex: the self-signed certificate generated by the operator,
+// or the certificates of the OpenShift service CA
+func (r *DexServerReconciler) getDexHTTPClient(dexServer *authv1alpha1.DexServer, ctx context.Context) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: dexServer.Name + SECRET_WEB_TLS_SUFFIX, Namespace: dexServer.Namespace}, secret)
+	switch {
+	case err == nil:
+		pool.AppendCertsFromPEM(secret.Data[corev1.TLSCertKey])
+	case !kubeerrors.IsNotFound(err):
+		return nil, errors.Wrap(err, "error getting serving certificate secret")
+	}
+	if serviceCA, err := os.ReadFile(serviceCAFile); err == nil {
+		pool.AppendCertsFromPEM(serviceCA)
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}, nil
+}
+
+// Fetch the discovery document and the JWKS of dex. The discovery document must announce the issuer, so that a
+// misrouted request can't replace the copy.
+func fetchDiscoveryDocuments(ctx context.Context, httpClient *http.Client, baseURL string, issuer string) (map[string][]byte, error) {
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s returned %s", path, resp.Status)
+		}
+		// documents are a few kilobytes, a larger answer is not dex
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}
+
+	discovery, err := get("/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	var document struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := json.Unmarshal(discovery, &document); err != nil {
+		return nil, errors.Wrap(err, "the discovery document is not valid JSON")
+	}
+	if document.Issuer != issuer {
+		return nil, fmt.Errorf("the discovery document announces the issuer %q instead of %q", document.Issuer, issuer)
+	}
+	keys, err := get("/keys")
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(keys, &jwks); err != nil {
+		return nil, errors.Wrap(err, "the JWKS is not valid JSON")
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, fmt.Errorf("the JWKS has no signing key")
+	}
+	return map[string][]byte{
+		DISCOVERY_CACHE_DISCOVERY_KEY: discovery,
+		DISCOVERY_CACHE_KEYS_KEY:      keys,
+	}, nil
+}
+
+// Get the Secret or ConfigMap of the discovery cache, and the object of the other kind
+func getDiscoveryCacheObjects(dexServer *authv1alpha1.DexServer) (client.Object, client.Object) {
+	objectMeta := metav1.ObjectMeta{Name: dexServer.Name + DISCOVERY_CACHE_SUFFIX, Namespace: dexServer.Namespace}
+	secret := &corev1.Secret{ObjectMeta: *objectMeta.DeepCopy()}
+	configMap := &corev1.ConfigMap{ObjectMeta: *objectMeta.DeepCopy()}
+	if dexServer.Spec.DiscoveryCache.Kind == "ConfigMap" {
+		return configMap, secret
+	}
+	return secret, configMap
+}
+
+// Create or update the Secret or ConfigMap of the discovery cache with the fetched documents
+func (r *DexServerReconciler) writeDiscoveryCache(dexServer *authv1alpha1.DexServer, ctx context.Context, data map[string][]byte) error {
+	log := ctrllog.FromContext(ctx)
+	obj, _ := getDiscoveryCacheObjects(dexServer)
+	setData := func(obj client.Object) {
+		switch o := obj.(type) {
+		case *corev1.Secret:
+			o.Data = data
+		case *corev1.ConfigMap:
+			o.Data = map[string]string{}
+			for key, value := range data {
+				o.Data[key] = string(value)
+			}
+		}
+	}
+	existing := obj.DeepCopyObject().(client.Object)
+	err := r.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		setData(obj)
+		obj.SetLabels(map[string]string{"app": dexServer.Name})
+		if err := ctrl.SetControllerReference(dexServer, obj, r.Scheme); err != nil {
+			return err
+		}
+		log.Info("Creating the discovery cache", "Name", obj.GetName())
+		return r.Create(ctx, obj)
+	case err != nil:
+		return err
+	}
+	updated := existing.DeepCopyObject().(client.Object)
+	setData(updated)
+	if reflect.DeepEqual(existing, updated) {
+		return nil
+	}
+	log.Info("Updating the discovery cache", "Name", obj.GetName())
+	return r.Update(ctx, updated)
+}
+
+// Delete the objects of the discovery cache that are not used anymore
+func (r *DexServerReconciler) deleteDiscoveryCache(ctx context.Context, objs ...client.Object) error {
+	for _, obj := range objs {
+		if err := r.Delete(ctx, obj); err != nil && !kubeerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error deleting the discovery cache %s", obj.GetName())
+		}
+	}
+	return nil
+}
+
+// Refresh the copy of the discovery document and the JWKS of a dex server. dex can only be read once it is available,
+// and the previous copy is kept when it can't be.
+func (r *DexServerReconciler) syncDiscoveryCache(dexServer *authv1alpha1.DexServer, ctx context.Context, available bool) error {
+	obj, other := getDiscoveryCacheObjects(dexServer)
+	if !dexServer.Spec.DiscoveryCache.Enabled {
+		return r.deleteDiscoveryCache(ctx, obj, other)
+	}
+	if err := r.deleteDiscoveryCache(ctx, other); err != nil {
+		return err
+	}
+	if !available {
+		return nil
+	}
+	baseURL, issuer, err := r.getInClusterIssuerURL(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	httpClient, err := r.getDexHTTPClient(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	data, err := fetchDiscoveryDocuments(ctx, httpClient, baseURL, issuer)
+	if err != nil {
+		ctrllog.FromContext(ctx).Error(err, "failed to fetch the discovery documents of dex, keeping the previous copy")
+		if r.Recorder != nil {
+			r.Recorder.Eventf(dexServer, corev1.EventTypeWarning, "DiscoveryCacheFailed", "Failed to refresh the discovery cache: %s", err)
+		}
+		return nil
+	}
+	return r.writeDiscoveryCache(dexServer, ctx, data)
+}
@@ -0,0 +1,83 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Cache the discovery documents of dex", func() {
+	keys := `{"keys":[{"use":"sig","kty":"RSA","kid":"my-key","alg":"RS256","n":"AQAB","e":"AQAB"}]}`
+	newDex := func(issuer string) *httptest.Server {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/dex/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"issuer":%q,"jwks_uri":"%s/keys"}`, issuer, issuer)
+		})
+		mux.HandleFunc("/dex/keys", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, keys)
+		})
+		return httptest.NewTLSServer(mux)
+	}
+
+	It("should fetch the discovery document and the JWKS of the issuer", func() {
+		server := newDex("https://discovery.testhost.com/dex")
+		defer server.Close()
+		data, err := fetchDiscoveryDocuments(context.TODO(), server.Client(), server.URL+"/dex", "https://discovery.testhost.com/dex")
+		Expect(err).To(BeNil())
+		Expect(string(data[DISCOVERY_CACHE_KEYS_KEY])).To(Equal(keys))
+		Expect(string(data[DISCOVERY_CACHE_DISCOVERY_KEY])).To(ContainSubstring(`"jwks_uri":"https://discovery.testhost.com/dex/keys"`))
+
+		By("refusing the documents of another issuer", func() {
+			_, err := fetchDiscoveryDocuments(context.TODO(), server.Client(), server.URL+"/dex", "https://other.testhost.com/dex")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("instead of"))
+		})
+	})
+	It("should write the documents to a Secret or a ConfigMap", func() {
+		namespace := "my-discovery-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-discovery-dexserver", Namespace: namespace, UID: "my-discovery-uid"},
+			Spec:       authv1alpha1.DexServerSpec{DiscoveryCache: authv1alpha1.DiscoveryCacheSpec{Enabled: true}},
+		}
+		data := map[string][]byte{DISCOVERY_CACHE_DISCOVERY_KEY: []byte(`{}`), DISCOVERY_CACHE_KEYS_KEY: []byte(keys)}
+		key := client.ObjectKey{Name: dexServer.Name + DISCOVERY_CACHE_SUFFIX, Namespace: namespace}
+
+		Expect(rDexServer.writeDiscoveryCache(dexServer, context.TODO(), data)).To(Succeed())
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(context.TODO(), key, secret)).To(Succeed())
+		Expect(string(secret.Data[DISCOVERY_CACHE_KEYS_KEY])).To(Equal(keys))
+		Expect(metav1.IsControlledBy(secret, dexServer)).To(BeTrue())
+
+		By("replacing the Secret with a ConfigMap", func() {
+			dexServer.Spec.DiscoveryCache.Kind = "ConfigMap"
+			// dex is not available, the ConfigMap is written on a later reconcile
+			Expect(rDexServer.syncDiscoveryCache(dexServer, context.TODO(), false)).To(Succeed())
+			err := k8sClient.Get(context.TODO(), key, &corev1.Secret{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+
+			Expect(rDexServer.writeDiscoveryCache(dexServer, context.TODO(), data)).To(Succeed())
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(context.TODO(), key, configMap)).To(Succeed())
+			Expect(configMap.Data[DISCOVERY_CACHE_KEYS_KEY]).To(Equal(keys))
+		})
+		By("deleting the copy once disabled", func() {
+			dexServer.Spec.DiscoveryCache.Enabled = false
+			Expect(rDexServer.syncDiscoveryCache(dexServer, context.TODO(), true)).To(Succeed())
+			err := k8sClient.Get(context.TODO(), key, &corev1.ConfigMap{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
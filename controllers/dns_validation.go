@@ -0,0 +1,65 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/identitatem/dex-operator/controllers/failures"
+)
+
+// HostResolver looks up the issuer hosts of the DexServers with spec.route.validateDNS. It is implemented by
+// net.Resolver.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// NewDNSServerResolver returns a resolver querying the DNS server at address, host:port, instead of the resolver of
+// the operator pod, for clusters whose issuers are published in a DNS zone the cluster DNS does not serve
+func NewDNSServerResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: 5 * time.Second}
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+func (r *DexServerReconciler) getHostResolver() HostResolver {
+	if r.Resolver != nil {
+		return r.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// Check that the hosts of the issuer resolve, see spec.route.validateDNS. The hosts of the cluster ingress domain
+// resolve to the router through its wildcard record and are not looked up. Only the hosts the DNS servers answer
+// do not exist fail the check, other lookup errors are logged as the DNS may be unavailable for a while.
+func (r *DexServerReconciler) validateIssuerDNS(ctx context.Context, hosts []string, domain string) error {
+	log := ctrllog.FromContext(ctx)
+	resolver := r.getHostResolver()
+	for _, host := range hosts {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) != nil || domain != "" && isHostInIngressDomain(host, domain) {
+			continue
+		}
+		_, err := resolver.LookupHost(ctx, host)
+		var dnsErr *net.DNSError
+		switch {
+		case err == nil:
+		case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+			return failures.New(failures.DNSConfigurationInvalid, "host %s of the issuer does not resolve, check its DNS record or the spelling of the issuer", host)
+		default:
+			log.Info("WARNING: could not validate the DNS record of the issuer", "Host", host, "Error", err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/identitatem/dex-operator/controllers/failures"
+)
+
+// Answers the hosts of its map with their error, nil when they resolve. The other hosts do not exist.
+type fakeResolver map[string]error
+
+func (f fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if err, ok := f[host]; ok {
+		return nil, err
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+var _ = Describe("Validate the DNS records of the issuer", func() {
+	r := &DexServerReconciler{Resolver: fakeResolver{
+		"dex.example.com":   nil,
+		"flaky.example.com": &net.DNSError{Err: "i/o timeout", Name: "flaky.example.com", IsTimeout: true},
+	}}
+	It("should report the hosts that do not exist", func() {
+		Expect(r.validateIssuerDNS(context.TODO(), []string{"dex.example.com", "10.0.0.1"}, "")).To(Succeed())
+		err := r.validateIssuerDNS(context.TODO(), []string{"dex.example.com", "dex.exmaple.com:443"}, "")
+		Expect(err).To(MatchError(ContainSubstring("dex.exmaple.com")))
+		class, _ := failures.ClassOf(err)
+		Expect(class).To(Equal(failures.DNSConfigurationInvalid))
+	})
+	It("should rely on the wildcard record of the router for the ingress domain", func() {
+		Expect(r.validateIssuerDNS(context.TODO(), []string{"dex-my-ns.apps.testhost.com"}, "apps.testhost.com")).To(Succeed())
+	})
+	It("should not fail when the DNS is unavailable", func() {
+		Expect(r.validateIssuerDNS(context.TODO(), []string{"flaky.example.com"}, "")).To(Succeed())
+	})
+})
@@ -0,0 +1,140 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+var apiServerConfigGVR = schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "apiservers"}
+
+// Time after which the encryption of the cluster is read again
+var encryptionCacheTTL = 10 * time.Minute
+
+var clusterEncryption = &encryptionCache{}
+
+// unencryptedCredentials is set to 1 for the DexServers whose credentials are written to an etcd that is not
+// encrypted at rest, and to 0 when the encryption is enabled or can't be verified
+var unencryptedCredentials = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "dex_operator_credentials_unencrypted_at_rest",
+	Help: "Whether the connector credentials and gRPC keys of the DexServer are stored in an etcd without encryption at rest",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(unencryptedCredentials)
+}
+
+// encryptionCache holds the etcd encryption type of the OpenShift API server config, so that it is not read on
+// each reconcile
+type encryptionCache struct {
+	mu      sync.Mutex
+	found   bool
+	typ     string
+	expires time.Time
+}
+
+// get returns the encryption type of the cluster, and false when the cluster has no API server config telling it
+func (c *encryptionCache) get(ctx context.Context, dynamicClient dynamic.Interface) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.expires) {
+		return c.typ, c.found, nil
+	}
+	apiServer, err := dynamicClient.Resource(apiServerConfigGVR).Get(ctx, "cluster", metav1.GetOptions{})
+	switch {
+	case err == nil:
+		c.found = true
+		c.typ, _, _ = unstructured.NestedString(apiServer.Object, "spec", "encryption", "type")
+	case kubeerrors.IsNotFound(err):
+		c.found = false
+		c.typ = ""
+	default:
+		return "", false, err
+	}
+	c.expires = time.Now().Add(encryptionCacheTTL)
+	return c.typ, c.found, nil
+}
+
+// Get the SecretsEncryptedAtRest condition. The encryption of etcd is only known on OpenShift, where it is
+// configured in the API server config, it is reported as Unknown on other clusters.
+func (r *DexServerReconciler) getEncryptionAtRestCondition(ctx context.Context) (metav1.Condition, error) {
+	cond := metav1.Condition{
+		Type:    authv1alpha1.DexServerConditionTypeSecretsEncryptedAtRest,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "EncryptionUnknown",
+		Message: "the encryption at rest of etcd can't be verified on this cluster",
+	}
+	if !r.OpenShift {
+		return cond, nil
+	}
+	encryptionType, found, err := clusterEncryption.get(ctx, r.DynamicClient)
+	if err != nil {
+		return cond, err
+	}
+	switch {
+	case !found:
+	case encryptionType == "" || encryptionType == "identity":
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "EncryptionDisabled"
+		cond.Message = "etcd is not encrypted at rest, the connector credentials and gRPC keys are stored in plaintext"
+	default:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "EncryptionEnabled"
+		cond.Message = fmt.Sprintf("etcd is encrypted at rest with %s", encryptionType)
+	}
+	return cond, nil
+}
+
+// Report the encryption at rest of the credentials written by the operator. A warning Event is recorded once, when
+// the condition becomes False, the operator logs it on startup. Under the strict policy, the credentials and the dex
+// configuration are only written once the encryption is verified.
+func (r *DexServerReconciler) checkEncryptionAtRest(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	cond, err := r.getEncryptionAtRestCondition(ctx)
+	if err != nil {
+		return err
+	}
+	value := 0.0
+	if cond.Status == metav1.ConditionFalse {
+		value = 1
+		if r.Recorder != nil && !meta.IsStatusConditionFalse(dexServer.Status.Conditions, cond.Type) {
+			r.Recorder.Event(dexServer, corev1.EventTypeWarning, cond.Reason, cond.Message)
+		}
+	}
+	// persisted with the Applied condition
+	dexServer.Status.Conditions = mergeStatusConditions(dexServer.Status.Conditions, cond)
+	unencryptedCredentials.WithLabelValues(dexServer.Namespace, dexServer.Name).Set(value)
+	if r.RequireEncryptionAtRest && cond.Status != metav1.ConditionTrue {
+		return fmt.Errorf("encryption at rest is required: %s", cond.Message)
+	}
+	return nil
+}
+
+// WarnUnencryptedAtRest logs a warning on startup when the etcd of the cluster is not verified to be encrypted at
+// rest, the DexServers then report it in their SecretsEncryptedAtRest condition
+func WarnUnencryptedAtRest(ctx context.Context, dynamicClient dynamic.Interface, openShift bool) error {
+	r := &DexServerReconciler{DynamicClient: dynamicClient, OpenShift: openShift}
+	cond, err := r.getEncryptionAtRestCondition(ctx)
+	if err != nil {
+		return err
+	}
+	if cond.Status != metav1.ConditionTrue {
+		ctrllog.FromContext(ctx).Info("WARNING: " + cond.Message)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Path of the export of the metrics server, see --enable-export
+	EXPORT_PATH = "/export"
+
+	// Directory of the export bundle holding the rendered dex configs, left out of the kustomization
+	exportRenderedDir = "rendered"
+)
+
+// ExportHandler serves the DexServers, DexClients, ClusterDexServers, DexQuickstarts and DexServerSets of the
+// cluster as a gzipped tar of a kustomize-ready bundle, to rebuild the dex servers after a disaster or to clone them
+// into another environment. The secrets are not exported, they must be restored separately.
+type ExportHandler struct {
+	// Reader of the custom resources, the API reader avoids caching the ConfigMaps of the whole cluster
+	Client client.Reader
+}
+
+func (h *ExportHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	log := ctrllog.FromContext(req.Context())
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	files, err := getExportBundle(req.Context(), h.Client)
+	if err != nil {
+		log.Error(err, "failed to export the dex servers")
+		http.Error(w, "failed to export the dex servers: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"dex-export-%s.tar.gz\"", time.Now().UTC().Format("20060102T150405Z")))
+	if err := writeExportArchive(w, files); err != nil {
+		log.Error(err, "failed to write the export archive")
+	}
+}
+
+// Get the files of the export bundle by path. The objects created by a controller, e.g. the DexServer of a
+// ClusterDexServer or the DexClient of an oauth2-proxy sidecar, are recreated from their owner and left out. The
+// rendered dex configs are exported for review under rendered/ but not applied, the operator renders them again.
+func getExportBundle(ctx context.Context, c client.Reader) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	resources := []string{}
+	namespaces := map[string]bool{}
+	add := func(obj client.Object, kind string) error {
+		if metav1.GetControllerOf(obj) != nil {
+			return nil
+		}
+		manifest, err := getExportManifest(obj, kind)
+		if err != nil {
+			return err
+		}
+		file := path.Join(strings.ToLower(kind)+"s", obj.GetNamespace(), obj.GetName()+".yaml")
+		files[file] = manifest
+		resources = append(resources, file)
+		if obj.GetNamespace() != "" {
+			namespaces[obj.GetNamespace()] = true
+		}
+		return nil
+	}
+
+	clusterDexServers := &authv1alpha1.ClusterDexServerList{}
+	if err := c.List(ctx, clusterDexServers); err != nil {
+		return nil, err
+	}
+	for i := range clusterDexServers.Items {
+		if err := add(&clusterDexServers.Items[i], "ClusterDexServer"); err != nil {
+			return nil, err
+		}
+	}
+	dexQuickstarts := &authv1alpha1.DexQuickstartList{}
+	if err := c.List(ctx, dexQuickstarts); err != nil {
+		return nil, err
+	}
+	for i := range dexQuickstarts.Items {
+		if err := add(&dexQuickstarts.Items[i], "DexQuickstart"); err != nil {
+			return nil, err
+		}
+	}
+	dexServerSets := &authv1alpha1.DexServerSetList{}
+	if err := c.List(ctx, dexServerSets); err != nil {
+		return nil, err
+	}
+	for i := range dexServerSets.Items {
+		if err := add(&dexServerSets.Items[i], "DexServerSet"); err != nil {
+			return nil, err
+		}
+	}
+	dexServers := &authv1alpha1.DexServerList{}
+	if err := c.List(ctx, dexServers); err != nil {
+		return nil, err
+	}
+	for i := range dexServers.Items {
+		dexServer := &dexServers.Items[i]
+		if err := add(dexServer, "DexServer"); err != nil {
+			return nil, err
+		}
+		// the config ConfigMap holds no secret material, see syncConfigMap
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, configMap); err != nil {
+			if kubeerrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if config, ok := configMap.Data["config.yaml"]; ok {
+			files[path.Join(exportRenderedDir, dexServer.Namespace, dexServer.Name, "config.yaml")] = []byte(config)
+		}
+	}
+	dexClients := &authv1alpha1.DexClientList{}
+	if err := c.List(ctx, dexClients); err != nil {
+		return nil, err
+	}
+	for i := range dexClients.Items {
+		if err := add(&dexClients.Items[i], "DexClient"); err != nil {
+			return nil, err
+		}
+	}
+
+	// the namespaces are applied first, the DexServers of a rebuilt cluster have none to go to
+	namespaceResources := []string{}
+	for namespace := range namespaces {
+		manifest, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": namespace},
+		})
+		if err != nil {
+			return nil, err
+		}
+		file := path.Join("namespaces", namespace+".yaml")
+		files[file] = manifest
+		namespaceResources = append(namespaceResources, file)
+	}
+	sort.Strings(namespaceResources)
+	sort.Strings(resources)
+	kustomization, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  append(namespaceResources, resources...),
+	})
+	if err != nil {
+		return nil, err
+	}
+	files["kustomization.yaml"] = kustomization
+	return files, nil
+}
+
+// Get the manifest of an exported object, with its spec and the metadata a user sets. The status, the owner
+// references and the fields set by the API server are specific to the cluster it is exported from.
+func getExportManifest(obj client.Object, kind string) ([]byte, error) {
+	content, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+	metadata := map[string]interface{}{"name": obj.GetName()}
+	if obj.GetNamespace() != "" {
+		metadata["namespace"] = obj.GetNamespace()
+	}
+	if labels := obj.GetLabels(); len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	annotations := map[string]string{}
+	for key, value := range obj.GetAnnotations() {
+		if key != corev1.LastAppliedConfigAnnotation {
+			annotations[key] = value
+		}
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	manifest := map[string]interface{}{
+		"apiVersion": authv1alpha1.GroupVersion.String(),
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+	if spec, ok := fields["spec"]; ok {
+		manifest["spec"] = spec
+	}
+	return yaml.Marshal(manifest)
+}
+
+// Write the files of the export bundle as a gzipped tar, sorted by path
+func writeExportArchive(w io.Writer, files map[string][]byte) error {
+	paths := make([]string, 0, len(files))
+	for file := range files {
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range paths {
+		header := &tar.Header{
+			Name:    file,
+			Mode:    0644,
+			Size:    int64(len(files[file])),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[file]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
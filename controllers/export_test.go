@@ -0,0 +1,96 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+
+	"github.com/ghodss/yaml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Export the dex servers", func() {
+	It("should export the custom resources and the rendered configs as a kustomize bundle", func() {
+		namespace := "my-export-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "my-export-dexserver",
+				Namespace:   namespace,
+				Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: "{}", "team": "identity"},
+			},
+			Spec: authv1alpha1.DexServerSpec{Issuer: "https://export.testhost.com"},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: dexServer.Name, Namespace: namespace},
+			Data:       map[string]string{"config.yaml": "issuer: https://export.testhost.com\n"},
+		})).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), &authv1alpha1.DexClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-export-dexclient", Namespace: namespace},
+			Spec: authv1alpha1.DexClientSpec{
+				ClientID:        "my-export-client",
+				ClientSecretRef: corev1.SecretReference{Name: "my-export-client-secret", Namespace: namespace},
+			},
+		})).To(Succeed())
+		owned := &authv1alpha1.DexClient{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-owned-dexclient", Namespace: namespace},
+			Spec:       authv1alpha1.DexClientSpec{ClientID: "my-owned-client"},
+		}
+		Expect(ctrl.SetControllerReference(dexServer, owned, k8sClient.Scheme())).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), owned)).To(Succeed())
+
+		files, err := getExportBundle(context.TODO(), k8sClient)
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveKey("dexclients/my-export-ns/my-export-dexclient.yaml"))
+		Expect(files).NotTo(HaveKey("dexclients/my-export-ns/my-owned-dexclient.yaml"))
+		Expect(string(files["rendered/my-export-ns/my-export-dexserver/config.yaml"])).To(Equal("issuer: https://export.testhost.com\n"))
+
+		exported := map[string]interface{}{}
+		Expect(yaml.Unmarshal(files["dexservers/my-export-ns/my-export-dexserver.yaml"], &exported)).To(Succeed())
+		Expect(exported).To(HaveKeyWithValue("kind", "DexServer"))
+		Expect(exported).NotTo(HaveKey("status"))
+		Expect(exported["metadata"]).To(Equal(map[string]interface{}{
+			"name":        "my-export-dexserver",
+			"namespace":   namespace,
+			"annotations": map[string]interface{}{"team": "identity"},
+		}))
+		Expect(exported["spec"]).To(HaveKeyWithValue("issuer", "https://export.testhost.com"))
+
+		kustomization := struct {
+			Resources []string `json:"resources"`
+		}{}
+		Expect(yaml.Unmarshal(files["kustomization.yaml"], &kustomization)).To(Succeed())
+		Expect(kustomization.Resources).To(ContainElements("namespaces/my-export-ns.yaml", "dexservers/my-export-ns/my-export-dexserver.yaml"))
+		Expect(kustomization.Resources).NotTo(ContainElement(HavePrefix("rendered/")))
+
+		By("writing the bundle as a gzipped tar", func() {
+			archive := &bytes.Buffer{}
+			Expect(writeExportArchive(archive, files)).To(Succeed())
+			gz, err := gzip.NewReader(archive)
+			Expect(err).To(BeNil())
+			tr := tar.NewReader(gz)
+			read := map[string][]byte{}
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).To(BeNil())
+				read[header.Name], err = io.ReadAll(tr)
+				Expect(err).To(BeNil())
+			}
+			Expect(read).To(Equal(files))
+		})
+	})
+})
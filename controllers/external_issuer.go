@@ -0,0 +1,176 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Default interval between two probes of an issuer served by a global load balancer
+	EXTERNAL_ISSUER_PROBE_INTERVAL = 5 * time.Minute
+	// The ExternalIssuerReachable condition warns of the certificate of the load balancer expiring within this delay
+	EXTERNAL_ISSUER_CERT_WARNING = 14 * 24 * time.Hour
+)
+
+// Get the interval between two probes of the issuer, spec.externalIssuer.probeInterval or 5m
+func getExternalIssuerProbeInterval(dexServer *authv1alpha1.DexServer) time.Duration {
+	if interval := dexServer.Spec.ExternalIssuer.ProbeInterval; interval != nil && interval.Duration > 0 {
+		return interval.Duration
+	}
+	return EXTERNAL_ISSUER_PROBE_INTERVAL
+}
+
+// Get the host of the Route or Ingress of this cluster behind the global load balancer, spec.externalIssuer.routeHost
+// or the host generated from the cluster ingress domain
+func (r *DexServerReconciler) getExternalIssuerRouteHost(dexServer *authv1alpha1.DexServer, ctx context.Context) (string, error) {
+	if host := dexServer.Spec.ExternalIssuer.RouteHost; host != "" {
+		return host, nil
+	}
+	generated, err := r.getGeneratedIssuer(dexServer, ctx)
+	if err != nil {
+		return "", err
+	}
+	if generated == "" {
+		return "", fmt.Errorf("spec.externalIssuer.routeHost is required, no host can be generated from the cluster ingress domain")
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(generated, dexServer.Spec.Route.Path), "https://"), nil
+}
+
+// Get an HTTP client trusting the certificate of the global load balancer, with the system trust or
+// spec.externalIssuer.caBundleRef
+func (r *DexServerReconciler) getExternalIssuerHTTPClient(dexServer *authv1alpha1.DexServer, ctx context.Context) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if ref := dexServer.Spec.ExternalIssuer.CABundleRef; ref != nil {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: dexServer.Namespace}, configMap); err != nil {
+			return nil, errors.Wrap(err, "error getting externalIssuer.caBundleRef")
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(configMap.Data[ref.Key])) {
+			return nil, fmt.Errorf("key %s of ConfigMap %s holds no certificate", ref.Key, ref.Name)
+		}
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			// the load balancer is reached as the clients of the issuer would, through the cluster proxy if any
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}, nil
+}
+
+// Fetch the discovery document of the issuer through the global load balancer. It must announce the issuer, as a
+// load balancer forwarding to another application would still answer. The earliest expiry of the certificate
+// chain presented by the load balancer is returned.
+func probeExternalIssuer(ctx context.Context, httpClient *http.Client, issuer string) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	var notAfter time.Time
+	if resp.TLS != nil {
+		for _, cert := range resp.TLS.PeerCertificates {
+			if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+				notAfter = cert.NotAfter
+			}
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return notAfter, fmt.Errorf("GET %s returned %s", req.URL.Path, resp.Status)
+	}
+	var document struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return notAfter, errors.Wrap(err, "the discovery document is not valid JSON")
+	}
+	if document.Issuer != issuer {
+		return notAfter, fmt.Errorf("the discovery document announces the issuer %q instead of %q", document.Issuer, issuer)
+	}
+	return notAfter, nil
+}
+
+// Get the ExternalIssuerReachable condition from the result of a probe
+func getExternalIssuerCondition(notAfter time.Time, err error, now time.Time) metav1.Condition {
+	cond := metav1.Condition{
+		Type:    authv1alpha1.DexServerConditionTypeExternalIssuerReachable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Reachable",
+		Message: "the issuer answers through the load balancer",
+	}
+	var certErr x509.CertificateInvalidError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.As(err, &certErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr):
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "CertificateInvalid"
+		cond.Message = fmt.Sprintf("the certificate of the load balancer is not valid: %s", err)
+	case err != nil:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "Unreachable"
+		cond.Message = fmt.Sprintf("the issuer does not answer through the load balancer: %s", err)
+	case !notAfter.IsZero() && notAfter.Sub(now) < EXTERNAL_ISSUER_CERT_WARNING:
+		cond.Reason = "CertificateExpiringSoon"
+		cond.Message = fmt.Sprintf("the certificate of the load balancer expires on %s", notAfter.UTC().Format(time.RFC3339))
+	}
+	return cond
+}
+
+// Probe the issuer served by a global load balancer, see spec.externalIssuer. The result is persisted with the
+// Available and Ready conditions.
+func (r *DexServerReconciler) syncExternalIssuer(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	if !dexServer.Spec.ExternalIssuer.Enabled {
+		dexServer.Status.ExternalIssuer = nil
+		meta.RemoveStatusCondition(&dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeExternalIssuerReachable)
+		return nil
+	}
+	if dexServer.Spec.Issuer == "" {
+		return fmt.Errorf("spec.issuer is required with spec.externalIssuer")
+	}
+	httpClient, err := r.getExternalIssuerHTTPClient(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	notAfter, probeErr := probeExternalIssuer(ctx, httpClient, dexServer.Spec.Issuer)
+	cond := getExternalIssuerCondition(notAfter, probeErr, now)
+	if cond.Status != metav1.ConditionTrue {
+		ctrllog.FromContext(ctx).Info("WARNING: " + cond.Message)
+		if r.Recorder != nil && !meta.IsStatusConditionFalse(dexServer.Status.Conditions, cond.Type) {
+			r.Recorder.Event(dexServer, corev1.EventTypeWarning, "ExternalIssuer"+cond.Reason, cond.Message)
+		}
+	}
+	dexServer.Status.ExternalIssuer = &authv1alpha1.ExternalIssuerStatus{LastProbeTime: metav1.NewTime(now)}
+	if !notAfter.IsZero() {
+		dexServer.Status.ExternalIssuer.CertificateNotAfter = &metav1.Time{Time: notAfter}
+	}
+	dexServer.Status.Conditions = mergeStatusConditions(dexServer.Status.Conditions, cond)
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Probe an issuer served by a global load balancer", func() {
+	newLoadBalancer := func(issuer func(*http.Request) string) *httptest.Server {
+		return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/.well-known/openid-configuration" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"issuer":%q}`, issuer(r))
+		}))
+	}
+
+	It("should report the issuer reachable with the expiry of its certificate", func() {
+		server := newLoadBalancer(func(r *http.Request) string { return "https://" + r.Host })
+		defer server.Close()
+		notAfter, err := probeExternalIssuer(context.TODO(), server.Client(), server.URL)
+		Expect(err).To(BeNil())
+		Expect(notAfter).To(Equal(server.Certificate().NotAfter))
+
+		cond := getExternalIssuerCondition(notAfter, err, time.Now())
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal("Reachable"))
+
+		By("warning of the certificate expiring soon", func() {
+			cond := getExternalIssuerCondition(notAfter, nil, notAfter.Add(-24*time.Hour))
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal("CertificateExpiringSoon"))
+		})
+	})
+	It("should report a load balancer answering for another issuer", func() {
+		server := newLoadBalancer(func(r *http.Request) string { return "https://other.testhost.com" })
+		defer server.Close()
+		notAfter, err := probeExternalIssuer(context.TODO(), server.Client(), server.URL)
+		Expect(err).ToNot(BeNil())
+		cond := getExternalIssuerCondition(notAfter, err, time.Now())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal("Unreachable"))
+	})
+	It("should report a certificate that is not trusted", func() {
+		server := newLoadBalancer(func(r *http.Request) string { return "https://" + r.Host })
+		defer server.Close()
+		httpClient, err := rDexServer.getExternalIssuerHTTPClient(&authv1alpha1.DexServer{}, context.TODO())
+		Expect(err).To(BeNil())
+		notAfter, err := probeExternalIssuer(context.TODO(), httpClient, server.URL)
+		Expect(err).ToNot(BeNil())
+		cond := getExternalIssuerCondition(notAfter, err, time.Now())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal("CertificateInvalid"))
+	})
+	It("should expose dex on the route host of this cluster", func() {
+		dexServer := &authv1alpha1.DexServer{Spec: authv1alpha1.DexServerSpec{
+			Issuer:         "https://sso.testhost.com",
+			ExternalIssuer: authv1alpha1.ExternalIssuerSpec{Enabled: true, RouteHost: "sso.cluster1.testhost.com"},
+		}}
+		Expect(rDexServer.getExternalIssuerRouteHost(dexServer, context.TODO())).To(Equal("sso.cluster1.testhost.com"))
+		Expect(getExternalIssuerProbeInterval(dexServer)).To(Equal(EXTERNAL_ISSUER_PROBE_INTERVAL))
+
+		By("defaulting to the host generated from the cluster ingress domain", func() {
+			previousIngressDomain := clusterIngressDomain
+			defer func() { clusterIngressDomain = previousIngressDomain }()
+			clusterIngressDomain = &ingressDomainCache{domain: "apps.example.com", expires: time.Now().Add(time.Hour)}
+			dexServer := dexServer.DeepCopy()
+			dexServer.ObjectMeta = metav1.ObjectMeta{Name: "my-dex", Namespace: "my-ns"}
+			dexServer.Spec.ExternalIssuer.RouteHost = ""
+			Expect(rDexServer.getExternalIssuerRouteHost(dexServer, context.TODO())).To(Equal("my-dex-my-ns.apps.example.com"))
+
+			clusterIngressDomain = &ingressDomainCache{expires: time.Now().Add(time.Hour)}
+			_, err := rDexServer.getExternalIssuerRouteHost(dexServer, context.TODO())
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
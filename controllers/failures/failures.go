@@ -0,0 +1,89 @@
+// Copyright Red Hat
+
+package failures
+
+import (
+	"errors"
+	"fmt"
+
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Class of a reconcile failure. A classified failure sets the reason of the Applied condition and the reason label
+// of the reconcile failures metric to its class, so that alerting and automation can branch on it.
+type Class string
+
+const (
+	// A Secret referenced by the DexServer, or needed to configure dex, does not exist
+	SecretMissing Class = "SecretMissing"
+	// The manifests or the dex configuration could not be rendered from the DexServer
+	RenderFailure Class = "RenderFailure"
+	// The issuer host would not be admitted by the OpenShift router
+	RouteNotAdmitted Class = "RouteNotAdmitted"
+	// The issuer host or an additional host does not resolve, see spec.route.validateDNS
+	DNSConfigurationInvalid Class = "DNSConfigurationInvalid"
+	// A certificate needed by the dex server has expired
+	CertExpired Class = "CertExpired"
+	// The operator is not allowed to grant the RBAC permissions of dex, it misses the escalate or bind verb
+	RBACEscalationDenied Class = "RBACEscalationDenied"
+)
+
+// Error is an error of a known class
+type Error struct {
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classifier is implemented by the error types that belong to a class
+type Classifier interface {
+	FailureClass() Class
+}
+
+// New returns an error of the class with the formatted message
+func New(class Class, format string, args ...interface{}) error {
+	return &Error{Class: class, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap classifies an error, it returns nil when err is nil
+func Wrap(class Class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: class, Err: err}
+}
+
+// ClassOf returns the class of the first classified error in the chain of err, and false when it is not classified.
+// Secrets that are not found are classified as SecretMissing.
+func ClassOf(err error) (Class, bool) {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Class, true
+	}
+	var classifier Classifier
+	if errors.As(err, &classifier) {
+		return classifier.FailureClass(), true
+	}
+	var status kubeerrors.APIStatus
+	if errors.As(err, &status) && kubeerrors.IsNotFound(err) {
+		if details := status.Status().Details; details != nil && details.Kind == "secrets" {
+			return SecretMissing, true
+		}
+	}
+	return "", false
+}
+
+// Reason returns the class of err when it is classified, and the given reason otherwise
+func Reason(err error, reason string) string {
+	if class, ok := ClassOf(err); ok {
+		return string(class)
+	}
+	return reason
+}
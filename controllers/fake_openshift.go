@@ -0,0 +1,142 @@
+// Copyright Red Hat
+
+//go:build devmode
+// +build devmode
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// FakeOpenShift is set in the builds with the devmode tag, which fake the OpenShift integrations on clusters
+// without the OpenShift APIs, such as kind or the envtest API server. See make run-local.
+const FakeOpenShift = true
+
+const (
+	// Annotation of the Services the OpenShift service CA operator provides a serving certificate for
+	SERVING_CERT_SECRET_ANNOTATION = "service.beta.openshift.io/serving-cert-secret-name"
+	// Annotation of the Ingresses the OpenShift router exposes with a route
+	ROUTE_TERMINATION_ANNOTATION = "route.openshift.io/termination"
+)
+
+// SetupFakeOpenShiftWithManager sets up the fake service CA and router with the Manager
+func SetupFakeOpenShiftWithManager(mgr ctrl.Manager) error {
+	if err := (&fakeServingCertReconciler{Client: mgr.GetClient()}).SetupWithManager(mgr); err != nil {
+		return err
+	}
+	return (&fakeRouterReconciler{Client: mgr.GetClient()}).SetupWithManager(mgr)
+}
+
+// fakeServingCertReconciler stands in for the OpenShift service CA operator: it creates the serving certificate
+// Secret requested by the annotation of a Service. The certificates are self-signed and never rotated.
+type fakeServingCertReconciler struct {
+	client.Client
+}
+
+func (r *fakeServingCertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	service := &corev1.Service{}
+	if err := r.Get(ctx, req.NamespacedName, service); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	secretName := service.Annotations[SERVING_CERT_SECRET_ANNOTATION]
+	if secretName == "" {
+		return ctrl.Result{}, nil
+	}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: service.Namespace}, &corev1.Secret{})
+	if !kubeerrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	certPEM, keyPEM, _, err := generateServingCert([]string{
+		fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", service.Name, service.Namespace),
+	}, false, nil)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: service.Namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM.Bytes(),
+			corev1.TLSPrivateKeyKey: keyPEM.Bytes(),
+		},
+	}
+	// deleted with the Service, as the secrets of the service CA operator
+	if err := ctrl.SetControllerReference(service, secret, r.Scheme()); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("Creating a fake serving certificate", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+	return ctrl.Result{}, r.Create(ctx, secret)
+}
+
+func (r *fakeServingCertReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	hasServingCert := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetAnnotations()[SERVING_CERT_SECRET_ANNOTATION] != ""
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("fakeservingcert").
+		For(&corev1.Service{}, builder.WithPredicates(hasServingCert)).
+		Owns(&corev1.Secret{}).
+		Complete(r)
+}
+
+// fakeRouterReconciler stands in for the OpenShift router: it publishes the CA bundle of the router certificate in
+// the ingress CA ConfigMap read for the issuer trust, once an Ingress is exposed with a route. It does not serve
+// the routes, the Ingresses are served by the ingress controller of the cluster, if any.
+type fakeRouterReconciler struct {
+	client.Client
+}
+
+func (r *fakeRouterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	err := r.Get(ctx, types.NamespacedName{Name: ingressCAName, Namespace: ingressCANamespace}, &corev1.ConfigMap{})
+	if !kubeerrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ingressCANamespace}}
+	if err := r.Create(ctx, namespace); err != nil && !kubeerrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, err
+	}
+	certPEM, _, _, err := generateServingCert([]string{"router-default.fake-openshift"}, false, nil)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ingressCAName,
+			Namespace: ingressCANamespace,
+		},
+		Data: map[string]string{
+			ingressCAKey: certPEM.String(),
+		},
+	}
+	log.Info("Creating a fake ingress CA", "ConfigMap.Namespace", ingressCANamespace, "ConfigMap.Name", ingressCAName)
+	return ctrl.Result{}, r.Create(ctx, configMap)
+}
+
+func (r *fakeRouterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	hasRoute := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetAnnotations()[ROUTE_TERMINATION_ANNOTATION] != ""
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("fakerouter").
+		For(&networkingv1.Ingress{}, builder.WithPredicates(hasRoute)).
+		Complete(r)
+}
@@ -0,0 +1,18 @@
+// Copyright Red Hat
+
+//go:build !devmode
+// +build !devmode
+
+package controllers
+
+import (
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// FakeOpenShift is only set in the builds with the devmode tag, see fake_openshift.go
+const FakeOpenShift = false
+
+// SetupFakeOpenShiftWithManager does nothing without the devmode tag
+func SetupFakeOpenShiftWithManager(mgr ctrl.Manager) error {
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright Red Hat
+
+//go:build devmode
+// +build devmode
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Fake the OpenShift integrations", func() {
+	FakeOpenShiftNamespace := "my-fake-openshift-ns"
+
+	It("should create the serving certificate requested by a Service", func() {
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: FakeOpenShiftNamespace}})
+		Expect(err).Should(BeNil())
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "my-served-service",
+				Namespace:   FakeOpenShiftNamespace,
+				Annotations: map[string]string{SERVING_CERT_SECRET_ANNOTATION: "my-serving-cert"},
+			},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "https", Port: 443}}},
+		}
+		Expect(k8sClient.Create(context.TODO(), service)).To(Succeed())
+		r := &fakeServingCertReconciler{Client: k8sClient}
+		req := ctrl.Request{}
+		req.Name = service.Name
+		req.Namespace = FakeOpenShiftNamespace
+		_, err = r.Reconcile(context.TODO(), req)
+		Expect(err).Should(BeNil())
+
+		secret := &corev1.Secret{}
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: "my-serving-cert", Namespace: FakeOpenShiftNamespace}, secret)
+		Expect(err).Should(BeNil())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(secret.Data).To(HaveKey(corev1.TLSPrivateKeyKey))
+		Expect(isCertificateForHosts(secret.Data[corev1.TLSCertKey], []string{
+			"my-served-service.my-fake-openshift-ns.svc",
+			"my-served-service.my-fake-openshift-ns.svc.cluster.local",
+		})).To(BeTrue())
+		Expect(metav1.IsControlledBy(secret, service)).To(BeTrue())
+
+		By("keeping the certificate already created", func() {
+			_, err := r.Reconcile(context.TODO(), req)
+			Expect(err).Should(BeNil())
+			existing := &corev1.Secret{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(secret), existing)
+			Expect(err).Should(BeNil())
+			Expect(existing.Data).To(Equal(secret.Data))
+		})
+		By("ignoring the Services without the annotation", func() {
+			other := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-unserved-service", Namespace: FakeOpenShiftNamespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+			}
+			Expect(k8sClient.Create(context.TODO(), other)).To(Succeed())
+			req.Name = other.Name
+			_, err := r.Reconcile(context.TODO(), req)
+			Expect(err).Should(BeNil())
+			secrets := &corev1.SecretList{}
+			err = k8sClient.List(context.TODO(), secrets, client.InNamespace(FakeOpenShiftNamespace))
+			Expect(err).Should(BeNil())
+			for _, s := range secrets.Items {
+				Expect(metav1.IsControlledBy(&s, other)).To(BeFalse())
+			}
+		})
+	})
+	It("should publish the CA of the router once an Ingress is exposed with a route", func() {
+		r := &fakeRouterReconciler{Client: k8sClient}
+		req := ctrl.Request{}
+		req.Name = "my-routed-ingress"
+		req.Namespace = FakeOpenShiftNamespace
+		_, err := r.Reconcile(context.TODO(), req)
+		Expect(err).Should(BeNil())
+		configMap := &corev1.ConfigMap{}
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: ingressCAName, Namespace: ingressCANamespace}, configMap)
+		Expect(err).Should(BeNil())
+		Expect(isCertificateForHosts([]byte(configMap.Data[ingressCAKey]), []string{"router-default.fake-openshift"})).To(BeTrue())
+
+		By("keeping the CA already published", func() {
+			_, err := r.Reconcile(context.TODO(), req)
+			Expect(err).Should(BeNil())
+			existing := &corev1.ConfigMap{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(configMap), existing)
+			Expect(err).Should(BeNil())
+			Expect(existing.Data).To(Equal(configMap.Data))
+		})
+	})
+})
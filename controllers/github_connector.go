@@ -0,0 +1,74 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Render the dex config of a GitHub connector. The client secret, and the client ID read from a secret, are read by dex
+// from environment variables, the CA of a GitHub Enterprise server is mounted in the dex pod.
+func (r *DexServerReconciler) renderGitHubConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// get an alphanumeric ID for the connector that can be used as a suffix in the env variable names containing the secrets of this connector
+	connectorAlphanumericId := getUniqueAlphanumericIdForConnector(connector)
+
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	err := r.copySecretToDexServerNamespace(dexServer, connector.GitHub.ClientSecretRef, ctx)
+	if err != nil {
+		return DexConnectorSpec{}, err
+	}
+
+	// Environment variable that references the GitHub client secret copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple GitHub connectors
+	clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + connectorAlphanumericId
+
+	// The client ID read from a secret is referenced by its own env variable in the dexserver deployment
+	clientID := connector.GitHub.ClientID
+	if connector.GitHub.ClientIDRef.Name != "" {
+		err := r.copySecretToDexServerNamespace(dexServer, connector.GitHub.ClientIDRef, ctx)
+		if err != nil {
+			return DexConnectorSpec{}, err
+		}
+		clientID = "$" + GITHUB_CLIENT_ID_ENV_VAR + "_" + connectorAlphanumericId
+	}
+
+	// The CA of a GitHub Enterprise server is mounted in the dex pod, from the secret copied into the
+	// dexserver ns or from the ConfigMap of the dex config for an inline CA
+	var rootCAPath string
+	if connector.GitHub.RootCARef.Name != "" {
+		err := r.copySecretToDexServerNamespace(dexServer, connector.GitHub.RootCARef, ctx)
+		if err != nil {
+			return DexConnectorSpec{}, err
+		}
+	}
+	if connector.GitHub.HostName != "" && (connector.GitHub.RootCARef.Name != "" || len(connector.GitHub.RootCAData) > 0) {
+		rootCAPath = "/etc/dex/githubcerts/" + connector.Id + "/ca.crt"
+	}
+
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeGitHub),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			ClientID:             clientID,
+			ClientSecret:         clientSecretEnvVariable,
+			RedirectURI:          connector.GitHub.RedirectURI,
+			Org:                  connector.GitHub.Org,
+			Orgs:                 connector.GitHub.Orgs,
+			HostName:             connector.GitHub.HostName,
+			RootCA:               rootCAPath,
+			TeamNameField:        connector.GitHub.TeamNameField,
+			LoadAllGroups:        connector.GitHub.LoadAllGroups,
+			UseLoginAsID:         connector.GitHub.UseLoginAsID,
+			PreferredEmailDomain: connector.GitHub.PreferredEmailDomain,
+		},
+	}, nil
+}
+
+// Key of the ConfigMap of the dex config holding the inline CA of a GitHub Enterprise server. The hex id of the
+// connector keeps the key valid whatever the id of the connector.
+func getGitHubRootCAKey(connector authv1alpha1.ConnectorSpec) string {
+	return "github-ca-" + getUniqueAlphanumericIdForConnector(connector) + ".crt"
+}
@@ -0,0 +1,47 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Render the dex config of a Google connector, with the service account key mounted in the dex pod
+func (r *DexServerReconciler) renderGoogleConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	err := r.copySecretToDexServerNamespace(dexServer, connector.Google.ClientSecretRef, ctx)
+	if err != nil {
+		return DexConnectorSpec{}, err
+	}
+
+	// Environment variable that references the Google client secret copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple Google connectors
+	clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + getUniqueAlphanumericIdForConnector(connector)
+
+	// If there is a secret reference to the service account, it is mounted in the dex pod
+	var serviceAccountFilePath string
+	if connector.Google.ServiceAccountRef.Name != "" {
+		err := r.copySecretToDexServerNamespace(dexServer, connector.Google.ServiceAccountRef, ctx)
+		if err != nil {
+			return DexConnectorSpec{}, err
+		}
+		serviceAccountFilePath = "/etc/dex/googlesa/" + connector.Id + "/" + GOOGLE_SERVICE_ACCOUNT_KEY
+	}
+
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeGoogle),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			ClientID:               connector.Google.ClientID,
+			ClientSecret:           clientSecretEnvVariable,
+			RedirectURI:            connector.Google.RedirectURI,
+			HostedDomains:          connector.Google.HostedDomains,
+			Groups:                 connector.Google.Groups,
+			ServiceAccountFilePath: serviceAccountFilePath,
+			AdminEmail:             connector.Google.AdminEmail,
+		},
+	}, nil
+}
@@ -0,0 +1,290 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// ConfigMap holding the ClusterRoleBindings of spec.groupBindings, to be reviewed and applied by an administrator
+	GROUP_BINDINGS_SUFFIX = "-group-bindings"
+	GROUP_BINDINGS_KEY    = "clusterrolebindings.yaml"
+
+	// Only group of the test user logged in by the mockCallback connectors
+	MOCK_CALLBACK_GROUP = "authors"
+)
+
+// Name of the ClusterRoleBinding of a group binding, unique across the DexServers of the cluster
+func getGroupBindingName(dexServer *authv1alpha1.DexServer, binding authv1alpha1.GroupBinding) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s/%s/%s/%s/%s", dexServer.Namespace, dexServer.Name, binding.Connector, binding.Group, binding.ClusterRole)
+	return fmt.Sprintf("dex-group-%x", h.Sum(nil))[:26]
+}
+
+// Labels selecting the ClusterRoleBindings of the group bindings of the DexServer
+func getGroupBindingLabels(dexServer *authv1alpha1.DexServer) map[string]string {
+	return map[string]string{
+		"dexconfig_name":      dexServer.Name,
+		"dexconfig_namespace": dexServer.Namespace,
+		COMPONENT_LABEL:       componentGroupBindings,
+	}
+}
+
+// Check that the connector of a group binding provides the group in the tokens it issues
+func validateGroupBinding(dexServer *authv1alpha1.DexServer, binding authv1alpha1.GroupBinding) error {
+	for _, connector := range dexServer.Spec.Connectors {
+		if connector.Id != binding.Connector {
+			continue
+		}
+		switch connector.Type {
+		case authv1alpha1.ConnectorTypeAuthProxy:
+			// the groups of the header, or the static groups of all the users
+			if connector.AuthProxy.GroupHeader == "" && !containsString(connector.AuthProxy.Groups, binding.Group) {
+				return fmt.Errorf("group %q is not one of the groups of connector %s, which has no group header", binding.Group, binding.Connector)
+			}
+			return nil
+		case authv1alpha1.ConnectorTypeMockCallback:
+			// the test user of the mockCallback connector is only in the authors group
+			if binding.Group != MOCK_CALLBACK_GROUP {
+				return fmt.Errorf("group %q is not the %s group of the test user of connector %s", binding.Group, MOCK_CALLBACK_GROUP, binding.Connector)
+			}
+			return nil
+		case authv1alpha1.ConnectorTypeMockPassword:
+			return fmt.Errorf("the test user of connector %s has no groups", binding.Connector)
+		case authv1alpha1.ConnectorTypeBitbucketCloud:
+			// dex only returns the team and team/group groups of the configured teams
+			teams := connector.BitbucketCloud.Teams
+			if len(teams) == 0 {
+				return nil
+			}
+			for _, team := range teams {
+				if team == strings.SplitN(binding.Group, "/", 2)[0] {
+					return nil
+				}
+			}
+			return fmt.Errorf("group %q is not one of the teams of connector %s", binding.Group, binding.Connector)
+		case authv1alpha1.ConnectorTypeGitHub:
+			// dex only returns the orgs and org:team groups of the configured orgs, unless all the groups are loaded
+			if connector.GitHub.LoadAllGroups {
+				return nil
+			}
+			orgs := append([]authv1alpha1.Org{}, connector.GitHub.Orgs...)
+			if connector.GitHub.Org != "" {
+				orgs = append(orgs, authv1alpha1.Org{Name: connector.GitHub.Org})
+			}
+			return validateOrgTeamGroup(orgs, binding)
+		case authv1alpha1.ConnectorTypeGitea:
+			// like GitHub, unless all the groups are loaded
+			if connector.Gitea.LoadAllGroups {
+				return nil
+			}
+			return validateOrgTeamGroup(connector.Gitea.Orgs, binding)
+		case authv1alpha1.ConnectorTypeGoogle:
+			if connector.Google.ServiceAccountRef.Name == "" {
+				return fmt.Errorf("connector %s has no service account to look up the groups", binding.Connector)
+			}
+			// dex only returns the configured groups
+			if len(connector.Google.Groups) > 0 && !containsString(connector.Google.Groups, binding.Group) {
+				return fmt.Errorf("group %q is not one of the groups of connector %s", binding.Group, binding.Connector)
+			}
+			return nil
+		case authv1alpha1.ConnectorTypeLDAP:
+			if connector.LDAP.GroupSearch.BaseDN == "" {
+				return fmt.Errorf("connector %s has no group search", binding.Connector)
+			}
+			return nil
+		case authv1alpha1.ConnectorTypeOIDC:
+			// dex only returns the groups claim with insecureEnableGroups, and the groups built from other claims
+			if !connector.OIDC.InsecureEnableGroups && len(connector.OIDC.ClaimModifications.NewGroupFromClaims) == 0 {
+				return fmt.Errorf("connector %s has neither the groups claim nor groups built from claims", binding.Connector)
+			}
+			return nil
+		case authv1alpha1.ConnectorTypeSAML:
+			if connector.SAML.GroupsAttr == "" {
+				return fmt.Errorf("connector %s has no groups attribute", binding.Connector)
+			}
+			return nil
+		default:
+			return nil
+		}
+	}
+	return fmt.Errorf("group %q is bound to the unknown connector %s", binding.Group, binding.Connector)
+}
+
+// Check that the org or org:team group of a binding is one of the orgs and teams a connector is restricted to
+func validateOrgTeamGroup(orgs []authv1alpha1.Org, binding authv1alpha1.GroupBinding) error {
+	if len(orgs) == 0 {
+		return nil
+	}
+	orgTeam := strings.SplitN(binding.Group, ":", 2)
+	for _, org := range orgs {
+		if org.Name != orgTeam[0] {
+			continue
+		}
+		if len(orgTeam) == 1 || len(org.Teams) == 0 {
+			return nil
+		}
+		for _, team := range org.Teams {
+			if team == orgTeam[1] {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("group %q is not one of the orgs and teams of connector %s", binding.Group, binding.Connector)
+}
+
+// Get the ClusterRoleBindings of spec.groupBindings
+func (r *DexServerReconciler) getGroupBindings(dexServer *authv1alpha1.DexServer) ([]rbacv1.ClusterRoleBinding, error) {
+	crbs := []rbacv1.ClusterRoleBinding{}
+	for _, binding := range dexServer.Spec.GroupBindings.Bindings {
+		if err := validateGroupBinding(dexServer, binding); err != nil {
+			return nil, err
+		}
+		crb := rbacv1.ClusterRoleBinding{
+			TypeMeta: metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        getGroupBindingName(dexServer, binding),
+				Labels:      getGroupBindingLabels(dexServer),
+				Annotations: map[string]string{},
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     binding.ClusterRole,
+			},
+			Subjects: []rbacv1.Subject{{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.GroupKind,
+				Name:     dexServer.Spec.GroupBindings.GroupsPrefix + binding.Group,
+			}},
+		}
+		r.addManagedMetadata(dexServer, componentGroupBindings, crb.Labels, crb.Annotations)
+		crbs = append(crbs, crb)
+	}
+	return crbs, nil
+}
+
+// Suggest the ClusterRoleBindings of spec.groupBindings in a ConfigMap, and create them with
+// spec.groupBindings.create. The ClusterRoleBindings that are no longer in the spec are deleted.
+func (r *DexServerReconciler) syncGroupBindings(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	name := dexServer.Name + GROUP_BINDINGS_SUFFIX
+	log.Info("syncGroupBindings", "ConfigMap.Name", name)
+
+	crbs, err := r.getGroupBindings(dexServer)
+	if err != nil {
+		return err
+	}
+	if err := r.syncGroupBindingsConfigMap(dexServer, ctx, name, crbs); err != nil {
+		return err
+	}
+
+	desired := map[string]bool{}
+	if dexServer.Spec.GroupBindings.Create {
+		for i := range crbs {
+			desired[crbs[i].Name] = true
+			if err := r.applyGroupBinding(ctx, &crbs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	existing := &rbacv1.ClusterRoleBindingList{}
+	if err := r.List(ctx, existing, client.MatchingLabels(getGroupBindingLabels(dexServer))); err != nil {
+		return err
+	}
+	for i := range existing.Items {
+		if desired[existing.Items[i].Name] {
+			continue
+		}
+		log.Info("Deleting a group binding", "ClusterRoleBinding.Name", existing.Items[i].Name)
+		if err := r.Delete(ctx, &existing.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DexServerReconciler) syncGroupBindingsConfigMap(dexServer *authv1alpha1.DexServer, ctx context.Context, name string, crbs []rbacv1.ClusterRoleBinding) error {
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: dexServer.Namespace}, configMap)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		if len(crbs) == 0 {
+			return nil
+		}
+	case err != nil:
+		return err
+	case len(crbs) == 0:
+		return r.Delete(ctx, configMap)
+	}
+
+	// a List can be applied with kubectl apply -f
+	list := struct {
+		APIVersion string                      `json:"apiVersion"`
+		Kind       string                      `json:"kind"`
+		Items      []rbacv1.ClusterRoleBinding `json:"items"`
+	}{APIVersion: "v1", Kind: "List", Items: crbs}
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	if configMap.Labels == nil {
+		configMap.Labels = map[string]string{}
+	}
+	if configMap.Annotations == nil {
+		configMap.Annotations = map[string]string{}
+	}
+	configMap.Labels["app"] = dexServer.Name
+	r.addManagedMetadata(dexServer, componentGroupBindings, configMap.Labels, configMap.Annotations)
+	configMap.Data = map[string]string{GROUP_BINDINGS_KEY: string(data)}
+	if configMap.ResourceVersion == "" {
+		configMap.Name = name
+		configMap.Namespace = dexServer.Namespace
+		if err := ctrl.SetControllerReference(dexServer, configMap, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, configMap)
+	}
+	return r.Update(ctx, configMap)
+}
+
+func (r *DexServerReconciler) applyGroupBinding(ctx context.Context, crb *rbacv1.ClusterRoleBinding) error {
+	existing := &rbacv1.ClusterRoleBinding{}
+	err := r.Get(ctx, types.NamespacedName{Name: crb.Name}, existing)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		ctrllog.FromContext(ctx).Info("Creating a group binding", "ClusterRoleBinding.Name", crb.Name, "ClusterRole", crb.RoleRef.Name)
+		return r.Create(ctx, crb)
+	case err != nil:
+		return err
+	}
+	// the name is derived from the role and group, only the metadata may change
+	if equality.Semantic.DeepEqual(existing.Labels, crb.Labels) && equality.Semantic.DeepEqual(existing.Annotations, crb.Annotations) {
+		return nil
+	}
+	existing.Labels = crb.Labels
+	existing.Annotations = crb.Annotations
+	return r.Update(ctx, existing)
+}
+
+// Delete the ClusterRoleBindings of the group bindings, they are cluster scoped and can't be owned by the DexServer
+func (r *DexServerReconciler) deleteGroupBindings(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	return r.DeleteAllOf(ctx, &rbacv1.ClusterRoleBinding{}, client.MatchingLabels(getGroupBindingLabels(dexServer)))
+}
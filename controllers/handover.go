@@ -0,0 +1,176 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	networkingv1 "k8s.io/api/networking/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/dexstorage"
+)
+
+// The handover of an issuer from a DexServer to the DexServer replacing it goes through these phases:
+//   - ImportingKeys: the signing keys of the replaced dex server are copied before the new dex server starts, so
+//     that the tokens it issued can still be verified
+//   - WaitingForAvailable: the new dex server starts without an Ingress, the replaced one still serves the issuer
+//   - SwitchingRoute: the replaced DexServer is annotated with HANDED_OVER_ANNOTATION and removes its Ingress
+//   - Completed: the Ingress of the new DexServer is created, the replaced DexServer can be deleted
+
+// checkHandoverAllowed verifies that the replaced DexServer has the issuer of the new one, and that it is in the
+// namespace of the new one or that its owner allowed the new DexServer to take over its issuer with
+// HANDOVER_ALLOWED_ANNOTATION
+func checkHandoverAllowed(dexServer *authv1alpha1.DexServer, replaced *authv1alpha1.DexServer) error {
+	if replaced.Spec.Issuer != dexServer.Spec.Issuer {
+		return fmt.Errorf("the replaced DexServer has issuer %s, expected %s", replaced.Spec.Issuer, dexServer.Spec.Issuer)
+	}
+	if replaced.Namespace == dexServer.Namespace {
+		return nil
+	}
+	if replaced.Annotations[HANDOVER_ALLOWED_ANNOTATION] != dexServer.Namespace+"/"+dexServer.Name {
+		return fmt.Errorf("the replaced DexServer %s/%s is in another namespace and is not annotated with %s: %s/%s",
+			replaced.Namespace, replaced.Name, HANDOVER_ALLOWED_ANNOTATION, dexServer.Namespace, dexServer.Name)
+	}
+	return nil
+}
+
+func setHandoverPhase(dexServer *authv1alpha1.DexServer, phase authv1alpha1.HandoverPhase, message string) {
+	if dexServer.Status.Handover == nil || dexServer.Status.Handover.Phase != phase {
+		dexServer.Status.Handover = &authv1alpha1.HandoverStatus{
+			Phase:              phase,
+			LastTransitionTime: metav1.Now(),
+		}
+	}
+	dexServer.Status.Handover.Message = message
+}
+
+// isWaitingForHandover is true while the issuer is still served by the DexServer this one replaces
+func isWaitingForHandover(dexServer *authv1alpha1.DexServer) bool {
+	return dexServer.Spec.Replaces != nil &&
+		(dexServer.Status.Handover == nil || dexServer.Status.Handover.Phase != authv1alpha1.HandoverPhaseCompleted)
+}
+
+// isIssuerHandedOver is true once the issuer is served by the DexServer replacing this one
+func isIssuerHandedOver(dexServer *authv1alpha1.DexServer) bool {
+	return dexServer.Annotations[HANDED_OVER_ANNOTATION] != ""
+}
+
+// importSigningKeys copies the signing keys of the replaced dex server into the storage of the new one
+func (r *DexServerReconciler) importSigningKeys(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	replaces := dexServer.Spec.Replaces
+	if replaces == nil {
+		dexServer.Status.Handover = nil
+		return nil
+	}
+	if dexServer.Status.Handover != nil && dexServer.Status.Handover.Phase != authv1alpha1.HandoverPhaseImportingKeys {
+		return nil
+	}
+	log.Info("importSigningKeys", "Replaces.Namespace", replaces.Namespace, "Replaces.Name", replaces.Name)
+	setHandoverPhase(dexServer, authv1alpha1.HandoverPhaseImportingKeys, "")
+
+	replaced := &authv1alpha1.DexServer{}
+	if err := r.Get(ctx, types.NamespacedName{Name: replaces.Name, Namespace: replaces.Namespace}, replaced); err != nil {
+		return errors.Wrap(err, "error getting the replaced DexServer")
+	}
+	if err := checkHandoverAllowed(dexServer, replaced); err != nil {
+		return err
+	}
+
+	source, err := r.openDexStorage(replaced, ctx)
+	if err != nil {
+		return errors.Wrap(err, "error opening the storage of the replaced dex server")
+	}
+	defer source.Close()
+	keys, err := source.ExportKeys(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error getting the signing keys of the replaced dex server")
+	}
+	if keys == nil {
+		return fmt.Errorf("the replaced dex server has no signing keys yet")
+	}
+	// the keys are written through the storage of the new dex server, which may already have started and generated
+	// its own keys
+	target, err := r.openDexStorage(dexServer, ctx)
+	if err != nil {
+		return errors.Wrap(err, "error opening the storage of the new dex server")
+	}
+	defer target.Close()
+	if len(r.dryRun) == 0 {
+		if err := target.Import(ctx, &dexstorage.Objects{Keys: keys}); err != nil {
+			return errors.Wrap(err, "error importing the signing keys")
+		}
+	}
+
+	setHandoverPhase(dexServer, authv1alpha1.HandoverPhaseWaitingForAvailable, "")
+	return nil
+}
+
+// advanceHandover moves the handover to its next phase once the new dex server is available. It returns whether the
+// handover is still in progress.
+func (r *DexServerReconciler) advanceHandover(dexServer *authv1alpha1.DexServer, ctx context.Context, available bool) (bool, error) {
+	log := ctrllog.FromContext(ctx)
+	if dexServer.Spec.Replaces == nil || dexServer.Status.Handover == nil {
+		return false, nil
+	}
+	replaces := dexServer.Spec.Replaces
+
+	switch dexServer.Status.Handover.Phase {
+	case authv1alpha1.HandoverPhaseWaitingForAvailable:
+		if !available {
+			return true, nil
+		}
+		log.Info("Handing over the issuer", "Replaces.Namespace", replaces.Namespace, "Replaces.Name", replaces.Name)
+		replaced := &authv1alpha1.DexServer{}
+		if err := r.Get(ctx, types.NamespacedName{Name: replaces.Name, Namespace: replaces.Namespace}, replaced); err != nil {
+			return true, errors.Wrap(err, "error getting the replaced DexServer")
+		}
+		if err := checkHandoverAllowed(dexServer, replaced); err != nil {
+			return true, err
+		}
+		patch := client.MergeFrom(replaced.DeepCopy())
+		if replaced.Annotations == nil {
+			replaced.Annotations = map[string]string{}
+		}
+		replaced.Annotations[HANDED_OVER_ANNOTATION] = dexServer.Namespace + "/" + dexServer.Name
+		if err := r.Patch(ctx, replaced, patch); err != nil {
+			return true, errors.Wrap(err, "error annotating the replaced DexServer")
+		}
+		setHandoverPhase(dexServer, authv1alpha1.HandoverPhaseSwitchingRoute, "waiting for the replaced DexServer to remove its Ingress")
+		return true, nil
+	case authv1alpha1.HandoverPhaseSwitchingRoute:
+		ingress := &networkingv1.Ingress{}
+		err := r.Get(ctx, types.NamespacedName{Name: replaces.Name, Namespace: replaces.Namespace}, ingress)
+		switch {
+		case err == nil:
+			return true, nil
+		case !kubeerrors.IsNotFound(err):
+			return true, err
+		}
+		setHandoverPhase(dexServer, authv1alpha1.HandoverPhaseCompleted, "the replaced DexServer can be deleted")
+		return false, r.syncIngress(dexServer, ctx)
+	}
+	return false, nil
+}
+
+// deleteHandedOverIngress removes the Ingress of a DexServer whose issuer is now served by its replacement
+func (r *DexServerReconciler) deleteHandedOverIngress(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	ingress := &networkingv1.Ingress{}
+	err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, ingress)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+	ctrllog.FromContext(ctx).Info("Deleting the Ingress of the handed over issuer", "HandedOverTo", dexServer.Annotations[HANDED_OVER_ANNOTATION])
+	return client.IgnoreNotFound(r.Delete(ctx, ingress))
+}
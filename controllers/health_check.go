@@ -0,0 +1,86 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Path of the health check of the dex web server, as checked by the probes of the dex container
+	DEX_HEALTH_CHECK_PATH = "/healthz"
+)
+
+// Get the path of the health check, dex serves it under the path of the issuer
+func getHealthCheckPath(dexServer *authv1alpha1.DexServer) string {
+	return getIssuerPath(dexServer) + DEX_HEALTH_CHECK_PATH
+}
+
+// Get the protocol dex serves to the load balancer, HTTP when the load balancer terminates the TLS connections
+func getHealthCheckProtocol(dexServer *authv1alpha1.DexServer) string {
+	if isTLSTerminatedAtLoadBalancer(dexServer) {
+		return "HTTP"
+	}
+	return "HTTPS"
+}
+
+// Get the annotations of the dex web Service configuring the health checks of the cloud load balancer, see
+// spec.service.healthCheckPreset. The TCP load balancers of GCP check the nodes, they have no annotation.
+func getServiceHealthCheckAnnotations(dexServer *authv1alpha1.DexServer) map[string]string {
+	protocol := getHealthCheckProtocol(dexServer)
+	loadBalancer := dexServer.Spec.Service.Type == corev1.ServiceTypeLoadBalancer
+	switch {
+	case dexServer.Spec.Service.HealthCheckPreset == authv1alpha1.HealthCheckPresetAWS && loadBalancer:
+		return map[string]string{
+			"service.beta.kubernetes.io/aws-load-balancer-healthcheck-protocol": protocol,
+			"service.beta.kubernetes.io/aws-load-balancer-healthcheck-path":     getHealthCheckPath(dexServer),
+			"service.beta.kubernetes.io/aws-load-balancer-healthcheck-port":     "traffic-port",
+		}
+	case dexServer.Spec.Service.HealthCheckPreset == authv1alpha1.HealthCheckPresetAzure && loadBalancer:
+		return map[string]string{
+			"service.beta.kubernetes.io/azure-load-balancer-health-probe-protocol":     strings.ToLower(protocol),
+			"service.beta.kubernetes.io/azure-load-balancer-health-probe-request-path": getHealthCheckPath(dexServer),
+		}
+	case dexServer.Spec.Service.HealthCheckPreset == authv1alpha1.HealthCheckPresetGCP && !loadBalancer:
+		// the GKE Ingress checks the path of the readiness probe, with the protocol of the Service port
+		return map[string]string{
+			"cloud.google.com/app-protocols": `{"http":"HTTPS"}`,
+		}
+	}
+	return nil
+}
+
+// Get the annotations of the Ingress configuring the health checks of the cloud load balancer, see
+// spec.service.healthCheckPreset
+func getIngressHealthCheckAnnotations(dexServer *authv1alpha1.DexServer) map[string]string {
+	switch dexServer.Spec.Service.HealthCheckPreset {
+	case authv1alpha1.HealthCheckPresetAWS:
+		return map[string]string{
+			"alb.ingress.kubernetes.io/backend-protocol":     "HTTPS",
+			"alb.ingress.kubernetes.io/healthcheck-protocol": "HTTPS",
+			"alb.ingress.kubernetes.io/healthcheck-path":     getHealthCheckPath(dexServer),
+		}
+	case authv1alpha1.HealthCheckPresetAzure:
+		return map[string]string{
+			"appgw.ingress.kubernetes.io/backend-protocol":  "https",
+			"appgw.ingress.kubernetes.io/health-probe-path": getHealthCheckPath(dexServer),
+		}
+	}
+	return nil
+}
+
+// Merge the annotations of a preset under the annotations set in the spec
+func mergePresetAnnotations(preset map[string]string, annotations map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range preset {
+		merged[key] = value
+	}
+	for key, value := range annotations {
+		merged[key] = value
+	}
+	return merged
+}
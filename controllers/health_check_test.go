@@ -0,0 +1,58 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Configure the health checks of the load balancers", func() {
+	It("should check the protocol dex serves to the load balancer", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		dexServer.Spec.Service.Type = corev1.ServiceTypeLoadBalancer
+		dexServer.Spec.Service.HealthCheckPreset = authv1alpha1.HealthCheckPresetAWS
+		annotations := getServiceHealthCheckAnnotations(dexServer)
+		Expect(annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-healthcheck-protocol", "HTTPS"))
+		Expect(annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-healthcheck-path", "/healthz"))
+
+		dexServer.Spec.Service.TLSTermination = authv1alpha1.TLSTerminationLoadBalancer
+		dexServer.Spec.Service.HealthCheckPreset = authv1alpha1.HealthCheckPresetAzure
+		annotations = getServiceHealthCheckAnnotations(dexServer)
+		Expect(annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/azure-load-balancer-health-probe-protocol", "http"))
+
+		dexServer.Spec.Service.HealthCheckPreset = authv1alpha1.HealthCheckPresetGCP
+		Expect(getServiceHealthCheckAnnotations(dexServer)).To(BeEmpty())
+	})
+	It("should configure the health checks of the Ingress", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		dexServer.Spec.Service.HealthCheckPreset = authv1alpha1.HealthCheckPresetGCP
+		Expect(getServiceHealthCheckAnnotations(dexServer)).To(HaveKeyWithValue("cloud.google.com/app-protocols", `{"http":"HTTPS"}`))
+		Expect(getIngressHealthCheckAnnotations(dexServer)).To(BeEmpty())
+
+		dexServer.Spec.Service.HealthCheckPreset = authv1alpha1.HealthCheckPresetAWS
+		dexServer.Spec.Ingress.Annotations = map[string]string{"alb.ingress.kubernetes.io/healthcheck-path": "/dex/healthz"}
+		annotations, err := getIngressAnnotations(dexServer, []string{"dex.example.com"})
+		Expect(err).To(BeNil())
+		Expect(annotations).To(HaveKeyWithValue("alb.ingress.kubernetes.io/backend-protocol", "HTTPS"))
+		Expect(annotations).To(HaveKeyWithValue("alb.ingress.kubernetes.io/healthcheck-path", "/dex/healthz"))
+	})
+	It("should set the cipher suites of the Ingress", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		dexServer.Spec.Ingress.TLS.CipherSuites = []string{"ECDHE-ECDSA-AES128-GCM-SHA256", "ECDHE-RSA-AES128-GCM-SHA256"}
+		annotations, err := getIngressAnnotations(dexServer, []string{"dex.example.com"})
+		Expect(err).To(BeNil())
+		Expect(annotations).To(HaveKeyWithValue(NGINX_SSL_CIPHERS_ANNOTATION, "ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256"))
+
+		dexServer.Spec.Ingress.TLS.CipherSuites = []string{"ECDHE-RSA-AES128-GCM-SHA256:!RC4"}
+		_, err = getIngressAnnotations(dexServer, []string{"dex.example.com"})
+		Expect(err).NotTo(BeNil())
+		dexServer.Spec.Ingress.TLS.CipherSuites = []string{"ECDHE-RSA-AES128-GCM-SHA256"}
+		dexServer.Spec.Ingress.Annotations = map[string]string{NGINX_SSL_CIPHERS_ANNOTATION: "HIGH"}
+		_, err = getIngressAnnotations(dexServer, []string{"dex.example.com"})
+		Expect(err).NotTo(BeNil())
+	})
+})
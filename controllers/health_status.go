@@ -0,0 +1,91 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Reasons of the Degraded condition, the other reasons are the reasons of the Applied condition
+const (
+	DEGRADED_REASON_AS_EXPECTED        = "AsExpected"
+	DEGRADED_REASON_NOT_AVAILABLE      = "NotAvailable"
+	DEGRADED_REASON_SMOKE_TEST_FAILED  = "SmokeTestFailed"
+	DEGRADED_REASON_CONNECTORS_SKIPPED = "ConnectorsSkipped"
+)
+
+// Get the Degraded condition from the other conditions. dex is degraded while its pods serve the logins with an
+// outdated configuration, because the last configuration could not be applied or failed the smoke test, or with
+// connectors left out of the configuration.
+func getDegradedCondition(conditions []metav1.Condition) metav1.Condition {
+	if !meta.IsStatusConditionTrue(conditions, authv1alpha1.DexServerDeploymentAvailable) {
+		// no dex pod serves the logins, see status.phase
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  DEGRADED_REASON_NOT_AVAILABLE,
+			Message: "DexServer deployment is not available",
+		}
+	}
+	if applied := meta.FindStatusCondition(conditions, authv1alpha1.DexServerConditionTypeApplied); applied != nil && applied.Status == metav1.ConditionFalse {
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  applied.Reason,
+			Message: "the dex pods serve the previous configuration, " + applied.Message,
+		}
+	}
+	if ready := meta.FindStatusCondition(conditions, authv1alpha1.DexServerConditionTypeReady); ready != nil && ready.Reason == DEGRADED_REASON_SMOKE_TEST_FAILED {
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  DEGRADED_REASON_SMOKE_TEST_FAILED,
+			Message: ready.Message,
+		}
+	}
+	if skipped := meta.FindStatusCondition(conditions, authv1alpha1.DexServerConditionTypeConnectorsSkipped); skipped != nil && skipped.Status == metav1.ConditionTrue {
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  DEGRADED_REASON_CONNECTORS_SKIPPED,
+			Message: skipped.Message,
+		}
+	}
+	return metav1.Condition{
+		Type:    authv1alpha1.DexServerConditionTypeDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  DEGRADED_REASON_AS_EXPECTED,
+		Message: "DexServer is not degraded",
+	}
+}
+
+// Get status.phase from the conditions, the Degraded condition included
+func getDexServerPhase(dexServer *authv1alpha1.DexServer) authv1alpha1.DexServerPhase {
+	conditions := dexServer.Status.Conditions
+	switch {
+	case dexServer.DeletionTimestamp != nil:
+		return authv1alpha1.DexServerPhaseDeleting
+	case meta.IsStatusConditionTrue(conditions, authv1alpha1.DexServerConditionTypeDegraded):
+		return authv1alpha1.DexServerPhaseDegraded
+	case meta.IsStatusConditionTrue(conditions, authv1alpha1.DexServerConditionTypeReady) &&
+		meta.IsStatusConditionTrue(conditions, authv1alpha1.DexServerDeploymentAvailable):
+		return authv1alpha1.DexServerPhaseReady
+	}
+	applied := meta.FindStatusCondition(conditions, authv1alpha1.DexServerConditionTypeApplied)
+	switch {
+	case applied == nil || applied.Reason == "WaitingForSecret":
+		return authv1alpha1.DexServerPhasePending
+	case applied.Status == metav1.ConditionFalse && !meta.IsStatusConditionTrue(conditions, authv1alpha1.DexServerDeploymentAvailable):
+		return authv1alpha1.DexServerPhaseFailed
+	}
+	return authv1alpha1.DexServerPhaseProgressing
+}
+
+// Set the Degraded condition and status.phase, the health contract of the DexServer for the GitOps tools
+func setHealthStatus(dexServer *authv1alpha1.DexServer) {
+	meta.SetStatusCondition(&dexServer.Status.Conditions, getDegradedCondition(dexServer.Status.Conditions))
+	dexServer.Status.Phase = getDexServerPhase(dexServer)
+}
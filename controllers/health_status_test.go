@@ -0,0 +1,66 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Report the health of the DexServer", func() {
+	setConditions := func(dexServer *authv1alpha1.DexServer, conditions ...metav1.Condition) {
+		dexServer.Status.Conditions = mergeStatusConditions(dexServer.Status.Conditions, conditions...)
+		setHealthStatus(dexServer)
+	}
+	applied := metav1.Condition{Type: authv1alpha1.DexServerConditionTypeApplied, Status: metav1.ConditionTrue, Reason: "Applied"}
+	available := metav1.Condition{Type: authv1alpha1.DexServerDeploymentAvailable, Status: metav1.ConditionTrue, Reason: "Available"}
+	ready := metav1.Condition{Type: authv1alpha1.DexServerConditionTypeReady, Status: metav1.ConditionTrue, Reason: "Available"}
+
+	It("should summarize the rollout in status.phase", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		setConditions(dexServer)
+		Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhasePending))
+
+		setConditions(dexServer, applied, metav1.Condition{Type: authv1alpha1.DexServerDeploymentAvailable, Status: metav1.ConditionFalse, Reason: "NotAvailable"})
+		Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhaseProgressing))
+		Expect(meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded).Reason).To(Equal(DEGRADED_REASON_NOT_AVAILABLE))
+
+		setConditions(dexServer, available, ready)
+		Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhaseReady))
+		Expect(meta.IsStatusConditionFalse(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded)).To(BeTrue())
+
+		now := metav1.Now()
+		dexServer.DeletionTimestamp = &now
+		setConditions(dexServer)
+		Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhaseDeleting))
+	})
+	It("should report the failures", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		setConditions(dexServer, metav1.Condition{Type: authv1alpha1.DexServerConditionTypeApplied, Status: metav1.ConditionFalse, Reason: "WaitingForSecret"})
+		Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhasePending))
+
+		setConditions(dexServer, metav1.Condition{Type: authv1alpha1.DexServerConditionTypeApplied, Status: metav1.ConditionFalse, Reason: "ConfigMapFailed", Message: "failed"})
+		Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhaseFailed))
+
+		By("degrading the DexServer still serving the logins", func() {
+			setConditions(dexServer, available, ready)
+			Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhaseDegraded))
+			degraded := meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded)
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Reason).To(Equal("ConfigMapFailed"))
+		})
+		By("degrading the DexServer with skipped connectors", func() {
+			setConditions(dexServer, applied, metav1.Condition{Type: authv1alpha1.DexServerConditionTypeConnectorsSkipped, Status: metav1.ConditionTrue, Reason: "ConnectorsSkipped"})
+			Expect(meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded).Reason).To(Equal(DEGRADED_REASON_CONNECTORS_SKIPPED))
+		})
+		By("degrading the DexServer failing the smoke test", func() {
+			setConditions(dexServer, metav1.Condition{Type: authv1alpha1.DexServerConditionTypeReady, Status: metav1.ConditionFalse, Reason: "SmokeTestFailed"})
+			Expect(meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded).Reason).To(Equal(DEGRADED_REASON_SMOKE_TEST_FAILED))
+			Expect(dexServer.Status.Phase).To(Equal(authv1alpha1.DexServerPhaseDegraded))
+		})
+	})
+})
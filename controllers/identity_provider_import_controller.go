@@ -0,0 +1,415 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Namespace of the secrets and ConfigMaps referenced by the OpenShift identity providers
+	OPENSHIFT_CONFIG_NAMESPACE = "openshift-config"
+	// Name of the OpenShift OAuth config
+	OPENSHIFT_OAUTH_NAME = "cluster"
+	// ConfigMap holding the connectors proposed from the OpenShift identity providers
+	IMPORTED_CONNECTORS_SUFFIX = "-imported-connectors"
+	// Keys of the proposal ConfigMap, the connectors in the format of spec.connectors and the reasons an identity
+	// provider or some of its settings are not imported, one per line
+	IMPORTED_CONNECTORS_KEY       = "connectors.yaml"
+	IMPORTED_CONNECTORS_NOTES_KEY = "notes"
+	// Prefix of the ids of the imported connectors
+	IMPORTED_CONNECTOR_ID_PREFIX = "openshift-"
+	// Annotation of the DexServer listing the ids of the connectors already imported, so that a connector removed
+	// from the DexServer is not imported again
+	IMPORTED_CONNECTORS_ANNOTATION = "auth.identitatem.io/imported-connectors"
+)
+
+var invalidConnectorIdChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// credentialImport copies a key of a secret or ConfigMap of openshift-config into a secret of the DexServer
+// namespace, under the key read by the operator
+type credentialImport struct {
+	FromConfigMap bool
+	Source        string
+	SourceKey     string
+	Target        string
+	TargetKey     string
+}
+
+// IdentityProviderImportReconciler translates the identity providers of the OpenShift OAuth config into connectors
+// of a DexServer, to migrate the cluster identity providers to dex. The connectors are always proposed in a
+// ConfigMap, and added to the DexServer when CreateConnectors is set.
+type IdentityProviderImportReconciler struct {
+	client.Client
+	// Namespace and name of the DexServer the connectors are imported in
+	Namespace string
+	Name      string
+	// Add the imported connectors to the DexServer, instead of only proposing them
+	CreateConnectors bool
+}
+
+//+kubebuilder:rbac:groups=config.openshift.io,resources=oauths,verbs=get;list;watch
+
+// Reconcile proposes the connectors translated from the current identity providers, and adds the connectors that
+// were not imported yet when CreateConnectors is set. The connectors already in the DexServer are never changed.
+func (r *IdentityProviderImportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	log.V(1).Info("Reconciling identity provider import...")
+
+	oauth := &configv1.OAuth{}
+	if err := r.Get(ctx, types.NamespacedName{Name: OPENSHIFT_OAUTH_NAME}, oauth); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	connectors, imports, notes := translateIdentityProviders(oauth.Spec.IdentityProviders, r.Namespace)
+	if err := r.syncProposal(ctx, connectors, notes); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !r.CreateConnectors {
+		return ctrl.Result{}, nil
+	}
+
+	dexServer := &authv1alpha1.DexServer{}
+	if err := r.Get(ctx, types.NamespacedName{Name: r.Name, Namespace: r.Namespace}, dexServer); err != nil {
+		if kubeerrors.IsNotFound(err) {
+			// imported once the DexServer is created
+			log.Info("DexServer not found, the imported connectors are only proposed", "DexServer.Namespace", r.Namespace, "DexServer.Name", r.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	for _, credential := range imports {
+		if err := r.importCredential(ctx, credential); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	imported := map[string]bool{}
+	importedIds := []string{}
+	if annotation := dexServer.Annotations[IMPORTED_CONNECTORS_ANNOTATION]; annotation != "" {
+		importedIds = strings.Split(annotation, ",")
+	}
+	for _, id := range importedIds {
+		imported[id] = true
+	}
+	added := 0
+	for _, connector := range connectors {
+		if imported[connector.Id] {
+			continue
+		}
+		if !hasConnectorSpecWithId(dexServer.Spec.Connectors, connector.Id) {
+			dexServer.Spec.Connectors = append(dexServer.Spec.Connectors, connector)
+		}
+		importedIds = append(importedIds, connector.Id)
+		added++
+	}
+	if added == 0 {
+		return ctrl.Result{}, nil
+	}
+	if dexServer.Annotations == nil {
+		dexServer.Annotations = map[string]string{}
+	}
+	dexServer.Annotations[IMPORTED_CONNECTORS_ANNOTATION] = strings.Join(importedIds, ",")
+	log.Info("Adding the imported connectors to the DexServer", "DexServer.Namespace", r.Namespace, "DexServer.Name", r.Name, "Connectors", added)
+	return ctrl.Result{}, r.Update(ctx, dexServer)
+}
+
+// Write the proposed connectors and the notes of the import in the proposal ConfigMap
+func (r *IdentityProviderImportReconciler) syncProposal(ctx context.Context, connectors []authv1alpha1.ConnectorSpec, notes []string) error {
+	connectorsYaml, err := yaml.Marshal(connectors)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		IMPORTED_CONNECTORS_KEY:       string(connectorsYaml),
+		IMPORTED_CONNECTORS_NOTES_KEY: strings.Join(notes, "\n"),
+	}
+	name := r.Name + IMPORTED_CONNECTORS_SUFFIX
+	configMap := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: r.Namespace}, configMap)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: r.Namespace,
+				Labels: map[string]string{
+					MANAGED_BY_LABEL: MANAGED_BY_VALUE,
+				},
+			},
+			Data: data,
+		}
+		return r.Create(ctx, configMap)
+	case err != nil:
+		return err
+	}
+	if configMap.Data[IMPORTED_CONNECTORS_KEY] == data[IMPORTED_CONNECTORS_KEY] &&
+		configMap.Data[IMPORTED_CONNECTORS_NOTES_KEY] == data[IMPORTED_CONNECTORS_NOTES_KEY] {
+		return nil
+	}
+	configMap.Data = data
+	return r.Update(ctx, configMap)
+}
+
+// Copy a credential of an identity provider into the DexServer namespace. An existing secret is left as is.
+func (r *IdentityProviderImportReconciler) importCredential(ctx context.Context, credential credentialImport) error {
+	err := r.Get(ctx, types.NamespacedName{Name: credential.Target, Namespace: r.Namespace}, &corev1.Secret{})
+	if !kubeerrors.IsNotFound(err) {
+		return err
+	}
+	var value []byte
+	if credential.FromConfigMap {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: credential.Source, Namespace: OPENSHIFT_CONFIG_NAMESPACE}, configMap); err != nil {
+			return errors.Wrapf(err, "error getting ConfigMap %s of the identity providers", credential.Source)
+		}
+		value = []byte(configMap.Data[credential.SourceKey])
+	} else {
+		source := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: credential.Source, Namespace: OPENSHIFT_CONFIG_NAMESPACE}, source); err != nil {
+			return errors.Wrapf(err, "error getting secret %s of the identity providers", credential.Source)
+		}
+		value = source.Data[credential.SourceKey]
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      credential.Target,
+			Namespace: r.Namespace,
+			Labels: map[string]string{
+				IDP_CREDENTIAL_LABEL: "",
+				MANAGED_BY_LABEL:     MANAGED_BY_VALUE,
+			},
+		},
+		Data: map[string][]byte{credential.TargetKey: value},
+	}
+	ctrllog.FromContext(ctx).Info("Importing an identity provider credential", "Secret.Namespace", r.Namespace, "Secret.Name", credential.Target)
+	return r.Create(ctx, secret)
+}
+
+// Translate the OpenShift identity providers into connectors of a DexServer of namespace. The credentials of the
+// connectors reference secrets of namespace, which are copied from openshift-config by the returned imports. The
+// notes list the identity providers and settings that can't be translated.
+func translateIdentityProviders(idps []configv1.IdentityProvider, namespace string) ([]authv1alpha1.ConnectorSpec, []credentialImport, []string) {
+	connectors := []authv1alpha1.ConnectorSpec{}
+	imports := []credentialImport{}
+	notes := []string{}
+	for _, idp := range idps {
+		id := IMPORTED_CONNECTOR_ID_PREFIX + strings.Trim(invalidConnectorIdChars.ReplaceAllString(strings.ToLower(idp.Name), "-"), "-")
+		connector := authv1alpha1.ConnectorSpec{Name: idp.Name, Id: id}
+		// adds a note for the identity provider
+		note := func(format string, args ...interface{}) {
+			notes = append(notes, fmt.Sprintf("%s: ", idp.Name)+fmt.Sprintf(format, args...))
+		}
+		// imports a credential under the key read by the operator, and returns its reference
+		credential := func(fromConfigMap bool, source string, sourceKey string, suffix string, targetKey string) corev1.SecretReference {
+			target := id + suffix
+			imports = append(imports, credentialImport{
+				FromConfigMap: fromConfigMap,
+				Source:        source,
+				SourceKey:     sourceKey,
+				Target:        target,
+				TargetKey:     targetKey,
+			})
+			return corev1.SecretReference{Name: target, Namespace: namespace}
+		}
+		switch {
+		case idp.Type == configv1.IdentityProviderTypeHTPasswd:
+			note("htpasswd identity providers are not imported, dex has no equivalent connector")
+			continue
+		case idp.Type == configv1.IdentityProviderTypeGitHub && idp.GitHub != nil:
+			connector.Type = authv1alpha1.ConnectorTypeGitHub
+			connector.GitHub = authv1alpha1.GitHubConfigSpec{
+				ClientID:        idp.GitHub.ClientID,
+				ClientSecretRef: credential(false, idp.GitHub.ClientSecret.Name, "clientSecret", "-client-secret", "clientSecret"),
+				HostName:        idp.GitHub.Hostname,
+				Orgs:            getGitHubOrgs(idp.GitHub.Organizations, idp.GitHub.Teams),
+			}
+			if idp.GitHub.CA.Name != "" {
+				connector.GitHub.RootCARef = credential(true, idp.GitHub.CA.Name, "ca.crt", "-ca", "ca.crt")
+			}
+		case idp.Type == configv1.IdentityProviderTypeLDAP && idp.LDAP != nil:
+			ldap, err := translateLDAPIdentityProvider(idp.LDAP, note)
+			if err != nil {
+				note("not imported: %v", err)
+				continue
+			}
+			if idp.LDAP.BindPassword.Name != "" {
+				ldap.BindPWRef = credential(false, idp.LDAP.BindPassword.Name, "bindPassword", "-bind-pw", "bindPW")
+			}
+			if idp.LDAP.CA.Name != "" {
+				ldap.RootCARef = credential(true, idp.LDAP.CA.Name, "ca.crt", "-ca", "ca.crt")
+			}
+			connector.Type = authv1alpha1.ConnectorTypeLDAP
+			connector.LDAP = *ldap
+		case idp.Type == configv1.IdentityProviderTypeOpenID && idp.OpenID != nil:
+			connector.Type = authv1alpha1.ConnectorTypeOIDC
+			connector.OIDC = authv1alpha1.OIDCConfigSpec{
+				ClientID:        idp.OpenID.ClientID,
+				ClientSecretRef: credential(false, idp.OpenID.ClientSecret.Name, "clientSecret", "-client-secret", "clientSecret"),
+				Issuer:          idp.OpenID.Issuer,
+				ClaimMapping: authv1alpha1.ClaimMappingSpec{
+					PreferredUsername: firstAttribute(idp.OpenID.Claims.PreferredUsername, "preferredUsername claim", note),
+					Name:              firstAttribute(idp.OpenID.Claims.Name, "name claim", note),
+					Email:             firstAttribute(idp.OpenID.Claims.Email, "email claim", note),
+				},
+			}
+			if len(idp.OpenID.Claims.Groups) > 0 || len(idp.OpenID.ExtraScopes) > 0 || len(idp.OpenID.ExtraAuthorizeParameters) > 0 {
+				note("the groups claims, extra scopes and extra authorize parameters are not imported")
+			}
+			if idp.OpenID.CA.Name != "" {
+				note("the ca ConfigMap is not imported, the oidc connector only trusts the system CAs")
+			}
+		default:
+			note("%s identity providers are not imported, the operator has no equivalent connector", idp.Type)
+			continue
+		}
+		if idp.MappingMethod != "" && idp.MappingMethod != configv1.MappingMethodClaim {
+			note("the %s mapping method does not apply to dex", idp.MappingMethod)
+		}
+		connectors = append(connectors, connector)
+	}
+	return connectors, imports, notes
+}
+
+// Translate an OpenShift LDAP identity provider, its URL is ldap[s]://host[:port]/basedn[?attribute[?scope[?filter]]]
+func translateLDAPIdentityProvider(idp *configv1.LDAPIdentityProvider, note func(string, ...interface{})) (*authv1alpha1.LDAPConfigSpec, error) {
+	u, err := url.Parse(idp.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %v", err)
+	}
+	ldap := &authv1alpha1.LDAPConfigSpec{
+		Host:   u.Host,
+		BindDN: idp.BindDN,
+	}
+	switch u.Scheme {
+	case "ldaps":
+		ldap.InsecureSkipVerify = idp.Insecure
+	case "ldap":
+		// without insecure, OpenShift upgrades the connection with StartTLS
+		ldap.InsecureNoSSL = idp.Insecure
+		ldap.StartTLS = !idp.Insecure
+	default:
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	query := strings.Split(u.RawQuery, "?")
+	for len(query) < 3 {
+		query = append(query, "")
+	}
+	attribute, scope, filter := query[0], query[1], query[2]
+	if attribute == "" {
+		attribute = "uid"
+	}
+	switch scope {
+	case "", "sub":
+		scope = "sub"
+	case "one":
+	default:
+		note("the %s search scope is not supported by dex, the sub scope is used", scope)
+		scope = "sub"
+	}
+	if filter == "" {
+		filter = "(objectClass=*)"
+	} else if filter, err = url.QueryUnescape(filter); err != nil {
+		return nil, fmt.Errorf("invalid url filter: %v", err)
+	}
+	id := firstAttribute(idp.Attributes.ID, "id attribute", note)
+	if strings.EqualFold(id, "dn") {
+		id = "DN"
+	}
+	ldap.UserSearch = authv1alpha1.UserSearchSpec{
+		BaseDN:    strings.TrimPrefix(u.Path, "/"),
+		Filter:    filter,
+		Username:  attribute,
+		Scope:     scope,
+		IDAttr:    id,
+		EmailAttr: firstAttribute(idp.Attributes.Email, "email attribute", note),
+		NameAttr:  firstAttribute(idp.Attributes.Name, "name attribute", note),
+	}
+	if len(idp.Attributes.PreferredUsername) > 0 {
+		note("the preferredUsername attributes are not imported")
+	}
+	return ldap, nil
+}
+
+// Get the first of the attributes tried in turn by OpenShift, dex reads a single attribute
+func firstAttribute(attributes []string, kind string, note func(string, ...interface{})) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+	if len(attributes) > 1 {
+		note("only the first %s %s is imported", kind, attributes[0])
+	}
+	return attributes[0]
+}
+
+// Get the orgs of a github connector from the organizations or the org/team teams of OpenShift
+func getGitHubOrgs(organizations []string, teams []string) []authv1alpha1.Org {
+	orgs := []authv1alpha1.Org{}
+	for _, organization := range organizations {
+		orgs = append(orgs, authv1alpha1.Org{Name: organization})
+	}
+	for _, team := range teams {
+		orgTeam := strings.SplitN(team, "/", 2)
+		if len(orgTeam) != 2 {
+			continue
+		}
+		i := 0
+		for i < len(orgs) && orgs[i].Name != orgTeam[0] {
+			i++
+		}
+		if i == len(orgs) {
+			orgs = append(orgs, authv1alpha1.Org{Name: orgTeam[0]})
+		}
+		orgs[i].Teams = append(orgs[i].Teams, orgTeam[1])
+	}
+	if len(orgs) == 0 {
+		return nil
+	}
+	return orgs
+}
+
+func hasConnectorSpecWithId(connectors []authv1alpha1.ConnectorSpec, id string) bool {
+	for _, connector := range connectors {
+		if connector.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IdentityProviderImportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isTarget := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetNamespace() == r.Namespace && o.GetName() == r.Name
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("identityproviderimport").
+		For(&configv1.OAuth{}).
+		// import the connectors once the DexServer is created
+		Watches(&source.Kind{Type: &authv1alpha1.DexServer{}},
+			handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: OPENSHIFT_OAUTH_NAME}}}
+			}),
+			builder.WithPredicates(isTarget)).
+		Complete(r)
+}
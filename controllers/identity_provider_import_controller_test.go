@@ -0,0 +1,122 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Import the OpenShift identity providers", func() {
+	It("should translate the identity providers into connectors", func() {
+		idps := []configv1.IdentityProvider{
+			{
+				Name: "My GitHub",
+				IdentityProviderConfig: configv1.IdentityProviderConfig{
+					Type: configv1.IdentityProviderTypeGitHub,
+					GitHub: &configv1.GitHubIdentityProvider{
+						ClientID:     "my-github-client",
+						ClientSecret: configv1.SecretNameReference{Name: "github-secret"},
+						Teams:        []string{"my-org/admins", "my-org/devs"},
+						Hostname:     "github.testhost.com",
+						CA:           configv1.ConfigMapNameReference{Name: "github-ca"},
+					},
+				},
+			},
+			{
+				Name: "ldap",
+				IdentityProviderConfig: configv1.IdentityProviderConfig{
+					Type: configv1.IdentityProviderTypeLDAP,
+					LDAP: &configv1.LDAPIdentityProvider{
+						URL:          "ldap://ldap.testhost.com:389/ou=users,dc=example,dc=com?uid?one?(objectClass=person)",
+						BindDN:       "cn=admin,dc=example,dc=com",
+						BindPassword: configv1.SecretNameReference{Name: "ldap-secret"},
+						CA:           configv1.ConfigMapNameReference{Name: "ldap-ca"},
+						Attributes: configv1.LDAPAttributeMapping{
+							ID:    []string{"dn"},
+							Email: []string{"mail"},
+							Name:  []string{"cn", "displayName"},
+						},
+					},
+				},
+			},
+			{
+				Name: "oidc",
+				IdentityProviderConfig: configv1.IdentityProviderConfig{
+					Type: configv1.IdentityProviderTypeOpenID,
+					OpenID: &configv1.OpenIDIdentityProvider{
+						ClientID:     "my-oidc-client",
+						ClientSecret: configv1.SecretNameReference{Name: "oidc-secret"},
+						Issuer:       "https://oidc.testhost.com",
+						Claims: configv1.OpenIDClaims{
+							PreferredUsername: []string{"preferred_username"},
+							Email:             []string{"email"},
+						},
+					},
+				},
+			},
+			{
+				Name: "htpasswd",
+				IdentityProviderConfig: configv1.IdentityProviderConfig{
+					Type:     configv1.IdentityProviderTypeHTPasswd,
+					HTPasswd: &configv1.HTPasswdIdentityProvider{},
+				},
+			},
+		}
+		connectors, imports, notes := translateIdentityProviders(idps, "my-import-ns")
+		Expect(connectors).To(HaveLen(3))
+
+		By("translating the github teams into orgs", func() {
+			Expect(connectors[0].Id).To(Equal("openshift-my-github"))
+			Expect(connectors[0].Type).To(Equal(authv1alpha1.ConnectorTypeGitHub))
+			Expect(connectors[0].GitHub.Orgs).To(Equal([]authv1alpha1.Org{{Name: "my-org", Teams: []string{"admins", "devs"}}}))
+			Expect(connectors[0].GitHub.ClientSecretRef).To(Equal(corev1.SecretReference{Name: "openshift-my-github-client-secret", Namespace: "my-import-ns"}))
+			Expect(connectors[0].GitHub.HostName).To(Equal("github.testhost.com"))
+			Expect(connectors[0].GitHub.RootCARef).To(Equal(corev1.SecretReference{Name: "openshift-my-github-ca", Namespace: "my-import-ns"}))
+		})
+		By("translating the ldap url into a user search", func() {
+			ldap := connectors[1].LDAP
+			Expect(ldap.Host).To(Equal("ldap.testhost.com:389"))
+			Expect(ldap.StartTLS).To(BeTrue())
+			Expect(ldap.UserSearch).To(Equal(authv1alpha1.UserSearchSpec{
+				BaseDN:    "ou=users,dc=example,dc=com",
+				Filter:    "(objectClass=person)",
+				Username:  "uid",
+				Scope:     "one",
+				IDAttr:    "DN",
+				EmailAttr: "mail",
+				NameAttr:  "cn",
+			}))
+			Expect(ldap.BindPWRef.Name).To(Equal("openshift-ldap-bind-pw"))
+			Expect(ldap.RootCARef.Name).To(Equal("openshift-ldap-ca"))
+		})
+		By("translating the oidc claims", func() {
+			Expect(connectors[2].OIDC.Issuer).To(Equal("https://oidc.testhost.com"))
+			Expect(connectors[2].OIDC.ClaimMapping.PreferredUsername).To(Equal("preferred_username"))
+			Expect(connectors[2].OIDC.ClaimMapping.Email).To(Equal("email"))
+		})
+		By("copying the credentials under the keys read by the operator", func() {
+			Expect(imports).To(ContainElement(credentialImport{
+				Source:    "ldap-secret",
+				SourceKey: "bindPassword",
+				Target:    "openshift-ldap-bind-pw",
+				TargetKey: "bindPW",
+			}))
+			Expect(imports).To(ContainElement(credentialImport{
+				FromConfigMap: true,
+				Source:        "ldap-ca",
+				SourceKey:     "ca.crt",
+				Target:        "openshift-ldap-ca",
+				TargetKey:     "ca.crt",
+			}))
+		})
+		By("noting the settings that are not imported", func() {
+			Expect(notes).To(ContainElement("ldap: only the first name attribute cn is imported"))
+			Expect(notes).To(ContainElement("htpasswd: htpasswd identity providers are not imported, dex has no equivalent connector"))
+		})
+	})
+})
@@ -0,0 +1,105 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Default Secret of the certificate issued for the Ingress with the CertManager and ACME strategies
+	INGRESS_TLS_SUFFIX = "-ingress-tls"
+
+	CERT_MANAGER_ISSUER_ANNOTATION         = "cert-manager.io/issuer"
+	CERT_MANAGER_CLUSTER_ISSUER_ANNOTATION = "cert-manager.io/cluster-issuer"
+	TLS_ACME_ANNOTATION                    = "kubernetes.io/tls-acme"
+	EXTERNAL_DNS_HOSTNAME_ANNOTATION       = "external-dns.alpha.kubernetes.io/hostname"
+	NGINX_SSL_CIPHERS_ANNOTATION           = "nginx.ingress.kubernetes.io/ssl-ciphers"
+)
+
+// Annotations of the Ingress template, they can't be set in spec.ingress.annotations
+var ingressTemplateAnnotations = []string{
+	"auth.identitatem.io/inventoryHash",
+	LAYOUT_VERSION_ANNOTATION,
+	"route.openshift.io/termination",
+	"nginx.ingress.kubernetes.io/backend-protocol",
+}
+
+// Get the Secret holding the certificate of the Ingress, empty when the Ingress uses the default certificate of the
+// ingress controller
+func getIngressTLSSecretName(dexServer *authv1alpha1.DexServer) string {
+	tls := dexServer.Spec.Ingress.TLS
+	switch {
+	case tls.SecretName != "":
+		return tls.SecretName
+	case tls.Strategy == authv1alpha1.IngressTLSCertManager || tls.Strategy == authv1alpha1.IngressTLSACME:
+		return dexServer.Name + INGRESS_TLS_SUFFIX
+	default:
+		return dexServer.Spec.IngressCertificateRef.Name
+	}
+}
+
+// Get the hosts of spec.additionalHosts, checking they are DNS names distinct from the host of the issuer
+func getAdditionalHosts(dexServer *authv1alpha1.DexServer, issuerHost string) ([]string, error) {
+	hosts := map[string]bool{issuerHost: true}
+	for _, host := range dexServer.Spec.AdditionalHosts {
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+			return nil, fmt.Errorf("additional host %s is invalid: %s", host, strings.Join(errs, ", "))
+		}
+		if hosts[host] {
+			return nil, fmt.Errorf("additional host %s is repeated, or is the host of the issuer", host)
+		}
+		hosts[host] = true
+	}
+	return dexServer.Spec.AdditionalHosts, nil
+}
+
+// Get the annotations of the Ingress requesting its certificate and DNS records and setting its cipher suites, along
+// with spec.ingress.annotations and the health check annotations of spec.service.healthCheckPreset
+func getIngressAnnotations(dexServer *authv1alpha1.DexServer, hosts []string) (map[string]string, error) {
+	annotations := mergePresetAnnotations(getIngressHealthCheckAnnotations(dexServer), dexServer.Spec.Ingress.Annotations)
+	for _, key := range ingressTemplateAnnotations {
+		if _, ok := dexServer.Spec.Ingress.Annotations[key]; ok {
+			return nil, fmt.Errorf("annotation %s of spec.ingress.annotations is set by the operator", key)
+		}
+	}
+
+	managed := map[string]string{}
+	tls := dexServer.Spec.Ingress.TLS
+	switch tls.Strategy {
+	case authv1alpha1.IngressTLSCertManager:
+		if tls.IssuerRef.Name == "" {
+			return nil, fmt.Errorf("spec.ingress.tls.issuerRef is required with the CertManager strategy")
+		}
+		if tls.IssuerRef.Kind == "ClusterIssuer" {
+			managed[CERT_MANAGER_CLUSTER_ISSUER_ANNOTATION] = tls.IssuerRef.Name
+		} else {
+			managed[CERT_MANAGER_ISSUER_ANNOTATION] = tls.IssuerRef.Name
+		}
+	case authv1alpha1.IngressTLSACME:
+		managed[TLS_ACME_ANNOTATION] = "true"
+	}
+	if dexServer.Spec.Ingress.ExternalDNS {
+		managed[EXTERNAL_DNS_HOSTNAME_ANNOTATION] = strings.Join(hosts, ",")
+	}
+	if len(tls.CipherSuites) > 0 {
+		for _, cipherSuite := range tls.CipherSuites {
+			if cipherSuite == "" || strings.ContainsAny(cipherSuite, ": ,") {
+				return nil, fmt.Errorf("cipher suite %q of spec.ingress.tls.cipherSuites is not an OpenSSL cipher suite name", cipherSuite)
+			}
+		}
+		managed[NGINX_SSL_CIPHERS_ANNOTATION] = strings.Join(tls.CipherSuites, ":")
+	}
+	for key, value := range managed {
+		if _, ok := annotations[key]; ok {
+			return nil, fmt.Errorf("annotation %s of spec.ingress.annotations is set by the operator from spec.ingress", key)
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
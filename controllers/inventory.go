@@ -0,0 +1,289 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Components of a dex server, set in the app.kubernetes.io/component label of the managed objects
+const (
+	componentConfig = "config"
+	componentWeb    = "web"
+	componentGRPC   = "grpc"
+	// ConfigMap and Job of a DexStorageMigration, owned by the migration rather than the DexServer
+	componentStorageMigration = "storage-migration"
+	// ConfigMap publishing the connection info of the dex server to consuming operators
+	componentConnectionInfo = "connection-info"
+	// Job validating the dex server after a configuration rollout, see spec.smokeTest
+	componentSmokeTest = "smoke-test"
+	// ClusterRoleBindings of the groups of the connectors, see spec.groupBindings
+	componentGroupBindings = "group-bindings"
+	// ConfigMap or Groups of the members of the GitHub teams, see spec.teamSync
+	componentTeamSync = "team-sync"
+	// DexClient and Secret of the password grant, see spec.oauth2.passwordClient
+	componentPasswordClient = "password-client"
+	// ConsoleLink of the OpenShift web console, see spec.consoleLink
+	componentConsoleLink = "console-link"
+	// OAuthClients of the OpenShift connectors without a clientID
+	componentOAuthClient = "oauth-client"
+	// DexClient and Secrets of the oauth2-proxy sidecars, see spec.oauthProxy
+	componentOAuthProxy = "oauth-proxy"
+	// VerticalPodAutoscaler of the dex Deployment, see spec.verticalAutoscaling
+	componentVerticalAutoscaling = "vertical-autoscaling"
+	// NetworkPolicy admitting the proxies of the authproxy connectors to dex
+	componentAuthProxy = "authproxy"
+)
+
+// getManagedLabels returns the labels set on every object managed for the DexServer
+func getManagedLabels(dexServer *authv1alpha1.DexServer, component string) map[string]string {
+	labels := map[string]string{
+		MANAGED_BY_LABEL: MANAGED_BY_VALUE,
+		INSTANCE_LABEL:   dexServer.Name,
+		COMPONENT_LABEL:  component,
+	}
+	if version := getDexVersion(); version != "" {
+		labels[VERSION_LABEL] = version
+	}
+	return labels
+}
+
+// getSharedLabels returns the labels set on the objects shared by the DexServers of a namespace: the grpc Service, the
+// mTLS Secret, the service account of dex and its ClusterRoleBinding. They are not labeled with the instance, nor
+// annotated with the inventory hash, which would change with each DexServer applying them.
+func getSharedLabels(component string) map[string]string {
+	labels := map[string]string{
+		MANAGED_BY_LABEL: MANAGED_BY_VALUE,
+		COMPONENT_LABEL:  component,
+	}
+	if version := getDexVersion(); version != "" {
+		labels[VERSION_LABEL] = version
+	}
+	return labels
+}
+
+// addSharedMetadata sets the shared labels and the layout version on an object shared by the DexServers of a namespace
+func addSharedMetadata(component string, labels map[string]string, annotations map[string]string) {
+	for k, v := range getSharedLabels(component) {
+		labels[k] = v
+	}
+	annotations[LAYOUT_VERSION_ANNOTATION] = strconv.Itoa(getLayoutVersion())
+}
+
+// removeInstanceMetadata removes the instance label and the inventory hash from a shared object labeled by a previous
+// version of the operator, the applier keeps the labels and annotations it does not set
+func (r *DexServerReconciler) removeInstanceMetadata(ctx context.Context, obj client.Object) error {
+	if getObserveReport(ctx) != nil {
+		return nil
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	_, hasInstance := obj.GetLabels()[INSTANCE_LABEL]
+	_, hasHash := obj.GetAnnotations()[INVENTORY_HASH_ANNOTATION]
+	if !hasInstance && !hasHash {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	labels, annotations := obj.GetLabels(), obj.GetAnnotations()
+	delete(labels, INSTANCE_LABEL)
+	delete(annotations, INVENTORY_HASH_ANNOTATION)
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return r.Patch(ctx, obj, patch)
+}
+
+// addManagedMetadata sets the managed labels, the inventory hash and the layout version on an object created by the
+// operator
+func (r *DexServerReconciler) addManagedMetadata(dexServer *authv1alpha1.DexServer, component string, labels map[string]string, annotations map[string]string) {
+	for k, v := range getManagedLabels(dexServer, component) {
+		labels[k] = v
+	}
+	annotations[INVENTORY_HASH_ANNOTATION] = getInventoryHash(r.getInventory(dexServer))
+	annotations[LAYOUT_VERSION_ANNOTATION] = strconv.Itoa(getLayoutVersion())
+}
+
+// The version of dex is the tag of its image, omitted when the image is referenced by digest
+func getDexVersion() string {
+	image := os.Getenv(DEX_IMAGE_ENV_NAME)
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	version := image[i+1:]
+	if len(validation.IsValidLabelValue(version)) > 0 {
+		return ""
+	}
+	return version
+}
+
+// getTemplateFuncMap returns the functions available to the dex server templates
+func (r *DexServerReconciler) getTemplateFuncMap(dexServer *authv1alpha1.DexServer) template.FuncMap {
+	return template.FuncMap{
+		// Yaml of the managed labels, to be indented under metadata.labels
+		"managedLabels": func(component string) string {
+			return labelsYaml(getManagedLabels(dexServer, component))
+		},
+		// Yaml of the labels of the objects shared by the DexServers of the namespace
+		"sharedLabels": func(component string) string {
+			return labelsYaml(getSharedLabels(component))
+		},
+		"inventoryHash": func() string {
+			return getInventoryHash(r.getInventory(dexServer))
+		},
+		"layoutVersion": getLayoutVersion,
+	}
+}
+
+// labelsYaml renders labels sorted by key
+func labelsYaml(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %q\n", k, labels[k])
+	}
+	return b.String()
+}
+
+// getInventory lists the objects managed for the DexServer, sorted by kind, namespace and name
+func (r *DexServerReconciler) getInventory(dexServer *authv1alpha1.DexServer) []authv1alpha1.RelatedObjectReference {
+	ns := dexServer.Namespace
+	inventory := []authv1alpha1.RelatedObjectReference{
+		{Kind: "ConfigMap", Name: dexServer.Name, Namespace: ns},
+		{Kind: "ConfigMap", Name: dexServer.Name + CONNECTION_INFO_SUFFIX, Namespace: ns},
+		{Kind: "Deployment", Name: dexServer.Name, Namespace: ns},
+		{Kind: "Secret", Name: SECRET_MTLS_NAME, Namespace: ns},
+		{Kind: "Service", Name: dexServer.Name, Namespace: ns},
+		{Kind: "Service", Name: GRPC_SERVICE_NAME, Namespace: ns},
+		{Kind: "ServiceAccount", Name: SERVICE_ACCOUNT_NAME, Namespace: ns},
+	}
+	if !r.OpenShift {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "Secret", Name: dexServer.Name + SECRET_WEB_TLS_SUFFIX, Namespace: ns})
+	}
+	if serviceType := dexServer.Spec.Service.Type; serviceType != corev1.ServiceTypeNodePort && serviceType != corev1.ServiceTypeLoadBalancer &&
+		!isIssuerHandedOver(dexServer) && !isWaitingForHandover(dexServer) {
+		if isWildcardRoute(dexServer, r.OpenShift) {
+			inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "Route", Name: dexServer.Name, Namespace: ns})
+		} else {
+			inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "Ingress", Name: dexServer.Name, Namespace: ns})
+		}
+	}
+	if dexServer.Spec.Telemetry.Enabled {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "Service", Name: dexServer.Name + METRICS_SERVICE_SUFFIX, Namespace: ns})
+	}
+	if dexServer.Spec.VerticalAutoscaling.Enabled {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "VerticalPodAutoscaler", Name: dexServer.Name, Namespace: ns})
+	}
+	if hasAuthProxyConnector(dexServer) {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "NetworkPolicy", Name: dexServer.Name + AUTHPROXY_POLICY_SUFFIX, Namespace: ns})
+	}
+	if !r.PreProvisionedRBAC {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "ClusterRoleBinding", Name: SERVICE_ACCOUNT_NAME + "-" + ns})
+	}
+	if bindings := dexServer.Spec.GroupBindings; len(bindings.Bindings) > 0 {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "ConfigMap", Name: dexServer.Name + GROUP_BINDINGS_SUFFIX, Namespace: ns})
+		if bindings.Create {
+			for _, binding := range bindings.Bindings {
+				inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "ClusterRoleBinding", Name: getGroupBindingName(dexServer, binding)})
+			}
+		}
+	}
+	if teamSync := dexServer.Spec.TeamSync; teamSync.Connector != "" {
+		if teamSync.Target == authv1alpha1.TeamSyncTargetGroup {
+			if dexServer.Status.TeamSync != nil {
+				for _, group := range dexServer.Status.TeamSync.Groups {
+					inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "Group", Name: group})
+				}
+			}
+		} else {
+			inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "ConfigMap", Name: dexServer.Name + TEAM_SYNC_SUFFIX, Namespace: ns})
+		}
+	}
+	if dexServer.Spec.ConsoleLink.Enabled && r.OpenShift {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "ConsoleLink", Name: getConsoleLinkName(dexServer)})
+	}
+	if r.OpenShift {
+		for _, connector := range dexServer.Spec.Connectors {
+			if hasManagedOAuthClient(connector) {
+				inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "OAuthClient", Name: getOAuthClientName(dexServer, connector)})
+			}
+		}
+	}
+	if dexServer.Spec.OAuth2.PasswordClient.Enabled {
+		inventory = append(inventory,
+			authv1alpha1.RelatedObjectReference{Kind: "DexClient", Name: dexServer.Name + PASSWORD_CLIENT_SUFFIX, Namespace: ns},
+			authv1alpha1.RelatedObjectReference{Kind: "Secret", Name: dexServer.Name + PASSWORD_CLIENT_SUFFIX, Namespace: ns})
+	}
+	if dexServer.Spec.OAuthProxy.Enabled {
+		inventory = append(inventory,
+			authv1alpha1.RelatedObjectReference{Kind: "DexClient", Name: dexServer.Name + OAUTH_PROXY_SUFFIX, Namespace: ns},
+			authv1alpha1.RelatedObjectReference{Kind: "Secret", Name: dexServer.Name + OAUTH_PROXY_SUFFIX, Namespace: ns})
+	}
+	for _, secretRef := range getCopiedSecretRefs(dexServer) {
+		inventory = append(inventory, authv1alpha1.RelatedObjectReference{Kind: "Secret", Name: secretRef.Namespace + "-" + secretRef.Name, Namespace: ns})
+	}
+
+	sort.Slice(inventory, func(i, j int) bool {
+		a, b := inventory[i], inventory[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	// The same secret may be referenced by several connectors
+	deduped := inventory[:0]
+	for i, ref := range inventory {
+		if i == 0 || ref != inventory[i-1] {
+			deduped = append(deduped, ref)
+		}
+	}
+	return deduped
+}
+
+// References to the connector secrets copied into the dex server namespace
+func getCopiedSecretRefs(dexServer *authv1alpha1.DexServer) []corev1.SecretReference {
+	refs := []corev1.SecretReference{}
+	for _, connector := range dexServer.Spec.Connectors {
+		for _, secretRef := range getConnectorSecretRefs(connector) {
+			// OIDC client secrets in the dex server namespace are used in place
+			if connector.Type == authv1alpha1.ConnectorTypeOIDC && secretRef.Namespace == dexServer.Namespace {
+				continue
+			}
+			refs = append(refs, secretRef)
+		}
+	}
+	return refs
+}
+
+// getInventoryHash identifies the set of managed objects. Objects carrying a different hash than the DexServer
+// inventory are left over from a previous configuration.
+func getInventoryHash(inventory []authv1alpha1.RelatedObjectReference) string {
+	h := sha256.New()
+	for _, ref := range inventory {
+		fmt.Fprintf(h, "%s/%s/%s\n", ref.Kind, ref.Namespace, ref.Name)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -0,0 +1,134 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Key of the issuer directory ConfigMap holding the JSON list of IssuerDirectoryEntry
+	ISSUER_DIRECTORY_KEY = "issuers.json"
+)
+
+// IssuerDirectoryEntry describes the issuer of one DexServer in the issuer directory
+type IssuerDirectoryEntry struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Issuer    string `json:"issuer"`
+	// Set from the Available condition of the DexServer
+	Available bool `json:"available"`
+	// Set from the Ready condition of the DexServer
+	Ready bool `json:"ready"`
+}
+
+// IssuerDirectoryReconciler lists the issuers of all the DexServers of the cluster in one ConfigMap, so that
+// platform portals can display the identity endpoints without reading the DexServers of every namespace.
+type IssuerDirectoryReconciler struct {
+	client.Client
+	// Namespace and name of the issuer directory ConfigMap
+	Namespace string
+	Name      string
+}
+
+// Reconcile rewrites the issuer directory from the current DexServers. Every DexServer event is mapped to the
+// ConfigMap, so concurrent changes are coalesced into one update.
+func (r *IssuerDirectoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	log.V(1).Info("Reconciling issuer directory...")
+
+	dexServers := &authv1alpha1.DexServerList{}
+	if err := r.List(ctx, dexServers); err != nil {
+		return ctrl.Result{}, err
+	}
+	entries := []IssuerDirectoryEntry{}
+	for _, dexServer := range dexServers.Items {
+		if dexServer.DeletionTimestamp != nil {
+			continue
+		}
+		issuer := dexServer.Status.Issuer
+		if issuer == "" {
+			issuer = dexServer.Spec.Issuer
+		}
+		entries = append(entries, IssuerDirectoryEntry{
+			Name:      dexServer.Name,
+			Namespace: dexServer.Namespace,
+			Issuer:    issuer,
+			Available: meta.IsStatusConditionTrue(dexServer.Status.Conditions, authv1alpha1.DexServerDeploymentAvailable),
+			Ready:     meta.IsStatusConditionTrue(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeReady),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: r.Name, Namespace: r.Namespace}, configMap)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      r.Name,
+				Namespace: r.Namespace,
+				Labels: map[string]string{
+					MANAGED_BY_LABEL: MANAGED_BY_VALUE,
+				},
+			},
+			Data: map[string]string{ISSUER_DIRECTORY_KEY: string(data)},
+		}
+		log.Info("Creating the issuer directory", "ConfigMap.Namespace", r.Namespace, "ConfigMap.Name", r.Name)
+		return ctrl.Result{}, r.Create(ctx, configMap)
+	case err != nil:
+		return ctrl.Result{}, err
+	}
+	if configMap.Data[ISSUER_DIRECTORY_KEY] == string(data) {
+		return ctrl.Result{}, nil
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[ISSUER_DIRECTORY_KEY] = string(data)
+	log.Info("Updating the issuer directory", "ConfigMap.Namespace", r.Namespace, "ConfigMap.Name", r.Name, "DexServers", len(entries))
+	return ctrl.Result{}, r.Update(ctx, configMap)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IssuerDirectoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isDirectory := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetNamespace() == r.Namespace && o.GetName() == r.Name
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("issuerdirectory").
+		// restore the directory when it is edited or deleted
+		For(&corev1.ConfigMap{}, builder.WithPredicates(isDirectory)).
+		Watches(&source.Kind{Type: &authv1alpha1.DexServer{}},
+			handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: r.Name, Namespace: r.Namespace}}}
+			})).
+		Complete(r)
+}
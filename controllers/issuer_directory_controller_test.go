@@ -0,0 +1,48 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Process the issuer directory", func() {
+	It("should list the issuer of every DexServer", func() {
+		namespace := rIssuerDirectory.Namespace
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		Expect(err).To(BeNil())
+		err = k8sClient.Create(context.TODO(), &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-directory-dexserver", Namespace: namespace},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://directory.testhost.com"},
+		})
+		Expect(err).To(BeNil())
+
+		req := ctrl.Request{}
+		req.Name = rIssuerDirectory.Name
+		req.Namespace = namespace
+		_, err = rIssuerDirectory.Reconcile(context.TODO(), req)
+		Expect(err).To(BeNil())
+
+		configMap := &corev1.ConfigMap{}
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: rIssuerDirectory.Name, Namespace: namespace}, configMap)
+		Expect(err).To(BeNil())
+		entries := []IssuerDirectoryEntry{}
+		err = json.Unmarshal([]byte(configMap.Data[ISSUER_DIRECTORY_KEY]), &entries)
+		Expect(err).To(BeNil())
+		Expect(entries).To(ContainElement(IssuerDirectoryEntry{
+			Name:      "my-directory-dexserver",
+			Namespace: namespace,
+			Issuer:    "https://directory.testhost.com",
+		}))
+	})
+})
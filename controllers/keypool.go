@@ -0,0 +1,86 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"sync"
+
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	DEFAULT_KEY_POOL_SIZE    = 16
+	DEFAULT_KEY_POOL_WORKERS = 2
+)
+
+// KeyPool generates the RSA private keys of the certificates in the background, so that the reconciles of DexServers
+// created in bulk, for example by fleet automation, don't each wait for the generation of their keys. A bounded number
+// of workers keeps up to Size keys ready, the keys are generated in the reconcile when the pool is empty.
+type KeyPool struct {
+	// Number of keys kept ready
+	Size int
+	// Number of keys generated in parallel
+	Workers int
+	// Size of the generated keys, see privateKeySize
+	Bits int
+
+	once sync.Once
+	keys chan *rsa.PrivateKey
+}
+
+func (p *KeyPool) init() {
+	p.once.Do(func() {
+		p.keys = make(chan *rsa.PrivateKey, p.Size)
+	})
+}
+
+// Start implements manager.Runnable, the workers refill the pool until the context is done
+func (p *KeyPool) Start(ctx context.Context) error {
+	log := ctrllog.FromContext(ctx).WithName("key-pool")
+	p.init()
+	log.Info("Starting the key pool", "Size", p.Size, "Workers", p.Workers, "Bits", p.Bits)
+	var wg sync.WaitGroup
+	for i := 0; i < p.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				key, err := rsa.GenerateKey(rand.Reader, p.Bits)
+				if err != nil {
+					log.Error(err, "failed to generate a key, the keys are generated in the reconciles")
+					return
+				}
+				select {
+				case p.keys <- key:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the pool of a standby operator is filled before
+// it is elected
+func (p *KeyPool) NeedLeaderElection() bool {
+	return false
+}
+
+// GenerateKey returns a key of the pool, or generates it when the pool is empty, nil or holds keys of another size
+func (p *KeyPool) GenerateKey(bits int) (*rsa.PrivateKey, error) {
+	if p != nil && p.Bits == bits {
+		p.init()
+		select {
+		case key := <-p.keys:
+			return key, nil
+		default:
+		}
+	}
+	return rsa.GenerateKey(rand.Reader, bits)
+}
@@ -0,0 +1,44 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generate the certificate keys ahead of the reconciles", func() {
+	It("should keep the pool filled", func() {
+		keyPool := &KeyPool{Size: 2, Workers: 2, Bits: 1024}
+		poolCtx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- keyPool.Start(poolCtx) }()
+		Eventually(func() int { return len(keyPool.keys) }, 30, 1).Should(Equal(2))
+
+		key, err := keyPool.GenerateKey(1024)
+		Expect(err).Should(BeNil())
+		Expect(key.N.BitLen()).To(Equal(1024))
+		Eventually(func() int { return len(keyPool.keys) }, 30, 1).Should(Equal(2))
+
+		By("stopping the workers", func() {
+			cancel()
+			Eventually(done, 30).Should(Receive(BeNil()))
+		})
+	})
+	It("should generate the keys when the pool can't provide them", func() {
+		By("generating the keys of another size", func() {
+			keyPool := &KeyPool{Size: 2, Workers: 1, Bits: 1024}
+			key, err := keyPool.GenerateKey(2048)
+			Expect(err).Should(BeNil())
+			Expect(key.N.BitLen()).To(Equal(2048))
+		})
+		By("generating the keys without a pool", func() {
+			var keyPool *KeyPool
+			key, err := keyPool.GenerateKey(1024)
+			Expect(err).Should(BeNil())
+			Expect(key.N.BitLen()).To(Equal(1024))
+		})
+	})
+})
@@ -0,0 +1,117 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Render the dex config of an LDAP connector with its certificates mounted in the dex pod. dex only supports one host,
+// the connector with several hosts is configured with the first reachable one, whose status is returned.
+func (r *DexServerReconciler) renderLDAPConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, *authv1alpha1.LDAPHostsStatus, error) {
+	log := ctrllog.FromContext(ctx)
+	if err := validateLDAPHosts(connector); err != nil {
+		return DexConnectorSpec{}, nil, err
+	}
+	// dex only supports one host, it is configured with the first reachable replica
+	host := connector.LDAP.Host
+	var hostsStatus *authv1alpha1.LDAPHostsStatus
+	if len(connector.LDAP.Hosts) > 0 {
+		status := probeLDAPHosts(ctx, connector)
+		host = status.Host
+		hostsStatus = &status
+	}
+
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	err := r.copySecretToDexServerNamespace(dexServer, connector.LDAP.BindPWRef, ctx)
+	if err != nil {
+		return DexConnectorSpec{}, nil, err
+	}
+
+	// Environment variable that references the LDAP Bind Password secret copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between bind passwords for multiple connectors
+	bindPWEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + getUniqueAlphanumericIdForConnector(connector)
+
+	// If there is a secret reference to the trusted Root CA
+	var rootCAPath, clientCertPath, clientKeyPath string
+	if connector.LDAP.RootCARef.Name != "" {
+		err := r.copySecretToDexServerNamespace(dexServer, connector.LDAP.RootCARef, ctx)
+		if err != nil {
+			return DexConnectorSpec{}, nil, err
+		}
+		// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+		secretName := connector.LDAP.RootCARef.Namespace + "-" + connector.LDAP.RootCARef.Name
+		secretNamespace := dexServer.Namespace
+		resource := &corev1.Secret{}
+
+		if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, resource); err != nil {
+			// Error getting secret
+			log.Error(err, "Error getting root CA secret in dex server ns")
+			return DexConnectorSpec{}, nil, err
+		}
+
+		if string(resource.Data["ca.crt"]) != "" {
+			rootCAPath = "/etc/dex/ldapcerts/" + connector.Id + "/ca.crt"
+		}
+		if string(resource.Data["tls.crt"]) != "" {
+			clientCertPath = "/etc/dex/ldapcerts/" + connector.Id + "/tls.crt"
+		}
+		if string(resource.Data["tls.key"]) != "" {
+			clientKeyPath = "/etc/dex/ldapcerts/" + connector.Id + "/tls.key"
+		}
+	}
+
+	newConnector := DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeLDAP),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			Host:               host,
+			InsecureNoSSL:      connector.LDAP.InsecureNoSSL,
+			InsecureSkipVerify: connector.LDAP.InsecureSkipVerify,
+			StartTLS:           connector.LDAP.StartTLS,
+			RootCA:             rootCAPath,
+			ClientCert:         clientCertPath,
+			ClientKey:          clientKeyPath,
+			BindDN:             connector.LDAP.BindDN,
+			BindPW:             bindPWEnvVariable,
+			UsernamePrompt:     connector.LDAP.UsernamePrompt,
+		},
+	}
+
+	if connector.LDAP.UserSearch.BaseDN != "" {
+		newConnector.Config.UserSearch.BaseDN = connector.LDAP.UserSearch.BaseDN
+		newConnector.Config.UserSearch.Filter = connector.LDAP.UserSearch.Filter
+		newConnector.Config.UserSearch.Username = connector.LDAP.UserSearch.Username
+		newConnector.Config.UserSearch.Scope = connector.LDAP.UserSearch.Scope
+		newConnector.Config.UserSearch.IDAttr = connector.LDAP.UserSearch.IDAttr
+		newConnector.Config.UserSearch.EmailAttr = connector.LDAP.UserSearch.EmailAttr
+		newConnector.Config.UserSearch.NameAttr = connector.LDAP.UserSearch.NameAttr
+		newConnector.Config.UserSearch = authv1alpha1.UserSearchSpec{
+			BaseDN:    connector.LDAP.UserSearch.BaseDN,
+			Filter:    connector.LDAP.UserSearch.Filter,
+			Username:  connector.LDAP.UserSearch.Username,
+			Scope:     connector.LDAP.UserSearch.Scope,
+			IDAttr:    connector.LDAP.UserSearch.IDAttr,
+			EmailAttr: connector.LDAP.UserSearch.EmailAttr,
+			NameAttr:  connector.LDAP.UserSearch.NameAttr,
+		}
+	}
+
+	if connector.LDAP.GroupSearch.BaseDN != "" {
+		newConnector.Config.GroupSearch = authv1alpha1.GroupSearchSpec{
+			BaseDN:       connector.LDAP.GroupSearch.BaseDN,
+			Filter:       connector.LDAP.GroupSearch.Filter,
+			Scope:        connector.LDAP.GroupSearch.Scope,
+			UserMatchers: connector.LDAP.GroupSearch.UserMatchers,
+			NameAttr:     connector.LDAP.GroupSearch.NameAttr,
+		}
+	}
+	return newConnector, hostsStatus, nil
+}
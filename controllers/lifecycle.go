@@ -0,0 +1,50 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Default delay before dex is stopped, the endpoints of the pod are removed from the Service and the routers in a
+	// few seconds
+	DEX_PRE_STOP_DELAY = 10 * time.Second
+	// Default time given to dex to complete the requests in flight once it is stopped
+	DEX_SHUTDOWN_TIMEOUT = 30 * time.Second
+)
+
+// Get the preStop delay of the dex container in seconds, spec.lifecycle.preStopDelay or 10s
+func getPreStopDelaySeconds(dexServer *authv1alpha1.DexServer) int64 {
+	delay := DEX_PRE_STOP_DELAY
+	if dexServer.Spec.Lifecycle.PreStopDelay != nil {
+		delay = dexServer.Spec.Lifecycle.PreStopDelay.Duration
+	}
+	return durationSeconds(delay)
+}
+
+// Get the termination grace period of the dex pods in seconds, spec.lifecycle.terminationGracePeriod or the preStop
+// delay and 30s. The kubelet kills the pod once it elapses, even during the preStop delay.
+func getTerminationGracePeriodSeconds(dexServer *authv1alpha1.DexServer) (int64, error) {
+	preStopDelay := getPreStopDelaySeconds(dexServer)
+	if dexServer.Spec.Lifecycle.TerminationGracePeriod == nil {
+		return preStopDelay + durationSeconds(DEX_SHUTDOWN_TIMEOUT), nil
+	}
+	gracePeriod := durationSeconds(dexServer.Spec.Lifecycle.TerminationGracePeriod.Duration)
+	if gracePeriod <= preStopDelay {
+		return 0, fmt.Errorf("spec.lifecycle.terminationGracePeriod %ds must be longer than the preStop delay %ds", gracePeriod, preStopDelay)
+	}
+	return gracePeriod, nil
+}
+
+// Round a duration up to whole seconds, negative durations are 0s
+func durationSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
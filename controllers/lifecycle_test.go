@@ -0,0 +1,41 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Drain the dex pods on shutdown", func() {
+	It("should wait for the endpoints to be removed before stopping dex", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		Expect(getPreStopDelaySeconds(dexServer)).To(Equal(int64(10)))
+		Expect(getTerminationGracePeriodSeconds(dexServer)).To(Equal(int64(40)))
+
+		dexServer.Spec.Lifecycle.PreStopDelay = &metav1.Duration{Duration: 1500 * time.Millisecond}
+		Expect(getPreStopDelaySeconds(dexServer)).To(Equal(int64(2)))
+		Expect(getTerminationGracePeriodSeconds(dexServer)).To(Equal(int64(32)))
+
+		By("stopping dex right away", func() {
+			dexServer := &authv1alpha1.DexServer{}
+			dexServer.Spec.Lifecycle.PreStopDelay = &metav1.Duration{}
+			Expect(getPreStopDelaySeconds(dexServer)).To(BeZero())
+			Expect(getTerminationGracePeriodSeconds(dexServer)).To(Equal(int64(30)))
+		})
+	})
+	It("should refuse a termination grace period ending during the preStop delay", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		dexServer.Spec.Lifecycle.TerminationGracePeriod = &metav1.Duration{Duration: 2 * time.Minute}
+		Expect(getTerminationGracePeriodSeconds(dexServer)).To(Equal(int64(120)))
+
+		dexServer.Spec.Lifecycle.TerminationGracePeriod = &metav1.Duration{Duration: 5 * time.Second}
+		_, err := getTerminationGracePeriodSeconds(dexServer)
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,85 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"regexp"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/identitatem/dex-operator/controllers/redact"
+)
+
+// Secret material of the fixtures of the suite, none of it may be logged
+var fixtureSecretMaterial = []string{"-----BEGIN", "PRIVATE KEY", "BogusSecret", "fakebindpw"}
+
+func expectNoSecretMaterial(logs string) {
+	for _, material := range fixtureSecretMaterial {
+		ExpectWithOffset(1, logs).NotTo(ContainSubstring(material))
+	}
+}
+
+// syncBuffer is a bytes.Buffer the loggers of the concurrent reconciles can write to
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+var _ = Describe("Redact the secret material from the logs", func() {
+	key, _ := rsa.GenerateKey(rand.Reader, 1024)
+	_, keyPEM := PEMEncode(nil, key)
+	config := "connectors:\n- type: ldap\n  config:\n    bindPW: fakebindpw\nstaticClients:\n- id: my-client\n  secret: x\n  clientSecret: BogusSecret\n"
+
+	It("should redact the messages, errors and values at every verbosity", func() {
+		logs := &syncBuffer{}
+		log := redact.NewLogger(zap.New(zap.WriteTo(logs), zap.UseDevMode(true)))
+
+		log.Info("rendered the config:\n" + config)
+		log.V(1).Info("generated the key", "PEM", keyPEM, "key", keyPEM)
+		log.V(1).Info("generated the certificates", "certs", keyPEM.String(), "raw", keyPEM.Bytes())
+		log.WithValues("config.yaml", config).Info("applied the config")
+		log.WithName("connectors").Info("copied the Secret", "Object", &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-ldap", Namespace: "my-ns"},
+			Data:       map[string][]byte{"bindPW": []byte("fakebindpw")},
+		}, "data", map[string][]byte{"clientSecret": []byte("BogusSecret")})
+		log.Error(errors.Wrap(fmt.Errorf("yaml: line 4: cannot unmarshal %q", "clientSecret: BogusSecret"), "invalid config"),
+			"failed to load "+keyPEM.String())
+
+		Expect(logs.String()).To(ContainSubstring("<redacted RSA PRIVATE KEY>"))
+		Expect(logs.String()).To(ContainSubstring("Secret my-ns/my-ldap"))
+		Expect(logs.String()).To(ContainSubstring("invalid config"))
+		expectNoSecretMaterial(logs.String())
+	})
+	It("should redact the matches of the added patterns", func() {
+		redact.AddPattern(regexp.MustCompile(`sha256~[A-Za-z0-9_-]+`))
+		Expect(redact.String("token sha256~abcdef was revoked")).To(Equal("token <redacted> was revoked"))
+		Expect(redact.String("Secret my-ns/my-secret not found")).To(Equal("Secret my-ns/my-secret not found"))
+	})
+	It("should keep the redacted errors classifiable", func() {
+		missing := &missingSecretError{Namespace: "my-ns", Name: "my-secret", ConnectorId: "my-ldap"}
+		var target *missingSecretError
+		Expect(errors.As(redact.Error(errors.Wrap(missing, "failed to copy")), &target)).To(BeTrue())
+	})
+})
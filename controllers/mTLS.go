@@ -4,28 +4,34 @@ package controllers
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"math/big"
 	"net"
-	"os/exec"
+	"strings"
 	"time"
 )
 
 const (
 	PRIVATE_KEY_SIZE = 2048
+	// FIPS 140-3 requires RSA keys of at least 3072 bits for new certificates
+	FIPS_PRIVATE_KEY_SIZE = 3072
 )
 
 var (
 	serialNumberLimit = new(big.Int).Lsh(big.NewInt(1), 128)
 	certDuration      = time.Hour * 24
 	certRenewalWindow = time.Hour * 2 // roll the cert when we get within this window of expiring
+	// keep trusting the previous CA for this long after the CA is rolled, unless configured in the DexServer
+	defaultCAOverlapWindow = time.Hour
+	// lifetime of the operator generated dex web certificate when the cluster does not provide serving certificates
+	servingCertDuration = time.Hour * 24 * 365
 )
 
 func GetCertDuration() time.Duration {
@@ -46,7 +52,73 @@ func inCertRenewalWindow(expiry time.Time) bool {
 	return time.Now().Add(certRenewalWindow).After(expiry)
 }
 
-func generateMTLSCerts(ns string) (*MTLSCerts, error) {
+// Size of the generated RSA keys, the certificates are signed with SHA-256 which is FIPS approved
+func privateKeySize(fips bool) int {
+	if fips {
+		return FIPS_PRIVATE_KEY_SIZE
+	}
+	return PRIVATE_KEY_SIZE
+}
+
+// Check the public key of the first certificate of a PEM bundle only uses FIPS approved algorithms and key sizes:
+// RSA keys of at least 3072 bits, or ECDSA keys on the P-256 or P-384 curves.
+func isFIPSCompliantCertificate(bundle []byte) bool {
+	block, _ := pem.Decode(bundle)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen() >= FIPS_PRIVATE_KEY_SIZE
+	case *ecdsa.PublicKey:
+		return key.Curve == elliptic.P256() || key.Curve == elliptic.P384()
+	}
+	return false
+}
+
+// Check the first certificate of a PEM bundle is issued for exactly the hosts of dex, so that it is regenerated when
+// a host is added, and when a host it was issued for is no longer a host of dex, like the previous address of the node
+// the issuer is derived from.
+func isCertificateForHosts(bundle []byte, hosts []string) bool {
+	block, _ := pem.Decode(bundle)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	issued := map[string]bool{}
+	for _, dnsName := range cert.DNSNames {
+		issued[strings.ToLower(dnsName)] = true
+	}
+	for _, ip := range cert.IPAddresses {
+		issued[ip.String()] = true
+	}
+	wanted := map[string]bool{}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			wanted[ip.String()] = true
+		} else {
+			wanted[strings.ToLower(host)] = true
+		}
+	}
+	if len(issued) != len(wanted) {
+		return false
+	}
+	for host := range wanted {
+		if !issued[host] {
+			return false
+		}
+	}
+	return true
+}
+
+func generateMTLSCerts(ns string, fips bool, keys *KeyPool) (*MTLSCerts, error) {
 	// TODO(cdoan): handle the error, and put this into a function to reuse
 	now := time.Now()
 	expiry := now.Add(GetCertDuration())
@@ -67,7 +139,7 @@ func generateMTLSCerts(ns string) (*MTLSCerts, error) {
 		BasicConstraintsValid: true,
 	}
 	// generate a private key
-	caPrivKey, err := rsa.GenerateKey(rand.Reader, PRIVATE_KEY_SIZE)
+	caPrivKey, err := keys.GenerateKey(privateKeySize(fips))
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +168,7 @@ func generateMTLSCerts(ns string) (*MTLSCerts, error) {
 
 	cert.DNSNames = []string{getServiceName(ns)}
 
-	certPrivKey, err := rsa.GenerateKey(rand.Reader, PRIVATE_KEY_SIZE)
+	certPrivKey, err := keys.GenerateKey(privateKeySize(fips))
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +198,7 @@ func generateMTLSCerts(ns string) (*MTLSCerts, error) {
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
 	}
-	clientPrivKey, err := rsa.GenerateKey(rand.Reader, PRIVATE_KEY_SIZE)
+	clientPrivKey, err := keys.GenerateKey(privateKeySize(fips))
 	if err != nil {
 		return nil, err
 	}
@@ -140,29 +212,6 @@ func generateMTLSCerts(ns string) (*MTLSCerts, error) {
 	// convert the server cert/key to PEM Encoiding
 	clientPEM, clientPrivKeyPEM := PEMEncode(clientBytes, clientPrivKey)
 
-	// fmt.Println("create ca:\n", ca)
-	// fmt.Println("create ca privatekey:\n", caPrivKey)
-	// fmt.Println("create ca cert:\n", caBytes)
-	// fmt.Println("create ca cert in PEM format:\n", caPEM.String())
-	// fmt.Println("create ca key in PEM format:\n", caPrivKeyPEM.String())
-
-	// bufferToFile("ca.crt", caPEM.Bytes())
-
-	// fmt.Println("create server certificate:\n", cert)
-	// fmt.Println("create server key:\n", certPrivKey)
-
-	// fmt.Println("sign cert:\n", certBytes)
-	// fmt.Println("signed server cert in PEM:\n", certPEM)
-	// fmt.Println("signed server key in PEM:\n", certPrivKeyPEM)
-
-	// fmt.Println("signed client cert in PEM:\n", clientPEM)
-	// fmt.Println("signed client key in PEM:\n", clientPrivKeyPEM)
-
-	// bufferToFile("server.crt", certPEM.Bytes())
-	// bufferToFile("server.key", certPrivKeyPEM.Bytes())
-	// bufferToFile("client.crt", clientPEM.Bytes())
-	// bufferToFile("client.key", clientPrivKeyPEM.Bytes())
-
 	return &MTLSCerts{
 		caPEM:            caPEM,
 		caPrivKeyPEM:     caPrivKeyPEM,
@@ -174,6 +223,51 @@ func generateMTLSCerts(ns string) (*MTLSCerts, error) {
 	}, nil
 }
 
+// Generate a self-signed certificate for the dex web endpoint, for clusters that do not provide service serving certificates.
+// Hosts that are IP addresses are added as IP SANs.
+func generateServingCert(hosts []string, fips bool, keys *KeyPool) (*bytes.Buffer, *bytes.Buffer, time.Time, error) {
+	var dnsNames []string
+	var ipAddresses []net.IP
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	now := time.Now()
+	expiry := now.Add(servingCertDuration)
+	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return nil, nil, expiry, err
+	}
+	cert := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"Red Hat, Inc."},
+			Country:      []string{"US"},
+			CommonName:   hosts[0],
+		},
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
+		NotBefore:             now,
+		NotAfter:              expiry,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+	}
+	certPrivKey, err := keys.GenerateKey(privateKeySize(fips))
+	if err != nil {
+		return nil, nil, expiry, err
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, cert, cert, &certPrivKey.PublicKey, certPrivKey)
+	if err != nil {
+		return nil, nil, expiry, err
+	}
+	certPEM, certPrivKeyPEM := PEMEncode(certBytes, certPrivKey)
+	return certPEM, certPrivKeyPEM, expiry, nil
+}
+
 func PEMEncode(caBytes []byte, caPrivKey *rsa.PrivateKey) (*bytes.Buffer, *bytes.Buffer) {
 	caPEM := new(bytes.Buffer)
 	pem.Encode(caPEM, &pem.Block{
@@ -190,30 +284,15 @@ func PEMEncode(caBytes []byte, caPrivKey *rsa.PrivateKey) (*bytes.Buffer, *bytes
 	return caPEM, caPrivKeyPEM
 }
 
-func bufferToFile(name string, thing []byte) {
-	err := ioutil.WriteFile(name, thing, 0644)
-	if err != nil {
-		fmt.Println("failed to write file: " + name)
+// Return the first PEM encoded certificate of a bundle
+func firstPEMCertificate(bundle []byte) []byte {
+	block, _ := pem.Decode(bundle)
+	if block == nil {
+		return nil
 	}
+	return pem.EncodeToMemory(block)
 }
 
 func getServiceName(ns string) string {
 	return fmt.Sprintf("%s.%s.svc.cluster.local", GRPC_SERVICE_NAME, ns)
 }
-
-func verifyCACert() error {
-	out, err := exec.Command("openssl", "verify", "-CAfile", "ca.crt", "server.crt").Output()
-	if err != nil {
-		log.Fatal(err)
-		return err
-	}
-	fmt.Printf("Output:\n%s\n", out)
-
-	out, err = exec.Command("openssl", "verify", "-CAfile", "ca.crt", "client.crt").Output()
-	if err != nil {
-		log.Fatal(err)
-		return err
-	}
-	fmt.Printf("Output:\n%s\n", out)
-	return nil
-}
@@ -0,0 +1,34 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Render the dex config of a Microsoft connector, its client secret is read by dex from an environment variable
+func (r *DexServerReconciler) renderMicrosoftConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	err := r.copySecretToDexServerNamespace(dexServer, connector.Microsoft.ClientSecretRef, ctx)
+	if err != nil {
+		return DexConnectorSpec{}, err
+	}
+
+	// Environment variable that references the Microsoft OAuth client secret copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple Microsoft connectors
+	clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + getUniqueAlphanumericIdForConnector(connector)
+
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeMicrosoft),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			ClientID:     connector.Microsoft.ClientID,
+			ClientSecret: clientSecretEnvVariable,
+			RedirectURI:  connector.Microsoft.RedirectURI,
+			Tenant:       connector.Microsoft.Tenant,
+		},
+	}, nil
+}
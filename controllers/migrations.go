@@ -0,0 +1,167 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Annotation stamping the layout version of the objects managed for a DexServer. The DexServer is stamped once
+	// the migrations of its objects to the layout version succeeded.
+	LAYOUT_VERSION_ANNOTATION = "auth.identitatem.io/layoutVersion"
+)
+
+// layoutMigration upgrades the objects of a DexServer from the layout of the previous version to Version
+type layoutMigration struct {
+	Version int
+	// Name of the migration in the logs and errors
+	Name    string
+	Migrate func(r *DexServerReconciler, dexServer *authv1alpha1.DexServer, ctx context.Context) error
+}
+
+// Migrations from the layouts of the previous operator versions, in the order of their versions. A migration must be
+// idempotent, as it runs again when a later migration fails. The DexServers created before the layout versions have
+// the version 0.
+var layoutMigrations []layoutMigration
+
+// The migrations are set in init, as they label the objects with the layout version read from layoutMigrations
+func init() {
+	layoutMigrations = []layoutMigration{
+		{Version: 1, Name: "adopt-grpc-objects", Migrate: adoptGRPCObjects},
+	}
+}
+
+// Get the layout version of the objects created by the operator
+func getLayoutVersion() int {
+	return layoutMigrations[len(layoutMigrations)-1].Version
+}
+
+// Get the layout version of the objects of the DexServer
+func getDexServerLayoutVersion(dexServer *authv1alpha1.DexServer) (int, error) {
+	value, ok := dexServer.Annotations[LAYOUT_VERSION_ANNOTATION]
+	if !ok {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid annotation %s: %q", LAYOUT_VERSION_ANNOTATION, value)
+	}
+	return version, nil
+}
+
+// Run the migrations of the layouts the objects of the DexServer missed, in order, and stamp the DexServer with the
+// layout version once they all succeeded. A DexServer stamped by a newer operator is not reconciled, so that a
+// downgraded operator doesn't revert the objects to a layout the newer dex servers and consumers don't expect.
+func (r *DexServerReconciler) migrateLayout(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	version, err := getDexServerLayoutVersion(dexServer)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == getLayoutVersion():
+		return nil
+	case version > getLayoutVersion():
+		return fmt.Errorf("the objects of the DexServer have the layout version %d of a newer operator, the layout version of this operator is %d", version, getLayoutVersion())
+	}
+
+	for _, migration := range layoutMigrations {
+		if migration.Version <= version {
+			continue
+		}
+		log.Info("Migrating the layout of the DexServer", "Migration", migration.Name, "From", version, "To", migration.Version)
+		if err := migration.Migrate(r, dexServer, ctx); err != nil {
+			return errors.Wrapf(err, "error migrating the layout of the DexServer with %s", migration.Name)
+		}
+	}
+
+	patch := client.MergeFrom(dexServer.DeepCopy())
+	if dexServer.Annotations == nil {
+		dexServer.Annotations = map[string]string{}
+	}
+	dexServer.Annotations[LAYOUT_VERSION_ANNOTATION] = strconv.Itoa(getLayoutVersion())
+	return r.Patch(ctx, dexServer, patch)
+}
+
+// MigrateLayouts runs the layout migrations of the DexServers on operator startup, so that the objects of every
+// DexServer are upgraded even when its reconcile fails before. The reconcile of a DexServer runs the migrations
+// again when they fail here.
+func (r *DexServerReconciler) MigrateLayouts(ctx context.Context) error {
+	log := ctrllog.FromContext(ctx).WithName("layout-migrations")
+	dexServers := &authv1alpha1.DexServerList{}
+	if err := r.List(ctx, dexServers); err != nil {
+		return errors.Wrap(err, "error listing the DexServers to migrate")
+	}
+	for i := range dexServers.Items {
+		dexServer := &dexServers.Items[i]
+		if dexServer.DeletionTimestamp != nil {
+			continue
+		}
+		dexServerCtx := ctrllog.IntoContext(ctx, log.WithValues("namespace", dexServer.Namespace, "name", dexServer.Name))
+		if err := r.migrateLayout(dexServer, dexServerCtx); err != nil {
+			log.Error(err, "failed to migrate the layout of the DexServer, its reconcile retries", "namespace", dexServer.Namespace, "name", dexServer.Name)
+		}
+	}
+	return nil
+}
+
+// The gRPC Service and the mTLS Secret have fixed names in the namespace of the DexServer. The operators before the
+// layout versions created them without the managed labels, and the mTLS Secret kept its metadata until its
+// certificates were renewed. Set their shared metadata, and the DexServer as their controller when they have none.
+// The objects already controlled by another DexServer of the namespace are shared with it and left to their
+// controller, so that the other dex servers of the namespace can still be migrated.
+func adoptGRPCObjects(r *DexServerReconciler, dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	for _, obj := range []client.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: GRPC_SERVICE_NAME}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: SECRET_MTLS_NAME}},
+	} {
+		name := obj.GetName()
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: dexServer.Namespace}, obj); err != nil {
+			if kubeerrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if owner := metav1.GetControllerOf(obj); owner != nil && owner.UID != dexServer.UID {
+			log.Info("Leaving the shared object to its controller", "Name", name, "Controller.Kind", owner.Kind, "Controller.Name", owner.Name)
+			continue
+		}
+		labels, annotations := obj.GetLabels(), obj.GetAnnotations()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		labels["app"] = dexServer.Name
+		delete(labels, INSTANCE_LABEL)
+		delete(annotations, INVENTORY_HASH_ANNOTATION)
+		addSharedMetadata(componentGRPC, labels, annotations)
+		obj.SetLabels(labels)
+		obj.SetAnnotations(annotations)
+		if metav1.GetControllerOf(obj) == nil {
+			if err := ctrl.SetControllerReference(dexServer, obj, r.Scheme); err != nil {
+				return err
+			}
+		}
+		if err := r.Update(ctx, obj); err != nil {
+			return errors.Wrapf(err, "error updating %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Render the dex config of a mockCallback connector, which logs in its test user without any configuration
+func (r *DexServerReconciler) renderMockCallbackConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeMockCallback),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+	}, nil
+}
+
+// Render the dex config of a mockPassword connector, its password is read by dex from an environment variable
+func (r *DexServerReconciler) renderMockPasswordConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	err := r.copySecretToDexServerNamespace(dexServer, connector.MockPassword.PasswordRef, ctx)
+	if err != nil {
+		return DexConnectorSpec{}, err
+	}
+
+	// Environment variable that references the password copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between the passwords of multiple mockPassword connectors
+	passwordEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + getUniqueAlphanumericIdForConnector(connector)
+
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeMockPassword),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			Username: connector.MockPassword.Username,
+			Password: passwordEnvVariable,
+		},
+	}, nil
+}
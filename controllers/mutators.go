@@ -0,0 +1,94 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"open-cluster-management.io/clusteradm/pkg/helpers/asset"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// ResourceMutator adjusts the objects rendered for a DexServer before they are created or updated, so that the
+// distributions embedding the operator can customize the Deployments, Services and other objects of the templates
+// without maintaining a fork. Mutators are registered with RegisterResourceMutator.
+type ResourceMutator interface {
+	// Mutate changes obj in place. It is called for each object rendered from the templates, on every reconcile,
+	// and must be idempotent. An error fails the reconcile of the DexServer.
+	Mutate(ctx context.Context, dexServer *authv1alpha1.DexServer, obj *unstructured.Unstructured) error
+}
+
+// ResourceMutatorFunc is a function implementing ResourceMutator
+type ResourceMutatorFunc func(ctx context.Context, dexServer *authv1alpha1.DexServer, obj *unstructured.Unstructured) error
+
+// Mutate implements ResourceMutator
+func (f ResourceMutatorFunc) Mutate(ctx context.Context, dexServer *authv1alpha1.DexServer, obj *unstructured.Unstructured) error {
+	return f(ctx, dexServer, obj)
+}
+
+type namedResourceMutator struct {
+	name    string
+	mutator ResourceMutator
+}
+
+var (
+	resourceMutatorsMu sync.RWMutex
+	resourceMutators   []namedResourceMutator
+)
+
+// RegisterResourceMutator adds a mutator called on the objects of every DexServer, after the mutators registered
+// before it. Mutators must be registered before the manager is started, the name identifies the mutator in the errors.
+func RegisterResourceMutator(name string, mutator ResourceMutator) {
+	resourceMutatorsMu.Lock()
+	defer resourceMutatorsMu.Unlock()
+	resourceMutators = append(resourceMutators, namedResourceMutator{name: name, mutator: mutator})
+}
+
+func getResourceMutators() []namedResourceMutator {
+	resourceMutatorsMu.RLock()
+	defer resourceMutatorsMu.RUnlock()
+	return append([]namedResourceMutator{}, resourceMutators...)
+}
+
+// Run the registered mutators on the objects rendered from files, and return the reader the applier renders the
+// mutated objects from. The reader of the templates is returned as is when no mutator is registered.
+func mutateObjects(ctx context.Context, dexServer *authv1alpha1.DexServer, reader asset.ScenarioReader, files []string, objects []*unstructured.Unstructured) (asset.ScenarioReader, error) {
+	mutators := getResourceMutators()
+	if len(mutators) == 0 {
+		return reader, nil
+	}
+	mutated := &mutatedReader{ScenarioReader: reader, assets: map[string][]byte{}}
+	for i, obj := range objects {
+		for _, m := range mutators {
+			if err := m.mutator.Mutate(ctx, dexServer, obj); err != nil {
+				return nil, errors.Wrapf(err, "resource mutator %s failed on %s %s", m.name, obj.GetKind(), obj.GetName())
+			}
+		}
+		objYaml, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		// a template printing the object verbatim, as the applier renders the assets it reads
+		mutated.assets[files[i]] = []byte(fmt.Sprintf("{{ %q }}", objYaml))
+	}
+	return mutated, nil
+}
+
+// mutatedReader serves the mutated objects in place of the templates they were rendered from
+type mutatedReader struct {
+	asset.ScenarioReader
+	assets map[string][]byte
+}
+
+func (r *mutatedReader) Asset(name string) ([]byte, error) {
+	if b, ok := r.assets[name]; ok {
+		return b, nil
+	}
+	return r.ScenarioReader.Asset(name)
+}
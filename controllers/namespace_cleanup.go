@@ -0,0 +1,173 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// sharedObject is an object with a fixed name, shared by the dex servers of a namespace
+type sharedObject struct {
+	Kind   string
+	Object client.Object
+}
+
+// Get the shared objects of a namespace: the gRPC Service, the service account of dex and its ClusterRoleBinding.
+// With pre-provisioned RBAC, the ClusterRoleBinding is owned by the administrator.
+func (r *DexServerReconciler) getSharedObjects(namespace string) []sharedObject {
+	objects := []sharedObject{
+		{Kind: "Service", Object: &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: GRPC_SERVICE_NAME, Namespace: namespace}}},
+		{Kind: "ServiceAccount", Object: &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: SERVICE_ACCOUNT_NAME, Namespace: namespace}}},
+	}
+	if !r.PreProvisionedRBAC {
+		objects = append(objects, sharedObject{
+			Kind:   "ClusterRoleBinding",
+			Object: &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: SERVICE_ACCOUNT_NAME + "-" + namespace}},
+		})
+	}
+	return objects
+}
+
+// Whether a shared object was created by the operator. The service account and the ClusterRoleBinding must be
+// labeled as managed by the operator, which the reconcile of a DexServer sets on those of the previous versions, as
+// an administrator may have created them, with pre-provisioned RBAC in particular. The "grpc" name is common, the
+// gRPC Services of the operator select the dex pods of the DexServer they are labeled with, the layout version 0
+// included.
+func isOperatorSharedObject(obj client.Object) bool {
+	managedBy, hasManagedBy := obj.GetLabels()[MANAGED_BY_LABEL]
+	if hasManagedBy && managedBy != MANAGED_BY_VALUE {
+		return false
+	}
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		return hasManagedBy
+	}
+	app := service.Labels["app"]
+	return app != "" && service.Spec.Selector["app"] == app
+}
+
+// Whether another DexServer of the namespace, not being deleted, uses the shared objects
+func (r *DexServerReconciler) isNamespaceInUse(namespace string, except types.UID, ctx context.Context) (bool, error) {
+	dexServers := &authv1alpha1.DexServerList{}
+	if err := r.List(ctx, dexServers, client.InNamespace(namespace)); err != nil {
+		return false, errors.Wrap(err, "error listing the DexServers of the namespace")
+	}
+	for _, dexServer := range dexServers.Items {
+		if dexServer.UID != except && dexServer.DeletionTimestamp == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Delete the shared objects of a namespace no DexServer uses anymore, and report them as "<kind> <namespace>/<name>",
+// "<kind> <name>" for the ClusterRoleBinding.
+// With NamespaceCleanupDryRun, the deletions are dry runs and the report lists the objects that would be deleted.
+func (r *DexServerReconciler) cleanupNamespace(namespace string, ctx context.Context) ([]string, error) {
+	log := ctrllog.FromContext(ctx)
+	var deleteOptions []client.DeleteOption
+	if r.NamespaceCleanupDryRun {
+		deleteOptions = append(deleteOptions, client.DryRunAll)
+	}
+	report := []string{}
+	for _, shared := range r.getSharedObjects(namespace) {
+		kind, obj := shared.Kind, shared.Object
+		key := client.ObjectKeyFromObject(obj)
+		if err := r.Get(ctx, key, obj); err != nil {
+			if kubeerrors.IsNotFound(err) {
+				continue
+			}
+			return report, err
+		}
+		if !isOperatorSharedObject(obj) {
+			log.Info("Keeping the shared object not created by the operator", "Name", key.Name, "Namespace", key.Namespace)
+			continue
+		}
+		if err := r.Delete(ctx, obj, deleteOptions...); err != nil && !kubeerrors.IsNotFound(err) {
+			return report, errors.Wrapf(err, "error deleting the %s %s", kind, key.Name)
+		}
+		log.Info("Cleaned up the shared object of the namespace", "Kind", kind, "Name", key.Name, "Namespace", key.Namespace,
+			"DryRun", r.NamespaceCleanupDryRun)
+		if key.Namespace == "" {
+			report = append(report, fmt.Sprintf("%s %s", kind, key.Name))
+		} else {
+			report = append(report, fmt.Sprintf("%s %s", kind, key))
+		}
+	}
+	return report, nil
+}
+
+// Clean up the shared objects when the last DexServer of its namespace is deleted, see cleanupNamespace
+func (r *DexServerReconciler) cleanupDexServerNamespace(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	inUse, err := r.isNamespaceInUse(dexServer.Namespace, dexServer.UID, ctx)
+	if err != nil || inUse {
+		return err
+	}
+	report, err := r.cleanupNamespace(dexServer.Namespace, ctx)
+	if err != nil {
+		return err
+	}
+	if r.Recorder != nil && len(report) > 0 {
+		verb := "Deleted"
+		if r.NamespaceCleanupDryRun {
+			verb = "Would delete"
+		}
+		r.Recorder.Eventf(dexServer, corev1.EventTypeNormal, "NamespaceCleanup", "%s the shared objects of the last DexServer of the namespace: %v", verb, report)
+	}
+	return nil
+}
+
+// CleanupNamespaces deletes the shared objects left over by the operators before the namespace cleanup, on operator
+// startup: in the namespaces without DexServer, and the ClusterRoleBindings of the deleted namespaces.
+func (r *DexServerReconciler) CleanupNamespaces(ctx context.Context) error {
+	log := ctrllog.FromContext(ctx).WithName("namespace-cleanup")
+	ctx = ctrllog.IntoContext(ctx, log)
+	namespaces := map[string]bool{}
+	serviceAccounts, err := r.KubeClient.CoreV1().ServiceAccounts(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", SERVICE_ACCOUNT_NAME).String(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error listing the service accounts of dex")
+	}
+	for _, serviceAccount := range serviceAccounts.Items {
+		namespaces[serviceAccount.Namespace] = true
+	}
+	if !r.PreProvisionedRBAC {
+		clusterRoleBindings, err := r.KubeClient.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "error listing the ClusterRoleBindings of dex")
+		}
+		for _, clusterRoleBinding := range clusterRoleBindings.Items {
+			for _, subject := range clusterRoleBinding.Subjects {
+				if subject.Kind == rbacv1.ServiceAccountKind && subject.Name == SERVICE_ACCOUNT_NAME &&
+					clusterRoleBinding.Name == SERVICE_ACCOUNT_NAME+"-"+subject.Namespace {
+					namespaces[subject.Namespace] = true
+				}
+			}
+		}
+	}
+
+	for namespace := range namespaces {
+		inUse, err := r.isNamespaceInUse(namespace, "", ctx)
+		if err == nil && !inUse {
+			_, err = r.cleanupNamespace(namespace, ctx)
+		}
+		if err != nil {
+			log.Error(err, "failed to clean up the shared objects of the namespace", "Namespace", namespace)
+		}
+	}
+	return nil
+}
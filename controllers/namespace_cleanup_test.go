@@ -0,0 +1,96 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Clean up the shared objects of the namespaces", func() {
+	namespace := "my-cleanup-ns"
+	grpcService := func(app string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: GRPC_SERVICE_NAME, Namespace: namespace, Labels: map[string]string{"app": app}},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": app},
+				Ports:    []corev1.ServicePort{{Name: "grpc", Port: 5557}},
+			},
+		}
+	}
+	managedLabels := map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE}
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: SERVICE_ACCOUNT_NAME, Namespace: namespace, Labels: managedLabels}}
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: SERVICE_ACCOUNT_NAME + "-" + namespace, Labels: managedLabels},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: SERVICE_ACCOUNT_NAME},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: SERVICE_ACCOUNT_NAME, Namespace: namespace}},
+	}
+
+	It("should delete the shared objects of the last DexServer of the namespace", func() {
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), grpcService("my-legacy-dexserver"))).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), serviceAccount.DeepCopy())).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), clusterRoleBinding.DeepCopy())).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-cleanup-dexserver", Namespace: namespace, UID: "my-cleanup-uid"}}
+
+		By("only reporting them in a dry run", func() {
+			dryRun := rDexServer
+			dryRun.NamespaceCleanupDryRun = true
+			report, err := dryRun.cleanupNamespace(namespace, context.TODO())
+			Expect(err).To(BeNil())
+			Expect(report).To(ConsistOf(
+				"Service my-cleanup-ns/grpc",
+				"ServiceAccount my-cleanup-ns/dex-operator-dexsso",
+				"ClusterRoleBinding dex-operator-dexsso-my-cleanup-ns",
+			))
+			Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(clusterRoleBinding), &rbacv1.ClusterRoleBinding{})).To(Succeed())
+		})
+		Expect(rDexServer.cleanupDexServerNamespace(dexServer, context.TODO())).To(Succeed())
+		for _, obj := range []client.Object{grpcService(""), serviceAccount.DeepCopy(), clusterRoleBinding.DeepCopy()} {
+			err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj)
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue(), "%s", client.ObjectKeyFromObject(obj))
+		}
+	})
+	It("should keep the shared objects while another DexServer uses them", func() {
+		namespace := "my-shared-cleanup-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-shared-dexserver", Namespace: namespace}}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+
+		inUse, err := rDexServer.isNamespaceInUse(namespace, "", context.TODO())
+		Expect(err).To(BeNil())
+		Expect(inUse).To(BeTrue())
+		inUse, err = rDexServer.isNamespaceInUse(namespace, dexServer.UID, context.TODO())
+		Expect(err).To(BeNil())
+		Expect(inUse).To(BeFalse())
+	})
+	It("should keep the gRPC Services of the other applications", func() {
+		service := grpcService("my-app")
+		service.Spec.Selector["app"] = "my-grpc-app"
+		Expect(isOperatorSharedObject(service)).To(BeFalse())
+		Expect(isOperatorSharedObject(grpcService("my-dexserver"))).To(BeTrue())
+	})
+	It("should keep the service accounts and ClusterRoleBindings not managed by the operator", func() {
+		namespace := "my-unmanaged-cleanup-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		unmanaged := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: SERVICE_ACCOUNT_NAME, Namespace: namespace}}
+		Expect(k8sClient.Create(context.TODO(), unmanaged)).To(Succeed())
+		Expect(isOperatorSharedObject(unmanaged)).To(BeFalse())
+		Expect(isOperatorSharedObject(&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{MANAGED_BY_LABEL: "my-admin"}}})).To(BeFalse())
+		Expect(isOperatorSharedObject(clusterRoleBinding)).To(BeTrue())
+
+		report, err := rDexServer.cleanupNamespace(namespace, context.TODO())
+		Expect(err).To(BeNil())
+		Expect(report).To(BeEmpty())
+		Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(unmanaged), &corev1.ServiceAccount{})).To(Succeed())
+	})
+})
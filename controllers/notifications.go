@@ -0,0 +1,156 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	NOTIFICATION_EVENT_DEGRADED            = "Degraded"
+	NOTIFICATION_EVENT_CREDENTIAL_EXPIRING = "CredentialExpiring"
+
+	notificationTimeout = 10 * time.Second
+)
+
+// Notifier posts the notifications of the DexServers to a webhook, see --notification-webhook-url. Each transition
+// is notified once, the notifications that could not be posted are retried on the next reconcile.
+type Notifier struct {
+	// URL of the webhook, for example a Slack incoming webhook
+	URL    string
+	Client *http.Client
+
+	mutex sync.Mutex
+	// DexServers whose Degraded transition was notified, or found Degraded when the operator started
+	degraded map[string]bool
+	// Expiry of the credentials whose expiry was notified, by DexServer and credential
+	expiring map[string]time.Time
+}
+
+// NewNotifier returns a notifier posting to the webhook at url
+func NewNotifier(url string) *Notifier {
+	return &Notifier{
+		URL:      url,
+		Client:   &http.Client{Timeout: notificationTimeout},
+		degraded: map[string]bool{},
+		expiring: map[string]time.Time{},
+	}
+}
+
+// Notification is the JSON body posted to the webhook. Slack only displays the text, the other fields are for the
+// generic receivers.
+type Notification struct {
+	Text      string `json:"text"`
+	Event     string `json:"event"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (n *Notifier) post(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("the notification webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Notify the transition of a DexServer to Degraded. wasDegraded is the Degraded condition before the reconcile, a
+// DexServer that was already Degraded, e.g. before the operator restarted, is not notified again.
+func (n *Notifier) notifyDegraded(dexServer *authv1alpha1.DexServer, wasDegraded bool, ctx context.Context) {
+	if n == nil {
+		return
+	}
+	log := ctrllog.FromContext(ctx)
+	key := dexServer.Namespace + "/" + dexServer.Name
+	condition := meta.FindStatusCondition(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded)
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if dexServer.DeletionTimestamp != nil || !meta.IsStatusConditionTrue(dexServer.Status.Conditions, authv1alpha1.DexServerConditionTypeDegraded) {
+		delete(n.degraded, key)
+		return
+	}
+	if n.degraded[key] {
+		return
+	}
+	if wasDegraded {
+		n.degraded[key] = true
+		return
+	}
+	err := n.post(ctx, Notification{
+		Text:      fmt.Sprintf("DexServer %s is Degraded: %s", key, condition.Message),
+		Event:     NOTIFICATION_EVENT_DEGRADED,
+		Namespace: dexServer.Namespace,
+		Name:      dexServer.Name,
+		Reason:    condition.Reason,
+		Message:   condition.Message,
+	})
+	if err != nil {
+		log.Info("WARNING: the Degraded notification could not be posted", "Error", err.Error())
+		return
+	}
+	n.degraded[key] = true
+}
+
+// Notify the credentials of a DexServer about to expire, see reportCredentialExpiry. Each expiry of a credential is
+// notified once, a renewed credential is notified again when its new expiry nears.
+func (n *Notifier) notifyCredentialsExpiring(dexServer *authv1alpha1.DexServer, expiring []authv1alpha1.CredentialExpiryStatus, ctx context.Context) {
+	if n == nil {
+		return
+	}
+	log := ctrllog.FromContext(ctx)
+	key := dexServer.Namespace + "/" + dexServer.Name
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	credentials := []string{}
+	for _, status := range expiring {
+		if notAfter, ok := n.expiring[key+"/"+status.Name]; ok && notAfter.Equal(status.NotAfter.Time) {
+			continue
+		}
+		credentials = append(credentials, fmt.Sprintf("%s on %s", status.Name, status.NotAfter.UTC().Format(time.RFC3339)))
+	}
+	if len(credentials) == 0 {
+		return
+	}
+	message := "Certificates about to expire: " + strings.Join(credentials, ", ")
+	err := n.post(ctx, Notification{
+		Text:      fmt.Sprintf("DexServer %s: %s", key, message),
+		Event:     NOTIFICATION_EVENT_CREDENTIAL_EXPIRING,
+		Namespace: dexServer.Namespace,
+		Name:      dexServer.Name,
+		Message:   message,
+	})
+	if err != nil {
+		log.Info("WARNING: the credential expiry notification could not be posted", "Error", err.Error())
+		return
+	}
+	for _, status := range expiring {
+		n.expiring[key+"/"+status.Name] = status.NotAfter.Time
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Post the notifications of the DexServers", func() {
+	var notifications []Notification
+	var status int
+	var server *httptest.Server
+	BeforeEach(func() {
+		notifications = nil
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			notification := Notification{}
+			Expect(json.NewDecoder(req.Body).Decode(&notification)).To(Succeed())
+			notifications = append(notifications, notification)
+			w.WriteHeader(status)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should notify the transitions to Degraded once", func() {
+		notifier := NewNotifier(server.URL)
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-dexserver", Namespace: "my-notified-ns"}}
+		dexServer.Status.Conditions = []metav1.Condition{{
+			Type:    authv1alpha1.DexServerConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  "SecretMissing",
+			Message: "the dex pods serve the previous configuration",
+		}}
+
+		By("retrying the notifications the webhook refused", func() {
+			status = http.StatusInternalServerError
+			notifier.notifyDegraded(dexServer, false, context.TODO())
+			status = http.StatusOK
+			notifier.notifyDegraded(dexServer, false, context.TODO())
+			Expect(notifications).To(HaveLen(2))
+			Expect(notifications[1].Event).To(Equal(NOTIFICATION_EVENT_DEGRADED))
+			Expect(notifications[1].Reason).To(Equal("SecretMissing"))
+			Expect(notifications[1].Text).To(ContainSubstring("my-notified-ns/my-dexserver"))
+		})
+		notifier.notifyDegraded(dexServer, true, context.TODO())
+		Expect(notifications).To(HaveLen(2))
+
+		By("notifying again once recovered", func() {
+			dexServer.Status.Conditions[0].Status = metav1.ConditionFalse
+			notifier.notifyDegraded(dexServer, true, context.TODO())
+			dexServer.Status.Conditions[0].Status = metav1.ConditionTrue
+			notifier.notifyDegraded(dexServer, false, context.TODO())
+			Expect(notifications).To(HaveLen(3))
+		})
+		By("not notifying the DexServers already Degraded when the operator started", func() {
+			other := dexServer.DeepCopy()
+			other.Name = "my-other-dexserver"
+			NewNotifier(server.URL).notifyDegraded(other, true, context.TODO())
+			Expect(notifications).To(HaveLen(3))
+		})
+	})
+	It("should notify each expiry of the credentials once", func() {
+		notifier := NewNotifier(server.URL)
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-dexserver", Namespace: "my-notified-ns"}}
+		expiring := []authv1alpha1.CredentialExpiryStatus{{Name: "connector/ldap", NotAfter: metav1.NewTime(time.Now().Add(time.Hour))}}
+		notifier.notifyCredentialsExpiring(dexServer, expiring, context.TODO())
+		notifier.notifyCredentialsExpiring(dexServer, expiring, context.TODO())
+		Expect(notifications).To(HaveLen(1))
+		Expect(notifications[0].Event).To(Equal(NOTIFICATION_EVENT_CREDENTIAL_EXPIRING))
+		Expect(notifications[0].Message).To(ContainSubstring("connector/ldap"))
+
+		expiring[0].NotAfter = metav1.NewTime(time.Now().Add(2 * time.Hour))
+		notifier.notifyCredentialsExpiring(dexServer, expiring, context.TODO())
+		Expect(notifications).To(HaveLen(2))
+
+		var disabled *Notifier
+		disabled.notifyCredentialsExpiring(dexServer, expiring, context.TODO())
+		Expect(notifications).To(HaveLen(2))
+	})
+})
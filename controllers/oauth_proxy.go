@@ -0,0 +1,249 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Set on the Services protected by the oauth2-proxy client of a DexServer, to <DexServer namespace>.<DexServer name>
+	OAUTH_PROXY_LABEL = "auth.identitatem.io/oauth-proxy"
+	// DexClient and Secrets of the oauth2-proxy sidecars, see spec.oauthProxy
+	OAUTH_PROXY_SUFFIX = "-oauth-proxy"
+
+	OAUTH_PROXY_CLIENT_ID_KEY     = "clientID"
+	OAUTH_PROXY_CLIENT_SECRET_KEY = "clientSecret"
+	OAUTH_PROXY_COOKIE_SECRET_KEY = "cookieSecret"
+	OAUTH_PROXY_ISSUER_KEY        = "issuer"
+
+	DEFAULT_OAUTH_PROXY_REDIRECT_URI_TEMPLATE = "https://{{ .Service }}-{{ .Namespace }}.{{ .Domain }}/oauth2/callback"
+)
+
+// Fields of spec.oauthProxy.redirectURITemplate
+type oauthProxyRedirectURIValues struct {
+	Service   string
+	Namespace string
+	Domain    string
+}
+
+// Get the value of the label of the Services protected by the oauth2-proxy client. The namespaces can't contain a dot,
+// the value is split at the first one.
+func getOAuthProxyLabelValue(dexServer *authv1alpha1.DexServer) string {
+	return dexServer.Namespace + "." + dexServer.Name
+}
+
+// Get the DexServer of the label of a Service protected by an oauth2-proxy client
+func getDexServerForOAuthProxyService(obj client.Object) []reconcile.Request {
+	value := obj.GetLabels()[OAUTH_PROXY_LABEL]
+	i := strings.Index(value, ".")
+	if i <= 0 {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: value[:i], Name: value[i+1:]}}}
+}
+
+// Get the client id of the oauth2-proxy client
+func getOAuthProxyClientID(dexServer *authv1alpha1.DexServer) string {
+	if clientID := dexServer.Spec.OAuthProxy.ClientID; clientID != "" {
+		return clientID
+	}
+	return dexServer.Name + OAUTH_PROXY_SUFFIX
+}
+
+// Get the domain of the issuer host, its host without the first label, which is the domain of the default Route hosts
+// on OpenShift
+func getIssuerDomain(issuer string) (string, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return "", err
+	}
+	host := u.Hostname()
+	i := strings.Index(host, ".")
+	if i < 0 {
+		return "", fmt.Errorf("issuer host %s has no domain", host)
+	}
+	return host[i+1:], nil
+}
+
+// Get the redirect URIs of the protected Services from spec.oauthProxy.redirectURITemplate, sorted
+func getOAuthProxyRedirectURIs(dexServer *authv1alpha1.DexServer, issuer string, services []corev1.Service) ([]string, error) {
+	text := dexServer.Spec.OAuthProxy.RedirectURITemplate
+	if text == "" {
+		text = DEFAULT_OAUTH_PROXY_REDIRECT_URI_TEMPLATE
+	}
+	tmpl, err := template.New("redirectURI").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing spec.oauthProxy.redirectURITemplate")
+	}
+	values := oauthProxyRedirectURIValues{}
+	if len(services) > 0 && strings.Contains(text, ".Domain") {
+		if values.Domain, err = getIssuerDomain(issuer); err != nil {
+			return nil, err
+		}
+	}
+	redirectURIs := []string{}
+	for _, service := range services {
+		values.Service = service.Name
+		values.Namespace = service.Namespace
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return nil, errors.Wrapf(err, "error rendering the redirect URI of Service %s/%s", service.Namespace, service.Name)
+		}
+		redirectURIs = append(redirectURIs, buf.String())
+	}
+	sort.Strings(redirectURIs)
+	return redirectURIs, nil
+}
+
+// Create the DexClient shared by the oauth2-proxy sidecars of spec.oauthProxy, with the redirect URIs of the Services
+// labelled for the DexServer, and publish its generated secret, a cookie secret and the issuer in the
+// <DexServer name>-oauth-proxy Secret of the DexServer namespace and of the namespaces of the Services. The copies in
+// the namespaces without a labelled Service are deleted, and all the objects are deleted once disabled.
+func (r *DexServerReconciler) syncOAuthProxyClient(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	name := dexServer.Name + OAUTH_PROXY_SUFFIX
+	log.Info("syncOAuthProxyClient", "DexClient.Name", name)
+
+	if !dexServer.Spec.OAuthProxy.Enabled {
+		if err := r.deleteOAuthProxySecretCopies(dexServer, ctx, nil); err != nil {
+			return err
+		}
+		return r.deleteGeneratedClient(dexServer, ctx, name)
+	}
+	labelValue := getOAuthProxyLabelValue(dexServer)
+	if errs := validation.IsValidLabelValue(labelValue); len(errs) > 0 {
+		return fmt.Errorf("the %s label of the protected Services can't be set to %s: %s", OAUTH_PROXY_LABEL, labelValue, strings.Join(errs, ", "))
+	}
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services, client.MatchingLabels{OAUTH_PROXY_LABEL: labelValue}); err != nil {
+		return errors.Wrap(err, "error listing the Services protected by oauth2-proxy")
+	}
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	redirectURIs, err := getOAuthProxyRedirectURIs(dexServer, issuer, services.Items)
+	if err != nil {
+		return err
+	}
+
+	clientID := getOAuthProxyClientID(dexServer)
+	data := map[string]string{
+		OAUTH_PROXY_CLIENT_ID_KEY: clientID,
+		OAUTH_PROXY_ISSUER_KEY:    issuer,
+	}
+	data, err = r.applyGeneratedClientSecret(dexServer, ctx, name, componentOAuthProxy, data, OAUTH_PROXY_CLIENT_SECRET_KEY, OAUTH_PROXY_COOKIE_SECRET_KEY)
+	if err != nil {
+		return err
+	}
+	keep := map[string]bool{}
+	for _, service := range services.Items {
+		if service.Namespace == dexServer.Namespace || keep[service.Namespace] {
+			continue
+		}
+		keep[service.Namespace] = true
+		if err := r.applyOAuthProxySecretCopy(dexServer, ctx, service.Namespace, data); err != nil {
+			return err
+		}
+	}
+	if err := r.deleteOAuthProxySecretCopies(dexServer, ctx, keep); err != nil {
+		return err
+	}
+	return r.applyGeneratedDexClient(dexServer, ctx, name, componentOAuthProxy, authv1alpha1.DexClientSpec{
+		ClientID:        clientID,
+		ClientSecretRef: corev1.SecretReference{Name: name, Namespace: dexServer.Namespace},
+		RedirectURIs:    redirectURIs,
+	})
+}
+
+// Labels of the copies of the oauth2-proxy Secret. The copies can't be owned by the DexServer, the labels tell them
+// apart from the Secrets created by someone else.
+func getOAuthProxySecretCopyLabels(dexServer *authv1alpha1.DexServer) map[string]string {
+	labels := getManagedLabels(dexServer, componentOAuthProxy)
+	labels[DEXSERVER_NAMESPACE_LABEL] = dexServer.Namespace
+	return labels
+}
+
+// Create or update the copy of the oauth2-proxy Secret in the namespace of a protected Service
+func (r *DexServerReconciler) applyOAuthProxySecretCopy(dexServer *authv1alpha1.DexServer, ctx context.Context, namespace string, data map[string]string) error {
+	log := ctrllog.FromContext(ctx)
+	name := dexServer.Name + OAUTH_PROXY_SUFFIX
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    getOAuthProxySecretCopyLabels(dexServer),
+			},
+			Type:       corev1.SecretTypeOpaque,
+			StringData: data,
+		}
+		log.Info("Creating the oauth2-proxy secret", "Secret.Namespace", namespace, "Secret.Name", name)
+		return r.Create(ctx, secret)
+	case err != nil:
+		return err
+	}
+	if secret.Labels[DEXSERVER_NAMESPACE_LABEL] != dexServer.Namespace || secret.Labels[INSTANCE_LABEL] != dexServer.Name {
+		return fmt.Errorf("Secret %s/%s already exists and is not managed by the DexServer", namespace, name)
+	}
+	updated := false
+	for key, value := range data {
+		if string(secret.Data[key]) != value {
+			updated = true
+		}
+	}
+	if !updated {
+		return nil
+	}
+	secret.StringData = data
+	log.Info("Updating the oauth2-proxy secret", "Secret.Namespace", namespace, "Secret.Name", name)
+	return r.Update(ctx, secret)
+}
+
+// Delete the copies of the oauth2-proxy Secret except those of the namespaces to keep
+func (r *DexServerReconciler) deleteOAuthProxySecretCopies(dexServer *authv1alpha1.DexServer, ctx context.Context, keep map[string]bool) error {
+	log := ctrllog.FromContext(ctx)
+	secrets := &corev1.SecretList{}
+	selector := client.MatchingLabels{
+		MANAGED_BY_LABEL:          MANAGED_BY_VALUE,
+		INSTANCE_LABEL:            dexServer.Name,
+		COMPONENT_LABEL:           componentOAuthProxy,
+		DEXSERVER_NAMESPACE_LABEL: dexServer.Namespace,
+	}
+	if err := r.List(ctx, secrets, selector); err != nil {
+		return errors.Wrap(err, "error listing the oauth2-proxy secrets")
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if keep[secret.Namespace] {
+			continue
+		}
+		log.Info("Deleting an oauth2-proxy secret", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+		if err := r.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return errors.Wrapf(err, "error deleting Secret %s/%s", secret.Namespace, secret.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Create the client of the oauth2-proxy sidecars", func() {
+	It("should render the redirect URIs of the labelled Services", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-dexserver", Namespace: "my-ns"},
+		}
+		services := []corev1.Service{
+			{ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "argocd", Namespace: "gitops"}},
+		}
+		redirectURIs, err := getOAuthProxyRedirectURIs(dexServer, "https://dex-my-ns.apps.testhost.com", services)
+		Expect(err).To(BeNil())
+		Expect(redirectURIs).To(Equal([]string{
+			"https://argocd-gitops.apps.testhost.com/oauth2/callback",
+			"https://grafana-monitoring.apps.testhost.com/oauth2/callback",
+		}))
+
+		dexServer.Spec.OAuthProxy.RedirectURITemplate = "https://{{ .Service }}.{{ .Namespace }}.example.com/callback"
+		redirectURIs, err = getOAuthProxyRedirectURIs(dexServer, "https://dex", services)
+		Expect(err).To(BeNil())
+		Expect(redirectURIs).To(ContainElement("https://grafana.monitoring.example.com/callback"))
+
+		service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{OAUTH_PROXY_LABEL: getOAuthProxyLabelValue(dexServer)}}}
+		Expect(getDexServerForOAuthProxyService(service)).To(Equal([]reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(dexServer)}}))
+	})
+	It("should publish the secret in the namespaces of the Services", func() {
+		namespace := "my-oauth-proxy-ns"
+		serviceNamespace := "my-protected-ns"
+		for _, ns := range []string{namespace, serviceNamespace} {
+			Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})).To(Succeed())
+		}
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-proxy-dexserver", Namespace: namespace, UID: "my-proxy-uid"},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:     "https://dex.apps.testhost.com",
+				OAuthProxy: authv1alpha1.OAuthProxySpec{Enabled: true},
+			},
+		}
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-app",
+				Namespace: serviceNamespace,
+				Labels:    map[string]string{OAUTH_PROXY_LABEL: getOAuthProxyLabelValue(dexServer)},
+			},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 4180}}},
+		}
+		Expect(k8sClient.Create(context.TODO(), service)).To(Succeed())
+		key := client.ObjectKey{Name: dexServer.Name + OAUTH_PROXY_SUFFIX, Namespace: namespace}
+		copyKey := client.ObjectKey{Name: key.Name, Namespace: serviceNamespace}
+
+		Expect(rDexServer.syncOAuthProxyClient(dexServer, context.TODO())).To(Succeed())
+		dexClient := &authv1alpha1.DexClient{}
+		Expect(k8sClient.Get(context.TODO(), key, dexClient)).To(Succeed())
+		Expect(dexClient.Spec.ClientID).To(Equal("my-proxy-dexserver-oauth-proxy"))
+		Expect(dexClient.Spec.RedirectURIs).To(Equal([]string{"https://my-app-my-protected-ns.apps.testhost.com/oauth2/callback"}))
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(context.TODO(), key, secret)).To(Succeed())
+		Expect(secret.Data[OAUTH_PROXY_COOKIE_SECRET_KEY]).ToNot(BeEmpty())
+		secretCopy := &corev1.Secret{}
+		Expect(k8sClient.Get(context.TODO(), copyKey, secretCopy)).To(Succeed())
+		Expect(secretCopy.Data).To(Equal(secret.Data))
+
+		By("deleting the copies once disabled", func() {
+			dexServer.Spec.OAuthProxy.Enabled = false
+			Expect(rDexServer.syncOAuthProxyClient(dexServer, context.TODO())).To(Succeed())
+			err := k8sClient.Get(context.TODO(), copyKey, &corev1.Secret{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,131 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/diff"
+)
+
+const (
+	// Annotation requesting the operator to observe a DexServer when set to "true": the DexServer is reconciled with
+	// dry runs, and the changes to its objects and conditions are reported instead of being made
+	OBSERVE_ANNOTATION = "auth.identitatem.io/observe"
+)
+
+// observedChanges reports the number of changes the reconcile of each observed DexServer would make
+var observedChanges = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "dex_operator_observed_changes",
+	Help: "Number of changes to the objects and conditions of the observed DexServer the operator would make",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(observedChanges)
+}
+
+type observeReportKey struct{}
+
+// observeReport collects the changes the dry runs of the reconcile of an observed DexServer would make
+type observeReport struct {
+	changes []string
+}
+
+func (r *DexServerReconciler) isObserved(dexServer *authv1alpha1.DexServer) bool {
+	return r.Observe || dexServer.Annotations[OBSERVE_ANNOTATION] == "true"
+}
+
+// withObserveReport returns a context in which applyWithDiff reports the changes to the objects it would apply
+func withObserveReport(ctx context.Context) (context.Context, *observeReport) {
+	report := &observeReport{}
+	return context.WithValue(ctx, observeReportKey{}, report), report
+}
+
+func getObserveReport(ctx context.Context) *observeReport {
+	report, _ := ctx.Value(observeReportKey{}).(*observeReport)
+	return report
+}
+
+func (report *observeReport) add(obj *unstructured.Unstructured, change string) {
+	report.changes = append(report.changes, fmt.Sprintf("%s %s (%s)", obj.GetKind(), obj.GetName(), change))
+}
+
+// Get a copy of the reconciler sending its writes as dry runs, the API server validates and defaults them without
+// persisting them. Its Events and notifications are left out, observe reports the changes in its own Event.
+func (r *DexServerReconciler) dryRunReconciler() *DexServerReconciler {
+	observer := *r
+	observer.Client = client.NewDryRunClient(r.Client)
+	observer.dryRun = []string{metav1.DryRunAll}
+	observer.Recorder = nil
+	observer.Notifier = nil
+	return &observer
+}
+
+// Reconcile an observed DexServer with dry runs, and report the changes to its objects and conditions in the logs,
+// an Observed Event and the dex_operator_observed_changes metric. Neither the DexServer nor its objects are written.
+func (r *DexServerReconciler) observe(dexServer *authv1alpha1.DexServer, ctx context.Context) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	ctx, report := withObserveReport(ctx)
+	observed := dexServer.DeepCopy()
+	result, err := r.dryRunReconciler().reconcileDexServer(observed, ctx)
+
+	for _, cond := range observed.Status.Conditions {
+		current := meta.FindStatusCondition(dexServer.Status.Conditions, cond.Type)
+		if current == nil || current.Status != cond.Status || current.Reason != cond.Reason {
+			report.changes = append(report.changes, fmt.Sprintf("condition %s=%s (%s)", cond.Type, cond.Status, cond.Reason))
+		}
+	}
+	if dexServer.DeletionTimestamp != nil {
+		observedChanges.DeleteLabelValues(dexServer.Namespace, dexServer.Name)
+	} else {
+		observedChanges.WithLabelValues(dexServer.Namespace, dexServer.Name).Set(float64(len(report.changes)))
+	}
+	log.Info("observed DexServer", "Changes", report.changes)
+
+	if r.Recorder != nil {
+		message := "The reconcile would not change the DexServer"
+		if len(report.changes) > 0 {
+			message = fmt.Sprintf("The reconcile would make %d changes: %s", len(report.changes), diff.Summary(report.changes, MAX_EVENT_DIFF_FIELDS))
+		}
+		r.Recorder.Event(dexServer, corev1.EventTypeNormal, "Observed", message)
+	}
+	return result, err
+}
+
+// Server-side apply the rendered objects as dry runs, and report the fields of the templates that differ from the
+// objects of the cluster, or the objects that would be created
+func (r *DexServerReconciler) observeObjects(ctx context.Context, report *observeReport, objects []*unstructured.Unstructured) error {
+	for _, obj := range objects {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(obj.GroupVersionKind())
+		getErr := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+		if getErr != nil && !kubeerrors.IsNotFound(getErr) {
+			return getErr
+		}
+		desired := obj.DeepCopy()
+		if err := r.Patch(ctx, desired, client.Apply, client.FieldOwner(REPAIR_FIELD_MANAGER), client.ForceOwnership, client.DryRunAll); err != nil {
+			return err
+		}
+		if getErr != nil {
+			report.add(obj, "created")
+			continue
+		}
+		if changed := diff.Fields(existing.Object, desired.Object); len(changed) > 0 {
+			report.add(existing, diff.Summary(changed, MAX_EVENT_DIFF_FIELDS))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Render the dex config of an OpenID Connect connector, its client secret is read by dex from an environment variable
+func (r *DexServerReconciler) renderOIDCConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// Check if secret is in the dex server namespace and copy it into the dexserver ns
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	if secretNamespace := connector.OIDC.ClientSecretRef.Namespace; secretNamespace != dexServer.Namespace {
+		err := r.copySecretToDexServerNamespace(dexServer, connector.OIDC.ClientSecretRef, ctx)
+		if err != nil {
+			return DexConnectorSpec{}, err
+		}
+	}
+
+	// Environment variable that references the GitHub client secret copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple GitHub connectors
+	clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + getUniqueAlphanumericIdForConnector(connector)
+
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeOIDC),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			ClientID:     connector.OIDC.ClientID,
+			ClientSecret: clientSecretEnvVariable,
+			RedirectURI:  connector.OIDC.RedirectURI,
+			Issuer:       connector.OIDC.Issuer,
+			UserNameKey:  connector.OIDC.ClaimMapping.Name,
+			ClaimMapping: DexClaimMappingSpec{
+				PreferredUsernameKey: connector.OIDC.ClaimMapping.PreferredUsername,
+				EmailKey:             connector.OIDC.ClaimMapping.Email,
+				GroupsKey:            connector.OIDC.ClaimMapping.Groups,
+			},
+			Scopes:                    connector.OIDC.Scopes,
+			InsecureSkipEmailVerified: connector.OIDC.InsecureSkipEmailVerified,
+			InsecureEnableGroups:      connector.OIDC.InsecureEnableGroups,
+			ClaimModifications:        connector.OIDC.ClaimModifications,
+		},
+	}, nil
+}
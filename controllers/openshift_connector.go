@@ -0,0 +1,222 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// In-cluster address of the API server, dex discovers the OAuth server of the cluster from it
+	OPENSHIFT_DEFAULT_ISSUER = "https://kubernetes.default.svc"
+	// CA bundle of the service account of the dex pods. On OpenShift it holds the CA of the API server and of the
+	// default ingress certificate the OAuth server is served with.
+	OPENSHIFT_DEFAULT_ROOT_CA = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+var oauthClientGVR = schema.GroupVersionResource{Group: "oauth.openshift.io", Version: "v1", Resource: "oauthclients"}
+
+// Get the issuer of an OpenShift connector, the API server of the cluster dex runs on by default
+func getOpenShiftIssuer(connector authv1alpha1.ConnectorSpec) string {
+	if connector.OpenShift.Issuer != "" {
+		return connector.OpenShift.Issuer
+	}
+	return OPENSHIFT_DEFAULT_ISSUER
+}
+
+// Whether the operator creates the OAuthClient of an OpenShift connector
+func hasManagedOAuthClient(connector authv1alpha1.ConnectorSpec) bool {
+	return connector.Type == authv1alpha1.ConnectorTypeOpenShift && connector.OpenShift.ClientID == ""
+}
+
+// The OAuthClients are cluster scoped, their name includes the namespace of the DexServer
+func getOAuthClientName(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec) string {
+	return fmt.Sprintf("dex-%s-%s-%s", dexServer.Namespace, dexServer.Name, connector.Id)
+}
+
+// Get the client id of an OpenShift connector, the name of its OAuthClient
+func getOpenShiftClientID(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec) string {
+	if connector.OpenShift.ClientID != "" {
+		return connector.OpenShift.ClientID
+	}
+	return getOAuthClientName(dexServer, connector)
+}
+
+// Labels of the OAuthClients of the DexServer. The OAuthClients can't be owned by the DexServer, the labels tell
+// them apart from the OAuthClients created by someone else.
+func getOAuthClientLabels(dexServer *authv1alpha1.DexServer) map[string]string {
+	labels := getManagedLabels(dexServer, componentOAuthClient)
+	labels[DEXSERVER_NAMESPACE_LABEL] = dexServer.Namespace
+	return labels
+}
+
+// Get the OAuthClient of an OpenShift connector, with the secret of the connector and the callback of dex
+func newOAuthClient(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, secret string, issuer string) *unstructured.Unstructured {
+	redirectURI := connector.OpenShift.RedirectURI
+	if redirectURI == "" {
+		redirectURI = strings.TrimSuffix(issuer, "/") + DEX_CALLBACK_PATH
+	}
+	oauthClient := &unstructured.Unstructured{Object: map[string]interface{}{
+		"secret":       secret,
+		"redirectURIs": []interface{}{redirectURI},
+		// dex is trusted by the cluster, the users are not asked to grant it access to their account
+		"grantMethod": "auto",
+	}}
+	oauthClient.SetAPIVersion("oauth.openshift.io/v1")
+	oauthClient.SetKind("OAuthClient")
+	oauthClient.SetName(getOAuthClientName(dexServer, connector))
+	oauthClient.SetLabels(getOAuthClientLabels(dexServer))
+	return oauthClient
+}
+
+// Create or update the OAuthClients of the OpenShift connectors without a clientID, with the secret of their
+// clientSecretRef. The OAuthClients of the connectors that were removed or given a clientID are deleted. A connector
+// whose secret does not exist yet is skipped, it is reported by the WaitingForSecret condition.
+func (r *DexServerReconciler) syncOAuthClients(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	log.Info("syncOAuthClients")
+
+	keep := map[string]bool{}
+	for _, connector := range dexServer.Spec.Connectors {
+		if !hasManagedOAuthClient(connector) {
+			continue
+		}
+		if !r.OpenShift {
+			return fmt.Errorf("the OAuthClient of connector %s can only be created on OpenShift", connector.Id)
+		}
+		name := getOAuthClientName(dexServer, connector)
+		keep[name] = true
+
+		secretRef := connector.OpenShift.ClientSecretRef
+		if secretRef.Namespace == "" {
+			secretRef.Namespace = dexServer.Namespace
+		}
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secretRef.Name, Namespace: secretRef.Namespace}, secret); err != nil {
+			if kubeerrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		clientSecret := string(secret.Data[envVariableForConnector[connector.Type].SecretKey])
+		if clientSecret == "" {
+			return fmt.Errorf("secret %s/%s of connector %s has no clientSecret", secretRef.Namespace, secretRef.Name, connector.Id)
+		}
+		issuer, err := r.getIssuer(dexServer, ctx)
+		if err != nil {
+			return err
+		}
+		if err := r.applyOAuthClient(dexServer, ctx, newOAuthClient(dexServer, connector, clientSecret, issuer)); err != nil {
+			return err
+		}
+	}
+	return r.deleteOAuthClients(dexServer, ctx, keep)
+}
+
+func (r *DexServerReconciler) applyOAuthClient(dexServer *authv1alpha1.DexServer, ctx context.Context, oauthClient *unstructured.Unstructured) error {
+	log := ctrllog.FromContext(ctx)
+	name := oauthClient.GetName()
+	client := r.DynamicClient.Resource(oauthClientGVR)
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case kubeerrors.IsNotFound(err):
+		log.Info("Creating the OAuthClient", "OAuthClient.Name", name)
+		_, err = client.Create(ctx, oauthClient, metav1.CreateOptions{DryRun: r.dryRun})
+		return errors.Wrapf(err, "error creating OAuthClient %s", name)
+	case err != nil:
+		return errors.Wrapf(err, "error getting OAuthClient %s", name)
+	}
+	if existing.GetLabels()[DEXSERVER_NAMESPACE_LABEL] != dexServer.Namespace || existing.GetLabels()[INSTANCE_LABEL] != dexServer.Name {
+		return fmt.Errorf("OAuthClient %s already exists and is not managed by the DexServer", name)
+	}
+	for _, field := range []string{"secret", "redirectURIs", "grantMethod"} {
+		existing.Object[field] = oauthClient.Object[field]
+	}
+	existing.SetLabels(oauthClient.GetLabels())
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{DryRun: r.dryRun})
+	return errors.Wrapf(err, "error updating OAuthClient %s", name)
+}
+
+// Delete the OAuthClients of the DexServer except those to keep
+func (r *DexServerReconciler) deleteOAuthClients(dexServer *authv1alpha1.DexServer, ctx context.Context, keep map[string]bool) error {
+	if !r.OpenShift {
+		return nil
+	}
+	log := ctrllog.FromContext(ctx)
+	selector := labels.SelectorFromSet(labels.Set{
+		MANAGED_BY_LABEL:          MANAGED_BY_VALUE,
+		INSTANCE_LABEL:            dexServer.Name,
+		COMPONENT_LABEL:           componentOAuthClient,
+		DEXSERVER_NAMESPACE_LABEL: dexServer.Namespace,
+	})
+	oauthClients, err := r.DynamicClient.Resource(oauthClientGVR).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "error listing OAuthClients")
+	}
+	for _, oauthClient := range oauthClients.Items {
+		if keep[oauthClient.GetName()] {
+			continue
+		}
+		log.Info("Deleting an OAuthClient", "OAuthClient.Name", oauthClient.GetName())
+		err := r.DynamicClient.Resource(oauthClientGVR).Delete(ctx, oauthClient.GetName(), metav1.DeleteOptions{DryRun: r.dryRun})
+		if err != nil && !kubeerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error deleting OAuthClient %s", oauthClient.GetName())
+		}
+	}
+	return nil
+}
+
+// Render the dex config of an OpenShift connector, dex trusts the CA bundle of its service account without root CA
+func (r *DexServerReconciler) renderOpenShiftConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// The secret copied into the dexserver ns will be referenced by the env variable in the dexserver deployment
+	err := r.copySecretToDexServerNamespace(dexServer, connector.OpenShift.ClientSecretRef, ctx)
+	if err != nil {
+		return DexConnectorSpec{}, err
+	}
+
+	// Environment variable that references the OpenShift client secret copied into the dexserver ns
+	// The name includes the connector's alphanumeric unique Id as a suffix to distinguish between client secrets for multiple OpenShift connectors
+	clientSecretEnvVariable := "$" + envVariableForConnector[connector.Type].EnvVarName + "_" + getUniqueAlphanumericIdForConnector(connector)
+
+	// If there is a secret reference to the root CA, it is mounted in the dex pod, otherwise dex trusts the
+	// CA bundle of its service account
+	rootCAPath := OPENSHIFT_DEFAULT_ROOT_CA
+	if connector.OpenShift.RootCARef.Name != "" {
+		err := r.copySecretToDexServerNamespace(dexServer, connector.OpenShift.RootCARef, ctx)
+		if err != nil {
+			return DexConnectorSpec{}, err
+		}
+		rootCAPath = "/etc/dex/openshiftcerts/" + connector.Id + "/ca.crt"
+	}
+
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeOpenShift),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			ClientID:     getOpenShiftClientID(dexServer, connector),
+			ClientSecret: clientSecretEnvVariable,
+			RedirectURI:  connector.OpenShift.RedirectURI,
+			Issuer:       getOpenShiftIssuer(connector),
+			Groups:       connector.OpenShift.Groups,
+			RootCA:       rootCAPath,
+		},
+	}, nil
+}
@@ -0,0 +1,36 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Log in with the OAuth server of OpenShift", func() {
+	It("should create the OAuthClient of a connector without a clientID", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-dexserver", Namespace: "my-openshift-ns"},
+		}
+		connector := authv1alpha1.ConnectorSpec{Id: "openshift", Type: authv1alpha1.ConnectorTypeOpenShift}
+		Expect(hasManagedOAuthClient(connector)).To(BeTrue())
+		Expect(getOpenShiftIssuer(connector)).To(Equal(OPENSHIFT_DEFAULT_ISSUER))
+		Expect(getOpenShiftClientID(dexServer, connector)).To(Equal("dex-my-openshift-ns-my-dexserver-openshift"))
+
+		oauthClient := newOAuthClient(dexServer, connector, "my-secret", "https://openshift.testhost.com/dex")
+		Expect(oauthClient.GetName()).To(Equal("dex-my-openshift-ns-my-dexserver-openshift"))
+		Expect(oauthClient.GetLabels()).To(HaveKeyWithValue(DEXSERVER_NAMESPACE_LABEL, "my-openshift-ns"))
+		Expect(oauthClient.Object).To(HaveKeyWithValue("secret", "my-secret"))
+		Expect(oauthClient.Object).To(HaveKeyWithValue("redirectURIs", []interface{}{"https://openshift.testhost.com/dex/callback"}))
+		Expect(oauthClient.Object).To(HaveKeyWithValue("grantMethod", "auto"))
+
+		By("using the OAuthClient named by the connector", func() {
+			connector.OpenShift.ClientID = "my-dex"
+			Expect(hasManagedOAuthClient(connector)).To(BeFalse())
+			Expect(getOpenShiftClientID(dexServer, connector)).To(Equal("my-dex"))
+		})
+	})
+})
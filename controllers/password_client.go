@@ -0,0 +1,210 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// DexClient and Secret of the password grant, see spec.oauth2.passwordClient
+	PASSWORD_CLIENT_SUFFIX = "-password-client"
+
+	PASSWORD_CLIENT_ID_KEY             = "clientID"
+	PASSWORD_CLIENT_SECRET_KEY         = "clientSecret"
+	PASSWORD_CLIENT_ISSUER_KEY         = "issuer"
+	PASSWORD_CLIENT_TOKEN_ENDPOINT_KEY = "tokenEndpoint"
+	// Random bytes of a generated client secret
+	clientSecretLength = 32
+)
+
+// Get the client id of the password client
+func getPasswordClientID(dexServer *authv1alpha1.DexServer) string {
+	if clientID := dexServer.Spec.OAuth2.PasswordClient.ClientID; clientID != "" {
+		return clientID
+	}
+	return dexServer.Name + PASSWORD_CLIENT_SUFFIX
+}
+
+// Get the password connector of spec.oauth2, which must be an ldap connector
+func getPasswordConnector(dexServer *authv1alpha1.DexServer) (authv1alpha1.ConnectorSpec, error) {
+	id := dexServer.Spec.OAuth2.PasswordConnector
+	if id == "" {
+		return authv1alpha1.ConnectorSpec{}, fmt.Errorf("spec.oauth2.passwordClient requires spec.oauth2.passwordConnector")
+	}
+	for _, connector := range dexServer.Spec.Connectors {
+		if connector.Id != id {
+			continue
+		}
+		if connector.Type != authv1alpha1.ConnectorTypeLDAP {
+			return connector, fmt.Errorf("password connector %s of type %s can't serve the password grant", connector.Id, connector.Type)
+		}
+		return connector, nil
+	}
+	return authv1alpha1.ConnectorSpec{}, fmt.Errorf("password connector %q does not match the id of a connector", id)
+}
+
+func generateClientSecret() (string, error) {
+	b := make([]byte, clientSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create the DexClient of the password grant of spec.oauth2.passwordClient, and the Secret holding its generated
+// secret with the issuer and token endpoint, for CI systems logging in with a service account of the password
+// connector. Both are deleted when the password client is disabled.
+func (r *DexServerReconciler) syncPasswordClient(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	name := dexServer.Name + PASSWORD_CLIENT_SUFFIX
+	log.Info("syncPasswordClient", "DexClient.Name", name)
+
+	if !dexServer.Spec.OAuth2.PasswordClient.Enabled {
+		return r.deleteGeneratedClient(dexServer, ctx, name)
+	}
+	if _, err := getPasswordConnector(dexServer); err != nil {
+		return err
+	}
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	clientID := getPasswordClientID(dexServer)
+	data := map[string]string{
+		PASSWORD_CLIENT_ID_KEY:             clientID,
+		PASSWORD_CLIENT_ISSUER_KEY:         issuer,
+		PASSWORD_CLIENT_TOKEN_ENDPOINT_KEY: strings.TrimSuffix(issuer, "/") + "/token",
+	}
+	if _, err := r.applyGeneratedClientSecret(dexServer, ctx, name, componentPasswordClient, data, PASSWORD_CLIENT_SECRET_KEY); err != nil {
+		return err
+	}
+	return r.applyGeneratedDexClient(dexServer, ctx, name, componentPasswordClient, authv1alpha1.DexClientSpec{
+		ClientID:        clientID,
+		ClientSecretRef: corev1.SecretReference{Name: name, Namespace: dexServer.Namespace},
+	})
+}
+
+// Create or update the Secret of a client generated for the DexServer with the data, and with the generated keys set
+// to random secrets. The generated secrets are kept, they are rotated by deleting the Secret. Returns the data of the
+// Secret.
+func (r *DexServerReconciler) applyGeneratedClientSecret(dexServer *authv1alpha1.DexServer, ctx context.Context, name string, component string, data map[string]string, generatedKeys ...string) (map[string]string, error) {
+	log := ctrllog.FromContext(ctx)
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: dexServer.Namespace}, secret)
+	if err != nil && !kubeerrors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+	for _, key := range generatedKeys {
+		if value := string(secret.Data[key]); value != "" {
+			data[key] = value
+			continue
+		}
+		if data[key], err = generateClientSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !exists {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   dexServer.Namespace,
+				Labels:      map[string]string{"app": dexServer.Name},
+				Annotations: map[string]string{},
+			},
+			Type:       corev1.SecretTypeOpaque,
+			StringData: data,
+		}
+		r.addManagedMetadata(dexServer, component, secret.Labels, secret.Annotations)
+		if err := ctrl.SetControllerReference(dexServer, secret, r.Scheme); err != nil {
+			return nil, err
+		}
+		log.Info("Creating the client secret", "Secret.Name", name)
+		return data, r.Create(ctx, secret)
+	}
+	updated := false
+	for key, value := range data {
+		if string(secret.Data[key]) != value {
+			updated = true
+		}
+	}
+	if !updated {
+		return data, nil
+	}
+	secret.StringData = data
+	log.Info("Updating the client secret", "Secret.Name", name)
+	return data, r.Update(ctx, secret)
+}
+
+// Create or update a DexClient generated for the DexServer
+func (r *DexServerReconciler) applyGeneratedDexClient(dexServer *authv1alpha1.DexServer, ctx context.Context, name string, component string, spec authv1alpha1.DexClientSpec) error {
+	log := ctrllog.FromContext(ctx)
+	dexClient := &authv1alpha1.DexClient{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: dexServer.Namespace}, dexClient)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		dexClient = &authv1alpha1.DexClient{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   dexServer.Namespace,
+				Labels:      map[string]string{"app": dexServer.Name},
+				Annotations: map[string]string{},
+			},
+			Spec: spec,
+		}
+		r.addManagedMetadata(dexServer, component, dexClient.Labels, dexClient.Annotations)
+		if err := ctrl.SetControllerReference(dexServer, dexClient, r.Scheme); err != nil {
+			return err
+		}
+		log.Info("Creating the DexClient", "DexClient.Name", name)
+		return r.Create(ctx, dexClient)
+	case err != nil:
+		return err
+	}
+	if !metav1.IsControlledBy(dexClient, dexServer) {
+		return fmt.Errorf("DexClient %s already exists and is not managed by the DexServer", name)
+	}
+	if equality.Semantic.DeepEqual(dexClient.Spec, spec) {
+		return nil
+	}
+	dexClient.Spec = spec
+	log.Info("Updating the DexClient", "DexClient.Name", name)
+	return r.Update(ctx, dexClient)
+}
+
+// Delete the DexClient and Secret of a disabled generated client, unless they are not managed by the DexServer
+func (r *DexServerReconciler) deleteGeneratedClient(dexServer *authv1alpha1.DexServer, ctx context.Context, name string) error {
+	key := types.NamespacedName{Name: name, Namespace: dexServer.Namespace}
+	for _, obj := range []client.Object{&authv1alpha1.DexClient{}, &corev1.Secret{}} {
+		if err := r.Get(ctx, key, obj); err != nil {
+			if kubeerrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(obj, dexServer) {
+			continue
+		}
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
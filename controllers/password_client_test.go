@@ -0,0 +1,85 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Create the client of the password grant", func() {
+	It("should require an ldap password connector", func() {
+		dexServer := &authv1alpha1.DexServer{
+			Spec: authv1alpha1.DexServerSpec{
+				Connectors: []authv1alpha1.ConnectorSpec{
+					{Id: "my-github", Type: authv1alpha1.ConnectorTypeGitHub},
+					{Id: "my-ldap", Type: authv1alpha1.ConnectorTypeLDAP},
+				},
+			},
+		}
+		_, err := getPasswordConnector(dexServer)
+		Expect(err).ToNot(BeNil())
+
+		dexServer.Spec.OAuth2.PasswordConnector = "my-github"
+		_, err = getPasswordConnector(dexServer)
+		Expect(err).ToNot(BeNil())
+
+		dexServer.Spec.OAuth2.PasswordConnector = "my-ldap"
+		connector, err := getPasswordConnector(dexServer)
+		Expect(err).To(BeNil())
+		Expect(connector.Id).To(Equal("my-ldap"))
+	})
+	It("should create the DexClient and publish its secret", func() {
+		namespace := "my-password-client-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-password-dexserver", Namespace: namespace, UID: "my-password-uid"},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer:     "https://password.testhost.com",
+				Connectors: []authv1alpha1.ConnectorSpec{{Id: "my-ldap", Type: authv1alpha1.ConnectorTypeLDAP}},
+				OAuth2: authv1alpha1.OAuth2Spec{
+					PasswordConnector: "my-ldap",
+					PasswordClient:    authv1alpha1.PasswordClientSpec{Enabled: true},
+				},
+			},
+		}
+		key := client.ObjectKey{Name: dexServer.Name + PASSWORD_CLIENT_SUFFIX, Namespace: namespace}
+
+		Expect(rDexServer.syncPasswordClient(dexServer, context.TODO())).To(Succeed())
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(context.TODO(), key, secret)).To(Succeed())
+		Expect(metav1.IsControlledBy(secret, dexServer)).To(BeTrue())
+		Expect(string(secret.Data[PASSWORD_CLIENT_ID_KEY])).To(Equal("my-password-dexserver-password-client"))
+		Expect(string(secret.Data[PASSWORD_CLIENT_TOKEN_ENDPOINT_KEY])).To(Equal("https://password.testhost.com/token"))
+		clientSecret := string(secret.Data[PASSWORD_CLIENT_SECRET_KEY])
+		Expect(clientSecret).ToNot(BeEmpty())
+		dexClient := &authv1alpha1.DexClient{}
+		Expect(k8sClient.Get(context.TODO(), key, dexClient)).To(Succeed())
+		Expect(metav1.IsControlledBy(dexClient, dexServer)).To(BeTrue())
+		Expect(dexClient.Spec.ClientSecretRef).To(Equal(corev1.SecretReference{Name: key.Name, Namespace: namespace}))
+
+		By("keeping the secret when the client id changes", func() {
+			dexServer.Spec.OAuth2.PasswordClient.ClientID = "my-ci"
+			Expect(rDexServer.syncPasswordClient(dexServer, context.TODO())).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), key, secret)).To(Succeed())
+			Expect(string(secret.Data[PASSWORD_CLIENT_SECRET_KEY])).To(Equal(clientSecret))
+			Expect(string(secret.Data[PASSWORD_CLIENT_ID_KEY])).To(Equal("my-ci"))
+			Expect(k8sClient.Get(context.TODO(), key, dexClient)).To(Succeed())
+			Expect(dexClient.Spec.ClientID).To(Equal("my-ci"))
+		})
+		By("deleting the client once disabled", func() {
+			dexServer.Spec.OAuth2.PasswordClient.Enabled = false
+			Expect(rDexServer.syncPasswordClient(dexServer, context.TODO())).To(Succeed())
+			err := k8sClient.Get(context.TODO(), key, &corev1.Secret{})
+			Expect(kubeerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
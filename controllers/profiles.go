@@ -0,0 +1,82 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// dexServerProfile holds the settings of a spec.profile
+type dexServerProfile struct {
+	Replicas  int32
+	Resources corev1.ResourceRequirements
+	// Lifetime of the authentication requests, dex stores one for each login until it expires
+	AuthRequests *metav1.Duration
+}
+
+// The profiles leave the CPU unlimited, dex is mostly idle but for the bcrypt and signing bursts of the logins
+var dexServerProfiles = map[authv1alpha1.DexServerProfile]dexServerProfile{
+	authv1alpha1.DexServerProfileSmall: {
+		Replicas: 1,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+	},
+	authv1alpha1.DexServerProfileMedium: {
+		Replicas: 2,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		},
+		AuthRequests: &metav1.Duration{Duration: time.Hour},
+	},
+	authv1alpha1.DexServerProfileLarge: {
+		Replicas: 3,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+		AuthRequests: &metav1.Duration{Duration: 15 * time.Minute},
+	},
+}
+
+// Get the number of dex pods, spec.replicas or the replicas of spec.profile
+func getDexReplicas(dexServer *authv1alpha1.DexServer) int32 {
+	if isStorageMigrating(dexServer) {
+		return 0
+	}
+	if dexServer.Spec.Replicas != nil {
+		return *dexServer.Spec.Replicas
+	}
+	if profile, ok := dexServerProfiles[dexServer.Spec.Profile]; ok {
+		return profile.Replicas
+	}
+	return 1
+}
+
+// Get the resources of the dex container, spec.resources or the resources of spec.profile
+func getDexResources(dexServer *authv1alpha1.DexServer) corev1.ResourceRequirements {
+	if dexServer.Spec.Resources != nil {
+		return *dexServer.Spec.Resources.DeepCopy()
+	}
+	if profile, ok := dexServerProfiles[dexServer.Spec.Profile]; ok {
+		return *profile.Resources.DeepCopy()
+	}
+	return corev1.ResourceRequirements{}
+}
+
+// Get spec.expiry completed with the lifetimes of spec.profile
+func getDexServerExpiry(dexServer *authv1alpha1.DexServer) authv1alpha1.ExpirySpec {
+	expiry := *dexServer.Spec.Expiry.DeepCopy()
+	if profile, ok := dexServerProfiles[dexServer.Spec.Profile]; ok && expiry.AuthRequests == nil {
+		expiry.AuthRequests = profile.AuthRequests
+	}
+	return expiry
+}
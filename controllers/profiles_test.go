@@ -0,0 +1,54 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Size the dex server with a profile", func() {
+	It("should keep a single dex pod without resources by default", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		Expect(getDexReplicas(dexServer)).To(Equal(int32(1)))
+		Expect(getDexResources(dexServer)).To(Equal(corev1.ResourceRequirements{}))
+		Expect(getDexServerExpiry(dexServer).AuthRequests).To(BeNil())
+	})
+	It("should apply the settings of the profile", func() {
+		dexServer := &authv1alpha1.DexServer{Spec: authv1alpha1.DexServerSpec{Profile: authv1alpha1.DexServerProfileLarge}}
+		Expect(getDexReplicas(dexServer)).To(Equal(int32(3)))
+		resources := getDexResources(dexServer)
+		Expect(resources.Limits.Memory().String()).To(Equal("1Gi"))
+		Expect(getDexServerExpiry(dexServer).AuthRequests.Duration).To(Equal(15 * time.Minute))
+
+		config := loadDexConfig(&authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-profile-dexserver", Namespace: "my-config-ns"},
+			Spec:       authv1alpha1.DexServerSpec{Issuer: "https://profile.testhost.com", Profile: authv1alpha1.DexServerProfileLarge},
+		}, nil)
+		Expect(config.Expiry.AuthRequests).To(Equal("15m0s"))
+	})
+	It("should let the explicit fields take precedence over the profile", func() {
+		replicas := int32(5)
+		dexServer := &authv1alpha1.DexServer{Spec: authv1alpha1.DexServerSpec{
+			Profile:  authv1alpha1.DexServerProfileMedium,
+			Replicas: &replicas,
+			Resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+			Expiry: authv1alpha1.ExpirySpec{AuthRequests: &metav1.Duration{Duration: 5 * time.Minute}},
+		}}
+		Expect(getDexReplicas(dexServer)).To(Equal(int32(5)))
+		resources := getDexResources(dexServer)
+		Expect(resources.Limits).To(BeEmpty())
+		Expect(resources.Requests.Memory().String()).To(Equal("1Gi"))
+		Expect(getDexServerExpiry(dexServer).AuthRequests.Duration).To(Equal(5 * time.Minute))
+		Expect(dexServer.Spec.Expiry.AuthRequests.Duration).To(Equal(5 * time.Minute))
+	})
+})
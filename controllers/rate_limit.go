@@ -0,0 +1,53 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	HAPROXY_RATE_LIMIT_ANNOTATION                = "haproxy.router.openshift.io/rate-limit-connections"
+	HAPROXY_RATE_LIMIT_CONCURRENT_TCP_ANNOTATION = "haproxy.router.openshift.io/rate-limit-connections.concurrent-tcp"
+	HAPROXY_RATE_LIMIT_RATE_HTTP_ANNOTATION      = "haproxy.router.openshift.io/rate-limit-connections.rate-http"
+	NGINX_LIMIT_CONNECTIONS_ANNOTATION           = "nginx.ingress.kubernetes.io/limit-connections"
+	NGINX_LIMIT_RPS_ANNOTATION                   = "nginx.ingress.kubernetes.io/limit-rps"
+)
+
+// The OpenShift router counts the HTTP requests of a client IP address over this period
+const HAPROXY_RATE_LIMIT_PERIOD_SECONDS int32 = 3
+
+// Get the annotations of the Ingress, or the wildcard Route, limiting the connections and requests of each client IP
+// address, see spec.route.rateLimit. The Routes generated from the Ingress inherit its annotations.
+func getRateLimitAnnotations(dexServer *authv1alpha1.DexServer, openShift bool) (map[string]string, error) {
+	rateLimit := dexServer.Spec.Route.RateLimit
+	if rateLimit.ConcurrentConnections == nil && rateLimit.RequestsPerSecond == nil {
+		return nil, nil
+	}
+	annotations := map[string]string{}
+	if openShift {
+		annotations[HAPROXY_RATE_LIMIT_ANNOTATION] = "true"
+		if rateLimit.ConcurrentConnections != nil {
+			annotations[HAPROXY_RATE_LIMIT_CONCURRENT_TCP_ANNOTATION] = strconv.Itoa(int(*rateLimit.ConcurrentConnections))
+		}
+		if rateLimit.RequestsPerSecond != nil {
+			annotations[HAPROXY_RATE_LIMIT_RATE_HTTP_ANNOTATION] = strconv.Itoa(int(*rateLimit.RequestsPerSecond * HAPROXY_RATE_LIMIT_PERIOD_SECONDS))
+		}
+	} else {
+		if rateLimit.ConcurrentConnections != nil {
+			annotations[NGINX_LIMIT_CONNECTIONS_ANNOTATION] = strconv.Itoa(int(*rateLimit.ConcurrentConnections))
+		}
+		if rateLimit.RequestsPerSecond != nil {
+			annotations[NGINX_LIMIT_RPS_ANNOTATION] = strconv.Itoa(int(*rateLimit.RequestsPerSecond))
+		}
+	}
+	for key := range annotations {
+		if _, ok := dexServer.Spec.Ingress.Annotations[key]; ok {
+			return nil, fmt.Errorf("annotation %s of spec.ingress.annotations is set by the operator from spec.route.rateLimit", key)
+		}
+	}
+	return annotations, nil
+}
@@ -0,0 +1,38 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+var _ = Describe("Rate limit the clients of dex", func() {
+	It("should annotate the route of the OpenShift router", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		Expect(getRateLimitAnnotations(dexServer, true)).To(BeEmpty())
+
+		concurrent, rps := int32(20), int32(10)
+		dexServer.Spec.Route.RateLimit = authv1alpha1.RouteRateLimitSpec{ConcurrentConnections: &concurrent, RequestsPerSecond: &rps}
+		annotations, err := getRateLimitAnnotations(dexServer, true)
+		Expect(err).To(BeNil())
+		Expect(annotations).To(HaveKeyWithValue(HAPROXY_RATE_LIMIT_ANNOTATION, "true"))
+		Expect(annotations).To(HaveKeyWithValue(HAPROXY_RATE_LIMIT_CONCURRENT_TCP_ANNOTATION, "20"))
+		Expect(annotations).To(HaveKeyWithValue(HAPROXY_RATE_LIMIT_RATE_HTTP_ANNOTATION, "30"))
+
+		By("annotating the Ingress of ingress-nginx", func() {
+			annotations, err := getRateLimitAnnotations(dexServer, false)
+			Expect(err).To(BeNil())
+			Expect(annotations).To(HaveKeyWithValue(NGINX_LIMIT_CONNECTIONS_ANNOTATION, "20"))
+			Expect(annotations).To(HaveKeyWithValue(NGINX_LIMIT_RPS_ANNOTATION, "10"))
+			Expect(annotations).ToNot(HaveKey(HAPROXY_RATE_LIMIT_ANNOTATION))
+		})
+		By("refusing the same annotations in spec.ingress.annotations", func() {
+			dexServer.Spec.Ingress.Annotations = map[string]string{NGINX_LIMIT_RPS_ANNOTATION: "100"}
+			_, err := getRateLimitAnnotations(dexServer, false)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,97 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Name of the environment variable dex reads the secret of a placeholder of a raw connector from. The id of the
+// connector in hex, without underscore, keeps the names unique among the connectors.
+func getRawConnectorEnvVarName(connector authv1alpha1.ConnectorSpec, placeholder string) string {
+	return "RAW_" + placeholder + "_" + getUniqueAlphanumericIdForConnector(connector)
+}
+
+// Get the config of a raw connector as JSON, with its placeholders replaced by the environment variables dex
+// expands. The braces keep the name of the variable apart from the text around the placeholder. An empty config is
+// rendered as an empty object.
+func getRawConnectorConfig(connector authv1alpha1.ConnectorSpec) (json.RawMessage, error) {
+	config := authv1alpha1.RawConfigPlaceholder.ReplaceAllStringFunc(connector.Raw.Config, func(placeholder string) string {
+		name := authv1alpha1.RawConfigPlaceholder.FindStringSubmatch(placeholder)[1]
+		return "${" + getRawConnectorEnvVarName(connector, name) + "}"
+	})
+	if strings.TrimSpace(config) == "" {
+		return json.RawMessage("{}"), nil
+	}
+	rawConfig, err := yaml.YAMLToJSON([]byte(config))
+	if err != nil {
+		return nil, err
+	}
+	return rawConfig, nil
+}
+
+// Get the reference to the secret of a placeholder of a raw connector. Secrets without namespace are in the DexServer
+// namespace, like the secrets of the other connectors.
+func getRawConnectorSecretRef(dexServer *authv1alpha1.DexServer, secret authv1alpha1.RawConnectorSecretSpec) corev1.SecretReference {
+	ref := secret.SecretRef
+	if ref.Namespace == "" {
+		ref.Namespace = dexServer.Namespace
+	}
+	return ref
+}
+
+// Get the environment variables of the placeholders of a raw connector, referencing the secrets copied into the
+// DexServer namespace. The secrets are copied when the config is rendered, a missing copy fails the deployment rather
+// than leaving the placeholder empty in the config of dex.
+func (r *DexServerReconciler) getRawConnectorEnvVariables(ctx context.Context, dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec) ([]corev1.EnvVar, error) {
+	envVariables := []corev1.EnvVar{}
+	for _, secret := range connector.Raw.Secrets {
+		ref := getRawConnectorSecretRef(dexServer, secret)
+		// To ensure uniqueness of names for secrets copied into the dex server namespace, the secret name is prefixed with the original namespace
+		secretName := ref.Namespace + "-" + ref.Name
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: secretName, Namespace: dexServer.Namespace}, &corev1.Secret{}); err != nil {
+			return nil, errors.Wrapf(err, "error getting the copy of secret %s/%s of placeholder %s", ref.Namespace, ref.Name, secret.Placeholder)
+		}
+		envVariables = append(envVariables, corev1.EnvVar{
+			Name: getRawConnectorEnvVarName(connector, secret.Placeholder),
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  secret.Key,
+				},
+			},
+		})
+	}
+	return envVariables, nil
+}
+
+// Render a raw connector with its config verbatim, after copying the secrets of its placeholders
+func (r *DexServerReconciler) renderRawConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// The secrets copied into the dexserver ns will be referenced by the env variables in the dexserver deployment
+	for _, secret := range connector.Raw.Secrets {
+		if err := r.copySecretToDexServerNamespace(dexServer, getRawConnectorSecretRef(dexServer, secret), ctx); err != nil {
+			return DexConnectorSpec{}, err
+		}
+	}
+
+	// The config is validated by getRenderedConnectors
+	rawConfig, err := getRawConnectorConfig(connector)
+	if err != nil {
+		return DexConnectorSpec{}, err
+	}
+	return DexConnectorSpec{
+		Type:      connector.Raw.ConnectorType,
+		Id:        connector.Id,
+		Name:      getConnectorDisplayName(connector),
+		RawConfig: rawConfig,
+	}, nil
+}
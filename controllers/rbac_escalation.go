@@ -0,0 +1,132 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/failures"
+)
+
+const (
+	// Requeue interval of a DexServer while the operator can't grant the RBAC permissions of dex, the permissions
+	// granted to the operator don't trigger a reconcile
+	RBAC_ESCALATION_REQUEUE_INTERVAL = 5 * time.Minute
+)
+
+var (
+	// Message of the forbidden errors returned by the API server when the operator creates or binds a role with
+	// permissions it does not hold itself. The appliers format the API errors, so the errors are matched on their
+	// message rather than on their status.
+	rbacEscalationMessage = "attempting to grant RBAC permissions not currently held"
+	// A permission listed in the forbidden error, e.g. {APIGroups:["dex.coreos.com"], Resources:["*"], Verbs:["*"]}
+	rbacMissingRule = regexp.MustCompile(`\{APIGroups:[^}]*\}`)
+)
+
+// rbacEscalationError is returned when the API server refuses the ClusterRole of dex or its ClusterRoleBinding
+// because the operator misses the escalate or bind verb, as in the restricted installs
+type rbacEscalationError struct {
+	// Kind and name of the refused object
+	Kind string
+	Name string
+	// ClusterRole granted by the refused object
+	ClusterRole string
+	// Permissions of the ClusterRole the operator does not hold
+	Missing []string
+	Err     error
+}
+
+func (e *rbacEscalationError) Error() string {
+	return fmt.Sprintf("the operator is not allowed to create %s %s granting %s. %s",
+		e.Kind, e.Name, strings.Join(e.Missing, ", "), e.remediation())
+}
+
+func (e *rbacEscalationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *rbacEscalationError) FailureClass() failures.Class {
+	return failures.RBACEscalationDenied
+}
+
+// Hint of the permissions to grant to the operator
+func (e *rbacEscalationError) remediation() string {
+	if e.Kind == "ClusterRoleBinding" {
+		return fmt.Sprintf("Grant the operator the bind verb on the ClusterRole %s, or the missing permissions, "+
+			"or create the ClusterRoleBindings and run the operator with --pre-provisioned-rbac", e.ClusterRole)
+	}
+	return "Grant the operator the escalate verb on clusterroles, or the missing permissions, " +
+		"or create the ClusterRole and run the operator with --pre-provisioned-rbac"
+}
+
+// Get the escalation error of a forbidden ClusterRole or ClusterRoleBinding, nil for the other errors
+func getRBACEscalationError(kind, name, clusterRole string, err error) *rbacEscalationError {
+	if err == nil || !strings.Contains(err.Error(), rbacEscalationMessage) {
+		return nil
+	}
+	missing := rbacMissingRule.FindAllString(err.Error(), -1)
+	if len(missing) == 0 {
+		missing = []string{"permissions not held by the operator"}
+	}
+	return &rbacEscalationError{Kind: kind, Name: name, ClusterRole: clusterRole, Missing: missing, Err: err}
+}
+
+// Install the ClusterRole of dex unless it is already installed: it is installed by SetupWithManager, and by the
+// reconciles when the operator was not allowed to install it yet
+func (r *DexServerReconciler) ensureClusterRole(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	if r.clusterRoleInstalled || r.Observe {
+		return nil
+	}
+	log := ctrllog.FromContext(ctx)
+	log.Info("ensureClusterRole", "ClusterRole.Name", r.getClusterRoleName())
+	if err := r.installClusterRole(); err != nil {
+		clusterRoleName := r.getClusterRoleName()
+		if escalation := getRBACEscalationError("ClusterRole", clusterRoleName, clusterRoleName, err); escalation != nil {
+			return escalation
+		}
+		return err
+	}
+	r.clusterRoleInstalled = true
+	return nil
+}
+
+// Create the ClusterRole of dex and bind it to the service account of the dex server
+func (r *DexServerReconciler) syncRBAC(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	if err := r.ensureClusterRole(dexServer, ctx); err != nil {
+		return err
+	}
+	if err := r.syncClusterRoleBinding(dexServer, ctx); err != nil {
+		clusterRoleBindingName := SERVICE_ACCOUNT_NAME + "-" + dexServer.Namespace
+		if escalation := getRBACEscalationError("ClusterRoleBinding", clusterRoleBindingName, r.getClusterRoleName(), err); escalation != nil {
+			return escalation
+		}
+		return err
+	}
+	return nil
+}
+
+// Report the RBAC permissions the operator could not grant to dex in the RBACEscalationDenied condition
+func getRBACEscalationCondition(escalation *rbacEscalationError) metav1.Condition {
+	if escalation == nil {
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeRBACEscalationDenied,
+			Status:  metav1.ConditionFalse,
+			Reason:  "PermissionsGranted",
+			Message: "the RBAC permissions of dex are granted",
+		}
+	}
+	return metav1.Condition{
+		Type:    authv1alpha1.DexServerConditionTypeRBACEscalationDenied,
+		Status:  metav1.ConditionTrue,
+		Reason:  string(failures.RBACEscalationDenied),
+		Message: escalation.Error(),
+	}
+}
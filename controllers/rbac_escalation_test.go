@@ -0,0 +1,42 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/identitatem/dex-operator/controllers/failures"
+)
+
+var _ = Describe("Report the RBAC escalation failures", func() {
+	forbidden := fmt.Errorf(`"dex-server/cluster_role_binding.yaml" (string): clusterrolebindings.rbac.authorization.k8s.io "dex-operator-dexsso-my-ns" is forbidden: ` +
+		`user "system:serviceaccount:dex-operator:dex-operator" (groups=["system:serviceaccounts"]) is attempting to grant RBAC permissions not currently held:` + "\n" +
+		`{APIGroups:["dex.coreos.com"], Resources:["*"], Verbs:["*"]}` + "\n" +
+		`{APIGroups:["authorization.k8s.io"], Resources:["subjectaccessreviews"], Verbs:["create"]}`)
+
+	It("should name the missing permissions and the remediation", func() {
+		escalation := getRBACEscalationError("ClusterRoleBinding", "dex-operator-dexsso-my-ns", "dex-operator-dexsso", forbidden)
+		Expect(escalation).NotTo(BeNil())
+		Expect(escalation.Missing).To(Equal([]string{
+			`{APIGroups:["dex.coreos.com"], Resources:["*"], Verbs:["*"]}`,
+			`{APIGroups:["authorization.k8s.io"], Resources:["subjectaccessreviews"], Verbs:["create"]}`,
+		}))
+		Expect(escalation.Error()).To(ContainSubstring("bind verb on the ClusterRole dex-operator-dexsso"))
+		Expect(failures.Reason(escalation, "ConfigClusterRoleBindingFailed")).To(Equal(string(failures.RBACEscalationDenied)))
+
+		cond := getRBACEscalationCondition(escalation)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Message).To(ContainSubstring(`Resources:["subjectaccessreviews"]`))
+		Expect(getRBACEscalationCondition(nil).Status).To(Equal(metav1.ConditionFalse))
+	})
+	It("should not take the other errors for escalation failures", func() {
+		Expect(getRBACEscalationError("ClusterRole", "dex-operator-dexsso", "dex-operator-dexsso", nil)).To(BeNil())
+		notFound := fmt.Errorf(`clusterroles.rbac.authorization.k8s.io "dex-operator-dexsso" not found`)
+		Expect(getRBACEscalationError("ClusterRole", "dex-operator-dexsso", "dex-operator-dexsso", notFound)).To(BeNil())
+	})
+})
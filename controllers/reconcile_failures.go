@@ -0,0 +1,29 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/failures"
+)
+
+// reconcileFailures counts the failed reconciles of each DexServer by the reason of their Applied condition
+var reconcileFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "dex_operator_reconcile_failures_total",
+	Help: "Number of failed reconciles of the DexServer, by the reason set in its Applied condition",
+}, []string{"namespace", "name", "reason"})
+
+func init() {
+	metrics.Registry.MustRegister(reconcileFailures)
+}
+
+// Count a failed reconcile phase and return the reason of the Applied condition: the class of the error when it
+// is classified, see the failures package, or the reason of the phase
+func recordFailure(dexServer *authv1alpha1.DexServer, err error, reason string) string {
+	reason = failures.Reason(err, reason)
+	reconcileFailures.WithLabelValues(dexServer.Namespace, dexServer.Name, reason).Inc()
+	return reason
+}
@@ -0,0 +1,37 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/failures"
+	"github.com/pkg/errors"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("Classify the reconcile failures", func() {
+	dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-failing-dexserver", Namespace: "my-failing-ns"}}
+
+	It("should set the reason of the phase to the unclassified failures", func() {
+		Expect(recordFailure(dexServer, fmt.Errorf("connection refused"), "ConfigMapFailed")).To(Equal("ConfigMapFailed"))
+	})
+	It("should set the class of the classified failures", func() {
+		missing := &missingSecretError{Namespace: "my-ns", Name: "my-secret", ConnectorId: "my-ldap"}
+		Expect(recordFailure(dexServer, errors.Wrap(missing, "failed to copy"), "ConfigMapFailed")).To(Equal(string(failures.SecretMissing)))
+		notFound := kubeerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "my-secret")
+		Expect(recordFailure(dexServer, errors.Wrap(notFound, "error getting secret"), "ConfigMapFailed")).To(Equal(string(failures.SecretMissing)))
+		rendering := failures.Wrap(failures.RenderFailure, fmt.Errorf("template: config_map.yaml: unexpected EOF"))
+		Expect(recordFailure(dexServer, rendering, "ConfigMapFailed")).To(Equal(string(failures.RenderFailure)))
+	})
+	It("should only classify the secrets that are not found", func() {
+		notFound := kubeerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "my-configmap")
+		Expect(recordFailure(dexServer, notFound, "ConfigIngressFailed")).To(Equal("ConfigIngressFailed"))
+	})
+})
@@ -0,0 +1,97 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Rendering of the templates and the dex configuration, along with the mutators
+	reconcilePhaseRender = "render"
+	// Generation and renewal of the certificates and keys
+	reconcilePhaseCert = "cert"
+	// Writes of the rendered objects and comparison with the existing objects
+	reconcilePhaseApply = "apply"
+)
+
+// Phases of tracePhase timed as certificate phases, the other phases are timed by applyWithDiff
+var certPhases = map[string]bool{
+	"manageMTLSSecret":      true,
+	"syncServingCertSecret": true,
+	"importSigningKeys":     true,
+}
+
+// Buckets from 5ms to about 40s
+var reconcileDurationBuckets = prometheus.ExponentialBuckets(0.005, 2, 14)
+
+// reconcileDuration observes the duration of the reconciles of each DexServer
+var reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "dex_operator_reconcile_duration_seconds",
+	Help:    "Duration of the reconciles of the DexServer",
+	Buckets: reconcileDurationBuckets,
+}, []string{"namespace", "name"})
+
+// reconcilePhaseDuration observes the time each reconcile of a DexServer spent in the render, cert and apply phases
+var reconcilePhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "dex_operator_reconcile_phase_duration_seconds",
+	Help:    "Time spent by the reconciles of the DexServer in the render, cert and apply phases",
+	Buckets: reconcileDurationBuckets,
+}, []string{"namespace", "name", "phase"})
+
+func init() {
+	metrics.Registry.MustRegister(reconcileDuration, reconcilePhaseDuration)
+}
+
+type reconcileTimerKey struct{}
+
+// reconcileTimer sums the time a reconcile spends in each phase, as the phases run several times in a reconcile
+type reconcileTimer struct {
+	start  time.Time
+	phases map[string]time.Duration
+}
+
+// withReconcileTimer returns a context in which the phases of the reconcile are timed
+func withReconcileTimer(ctx context.Context) (context.Context, *reconcileTimer) {
+	timer := &reconcileTimer{
+		start: time.Now(),
+		phases: map[string]time.Duration{
+			reconcilePhaseRender: 0,
+			reconcilePhaseCert:   0,
+			reconcilePhaseApply:  0,
+		},
+	}
+	return context.WithValue(ctx, reconcileTimerKey{}, timer), timer
+}
+
+// Add the time elapsed since start to the phase of the reconcile timed in the context, if any
+func timePhase(ctx context.Context, phase string, start time.Time) {
+	if timer, ok := ctx.Value(reconcileTimerKey{}).(*reconcileTimer); ok {
+		timer.phases[phase] += time.Since(start)
+	}
+}
+
+// Observe the durations of the reconcile, the metrics of a deleted DexServer are not observed again
+func (timer *reconcileTimer) observe(dexServer *authv1alpha1.DexServer) {
+	if dexServer.DeletionTimestamp != nil {
+		return
+	}
+	reconcileDuration.WithLabelValues(dexServer.Namespace, dexServer.Name).Observe(time.Since(timer.start).Seconds())
+	for phase, duration := range timer.phases {
+		reconcilePhaseDuration.WithLabelValues(dexServer.Namespace, dexServer.Name, phase).Observe(duration.Seconds())
+	}
+}
+
+// Delete the duration metrics of a deleted DexServer
+func deleteReconcileDurationMetrics(dexServer *authv1alpha1.DexServer) {
+	reconcileDuration.DeleteLabelValues(dexServer.Namespace, dexServer.Name)
+	for _, phase := range []string{reconcilePhaseRender, reconcilePhaseCert, reconcilePhaseApply} {
+		reconcilePhaseDuration.DeleteLabelValues(dexServer.Namespace, dexServer.Name, phase)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Time the reconciles", func() {
+	dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-timed-dexserver", Namespace: "my-timed-ns"}}
+
+	It("should observe the duration of each phase of a reconcile", func() {
+		reconciles, phases := testutil.CollectAndCount(reconcileDuration), testutil.CollectAndCount(reconcilePhaseDuration)
+		ctx, timer := withReconcileTimer(context.TODO())
+		start := time.Now().Add(-time.Second)
+		timePhase(ctx, reconcilePhaseRender, start)
+		timePhase(ctx, reconcilePhaseRender, start)
+		Expect(timer.phases[reconcilePhaseRender]).To(BeNumerically(">=", 2*time.Second))
+		Expect(timer.phases[reconcilePhaseCert]).To(BeZero())
+
+		timer.observe(dexServer)
+		Expect(testutil.CollectAndCount(reconcileDuration)).To(Equal(reconciles + 1))
+		Expect(testutil.CollectAndCount(reconcilePhaseDuration)).To(Equal(phases + 3))
+		By("deleting the metrics of a deleted DexServer", func() {
+			deleteReconcileDurationMetrics(dexServer)
+			Expect(testutil.CollectAndCount(reconcileDuration)).To(Equal(reconciles))
+			Expect(testutil.CollectAndCount(reconcilePhaseDuration)).To(Equal(phases))
+		})
+	})
+	It("should not time the phases out of a reconcile", func() {
+		Expect(func() { timePhase(context.TODO(), reconcilePhaseApply, time.Now()) }).NotTo(Panic())
+	})
+})
@@ -0,0 +1,169 @@
+// Copyright Red Hat
+
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const Redacted = "<redacted>"
+
+var (
+	// PEM blocks, along with the blocks truncated by the messages they are embedded in
+	pemBlock = regexp.MustCompile(`-----BEGIN ([A-Z0-9 ]+)-----[\s\S]*?(-----END [A-Z0-9 ]+-----|$)`)
+	// Values assigned to the secret fields of the dex configuration, in YAML, JSON or flags
+	secretField = regexp.MustCompile(`(?i)(\b(?:client_?secret|bind_?pw|password|passwd|token|private_?key)"?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,;}\]]+)`)
+
+	patternsMu sync.RWMutex
+	patterns   []*regexp.Regexp
+)
+
+// Keys of the logged values that hold secret material, compared in lower case without separators
+var secretKeySuffixes = []string{"password", "passwd", "bindpw", "token", "clientsecret", "privatekey", "pem", "configyaml"}
+var secretKeys = map[string]bool{"secret": true, "key": true, "data": true, "stringdata": true}
+
+// AddPattern redacts the matches of pattern from the logs, along with the PEM blocks and the secret fields of the
+// dex configuration. Patterns must be added before the manager is started.
+func AddPattern(pattern *regexp.Regexp) {
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+	patterns = append(patterns, pattern)
+}
+
+// String redacts the PEM blocks, the values of the secret fields and the matches of the added patterns from s
+func String(s string) string {
+	s = pemBlock.ReplaceAllString(s, "<redacted $1>")
+	s = secretField.ReplaceAllString(s, "${1}"+Redacted)
+	patternsMu.RLock()
+	defer patternsMu.RUnlock()
+	for _, p := range patterns {
+		s = p.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// Error redacts the message of err, the redacted error unwraps to err so that it can still be classified
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err: err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return String(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Format redacts the verbose form of the errors, which carries the stack traces of github.com/pkg/errors
+func (e *redactedError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprint(s, String(fmt.Sprintf("%+v", e.err)))
+		return
+	}
+	fmt.Fprint(s, e.Error())
+}
+
+func isSecretKey(key interface{}) bool {
+	k, ok := key.(string)
+	if !ok {
+		return false
+	}
+	k = strings.ToLower(strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(k))
+	if secretKeys[k] {
+		return true
+	}
+	for _, suffix := range secretKeySuffixes {
+		if strings.HasSuffix(k, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value redacts a logged value. The bytes and the data of the Secrets are never logged, as they can't be told
+// apart from the keys and certificates.
+func Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return String(v)
+	case []byte:
+		return fmt.Sprintf("<redacted %d bytes>", len(v))
+	case map[string][]byte:
+		return fmt.Sprintf("<redacted %d keys>", len(v))
+	case error:
+		return Error(v)
+	case *corev1.Secret:
+		if v == nil {
+			return v
+		}
+		return fmt.Sprintf("Secret %s/%s", v.Namespace, v.Name)
+	case corev1.Secret:
+		return fmt.Sprintf("Secret %s/%s", v.Namespace, v.Name)
+	default:
+		return value
+	}
+}
+
+// Values redacts the values of keysAndValues, the values of the secret keys are replaced as a whole
+func Values(keysAndValues []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	for i := range keysAndValues {
+		switch {
+		case i%2 == 0:
+			redacted[i] = keysAndValues[i]
+		case isSecretKey(keysAndValues[i-1]):
+			redacted[i] = Redacted
+		default:
+			redacted[i] = Value(keysAndValues[i])
+		}
+	}
+	return redacted
+}
+
+// NewLogger wraps delegate, redacting the messages, errors and values it logs at every verbosity, so that the
+// rendered configurations, Secrets and PEM contents never reach the logs of the operator
+func NewLogger(delegate logr.Logger) logr.Logger {
+	return &logger{delegate: logr.WithCallDepth(delegate, 1)}
+}
+
+type logger struct {
+	delegate logr.Logger
+}
+
+func (l *logger) Enabled() bool {
+	return l.delegate.Enabled()
+}
+
+func (l *logger) Info(msg string, keysAndValues ...interface{}) {
+	l.delegate.Info(String(msg), Values(keysAndValues)...)
+}
+
+func (l *logger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.delegate.Error(Error(err), String(msg), Values(keysAndValues)...)
+}
+
+func (l *logger) V(level int) logr.Logger {
+	return &logger{delegate: l.delegate.V(level)}
+}
+
+func (l *logger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &logger{delegate: l.delegate.WithValues(Values(keysAndValues)...)}
+}
+
+func (l *logger) WithName(name string) logr.Logger {
+	return &logger{delegate: l.delegate.WithName(name)}
+}
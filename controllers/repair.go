@@ -0,0 +1,82 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Annotation requesting the operator to reassert every object rendered for a DexServer, for example after
+	// manual edits or a partial restore. It is removed once the objects are repaired.
+	REPAIR_ANNOTATION = "auth.identitatem.io/repair"
+	// Field manager of the server-side applies of a repair
+	REPAIR_FIELD_MANAGER = "dex-operator"
+)
+
+type repairReportKey struct{}
+
+// repairReport collects the objects changed by the server-side applies of a repair
+type repairReport struct {
+	applied  int
+	repaired []string
+}
+
+func isRepairRequested(dexServer *authv1alpha1.DexServer) bool {
+	_, ok := dexServer.Annotations[REPAIR_ANNOTATION]
+	return ok
+}
+
+// withRepairReport returns a context in which applyWithDiff repairs the objects it applies
+func withRepairReport(ctx context.Context) (context.Context, *repairReport) {
+	report := &repairReport{}
+	return context.WithValue(ctx, repairReportKey{}, report), report
+}
+
+func getRepairReport(ctx context.Context) *repairReport {
+	report, _ := ctx.Value(repairReportKey{}).(*repairReport)
+	return report
+}
+
+func (report *repairReport) add(obj *unstructured.Unstructured, change string) {
+	report.repaired = append(report.repaired, fmt.Sprintf("%s %s (%s)", obj.GetKind(), obj.GetName(), change))
+}
+
+// Server-side apply the rendered objects, taking over the fields set by other field managers. Unlike the applier,
+// which only updates the fields it compares, this reasserts every field of the templates.
+func (r *DexServerReconciler) forceApply(ctx context.Context, objects []*unstructured.Unstructured) error {
+	report := getRepairReport(ctx)
+	for _, obj := range objects {
+		if err := r.Patch(ctx, obj, client.Apply, client.FieldOwner(REPAIR_FIELD_MANAGER), client.ForceOwnership); err != nil {
+			return err
+		}
+		report.applied++
+	}
+	return nil
+}
+
+// Record the outcome of a repair in an Event and remove the repair annotation
+func (r *DexServerReconciler) completeRepair(dexServer *authv1alpha1.DexServer, ctx context.Context, report *repairReport) error {
+	log := ctrllog.FromContext(ctx)
+	message := fmt.Sprintf("Reapplied %d objects, none had drifted", report.applied)
+	if len(report.repaired) > 0 {
+		message = fmt.Sprintf("Reapplied %d objects, repaired %d: %s", report.applied, len(report.repaired), strings.Join(report.repaired, ", "))
+	}
+	log.Info("repaired DexServer", "Objects", report.applied, "Repaired", report.repaired)
+	if r.Recorder != nil {
+		r.Recorder.Event(dexServer, corev1.EventTypeNormal, "Repaired", message)
+	}
+
+	patch := client.MergeFrom(dexServer.DeepCopy())
+	delete(dexServer.Annotations, REPAIR_ANNOTATION)
+	return r.Patch(ctx, dexServer, patch)
+}
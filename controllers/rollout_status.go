@@ -0,0 +1,47 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Annotation of the pod template of dex holding the hash of the dex ConfigMap
+	CONFIG_HASH_ANNOTATION = "auth.identitatem.io/configHash"
+)
+
+// Report the image and configuration hash of the dex pods serving the logins in status.deployedImage and
+// status.configHash once the rollout of the Deployment is complete, so that GitOps tools can tell which
+// configuration is live. status.lastRolloutTime is set when either changes.
+func (r *DexServerReconciler) reportRollout(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKey{Name: dexServer.Name, Namespace: dexServer.Namespace}, deployment); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !isRolloutComplete(deployment) {
+		// the previous image and configuration may still be serving the logins
+		return nil
+	}
+	var image string
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == dexServer.Name {
+			image = container.Image
+		}
+	}
+	configHash := deployment.Spec.Template.Annotations[CONFIG_HASH_ANNOTATION]
+	if image == dexServer.Status.DeployedImage && configHash == dexServer.Status.ConfigHash {
+		return nil
+	}
+	dexServer.Status.DeployedImage = image
+	dexServer.Status.ConfigHash = configHash
+	now := metav1.Now()
+	dexServer.Status.LastRolloutTime = &now
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Report the rollout of dex", func() {
+	It("should report the image and configuration once the rollout is complete", func() {
+		namespace := "my-rollout-ns"
+		Expect(k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		dexServer := &authv1alpha1.DexServer{ObjectMeta: metav1.ObjectMeta{Name: "my-rollout-dexserver", Namespace: namespace}}
+		labels := map[string]string{"app": dexServer.Name}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: dexServer.Name, Namespace: namespace},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      labels,
+						Annotations: map[string]string{CONFIG_HASH_ANNOTATION: "my-config-hash"},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: dexServer.Name, Image: "ghcr.io/dexidp/dex:v2.30.2"}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), deployment)).To(Succeed())
+
+		By("waiting for the rollout", func() {
+			Expect(rDexServer.reportRollout(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.DeployedImage).To(BeEmpty())
+			Expect(dexServer.Status.LastRolloutTime).To(BeNil())
+		})
+		deployment.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: deployment.Generation,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		}
+		Expect(k8sClient.Status().Update(context.TODO(), deployment)).To(Succeed())
+		Eventually(func() string {
+			Expect(rDexServer.reportRollout(dexServer, context.TODO())).To(Succeed())
+			return dexServer.Status.DeployedImage
+		}, 10, 1).Should(Equal("ghcr.io/dexidp/dex:v2.30.2"))
+		Expect(dexServer.Status.ConfigHash).To(Equal("my-config-hash"))
+		Expect(dexServer.Status.LastRolloutTime).NotTo(BeNil())
+
+		By("keeping the rollout time of the same image and configuration", func() {
+			rolloutTime := dexServer.Status.LastRolloutTime
+			Expect(rDexServer.reportRollout(dexServer, context.TODO())).To(Succeed())
+			Expect(dexServer.Status.LastRolloutTime).To(BeIdenticalTo(rolloutTime))
+		})
+	})
+})
@@ -0,0 +1,153 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Path of the callback of the upstream identity providers, under the issuer
+const DEX_CALLBACK_PATH = "/callback"
+
+var routeGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+
+// Get the path dex serves the issuer under, without a trailing slash. This is the path of spec.issuer, or
+// spec.route.path for the issuer generated from the route.
+func getIssuerPath(dexServer *authv1alpha1.DexServer) string {
+	if dexServer.Spec.Issuer != "" {
+		u, err := url.Parse(dexServer.Spec.Issuer)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSuffix(u.Path, "/")
+	}
+	if serviceType := dexServer.Spec.Service.Type; serviceType == corev1.ServiceTypeNodePort || serviceType == corev1.ServiceTypeLoadBalancer {
+		// the issuer is the address of the Service
+		return ""
+	}
+	return dexServer.Spec.Route.Path
+}
+
+// Get the path routed to dex by the Ingress or the Route, all the paths when dex serves the issuer at the root
+func getRoutePath(dexServer *authv1alpha1.DexServer) string {
+	if issuerPath := getIssuerPath(dexServer); issuerPath != "" {
+		return issuerPath
+	}
+	return "/"
+}
+
+// Set the redirect URI of the connectors that have none to the callback of dex. dex only serves the callback under
+// the issuer, the redirect URI registered with the identity provider must follow the issuer path.
+func setDefaultRedirectURIs(connectors []DexConnectorSpec, issuer string) {
+	for i := range connectors {
+		// the LDAP and mock connectors have no callback, and dex redirects the authproxy connectors to its own callback
+		switch authv1alpha1.ConnectorType(connectors[i].Type) {
+		case authv1alpha1.ConnectorTypeLDAP, authv1alpha1.ConnectorTypeAuthProxy, authv1alpha1.ConnectorTypeMockCallback, authv1alpha1.ConnectorTypeMockPassword:
+			continue
+		}
+		if connectors[i].Config.RedirectURI == "" && issuer != "" {
+			connectors[i].Config.RedirectURI = strings.TrimSuffix(issuer, "/") + DEX_CALLBACK_PATH
+		}
+	}
+}
+
+// Check whether dex is exposed by a wildcard Route created by the operator instead of the Route generated from the
+// Ingress. Wildcard routes only exist on OpenShift.
+func isWildcardRoute(dexServer *authv1alpha1.DexServer, openShift bool) bool {
+	return openShift && dexServer.Spec.Route.WildcardPolicy == authv1alpha1.RouteWildcardPolicySubdomain
+}
+
+// Get the certificate and key of the Route from the Secret of the Ingress certificate. The router only reads the
+// certificate of the Route itself, not a Secret.
+func (r *DexServerReconciler) getRouteCertificate(dexServer *authv1alpha1.DexServer, ctx context.Context) (string, string, error) {
+	secretName := getIngressTLSSecretName(dexServer)
+	if secretName == "" {
+		// served with the default certificate of the router
+		return "", "", nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: dexServer.Namespace}, secret); err != nil {
+		if kubeerrors.IsNotFound(err) {
+			// the certificate is not issued yet, the Route is updated once the Secret is created
+			return "", "", nil
+		}
+		return "", "", errors.Wrap(err, "error getting the certificate of the route")
+	}
+	return string(secret.Data[corev1.TLSCertKey]), string(secret.Data[corev1.TLSPrivateKeyKey]), nil
+}
+
+// Delete the Ingress, or the wildcard Route, that does not expose dex anymore
+func (r *DexServerReconciler) deleteUnusedRoute(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	var obj client.Object
+	if isWildcardRoute(dexServer, r.OpenShift) {
+		obj = &networkingv1.Ingress{}
+	} else if r.OpenShift {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(routeGVK)
+		obj = route
+	} else {
+		return nil
+	}
+	err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, obj)
+	switch {
+	case kubeerrors.IsNotFound(err), meta.IsNoMatchError(err):
+		// the route API is not served by the clusters faking OpenShift
+		return nil
+	case err != nil:
+		return err
+	}
+	// the Routes generated from the Ingress are owned by the Ingress
+	if !metav1.IsControlledBy(obj, dexServer) {
+		return nil
+	}
+	ctrllog.FromContext(ctx).Info("Deleting the unused route of dex", "Kind", obj.GetObjectKind().GroupVersionKind().Kind, "Name", obj.GetName())
+	return client.IgnoreNotFound(r.Delete(ctx, obj))
+}
+
+// getServedHosts returns the hosts the Ingress, or the wildcard Route, of the DexServer already serves. The hosts
+// outside of the cluster ingress domain it serves were accepted by the previous versions of the operator, which did
+// not check the domain, and are kept so that an upgrade of the operator doesn't take a working issuer down.
+func (r *DexServerReconciler) getServedHosts(dexServer *authv1alpha1.DexServer, ctx context.Context) (map[string]bool, error) {
+	hosts := map[string]bool{}
+	if isWildcardRoute(dexServer, r.OpenShift) {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(routeGVK)
+		err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, route)
+		switch {
+		case kubeerrors.IsNotFound(err), meta.IsNoMatchError(err):
+			return hosts, nil
+		case err != nil:
+			return nil, err
+		}
+		if host, _, _ := unstructured.NestedString(route.Object, "spec", "host"); host != "" {
+			hosts[host] = true
+		}
+		return hosts, nil
+	}
+	ingress := &networkingv1.Ingress{}
+	if err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, ingress); err != nil {
+		return hosts, client.IgnoreNotFound(err)
+	}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			hosts[rule.Host] = true
+		}
+	}
+	return hosts, nil
+}
@@ -0,0 +1,63 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("Serve the issuer under a path", func() {
+	It("should get the path of the issuer", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		Expect(getIssuerPath(dexServer)).To(BeEmpty())
+		Expect(getRoutePath(dexServer)).To(Equal("/"))
+
+		dexServer.Spec.Route.Path = "/dex"
+		Expect(getRoutePath(dexServer)).To(Equal("/dex"))
+		Expect(getHealthCheckPath(dexServer)).To(Equal("/dex/healthz"))
+
+		dexServer.Spec.Issuer = "https://sso.testhost.com/sso/"
+		Expect(getIssuerPath(dexServer)).To(Equal("/sso"))
+
+		dexServer.Spec.Issuer = ""
+		dexServer.Spec.Service.Type = corev1.ServiceTypeNodePort
+		Expect(getIssuerPath(dexServer)).To(BeEmpty())
+	})
+	It("should default the redirect URI of the connectors to the callback under the issuer", func() {
+		connectors := []DexConnectorSpec{
+			{Type: string(authv1alpha1.ConnectorTypeGitHub), Id: "github"},
+			{Type: string(authv1alpha1.ConnectorTypeOIDC), Id: "oidc", Config: DexConnectorConfigSpec{RedirectURI: "https://other.testhost.com/callback"}},
+			{Type: string(authv1alpha1.ConnectorTypeLDAP), Id: "ldap"},
+		}
+		setDefaultRedirectURIs(connectors, "https://sso.testhost.com/dex")
+		Expect(connectors[0].Config.RedirectURI).To(Equal("https://sso.testhost.com/dex/callback"))
+		Expect(connectors[1].Config.RedirectURI).To(Equal("https://other.testhost.com/callback"))
+		Expect(connectors[2].Config.RedirectURI).To(BeEmpty())
+	})
+	It("should validate the route options", func() {
+		fldPath := field.NewPath("spec", "route")
+		spec := &authv1alpha1.DexServerSpec{Issuer: "https://sso.testhost.com/dex"}
+		spec.Route.Path = "/dex"
+		Expect(authv1alpha1.ValidateRoute(spec, fldPath)).To(BeEmpty())
+
+		spec.Route.Path = "/sso"
+		Expect(authv1alpha1.ValidateRoute(spec, fldPath)).To(HaveLen(1))
+
+		spec.Route.Path = ""
+		spec.Route.WildcardPolicy = authv1alpha1.RouteWildcardPolicySubdomain
+		Expect(authv1alpha1.ValidateRoute(spec, fldPath)).To(BeEmpty())
+		spec.AdditionalHosts = []string{"dex.testhost.com"}
+		Expect(authv1alpha1.ValidateRoute(spec, fldPath)).To(HaveLen(1))
+	})
+	It("should only create a wildcard route on OpenShift", func() {
+		dexServer := &authv1alpha1.DexServer{}
+		dexServer.Spec.Route.WildcardPolicy = authv1alpha1.RouteWildcardPolicySubdomain
+		Expect(isWildcardRoute(dexServer, true)).To(BeTrue())
+		Expect(isWildcardRoute(dexServer, false)).To(BeFalse())
+	})
+})
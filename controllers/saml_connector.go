@@ -0,0 +1,39 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Render the dex config of a SAML connector, with the signing certificate mounted in the dex pod
+func (r *DexServerReconciler) renderSAMLConnector(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (DexConnectorSpec, error) {
+	// If there is a secret reference to the signing certificate, it is mounted in the dex pod
+	var caPath string
+	if connector.SAML.CARef.Name != "" {
+		err := r.copySecretToDexServerNamespace(dexServer, connector.SAML.CARef, ctx)
+		if err != nil {
+			return DexConnectorSpec{}, err
+		}
+		caPath = "/etc/dex/samlcerts/" + connector.Id + "/ca.crt"
+	}
+
+	return DexConnectorSpec{
+		Type: string(authv1alpha1.ConnectorTypeSAML),
+		Id:   connector.Id,
+		Name: getConnectorDisplayName(connector),
+		Config: DexConnectorConfigSpec{
+			SSOURL:       connector.SAML.SSOURL,
+			CA:           caPath,
+			CAData:       connector.SAML.CAData,
+			EntityIssuer: connector.SAML.EntityIssuer,
+			SSOIssuer:    connector.SAML.SSOIssuer,
+			RedirectURI:  connector.SAML.RedirectURI,
+			UsernameAttr: connector.SAML.UsernameAttr,
+			EmailAttr:    connector.SAML.EmailAttr,
+			GroupsAttr:   connector.SAML.GroupsAttr,
+		},
+	}, nil
+}
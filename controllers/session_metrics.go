@@ -0,0 +1,112 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// Minimum interval between the counts of the sessions stored by dex, the reconciles in between keep the last count
+	SESSION_COUNT_INTERVAL = 15 * time.Minute
+	// Objects of the dex storage listed per request
+	storageListPageSize = 500
+)
+
+var (
+	refreshTokensGVR   = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "refreshtokens"}
+	offlineSessionsGVR = schema.GroupVersionResource{Group: "dex.coreos.com", Version: "v1", Resource: "offlinesessionses"}
+)
+
+// refreshTokensCount and offlineSessionsCount are the counts of the sessions stored by dex, as reported in status.sessions
+var (
+	refreshTokensCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dex_operator_refresh_tokens",
+		Help: "Number of the refresh tokens stored by dex in the DexServer namespace",
+	}, []string{"namespace", "name"})
+	offlineSessionsCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dex_operator_offline_sessions",
+		Help: "Number of the offline sessions stored by dex in the DexServer namespace",
+	}, []string{"namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(refreshTokensCount, offlineSessionsCount)
+}
+
+// Whether the sessions are due for a new count
+func isSessionCountDue(dexServer *authv1alpha1.DexServer, now time.Time) bool {
+	status := dexServer.Status.Sessions
+	return status == nil || !now.Before(status.LastCountTime.Add(SESSION_COUNT_INTERVAL))
+}
+
+// Count the objects of a resource of the dex storage in a namespace. The resource is not defined until dex starts.
+func (r *DexServerReconciler) countStorageObjects(gvr schema.GroupVersionResource, namespace string, ctx context.Context) (int64, error) {
+	var count int64
+	opts := metav1.ListOptions{Limit: storageListPageSize}
+	for {
+		list, err := r.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, opts)
+		if kubeerrors.IsNotFound(err) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		count += int64(len(list.Items))
+		if list.GetContinue() == "" {
+			return count, nil
+		}
+		opts.Continue = list.GetContinue()
+	}
+}
+
+// Report the number of refresh tokens and offline sessions stored by dex in the status and in metrics, counted at
+// most every SESSION_COUNT_INTERVAL. Dex stores them in the DexServer namespace, the DexServers of a namespace report
+// the same counts. The last count is kept when the objects can't be listed.
+func (r *DexServerReconciler) reportSessions(dexServer *authv1alpha1.DexServer, ctx context.Context) {
+	log := ctrllog.FromContext(ctx)
+	if !isKubernetesStorage(dexServer) {
+		// the sessions are not custom resources of the namespace
+		dexServer.Status.Sessions = nil
+		deleteSessionMetrics(dexServer)
+		return
+	}
+	now := time.Now()
+	if !isSessionCountDue(dexServer, now) {
+		return
+	}
+	tokens, err := r.countStorageObjects(refreshTokensGVR, dexServer.Namespace, ctx)
+	if err != nil {
+		log.Error(err, "Error counting the refresh tokens")
+		return
+	}
+	sessions, err := r.countStorageObjects(offlineSessionsGVR, dexServer.Namespace, ctx)
+	if err != nil {
+		log.Error(err, "Error counting the offline sessions")
+		return
+	}
+	refreshTokensCount.WithLabelValues(dexServer.Namespace, dexServer.Name).Set(float64(tokens))
+	offlineSessionsCount.WithLabelValues(dexServer.Namespace, dexServer.Name).Set(float64(sessions))
+	// persisted with the Applied condition
+	dexServer.Status.Sessions = &authv1alpha1.SessionsStatus{
+		LastCountTime:   metav1.NewTime(now),
+		RefreshTokens:   tokens,
+		OfflineSessions: sessions,
+	}
+}
+
+// Delete the session metrics of a deleted DexServer
+func deleteSessionMetrics(dexServer *authv1alpha1.DexServer) {
+	refreshTokensCount.DeleteLabelValues(dexServer.Namespace, dexServer.Name)
+	offlineSessionsCount.DeleteLabelValues(dexServer.Namespace, dexServer.Name)
+}
@@ -0,0 +1,42 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Count the sessions stored by dex", func() {
+	It("should count the sessions at most every interval", func() {
+		now := time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC)
+		dexServer := &authv1alpha1.DexServer{}
+		Expect(isSessionCountDue(dexServer, now)).To(BeTrue())
+
+		dexServer.Status.Sessions = &authv1alpha1.SessionsStatus{LastCountTime: metav1.NewTime(now)}
+		Expect(isSessionCountDue(dexServer, now.Add(time.Minute))).To(BeFalse())
+		Expect(isSessionCountDue(dexServer, now.Add(SESSION_COUNT_INTERVAL))).To(BeTrue())
+	})
+	It("should report no session before dex defines its storage", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-sessions-dexserver", Namespace: "default"},
+		}
+		rDexServer.reportSessions(dexServer, context.TODO())
+		Expect(dexServer.Status.Sessions).ToNot(BeNil())
+		Expect(dexServer.Status.Sessions.RefreshTokens).To(BeZero())
+		Expect(dexServer.Status.Sessions.OfflineSessions).To(BeZero())
+
+		By("keeping the last count within the interval", func() {
+			dexServer.Status.Sessions.RefreshTokens = 12
+			rDexServer.reportSessions(dexServer, context.TODO())
+			Expect(dexServer.Status.Sessions.RefreshTokens).To(Equal(int64(12)))
+		})
+		deleteSessionMetrics(dexServer)
+	})
+})
@@ -0,0 +1,260 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// dex serves the client_credentials grant since v2.35.0
+var clientCredentialsMinDexVersion = version.MustParseGeneric("v2.35.0")
+
+// getReadyCondition sets the Ready condition once the deployment is available and, when spec.smokeTest is enabled,
+// the smoke test Job of the rolled out configuration succeeded. The Job is created once the rollout completes.
+func (r *DexServerReconciler) getReadyCondition(dexServer *authv1alpha1.DexServer, ctx context.Context, available metav1.Condition) (metav1.Condition, error) {
+	if available.Status != metav1.ConditionTrue {
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NotAvailable",
+			Message: available.Message,
+		}, nil
+	}
+	if !dexServer.Spec.SmokeTest.Enabled {
+		dexServer.Status.SmokeTest = nil
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Available",
+			Message: "DexServer deployment is available",
+		}, nil
+	}
+
+	result, err := r.syncSmokeTest(dexServer, ctx)
+	if err != nil {
+		return metav1.Condition{}, err
+	}
+	switch result {
+	case authv1alpha1.SmokeTestSucceeded:
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  "SmokeTestSucceeded",
+			Message: "DexServer deployment is available and passed the smoke test",
+		}, nil
+	case authv1alpha1.SmokeTestFailed:
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "SmokeTestFailed",
+			Message: dexServer.Status.SmokeTest.Message,
+		}, nil
+	default:
+		return metav1.Condition{
+			Type:    authv1alpha1.DexServerConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "SmokeTestRunning",
+			Message: "waiting for the smoke test of the rolled out configuration",
+		}, nil
+	}
+}
+
+// syncSmokeTest creates the smoke test Job of the rolled out configuration and records its result in the status.
+// The Jobs of the previous configurations are deleted.
+func (r *DexServerReconciler) syncSmokeTest(dexServer *authv1alpha1.DexServer, ctx context.Context) (authv1alpha1.SmokeTestResult, error) {
+	log := ctrllog.FromContext(ctx)
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace}, deployment); err != nil {
+		return "", err
+	}
+	if !isRolloutComplete(deployment) {
+		return authv1alpha1.SmokeTestRunning, nil
+	}
+	jobName := getSmokeTestJobName(dexServer, deployment)
+	if err := r.deletePreviousSmokeTestJobs(dexServer, ctx, jobName); err != nil {
+		return "", err
+	}
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: dexServer.Namespace}, job)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		log.Info("Creating smoke test Job", "Job.Name", jobName)
+		if err := r.createSmokeTestJob(dexServer, ctx, jobName); err != nil {
+			return "", errors.Wrap(err, "error creating smoke test Job")
+		}
+		dexServer.Status.SmokeTest = &authv1alpha1.SmokeTestStatus{JobName: jobName, Result: authv1alpha1.SmokeTestRunning}
+		return authv1alpha1.SmokeTestRunning, nil
+	case err != nil:
+		return "", err
+	}
+
+	status := &authv1alpha1.SmokeTestStatus{JobName: jobName, Result: authv1alpha1.SmokeTestRunning}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			status.Result = authv1alpha1.SmokeTestSucceeded
+		case batchv1.JobFailed:
+			status.Result = authv1alpha1.SmokeTestFailed
+			status.Message = fmt.Sprintf("smoke test failed: %s, see kubectl logs -n %s job/%s", cond.Message, dexServer.Namespace, jobName)
+		}
+	}
+	dexServer.Status.SmokeTest = status
+	return status.Result, nil
+}
+
+func isRolloutComplete(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
+
+// getSmokeTestJobName identifies the rollout of the dex server by the hashes of its configuration and credentials
+// set on the pod template, and its image
+func getSmokeTestJobName(dexServer *authv1alpha1.DexServer, deployment *appsv1.Deployment) string {
+	annotations := deployment.Spec.Template.Annotations
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, annotations[k])
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		fmt.Fprintf(h, "%s\n", container.Image)
+	}
+	return fmt.Sprintf("%s-smoke-test-%x", dexServer.Name, h.Sum(nil)[:5])
+}
+
+func (r *DexServerReconciler) createSmokeTestJob(dexServer *authv1alpha1.DexServer, ctx context.Context, jobName string) error {
+	log := ctrllog.FromContext(ctx)
+
+	dexURL, issuer, err := r.getInClusterIssuerURL(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(dexURL)
+	if err != nil {
+		return err
+	}
+
+	values := struct {
+		JobName          string
+		Image            string
+		URL              string
+		Scheme           string
+		Host             string
+		Port             string
+		Path             string
+		Issuer           string
+		TLSSecretName    string
+		ServiceCAFile    string
+		ClientID         string
+		ClientSecretName string
+		ClientSecretKey  string
+		DexServer        *authv1alpha1.DexServer
+	}{
+		JobName:       jobName,
+		Image:         os.Getenv(DEX_IMAGE_ENV_NAME),
+		URL:           dexURL,
+		Scheme:        u.Scheme,
+		Host:          u.Hostname(),
+		Port:          u.Port(),
+		Path:          u.Path,
+		Issuer:        issuer,
+		ServiceCAFile: serviceCAFile,
+		ClientID:      dexServer.Spec.SmokeTest.ClientID,
+		DexServer:     dexServer,
+	}
+	// The certificate of dex is verified against the generated serving certificate, or the OpenShift service CA
+	if u.Scheme == "https" {
+		values.TLSSecretName = dexServer.Name + SECRET_WEB_TLS_SUFFIX
+	}
+	if ref := dexServer.Spec.SmokeTest.ClientSecretRef; values.ClientID != "" {
+		if ref == nil {
+			return fmt.Errorf("spec.smokeTest.clientSecretRef is required with spec.smokeTest.clientID")
+		}
+		values.ClientSecretName = ref.Name
+		values.ClientSecretKey = ref.Key
+	}
+	if values.ClientID != "" && !isClientCredentialsGrantSupported() {
+		log.Info("Skipping the token request of the smoke test, the dex image does not support the client_credentials grant",
+			"image", values.Image, "minVersion", clientCredentialsMinDexVersion.String())
+		values.ClientID = ""
+	}
+
+	// Job specs are immutable, the Job is created once for each rollout
+	applier, readerDeploy := r.getApplierAndReader(dexServer)
+	rendered, err := applier.MustTemplateAssets(readerDeploy, values, "", "dex-server/smoke_test_job.yaml")
+	if err != nil {
+		return err
+	}
+	job := &batchv1.Job{}
+	if err := yaml.Unmarshal([]byte(rendered[0]), job); err != nil {
+		return err
+	}
+	if err := ctrl.SetControllerReference(dexServer, job, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, job)
+}
+
+// isClientCredentialsGrantSupported tells if the version tag of the dex image serves the client_credentials grant.
+// Images referenced by digest or without a version tag are assumed not to.
+func isClientCredentialsGrantSupported() bool {
+	v, err := version.ParseGeneric(getDexVersion())
+	if err != nil {
+		return false
+	}
+	return v.AtLeast(clientCredentialsMinDexVersion)
+}
+
+func (r *DexServerReconciler) deletePreviousSmokeTestJobs(dexServer *authv1alpha1.DexServer, ctx context.Context, jobName string) error {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(dexServer.Namespace), client.MatchingLabels{
+		INSTANCE_LABEL:  dexServer.Name,
+		COMPONENT_LABEL: componentSmokeTest,
+	}); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		if jobs.Items[i].Name == jobName {
+			continue
+		}
+		if err := r.Delete(ctx, &jobs.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
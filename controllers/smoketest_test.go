@@ -0,0 +1,102 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Smoke test the dex server", func() {
+	SmokeTestNamespace := "my-smoke-test-ns"
+
+	// setDexImage sets the dex image of the operator, the returned function restores the previous one
+	setDexImage := func(image string) func() {
+		previous := os.Getenv(DEX_IMAGE_ENV_NAME)
+		Expect(os.Setenv(DEX_IMAGE_ENV_NAME, image)).To(Succeed())
+		return func() {
+			Expect(os.Setenv(DEX_IMAGE_ENV_NAME, previous)).To(Succeed())
+		}
+	}
+
+	It("should only request a token from the dex images supporting the client_credentials grant", func() {
+		for _, test := range []struct {
+			image     string
+			supported bool
+		}{
+			{image: "ghcr.io/dexidp/dex:v2.30.2", supported: false},
+			{image: "ghcr.io/dexidp/dex:v2.34.0", supported: false},
+			{image: "ghcr.io/dexidp/dex:v2.35.0", supported: true},
+			{image: "ghcr.io/dexidp/dex:v2.37.0", supported: true},
+			{image: "ghcr.io/dexidp/dex:latest", supported: false},
+			{image: "ghcr.io/dexidp/dex@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", supported: false},
+			{image: "dex_image", supported: false},
+		} {
+			restore := setDexImage(test.image)
+			Expect(isClientCredentialsGrantSupported()).To(Equal(test.supported), test.image)
+			restore()
+		}
+	})
+	It("should verify the certificate of dex against its serving certificate", func() {
+		err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: SmokeTestNamespace}})
+		Expect(err).Should(BeNil())
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-smoke-tested-dexserver", Namespace: SmokeTestNamespace},
+			Spec: authv1alpha1.DexServerSpec{
+				Issuer: "https://my-smoke-tested-dexserver.testhost.com/dex",
+				SmokeTest: authv1alpha1.SmokeTestSpec{
+					ClientID: "my-smoke-test-client",
+					ClientSecretRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "my-smoke-test-client"},
+						Key:                  "secret",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), dexServer)).To(Succeed())
+
+		// getJob creates the smoke test Job with the given dex image
+		getJob := func(name string, image string) *batchv1.Job {
+			defer setDexImage(image)()
+			r := rDexServer
+			err := r.createSmokeTestJob(dexServer, context.TODO(), name)
+			Expect(err).Should(BeNil())
+			job := &batchv1.Job{}
+			err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: SmokeTestNamespace}, job)
+			Expect(err).Should(BeNil())
+			return job
+		}
+		job := getJob("my-smoke-test-v2-30", "ghcr.io/dexidp/dex:v2.30.2")
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].Secret).ToNot(BeNil())
+		Expect(podSpec.Volumes[0].Secret.SecretName).To(Equal(dexServer.Name + SECRET_WEB_TLS_SUFFIX))
+		container := podSpec.Containers[0]
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "tls", MountPath: "/etc/dex/tls", ReadOnly: true}))
+		Expect(container.Command[2]).ToNot(ContainSubstring("--no-check-certificate"))
+		Expect(container.Env).To(ContainElements(
+			corev1.EnvVar{Name: "DEX_HOST", Value: "my-smoke-tested-dexserver.my-smoke-test-ns.svc"},
+			corev1.EnvVar{Name: "DEX_PATH", Value: "/dex"},
+			corev1.EnvVar{Name: "SERVICE_CA_FILE", Value: serviceCAFile},
+		))
+
+		By("skipping the token request with a dex image older than v2.35.0", func() {
+			for _, env := range container.Env {
+				Expect(env.Name).ToNot(Equal("CLIENT_ID"))
+			}
+		})
+		By("requesting a token with a dex image supporting the client_credentials grant", func() {
+			job := getJob("my-smoke-test-v2-35", "ghcr.io/dexidp/dex:v2.35.0")
+			Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CLIENT_ID", Value: "my-smoke-test-client"}))
+		})
+	})
+})
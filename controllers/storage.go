@@ -0,0 +1,171 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/dexstorage"
+)
+
+const (
+	// Annotation set on a DexServer by the DexStorageMigration copying its objects to another storage, the dex pods
+	// are stopped while it is set
+	STORAGE_MIGRATION_ANNOTATION = "auth.identitatem.io/storageMigration"
+	// Environment variable dex reads the password of the storage from, the storage config references it
+	STORAGE_PASSWORD_ENV_VAR = "DEX_STORAGE_PASSWORD"
+	DEFAULT_POSTGRES_PORT    = 5432
+	DEFAULT_POSTGRES_SSLMODE = "verify-full"
+)
+
+// Storage section of the dex config
+type DexStorageSpec struct {
+	Type   string      `json:"type"`
+	Config interface{} `json:"config"`
+}
+
+// Config of the dex kubernetes storage, in the namespace of the dex pod
+type DexKubernetesStorageConfig struct {
+	InCluster bool `json:"inCluster"`
+}
+
+// Config of the dex postgres storage
+type DexPostgresStorageConfig struct {
+	Host     string            `json:"host"`
+	Port     int32             `json:"port"`
+	Database string            `json:"database"`
+	User     string            `json:"user"`
+	Password string            `json:"password"`
+	SSL      DexStorageSSLSpec `json:"ssl"`
+}
+
+// Config of the dex etcd storage
+type DexEtcdStorageConfig struct {
+	Endpoints []string `json:"endpoints"`
+	Namespace string   `json:"namespace,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	Password  string   `json:"password,omitempty"`
+}
+
+type DexStorageSSLSpec struct {
+	Mode string `json:"mode"`
+}
+
+// getStorageType returns the type of a storage, kubernetes by default
+func getStorageType(storage authv1alpha1.StorageSpec) authv1alpha1.StorageType {
+	if storage.Type == "" {
+		return authv1alpha1.StorageTypeKubernetes
+	}
+	return storage.Type
+}
+
+// isKubernetesStorage is true when dex stores its objects as custom resources of the DexServer namespace. The
+// handover, the storage cleanup and the session counts read these custom resources.
+func isKubernetesStorage(dexServer *authv1alpha1.DexServer) bool {
+	return getStorageType(dexServer.Spec.Storage) == authv1alpha1.StorageTypeKubernetes
+}
+
+// isStorageMigrating is true while a DexStorageMigration copies the objects of the storage of the DexServer
+func isStorageMigrating(dexServer *authv1alpha1.DexServer) bool {
+	return dexServer.Annotations[STORAGE_MIGRATION_ANNOTATION] != ""
+}
+
+// Get the storage section of the dex config. The password is not rendered, the config references the environment
+// variable passwordEnvVar dex expands, see getStorageEnvVariables.
+func getDexStorage(storage authv1alpha1.StorageSpec, passwordEnvVar string) DexStorageSpec {
+	storageType := getStorageType(storage)
+	dexStorage := DexStorageSpec{Type: string(storageType)}
+	switch {
+	case storageType == authv1alpha1.StorageTypePostgres && storage.Postgres != nil:
+		config := DexPostgresStorageConfig{
+			Host:     storage.Postgres.Host,
+			Port:     storage.Postgres.Port,
+			Database: storage.Postgres.Database,
+			User:     storage.Postgres.User,
+			Password: "${" + passwordEnvVar + "}",
+			SSL:      DexStorageSSLSpec{Mode: storage.Postgres.SSLMode},
+		}
+		if config.Port == 0 {
+			config.Port = DEFAULT_POSTGRES_PORT
+		}
+		if config.SSL.Mode == "" {
+			config.SSL.Mode = DEFAULT_POSTGRES_SSLMODE
+		}
+		dexStorage.Config = config
+	case storageType == authv1alpha1.StorageTypeEtcd && storage.Etcd != nil:
+		config := DexEtcdStorageConfig{
+			Endpoints: storage.Etcd.Endpoints,
+			Namespace: storage.Etcd.Namespace,
+			Username:  storage.Etcd.Username,
+		}
+		if storage.Etcd.PasswordRef != nil {
+			config.Password = "${" + passwordEnvVar + "}"
+		}
+		dexStorage.Config = config
+	default:
+		dexStorage.Type = string(authv1alpha1.StorageTypeKubernetes)
+		dexStorage.Config = DexKubernetesStorageConfig{InCluster: true}
+	}
+	return dexStorage
+}
+
+// Get the Secret key holding the password of a storage, nil when the storage has no password
+func getStoragePasswordRef(storage authv1alpha1.StorageSpec) *corev1.SecretKeySelector {
+	switch getStorageType(storage) {
+	case authv1alpha1.StorageTypePostgres:
+		if storage.Postgres != nil {
+			return &storage.Postgres.PasswordRef
+		}
+	case authv1alpha1.StorageTypeEtcd:
+		if storage.Etcd != nil {
+			return storage.Etcd.PasswordRef
+		}
+	}
+	return nil
+}
+
+// Get the environment variable of the password of a storage, referencing its Secret of the DexServer namespace
+func getStorageEnvVariables(storage authv1alpha1.StorageSpec, passwordEnvVar string) []corev1.EnvVar {
+	passwordRef := getStoragePasswordRef(storage)
+	if passwordRef == nil {
+		return nil
+	}
+	return []corev1.EnvVar{{
+		Name:      passwordEnvVar,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: passwordRef.DeepCopy()},
+	}}
+}
+
+// openDexStorage opens the storage of a DexServer, with the password of its Secret. The kubernetes storage is read and
+// written through DynamicClient in the DexServer namespace.
+func (r *DexServerReconciler) openDexStorage(dexServer *authv1alpha1.DexServer, ctx context.Context) (dexstorage.Storage, error) {
+	data, err := json.Marshal(getDexStorage(dexServer.Spec.Storage, STORAGE_PASSWORD_ENV_VAR))
+	if err != nil {
+		return nil, err
+	}
+	storage := dexstorage.Config{}
+	if err := json.Unmarshal(data, &storage); err != nil {
+		return nil, err
+	}
+	password := ""
+	if passwordRef := getStoragePasswordRef(dexServer.Spec.Storage); passwordRef != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: passwordRef.Name, Namespace: dexServer.Namespace}, secret); err != nil {
+			return nil, errors.Wrapf(err, "error getting the password of the storage of DexServer %s/%s", dexServer.Namespace, dexServer.Name)
+		}
+		password = string(secret.Data[passwordRef.Key])
+	}
+	getenv := func(name string) string {
+		if name == STORAGE_PASSWORD_ENV_VAR {
+			return password
+		}
+		return ""
+	}
+	return dexstorage.Open(storage, getenv, r.DynamicClient, dexServer.Namespace)
+}
@@ -0,0 +1,125 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+var defaultStorageCleanupInterval = time.Hour
+
+// The resources of the dex storage whose objects expire, dex sets their expiry field
+var expiringStorageGVRs = []schema.GroupVersionResource{
+	{Group: "dex.coreos.com", Version: "v1", Resource: "authcodes"},
+	{Group: "dex.coreos.com", Version: "v1", Resource: "authrequests"},
+	{Group: "dex.coreos.com", Version: "v1", Resource: "devicerequests"},
+	{Group: "dex.coreos.com", Version: "v1", Resource: "devicetokens"},
+}
+
+func getStorageCleanupInterval(dexServer *authv1alpha1.DexServer) time.Duration {
+	if interval := dexServer.Spec.StorageCleanup.Interval; interval != nil && interval.Duration > 0 {
+		return interval.Duration
+	}
+	return defaultStorageCleanupInterval
+}
+
+// Get the time left before the next cleanup of the storage, zero when it is due
+func getStorageCleanupRequeueAfter(dexServer *authv1alpha1.DexServer, now time.Time) time.Duration {
+	status := dexServer.Status.StorageCleanup
+	if status == nil {
+		return 0
+	}
+	if remaining := status.LastCleanupTime.Add(getStorageCleanupInterval(dexServer)).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Whether an object of the dex storage expired more than the retention ago. The objects without a valid expiry are
+// kept.
+func isStorageObjectExpired(obj unstructured.Unstructured, retention time.Duration, now time.Time) bool {
+	value, ok := obj.Object["expiry"].(string)
+	if !ok {
+		return false
+	}
+	expiry, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return false
+	}
+	return now.After(expiry.Add(retention))
+}
+
+// Delete the expired objects of a resource of the dex storage in a namespace. The resource is not defined until dex
+// starts.
+func (r *DexServerReconciler) deleteExpiredStorageObjects(gvr schema.GroupVersionResource, namespace string, retention time.Duration, now time.Time, ctx context.Context) (int64, error) {
+	var deleted int64
+	client := r.DynamicClient.Resource(gvr).Namespace(namespace)
+	opts := metav1.ListOptions{Limit: storageListPageSize}
+	for {
+		list, err := client.List(ctx, opts)
+		if kubeerrors.IsNotFound(err) {
+			return deleted, nil
+		}
+		if err != nil {
+			return deleted, err
+		}
+		for _, obj := range list.Items {
+			if !isStorageObjectExpired(obj, retention, now) {
+				continue
+			}
+			err := client.Delete(ctx, obj.GetName(), metav1.DeleteOptions{DryRun: r.dryRun})
+			if err != nil && !kubeerrors.IsNotFound(err) {
+				return deleted, err
+			}
+			deleted++
+		}
+		if list.GetContinue() == "" {
+			return deleted, nil
+		}
+		opts.Continue = list.GetContinue()
+	}
+}
+
+// Delete the expired auth codes, auth requests, device requests and device tokens of the dex storage once
+// spec.storageCleanup.interval elapses, and report the cleanup in the status. Dex stores them in the DexServer
+// namespace, the DexServers of a namespace clean up the same objects.
+func (r *DexServerReconciler) cleanupStorage(dexServer *authv1alpha1.DexServer, ctx context.Context) {
+	if !dexServer.Spec.StorageCleanup.Enabled || !isKubernetesStorage(dexServer) {
+		dexServer.Status.StorageCleanup = nil
+		return
+	}
+	now := time.Now()
+	if getStorageCleanupRequeueAfter(dexServer, now) > 0 {
+		return
+	}
+	log := ctrllog.FromContext(ctx)
+	var retention time.Duration
+	if dexServer.Spec.StorageCleanup.Retention != nil {
+		retention = dexServer.Spec.StorageCleanup.Retention.Duration
+	}
+	status := &authv1alpha1.StorageCleanupStatus{LastCleanupTime: metav1.NewTime(now)}
+	for _, gvr := range expiringStorageGVRs {
+		deleted, err := r.deleteExpiredStorageObjects(gvr, dexServer.Namespace, retention, now, ctx)
+		status.Deleted += deleted
+		if err != nil {
+			log.Error(err, "Error deleting the expired objects of the dex storage", "Resource", gvr.Resource)
+			status.Message = fmt.Sprintf("error deleting the expired %s: %s", gvr.Resource, err.Error())
+			break
+		}
+	}
+	if status.Deleted > 0 {
+		log.Info("Deleted the expired objects of the dex storage", "Deleted", status.Deleted)
+	}
+	// persisted with the Applied condition
+	dexServer.Status.StorageCleanup = status
+}
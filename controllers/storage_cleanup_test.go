@@ -0,0 +1,47 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Clean up the dex storage", func() {
+	It("should only delete the objects expired for longer than the retention", func() {
+		now := time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC)
+		authCode := unstructured.Unstructured{Object: map[string]interface{}{"expiry": "2021-06-01T09:00:00Z"}}
+		Expect(isStorageObjectExpired(authCode, 0, now)).To(BeTrue())
+		Expect(isStorageObjectExpired(authCode, 2*time.Hour, now)).To(BeFalse())
+		Expect(isStorageObjectExpired(unstructured.Unstructured{Object: map[string]interface{}{}}, 0, now)).To(BeFalse())
+	})
+	It("should clean up the storage once the interval elapses", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-cleanup-dexserver", Namespace: "default"},
+			Spec: authv1alpha1.DexServerSpec{
+				StorageCleanup: authv1alpha1.StorageCleanupSpec{Enabled: true, Interval: &metav1.Duration{Duration: 10 * time.Minute}},
+			},
+		}
+		// the storage of dex is not defined in the test environment
+		rDexServer.cleanupStorage(dexServer, context.TODO())
+		Expect(dexServer.Status.StorageCleanup).ToNot(BeNil())
+		Expect(dexServer.Status.StorageCleanup.Deleted).To(BeZero())
+		Expect(dexServer.Status.StorageCleanup.Message).To(BeEmpty())
+		lastCleanupTime := dexServer.Status.StorageCleanup.LastCleanupTime
+		Expect(getStorageCleanupRequeueAfter(dexServer, lastCleanupTime.Add(time.Minute))).To(Equal(9 * time.Minute))
+		Expect(getStorageCleanupRequeueAfter(dexServer, lastCleanupTime.Add(10*time.Minute))).To(BeZero())
+
+		By("dropping the status once disabled", func() {
+			dexServer.Spec.StorageCleanup.Enabled = false
+			rDexServer.cleanupStorage(dexServer, context.TODO())
+			Expect(dexServer.Status.StorageCleanup).To(BeNil())
+		})
+	})
+})
@@ -4,6 +4,7 @@ package controllers
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -21,6 +22,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/redact"
 	ctrl "sigs.k8s.io/controller-runtime"
 	//+kubebuilder:scaffold:imports
 )
@@ -29,12 +31,19 @@ import (
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
 
 var (
-	k8sClient  client.Client
-	testEnv    *envtest.Environment
-	ctx        context.Context
-	cancel     context.CancelFunc
-	rDexServer DexServerReconciler
-	rDexClient DexClientReconciler
+	k8sClient         client.Client
+	k8sCachedClient   client.Client
+	testEnv           *envtest.Environment
+	ctx               context.Context
+	cancel            context.CancelFunc
+	rDexServer        DexServerReconciler
+	rDexClient        DexClientReconciler
+	rStorageMigration DexStorageMigrationReconciler
+	rClusterDexServer ClusterDexServerReconciler
+	rDexQuickstart    DexQuickstartReconciler
+	rDexServerSet     DexServerSetReconciler
+	rIssuerDirectory  IssuerDirectoryReconciler
+	suiteLog          = &syncBuffer{}
 )
 
 func TestAPIs(t *testing.T) {
@@ -51,7 +60,8 @@ func TestAPIs(t *testing.T) {
 }
 
 var _ = BeforeSuite(func() {
-	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+	// the logs of the suite are checked for secret material in AfterSuite
+	logf.SetLogger(redact.NewLogger(zap.New(zap.WriteTo(io.MultiWriter(GinkgoWriter, suiteLog)), zap.UseDevMode(true))))
 
 	ctx, cancel = context.WithCancel(context.TODO())
 
@@ -91,6 +101,8 @@ var _ = BeforeSuite(func() {
 		Scheme: scheme.Scheme,
 	})
 	Expect(err).ToNot(HaveOccurred())
+	// the client of the manager reads from its cache and its field indexes
+	k8sCachedClient = k8sManager.GetClient()
 
 	By("Init the reconcilers")
 	rDexServer = DexServerReconciler{
@@ -99,6 +111,7 @@ var _ = BeforeSuite(func() {
 		DynamicClient:      dynamic.NewForConfigOrDie(cfg),
 		APIExtensionClient: apiextensionsclient.NewForConfigOrDie(cfg),
 		Scheme:             scheme.Scheme,
+		OpenShift:          true,
 	}
 
 	err = (rDexServer).SetupWithManager(k8sManager)
@@ -112,6 +125,34 @@ var _ = BeforeSuite(func() {
 	err = (rDexClient).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	rStorageMigration = DexStorageMigrationReconciler{
+		Client:  k8sClient,
+		Scheme:  scheme.Scheme,
+		Image:   "dex_operator_image",
+		Command: []string{"/manager", STORAGE_MIGRATION_COMMAND},
+	}
+
+	rClusterDexServer = ClusterDexServerReconciler{
+		Client: k8sClient,
+		Scheme: scheme.Scheme,
+	}
+
+	rDexQuickstart = DexQuickstartReconciler{
+		Client: k8sClient,
+		Scheme: scheme.Scheme,
+	}
+
+	rDexServerSet = DexServerSetReconciler{
+		Client: k8sClient,
+		Scheme: scheme.Scheme,
+	}
+
+	rIssuerDirectory = IssuerDirectoryReconciler{
+		Client:    k8sClient,
+		Namespace: "issuer-directory-ns",
+		Name:      "dex-issuers",
+	}
+
 	go func() {
 		defer GinkgoRecover()
 		err = k8sManager.Start(ctx)
@@ -121,6 +162,8 @@ var _ = BeforeSuite(func() {
 
 var _ = AfterSuite(func() {
 	cancel()
+	By("checking that no secret material was logged")
+	expectNoSecretMaterial(suiteLog.String())
 	By("tearing down the test environment")
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
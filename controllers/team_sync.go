@@ -0,0 +1,590 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// ConfigMap holding the members of the GitHub teams with the ConfigMap target of spec.teamSync
+	TEAM_SYNC_SUFFIX = "-github-teams"
+	TEAM_SYNC_KEY    = "groups.yaml"
+	// Key of the clientSecretRef Secret of the connector holding the GitHub token, unless spec.teamSync.tokenRef is set
+	TEAM_SYNC_TOKEN_KEY = "token"
+	// Number of teams or members per page of the GitHub API, its maximum
+	githubPageSize = 100
+	// Timeout of a request of the GitHub API
+	githubRequestTimeout = 30 * time.Second
+	// Number of retries of a request of the GitHub API that is rate limited or fails on the server side
+	githubMaxRetries = 3
+)
+
+var (
+	openShiftGroupGVR       = schema.GroupVersionResource{Group: "user.openshift.io", Version: "v1", Resource: "groups"}
+	defaultTeamSyncInterval = time.Hour
+	// How often the DexServers are checked for teams due to sync, see SyncTeams
+	teamSyncPeriod = time.Minute
+	// Deadline of the sync of the teams of a DexServer, including the waits for the rate limit of the GitHub API
+	teamSyncTimeout = 5 * time.Minute
+	// Backoff of the retries of the requests of the GitHub API failing on the server side, doubled on each retry
+	githubRetryBackoff = time.Second
+)
+
+// githubTeam is a team of the GitHub list teams API
+type githubTeam struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// githubMember is a user of the GitHub list team members API
+type githubMember struct {
+	Login string `json:"login"`
+}
+
+func getTeamSyncInterval(dexServer *authv1alpha1.DexServer) time.Duration {
+	if interval := dexServer.Spec.TeamSync.Interval; interval != nil && interval.Duration > 0 {
+		return interval.Duration
+	}
+	return defaultTeamSyncInterval
+}
+
+// Get the time left before the next sync of the teams, zero when they are due
+func getTeamSyncRequeueAfter(dexServer *authv1alpha1.DexServer, now time.Time) time.Duration {
+	status := dexServer.Status.TeamSync
+	if status == nil || status.ObservedGeneration != dexServer.Generation {
+		return 0
+	}
+	if remaining := status.LastSyncTime.Add(getTeamSyncInterval(dexServer)).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Labels selecting the Groups of the teams of the DexServer
+func getTeamSyncGroupLabels(dexServer *authv1alpha1.DexServer) map[string]string {
+	return map[string]string{
+		"dexconfig_name":      dexServer.Name,
+		"dexconfig_namespace": dexServer.Namespace,
+		COMPONENT_LABEL:       componentTeamSync,
+	}
+}
+
+// Get the github connector of spec.teamSync
+func getTeamSyncConnector(dexServer *authv1alpha1.DexServer) (authv1alpha1.ConnectorSpec, error) {
+	for _, connector := range dexServer.Spec.Connectors {
+		if connector.Id != dexServer.Spec.TeamSync.Connector {
+			continue
+		}
+		if connector.Type != authv1alpha1.ConnectorTypeGitHub {
+			return connector, fmt.Errorf("connector %s of type %s has no GitHub teams", connector.Id, connector.Type)
+		}
+		return connector, nil
+	}
+	return authv1alpha1.ConnectorSpec{}, fmt.Errorf("teams are synced from the unknown connector %s", dexServer.Spec.TeamSync.Connector)
+}
+
+// Get the GitHub token of spec.teamSync.tokenRef, or of the token key of the clientSecretRef Secret of the connector
+func (r *DexServerReconciler) getTeamSyncToken(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (string, error) {
+	name, namespace, key := connector.GitHub.ClientSecretRef.Name, connector.GitHub.ClientSecretRef.Namespace, TEAM_SYNC_TOKEN_KEY
+	if ref := dexServer.Spec.TeamSync.TokenRef; ref != nil {
+		name, namespace, key = ref.Name, dexServer.Namespace, ref.Key
+	}
+	if namespace == "" {
+		namespace = dexServer.Namespace
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		return "", errors.Wrapf(err, "error getting the GitHub token Secret %s/%s", namespace, name)
+	}
+	if len(secret.Data[key]) == 0 {
+		return "", fmt.Errorf("key %s of Secret %s/%s holding the GitHub token is empty", key, namespace, name)
+	}
+	return strings.TrimSpace(string(secret.Data[key])), nil
+}
+
+func getGitHubAPIURL(hostName string) string {
+	if hostName == "" || hostName == "github.com" {
+		return "https://api.github.com"
+	}
+	// GitHub Enterprise Server
+	return "https://" + hostName + "/api/v3"
+}
+
+// Get the client of the GitHub API of a connector. Like dex, it only trusts the root CA of the connector, when set,
+// for a GitHub Enterprise server, read inline or from the copy of rootCARef in the DexServer namespace.
+func (r *DexServerReconciler) getGitHubHTTPClient(dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, ctx context.Context) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if github := connector.GitHub; github.HostName != "" {
+		var rootCA []byte
+		switch {
+		case len(github.RootCAData) > 0:
+			rootCA = github.RootCAData
+		case github.RootCARef.Name != "":
+			secretName := github.RootCARef.Namespace + "-" + github.RootCARef.Name
+			secret := &corev1.Secret{}
+			if err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: dexServer.Namespace}, secret); err != nil {
+				return nil, errors.Wrapf(err, "error getting the root CA Secret %s of connector %s", secretName, connector.Id)
+			}
+			rootCA = secret.Data["ca.crt"]
+		}
+		if rootCA != nil {
+			pool = x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(rootCA) {
+				return nil, fmt.Errorf("the root CA of connector %s holds no certificate", connector.Id)
+			}
+		}
+	}
+	return &http.Client{
+		Timeout: githubRequestTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}, nil
+}
+
+// Get the delay before retrying a request of the GitHub API, false when the response can't be retried. A rate
+// limited request is retried once the rate limit resets, see
+// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting, and the failures on the server
+// side with an exponential backoff.
+func getGitHubRetryDelay(resp *http.Response, attempt int, now time.Time) (time.Duration, bool) {
+	if attempt >= githubMaxRetries {
+		return 0, false
+	}
+	rateLimited := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests
+	if retryAfter := resp.Header.Get("Retry-After"); rateLimited && retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	if rateLimited && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// the reset time has a precision of a second
+			if delay := time.Unix(reset, 0).Sub(now) + time.Second; delay > 0 {
+				return delay, true
+			}
+			return time.Second, true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return githubRetryBackoff << attempt, true
+	}
+	return 0, false
+}
+
+// Get a page of a GitHub list API into items, the last page has less than githubPageSize items. The rate limited
+// requests are retried until the deadline of ctx.
+func getGitHubPage(ctx context.Context, httpClient *http.Client, listURL string, token string, page int, items interface{}) error {
+	u, err := url.Parse(listURL)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	query.Set("per_page", fmt.Sprint(githubPageSize))
+	query.Set("page", fmt.Sprint(page))
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+token)
+	for attempt := 0; ; attempt++ {
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusOK {
+			defer resp.Body.Close()
+			if err := json.NewDecoder(resp.Body).Decode(items); err != nil {
+				return errors.Wrapf(err, "error decoding GET %s", u.Path)
+			}
+			return nil
+		}
+		resp.Body.Close()
+		delay, retry := getGitHubRetryDelay(resp, attempt, time.Now())
+		if !retry {
+			return fmt.Errorf("GET %s returned %s", u.Path, resp.Status)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("GET %s returned %s, retrying in %s would exceed the deadline of the sync", u.Path, resp.Status, delay.Round(time.Second))
+		}
+		ctrllog.FromContext(ctx).V(1).Info("retrying a request of the GitHub API", "Path", u.Path, "Status", resp.Status, "Delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func listGitHubTeams(ctx context.Context, httpClient *http.Client, apiURL string, token string, org string) ([]githubTeam, error) {
+	teams := []githubTeam{}
+	for page := 1; ; page++ {
+		items := []githubTeam{}
+		if err := getGitHubPage(ctx, httpClient, fmt.Sprintf("%s/orgs/%s/teams", apiURL, url.PathEscape(org)), token, page, &items); err != nil {
+			return nil, errors.Wrapf(err, "error listing the teams of org %s", org)
+		}
+		teams = append(teams, items...)
+		if len(items) < githubPageSize {
+			return teams, nil
+		}
+	}
+}
+
+func listGitHubTeamMembers(ctx context.Context, httpClient *http.Client, apiURL string, token string, org string, team githubTeam) ([]string, error) {
+	logins := []string{}
+	for page := 1; ; page++ {
+		items := []githubMember{}
+		if err := getGitHubPage(ctx, httpClient, fmt.Sprintf("%s/orgs/%s/teams/%s/members", apiURL, url.PathEscape(org), url.PathEscape(team.Slug)), token, page, &items); err != nil {
+			return nil, errors.Wrapf(err, "error listing the members of team %s of org %s", team.Slug, org)
+		}
+		for _, member := range items {
+			logins = append(logins, member.Login)
+		}
+		if len(items) < githubPageSize {
+			return logins, nil
+		}
+	}
+}
+
+// Names of the groups of a team in the groups claim issued by the connector, see its teamNameField
+func getTeamGroupNames(connector authv1alpha1.ConnectorSpec, org string, team githubTeam) []string {
+	switch connector.GitHub.TeamNameField {
+	case "slug":
+		return []string{org + ":" + team.Slug}
+	case "both":
+		if team.Name == team.Slug {
+			return []string{org + ":" + team.Name}
+		}
+		return []string{org + ":" + team.Name, org + ":" + team.Slug}
+	default:
+		return []string{org + ":" + team.Name}
+	}
+}
+
+// Get the members of the teams of the orgs of the connector, by group name. The teams listed by an org of the
+// connector are synced, or all the teams of the org when it lists none.
+func getGitHubTeamMembers(ctx context.Context, httpClient *http.Client, dexServer *authv1alpha1.DexServer, connector authv1alpha1.ConnectorSpec, token string) (map[string][]string, error) {
+	orgs := append([]authv1alpha1.Org{}, connector.GitHub.Orgs...)
+	if connector.GitHub.Org != "" {
+		orgs = append(orgs, authv1alpha1.Org{Name: connector.GitHub.Org})
+	}
+	if len(orgs) == 0 {
+		return nil, fmt.Errorf("connector %s has no orgs to sync the teams of", connector.Id)
+	}
+
+	apiURL := getGitHubAPIURL(connector.GitHub.HostName)
+	groups := map[string][]string{}
+	for _, org := range orgs {
+		teams, err := listGitHubTeams(ctx, httpClient, apiURL, token, org.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, team := range teams {
+			if len(org.Teams) > 0 && !containsString(org.Teams, team.Name) && !containsString(org.Teams, team.Slug) {
+				continue
+			}
+			logins, err := listGitHubTeamMembers(ctx, httpClient, apiURL, token, org.Name, team)
+			if err != nil {
+				return nil, err
+			}
+			users := make([]string, 0, len(logins))
+			for _, login := range logins {
+				users = append(users, dexServer.Spec.TeamSync.UsernamePrefix+login)
+			}
+			sort.Strings(users)
+			for _, name := range getTeamGroupNames(connector, org.Name, team) {
+				groups[dexServer.Spec.GroupBindings.GroupsPrefix+name] = users
+			}
+		}
+	}
+	return groups, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Check spec.teamSync on each reconcile, and delete the ConfigMap and the Groups of the teams once the sync is
+// disabled. The members of the teams are synced by SyncTeams, outside of the reconciles.
+func (r *DexServerReconciler) syncTeams(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	log.Info("syncTeams")
+
+	spec := dexServer.Spec.TeamSync
+	if spec.Connector == "" {
+		if dexServer.Status.TeamSync == nil {
+			return nil
+		}
+		// the sync of the teams was disabled
+		if err := r.syncTeamsConfigMap(dexServer, ctx, nil); err != nil {
+			return err
+		}
+		if err := r.deleteTeamSyncGroups(dexServer, ctx, nil); err != nil {
+			return err
+		}
+		dexServer.Status.TeamSync = nil
+		return nil
+	}
+	if _, err := getTeamSyncConnector(dexServer); err != nil {
+		return err
+	}
+	if spec.Target == authv1alpha1.TeamSyncTargetGroup && !r.OpenShift {
+		return fmt.Errorf("the Group target of spec.teamSync is only supported on OpenShift")
+	}
+	return nil
+}
+
+// SyncTeams implements manager.Runnable. Every teamSyncPeriod, the members of the GitHub teams of the DexServers are
+// synced once spec.teamSync.interval elapses or the spec changes. The paged requests of the GitHub API, and their
+// waits for its rate limit, don't hold the reconciles of the DexServers and are bounded by teamSyncTimeout.
+func (r *DexServerReconciler) SyncTeams(ctx context.Context) error {
+	log := ctrllog.FromContext(ctx).WithName("team-sync")
+	ctx = ctrllog.IntoContext(ctx, log)
+	ticker := time.NewTicker(teamSyncPeriod)
+	defer ticker.Stop()
+	for {
+		r.syncDueTeams(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Sync the members of the teams of the DexServers due to sync, and write their status
+func (r *DexServerReconciler) syncDueTeams(ctx context.Context) {
+	log := ctrllog.FromContext(ctx)
+	dexServers := &authv1alpha1.DexServerList{}
+	if err := r.List(ctx, dexServers); err != nil {
+		log.Error(err, "failed to list the DexServers")
+		return
+	}
+	for i := range dexServers.Items {
+		dexServer := &dexServers.Items[i]
+		if dexServer.Spec.TeamSync.Connector == "" || !dexServer.DeletionTimestamp.IsZero() || getTeamSyncRequeueAfter(dexServer, time.Now()) > 0 {
+			continue
+		}
+		if err := r.syncTeamMembers(dexServer, ctx); err != nil {
+			log.Error(err, "failed to sync the GitHub teams", "DexServer.Namespace", dexServer.Namespace, "DexServer.Name", dexServer.Name)
+			continue
+		}
+		// a conflict with a reconcile is retried on the next period
+		if err := r.Status().Update(ctx, dexServer); err != nil {
+			log.Error(err, "failed to update the status of the GitHub teams", "DexServer.Namespace", dexServer.Namespace, "DexServer.Name", dexServer.Name)
+		}
+	}
+}
+
+// Sync the members of the GitHub teams of spec.teamSync.connector to the ConfigMap or the Groups of
+// spec.teamSync.target, within teamSyncTimeout. A failure of the GitHub API is reported in the status, and the groups
+// of the previous sync are kept until the next sync.
+func (r *DexServerReconciler) syncTeamMembers(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	spec := dexServer.Spec.TeamSync
+	connector, err := getTeamSyncConnector(dexServer)
+	if err != nil {
+		return err
+	}
+	if spec.Target == authv1alpha1.TeamSyncTargetGroup && !r.OpenShift {
+		return fmt.Errorf("the Group target of spec.teamSync is only supported on OpenShift")
+	}
+	token, err := r.getTeamSyncToken(dexServer, connector, ctx)
+	if err != nil {
+		return err
+	}
+	httpClient, err := r.getGitHubHTTPClient(dexServer, connector, ctx)
+	if err != nil {
+		return err
+	}
+
+	status := &authv1alpha1.TeamSyncStatus{LastSyncTime: metav1.Now(), ObservedGeneration: dexServer.Generation}
+	githubCtx, cancel := context.WithTimeout(ctx, teamSyncTimeout)
+	defer cancel()
+	groups, err := getGitHubTeamMembers(githubCtx, httpClient, dexServer, connector, token)
+	if err != nil {
+		log.Info("failed to sync the GitHub teams", "Connector.Id", connector.Id, "error", err.Error())
+		if previous := dexServer.Status.TeamSync; previous != nil {
+			status.Groups = previous.Groups
+		}
+		status.Message = err.Error()
+		dexServer.Status.TeamSync = status
+		return nil
+	}
+
+	if spec.Target == authv1alpha1.TeamSyncTargetGroup {
+		if err := r.syncTeamsConfigMap(dexServer, ctx, nil); err != nil {
+			return err
+		}
+		if err := r.syncTeamSyncGroups(dexServer, ctx, groups); err != nil {
+			return err
+		}
+	} else {
+		if err := r.deleteTeamSyncGroups(dexServer, ctx, nil); err != nil {
+			return err
+		}
+		if err := r.syncTeamsConfigMap(dexServer, ctx, groups); err != nil {
+			return err
+		}
+	}
+	for name := range groups {
+		status.Groups = append(status.Groups, name)
+	}
+	sort.Strings(status.Groups)
+	dexServer.Status.TeamSync = status
+	return nil
+}
+
+// Write the members of the groups in the ConfigMap of the teams, the ConfigMap is deleted when groups is nil
+func (r *DexServerReconciler) syncTeamsConfigMap(dexServer *authv1alpha1.DexServer, ctx context.Context, groups map[string][]string) error {
+	name := dexServer.Name + TEAM_SYNC_SUFFIX
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: dexServer.Namespace}, configMap)
+	switch {
+	case kubeerrors.IsNotFound(err):
+		if groups == nil {
+			return nil
+		}
+	case err != nil:
+		return err
+	case groups == nil:
+		return r.Delete(ctx, configMap)
+	}
+
+	data, err := yaml.Marshal(groups)
+	if err != nil {
+		return err
+	}
+	if configMap.Labels == nil {
+		configMap.Labels = map[string]string{}
+	}
+	if configMap.Annotations == nil {
+		configMap.Annotations = map[string]string{}
+	}
+	configMap.Labels["app"] = dexServer.Name
+	r.addManagedMetadata(dexServer, componentTeamSync, configMap.Labels, configMap.Annotations)
+	configMap.Data = map[string]string{TEAM_SYNC_KEY: string(data)}
+	if configMap.ResourceVersion == "" {
+		configMap.Name = name
+		configMap.Namespace = dexServer.Namespace
+		if err := ctrl.SetControllerReference(dexServer, configMap, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, configMap)
+	}
+	return r.Update(ctx, configMap)
+}
+
+// Create or update an OpenShift Group for each group, and delete the Groups of the teams that no longer exist
+func (r *DexServerReconciler) syncTeamSyncGroups(dexServer *authv1alpha1.DexServer, ctx context.Context, groups map[string][]string) error {
+	log := ctrllog.FromContext(ctx)
+	client := r.DynamicClient.Resource(openShiftGroupGVR)
+	groupLabels := getTeamSyncGroupLabels(dexServer)
+	annotations := map[string]string{}
+	r.addManagedMetadata(dexServer, componentTeamSync, groupLabels, annotations)
+
+	keep := map[string]bool{}
+	for name, users := range groups {
+		keep[name] = true
+		existing, err := client.Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case kubeerrors.IsNotFound(err):
+			group := &unstructured.Unstructured{Object: map[string]interface{}{"users": toInterfaceSlice(users)}}
+			group.SetAPIVersion("user.openshift.io/v1")
+			group.SetKind("Group")
+			group.SetName(name)
+			group.SetLabels(groupLabels)
+			group.SetAnnotations(annotations)
+			log.Info("Creating a team Group", "Group.Name", name)
+			if _, err := client.Create(ctx, group, metav1.CreateOptions{DryRun: r.dryRun}); err != nil {
+				return errors.Wrapf(err, "error creating Group %s", name)
+			}
+			continue
+		case err != nil:
+			return errors.Wrapf(err, "error getting Group %s", name)
+		}
+		if existing.GetLabels()["dexconfig_name"] != dexServer.Name || existing.GetLabels()["dexconfig_namespace"] != dexServer.Namespace {
+			return fmt.Errorf("the Group %s already exists and is not managed by the DexServer", name)
+		}
+		existing.Object["users"] = toInterfaceSlice(users)
+		existing.SetLabels(groupLabels)
+		existing.SetAnnotations(annotations)
+		if _, err := client.Update(ctx, existing, metav1.UpdateOptions{DryRun: r.dryRun}); err != nil {
+			return errors.Wrapf(err, "error updating Group %s", name)
+		}
+	}
+	return r.deleteTeamSyncGroups(dexServer, ctx, keep)
+}
+
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
+
+// Delete the Groups of the teams of the DexServer, except the keep Groups. The Groups are cluster scoped and can't
+// be owned by the DexServer.
+func (r *DexServerReconciler) deleteTeamSyncGroups(dexServer *authv1alpha1.DexServer, ctx context.Context, keep map[string]bool) error {
+	if !r.OpenShift {
+		return nil
+	}
+	log := ctrllog.FromContext(ctx)
+	selector := labels.SelectorFromSet(labels.Set(getTeamSyncGroupLabels(dexServer)))
+	groups, err := r.DynamicClient.Resource(openShiftGroupGVR).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "error listing Groups")
+	}
+	for _, group := range groups.Items {
+		if keep[group.GetName()] {
+			continue
+		}
+		log.Info("Deleting a team Group", "Group.Name", group.GetName())
+		err := r.DynamicClient.Resource(openShiftGroupGVR).Delete(ctx, group.GetName(), metav1.DeleteOptions{DryRun: r.dryRun})
+		if err != nil && !kubeerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error deleting Group %s", group.GetName())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	DEX_TELEMETRY_PORT = 5558
+	// Port of the kube-rbac-proxy sidecar fronting the dex metrics, see spec.telemetry.rbacProxy
+	RBAC_PROXY_PORT           = 8443
+	RBAC_PROXY_IMAGE_ENV_NAME = "RELATED_IMAGE_KUBE_RBAC_PROXY"
+	METRICS_SERVICE_SUFFIX    = "-metrics"
+	// Secret of the kube-rbac-proxy serving certificate, requested from the OpenShift service serving certificates
+	SECRET_METRICS_TLS_SUFFIX = "-metrics-tls"
+)
+
+func getTelemetryPort(dexServer *authv1alpha1.DexServer) int32 {
+	if dexServer.Spec.Ports.Telemetry != 0 {
+		return dexServer.Spec.Ports.Telemetry
+	}
+	return DEX_TELEMETRY_PORT
+}
+
+// Get the address dex serves its metrics on, empty when the telemetry is disabled. Behind kube-rbac-proxy, the
+// metrics are only reachable through the sidecar.
+func getTelemetryAddress(dexServer *authv1alpha1.DexServer) (string, error) {
+	if !dexServer.Spec.Telemetry.Enabled {
+		return "", nil
+	}
+	port := strconv.Itoa(int(getTelemetryPort(dexServer)))
+	if dexServer.Spec.Telemetry.RBACProxy {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+	host := dexServer.Spec.Ports.ListenAddress
+	if host != "" && net.ParseIP(host) == nil {
+		return "", fmt.Errorf("listen address %q is not an IP address", host)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func isRBACProxyEnabled(dexServer *authv1alpha1.DexServer) bool {
+	return dexServer.Spec.Telemetry.Enabled && dexServer.Spec.Telemetry.RBACProxy
+}
+
+func getRBACProxyImagePullSpec() (string, error) {
+	imageName := os.Getenv(RBAC_PROXY_IMAGE_ENV_NAME)
+	if len(imageName) == 0 {
+		return "", fmt.Errorf("required environment variable %v is empty or not set", RBAC_PROXY_IMAGE_ENV_NAME)
+	}
+	return imageName, nil
+}
+
+// Expose the dex metrics for Prometheus, and remove the Service once the telemetry is disabled
+func (r *DexServerReconciler) syncServiceMetrics(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	serviceName := dexServer.Name + METRICS_SERVICE_SUFFIX
+	log.Info("syncServiceMetrics", "Service.Name", serviceName, "DexServer.Namespace", dexServer.Namespace)
+
+	if !dexServer.Spec.Telemetry.Enabled {
+		service := &corev1.Service{}
+		if err := r.Get(ctx, types.NamespacedName{Name: serviceName, Namespace: dexServer.Namespace}, service); err != nil {
+			if kubeerrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		log.Info("Deleting the metrics Service of a disabled telemetry", "Service.Name", serviceName)
+		if err := r.Delete(ctx, service); err != nil && !kubeerrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	port, portName := getTelemetryPort(dexServer), "metrics"
+	var servingCertSecretName string
+	if dexServer.Spec.Telemetry.RBACProxy {
+		port, portName = RBAC_PROXY_PORT, "https-metrics"
+		// Without OpenShift, kube-rbac-proxy serves a self-signed certificate
+		if r.OpenShift {
+			servingCertSecretName = dexServer.Name + SECRET_METRICS_TLS_SUFFIX
+		}
+	}
+	ipFamiliesYaml, err := getServiceIPFamiliesYaml(dexServer)
+	if err != nil {
+		return err
+	}
+
+	values := struct {
+		ServiceName           string
+		ServingCertSecretName string
+		Port                  int32
+		PortName              string
+		IPFamiliesYaml        string
+		DexServer             *authv1alpha1.DexServer
+	}{
+		ServiceName:           serviceName,
+		ServingCertSecretName: servingCertSecretName,
+		Port:                  port,
+		PortName:              portName,
+		IPFamiliesYaml:        ipFamiliesYaml,
+		DexServer:             dexServer,
+	}
+
+	files := []string{
+		"dex-server/service_metrics.yaml",
+	}
+
+	applier, readerDeploy := r.getApplierAndReader(dexServer)
+	return r.applyWithDiff(dexServer, ctx, applier, applier.ApplyDirectly, readerDeploy, values, files...)
+}
@@ -0,0 +1,127 @@
+// Copyright Red Hat
+
+package tracing
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Tracing is configured with the standard OpenTelemetry environment variables, read by the OTLP/HTTP exporter of the
+// OpenTelemetry SDK. Spans are only recorded when an OTLP endpoint is set.
+const (
+	ENDPOINT_ENV_NAME        = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	TRACES_ENDPOINT_ENV_NAME = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	SERVICE_NAME_ENV_NAME    = "OTEL_SERVICE_NAME"
+	DEFAULT_SERVICE_NAME     = "dex-operator"
+	SCOPE_NAME               = "github.com/identitatem/dex-operator"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+var (
+	log = ctrl.Log.WithName("tracing")
+	// nil when tracing is disabled, Start then records nothing
+	tracer trace.Tracer
+)
+
+// Span records a single phase of a reconcile. A nil Span is valid and records nothing, which is what Start
+// returns when tracing is not configured.
+type Span struct {
+	span trace.Span
+}
+
+// Setup reads the OpenTelemetry environment variables and starts exporting spans in the background.
+// The returned function flushes the remaining spans and must be called before the process exits.
+func Setup() (shutdown func()) {
+	if os.Getenv(TRACES_ENDPOINT_ENV_NAME) == "" && os.Getenv(ENDPOINT_ENV_NAME) == "" {
+		log.V(1).Info("no OTLP endpoint configured, tracing is disabled")
+		return func() {}
+	}
+	serviceName := os.Getenv(SERVICE_NAME_ENV_NAME)
+	if serviceName == "" {
+		serviceName = DEFAULT_SERVICE_NAME
+	}
+
+	// the exporter reads the endpoint, the headers, the timeout and the TLS settings from the environment
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		log.Error(err, "failed to create the OTLP exporter, tracing is disabled")
+		return func() {}
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	Use(provider)
+	log.Info("exporting traces", "serviceName", serviceName)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Error(err, "failed to export the remaining spans")
+		}
+	}
+}
+
+// Use records the spans with the tracers of provider, or disables the tracing when provider is nil. Setup calls it
+// with the provider exporting the spans.
+func Use(provider trace.TracerProvider) {
+	if provider == nil {
+		tracer = nil
+		return
+	}
+	tracer = provider.Tracer(SCOPE_NAME)
+}
+
+// Start a span named after a reconcile phase. The span is a child of the span found in ctx, if any.
+// Attributes are given as key, value pairs.
+func Start(ctx context.Context, name string, attributes ...string) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	kvs := make([]attribute.KeyValue, 0, len(attributes)/2)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		kvs = append(kvs, attribute.String(attributes[i], attributes[i+1]))
+	}
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(kvs...))
+	return ctx, &Span{span: span}
+}
+
+// SetAttribute adds an attribute to the span
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(attribute.String(key, value))
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End the span and queue it for export
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
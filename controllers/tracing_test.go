@@ -0,0 +1,105 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	"github.com/identitatem/dex-operator/controllers/tracing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var _ = Describe("Trace the reconciles", func() {
+	AfterEach(func() {
+		tracing.Use(nil)
+	})
+
+	It("should record a span per phase under the span of the reconcile", func() {
+		recorder := tracetest.NewSpanRecorder()
+		tracing.Use(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+		ctx, reconcileSpan := tracing.Start(context.TODO(), "Tracing.Reconcile", "namespace", "my-tracing-ns", "name", "my-dex")
+		_, phaseSpan := tracing.Start(ctx, "syncTracing")
+		phaseSpan.SetAttribute("Secret.Name", "my-secret")
+		phaseSpan.RecordError(fmt.Errorf("failed to sync"))
+		phaseSpan.End()
+		reconcileSpan.End()
+
+		spans := map[string]sdktrace.ReadOnlySpan{}
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+		Expect(spans).To(HaveKey("Tracing.Reconcile"))
+		Expect(spans).To(HaveKey("syncTracing"))
+		reconcile, phase := spans["Tracing.Reconcile"], spans["syncTracing"]
+		Expect(reconcile.Attributes()).To(ContainElements(
+			attribute.String("namespace", "my-tracing-ns"),
+			attribute.String("name", "my-dex")))
+		Expect(reconcile.Status().Code).ToNot(Equal(codes.Error))
+
+		By("linking the phase to the reconcile", func() {
+			Expect(phase.SpanContext().TraceID()).To(Equal(reconcile.SpanContext().TraceID()))
+			Expect(phase.Parent().SpanID()).To(Equal(reconcile.SpanContext().SpanID()))
+		})
+		By("recording the error of the phase", func() {
+			Expect(phase.Attributes()).To(ContainElement(attribute.String("Secret.Name", "my-secret")))
+			Expect(phase.Status().Code).To(Equal(codes.Error))
+			Expect(phase.Status().Description).To(Equal("failed to sync"))
+			Expect(phase.Events()).To(HaveLen(1))
+		})
+	})
+	It("should record nothing when tracing is not configured", func() {
+		ctx, span := tracing.Start(context.TODO(), "Tracing.Reconcile")
+		Expect(span).To(BeNil())
+		Expect(ctx).To(Equal(context.TODO()))
+		// a nil span is valid
+		span.SetAttribute("name", "my-dex")
+		span.RecordError(fmt.Errorf("failed"))
+		span.End()
+
+		Expect(os.Unsetenv(tracing.ENDPOINT_ENV_NAME)).To(Succeed())
+		Expect(os.Unsetenv(tracing.TRACES_ENDPOINT_ENV_NAME)).To(Succeed())
+		tracing.Setup()()
+		_, span = tracing.Start(context.TODO(), "Tracing.Reconcile")
+		Expect(span).To(BeNil())
+	})
+	It("should export the spans to the OTLP/HTTP endpoint", func() {
+		var mu sync.Mutex
+		requests := []*http.Request{}
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, req)
+		}))
+		defer collector.Close()
+		Expect(os.Setenv(tracing.ENDPOINT_ENV_NAME, collector.URL)).To(Succeed())
+		Expect(os.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-tracing-token=my-token")).To(Succeed())
+		defer os.Unsetenv(tracing.ENDPOINT_ENV_NAME)
+		defer os.Unsetenv("OTEL_EXPORTER_OTLP_HEADERS")
+
+		shutdown := tracing.Setup()
+		_, span := tracing.Start(context.TODO(), "Tracing.Reconcile")
+		Expect(span).ToNot(BeNil())
+		span.End()
+		// the remaining spans are flushed on shutdown
+		shutdown()
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(requests).ToNot(BeEmpty())
+		Expect(requests[0].Method).To(Equal(http.MethodPost))
+		Expect(requests[0].URL.Path).To(Equal("/v1/traces"))
+		Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/x-protobuf"))
+		Expect(requests[0].Header.Get("x-tracing-token")).To(Equal("my-token"))
+	})
+})
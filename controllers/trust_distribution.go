@@ -0,0 +1,221 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	"github.com/identitatem/dex-operator/controllers/failures"
+)
+
+var (
+	managedClusterGVR = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+	manifestWorkGVR   = schema.GroupVersionResource{Group: "work.open-cluster-management.io", Version: "v1", Resource: "manifestworks"}
+)
+
+const (
+	defaultTrustTargetNamespace = "openshift-config"
+	// The OpenShift ingress CA, which signs the router certificate the issuer is served with
+	ingressCANamespace = "openshift-config-managed"
+	ingressCAName      = "default-ingress-cert"
+	ingressCAKey       = "ca-bundle.crt"
+)
+
+// syncTrustDistribution creates a ManifestWork in the namespace of each selected ACM managed cluster, delivering a
+// ConfigMap with the issuer, its CA bundle and the client ID the cluster API server authenticates users with.
+// ManifestWorks of clusters that are no longer selected are deleted.
+func (r *DexServerReconciler) syncTrustDistribution(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	log.Info("syncTrustDistribution")
+
+	spec := dexServer.Spec.TrustDistribution
+	if !spec.Enabled {
+		if len(dexServer.Status.TrustDistributedClusters) == 0 {
+			return nil
+		}
+		// the trust distribution was disabled
+		dexServer.Status.TrustDistributedClusters = nil
+		return r.deleteTrustManifestWorks(dexServer, ctx, nil)
+	}
+
+	issuer, err := r.getIssuer(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+	caBundle, err := r.getIssuerCABundle(dexServer, ctx)
+	if err != nil {
+		return err
+	}
+
+	selector := labels.Everything()
+	if spec.ClusterSelector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(spec.ClusterSelector)
+		if err != nil {
+			return errors.Wrap(err, "invalid trustDistribution.clusterSelector")
+		}
+	}
+	clusters, err := r.DynamicClient.Resource(managedClusterGVR).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return errors.Wrap(err, "error listing managed clusters, is this an ACM hub?")
+	}
+
+	selected := map[string]bool{}
+	for _, cluster := range clusters.Items {
+		work := newTrustManifestWork(dexServer, cluster.GetName(), issuer, caBundle)
+		if err := r.applyManifestWork(ctx, work); err != nil {
+			return errors.Wrapf(err, "error applying ManifestWork for managed cluster %s", cluster.GetName())
+		}
+		selected[cluster.GetName()] = true
+	}
+	if err := r.deleteTrustManifestWorks(dexServer, ctx, selected); err != nil {
+		return err
+	}
+
+	distributed := make([]string, 0, len(selected))
+	for name := range selected {
+		distributed = append(distributed, name)
+	}
+	sort.Strings(distributed)
+	dexServer.Status.TrustDistributedClusters = distributed
+	return nil
+}
+
+// getIssuerCABundle returns the CA bundle clients need to trust the certificate the issuer is served with
+func (r *DexServerReconciler) getIssuerCABundle(dexServer *authv1alpha1.DexServer, ctx context.Context) (string, error) {
+	if ref := dexServer.Spec.TrustDistribution.CABundleRef; ref != nil {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: dexServer.Namespace}, configMap); err != nil {
+			return "", errors.Wrap(err, "error getting trustDistribution.caBundleRef")
+		}
+		if configMap.Data[ref.Key] == "" {
+			return "", fmt.Errorf("key %s of ConfigMap %s is empty", ref.Key, ref.Name)
+		}
+		if isCertificateBundleExpired([]byte(configMap.Data[ref.Key])) {
+			return "", failures.New(failures.CertExpired, "the certificates of key %s of ConfigMap %s have expired", ref.Key, ref.Name)
+		}
+		return configMap.Data[ref.Key], nil
+	}
+
+	if r.OpenShift {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ingressCAName, Namespace: ingressCANamespace}, configMap); err != nil {
+			return "", errors.Wrap(err, "error getting the ingress CA bundle")
+		}
+		return configMap.Data[ingressCAKey], nil
+	}
+
+	// The serving certificate generated by the operator is self-signed
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: dexServer.Name + SECRET_WEB_TLS_SUFFIX, Namespace: dexServer.Namespace}, secret); err != nil {
+		return "", errors.Wrap(err, "error getting serving certificate secret")
+	}
+	return string(secret.Data[corev1.TLSCertKey]), nil
+}
+
+func getTrustManifestWorkName(dexServer *authv1alpha1.DexServer) string {
+	return fmt.Sprintf("dex-trust-%s-%s", dexServer.Namespace, dexServer.Name)
+}
+
+func newTrustManifestWork(dexServer *authv1alpha1.DexServer, clusterName string, issuer string, caBundle string) *unstructured.Unstructured {
+	targetNamespace := dexServer.Spec.TrustDistribution.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = defaultTrustTargetNamespace
+	}
+	data := map[string]interface{}{
+		"issuer": issuer,
+		"ca.crt": caBundle,
+	}
+	if clientID := dexServer.Spec.TrustDistribution.ClientID; clientID != "" {
+		data["clientID"] = clientID
+	}
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      dexServer.Name + "-oidc-issuer",
+			"namespace": targetNamespace,
+			"labels": map[string]interface{}{
+				MANAGED_BY_LABEL: MANAGED_BY_VALUE,
+			},
+		},
+		"data": data,
+	}
+
+	work := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"workload": map[string]interface{}{
+				"manifests": []interface{}{configMap},
+			},
+		},
+	}}
+	work.SetAPIVersion("work.open-cluster-management.io/v1")
+	work.SetKind("ManifestWork")
+	work.SetName(getTrustManifestWorkName(dexServer))
+	work.SetNamespace(clusterName)
+	// ManifestWorks live in the namespace of the managed cluster and can't be owned by the DexServer,
+	// they are found with these labels when the DexServer is deleted
+	work.SetLabels(map[string]string{
+		MANAGED_BY_LABEL:          MANAGED_BY_VALUE,
+		INSTANCE_LABEL:            dexServer.Name,
+		DEXSERVER_NAMESPACE_LABEL: dexServer.Namespace,
+	})
+	return work
+}
+
+func (r *DexServerReconciler) applyManifestWork(ctx context.Context, work *unstructured.Unstructured) error {
+	client := r.DynamicClient.Resource(manifestWorkGVR).Namespace(work.GetNamespace())
+	existing, err := client.Get(ctx, work.GetName(), metav1.GetOptions{})
+	switch {
+	case kubeerrors.IsNotFound(err):
+		_, err = client.Create(ctx, work, metav1.CreateOptions{DryRun: r.dryRun})
+		return err
+	case err != nil:
+		return err
+	}
+	existing.Object["spec"] = work.Object["spec"]
+	existing.SetLabels(work.GetLabels())
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{DryRun: r.dryRun})
+	return err
+}
+
+// deleteTrustManifestWorks deletes the ManifestWorks of the DexServer, except those of the keep clusters
+func (r *DexServerReconciler) deleteTrustManifestWorks(dexServer *authv1alpha1.DexServer, ctx context.Context, keep map[string]bool) error {
+	log := ctrllog.FromContext(ctx)
+	selector := labels.SelectorFromSet(labels.Set{
+		MANAGED_BY_LABEL:          MANAGED_BY_VALUE,
+		INSTANCE_LABEL:            dexServer.Name,
+		DEXSERVER_NAMESPACE_LABEL: dexServer.Namespace,
+	})
+	works, err := r.DynamicClient.Resource(manifestWorkGVR).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			// not an ACM hub
+			return nil
+		}
+		return errors.Wrap(err, "error listing ManifestWorks")
+	}
+	for _, work := range works.Items {
+		if keep[work.GetNamespace()] {
+			continue
+		}
+		log.Info("Deleting ManifestWork", "ManifestWork.Namespace", work.GetNamespace(), "ManifestWork.Name", work.GetName())
+		err := r.DynamicClient.Resource(manifestWorkGVR).Namespace(work.GetNamespace()).Delete(ctx, work.GetName(), metav1.DeleteOptions{DryRun: r.dryRun})
+		if err != nil && !kubeerrors.IsNotFound(err) {
+			return errors.Wrap(err, "error deleting ManifestWork")
+		}
+	}
+	return nil
+}
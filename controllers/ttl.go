@@ -0,0 +1,91 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+const (
+	// How often status.ttl.remaining is refreshed
+	TTL_COUNTDOWN_INTERVAL = 5 * time.Minute
+	// A warning Event is emitted when the DexServer is deleted within this window
+	TTL_WARNING_WINDOW = 15 * time.Minute
+)
+
+// Get the time a DexServer with a spec.ttl is deleted
+func getTTLExpiry(dexServer *authv1alpha1.DexServer) (time.Time, bool) {
+	if dexServer.Spec.TTL == nil {
+		return time.Time{}, false
+	}
+	return dexServer.CreationTimestamp.Add(dexServer.Spec.TTL.Duration), true
+}
+
+// Update the countdown of spec.ttl in the status, and delete the DexServer once it elapsed. The status is persisted
+// with the Applied condition. Returns true when the DexServer is being deleted.
+func (r *DexServerReconciler) checkTTL(dexServer *authv1alpha1.DexServer, ctx context.Context) (bool, error) {
+	log := ctrllog.FromContext(ctx)
+	expiresAt, ok := getTTLExpiry(dexServer)
+	if !ok {
+		dexServer.Status.TTL = nil
+		return false, nil
+	}
+	remaining := time.Until(expiresAt)
+
+	if dexServer.Status.TTL == nil || !dexServer.Status.TTL.ExpiresAt.Time.Equal(expiresAt) {
+		log.Info("DexServer deletion scheduled", "ExpiresAt", expiresAt)
+		r.recordTTLEvent(dexServer, corev1.EventTypeNormal, "TTLScheduled", "DexServer is deleted at %s", expiresAt.UTC().Format(time.RFC3339))
+	}
+
+	if remaining <= 0 {
+		log.Info("DexServer TTL elapsed, deleting the DexServer", "TTL", dexServer.Spec.TTL.Duration)
+		r.recordTTLEvent(dexServer, corev1.EventTypeNormal, "TTLExpired", "The TTL of %s elapsed, deleting the DexServer", dexServer.Spec.TTL.Duration)
+		// a DexServer of a ClusterDexServer would be created again, the ClusterDexServer is deleted instead
+		var obj client.Object = dexServer
+		if owner := metav1.GetControllerOf(dexServer); owner != nil && owner.Kind == "ClusterDexServer" &&
+			owner.APIVersion == authv1alpha1.GroupVersion.String() {
+			obj = &authv1alpha1.ClusterDexServer{ObjectMeta: metav1.ObjectMeta{Name: owner.Name}}
+		}
+		return true, client.IgnoreNotFound(r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	}
+
+	if remaining < TTL_WARNING_WINDOW {
+		// the message does not change, so that the recorder aggregates the Events of each countdown refresh
+		r.recordTTLEvent(dexServer, corev1.EventTypeWarning, "TTLExpiring", "DexServer is deleted at %s", expiresAt.UTC().Format(time.RFC3339))
+	}
+	dexServer.Status.TTL = &authv1alpha1.TTLStatus{
+		ExpiresAt: metav1.NewTime(expiresAt),
+		Remaining: remaining.Round(time.Second).String(),
+	}
+	return false, nil
+}
+
+func (r *DexServerReconciler) recordTTLEvent(dexServer *authv1alpha1.DexServer, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(dexServer, eventType, reason, messageFmt, args...)
+	}
+}
+
+// Get when to reconcile again to refresh the countdown of spec.ttl, or to delete the DexServer
+func getTTLRequeueAfter(dexServer *authv1alpha1.DexServer) (time.Duration, bool) {
+	expiresAt, ok := getTTLExpiry(dexServer)
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(expiresAt)
+	if remaining > TTL_COUNTDOWN_INTERVAL {
+		return TTL_COUNTDOWN_INTERVAL, true
+	}
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	return remaining, true
+}
@@ -0,0 +1,114 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+var verticalPodAutoscalerGVR = schema.GroupVersionResource{Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"}
+
+func getResourceListValues(resources corev1.ResourceList) map[string]interface{} {
+	values := map[string]interface{}{}
+	for name, quantity := range resources {
+		values[string(name)] = quantity.String()
+	}
+	return values
+}
+
+// Get the VerticalPodAutoscaler of spec.verticalAutoscaling, targeting the dex container of the Deployment
+func newVerticalPodAutoscaler(dexServer *authv1alpha1.DexServer) *unstructured.Unstructured {
+	spec := dexServer.Spec.VerticalAutoscaling
+	updateMode := spec.UpdateMode
+	if updateMode == "" {
+		updateMode = authv1alpha1.VerticalAutoscalingUpdateModeOff
+	}
+	// the dex container is named after the DexServer, the kube-rbac-proxy sidecar is left out of the recommendations
+	containerPolicy := map[string]interface{}{"containerName": dexServer.Name}
+	if len(spec.MinAllowed) > 0 {
+		containerPolicy["minAllowed"] = getResourceListValues(spec.MinAllowed)
+	}
+	if len(spec.MaxAllowed) > 0 {
+		containerPolicy["maxAllowed"] = getResourceListValues(spec.MaxAllowed)
+	}
+	vpa := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"targetRef": map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       dexServer.Name,
+			},
+			"updatePolicy": map[string]interface{}{"updateMode": string(updateMode)},
+			"resourcePolicy": map[string]interface{}{
+				"containerPolicies": []interface{}{
+					containerPolicy,
+					map[string]interface{}{"containerName": "*", "mode": "Off"},
+				},
+			},
+		},
+	}}
+	vpa.SetAPIVersion("autoscaling.k8s.io/v1")
+	vpa.SetKind("VerticalPodAutoscaler")
+	vpa.SetName(dexServer.Name)
+	vpa.SetNamespace(dexServer.Namespace)
+	vpa.SetLabels(getManagedLabels(dexServer, componentVerticalAutoscaling))
+	return vpa
+}
+
+// Create or update the VerticalPodAutoscaler of the dex Deployment, see spec.verticalAutoscaling. It is owned by the
+// DexServer, and deleted when the vertical autoscaling is disabled.
+func (r *DexServerReconciler) syncVerticalAutoscaling(dexServer *authv1alpha1.DexServer, ctx context.Context) error {
+	log := ctrllog.FromContext(ctx)
+	log.Info("syncVerticalAutoscaling", "VerticalPodAutoscaler.Name", dexServer.Name)
+
+	client := r.DynamicClient.Resource(verticalPodAutoscalerGVR).Namespace(dexServer.Namespace)
+	existing, err := client.Get(ctx, dexServer.Name, metav1.GetOptions{})
+	// also not found when the vertical pod autoscaler is not installed
+	notFound := kubeerrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return errors.Wrap(err, "error getting the VerticalPodAutoscaler")
+	}
+	if !dexServer.Spec.VerticalAutoscaling.Enabled {
+		if notFound || !metav1.IsControlledBy(existing, dexServer) {
+			return nil
+		}
+		log.Info("Deleting the VerticalPodAutoscaler", "VerticalPodAutoscaler.Name", dexServer.Name)
+		err := client.Delete(ctx, dexServer.Name, metav1.DeleteOptions{DryRun: r.dryRun})
+		if err != nil && !kubeerrors.IsNotFound(err) {
+			return errors.Wrap(err, "error deleting the VerticalPodAutoscaler")
+		}
+		return nil
+	}
+
+	vpa := newVerticalPodAutoscaler(dexServer)
+	if err := ctrl.SetControllerReference(dexServer, vpa, r.Scheme); err != nil {
+		return err
+	}
+	if notFound {
+		log.Info("Creating the VerticalPodAutoscaler", "VerticalPodAutoscaler.Name", dexServer.Name)
+		_, err = client.Create(ctx, vpa, metav1.CreateOptions{DryRun: r.dryRun})
+		if kubeerrors.IsNotFound(err) {
+			return fmt.Errorf("spec.verticalAutoscaling requires the vertical pod autoscaler to be installed")
+		}
+		return errors.Wrap(err, "error creating the VerticalPodAutoscaler")
+	}
+	if !metav1.IsControlledBy(existing, dexServer) {
+		return fmt.Errorf("VerticalPodAutoscaler %s already exists and is not managed by the DexServer", dexServer.Name)
+	}
+	existing.Object["spec"] = vpa.Object["spec"]
+	existing.SetLabels(vpa.GetLabels())
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{DryRun: r.dryRun})
+	return errors.Wrap(err, "error updating the VerticalPodAutoscaler")
+}
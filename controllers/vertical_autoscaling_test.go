@@ -0,0 +1,52 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Create the VerticalPodAutoscaler of dex", func() {
+	It("should target the dex container of the Deployment", func() {
+		dexServer := &authv1alpha1.DexServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-vpa-dexserver", Namespace: "my-ns"},
+			Spec: authv1alpha1.DexServerSpec{
+				VerticalAutoscaling: authv1alpha1.VerticalAutoscalingSpec{
+					Enabled:    true,
+					MaxAllowed: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+			},
+		}
+		vpa := newVerticalPodAutoscaler(dexServer)
+		Expect(vpa.GetLabels()).To(HaveKeyWithValue(COMPONENT_LABEL, componentVerticalAutoscaling))
+		target, _, _ := unstructured.NestedString(vpa.Object, "spec", "targetRef", "name")
+		Expect(target).To(Equal("my-vpa-dexserver"))
+		updateMode, _, _ := unstructured.NestedString(vpa.Object, "spec", "updatePolicy", "updateMode")
+		Expect(updateMode).To(Equal("Off"))
+		policies, _, _ := unstructured.NestedSlice(vpa.Object, "spec", "resourcePolicy", "containerPolicies")
+		Expect(policies).To(HaveLen(2))
+		Expect(policies[0]).To(HaveKeyWithValue("containerName", "my-vpa-dexserver"))
+		Expect(policies[0]).To(HaveKeyWithValue("maxAllowed", map[string]interface{}{"memory": "512Mi"}))
+
+		By("applying the recommendations in Auto mode", func() {
+			dexServer.Spec.VerticalAutoscaling.UpdateMode = authv1alpha1.VerticalAutoscalingUpdateModeAuto
+			updateMode, _, _ := unstructured.NestedString(newVerticalPodAutoscaler(dexServer).Object, "spec", "updatePolicy", "updateMode")
+			Expect(updateMode).To(Equal("Auto"))
+		})
+		By("requiring the vertical pod autoscaler", func() {
+			// the VerticalPodAutoscaler API is not served by the test environment
+			Expect(rDexServer.syncVerticalAutoscaling(dexServer, context.TODO())).ToNot(Succeed())
+			dexServer.Spec.VerticalAutoscaling.Enabled = false
+			Expect(rDexServer.syncVerticalAutoscaling(dexServer, context.TODO())).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,75 @@
+// Copyright Red Hat
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
+)
+
+// Directory of the web content served by dex, the content bundled in the dex image overlaid with the templates of
+// spec.web.templatesConfigMapRef by an init container
+const DEX_WEB_DIR = "/etc/dex/web"
+
+// Get the hash of the content of the ConfigMap of spec.web.templatesConfigMapRef, empty when it is not set. The hash is
+// set on the pod template so that dex restarts with the new templates, it only reads them on startup.
+func (r *DexServerReconciler) getWebTemplatesHash(dexServer *authv1alpha1.DexServer, ctx context.Context) (string, error) {
+	ref := dexServer.Spec.Web.TemplatesConfigMapRef
+	if ref == nil {
+		return "", nil
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: dexServer.Namespace}, configMap); err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return "", fmt.Errorf("ConfigMap %s of spec.web.templatesConfigMapRef not found", ref.Name)
+		}
+		return "", errors.Wrap(err, "error getting the web templates ConfigMap")
+	}
+	keys := make([]string, 0, len(configMap.Data)+len(configMap.BinaryData))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	for key := range configMap.BinaryData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=", key)
+		if value, ok := configMap.Data[key]; ok {
+			fmt.Fprintf(h, "%q\n", value)
+		} else {
+			fmt.Fprintf(h, "%q\n", configMap.BinaryData[key])
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Map a ConfigMap to the DexServers of its namespace using it as web templates
+func getDexServersForWebTemplates(c client.Client, configMap client.Object) []reconcile.Request {
+	var dexServerList authv1alpha1.DexServerList
+	if err := c.List(context.TODO(), &dexServerList, client.InNamespace(configMap.GetNamespace())); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, dexServer := range dexServerList.Items {
+		ref := dexServer.Spec.Web.TemplatesConfigMapRef
+		if ref != nil && ref.Name == configMap.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: dexServer.Name, Namespace: dexServer.Namespace},
+			})
+		}
+	}
+	return requests
+}
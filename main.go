@@ -3,16 +3,23 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	dexconfig "github.com/identitatem/dex-operator/config"
+	configv1 "github.com/openshift/api/config/v1"
 	routev1 "github.com/openshift/api/route/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/dynamic"
@@ -20,11 +27,16 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	clusteradmapply "open-cluster-management.io/clusteradm/pkg/helpers/apply"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	authv1alpha1 "github.com/identitatem/dex-operator/api/v1alpha1"
 	"github.com/identitatem/dex-operator/controllers"
+	dexapi "github.com/identitatem/dex-operator/controllers/dex"
+	"github.com/identitatem/dex-operator/controllers/redact"
+	"github.com/identitatem/dex-operator/controllers/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -37,26 +49,145 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(authv1alpha1.AddToScheme(scheme))
-	utilruntime.Must(routev1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
+	// The Jobs of the DexStorageMigrations run the operator image to copy the objects
+	if len(os.Args) > 1 && os.Args[1] == controllers.STORAGE_MIGRATION_COMMAND {
+		migrateStorage()
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var preProvisionedRBAC bool
+	var clusterRoleName string
+	var dexClientConcurrency int
+	var dexMaxConcurrentCalls int
+	var storageMigrationImage string
+	var fips bool
+	var requireEncryptionAtRest bool
+	var issuerDirectoryNamespace string
+	var issuerDirectoryName string
+	var importIdentityProviders string
+	var importIdentityProvidersMode string
+	var enableWebhooks bool
+	var keyPoolSize int
+	var keyPoolWorkers int
+	var observe bool
+	var namespaceCleanupDryRun bool
+	var dnsValidationServer string
+	var notificationWebhookURL string
+	var enableExport bool
+	var dexEnabledNamespacesSet string
+	var ingressControllerNamespaces string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&preProvisionedRBAC, "pre-provisioned-rbac", false,
+		"Do not create the ClusterRole and ClusterRoleBindings of the dex servers. "+
+			"They must be created by an administrator, the operator only validates they exist.")
+	flag.StringVar(&clusterRoleName, "cluster-role-name", controllers.SERVICE_ACCOUNT_NAME,
+		"The name of the ClusterRole bound to the dex server service accounts.")
+	flag.IntVar(&dexClientConcurrency, "dexclient-concurrency", 1,
+		"The number of DexClients registered with the dex servers in parallel.")
+	flag.IntVar(&dexMaxConcurrentCalls, "dex-grpc-max-concurrent-calls", dexapi.DefaultMaxConcurrentCalls,
+		"The maximum number of gRPC calls in flight to a dex server.")
+	flag.StringVar(&storageMigrationImage, "storage-migration-image", "",
+		"The image of the Jobs of the DexStorageMigrations, copying the objects of a dex storage to another one. "+
+			"Defaults to the image of the operator, which copies them with its "+controllers.STORAGE_MIGRATION_COMMAND+" command.")
+	flag.StringVar(&issuerDirectoryNamespace, "issuer-directory-namespace", "",
+		"The namespace of the ConfigMap listing the issuers of all the DexServers. The directory is not maintained when empty.")
+	flag.StringVar(&issuerDirectoryName, "issuer-directory-name", "dex-issuers",
+		"The name of the ConfigMap listing the issuers of all the DexServers.")
+	flag.StringVar(&importIdentityProviders, "import-identity-providers", "",
+		"The <namespace>/<name> of the DexServer the identity providers of the OpenShift OAuth config are imported in. "+
+			"The identity providers are not imported when empty.")
+	flag.StringVar(&importIdentityProvidersMode, "import-identity-providers-mode", "propose",
+		"Whether the imported connectors are only proposed in a ConfigMap (propose), or also added to the DexServer (create).")
+	flag.BoolVar(&fips, "fips", false,
+		"Only use FIPS approved algorithms and key sizes for the generated certificates, and run the dex servers in FIPS mode.")
+	flag.BoolVar(&requireEncryptionAtRest, "require-encryption-at-rest", false,
+		"Only write the connector credentials and dex configurations once etcd is verified to be encrypted at rest.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the DexClient defaulting and validation webhooks, and the DexServer validation webhook. They must be registered with config/webhook.")
+	flag.IntVar(&keyPoolSize, "key-pool-size", controllers.DEFAULT_KEY_POOL_SIZE,
+		"The number of certificate keys generated ahead of the DexServer reconciles. The keys are generated in the reconciles when 0.")
+	flag.IntVar(&keyPoolWorkers, "key-pool-workers", controllers.DEFAULT_KEY_POOL_WORKERS,
+		"The number of certificate keys generated in parallel ahead of the DexServer reconciles.")
+	flag.BoolVar(&observe, "observe", false,
+		"Reconcile the DexServers with dry runs and report the changes the operator would make, without writing. "+
+			"Used to validate an upgrade of the operator against the DexServers of a cluster, alongside the operator managing them.")
+	flag.BoolVar(&namespaceCleanupDryRun, "namespace-cleanup-dry-run", false,
+		"Only report the gRPC Service, service account and ClusterRoleBinding of the namespaces without DexServer, "+
+			"instead of deleting them.")
+	flag.StringVar(&dnsValidationServer, "dns-validation-server", "",
+		"The address, host:port, of the DNS server looking up the issuer hosts of the DexServers with spec.route.validateDNS. "+
+			"Defaults to the resolver of the operator pod.")
+	flag.StringVar(&notificationWebhookURL, "notification-webhook-url", "",
+		"The URL of a webhook, e.g. a Slack incoming webhook, the DexServers becoming Degraded and the certificates about to expire are posted to. "+
+			"No notifications are posted when unset.")
+	flag.BoolVar(&enableExport, "enable-export", false,
+		"Serve the DexServers, DexClients and rendered dex configs of the cluster as a kustomize bundle on the /export path of the metrics endpoint, "+
+			"to rebuild them after a disaster or clone them into another environment. The secrets are not exported.")
+	flag.StringVar(&dexEnabledNamespacesSet, "dex-enabled-namespaces-set", "",
+		"The name of the DexServerSet stamping out a DexServer in the namespaces labeled "+controllers.DEX_ENABLED_NAMESPACE_LABEL+"="+
+			controllers.DEX_ENABLED_NAMESPACE_VALUE+", among those of its namespaceSelector. The namespaces don't opt in with the label when empty.")
+	flag.StringVar(&ingressControllerNamespaces, "ingress-controller-namespaces", controllers.DEFAULT_INGRESS_CONTROLLER_NAMESPACE,
+		"The namespaces of the ingress controllers, separated by commas, admitted to the dex servers with authproxy connectors outside of OpenShift. "+
+			"On OpenShift the namespaces of the routers are admitted.")
+	flag.Func("redact-log-pattern",
+		"A regular expression whose matches are redacted from the logs, along with the PEM blocks and the secret fields of the dex configuration. Can be repeated.",
+		func(pattern string) error {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			redact.AddPattern(re)
+			return nil
+		})
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// The logs are redacted at every verbosity, see redact.NewLogger
+	ctrl.SetLogger(redact.NewLogger(zap.New(zap.UseFlagOptions(&opts))))
+
+	// Export reconcile traces when an OTLP endpoint is configured through the OTEL_* environment variables
+	shutdownTracing := tracing.Setup()
+	defer shutdownTracing()
+
+	// Only rely on the OpenShift APIs when the cluster serves them
+	isOpenShift, err := controllers.IsOpenShift(kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie()))
+	if err != nil {
+		setupLog.Error(err, "unable to detect the OpenShift APIs")
+		os.Exit(1)
+	}
+	// The devmode builds fake the OpenShift service CA and router, to exercise the OpenShift code paths locally
+	fakeOpenShift := !isOpenShift && controllers.FakeOpenShift
+	if fakeOpenShift {
+		setupLog.Info("OpenShift APIs not found, faking the OpenShift serving certificates and routes")
+		isOpenShift = true
+	}
+	if isOpenShift {
+		utilruntime.Must(routev1.AddToScheme(scheme))
+		utilruntime.Must(configv1.AddToScheme(scheme))
+	} else {
+		setupLog.Info("OpenShift APIs not found, running in plain Kubernetes mode")
+	}
+
+	// An observing operator runs alongside the operator managing the DexServers, and must not take its leadership
+	leaderElectionID := "09c5986b.identitatem.io"
+	if observe {
+		setupLog.Info("Observe mode, the operator reports the changes it would make without writing")
+		leaderElectionID = "observe.09c5986b.identitatem.io"
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -64,7 +195,7 @@ func main() {
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "09c5986b.identitatem.io",
+		LeaderElectionID:       leaderElectionID,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -76,39 +207,207 @@ func main() {
 	dynamicClient := dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie())
 	apiExtensionClient := apiextensionsclient.NewForConfigOrDie(ctrl.GetConfigOrDie())
 
+	// The Jobs of the DexStorageMigrations run the migrate-storage command of the operator image by default
+	var storageMigrationCommand []string
+	if storageMigrationImage == "" {
+		if storageMigrationImage, err = getOperatorImage(kubeClient); err != nil {
+			setupLog.Error(err, "unable to find the image of the operator, the DexStorageMigrations fail unless --storage-migration-image is set")
+		} else {
+			storageMigrationCommand = []string{"/manager", controllers.STORAGE_MIGRATION_COMMAND}
+		}
+	}
+
 	applierBuilder := &clusteradmapply.ApplierBuilder{}
 	applier := applierBuilder.WithClient(kubeClient, apiExtensionClient, dynamicClient).Build()
 
 	readerConfig := dexconfig.GetScenarioResourcesReader()
 
 	files := []string{"crd/bases/auth.identitatem.io_dexclients.yaml",
-		"crd/bases/auth.identitatem.io_dexservers.yaml"}
+		"crd/bases/auth.identitatem.io_dexservers.yaml",
+		"crd/bases/auth.identitatem.io_dexstoragemigrations.yaml",
+		"crd/bases/auth.identitatem.io_clusterdexservers.yaml",
+		"crd/bases/auth.identitatem.io_dexquickstarts.yaml",
+		"crd/bases/auth.identitatem.io_dexserversets.yaml"}
 
-	_, err = applier.ApplyDirectly(readerConfig, nil, false, "", files...)
-	if err != nil {
-		setupLog.Error(err, "unable to create install the crds for controller", "crds", files)
-		os.Exit(1)
+	if !observe {
+		_, err = applier.ApplyDirectly(readerConfig, nil, false, "", files...)
+		if err != nil {
+			setupLog.Error(err, "unable to create install the crds for controller", "crds", files)
+			os.Exit(1)
+		}
 	}
 
-	if err = (&controllers.DexServerReconciler{
-		Client:             mgr.GetClient(),
-		KubeClient:         kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie()),
-		DynamicClient:      dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie()),
-		APIExtensionClient: apiextensionsclient.NewForConfigOrDie(ctrl.GetConfigOrDie()),
-		Scheme:             mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	// The writes of the other controllers are sent as dry runs in observe mode
+	writeClient := mgr.GetClient()
+	if observe {
+		writeClient = client.NewDryRunClient(writeClient)
+	}
+
+	if err := controllers.WarnUnencryptedAtRest(context.TODO(), dynamicClient, isOpenShift); err != nil {
+		setupLog.Error(err, "unable to check the encryption at rest of etcd")
+	}
+
+	var keyPool *controllers.KeyPool
+	if keyPoolSize > 0 {
+		keyPool = &controllers.KeyPool{Size: keyPoolSize, Workers: keyPoolWorkers, Bits: controllers.PRIVATE_KEY_SIZE}
+		if fips {
+			keyPool.Bits = controllers.FIPS_PRIVATE_KEY_SIZE
+		}
+		if err := mgr.Add(keyPool); err != nil {
+			setupLog.Error(err, "unable to add the key pool")
+			os.Exit(1)
+		}
+	}
+
+	dexServerReconciler := &controllers.DexServerReconciler{
+		Client:                  mgr.GetClient(),
+		KubeClient:              kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie()),
+		DynamicClient:           dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie()),
+		APIExtensionClient:      apiextensionsclient.NewForConfigOrDie(ctrl.GetConfigOrDie()),
+		Scheme:                  mgr.GetScheme(),
+		OpenShift:               isOpenShift,
+		PreProvisionedRBAC:      preProvisionedRBAC,
+		ClusterRoleName:         clusterRoleName,
+		Recorder:                mgr.GetEventRecorderFor("dexserver-controller"),
+		FIPS:                    fips,
+		RequireEncryptionAtRest: requireEncryptionAtRest,
+		KeyPool:                 keyPool,
+		Observe:                 observe,
+		NamespaceCleanupDryRun:  namespaceCleanupDryRun,
+	}
+	if ingressControllerNamespaces != "" {
+		dexServerReconciler.IngressControllerNamespaces = strings.Split(ingressControllerNamespaces, ",")
+	}
+	if dnsValidationServer != "" {
+		dexServerReconciler.Resolver = controllers.NewDNSServerResolver(dnsValidationServer)
+	}
+	if notificationWebhookURL != "" {
+		dexServerReconciler.Notifier = controllers.NewNotifier(notificationWebhookURL)
+	}
+	if err = dexServerReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DexServer")
 		os.Exit(1)
 	}
-	if err = (&controllers.DexClientReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	// The objects of the DexServers are upgraded from the layouts of the previous operator versions once elected
+	if !observe {
+		if err := mgr.Add(manager.RunnableFunc(dexServerReconciler.MigrateLayouts)); err != nil {
+			setupLog.Error(err, "unable to add the layout migrations")
+			os.Exit(1)
+		}
+		// the shared objects left over in the namespaces without DexServer by the previous operator versions
+		if err := mgr.Add(manager.RunnableFunc(dexServerReconciler.CleanupNamespaces)); err != nil {
+			setupLog.Error(err, "unable to add the namespace cleanup")
+			os.Exit(1)
+		}
+		// the members of the GitHub teams of spec.teamSync, outside of the reconciles
+		if err := mgr.Add(manager.RunnableFunc(dexServerReconciler.SyncTeams)); err != nil {
+			setupLog.Error(err, "unable to add the sync of the GitHub teams")
+			os.Exit(1)
+		}
+	}
+	if observe {
+		// the clients are registered through the gRPC API of the dex servers, which has no dry run
+		setupLog.Info("Observe mode, the DexClients are not reconciled")
+	} else if err = (&controllers.DexClientReconciler{
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Pool:                    &dexapi.Pool{},
+		MaxConcurrentGRPCCalls:  dexMaxConcurrentCalls,
+		MaxConcurrentReconciles: dexClientConcurrency,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DexClient")
 		os.Exit(1)
 	}
+	if err = (&controllers.DexStorageMigrationReconciler{
+		Client:  writeClient,
+		Scheme:  mgr.GetScheme(),
+		Image:   storageMigrationImage,
+		Command: storageMigrationCommand,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DexStorageMigration")
+		os.Exit(1)
+	}
+	if err = (&controllers.ClusterDexServerReconciler{
+		Client: writeClient,
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterDexServer")
+		os.Exit(1)
+	}
+	if err = (&controllers.DexQuickstartReconciler{
+		Client: writeClient,
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DexQuickstart")
+		os.Exit(1)
+	}
+	if err = (&controllers.DexServerSetReconciler{
+		Client:                  writeClient,
+		Scheme:                  mgr.GetScheme(),
+		DexEnabledNamespacesSet: dexEnabledNamespacesSet,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DexServerSet")
+		os.Exit(1)
+	}
+	if issuerDirectoryNamespace != "" {
+		if err = (&controllers.IssuerDirectoryReconciler{
+			Client:    writeClient,
+			Namespace: issuerDirectoryNamespace,
+			Name:      issuerDirectoryName,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "IssuerDirectory")
+			os.Exit(1)
+		}
+	}
+	if importIdentityProviders != "" {
+		// the OAuth config is only served by OpenShift clusters
+		namespaceName := strings.SplitN(importIdentityProviders, "/", 2)
+		switch {
+		case len(namespaceName) != 2 || namespaceName[0] == "" || namespaceName[1] == "":
+			setupLog.Error(nil, "invalid --import-identity-providers, expected <namespace>/<name>", "value", importIdentityProviders)
+			os.Exit(1)
+		case importIdentityProvidersMode != "propose" && importIdentityProvidersMode != "create":
+			setupLog.Error(nil, "invalid --import-identity-providers-mode, expected propose or create", "value", importIdentityProvidersMode)
+			os.Exit(1)
+		case !isOpenShift || fakeOpenShift:
+			setupLog.Info("OpenShift OAuth config not served, the identity providers are not imported")
+		default:
+			if err = (&controllers.IdentityProviderImportReconciler{
+				Client:           writeClient,
+				Namespace:        namespaceName[0],
+				Name:             namespaceName[1],
+				CreateConnectors: importIdentityProvidersMode == "create",
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "IdentityProviderImport")
+				os.Exit(1)
+			}
+		}
+	}
+	if fakeOpenShift {
+		if err = controllers.SetupFakeOpenShiftWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create the fake OpenShift controllers")
+			os.Exit(1)
+		}
+	}
+	if enableWebhooks {
+		if err = (&authv1alpha1.DexClient{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DexClient")
+			os.Exit(1)
+		}
+		if err = (&authv1alpha1.DexServer{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DexServer")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
+	if enableExport {
+		if err := mgr.AddMetricsExtraHandler(controllers.EXPORT_PATH, &controllers.ExportHandler{Client: mgr.GetAPIReader()}); err != nil {
+			setupLog.Error(err, "unable to add the export endpoint")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -121,6 +420,39 @@ func main() {
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
+		shutdownTracing()
+		os.Exit(1)
+	}
+}
+
+// getOperatorImage returns the image of the manager container of the operator pod, set by the downward API in the
+// POD_NAME and POD_NAMESPACE environment variables
+func getOperatorImage(kubeClient kubernetes.Interface) (string, error) {
+	pod, err := kubeClient.CoreV1().Pods(os.Getenv("POD_NAMESPACE")).Get(context.TODO(), os.Getenv("POD_NAME"), metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "manager" {
+			return container.Image, nil
+		}
+	}
+	return "", fmt.Errorf("the pod %s has no manager container", pod.Name)
+}
+
+// migrateStorage copies the objects of the storage of a DexServer to the target storage of a DexStorageMigration, in
+// its Job
+func migrateStorage() {
+	ctrl.SetLogger(redact.NewLogger(zap.New()))
+	namespace, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		setupLog.Error(err, "unable to read the namespace of the Job")
+		os.Exit(1)
+	}
+	dynamicClient := dynamic.NewForConfigOrDie(ctrl.GetConfigOrDie())
+	if err := controllers.RunStorageMigration(context.TODO(), dynamicClient, strings.TrimSpace(string(namespace))); err != nil {
+		setupLog.Error(err, "unable to copy the objects of the storage")
 		os.Exit(1)
 	}
+	setupLog.Info("Copied the objects of the storage")
 }